@@ -4,7 +4,6 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
-	"log"
 	"os"
 	"path/filepath"
 	"sync"
@@ -14,10 +13,13 @@ import (
 	vss "github.com/corestario/kyber/share/vss/rabin"
 	"github.com/syndtr/goleveldb/leveldb"
 
+	"github.com/lidofinance/dc4bc/client/modules/logger"
 	client "github.com/lidofinance/dc4bc/client/types"
 	"github.com/lidofinance/dc4bc/dkg"
 	"github.com/lidofinance/dc4bc/fsm/fsm"
 	"github.com/lidofinance/dc4bc/fsm/state_machines/dkg_proposal_fsm"
+	"github.com/lidofinance/dc4bc/fsm/state_machines/resharing_fsm"
+	"github.com/lidofinance/dc4bc/fsm/state_machines/share_refresh_fsm"
 	"github.com/lidofinance/dc4bc/fsm/state_machines/signature_proposal_fsm"
 	"github.com/lidofinance/dc4bc/fsm/state_machines/signing_proposal_fsm"
 	"github.com/lidofinance/dc4bc/fsm/types/requests"
@@ -32,7 +34,10 @@ type Machine struct {
 
 	ResultFolder string
 
+	// DKG instances of rounds, every round has its own DKG key pair, operation log and result folder
 	dkgInstances map[string]*dkg.DKG
+	// DKG instances of share refreshes, the keyring of a round is refreshed by a resharing among its participants
+	refreshInstances map[string]*dkg.DKG
 	// Used to encrypt local sensitive data, e.g. BLS keyrings.
 	encryptionKey []byte
 	pubKey        kyber.Point
@@ -40,35 +45,39 @@ type Machine struct {
 	baseSuite     vss.Suite
 	baseSeed      []byte
 
+	// the key derived from encryptionKey and the salt, it is kept to not repeat the slow derivation
+	derivedKey []byte
+
 	db *leveldb.DB
+
+	logger logger.Logger
+}
+
+// operationLogger adds the DKG round and the operation to logged messages
+func (am *Machine) operationLogger(operation client.Operation) logger.Logger {
+	return am.logger.With(logger.DkgID(operation.DKGIdentifier), logger.OperationID(operation.ID),
+		logger.Any("operation_type", operation.Type))
 }
 
-func NewMachine(dbPath string) (*Machine, error) {
+// NewMachine opens the machine's LevelDB storage at dbPath, messages of the machine are written to the logger
+func NewMachine(dbPath string, l logger.Logger) (*Machine, error) {
 	var (
 		err error
 	)
 
 	am := &Machine{
-		dkgInstances: make(map[string]*dkg.DKG),
+		dkgInstances:     make(map[string]*dkg.DKG),
+		refreshInstances: make(map[string]*dkg.DKG),
+		logger:           l,
 	}
 
 	if am.db, err = leveldb.OpenFile(dbPath, nil); err != nil {
 		return nil, fmt.Errorf("failed to open db file %s for keys: %w", dbPath, err)
 	}
 
-	if err := am.loadBaseSeed(); err != nil {
-		return nil, fmt.Errorf("failed to loadBaseSeed: %w", err)
-	}
-
-	if _, err = am.db.Get([]byte(operationsLogDBKey), nil); err != nil {
-		if errors.Is(err, leveldb.ErrNotFound) {
-			operationsLogBz, _ := json.Marshal(RoundOperationLog{})
-			if err := am.db.Put([]byte(operationsLogDBKey), operationsLogBz, nil); err != nil {
-				return nil, fmt.Errorf("failed to init Operation log: %w", err)
-			}
-		} else {
-			return nil, fmt.Errorf("failed to init Operation log (fatal): %w", err)
-		}
+	// a storage of a previous version is left as is, it is migrated only by Migrate
+	if err = am.initSchemaVersion(); err != nil {
+		return nil, err
 	}
 
 	return am, nil
@@ -79,7 +88,20 @@ func (am *Machine) SetResultFolder(resultFolder string) {
 }
 
 // InitKeys load keys public and private keys for DKG from LevelDB. If keys do not exist, it creates them.
+// The base seed is loaded as well, so the encryption key must be set
 func (am *Machine) InitKeys() error {
+	needsMigration, err := am.NeedsMigration()
+	if err != nil {
+		return err
+	}
+	if needsMigration {
+		return ErrNeedsMigration
+	}
+
+	if err := am.loadBaseSeed(); err != nil {
+		return fmt.Errorf("failed to loadBaseSeed: %w", err)
+	}
+
 	if err := am.LoadKeysFromDB(); err != nil {
 		// If keys were not generated yet.
 		if errors.Is(err, leveldb.ErrNotFound) {
@@ -105,6 +127,7 @@ func (am *Machine) GenerateKeys() error {
 // SetEncryptionKey set a key to encrypt and decrypt sensitive data.
 func (am *Machine) SetEncryptionKey(key []byte) {
 	am.encryptionKey = key
+	am.derivedKey = nil
 }
 
 // SensitiveDataRemoved indicates whether sensitive information has been cleared
@@ -121,8 +144,12 @@ func (am *Machine) DropSensitiveData() {
 	am.secKey = nil
 	am.pubKey = nil
 	am.encryptionKey = nil
+	am.derivedKey = nil
+	am.baseSeed = nil
 }
 
+// ReplayOperationsLog processes the stored operations of the round again. After a finished share refresh the log
+// starts from the refreshed keyring, the operations of the DKG and the refresh are not kept
 func (am *Machine) ReplayOperationsLog(dkgIdentifier string) error {
 	operationsLog, err := am.getOperationsLog(dkgIdentifier)
 	if err != nil {
@@ -135,15 +162,23 @@ func (am *Machine) ReplayOperationsLog(dkgIdentifier string) error {
 			return fmt.Errorf("failed to ProcessOperation: %w", err)
 		}
 
-		log.Printf("JSON file for operation %d was saved to: %s\n", idx, path)
+		am.operationLogger(operation).Infof("JSON file for operation %d was saved to: %s", idx, path)
 	}
 
-	log.Println("Successfully replayed Operation log")
+	am.logger.With(logger.DkgID(dkgIdentifier)).Infof("Successfully replayed Operation log")
 
 	return nil
 }
 
 func (am *Machine) ProcessOperation(operation client.Operation, storeOperation bool) (string, error) {
+	archived, err := am.isRoundArchived(operation.DKGIdentifier)
+	if err != nil {
+		return "", err
+	}
+	if archived {
+		return "", fmt.Errorf("round %s is archived", operation.DKGIdentifier)
+	}
+
 	resultOperation, err := am.GetOperationResult(operation)
 	if err != nil {
 		return "", fmt.Errorf(
@@ -162,7 +197,11 @@ func (am *Machine) ProcessOperation(operation client.Operation, storeOperation b
 		return "", fmt.Errorf("failed to marshal operation: %w", err)
 	}
 
-	path := filepath.Join(am.ResultFolder, operation.Filename()+"_result.json")
+	folder, err := am.roundResultFolder(operation.DKGIdentifier)
+	if err != nil {
+		return "", err
+	}
+	path := filepath.Join(folder, operation.Filename()+"_result.json")
 
 	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE, 0600)
 	if err != nil {
@@ -187,18 +226,16 @@ func (am *Machine) DropOperationsLog(dkgIdentifier string) error {
 func (am *Machine) getParticipantID(dkgIdentifier string) (int, error) {
 	dkgInstance, ok := am.dkgInstances[dkgIdentifier]
 	if !ok {
-		return 0, fmt.Errorf("invalid dkg identifier: %s", dkgIdentifier)
+		// the DKG instance is not restored after a share refresh, the participant id is the same
+		if dkgInstance, ok = am.refreshInstances[dkgIdentifier]; !ok {
+			return 0, fmt.Errorf("invalid dkg identifier: %s", dkgIdentifier)
+		}
 	}
 	return dkgInstance.ParticipantID, nil
 }
 
 // encryptDataForParticipant encrypts a data using the public key of the participant to whom the data is sent
-func (am *Machine) encryptDataForParticipant(dkgIdentifier, to string, data []byte) ([]byte, error) {
-	dkgInstance, ok := am.dkgInstances[dkgIdentifier]
-	if !ok {
-		return nil, fmt.Errorf("invalid dkg identifier: %s", dkgIdentifier)
-	}
-
+func (am *Machine) encryptDataForParticipant(dkgInstance *dkg.DKG, to string, data []byte) ([]byte, error) {
 	pk, err := dkgInstance.GetPubKeyByParticipant(to)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get pk for participant %s: %w", to, err)
@@ -211,9 +248,9 @@ func (am *Machine) encryptDataForParticipant(dkgIdentifier, to string, data []by
 	return encryptedData, nil
 }
 
-// decryptDataFromParticipant decrypts the data that was sent to us
-func (am *Machine) decryptDataFromParticipant(data []byte) ([]byte, error) {
-	decryptedData, err := ecies.Decrypt(am.baseSuite, am.secKey, data, am.baseSuite.Hash)
+// decryptDataFromParticipant decrypts the data that was sent to us with our DKG key of the instance
+func (am *Machine) decryptDataFromParticipant(dkgInstance *dkg.DKG, data []byte) ([]byte, error) {
+	decryptedData, err := ecies.Decrypt(am.baseSuite, dkgInstance.GetSecKey(), data, am.baseSuite.Hash)
 	if err != nil {
 		return nil, fmt.Errorf("failed to decrypt data: %w", err)
 	}
@@ -224,6 +261,7 @@ func (am *Machine) GetOperationResult(operation client.Operation) (client.Operat
 	var (
 		err error
 	)
+	am.operationLogger(operation).Debugf("Handling operation %s", operation.Type)
 	// handler gets a pointer to an operation, do necessary things
 	// and write a result (or an error) to .Result field of operation
 	switch fsm.State(operation.Type) {
@@ -239,14 +277,30 @@ func (am *Machine) GetOperationResult(operation client.Operation) (client.Operat
 		err = am.handleStateDkgMasterKeyAwaitConfirmations(&operation)
 	case signing_proposal_fsm.StateSigningAwaitPartialSigns:
 		err = am.handleStateSigningAwaitPartialSigns(&operation)
+	case share_refresh_fsm.StateShareRefreshCommitsAwaitConfirmations:
+		err = am.handleStateShareRefreshCommitsAwaitConfirmations(&operation)
+	case share_refresh_fsm.StateShareRefreshDealsAwaitConfirmations:
+		err = am.handleStateShareRefreshDealsAwaitConfirmations(&operation)
+	case share_refresh_fsm.StateShareRefreshResponsesAwaitConfirmations:
+		err = am.handleStateShareRefreshResponsesAwaitConfirmations(&operation)
+	case share_refresh_fsm.StateShareRefreshKeyringAwaitConfirmations:
+		err = am.handleStateShareRefreshKeyringAwaitConfirmations(&operation)
+	case resharing_fsm.StateResharingCommitsAwaitConfirmations:
+		err = am.handleStateResharingCommitsAwaitConfirmations(&operation)
+	case resharing_fsm.StateResharingDealsAwaitConfirmations:
+		err = am.handleStateResharingDealsAwaitConfirmations(&operation)
+	case resharing_fsm.StateResharingResponsesAwaitConfirmations:
+		err = am.handleStateResharingResponsesAwaitConfirmations(&operation)
+	case resharing_fsm.StateResharingKeyringAwaitConfirmations:
+		err = am.handleStateResharingKeyringAwaitConfirmations(&operation)
 	default:
 		err = fmt.Errorf("invalid operation type: %s", operation.Type)
 	}
 
 	// if we have error after handling the operation, we write the error to the operation, so we can feed it to a FSM
 	if err != nil {
-		log.Println(fmt.Sprintf("failed to handle operation %s, returning response with error to node: %v",
-			operation.Type, err))
+		am.operationLogger(operation).Errorf("failed to handle operation %s, returning response with error to node: %v",
+			operation.Type, err)
 		if e := am.writeErrorRequestToOperation(&operation, err); e != nil {
 			return operation, fmt.Errorf("failed to write error request to an operation: %w", e)
 		}
@@ -267,6 +321,15 @@ func (am *Machine) writeErrorRequestToOperation(o *client.Operation, handlerErro
 		dkg_proposal_fsm.StateDkgMasterKeyAwaitConfirmations:       dkg_proposal_fsm.EventDKGMasterKeyConfirmationError,
 		signing_proposal_fsm.StateSigningAwaitPartialSigns:         signing_proposal_fsm.EventSigningPartialSignError,
 		signing_proposal_fsm.StateSigningPartialSignsCollected:     client.SignatureReconstructionFailed,
+
+		share_refresh_fsm.StateShareRefreshCommitsAwaitConfirmations:   share_refresh_fsm.EventShareRefreshCommitConfirmationError,
+		share_refresh_fsm.StateShareRefreshDealsAwaitConfirmations:     share_refresh_fsm.EventShareRefreshDealConfirmationError,
+		share_refresh_fsm.StateShareRefreshResponsesAwaitConfirmations: share_refresh_fsm.EventShareRefreshResponseConfirmationError,
+		share_refresh_fsm.StateShareRefreshKeyringAwaitConfirmations:   share_refresh_fsm.EventShareRefreshKeyringConfirmationError,
+		resharing_fsm.StateResharingCommitsAwaitConfirmations:          resharing_fsm.EventResharingCommitConfirmationError,
+		resharing_fsm.StateResharingDealsAwaitConfirmations:            resharing_fsm.EventResharingDealConfirmationError,
+		resharing_fsm.StateResharingResponsesAwaitConfirmations:        resharing_fsm.EventResharingResponseConfirmationError,
+		resharing_fsm.StateResharingKeyringAwaitConfirmations:          resharing_fsm.EventResharingKeyringConfirmationError,
 	}
 	pid, err := am.getParticipantID(o.DKGIdentifier)
 	if err != nil {
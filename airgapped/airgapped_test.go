@@ -9,12 +9,20 @@ import (
 	"testing"
 	"time"
 
+	"github.com/corestario/kyber"
+	"github.com/corestario/kyber/pairing"
+	"github.com/corestario/kyber/share"
+	"github.com/corestario/kyber/sign/tbls"
 	"github.com/google/uuid"
 	"github.com/stretchr/testify/require"
 
+	"github.com/lidofinance/dc4bc/client/modules/logger"
 	client "github.com/lidofinance/dc4bc/client/types"
+	"github.com/lidofinance/dc4bc/dkg"
 	"github.com/lidofinance/dc4bc/fsm/fsm"
 	"github.com/lidofinance/dc4bc/fsm/state_machines/dkg_proposal_fsm"
+	"github.com/lidofinance/dc4bc/fsm/state_machines/resharing_fsm"
+	"github.com/lidofinance/dc4bc/fsm/state_machines/share_refresh_fsm"
 	"github.com/lidofinance/dc4bc/fsm/state_machines/signature_proposal_fsm"
 	"github.com/lidofinance/dc4bc/fsm/state_machines/signing_proposal_fsm"
 	"github.com/lidofinance/dc4bc/fsm/types/requests"
@@ -24,6 +32,7 @@ import (
 
 const (
 	DKGIdentifier            = "dkg_identifier"
+	ResharingIdentifier      = "resharing_identifier"
 	testDB                   = "test_level_db"
 	testDir                  = "/tmp/airgapped_test"
 	successfulBatchSigningID = "successful_batch_signing_id"
@@ -33,6 +42,7 @@ type Node struct {
 	ParticipantID              int
 	Participant                string
 	Machine                    *Machine
+	dkgPubKey                  kyber.Point
 	participationConfirmations []requests.SignatureProposalParticipantRequest
 	commits                    []requests.DKGProposalCommitConfirmationRequest
 	deals                      []requests.DKGProposalDealConfirmationRequest
@@ -49,25 +59,29 @@ func (n *Node) storeOperation(msg storage.Message) error {
 			return fmt.Errorf("failed to unmarshal fsm req: %w", err)
 		}
 		n.participationConfirmations = append(n.participationConfirmations, req)
-	case dkg_proposal_fsm.EventDKGCommitConfirmationReceived:
+	case dkg_proposal_fsm.EventDKGCommitConfirmationReceived, share_refresh_fsm.EventShareRefreshCommitConfirmationReceived,
+		resharing_fsm.EventResharingCommitConfirmationReceived:
 		var req requests.DKGProposalCommitConfirmationRequest
 		if err := json.Unmarshal(msg.Data, &req); err != nil {
 			return fmt.Errorf("failed to unmarshal fsm req: %w", err)
 		}
 		n.commits = append(n.commits, req)
-	case dkg_proposal_fsm.EventDKGDealConfirmationReceived:
+	case dkg_proposal_fsm.EventDKGDealConfirmationReceived, share_refresh_fsm.EventShareRefreshDealConfirmationReceived,
+		resharing_fsm.EventResharingDealConfirmationReceived:
 		var req requests.DKGProposalDealConfirmationRequest
 		if err := json.Unmarshal(msg.Data, &req); err != nil {
 			return fmt.Errorf("failed to unmarshal fsm req: %w", err)
 		}
 		n.deals = append(n.deals, req)
-	case dkg_proposal_fsm.EventDKGResponseConfirmationReceived:
+	case dkg_proposal_fsm.EventDKGResponseConfirmationReceived, share_refresh_fsm.EventShareRefreshResponseConfirmationReceived,
+		resharing_fsm.EventResharingResponseConfirmationReceived:
 		var req requests.DKGProposalResponseConfirmationRequest
 		if err := json.Unmarshal(msg.Data, &req); err != nil {
 			return fmt.Errorf("failed to unmarshal fsm req: %w", err)
 		}
 		n.responses = append(n.responses, req)
-	case dkg_proposal_fsm.EventDKGMasterKeyConfirmationReceived:
+	case dkg_proposal_fsm.EventDKGMasterKeyConfirmationReceived, share_refresh_fsm.EventShareRefreshKeyringConfirmationReceived,
+		resharing_fsm.EventResharingKeyringConfirmationReceived:
 		var req requests.DKGProposalMasterKeyConfirmationRequest
 		if err := json.Unmarshal(msg.Data, &req); err != nil {
 			return fmt.Errorf("failed to unmarshal fsm req: %w", err)
@@ -103,7 +117,7 @@ func (tr *Transport) BroadcastMessage(msg storage.Message) error {
 func createTransport(participants []string) (*Transport, error) {
 	tr := &Transport{}
 	for i := 0; i < len(participants); i++ {
-		am, err := NewMachine(fmt.Sprintf("%s/%s-%d", testDir, testDB, i))
+		am, err := NewMachine(fmt.Sprintf("%s/%s-%d", testDir, testDB, i), logger.NewLogger(participants[i]))
 		if err != nil {
 			return nil, fmt.Errorf("failed to create airgapped machine: %w", err)
 		}
@@ -115,6 +129,7 @@ func createTransport(participants []string) (*Transport, error) {
 			ParticipantID: i,
 			Participant:   participants[i],
 			Machine:       am,
+			dkgPubKey:     am.pubKey,
 		}
 		tr.nodes = append(tr.nodes, &node)
 	}
@@ -158,7 +173,7 @@ func (tr *Transport) processOperation(n *Node, op client.Operation) error {
 func (tr *Transport) initRequest(threshold int) error {
 	var initReq responses.SignatureProposalParticipantInvitationsResponse
 	for _, n := range tr.nodes {
-		pubKey, err := n.Machine.pubKey.MarshalBinary()
+		pubKey, err := n.dkgPubKey.MarshalBinary()
 		if err != nil {
 			return fmt.Errorf("failed to marshal dkg pubkey: %w", err)
 		}
@@ -187,7 +202,7 @@ func (tr *Transport) initRequest(threshold int) error {
 func (tr *Transport) commitsStep(threshold int) error {
 	var getCommitsRequest responses.DKGProposalPubKeysParticipantResponse
 	for _, n := range tr.nodes {
-		pubKey, err := n.Machine.pubKey.MarshalBinary()
+		pubKey, err := n.dkgPubKey.MarshalBinary()
 		if err != nil {
 			return fmt.Errorf("%s: failed to marshal pubkey: %w", n.Participant, err)
 		}
@@ -213,7 +228,7 @@ func (tr *Transport) commitsStep(threshold int) error {
 	})
 }
 
-func (tr *Transport) dealsStep() error {
+func (tr *Transport) dealsStep(opType fsm.State) error {
 	return runStep(tr, func(n *Node, wg *sync.WaitGroup) error {
 		defer wg.Done()
 
@@ -226,7 +241,7 @@ func (tr *Transport) dealsStep() error {
 			}
 			payload = append(payload, &p)
 		}
-		op, err := createOperation(string(dkg_proposal_fsm.StateDkgDealsAwaitConfirmations), "", payload)
+		op, err := createOperation(string(opType), "", payload)
 		if err != nil {
 			return fmt.Errorf("failed to create operation: %w", err)
 		}
@@ -238,7 +253,7 @@ func (tr *Transport) dealsStep() error {
 	})
 }
 
-func (tr *Transport) responsesStep() error {
+func (tr *Transport) responsesStep(opType fsm.State) error {
 	return runStep(tr, func(n *Node, wg *sync.WaitGroup) error {
 		defer wg.Done()
 
@@ -251,7 +266,7 @@ func (tr *Transport) responsesStep() error {
 			}
 			payload = append(payload, &p)
 		}
-		op, err := createOperation(string(dkg_proposal_fsm.StateDkgResponsesAwaitConfirmations), "", payload)
+		op, err := createOperation(string(opType), "", payload)
 		if err != nil {
 			return fmt.Errorf("failed to create operation: %w", err)
 		}
@@ -263,7 +278,7 @@ func (tr *Transport) responsesStep() error {
 	})
 }
 
-func (tr *Transport) masterKeysStep() error {
+func (tr *Transport) masterKeysStep(opType fsm.State) error {
 	return runStep(tr, func(n *Node, wg *sync.WaitGroup) error {
 		defer wg.Done()
 
@@ -276,7 +291,7 @@ func (tr *Transport) masterKeysStep() error {
 			}
 			payload = append(payload, &p)
 		}
-		op, err := createOperation(string(dkg_proposal_fsm.StateDkgMasterKeyAwaitConfirmations), "", payload)
+		op, err := createOperation(string(opType), "", payload)
 		if err != nil {
 			return fmt.Errorf("failed to create operation: %w", err)
 		}
@@ -288,7 +303,7 @@ func (tr *Transport) masterKeysStep() error {
 	})
 }
 
-func (tr *Transport) partialSignsStep(batchID string, msgsToSign []requests.MessageToSign) error {
+func (tr *Transport) partialSignsStep(batchID string, msgsToSign []requests.MessageToSign, keyringVersion int) error {
 	return runStep(tr, func(n *Node, wg *sync.WaitGroup) error {
 		defer wg.Done()
 
@@ -298,8 +313,9 @@ func (tr *Transport) partialSignsStep(batchID string, msgsToSign []requests.Mess
 		}
 
 		payload := responses.SigningPartialSignsParticipantInvitationsResponse{
-			BatchID:    batchID,
-			SrcPayload: msgs,
+			BatchID:        batchID,
+			SrcPayload:     msgs,
+			KeyringVersion: keyringVersion,
 		}
 
 		op, err := createOperation(string(signing_proposal_fsm.StateSigningAwaitPartialSigns), "", payload)
@@ -314,6 +330,34 @@ func (tr *Transport) partialSignsStep(batchID string, msgsToSign []requests.Mess
 	})
 }
 
+func (tr *Transport) shareRefreshCommitsStep(threshold, keyringVersion int) error {
+	payload := responses.ShareRefreshInvitationResponse{KeyringVersion: keyringVersion}
+	for _, n := range tr.nodes {
+		pubKey, err := n.dkgPubKey.MarshalBinary()
+		if err != nil {
+			return fmt.Errorf("%s: failed to marshal pubkey: %w", n.Participant, err)
+		}
+		payload.Participants = append(payload.Participants, &responses.DKGProposalPubKeysParticipantEntry{
+			ParticipantId: n.ParticipantID,
+			Username:      n.Participant,
+			DkgPubKey:     pubKey,
+			Threshold:     threshold,
+		})
+	}
+	op, err := createOperation(string(share_refresh_fsm.StateShareRefreshCommitsAwaitConfirmations), "", payload)
+	if err != nil {
+		return fmt.Errorf("failed to create operation: %w", err)
+	}
+	return runStep(tr, func(n *Node, wg *sync.WaitGroup) error {
+		defer wg.Done()
+
+		if err := tr.processOperation(n, *op); err != nil {
+			return fmt.Errorf("failed to process operation: %w", err)
+		}
+		return nil
+	})
+}
+
 func (tr *Transport) checkReconstructedMasterKeys() error {
 	for _, n := range tr.nodes {
 		for i := 0; i < len(n.masterKeys); i++ {
@@ -343,15 +387,15 @@ func TestAirgappedAllSteps(t *testing.T) {
 		t.Fatal(fmt.Errorf("failed to do commits step: %w", err))
 	}
 
-	if err := tr.dealsStep(); err != nil {
+	if err := tr.dealsStep(dkg_proposal_fsm.StateDkgDealsAwaitConfirmations); err != nil {
 		t.Fatal(fmt.Errorf("failed to do deals step: %w", err))
 	}
 
-	if err := tr.responsesStep(); err != nil {
+	if err := tr.responsesStep(dkg_proposal_fsm.StateDkgResponsesAwaitConfirmations); err != nil {
 		t.Fatal(fmt.Errorf("failed to do responses step: %w", err))
 	}
 
-	if err := tr.masterKeysStep(); err != nil {
+	if err := tr.masterKeysStep(dkg_proposal_fsm.StateDkgMasterKeyAwaitConfirmations); err != nil {
 		t.Fatal(fmt.Errorf("failed to do master keys step: %w", err))
 	}
 
@@ -366,7 +410,7 @@ func TestAirgappedAllSteps(t *testing.T) {
 		},
 	}
 
-	if err := tr.partialSignsStep(successfulBatchSigningID, msgToSign); err != nil {
+	if err := tr.partialSignsStep(successfulBatchSigningID, msgToSign, 0); err != nil {
 		t.Fatal(fmt.Errorf("failed to do master keys step: %w", err))
 	}
 
@@ -391,7 +435,7 @@ func TestAirgappedMachine_Replay(t *testing.T) {
 		t.Fatal(fmt.Errorf("failed to do init request: %w", err))
 	}
 
-	if err := tr.dealsStep(); err != nil {
+	if err := tr.dealsStep(dkg_proposal_fsm.StateDkgDealsAwaitConfirmations); err != nil {
 		t.Fatal(fmt.Errorf("failed to do init request: %w", err))
 	}
 
@@ -414,6 +458,7 @@ func TestAirgappedMachine_Replay(t *testing.T) {
 	}
 
 	for _, node := range newTr.nodes {
+		node.Machine.SetResultFolder(testDir)
 		err := node.Machine.ReplayOperationsLog(DKGIdentifier)
 		require.NoError(t, err)
 	}
@@ -421,11 +466,11 @@ func TestAirgappedMachine_Replay(t *testing.T) {
 	//oldTr := tr
 	tr = newTr
 
-	if err := tr.responsesStep(); err != nil {
+	if err := tr.responsesStep(dkg_proposal_fsm.StateDkgResponsesAwaitConfirmations); err != nil {
 		t.Fatal(fmt.Errorf("failed to do init request: %w", err))
 	}
 
-	if err := tr.masterKeysStep(); err != nil {
+	if err := tr.masterKeysStep(dkg_proposal_fsm.StateDkgMasterKeyAwaitConfirmations); err != nil {
 		t.Fatal(fmt.Errorf("failed to do init request: %w", err))
 	}
 
@@ -440,13 +485,335 @@ func TestAirgappedMachine_Replay(t *testing.T) {
 		},
 	}
 
-	if err := tr.partialSignsStep(successfulBatchSigningID, msgToSign); err != nil {
+	if err := tr.partialSignsStep(successfulBatchSigningID, msgToSign, 0); err != nil {
 		t.Fatal(fmt.Errorf("failed to do init request: %w", err))
 	}
 
 	fmt.Println("DKG succeeded")
 }
 
+func TestAirgappedMachine_ShareRefresh(t *testing.T) {
+	nodesCount := 4
+	threshold := 3
+	participants := make([]string, nodesCount)
+	for i := 0; i < nodesCount; i++ {
+		participants[i] = fmt.Sprintf("Participant#%d", i)
+	}
+
+	tr, err := createTransport(participants)
+	require.NoError(t, err)
+	defer os.RemoveAll(testDir)
+
+	require.NoError(t, tr.commitsStep(threshold))
+	require.NoError(t, tr.dealsStep(dkg_proposal_fsm.StateDkgDealsAwaitConfirmations))
+	require.NoError(t, tr.responsesStep(dkg_proposal_fsm.StateDkgResponsesAwaitConfirmations))
+	require.NoError(t, tr.masterKeysStep(dkg_proposal_fsm.StateDkgMasterKeyAwaitConfirmations))
+	require.NoError(t, tr.checkReconstructedMasterKeys())
+
+	masterKey := tr.nodes[0].masterKeys[0].MasterKey
+	oldKeyrings := make([]*dkg.BLSKeyring, 0, nodesCount)
+	for _, n := range tr.nodes {
+		keyring, err := n.Machine.loadBLSKeyring(DKGIdentifier)
+		require.NoError(t, err)
+		oldKeyrings = append(oldKeyrings, keyring)
+
+		n.commits, n.deals, n.responses, n.masterKeys = nil, nil, nil, nil
+	}
+
+	require.NoError(t, tr.shareRefreshCommitsStep(threshold, 0))
+	require.NoError(t, tr.dealsStep(share_refresh_fsm.StateShareRefreshDealsAwaitConfirmations))
+	require.NoError(t, tr.responsesStep(share_refresh_fsm.StateShareRefreshResponsesAwaitConfirmations))
+	require.NoError(t, tr.masterKeysStep(share_refresh_fsm.StateShareRefreshKeyringAwaitConfirmations))
+	require.NoError(t, tr.checkReconstructedMasterKeys())
+	require.Equal(t, masterKey, tr.nodes[0].masterKeys[0].MasterKey)
+
+	// the refreshed keyring is used by the signing of the new version only
+	for _, n := range tr.nodes {
+		version, err := n.Machine.GetBLSKeyringVersion(DKGIdentifier)
+		require.NoError(t, err)
+		require.Equal(t, 0, version)
+	}
+
+	msgToSign := []requests.MessageToSign{
+		{
+			MessageID: "s1",
+			Payload:   []byte("i am a message"),
+		},
+	}
+	require.NoError(t, tr.partialSignsStep(successfulBatchSigningID, msgToSign, 1))
+
+	for i, n := range tr.nodes {
+		version, err := n.Machine.GetBLSKeyringVersion(DKGIdentifier)
+		require.NoError(t, err)
+		require.Equal(t, 1, version)
+
+		keyring, err := n.Machine.loadBLSKeyring(DKGIdentifier)
+		require.NoError(t, err)
+		require.True(t, keyring.PubPoly.Commit().Equal(oldKeyrings[i].PubPoly.Commit()))
+		require.False(t, keyring.Share.V.Equal(oldKeyrings[i].Share.V))
+
+		// the operations dealing the old shares are dropped, the replay keeps the refreshed keyring
+		ops, err := n.Machine.getOperationsLog(DKGIdentifier)
+		require.NoError(t, err)
+		require.Empty(t, ops)
+
+		n.Machine.SetResultFolder(testDir)
+		require.NoError(t, n.Machine.ReplayOperationsLog(DKGIdentifier))
+		replayedKeyring, err := n.Machine.loadBLSKeyring(DKGIdentifier)
+		require.NoError(t, err)
+		require.True(t, replayedKeyring.Share.V.Equal(keyring.Share.V))
+	}
+
+	var partialSigns [][]byte
+	for _, req := range tr.nodes[0].partialSigns {
+		partialSigns = append(partialSigns, req.PartialSigns[0].Sign)
+	}
+
+	// the new shares reconstruct a signature of the same key
+	keyring, err := tr.nodes[0].Machine.loadBLSKeyring(DKGIdentifier)
+	require.NoError(t, err)
+	signature, err := tbls.Recover(tr.nodes[0].Machine.baseSuite.(pairing.Suite), keyring.PubPoly, msgToSign[0].Payload,
+		partialSigns[:threshold], threshold, nodesCount)
+	require.NoError(t, err)
+	require.NoError(t, tr.nodes[0].Machine.VerifySign(msgToSign[0].Payload, signature, DKGIdentifier))
+
+	// there is no keyring of the old version anymore
+	require.Error(t, tr.partialSignsStep(successfulBatchSigningID, msgToSign, 0))
+}
+
+func TestAirgappedMachine_Resharing(t *testing.T) {
+	var (
+		oldNodesCount = 4
+		oldThreshold  = 3
+		threshold     = 4
+	)
+	participants := make([]string, oldNodesCount+2)
+	for i := range participants {
+		participants[i] = fmt.Sprintf("Participant#%d", i)
+	}
+
+	tr, err := createTransport(participants)
+	require.NoError(t, err)
+	defer os.RemoveAll(testDir)
+
+	oldTr := &Transport{nodes: tr.nodes[:oldNodesCount]}
+	require.NoError(t, oldTr.commitsStep(oldThreshold))
+	require.NoError(t, oldTr.dealsStep(dkg_proposal_fsm.StateDkgDealsAwaitConfirmations))
+	require.NoError(t, oldTr.responsesStep(dkg_proposal_fsm.StateDkgResponsesAwaitConfirmations))
+	require.NoError(t, oldTr.masterKeysStep(dkg_proposal_fsm.StateDkgMasterKeyAwaitConfirmations))
+	require.NoError(t, oldTr.checkReconstructedMasterKeys())
+
+	masterKey := tr.nodes[0].masterKeys[0].MasterKey
+	oldKeyring, err := tr.nodes[0].Machine.loadBLSKeyring(DKGIdentifier)
+	require.NoError(t, err)
+	oldPubPolyBz, err := oldKeyring.PubPolyBytes()
+	require.NoError(t, err)
+
+	// the new committee is the old participants #1, #2, #3 and the new participants #4, #5,
+	// the old participants #0, #1, #2 deal their shares, #0 leaves the committee and #3 does not deal
+	var (
+		receivers = tr.nodes[1:]
+		roundIDs  = []int{5, 0, 1, 2, 3, 4}
+		payload   = responses.ResharingInvitationResponse{
+			OldDkgID:     DKGIdentifier,
+			OldThreshold: oldThreshold,
+			OldPubPolyBz: oldPubPolyBz,
+			Dealers:      map[int]int{5: 0, 0: 1, 1: 2},
+			Receivers:    len(receivers),
+			Threshold:    threshold,
+		}
+		usernames = make(map[int]string)
+	)
+	for i, n := range tr.nodes {
+		n.commits, n.deals, n.responses, n.masterKeys = nil, nil, nil, nil
+
+		pubKey, err := n.dkgPubKey.MarshalBinary()
+		require.NoError(t, err)
+		if i < oldNodesCount {
+			payload.OldParticipants = append(payload.OldParticipants, &responses.DKGProposalPubKeysParticipantEntry{
+				ParticipantId: i,
+				Username:      n.Participant,
+				DkgPubKey:     pubKey,
+				Threshold:     oldThreshold,
+			})
+		}
+		payload.Participants = append(payload.Participants, &responses.DKGProposalPubKeysParticipantEntry{
+			ParticipantId: roundIDs[i],
+			Username:      n.Participant,
+			DkgPubKey:     pubKey,
+			Threshold:     threshold,
+		})
+		usernames[roundIDs[i]] = n.Participant
+	}
+
+	// a dealer refuses to deal when the public polynomial to reshare differs from its keyring
+	_, commits := oldKeyring.PubPoly.Info()
+	tamperedCommits := make([]kyber.Point, 0, len(commits))
+	for _, commit := range commits {
+		tamperedCommits = append(tamperedCommits, commit.Clone())
+	}
+	tamperedCommits[1].Add(tamperedCommits[1], tamperedCommits[1])
+	tamperedKeyring := dkg.BLSKeyring{PubPoly: share.NewPubPoly(tr.nodes[0].Machine.baseSuite, nil, tamperedCommits)}
+	tamperedPayload := payload
+	tamperedPayload.OldPubPolyBz, err = tamperedKeyring.PubPolyBytes()
+	require.NoError(t, err)
+	op, err := createOperation(string(resharing_fsm.StateResharingCommitsAwaitConfirmations), "", tamperedPayload)
+	require.NoError(t, err)
+	op.DKGIdentifier = "tampered_resharing_identifier"
+	require.ErrorContains(t, tr.nodes[0].Machine.handleStateResharingCommitsAwaitConfirmations(op),
+		"public polynomial of DKG #"+DKGIdentifier+" does not match the reshared one")
+
+	processResharingOperation := func(nodes []*Node, opType fsm.State, getPayload func(n *Node) interface{}) {
+		for _, n := range nodes {
+			op, err := createOperation(string(opType), "", getPayload(n))
+			require.NoError(t, err)
+			op.DKGIdentifier = ResharingIdentifier
+			require.NoError(t, tr.processOperation(n, *op))
+		}
+	}
+
+	processResharingOperation(tr.nodes, resharing_fsm.StateResharingCommitsAwaitConfirmations, func(n *Node) interface{} {
+		return payload
+	})
+
+	dealers := []*Node{tr.nodes[0], tr.nodes[1], tr.nodes[2]}
+	processResharingOperation(dealers, resharing_fsm.StateResharingDealsAwaitConfirmations, func(n *Node) interface{} {
+		var commits responses.DKGProposalCommitParticipantResponse
+		for _, req := range n.commits {
+			commits = append(commits, &responses.DKGProposalCommitParticipantEntry{
+				ParticipantId: req.ParticipantId,
+				Username:      usernames[req.ParticipantId],
+				DkgCommit:     req.Commit,
+			})
+		}
+		return commits
+	})
+
+	// every participant gets a deal or a marker from every dealer
+	for _, n := range tr.nodes {
+		require.Len(t, n.deals, len(dealers))
+	}
+
+	processResharingOperation(receivers, resharing_fsm.StateResharingResponsesAwaitConfirmations, func(n *Node) interface{} {
+		var deals responses.ResharingDealsParticipantResponse
+		for _, req := range n.commits {
+			if _, ok := payload.Dealers[req.ParticipantId]; ok {
+				deals.Commits = append(deals.Commits, &responses.DKGProposalCommitParticipantEntry{
+					ParticipantId: req.ParticipantId,
+					Username:      usernames[req.ParticipantId],
+					DkgCommit:     req.Commit,
+				})
+			}
+		}
+		for _, req := range n.deals {
+			deals.Deals = append(deals.Deals, &responses.DKGProposalDealParticipantEntry{
+				ParticipantId: req.ParticipantId,
+				Username:      usernames[req.ParticipantId],
+				DkgDeal:       req.Deal,
+			})
+		}
+		return deals
+	})
+
+	processResharingOperation(receivers, resharing_fsm.StateResharingKeyringAwaitConfirmations, func(n *Node) interface{} {
+		var resps responses.DKGProposalResponseParticipantResponse
+		for _, req := range n.responses {
+			resps = append(resps, &responses.DKGProposalResponseParticipantEntry{
+				ParticipantId: req.ParticipantId,
+				Username:      usernames[req.ParticipantId],
+				DkgResponse:   req.Response,
+			})
+		}
+		return resps
+	})
+
+	require.NoError(t, tr.checkReconstructedMasterKeys())
+	for _, n := range receivers {
+		require.Len(t, n.masterKeys, len(receivers))
+		require.Equal(t, masterKey, n.masterKeys[0].MasterKey)
+	}
+
+	// the new committee signs with the same key and the new threshold
+	msgToSign := []requests.MessageToSign{
+		{
+			MessageID: "s1",
+			Payload:   []byte("i am a message"),
+		},
+	}
+	msgs, err := json.Marshal(msgToSign)
+	require.NoError(t, err)
+	processResharingOperation(receivers, signing_proposal_fsm.StateSigningAwaitPartialSigns, func(n *Node) interface{} {
+		return responses.SigningPartialSignsParticipantInvitationsResponse{
+			BatchID:    successfulBatchSigningID,
+			SrcPayload: msgs,
+		}
+	})
+
+	var partialSigns [][]byte
+	for _, req := range tr.nodes[1].partialSigns {
+		partialSigns = append(partialSigns, req.PartialSigns[0].Sign)
+	}
+	require.Len(t, partialSigns, len(receivers))
+
+	keyring, err := tr.nodes[1].Machine.loadBLSKeyring(ResharingIdentifier)
+	require.NoError(t, err)
+	suite := tr.nodes[1].Machine.baseSuite.(pairing.Suite)
+
+	_, err = tbls.Recover(suite, keyring.PubPoly, msgToSign[0].Payload, partialSigns[:threshold-1], threshold, len(receivers))
+	require.Error(t, err)
+
+	signature, err := tbls.Recover(suite, keyring.PubPoly, msgToSign[0].Payload, partialSigns[:threshold], threshold, len(receivers))
+	require.NoError(t, err)
+	require.NoError(t, tr.nodes[0].Machine.VerifySign(msgToSign[0].Payload, signature, DKGIdentifier))
+}
+
+func TestAirgappedMachine_RoundKeys(t *testing.T) {
+	nodesCount := 3
+	threshold := 2
+	participants := make([]string, nodesCount)
+	for i := 0; i < nodesCount; i++ {
+		participants[i] = fmt.Sprintf("Participant#%d", i)
+	}
+
+	tr, err := createTransport(participants)
+	require.NoError(t, err)
+	defer os.RemoveAll(testDir)
+
+	// every participant takes part in the round with a key pair generated for the round
+	for _, n := range tr.nodes {
+		n.dkgPubKey, err = n.Machine.GenerateRoundKeys()
+		require.NoError(t, err)
+		require.False(t, n.dkgPubKey.Equal(n.Machine.pubKey))
+	}
+
+	require.NoError(t, tr.commitsStep(threshold))
+	require.NoError(t, tr.dealsStep(dkg_proposal_fsm.StateDkgDealsAwaitConfirmations))
+	require.NoError(t, tr.responsesStep(dkg_proposal_fsm.StateDkgResponsesAwaitConfirmations))
+	require.NoError(t, tr.masterKeysStep(dkg_proposal_fsm.StateDkgMasterKeyAwaitConfirmations))
+	require.NoError(t, tr.checkReconstructedMasterKeys())
+
+	for _, n := range tr.nodes {
+		rounds, err := n.Machine.ListRounds()
+		require.NoError(t, err)
+		require.Len(t, rounds, 1)
+		require.Equal(t, DKGIdentifier, rounds[0].DKGIdentifier)
+		require.True(t, rounds[0].Finished)
+		require.Equal(t, 4, rounds[0].Operations)
+
+		pubKeyBz, err := n.dkgPubKey.MarshalBinary()
+		require.NoError(t, err)
+		require.Equal(t, pubKeyBz, rounds[0].PubKey)
+	}
+
+	msgToSign := []requests.MessageToSign{
+		{
+			MessageID: "s1",
+			Payload:   []byte("i am a message"),
+		},
+	}
+	require.NoError(t, tr.partialSignsStep(successfulBatchSigningID, msgToSign, 0))
+}
+
 func runStep(transport *Transport, cb func(n *Node, wg *sync.WaitGroup) error) error {
 	var wg = &sync.WaitGroup{}
 	for _, node := range transport.nodes {
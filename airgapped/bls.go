@@ -35,6 +35,11 @@ func (am *Machine) handleStateSigningAwaitPartialSigns(o *client.Operation) erro
 		return fmt.Errorf("failed to unmarshal messages to sign: %w", err)
 	}
 
+	// the signing may be the first one after a share refresh, so the refreshed keyring replaces the old one
+	if err = am.activateBLSKeyring(o.DKGIdentifier, payload.KeyringVersion); err != nil {
+		return fmt.Errorf("failed to activate BLSKeyring: %w", err)
+	}
+
 	signs := make([]requests.PartialSign, 0, len(signingTasks))
 	participantID, err := am.getParticipantID(o.DKGIdentifier)
 	if err != nil {
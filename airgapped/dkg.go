@@ -75,25 +75,18 @@ func (am *Machine) handleStateDkgCommitsAwaitConfirmations(o *client.Operation)
 		return fmt.Errorf("failed to unmarshal payload: %w", err)
 	}
 
-	pid := -1
-	for _, r := range payload {
-		pubkey := am.baseSuite.Point()
-		if err := pubkey.UnmarshalBinary(r.DkgPubKey); err != nil {
-			return fmt.Errorf("failed to unmarshal dkg pubkey: %w", err)
-		}
-		if am.pubKey.Equal(pubkey) {
-			pid = r.ParticipantId
-			break
-		}
-	}
-	if pid < 0 {
-		return fmt.Errorf("failed to determine participant id for DKG #%s", o.DKGIdentifier)
-	}
-
 	if _, ok := am.dkgInstances[o.DKGIdentifier]; ok {
 		return fmt.Errorf("dkg instance %s already exists", o.DKGIdentifier)
 	}
 
+	if _, err = am.bindRoundKeys(o.DKGIdentifier, payload); err != nil {
+		return err
+	}
+	pubKey, secKey, err := am.getRoundKeys(o.DKGIdentifier)
+	if err != nil {
+		return err
+	}
+
 	// Here we create a new seeded suite for the new DKG round with seed =
 	// sha256.Sum256(baseSeed + DKGIdentifier). We need this to avoid identical
 	// DKG rounds.
@@ -101,22 +94,37 @@ func (am *Machine) handleStateDkgCommitsAwaitConfirmations(o *client.Operation)
 		dkgSeed = sha256.Sum256(append([]byte(o.DKGIdentifier), am.baseSeed...))
 		suite   = bls.NewBLS12381Suite(dkgSeed[:])
 	)
-	dkgInstance := dkg.Init(suite, am.pubKey, am.secKey)
+	dkgInstance := dkg.Init(suite, pubKey, secKey)
 	dkgInstance.Threshold = payload[0].Threshold //same for everyone
 	dkgInstance.N = len(payload)
 
+	if err = am.storePubKeys(dkgInstance, payload); err != nil {
+		return err
+	}
+
+	if err = dkgInstance.InitDKGInstance(am.baseSeed); err != nil {
+		return fmt.Errorf("failed to init dkg instance: %w", err)
+	}
+
+	am.dkgInstances[o.DKGIdentifier] = dkgInstance
+
+	return am.sendCommits(o, dkgInstance, dkg_proposal_fsm.EventDKGCommitConfirmationReceived)
+}
+
+// storePubKeys stores the DKG pub keys of the participants in the DKG instance
+func (am *Machine) storePubKeys(dkgInstance *dkg.DKG, payload responses.DKGProposalPubKeysParticipantResponse) error {
 	for _, entry := range payload {
 		pubKey := am.baseSuite.Point()
-		if err = pubKey.UnmarshalBinary(entry.DkgPubKey); err != nil {
+		if err := pubKey.UnmarshalBinary(entry.DkgPubKey); err != nil {
 			return fmt.Errorf("failed to unmarshal pubkey: %w", err)
 		}
 		dkgInstance.StorePubKey(entry.Username, entry.ParticipantId, pubKey)
 	}
+	return nil
+}
 
-	if err = dkgInstance.InitDKGInstance(am.baseSeed); err != nil {
-		return fmt.Errorf("failed to init dkg instance: %w", err)
-	}
-
+// sendCommits returns the commits of the DKG instance to broadcast
+func (am *Machine) sendCommits(o *client.Operation, dkgInstance *dkg.DKG, event fsm.Event) error {
 	dkgCommits := dkgInstance.GetCommits()
 	marshaledCommits := make([][]byte, 0, len(dkgCommits))
 	for _, commit := range dkgCommits {
@@ -131,8 +139,6 @@ func (am *Machine) handleStateDkgCommitsAwaitConfirmations(o *client.Operation)
 		return fmt.Errorf("failed to marshal marshaledCommits: %w", err)
 	}
 
-	am.dkgInstances[o.DKGIdentifier] = dkgInstance
-
 	req := requests.DKGProposalCommitConfirmationRequest{
 		ParticipantId: dkgInstance.ParticipantID,
 		Commit:        commitsBz,
@@ -143,7 +149,7 @@ func (am *Machine) handleStateDkgCommitsAwaitConfirmations(o *client.Operation)
 		return fmt.Errorf("failed to generate fsm request: %w", err)
 	}
 
-	o.Event = dkg_proposal_fsm.EventDKGCommitConfirmationReceived
+	o.Event = event
 	o.ResultMsgs = append(o.ResultMsgs, createMessage(*o, reqBz))
 	return nil
 }
@@ -152,34 +158,27 @@ func (am *Machine) handleStateDkgCommitsAwaitConfirmations(o *client.Operation)
 // returns a private deal for every participant.
 // Each deal is encrypted with a participant's public key which received on the previous step
 func (am *Machine) handleStateDkgDealsAwaitConfirmations(o *client.Operation) error {
-	var (
-		payload responses.DKGProposalCommitParticipantResponse
-		err     error
-	)
-
 	dkgInstance, ok := am.dkgInstances[o.DKGIdentifier]
 	if !ok {
 		return fmt.Errorf("dkg instance with identifier %s does not exist", o.DKGIdentifier)
 	}
 
+	return am.sendDeals(o, dkgInstance, dkg_proposal_fsm.EventDKGDealConfirmationReceived)
+}
+
+// sendDeals stores the commits of the payload in the DKG instance and returns its encrypted deals
+func (am *Machine) sendDeals(o *client.Operation, dkgInstance *dkg.DKG, event fsm.Event) error {
+	var (
+		payload responses.DKGProposalCommitParticipantResponse
+		err     error
+	)
+
 	if err = json.Unmarshal(o.Payload, &payload); err != nil {
 		return fmt.Errorf("failed to unmarshal payload: %w", err)
 	}
 
-	for _, entry := range payload {
-		var commitsBz [][]byte
-		if err = json.Unmarshal(entry.DkgCommit, &commitsBz); err != nil {
-			return fmt.Errorf("failed to unmarshal commits: %w", err)
-		}
-		dkgCommits := make([]kyber.Point, 0, len(commitsBz))
-		for _, commitBz := range commitsBz {
-			commit := am.baseSuite.Point()
-			if err = commit.UnmarshalBinary(commitBz); err != nil {
-				return fmt.Errorf("failed to unmarshal commit: %w", err)
-			}
-			dkgCommits = append(dkgCommits, commit)
-		}
-		dkgInstance.StoreCommits(entry.Username, dkgCommits)
+	if err = am.storeCommits(dkgInstance, payload); err != nil {
+		return err
 	}
 
 	deals, err := dkgInstance.GetDeals()
@@ -187,8 +186,6 @@ func (am *Machine) handleStateDkgDealsAwaitConfirmations(o *client.Operation) er
 		return fmt.Errorf("failed to get deals: %w", err)
 	}
 
-	am.dkgInstances[o.DKGIdentifier] = dkgInstance
-
 	// deals variable is a map, so every key is an index of participant we should send a deal
 	for index, deal := range deals {
 		dealBz, err := json.Marshal(deal)
@@ -196,7 +193,7 @@ func (am *Machine) handleStateDkgDealsAwaitConfirmations(o *client.Operation) er
 			return fmt.Errorf("failed to marshal deal: %w", err)
 		}
 		toParticipant := dkgInstance.GetParticipantByIndex(index)
-		encryptedDeal, err := am.encryptDataForParticipant(o.DKGIdentifier, toParticipant, dealBz)
+		encryptedDeal, err := am.encryptDataForParticipant(dkgInstance, toParticipant, dealBz)
 		if err != nil {
 			return fmt.Errorf("failed to encrypt deal: %w", err)
 		}
@@ -210,50 +207,89 @@ func (am *Machine) handleStateDkgDealsAwaitConfirmations(o *client.Operation) er
 		if err != nil {
 			return fmt.Errorf("failed to generate fsm request: %w", err)
 		}
-		o.Event = dkg_proposal_fsm.EventDKGDealConfirmationReceived
+		o.Event = event
 		o.ResultMsgs = append(o.ResultMsgs, createMessage(*o, reqBz))
 	}
 
 	//a dirty but simple hack to inform FSM we are done with deals step
 	//the hack prevents a bug when FSM switches state to responses step with unfinished deals
+	return am.sendDealMarker(o, dkgInstance, dkgInstance.GetParticipantByIndex(dkgInstance.ParticipantID), event)
+}
+
+// sendDealMarker returns a deal without data to the participant to confirm the deals step in its FSM
+func (am *Machine) sendDealMarker(o *client.Operation, dkgInstance *dkg.DKG, toParticipant string, event fsm.Event) error {
 	req := requests.DKGProposalDealConfirmationRequest{
 		ParticipantId: dkgInstance.ParticipantID,
 		Deal:          []byte("self-confirm"),
 		CreatedAt:     o.CreatedAt,
 	}
-	o.To = dkgInstance.GetParticipantByIndex(dkgInstance.ParticipantID)
+	o.To = toParticipant
 	reqBz, err := json.Marshal(req)
 	if err != nil {
 		return fmt.Errorf("failed to generate fsm request: %w", err)
 	}
-	o.Event = dkg_proposal_fsm.EventDKGDealConfirmationReceived
+	o.Event = event
 	o.ResultMsgs = append(o.ResultMsgs, createMessage(*o, reqBz))
 	return nil
 }
 
+// storeCommits stores the commits of the participants in the DKG instance, participants without commits are skipped
+func (am *Machine) storeCommits(dkgInstance *dkg.DKG, payload responses.DKGProposalCommitParticipantResponse) error {
+	for _, entry := range payload {
+		var commitsBz [][]byte
+		if err := json.Unmarshal(entry.DkgCommit, &commitsBz); err != nil {
+			return fmt.Errorf("failed to unmarshal commits: %w", err)
+		}
+		if len(commitsBz) == 0 {
+			continue
+		}
+		dkgCommits := make([]kyber.Point, 0, len(commitsBz))
+		for _, commitBz := range commitsBz {
+			commit := am.baseSuite.Point()
+			if err := commit.UnmarshalBinary(commitBz); err != nil {
+				return fmt.Errorf("failed to unmarshal commit: %w", err)
+			}
+			dkgCommits = append(dkgCommits, commit)
+		}
+		dkgInstance.StoreCommits(entry.Username, dkgCommits)
+	}
+	return nil
+}
+
 // handleStateDkgResponsesAwaitConfirmations takes deals sent to us as payload, decrypt and process them and
 // returns responses to broadcast
 func (am *Machine) handleStateDkgResponsesAwaitConfirmations(o *client.Operation) error {
-	var (
-		payload responses.DKGProposalDealParticipantResponse
-		err     error
-	)
-
 	dkgInstance, ok := am.dkgInstances[o.DKGIdentifier]
 	if !ok {
 		return fmt.Errorf("dkg instance with identifier %s does not exist", o.DKGIdentifier)
 	}
 
+	return am.sendResponses(o, dkgInstance, dkg_proposal_fsm.EventDKGResponseConfirmationReceived)
+}
+
+// sendResponses processes the deals of the payload sent to us and returns the responses to broadcast
+func (am *Machine) sendResponses(o *client.Operation, dkgInstance *dkg.DKG, event fsm.Event) error {
+	var (
+		payload responses.DKGProposalDealParticipantResponse
+		err     error
+	)
+
 	if err = json.Unmarshal(o.Payload, &payload); err != nil {
 		return fmt.Errorf("failed to unmarshal payload: %w", err)
 	}
 
+	return am.processDeals(o, dkgInstance, payload, event)
+}
+
+// processDeals processes the deals sent to us and returns the responses to broadcast
+func (am *Machine) processDeals(o *client.Operation, dkgInstance *dkg.DKG, payload responses.DKGProposalDealParticipantResponse,
+	event fsm.Event) error {
 	for _, entry := range payload {
-		//do not store deals from ourselves because of the hack above
+		//do not store deals from ourselves because of the self-confirm hack of sendDeals
 		if entry.ParticipantId == dkgInstance.ParticipantID {
 			continue
 		}
-		decryptedDealBz, err := am.decryptDataFromParticipant(entry.DkgDeal)
+		decryptedDealBz, err := am.decryptDataFromParticipant(dkgInstance, entry.DkgDeal)
 		if err != nil {
 			return fmt.Errorf("failed to decrypt deal: %w", err)
 		}
@@ -269,8 +305,6 @@ func (am *Machine) handleStateDkgResponsesAwaitConfirmations(o *client.Operation
 		return fmt.Errorf("failed to process deals: %w", err)
 	}
 
-	am.dkgInstances[o.DKGIdentifier] = dkgInstance
-
 	responsesBz, err := json.Marshal(processedResponses)
 	if err != nil {
 		return fmt.Errorf("failed to marshal deals")
@@ -287,7 +321,7 @@ func (am *Machine) handleStateDkgResponsesAwaitConfirmations(o *client.Operation
 		return fmt.Errorf("failed to generate fsm request: %w", err)
 	}
 
-	o.Event = dkg_proposal_fsm.EventDKGResponseConfirmationReceived
+	o.Event = event
 	o.ResultMsgs = append(o.ResultMsgs, createMessage(*o, reqBz))
 	return nil
 }
@@ -295,32 +329,55 @@ func (am *Machine) handleStateDkgResponsesAwaitConfirmations(o *client.Operation
 // handleStateDkgMasterKeyAwaitConfirmations takes broadcasted responses from the previous step, process them,
 // reconstructs a distributed DKG public key to broadcast and saves a private part of the key
 func (am *Machine) handleStateDkgMasterKeyAwaitConfirmations(o *client.Operation) error {
-	var (
-		payload responses.DKGProposalResponseParticipantResponse
-		err     error
-	)
-
 	dkgInstance, ok := am.dkgInstances[o.DKGIdentifier]
 	if !ok {
 		return fmt.Errorf("dkg instance with identifier %s does not exist", o.DKGIdentifier)
 	}
 
+	blsKeyring, err := am.reconstructBLSKeyring(o, dkgInstance)
+	if err != nil {
+		return err
+	}
+
+	if err = am.saveBLSKeyring(o.DKGIdentifier, blsKeyring); err != nil {
+		return fmt.Errorf("failed to save BLSKeyring: %w", err)
+	}
+
+	return am.sendMasterKey(o, dkgInstance, blsKeyring, dkg_proposal_fsm.EventDKGMasterKeyConfirmationReceived)
+}
+
+// reconstructBLSKeyring processes the responses of the payload and returns the keyring of the DKG instance
+func (am *Machine) reconstructBLSKeyring(o *client.Operation, dkgInstance *dkg.DKG) (*dkg.BLSKeyring, error) {
+	var (
+		payload responses.DKGProposalResponseParticipantResponse
+		err     error
+	)
+
 	if err = json.Unmarshal(o.Payload, &payload); err != nil {
-		return fmt.Errorf("failed to unmarshal payload: %w", err)
+		return nil, fmt.Errorf("failed to unmarshal payload: %w", err)
 	}
 
 	for _, entry := range payload {
 		var entryResponses []*dkgPedersen.Response
 		if err = json.Unmarshal(entry.DkgResponse, &entryResponses); err != nil {
-			return fmt.Errorf("failed to unmarshal responses: %w", err)
+			return nil, fmt.Errorf("failed to unmarshal responses: %w", err)
 		}
 		dkgInstance.StoreResponses(entry.Username, entryResponses)
 	}
 
 	if err = dkgInstance.ProcessResponses(); err != nil {
-		return fmt.Errorf("failed to process responses: %w", err)
+		return nil, fmt.Errorf("failed to process responses: %w", err)
 	}
 
+	blsKeyring, err := dkgInstance.GetBLSKeyring()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get BLSKeyring: %w", err)
+	}
+	return blsKeyring, nil
+}
+
+// sendMasterKey returns the master public key and the public polynomial of the keyring to broadcast
+func (am *Machine) sendMasterKey(o *client.Operation, dkgInstance *dkg.DKG, blsKeyring *dkg.BLSKeyring, event fsm.Event) error {
 	pubKey, err := dkgInstance.GetDistributedPublicKey()
 	if err != nil {
 		return fmt.Errorf("failed to get master pub key: %w", err)
@@ -331,17 +388,6 @@ func (am *Machine) handleStateDkgMasterKeyAwaitConfirmations(o *client.Operation
 		return fmt.Errorf("failed to marshal master pub key: %w", err)
 	}
 
-	am.dkgInstances[o.DKGIdentifier] = dkgInstance
-
-	blsKeyring, err := dkgInstance.GetBLSKeyring()
-	if err != nil {
-		return fmt.Errorf("failed to get BLSKeyring: %w", err)
-	}
-
-	if err = am.saveBLSKeyring(o.DKGIdentifier, blsKeyring); err != nil {
-		return fmt.Errorf("failed to save BLSKeyring: %w", err)
-	}
-
 	pubPolyBz, err := blsKeyring.PubPolyBytes()
 	if err != nil {
 		return fmt.Errorf("failed to marshal BLSKeyring's PubPoly: %w", err)
@@ -357,7 +403,7 @@ func (am *Machine) handleStateDkgMasterKeyAwaitConfirmations(o *client.Operation
 		return fmt.Errorf("failed to generate fsm request: %w", err)
 	}
 
-	o.Event = dkg_proposal_fsm.EventDKGMasterKeyConfirmationReceived
+	o.Event = event
 	o.ResultMsgs = append(o.ResultMsgs, createMessage(*o, reqBz))
 
 	return nil
@@ -4,21 +4,28 @@ import (
 	"crypto/aes"
 	"crypto/cipher"
 	"crypto/rand"
+	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"math"
+	"strings"
 
+	bls12381 "github.com/corestario/kyber/pairing/bls12381"
+	"github.com/syndtr/goleveldb/leveldb"
+	"github.com/syndtr/goleveldb/leveldb/util"
 	"golang.org/x/crypto/scrypt"
 )
 
 var N = int(math.Pow(2, 16))
 
-func encrypt(key, salt, data []byte) ([]byte, error) {
-	derivedKey, err := scrypt.Key(key, salt, N, 8, 1, 32)
-	if err != nil {
-		return nil, err
-	}
+// deriveKey derives an AES key from the password, the derivation is slow on purpose
+func deriveKey(key, salt []byte) ([]byte, error) {
+	return scrypt.Key(key, salt, N, 8, 1, 32)
+}
 
+// sealData encrypts the data with a key derived by deriveKey
+func sealData(derivedKey, data []byte) ([]byte, error) {
 	c, err := aes.NewCipher(derivedKey)
 	if err != nil {
 		return nil, err
@@ -37,12 +44,8 @@ func encrypt(key, salt, data []byte) ([]byte, error) {
 	return gcm.Seal(nonce, nonce, data, nil), nil
 }
 
-func decrypt(key, salt, data []byte) ([]byte, error) {
-	derivedKey, err := scrypt.Key(key, salt, N, 8, 1, 32)
-	if err != nil {
-		return nil, err
-	}
-
+// openData decrypts the data encrypted by sealData
+func openData(derivedKey, data []byte) ([]byte, error) {
 	c, err := aes.NewCipher(derivedKey)
 	if err != nil {
 		return nil, err
@@ -66,3 +69,186 @@ func decrypt(key, salt, data []byte) ([]byte, error) {
 
 	return decryptedData, nil
 }
+
+// getDerivedKey returns the key derived from the encryption key to encrypt sensitive data, the salt is generated
+// if there is no sensitive data yet
+func (am *Machine) getDerivedKey() ([]byte, error) {
+	if len(am.encryptionKey) == 0 {
+		return nil, fmt.Errorf("encryption key is not set")
+	}
+	if am.derivedKey != nil {
+		return am.derivedKey, nil
+	}
+
+	salt, err := am.db.Get([]byte(saltDBKey), nil)
+	if errors.Is(err, leveldb.ErrNotFound) {
+		salt = make([]byte, 32)
+		if _, err = rand.Read(salt); err != nil {
+			return nil, fmt.Errorf("failed to generate salt: %w", err)
+		}
+		if err = am.db.Put([]byte(saltDBKey), salt, nil); err != nil {
+			return nil, fmt.Errorf("failed to put salt into db: %w", err)
+		}
+	} else if err != nil {
+		return nil, fmt.Errorf("failed to read salt from db: %w", err)
+	}
+
+	derivedKey, err := deriveKey(am.encryptionKey, salt)
+	if err != nil {
+		return nil, fmt.Errorf("failed to derive key: %w", err)
+	}
+	am.derivedKey = derivedKey
+	return derivedKey, nil
+}
+
+// encryptSensitiveData encrypts the data with the encryption key of the machine
+func (am *Machine) encryptSensitiveData(data []byte) ([]byte, error) {
+	derivedKey, err := am.getDerivedKey()
+	if err != nil {
+		return nil, err
+	}
+	return sealData(derivedKey, data)
+}
+
+// decryptSensitiveData decrypts the data encrypted with encryptSensitiveData
+func (am *Machine) decryptSensitiveData(data []byte) ([]byte, error) {
+	derivedKey, err := am.getDerivedKey()
+	if err != nil {
+		return nil, err
+	}
+	return openData(derivedKey, data)
+}
+
+// isEncryptedDBKey returns true if the record is encrypted with the encryption key of the machine
+func isEncryptedDBKey(key string) bool {
+	switch key {
+	case pubKeyDBKey, privateKeyDBKey, encryptedBaseSeedKey:
+		return true
+	}
+	if strings.HasPrefix(key, pendingRoundKeyPrefix+"/") {
+		return true
+	}
+	if !strings.HasPrefix(key, roundPrefix+"/") {
+		return false
+	}
+	_, name, ok := parseRoundDBKey(key)
+	if !ok {
+		return false
+	}
+	switch name {
+	case roundPrivateKeyName, blsKeyringPrefix, refreshedBLSKeyringPrefix:
+		return true
+	}
+	return strings.HasPrefix(name, roundOperationPrefix+"/")
+}
+
+// encryptPlaintextData puts the base seed and the operations stored in plaintext by previous versions encrypted with
+// the encryption key to the batch. The encryption key is checked against the DKG keys of the machine or the
+// encrypted seed
+func (am *Machine) encryptPlaintextData(batch *leveldb.Batch) error {
+	seed, err := am.db.Get([]byte(baseSeedKey), nil)
+	switch {
+	case err == nil:
+		am.baseSeed = seed
+		am.baseSuite = bls12381.NewBLS12381Suite(am.baseSeed)
+		if err = am.LoadKeysFromDB(); err != nil && !errors.Is(err, leveldb.ErrNotFound) {
+			return fmt.Errorf("failed to load keys with the encryption key: %w", err)
+		}
+
+		encryptedSeed, err := am.encryptSensitiveData(seed)
+		if err != nil {
+			return fmt.Errorf("failed to encrypt baseSeed: %w", err)
+		}
+		batch.Put([]byte(encryptedBaseSeedKey), encryptedSeed)
+		batch.Delete([]byte(baseSeedKey))
+	case errors.Is(err, leveldb.ErrNotFound):
+		if _, err = am.getBaseSeed(); err != nil && !errors.Is(err, leveldb.ErrNotFound) {
+			return fmt.Errorf("failed to check the encryption key: %w", err)
+		}
+	default:
+		return fmt.Errorf("failed to get baseSeed: %w", err)
+	}
+
+	operations := 0
+	iter := am.db.NewIterator(util.BytesPrefix([]byte(roundPrefix+"/")), nil)
+	for iter.Next() {
+		_, name, ok := parseRoundDBKey(string(iter.Key()))
+		if !ok || !strings.HasPrefix(name, roundOperationPrefix+"/") {
+			continue
+		}
+		// an encrypted operation is not a valid JSON
+		if !json.Valid(iter.Value()) {
+			continue
+		}
+		encryptedOperation, err := am.encryptSensitiveData(iter.Value())
+		if err != nil {
+			iter.Release()
+			return fmt.Errorf("failed to encrypt operation: %w", err)
+		}
+		batch.Put(append([]byte{}, iter.Key()...), encryptedOperation)
+		operations++
+	}
+	iter.Release()
+	if err = iter.Error(); err != nil {
+		return fmt.Errorf("failed to iterate operations: %w", err)
+	}
+
+	if operations > 0 {
+		am.logger.Infof("Encrypting %d operations stored in plaintext", operations)
+	}
+	return nil
+}
+
+// ChangeEncryptionKey re-encrypts all sensitive data with the new encryption key and a new salt. The data is
+// re-encrypted atomically, so the storage is either left with the old key or with the new one
+func (am *Machine) ChangeEncryptionKey(newKey []byte) error {
+	if len(newKey) == 0 {
+		return fmt.Errorf("empty encryption key")
+	}
+	derivedKey, err := am.getDerivedKey()
+	if err != nil {
+		return err
+	}
+
+	newSalt := make([]byte, 32)
+	if _, err = rand.Read(newSalt); err != nil {
+		return fmt.Errorf("failed to generate salt: %w", err)
+	}
+	newDerivedKey, err := deriveKey(newKey, newSalt)
+	if err != nil {
+		return fmt.Errorf("failed to derive key: %w", err)
+	}
+
+	batch := new(leveldb.Batch)
+	iter := am.db.NewIterator(nil, nil)
+	for iter.Next() {
+		key := string(iter.Key())
+		if !isEncryptedDBKey(key) {
+			continue
+		}
+		data, err := openData(derivedKey, iter.Value())
+		if err != nil {
+			iter.Release()
+			return fmt.Errorf("failed to decrypt %s: %w", key, err)
+		}
+		if data, err = sealData(newDerivedKey, data); err != nil {
+			iter.Release()
+			return fmt.Errorf("failed to encrypt %s: %w", key, err)
+		}
+		batch.Put([]byte(key), data)
+	}
+	iter.Release()
+	if err = iter.Error(); err != nil {
+		return fmt.Errorf("failed to iterate db: %w", err)
+	}
+	batch.Put([]byte(saltDBKey), newSalt)
+
+	if err = am.db.Write(batch, nil); err != nil {
+		return fmt.Errorf("failed to write re-encrypted data: %w", err)
+	}
+
+	am.encryptionKey = newKey
+	am.derivedKey = newDerivedKey
+	am.logger.Infof("Sensitive data is re-encrypted with the new encryption key")
+	return nil
+}
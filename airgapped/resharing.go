@@ -0,0 +1,174 @@
+package airgapped
+
+import (
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"sort"
+
+	bls "github.com/corestario/kyber/pairing/bls12381"
+
+	client "github.com/lidofinance/dc4bc/client/types"
+	"github.com/lidofinance/dc4bc/dkg"
+	"github.com/lidofinance/dc4bc/fsm/state_machines/resharing_fsm"
+	"github.com/lidofinance/dc4bc/fsm/types/responses"
+)
+
+// handleStateResharingCommitsAwaitConfirmations takes the old and the new participants DKG pub keys and the old
+// keyring to reshare as payload and returns commits of a resharing of our old share to broadcast. Commits are empty
+// if we are not a dealer
+func (am *Machine) handleStateResharingCommitsAwaitConfirmations(o *client.Operation) error {
+	var (
+		payload responses.ResharingInvitationResponse
+		err     error
+	)
+
+	if err = json.Unmarshal(o.Payload, &payload); err != nil {
+		return fmt.Errorf("failed to unmarshal payload: %w", err)
+	}
+	if len(payload.Participants) == 0 || len(payload.OldParticipants) == 0 {
+		return fmt.Errorf("empty list of participants for resharing of DKG #%s", payload.OldDkgID)
+	}
+
+	oldPubPoly, err := dkg.LoadPubPolyBLSKeyringFromBytes(am.baseSuite, payload.OldPubPolyBz)
+	if err != nil {
+		return fmt.Errorf("failed to load public polynomial of DKG #%s: %w", payload.OldDkgID, err)
+	}
+	_, publicCoeffs := oldPubPoly.PubPoly.Info()
+
+	var (
+		resharingSeed = sha256.Sum256(append([]byte(o.DKGIdentifier), am.baseSeed...))
+		suite         = bls.NewBLS12381Suite(resharingSeed[:])
+	)
+	participantID, err := am.bindRoundKeys(o.DKGIdentifier, payload.Participants)
+	if err != nil {
+		return err
+	}
+	pubKey, secKey, err := am.getRoundKeys(o.DKGIdentifier)
+	if err != nil {
+		return err
+	}
+	resharingInstance := dkg.Init(suite, pubKey, secKey)
+	resharingInstance.Threshold = payload.Threshold
+	resharingInstance.N = len(payload.Participants)
+
+	if err = am.storePubKeys(resharingInstance, payload.Participants); err != nil {
+		return err
+	}
+	for _, entry := range payload.OldParticipants {
+		pubKey := am.baseSuite.Point()
+		if err = pubKey.UnmarshalBinary(entry.DkgPubKey); err != nil {
+			return fmt.Errorf("failed to unmarshal pubkey: %w", err)
+		}
+		resharingInstance.StoreOldPubKey(entry.Username, entry.ParticipantId, pubKey)
+	}
+
+	dealers := make([]int, 0, len(payload.Dealers))
+	for _, oldParticipantID := range payload.Dealers {
+		dealers = append(dealers, oldParticipantID)
+	}
+	sort.Ints(dealers)
+
+	var blsKeyring *dkg.BLSKeyring
+	if _, ok := payload.Dealers[participantID]; ok {
+		if err = am.activateBLSKeyring(payload.OldDkgID, payload.OldKeyringVersion); err != nil {
+			return fmt.Errorf("failed to activate BLSKeyring: %w", err)
+		}
+		if blsKeyring, err = am.loadBLSKeyring(payload.OldDkgID); err != nil {
+			return fmt.Errorf("failed to load BLSKeyring: %w", err)
+		}
+		if !blsKeyring.PubPoly.Commit().Equal(oldPubPoly.PubPoly.Commit()) {
+			return fmt.Errorf("master key of DKG #%s does not match the reshared one", payload.OldDkgID)
+		}
+		_, keyringCoeffs := blsKeyring.PubPoly.Info()
+		if len(keyringCoeffs) != len(publicCoeffs) {
+			return fmt.Errorf("public polynomial of DKG #%s does not match the reshared one", payload.OldDkgID)
+		}
+		for i := range keyringCoeffs {
+			if !keyringCoeffs[i].Equal(publicCoeffs[i]) {
+				return fmt.Errorf("public polynomial of DKG #%s does not match the reshared one", payload.OldDkgID)
+			}
+		}
+	}
+
+	if err = resharingInstance.InitResharingInstance(resharingSeed[:], blsKeyring, payload.Receivers,
+		payload.OldThreshold, publicCoeffs, dealers); err != nil {
+		return fmt.Errorf("failed to init resharing instance: %w", err)
+	}
+
+	am.dkgInstances[o.DKGIdentifier] = resharingInstance
+
+	return am.sendCommits(o, resharingInstance, resharing_fsm.EventResharingCommitConfirmationReceived)
+}
+
+// handleStateResharingDealsAwaitConfirmations takes broadcasted participants commits as payload and returns
+// encrypted deals of our old share for every new participant. The participants receiving no deals are informed
+// we are done with the deals step
+func (am *Machine) handleStateResharingDealsAwaitConfirmations(o *client.Operation) error {
+	resharingInstance, ok := am.dkgInstances[o.DKGIdentifier]
+	if !ok {
+		return fmt.Errorf("resharing instance with identifier %s does not exist", o.DKGIdentifier)
+	}
+
+	event := resharing_fsm.EventResharingDealConfirmationReceived
+
+	if err := am.sendDeals(o, resharingInstance, event); err != nil {
+		return err
+	}
+
+	for index := resharingInstance.GetReceivers(); index < resharingInstance.N; index++ {
+		if index == resharingInstance.ParticipantID {
+			continue
+		}
+		toParticipant := resharingInstance.GetParticipantByIndex(index)
+		if err := am.sendDealMarker(o, resharingInstance, toParticipant, event); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// handleStateResharingResponsesAwaitConfirmations takes the commits of the dealers and the deals sent to us as payload,
+// decrypt and process them and returns responses to broadcast
+func (am *Machine) handleStateResharingResponsesAwaitConfirmations(o *client.Operation) error {
+	var (
+		payload responses.ResharingDealsParticipantResponse
+		err     error
+	)
+
+	resharingInstance, ok := am.dkgInstances[o.DKGIdentifier]
+	if !ok {
+		return fmt.Errorf("resharing instance with identifier %s does not exist", o.DKGIdentifier)
+	}
+
+	if err = json.Unmarshal(o.Payload, &payload); err != nil {
+		return fmt.Errorf("failed to unmarshal payload: %w", err)
+	}
+
+	// we get no commits at the deals step if we are not a dealer
+	if err = am.storeCommits(resharingInstance, payload.Commits); err != nil {
+		return err
+	}
+
+	return am.processDeals(o, resharingInstance, payload.Deals, resharing_fsm.EventResharingResponseConfirmationReceived)
+}
+
+// handleStateResharingKeyringAwaitConfirmations takes broadcasted responses from the previous step, process them
+// and saves the keyring of the new committee. The master key of the keyring must be the master key of the old round
+func (am *Machine) handleStateResharingKeyringAwaitConfirmations(o *client.Operation) error {
+	resharingInstance, ok := am.dkgInstances[o.DKGIdentifier]
+	if !ok {
+		return fmt.Errorf("resharing instance with identifier %s does not exist", o.DKGIdentifier)
+	}
+
+	blsKeyring, err := am.reconstructBLSKeyring(o, resharingInstance)
+	if err != nil {
+		return err
+	}
+
+	if err = am.saveBLSKeyring(o.DKGIdentifier, blsKeyring); err != nil {
+		return fmt.Errorf("failed to save BLSKeyring: %w", err)
+	}
+
+	return am.sendMasterKey(o, resharingInstance, blsKeyring, resharing_fsm.EventResharingKeyringConfirmationReceived)
+}
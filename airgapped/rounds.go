@@ -0,0 +1,445 @@
+package airgapped
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/corestario/kyber"
+	bls "github.com/corestario/kyber/pairing/bls12381"
+	"github.com/syndtr/goleveldb/leveldb"
+	"github.com/syndtr/goleveldb/leveldb/util"
+
+	"github.com/lidofinance/dc4bc/fsm/types/responses"
+)
+
+const (
+	// records of a DKG round are stored under "round/<dkg identifier>/<record name>"
+	roundPrefix = "round"
+	// our DKG key pair in the round, the private key is encrypted
+	roundPubKeyName     = "public_key"
+	roundPrivateKeyName = "private_key"
+	// the operations of the round to replay are stored as "operation/<sequence number>", the head of the log keeps
+	// the number of the operations and their digest
+	roundOperationPrefix    = "operation"
+	roundOperationsHeadName = "operations_head"
+	// the operation log of the round stored as one record by previous versions, see migrateOperationsLogs
+	roundOperationsLogName = "operations_log"
+	// the round refuses new operations when it is archived
+	roundArchivedName = "archived"
+	// DKG key pairs generated for rounds which are not started yet, keyed by the hex of the public key
+	pendingRoundKeyPrefix = "pending_round_key"
+	// the number of generated round key pairs, the n-th key pair is derived from the base seed and n
+	roundKeysCountDBKey = "round_keys_count"
+
+	roundExportFilename = "dc4bc_round_export.json"
+)
+
+func makeRoundDBPrefix(dkgID string) string {
+	return fmt.Sprintf("%s/%s/", roundPrefix, dkgID)
+}
+
+func makeRoundDBKey(dkgID, name string) string {
+	return makeRoundDBPrefix(dkgID) + name
+}
+
+func makeOperationDBKey(dkgID string, seq int) string {
+	return makeRoundDBKey(dkgID, fmt.Sprintf("%s/%020d", roundOperationPrefix, seq))
+}
+
+func makePendingRoundKeyDBKey(pubKeyBz []byte) string {
+	return fmt.Sprintf("%s/%s", pendingRoundKeyPrefix, hex.EncodeToString(pubKeyBz))
+}
+
+// RoundInfo describes a DKG round stored on the machine
+type RoundInfo struct {
+	DKGIdentifier string
+	// our DKG pub key in the round
+	PubKey     []byte
+	Operations int
+	// the round has a keyring
+	Finished       bool
+	KeyringVersion int
+	Archived       bool
+}
+
+// RoundExport holds all records of a round, the sensitive ones are encrypted with the salt
+type RoundExport struct {
+	DKGIdentifier string            `json:"dkg_identifier"`
+	Salt          []byte            `json:"salt"`
+	Records       map[string][]byte `json:"records"`
+}
+
+// GenerateRoundKeys generates a DKG key pair to take part in a new round. The round is bound to the key pair when
+// the pub key is found in the list of the round participants, so every round can have its own key pair
+func (am *Machine) GenerateRoundKeys() (kyber.Point, error) {
+	count, err := am.getKeyringVersion(roundKeysCountDBKey)
+	if err != nil && !errors.Is(err, leveldb.ErrNotFound) {
+		return nil, fmt.Errorf("failed to get round keys count: %w", err)
+	}
+
+	// the key pair is derived from the base seed, so it can be restored with the mnemonic
+	var (
+		keySeed = sha256.Sum256(append([]byte(fmt.Sprintf("round_key_%d", count)), am.baseSeed...))
+		suite   = bls.NewBLS12381Suite(keySeed[:])
+	)
+	secKey := suite.Scalar().Pick(suite.RandomStream())
+	pubKey := suite.Point().Mul(secKey, nil)
+
+	pubKeyBz, err := pubKey.MarshalBinary()
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal pub key: %w", err)
+	}
+	secKeyBz, err := secKey.MarshalBinary()
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal private key: %w", err)
+	}
+	encryptedSecKey, err := am.encryptSensitiveData(secKeyBz)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encrypt private key: %w", err)
+	}
+
+	batch := new(leveldb.Batch)
+	batch.Put([]byte(makePendingRoundKeyDBKey(pubKeyBz)), encryptedSecKey)
+	batch.Put([]byte(roundKeysCountDBKey), []byte(strconv.Itoa(count+1)))
+	if err = am.db.Write(batch, nil); err != nil {
+		return nil, fmt.Errorf("failed to save round keys: %w", err)
+	}
+	return pubKey, nil
+}
+
+// bindRoundKeys binds the round to the one of our DKG key pairs which is in the list of participants and returns
+// our participant id. Key pairs generated for new rounds are tried first, then the ones of other rounds, e.g. of
+// the round being reshared, and the default key pair of the machine
+func (am *Machine) bindRoundKeys(dkgID string, participants responses.DKGProposalPubKeysParticipantResponse) (int, error) {
+	if err := validateDKGIdentifier(dkgID); err != nil {
+		return -1, err
+	}
+
+	participantID := func(pubKeyBz []byte) int {
+		for _, entry := range participants {
+			if bytes.Equal(entry.DkgPubKey, pubKeyBz) {
+				return entry.ParticipantId
+			}
+		}
+		return -1
+	}
+
+	// the round is bound already if the operation is replayed
+	pubKeyBz, err := am.db.Get([]byte(makeRoundDBKey(dkgID, roundPubKeyName)), nil)
+	if err == nil {
+		pid := participantID(pubKeyBz)
+		if pid < 0 {
+			return -1, fmt.Errorf("the DKG pub key of round %s is not in the list of participants", dkgID)
+		}
+		return pid, nil
+	}
+	if !errors.Is(err, leveldb.ErrNotFound) {
+		return -1, fmt.Errorf("failed to get round pub key: %w", err)
+	}
+
+	var pendingKey, encryptedSecKey []byte
+	iter := am.db.NewIterator(util.BytesPrefix([]byte(pendingRoundKeyPrefix+"/")), nil)
+	for pendingKey == nil && iter.Next() {
+		pubKeyBz, err = hex.DecodeString(strings.TrimPrefix(string(iter.Key()), pendingRoundKeyPrefix+"/"))
+		if err == nil && participantID(pubKeyBz) >= 0 {
+			pendingKey = append([]byte{}, iter.Key()...)
+			encryptedSecKey = append([]byte{}, iter.Value()...)
+		}
+	}
+	iter.Release()
+	if err = iter.Error(); err != nil {
+		return -1, fmt.Errorf("failed to iterate pending round keys: %w", err)
+	}
+
+	if pendingKey == nil {
+		rounds, err := am.ListRounds()
+		if err != nil {
+			return -1, err
+		}
+		for _, round := range rounds {
+			if round.PubKey == nil || participantID(round.PubKey) < 0 {
+				continue
+			}
+			if encryptedSecKey, err = am.db.Get([]byte(makeRoundDBKey(round.DKGIdentifier, roundPrivateKeyName)), nil); err != nil {
+				return -1, fmt.Errorf("failed to get private key of round %s: %w", round.DKGIdentifier, err)
+			}
+			pubKeyBz = round.PubKey
+			break
+		}
+	}
+
+	if encryptedSecKey == nil && am.pubKey != nil {
+		if pubKeyBz, err = am.pubKey.MarshalBinary(); err != nil {
+			return -1, fmt.Errorf("failed to marshal pub key: %w", err)
+		}
+		if participantID(pubKeyBz) >= 0 {
+			if encryptedSecKey, err = am.db.Get([]byte(privateKeyDBKey), nil); err != nil {
+				return -1, fmt.Errorf("failed to get private key from db: %w", err)
+			}
+		}
+	}
+
+	if encryptedSecKey == nil {
+		return -1, fmt.Errorf("failed to determine participant id for DKG #%s", dkgID)
+	}
+
+	batch := new(leveldb.Batch)
+	batch.Put([]byte(makeRoundDBKey(dkgID, roundPubKeyName)), pubKeyBz)
+	batch.Put([]byte(makeRoundDBKey(dkgID, roundPrivateKeyName)), encryptedSecKey)
+	if pendingKey != nil {
+		batch.Delete(pendingKey)
+	}
+	if err = am.db.Write(batch, nil); err != nil {
+		return -1, fmt.Errorf("failed to bind keys to round %s: %w", dkgID, err)
+	}
+	return participantID(pubKeyBz), nil
+}
+
+// getRoundKeys returns our DKG key pair in the round. A round which is not bound to a key pair, e.g. a round
+// migrated from the storage without round namespaces, uses the default key pair of the machine
+func (am *Machine) getRoundKeys(dkgID string) (kyber.Point, kyber.Scalar, error) {
+	pubKeyBz, err := am.db.Get([]byte(makeRoundDBKey(dkgID, roundPubKeyName)), nil)
+	if errors.Is(err, leveldb.ErrNotFound) && am.pubKey != nil {
+		return am.pubKey, am.secKey, nil
+	}
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to get pub key of round %s: %w", dkgID, err)
+	}
+	encryptedSecKey, err := am.db.Get([]byte(makeRoundDBKey(dkgID, roundPrivateKeyName)), nil)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to get private key of round %s: %w", dkgID, err)
+	}
+	secKeyBz, err := am.decryptSensitiveData(encryptedSecKey)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to decrypt private key of round %s: %w", dkgID, err)
+	}
+
+	pubKey := am.baseSuite.Point()
+	if err = pubKey.UnmarshalBinary(pubKeyBz); err != nil {
+		return nil, nil, fmt.Errorf("failed to unmarshal public key: %w", err)
+	}
+	secKey := am.baseSuite.Scalar()
+	if err = secKey.UnmarshalBinary(secKeyBz); err != nil {
+		return nil, nil, fmt.Errorf("failed to unmarshal private key: %w", err)
+	}
+	return pubKey, secKey, nil
+}
+
+// ListRounds returns the DKG rounds stored on the machine sorted by identifier
+func (am *Machine) ListRounds() ([]RoundInfo, error) {
+	rounds := make(map[string]*RoundInfo)
+	iter := am.db.NewIterator(util.BytesPrefix([]byte(roundPrefix+"/")), nil)
+	defer iter.Release()
+
+	for iter.Next() {
+		dkgID, name, ok := parseRoundDBKey(string(iter.Key()))
+		if !ok {
+			continue
+		}
+		round, ok := rounds[dkgID]
+		if !ok {
+			round = &RoundInfo{DKGIdentifier: dkgID}
+			rounds[dkgID] = round
+		}
+		switch name {
+		case roundPubKeyName:
+			round.PubKey = append([]byte{}, iter.Value()...)
+		case roundOperationsHeadName:
+			var head operationsLogHead
+			if err := json.Unmarshal(iter.Value(), &head); err != nil {
+				return nil, fmt.Errorf("failed to unmarshal operation log head of round %s: %w", dkgID, err)
+			}
+			round.Operations = head.Count
+		case blsKeyringPrefix:
+			round.Finished = true
+		case keyringVersionPrefix:
+			version, err := strconv.Atoi(string(iter.Value()))
+			if err != nil {
+				return nil, fmt.Errorf("failed to parse keyring version of round %s: %w", dkgID, err)
+			}
+			round.KeyringVersion = version
+		case roundArchivedName:
+			round.Archived = true
+		}
+	}
+	if err := iter.Error(); err != nil {
+		return nil, fmt.Errorf("failed to iterate rounds: %w", err)
+	}
+
+	list := make([]RoundInfo, 0, len(rounds))
+	for _, round := range rounds {
+		list = append(list, *round)
+	}
+	sort.Slice(list, func(i, j int) bool {
+		return list[i].DKGIdentifier < list[j].DKGIdentifier
+	})
+	return list, nil
+}
+
+// isRoundArchived returns true if the round is archived
+func (am *Machine) isRoundArchived(dkgID string) (bool, error) {
+	has, err := am.db.Has([]byte(makeRoundDBKey(dkgID, roundArchivedName)), nil)
+	if err != nil {
+		return false, fmt.Errorf("failed to check round %s: %w", dkgID, err)
+	}
+	return has, nil
+}
+
+// ArchiveRound archives the round, the records of the round are kept, but the round refuses new operations and
+// its keyring is not listed among finished rounds
+func (am *Machine) ArchiveRound(dkgID string) error {
+	if _, err := am.getRoundRecords(dkgID); err != nil {
+		return err
+	}
+	if err := am.db.Put([]byte(makeRoundDBKey(dkgID, roundArchivedName)), []byte{}, nil); err != nil {
+		return fmt.Errorf("failed to archive round %s: %w", dkgID, err)
+	}
+	delete(am.dkgInstances, dkgID)
+	delete(am.refreshInstances, dkgID)
+
+	am.logger.Infof("Round %s is archived", dkgID)
+	return nil
+}
+
+// ExportRound saves all records of the round to a JSON file in the result folder of the round. The records are
+// exported as they are stored, so the keyring and the private key are encrypted with the password of the machine
+func (am *Machine) ExportRound(dkgID string) (string, error) {
+	records, err := am.getRoundRecords(dkgID)
+	if err != nil {
+		return "", err
+	}
+	salt, err := am.db.Get([]byte(saltDBKey), nil)
+	if err != nil {
+		return "", fmt.Errorf("failed to read salt from db: %w", err)
+	}
+
+	exportBz, err := json.Marshal(RoundExport{
+		DKGIdentifier: dkgID,
+		Salt:          salt,
+		Records:       records,
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal round export: %w", err)
+	}
+
+	folder, err := am.roundResultFolder(dkgID)
+	if err != nil {
+		return "", err
+	}
+	path := filepath.Join(folder, roundExportFilename)
+	if err = os.WriteFile(path, exportBz, 0600); err != nil {
+		return "", fmt.Errorf("failed to write file: %w", err)
+	}
+	return path, nil
+}
+
+// getRoundRecords returns the records of the round by name
+func (am *Machine) getRoundRecords(dkgID string) (map[string][]byte, error) {
+	if err := validateDKGIdentifier(dkgID); err != nil {
+		return nil, err
+	}
+
+	records := make(map[string][]byte)
+	iter := am.db.NewIterator(util.BytesPrefix([]byte(makeRoundDBPrefix(dkgID))), nil)
+	defer iter.Release()
+
+	for iter.Next() {
+		name := strings.TrimPrefix(string(iter.Key()), makeRoundDBPrefix(dkgID))
+		records[name] = append([]byte{}, iter.Value()...)
+	}
+	if err := iter.Error(); err != nil {
+		return nil, fmt.Errorf("failed to iterate records of round %s: %w", dkgID, err)
+	}
+	if len(records) == 0 {
+		return nil, fmt.Errorf("round %s not found", dkgID)
+	}
+	return records, nil
+}
+
+// roundResultFolder returns the folder for result files of the round, the folder is created if needed
+func (am *Machine) roundResultFolder(dkgID string) (string, error) {
+	if err := validateDKGIdentifier(dkgID); err != nil {
+		return "", err
+	}
+	folder := filepath.Join(am.ResultFolder, dkgID)
+	if err := os.MkdirAll(folder, 0700); err != nil {
+		return "", fmt.Errorf("failed to create result folder of round %s: %w", dkgID, err)
+	}
+	return folder, nil
+}
+
+// parseRoundDBKey splits a key of a round record into the round identifier and the record name
+func parseRoundDBKey(key string) (string, string, bool) {
+	parts := strings.SplitN(strings.TrimPrefix(key, roundPrefix+"/"), "/", 2)
+	if len(parts) != 2 {
+		return "", "", false
+	}
+	return parts[0], parts[1], true
+}
+
+// validateDKGIdentifier checks that the identifier can be used as a namespace of round records and a folder name
+func validateDKGIdentifier(dkgID string) error {
+	if dkgID == "" || dkgID == "." || dkgID == ".." || strings.ContainsAny(dkgID, `/\`) {
+		return fmt.Errorf("invalid dkg identifier: %q", dkgID)
+	}
+	return nil
+}
+
+// migrateRounds moves the records of the rounds from the layout where all rounds share the keys of the machine and
+// one operation log into the namespaces of the rounds. The migrated rounds are not bound to key pairs, so they keep
+// using the default key pair of the machine. The moved records are put to the batch, the operations are encrypted
+func (am *Machine) migrateRounds(batch *leveldb.Batch) error {
+	legacyPrefixes := []string{
+		refreshedBLSKeyringPrefix,
+		refreshedKeyringVersionPrefix,
+		blsKeyringPrefix,
+		keyringVersionPrefix,
+	}
+
+	dkgIDs := make(map[string]bool)
+
+	iter := am.db.NewIterator(nil, nil)
+	for iter.Next() {
+		key := string(iter.Key())
+		for _, prefix := range legacyPrefixes {
+			if !strings.HasPrefix(key, prefix+"_") {
+				continue
+			}
+			dkgID := strings.TrimPrefix(key, prefix+"_")
+			batch.Put([]byte(makeRoundDBKey(dkgID, prefix)), append([]byte{}, iter.Value()...))
+			batch.Delete([]byte(key))
+			dkgIDs[dkgID] = true
+			break
+		}
+	}
+	iter.Release()
+	if err := iter.Error(); err != nil {
+		return fmt.Errorf("failed to iterate db: %w", err)
+	}
+
+	roundOperationsLog, err := am.getLegacyRoundOperationLog()
+	if err != nil && !errors.Is(err, leveldb.ErrNotFound) {
+		return err
+	}
+	for dkgID, operations := range roundOperationsLog {
+		if _, err = appendOperations(batch, dkgID, operationsLogHead{}, operations, am.encryptSensitiveData); err != nil {
+			return err
+		}
+		dkgIDs[dkgID] = true
+	}
+	batch.Delete([]byte(operationsLogDBKey))
+
+	if len(dkgIDs) > 0 {
+		am.logger.Infof("Moving %d rounds to separate storage namespaces", len(dkgIDs))
+	}
+	return nil
+}
@@ -0,0 +1,186 @@
+package airgapped
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"github.com/syndtr/goleveldb/leveldb"
+
+	"github.com/lidofinance/dc4bc/client/modules/logger"
+	client "github.com/lidofinance/dc4bc/client/types"
+	"github.com/lidofinance/dc4bc/fsm/types/responses"
+)
+
+func newTestMachine(t *testing.T, dbPath string) *Machine {
+	am, err := NewMachine(dbPath, logger.NewLogger("airgapped"))
+	require.NoError(t, err)
+	am.SetEncryptionKey([]byte("password"))
+	require.NoError(t, am.InitKeys())
+	return am
+}
+
+func TestMachine_BindRoundKeys(t *testing.T) {
+	testDir := "/tmp/dc4bc_test_round_keys"
+	defer os.RemoveAll(testDir)
+
+	am := newTestMachine(t, testDir)
+
+	roundPubKey, err := am.GenerateRoundKeys()
+	require.NoError(t, err)
+	nextRoundPubKey, err := am.GenerateRoundKeys()
+	require.NoError(t, err)
+	require.False(t, roundPubKey.Equal(nextRoundPubKey))
+
+	roundPubKeyBz, err := roundPubKey.MarshalBinary()
+	require.NoError(t, err)
+	defaultPubKeyBz, err := am.pubKey.MarshalBinary()
+	require.NoError(t, err)
+
+	pid, err := am.bindRoundKeys("round_1", responses.DKGProposalPubKeysParticipantResponse{
+		{ParticipantId: 0, DkgPubKey: []byte("someone else")},
+		{ParticipantId: 1, DkgPubKey: roundPubKeyBz},
+	})
+	require.NoError(t, err)
+	require.Equal(t, 1, pid)
+
+	pubKey, secKey, err := am.getRoundKeys("round_1")
+	require.NoError(t, err)
+	require.True(t, pubKey.Equal(roundPubKey))
+	require.True(t, am.baseSuite.Point().Mul(secKey, nil).Equal(roundPubKey))
+
+	// the pending key pair is bound to the round, another round finds it among the keys of other rounds
+	pid, err = am.bindRoundKeys("round_2", responses.DKGProposalPubKeysParticipantResponse{
+		{ParticipantId: 0, DkgPubKey: roundPubKeyBz},
+	})
+	require.NoError(t, err)
+	require.Equal(t, 0, pid)
+
+	pid, err = am.bindRoundKeys("round_3", responses.DKGProposalPubKeysParticipantResponse{
+		{ParticipantId: 0, DkgPubKey: []byte("someone else")},
+		{ParticipantId: 1, DkgPubKey: defaultPubKeyBz},
+	})
+	require.NoError(t, err)
+	require.Equal(t, 1, pid)
+	pubKey, _, err = am.getRoundKeys("round_3")
+	require.NoError(t, err)
+	require.True(t, pubKey.Equal(am.pubKey))
+
+	_, err = am.bindRoundKeys("round_4", responses.DKGProposalPubKeysParticipantResponse{
+		{ParticipantId: 0, DkgPubKey: []byte("someone else")},
+	})
+	require.Error(t, err)
+
+	_, err = am.bindRoundKeys("../round", responses.DKGProposalPubKeysParticipantResponse{
+		{ParticipantId: 0, DkgPubKey: defaultPubKeyBz},
+	})
+	require.Error(t, err)
+
+	rounds, err := am.ListRounds()
+	require.NoError(t, err)
+	require.Len(t, rounds, 3)
+	require.Equal(t, roundPubKeyBz, rounds[0].PubKey)
+	require.Equal(t, roundPubKeyBz, rounds[1].PubKey)
+	require.Equal(t, defaultPubKeyBz, rounds[2].PubKey)
+}
+
+func TestMachine_ArchiveAndExportRound(t *testing.T) {
+	testDir := "/tmp/dc4bc_test_archive_round"
+	resultFolder := "/tmp/dc4bc_test_archive_round_results"
+	defer os.RemoveAll(testDir)
+	defer os.RemoveAll(resultFolder)
+
+	am := newTestMachine(t, testDir)
+	am.SetResultFolder(resultFolder)
+
+	require.NoError(t, am.storeOperation(client.Operation{DKGIdentifier: "round_1", ID: "id_1"}))
+	require.NoError(t, am.storeOperation(client.Operation{DKGIdentifier: "round_2", ID: "id_2"}))
+
+	require.NoError(t, am.ArchiveRound("round_1"))
+	require.Error(t, am.ArchiveRound("round_3"))
+
+	_, err := am.ProcessOperation(client.Operation{DKGIdentifier: "round_1", ID: "id_3"}, true)
+	require.ErrorContains(t, err, "archived")
+
+	rounds, err := am.ListRounds()
+	require.NoError(t, err)
+	require.Len(t, rounds, 2)
+	require.True(t, rounds[0].Archived)
+	require.Equal(t, 1, rounds[0].Operations)
+	require.False(t, rounds[1].Archived)
+
+	path, err := am.ExportRound("round_1")
+	require.NoError(t, err)
+	require.Equal(t, filepath.Join(resultFolder, "round_1", roundExportFilename), path)
+
+	exportBz, err := os.ReadFile(path)
+	require.NoError(t, err)
+	var export RoundExport
+	require.NoError(t, json.Unmarshal(exportBz, &export))
+	require.Equal(t, "round_1", export.DKGIdentifier)
+	require.NotEmpty(t, export.Salt)
+	require.Contains(t, export.Records, roundArchivedName)
+
+	// the exported operations are encrypted as they are stored
+	operationBz, err := am.decryptSensitiveData(export.Records[fmt.Sprintf("%s/%020d", roundOperationPrefix, 0)])
+	require.NoError(t, err)
+	var operation client.Operation
+	require.NoError(t, json.Unmarshal(operationBz, &operation))
+	require.Equal(t, "id_1", operation.ID)
+	require.Contains(t, export.Records, roundOperationsHeadName)
+}
+
+func TestMachine_MigrateRounds(t *testing.T) {
+	testDir := "/tmp/dc4bc_test_migrate_rounds"
+	defer os.RemoveAll(testDir)
+
+	db, err := leveldb.OpenFile(testDir, nil)
+	require.NoError(t, err)
+	roundOperationsLogBz, err := json.Marshal(RoundOperationLog{
+		"round_1": {{DKGIdentifier: "round_1", ID: "id_1"}, {DKGIdentifier: "round_1", ID: "id_2"}},
+		"round_2": {{DKGIdentifier: "round_2", ID: "id_3"}},
+	})
+	require.NoError(t, err)
+	require.NoError(t, db.Put([]byte(operationsLogDBKey), roundOperationsLogBz, nil))
+	require.NoError(t, db.Put([]byte(blsKeyringPrefix+"_round_1"), []byte("keyring"), nil))
+	require.NoError(t, db.Put([]byte(keyringVersionPrefix+"_round_1"), []byte("2"), nil))
+	require.NoError(t, db.Put([]byte(baseSeedKey), make([]byte, seedSize), nil))
+	require.NoError(t, db.Close())
+
+	am, err := NewMachine(testDir, logger.NewLogger("airgapped"))
+	require.NoError(t, err)
+	has, err := am.db.Has([]byte(operationsLogDBKey), nil)
+	require.NoError(t, err)
+	require.True(t, has)
+	am.SetEncryptionKey([]byte("password"))
+	require.NoError(t, am.Migrate())
+
+	ops, err := am.getOperationsLog("round_1")
+	require.NoError(t, err)
+	require.Len(t, ops, 2)
+	ops, err = am.getOperationsLog("round_2")
+	require.NoError(t, err)
+	require.Len(t, ops, 1)
+
+	keyring, err := am.db.Get([]byte(makeBLSKeyKeyringDBKey("round_1")), nil)
+	require.NoError(t, err)
+	require.Equal(t, []byte("keyring"), keyring)
+	version, err := am.GetBLSKeyringVersion("round_1")
+	require.NoError(t, err)
+	require.Equal(t, 2, version)
+
+	for _, key := range []string{operationsLogDBKey, blsKeyringPrefix + "_round_1", keyringVersionPrefix + "_round_1"} {
+		has, err := am.db.Has([]byte(key), nil)
+		require.NoError(t, err)
+		require.False(t, has, key)
+	}
+
+	rounds, err := am.ListRounds()
+	require.NoError(t, err)
+	require.Len(t, rounds, 2)
+	require.True(t, rounds[0].Finished)
+	require.Nil(t, rounds[0].PubKey)
+}
@@ -0,0 +1,120 @@
+package airgapped
+
+import (
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+
+	bls "github.com/corestario/kyber/pairing/bls12381"
+
+	client "github.com/lidofinance/dc4bc/client/types"
+	"github.com/lidofinance/dc4bc/dkg"
+	"github.com/lidofinance/dc4bc/fsm/state_machines/share_refresh_fsm"
+	"github.com/lidofinance/dc4bc/fsm/types/responses"
+)
+
+// handleStateShareRefreshCommitsAwaitConfirmations takes a list of participants DKG pub keys and the keyring version
+// to refresh as payload and returns commits of a resharing of our share to broadcast
+func (am *Machine) handleStateShareRefreshCommitsAwaitConfirmations(o *client.Operation) error {
+	var (
+		payload responses.ShareRefreshInvitationResponse
+		err     error
+	)
+
+	if err = json.Unmarshal(o.Payload, &payload); err != nil {
+		return fmt.Errorf("failed to unmarshal payload: %w", err)
+	}
+	if len(payload.Participants) == 0 {
+		return fmt.Errorf("empty list of participants for share refresh of DKG #%s", o.DKGIdentifier)
+	}
+
+	if err = am.activateBLSKeyring(o.DKGIdentifier, payload.KeyringVersion); err != nil {
+		return fmt.Errorf("failed to activate BLSKeyring: %w", err)
+	}
+
+	blsKeyring, err := am.loadBLSKeyring(o.DKGIdentifier)
+	if err != nil {
+		return fmt.Errorf("failed to load BLSKeyring: %w", err)
+	}
+
+	// Every refresh deals new random polynomials, so the seed of the refresh depends on the produced version
+	var (
+		refreshSeed = sha256.Sum256(append([]byte(fmt.Sprintf("%s_refresh_%d", o.DKGIdentifier, payload.KeyringVersion+1)),
+			am.baseSeed...))
+		suite = bls.NewBLS12381Suite(refreshSeed[:])
+	)
+	pubKey, secKey, err := am.getRoundKeys(o.DKGIdentifier)
+	if err != nil {
+		return err
+	}
+	refreshInstance := dkg.Init(suite, pubKey, secKey)
+	refreshInstance.Threshold = payload.Participants[0].Threshold //same for everyone
+	refreshInstance.N = len(payload.Participants)
+
+	if err = am.storePubKeys(refreshInstance, payload.Participants); err != nil {
+		return err
+	}
+
+	if err = refreshInstance.InitRefreshInstance(refreshSeed[:], blsKeyring); err != nil {
+		return fmt.Errorf("failed to init share refresh instance: %w", err)
+	}
+
+	am.refreshInstances[o.DKGIdentifier] = refreshInstance
+
+	return am.sendCommits(o, refreshInstance, share_refresh_fsm.EventShareRefreshCommitConfirmationReceived)
+}
+
+// handleStateShareRefreshDealsAwaitConfirmations takes broadcasted participants commits as payload and
+// returns encrypted deals of our share for every participant
+func (am *Machine) handleStateShareRefreshDealsAwaitConfirmations(o *client.Operation) error {
+	refreshInstance, ok := am.refreshInstances[o.DKGIdentifier]
+	if !ok {
+		return fmt.Errorf("share refresh instance with identifier %s does not exist", o.DKGIdentifier)
+	}
+
+	return am.sendDeals(o, refreshInstance, share_refresh_fsm.EventShareRefreshDealConfirmationReceived)
+}
+
+// handleStateShareRefreshResponsesAwaitConfirmations takes deals sent to us as payload, decrypt and process them and
+// returns responses to broadcast
+func (am *Machine) handleStateShareRefreshResponsesAwaitConfirmations(o *client.Operation) error {
+	refreshInstance, ok := am.refreshInstances[o.DKGIdentifier]
+	if !ok {
+		return fmt.Errorf("share refresh instance with identifier %s does not exist", o.DKGIdentifier)
+	}
+
+	return am.sendResponses(o, refreshInstance, share_refresh_fsm.EventShareRefreshResponseConfirmationReceived)
+}
+
+// handleStateShareRefreshKeyringAwaitConfirmations takes broadcasted responses from the previous step, process them
+// and saves the refreshed keyring. The master key of the keyring must be the master key of the round
+func (am *Machine) handleStateShareRefreshKeyringAwaitConfirmations(o *client.Operation) error {
+	refreshInstance, ok := am.refreshInstances[o.DKGIdentifier]
+	if !ok {
+		return fmt.Errorf("share refresh instance with identifier %s does not exist", o.DKGIdentifier)
+	}
+
+	blsKeyring, err := am.loadBLSKeyring(o.DKGIdentifier)
+	if err != nil {
+		return fmt.Errorf("failed to load BLSKeyring: %w", err)
+	}
+	version, err := am.GetBLSKeyringVersion(o.DKGIdentifier)
+	if err != nil {
+		return err
+	}
+
+	refreshedKeyring, err := am.reconstructBLSKeyring(o, refreshInstance)
+	if err != nil {
+		return err
+	}
+
+	if !refreshedKeyring.PubPoly.Commit().Equal(blsKeyring.PubPoly.Commit()) {
+		return fmt.Errorf("master key is changed by the share refresh")
+	}
+
+	if err = am.saveRefreshedBLSKeyring(o.DKGIdentifier, refreshedKeyring, version+1); err != nil {
+		return fmt.Errorf("failed to save refreshed BLSKeyring: %w", err)
+	}
+
+	return am.sendMasterKey(o, refreshInstance, refreshedKeyring, share_refresh_fsm.EventShareRefreshKeyringConfirmationReceived)
+}
@@ -1,16 +1,19 @@
 package airgapped
 
 import (
-	"crypto/rand"
+	"bytes"
+	"crypto/sha256"
 	"crypto/sha512"
 	"encoding/json"
 	"errors"
 	"fmt"
-	"log"
+	"os"
+	"strconv"
 
 	bls12381 "github.com/corestario/kyber/pairing/bls12381"
 
 	"github.com/syndtr/goleveldb/leveldb"
+	"github.com/syndtr/goleveldb/leveldb/util"
 	"github.com/tyler-smith/go-bip39"
 	"golang.org/x/crypto/pbkdf2"
 
@@ -24,14 +27,27 @@ const (
 	baseSeedKey        = "base_seed_key"
 	operationsLogDBKey = "operations_log"
 	mnemonicSalt       = "mnemonic"
+	// the base seed encrypted with the encryption key, baseSeedKey holds the seed stored in plaintext by old versions
+	encryptedBaseSeedKey = "encrypted_base_seed_key"
+	// the version of the storage layout, storages of previous versions have no version
+	schemaVersionDBKey = "schema_version"
 )
 
+// storageSchemaVersion is the version of the storage layout: the rounds have separate namespaces, the operations
+// are stored as separate records and the sensitive data is encrypted
+const storageSchemaVersion = 1
+
+// ErrNeedsMigration is returned if the storage is made by a previous version of the machine, the storage has to be
+// migrated by Migrate before the machine is used
+var ErrNeedsMigration = errors.New("storage is made by a previous version and needs migration")
+
+// RoundOperationLog is the operation log of all rounds stored as one record before the rounds got separate namespaces
 type RoundOperationLog map[string][]client.Operation
 
 func (am *Machine) loadBaseSeed() error {
 	seed, err := am.getBaseSeed()
 	if errors.Is(err, leveldb.ErrNotFound) {
-		log.Println("Base seed not initialized, making a new one...")
+		am.logger.Infof("Base seed not initialized, making a new one...")
 		entropy, err := bip39.NewEntropy(256) //maximum
 		if err != nil {
 			return fmt.Errorf("failed to generate bip39 entropy: %w", err)
@@ -48,8 +64,9 @@ func (am *Machine) loadBaseSeed() error {
 			return fmt.Errorf("failed to storeBaseSeed: %w", err)
 		}
 
-		log.Println("Successfully generated a new seed")
-		log.Println("Write down your mnemonic: ", mnemonic)
+		am.logger.Infof("Successfully generated a new seed")
+		// the mnemonic is shown to the operator regardless of the log level and never gets into log files
+		fmt.Fprintln(os.Stderr, "Write down your mnemonic: ", mnemonic)
 	} else if err != nil {
 		return fmt.Errorf("failed to getBaseSeed: %w", err)
 	}
@@ -74,13 +91,18 @@ func (am *Machine) SetBaseSeed(mnemonic string) error {
 	am.baseSeed = seed
 	am.baseSuite = bls12381.NewBLS12381Suite(am.baseSeed)
 
-	log.Println("Successfully set a base seed")
+	am.logger.Infof("Successfully set a base seed")
 
 	return nil
 }
 
 func (am *Machine) storeBaseSeed(seed []byte) error {
-	if err := am.db.Put([]byte(baseSeedKey), seed, nil); err != nil {
+	encryptedSeed, err := am.encryptSensitiveData(seed)
+	if err != nil {
+		return fmt.Errorf("failed to encrypt baseSeed: %w", err)
+	}
+
+	if err := am.db.Put([]byte(encryptedBaseSeedKey), encryptedSeed, nil); err != nil {
 		return fmt.Errorf("failed to put baseSeed: %w", err)
 	}
 
@@ -88,67 +110,185 @@ func (am *Machine) storeBaseSeed(seed []byte) error {
 }
 
 func (am *Machine) getBaseSeed() ([]byte, error) {
-	seed, err := am.db.Get([]byte(baseSeedKey), nil)
+	encryptedSeed, err := am.db.Get([]byte(encryptedBaseSeedKey), nil)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get baseSeed: %w", err)
 	}
 
+	seed, err := am.decryptSensitiveData(encryptedSeed)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decrypt baseSeed: %w", err)
+	}
+
 	return seed, nil
 }
 
+// operationsLogHead is the state of the operation log of a round, it is written along with every operation
+type operationsLogHead struct {
+	// the number of operations, the sequence number of the next operation
+	Count int `json:"count"`
+	// the digest of the operations, sha256(previous digest || operation), the operations are hashed before encryption
+	Digest []byte `json:"digest"`
+}
+
+func makeOperationDigest(prevDigest, operationBz []byte) []byte {
+	digest := sha256.Sum256(append(append([]byte{}, prevDigest...), operationBz...))
+	return digest[:]
+}
+
+// storeOperation appends the operation to the operation log of the round. The operation and the updated head of
+// the log are written atomically
 func (am *Machine) storeOperation(o client.Operation) error {
-	roundOperationsLog, err := am.getRoundOperationLog()
-	if err != nil {
-		if errors.Is(err, leveldb.ErrNotFound) {
-			return err
-		}
-		return fmt.Errorf("failed to get operationsLogBz from db: %w", err)
+	head, err := am.getOperationsLogHead(o.DKGIdentifier)
+	if err != nil && !errors.Is(err, leveldb.ErrNotFound) {
+		return fmt.Errorf("failed to get operationsLog from db: %w", err)
 	}
 
-	operationsLog := roundOperationsLog[o.DKGIdentifier]
-	operationsLog = append(operationsLog, o)
-	roundOperationsLog[o.DKGIdentifier] = operationsLog
-
-	roundOperationsLogBz, err := json.Marshal(roundOperationsLog)
-	if err != nil {
-		return fmt.Errorf("failed to marshal operationsLog: %w", err)
+	batch := new(leveldb.Batch)
+	if _, err = appendOperations(batch, o.DKGIdentifier, head, []client.Operation{o}, am.encryptSensitiveData); err != nil {
+		return err
 	}
 
-	if err := am.db.Put([]byte(operationsLogDBKey), roundOperationsLogBz, nil); err != nil {
+	if err = am.db.Write(batch, nil); err != nil {
 		return fmt.Errorf("failed to put updated operationsLog: %w", err)
 	}
 
 	return nil
 }
 
+// appendOperations puts the operations after the head of the operation log of the round to the batch. The operations
+// are encrypted with seal, they are put as is if seal is nil
+func appendOperations(batch *leveldb.Batch, dkgIdentifier string, head operationsLogHead, operations []client.Operation,
+	seal func(data []byte) ([]byte, error)) (operationsLogHead, error) {
+	if err := validateDKGIdentifier(dkgIdentifier); err != nil {
+		return head, err
+	}
+
+	for _, o := range operations {
+		operationBz, err := json.Marshal(o)
+		if err != nil {
+			return head, fmt.Errorf("failed to marshal operation: %w", err)
+		}
+		head.Digest = makeOperationDigest(head.Digest, operationBz)
+		if seal != nil {
+			if operationBz, err = seal(operationBz); err != nil {
+				return head, fmt.Errorf("failed to encrypt operation: %w", err)
+			}
+		}
+		batch.Put([]byte(makeOperationDBKey(dkgIdentifier, head.Count)), operationBz)
+		head.Count++
+	}
+
+	headBz, err := json.Marshal(head)
+	if err != nil {
+		return head, fmt.Errorf("failed to marshal operationsLog head: %w", err)
+	}
+	batch.Put([]byte(makeRoundDBKey(dkgIdentifier, roundOperationsHeadName)), headBz)
+
+	return head, nil
+}
+
+func (am *Machine) getOperationsLogHead(dkgIdentifier string) (operationsLogHead, error) {
+	var head operationsLogHead
+	if err := validateDKGIdentifier(dkgIdentifier); err != nil {
+		return head, err
+	}
+
+	headBz, err := am.db.Get([]byte(makeRoundDBKey(dkgIdentifier, roundOperationsHeadName)), nil)
+	if err != nil {
+		return head, err
+	}
+	if err = json.Unmarshal(headBz, &head); err != nil {
+		return head, fmt.Errorf("failed to unmarshal operationsLog head: %w", err)
+	}
+	return head, nil
+}
+
+// getOperationsLog returns the operation log of the round, leveldb.ErrNotFound if the round has no operation log.
+// The operations are checked against the digest of the log
 func (am *Machine) getOperationsLog(dkgIdentifier string) ([]client.Operation, error) {
-	roundOperationsLog, err := am.getRoundOperationLog()
+	needsMigration, err := am.NeedsMigration()
 	if err != nil {
-		if errors.Is(err, leveldb.ErrNotFound) {
-			return nil, err
+		return nil, err
+	}
+	if needsMigration {
+		return nil, fmt.Errorf("failed to get operation log of %s: %w", dkgIdentifier, ErrNeedsMigration)
+	}
+
+	head, err := am.getOperationsLogHead(dkgIdentifier)
+	if err != nil {
+		return nil, fmt.Errorf("operation log not found for %s: %w", dkgIdentifier, err)
+	}
+
+	var (
+		operationsLog = make([]client.Operation, 0, head.Count)
+		digest        []byte
+	)
+	iter := am.db.NewIterator(util.BytesPrefix([]byte(makeRoundDBKey(dkgIdentifier, roundOperationPrefix+"/"))), nil)
+	defer iter.Release()
+
+	for iter.Next() {
+		if string(iter.Key()) != makeOperationDBKey(dkgIdentifier, len(operationsLog)) {
+			return nil, fmt.Errorf("operation log of %s is corrupted: unexpected record %s", dkgIdentifier, iter.Key())
 		}
-		return nil, fmt.Errorf("failed to get operationsLogBz from db: %w", err)
+		operationBz, err := am.decryptSensitiveData(iter.Value())
+		if err != nil {
+			return nil, fmt.Errorf("operation log of %s is corrupted: failed to decrypt operation %d: %w",
+				dkgIdentifier, len(operationsLog), err)
+		}
+		var o client.Operation
+		if err = json.Unmarshal(operationBz, &o); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal stored operation: %w", err)
+		}
+		digest = makeOperationDigest(digest, operationBz)
+		operationsLog = append(operationsLog, o)
+	}
+	if err = iter.Error(); err != nil {
+		return nil, fmt.Errorf("failed to iterate operationsLog: %w", err)
 	}
 
-	operationsLog, ok := roundOperationsLog[dkgIdentifier]
-	if !ok {
-		return nil, fmt.Errorf("operation log not found for %s", dkgIdentifier)
+	if len(operationsLog) != head.Count || !bytes.Equal(digest, head.Digest) {
+		return nil, fmt.Errorf("operation log of %s is corrupted: %d operations do not match the digest of %d operations",
+			dkgIdentifier, len(operationsLog), head.Count)
 	}
 
 	return operationsLog, nil
 }
 
+// putOperationsLog replaces the operation log of the round with the operations
+func (am *Machine) putOperationsLog(dkgIdentifier string, operationsLog []client.Operation) error {
+	batch := new(leveldb.Batch)
+	if err := am.replaceOperationsLog(batch, dkgIdentifier, operationsLog); err != nil {
+		return err
+	}
+
+	if err := am.db.Write(batch, nil); err != nil {
+		return fmt.Errorf("failed to put updated operationsLog: %w", err)
+	}
+
+	return nil
+}
+
+// replaceOperationsLog puts the replacement of the operation log of the round with the operations to the batch
+func (am *Machine) replaceOperationsLog(batch *leveldb.Batch, dkgIdentifier string, operationsLog []client.Operation) error {
+	head, err := am.getOperationsLogHead(dkgIdentifier)
+	if err != nil && !errors.Is(err, leveldb.ErrNotFound) {
+		return fmt.Errorf("failed to get operationsLog from db: %w", err)
+	}
+
+	for seq := 0; seq < head.Count; seq++ {
+		batch.Delete([]byte(makeOperationDBKey(dkgIdentifier, seq)))
+	}
+	_, err = appendOperations(batch, dkgIdentifier, operationsLogHead{}, operationsLog, am.encryptSensitiveData)
+	return err
+}
+
 func (am *Machine) clearOperationsLog(dkgIdentifier string, remove func(o client.Operation) bool) error {
-	roundOperationsLog, err := am.getRoundOperationLog()
+	operations, err := am.getOperationsLog(dkgIdentifier)
 	if err != nil {
-		if errors.Is(err, leveldb.ErrNotFound) {
-			return err
-		}
-		return fmt.Errorf("failed to get operationsLogBz from db: %w", err)
+		return err
 	}
 
-	operations := roundOperationsLog[dkgIdentifier]
-
 	savedOperations := make([]client.Operation, 0)
 	for _, o := range operations {
 		if remove(o) {
@@ -157,42 +297,116 @@ func (am *Machine) clearOperationsLog(dkgIdentifier string, remove func(o client
 		savedOperations = append(savedOperations, o)
 	}
 
-	roundOperationsLog[dkgIdentifier] = savedOperations
-	roundOperationsLogBz, err := json.Marshal(roundOperationsLog)
-	if err != nil {
-		return fmt.Errorf("failed to marshal operationsLog: %w", err)
-	}
+	return am.putOperationsLog(dkgIdentifier, savedOperations)
+}
 
-	if err := am.db.Put([]byte(operationsLogDBKey), roundOperationsLogBz, nil); err != nil {
-		return fmt.Errorf("failed to put updated operationsLog: %w", err)
+func (am *Machine) dropRoundOperationLog(dkgIdentifier string) error {
+	return am.putOperationsLog(dkgIdentifier, []client.Operation{})
+}
+
+// initSchemaVersion marks a new storage with the current schema version
+func (am *Machine) initSchemaVersion() error {
+	iter := am.db.NewIterator(nil, nil)
+	empty := !iter.First()
+	iter.Release()
+	if err := iter.Error(); err != nil {
+		return fmt.Errorf("failed to iterate db: %w", err)
+	}
+	if !empty {
+		return nil
 	}
 
+	if err := am.db.Put([]byte(schemaVersionDBKey), []byte(strconv.Itoa(storageSchemaVersion)), nil); err != nil {
+		return fmt.Errorf("failed to put schema version: %w", err)
+	}
 	return nil
 }
 
-func (am *Machine) dropRoundOperationLog(dkgIdentifier string) error {
-	roundOperationsLog, err := am.getRoundOperationLog()
+// NeedsMigration returns true if the storage is made by a previous version of the machine
+func (am *Machine) NeedsMigration() (bool, error) {
+	versionBz, err := am.db.Get([]byte(schemaVersionDBKey), nil)
+	if errors.Is(err, leveldb.ErrNotFound) {
+		return true, nil
+	}
 	if err != nil {
-		if errors.Is(err, leveldb.ErrNotFound) {
-			return err
-		}
-		return fmt.Errorf("failed to get operationsLogBz from db: %w", err)
+		return false, fmt.Errorf("failed to get schema version: %w", err)
 	}
+	version, err := strconv.Atoi(string(versionBz))
+	if err != nil {
+		return false, fmt.Errorf("failed to parse schema version: %w", err)
+	}
+	return version < storageSchemaVersion, nil
+}
 
-	roundOperationsLog[dkgIdentifier] = []client.Operation{}
-	roundOperationsLogBz, err := json.Marshal(roundOperationsLog)
+// Migrate migrates the storage made by a previous version of the machine: the records of the rounds are moved into
+// the namespaces of the rounds, the operation logs are split into separate records and the sensitive data stored in
+// plaintext is encrypted with the encryption key. The storage is migrated atomically, but it can not be restored
+// after the migration, so a backup of it has to be made before
+func (am *Machine) Migrate() error {
+	needsMigration, err := am.NeedsMigration()
 	if err != nil {
-		return fmt.Errorf("failed to marshal operationsLog: %w", err)
+		return err
+	}
+	if !needsMigration {
+		return nil
 	}
 
-	if err := am.db.Put([]byte(operationsLogDBKey), roundOperationsLogBz, nil); err != nil {
-		return fmt.Errorf("failed to put updated operationsLog: %w", err)
+	// the encryption key is checked first, so nothing is migrated with a wrong one
+	batch := new(leveldb.Batch)
+	if err = am.encryptPlaintextData(batch); err != nil {
+		return fmt.Errorf("failed to encrypt plaintext data: %w", err)
+	}
+	if err = am.migrateRounds(batch); err != nil {
+		return fmt.Errorf("failed to migrate rounds: %w", err)
 	}
+	if err = am.migrateOperationsLogs(batch); err != nil {
+		return fmt.Errorf("failed to migrate operation logs: %w", err)
+	}
+	batch.Put([]byte(schemaVersionDBKey), []byte(strconv.Itoa(storageSchemaVersion)))
 
+	if err = am.db.Write(batch, nil); err != nil {
+		return fmt.Errorf("failed to write migrated storage: %w", err)
+	}
+	am.logger.Infof("Migrated the storage to schema version %d", storageSchemaVersion)
 	return nil
 }
 
-func (am *Machine) getRoundOperationLog() (RoundOperationLog, error) {
+// migrateOperationsLogs puts the moving of the operation logs stored as one record per round into separate encrypted
+// records to the batch
+func (am *Machine) migrateOperationsLogs(batch *leveldb.Batch) error {
+	migrated := 0
+
+	iter := am.db.NewIterator(util.BytesPrefix([]byte(roundPrefix+"/")), nil)
+	for iter.Next() {
+		dkgID, name, ok := parseRoundDBKey(string(iter.Key()))
+		if !ok || name != roundOperationsLogName {
+			continue
+		}
+		var operationsLog []client.Operation
+		if err := json.Unmarshal(iter.Value(), &operationsLog); err != nil {
+			iter.Release()
+			return fmt.Errorf("failed to unmarshal stored operationsLog of %s: %w", dkgID, err)
+		}
+		if _, err := appendOperations(batch, dkgID, operationsLogHead{}, operationsLog, am.encryptSensitiveData); err != nil {
+			iter.Release()
+			return err
+		}
+		batch.Delete(append([]byte{}, iter.Key()...))
+		migrated++
+	}
+	iter.Release()
+	if err := iter.Error(); err != nil {
+		return fmt.Errorf("failed to iterate db: %w", err)
+	}
+
+	if migrated > 0 {
+		am.logger.Infof("Moving operation logs of %d rounds to separate records", migrated)
+	}
+	return nil
+}
+
+// getLegacyRoundOperationLog returns the operation log of all rounds stored as one record, see migrateRounds
+func (am *Machine) getLegacyRoundOperationLog() (RoundOperationLog, error) {
 	operationsLogBz, err := am.db.Get([]byte(operationsLogDBKey), nil)
 	if err != nil {
 		return nil, err
@@ -224,20 +438,12 @@ func (am *Machine) LoadKeysFromDB() error {
 		return fmt.Errorf("failed to get private key from db: %w", err)
 	}
 
-	salt, err := am.db.Get([]byte(saltDBKey), nil)
-	if err != nil {
-		if errors.Is(err, leveldb.ErrNotFound) {
-			return err
-		}
-		return fmt.Errorf("failed to read salt from db: %w", err)
-	}
-
-	decryptedPubKey, err := decrypt(am.encryptionKey, salt, pubKeyBz)
+	decryptedPubKey, err := am.decryptSensitiveData(pubKeyBz)
 	if err != nil {
 		return err
 	}
 
-	decryptedPrivateKey, err := decrypt(am.encryptionKey, salt, privateKeyBz)
+	decryptedPrivateKey, err := am.decryptSensitiveData(privateKeyBz)
 	if err != nil {
 		return err
 	}
@@ -265,16 +471,12 @@ func (am *Machine) SaveKeysToDB() error {
 		return fmt.Errorf("failed to marshal private key: %w", err)
 	}
 
-	salt := make([]byte, 32)
-	if _, err := rand.Read(salt); err != nil {
-		return fmt.Errorf("failed to generate salt: %w", err)
-	}
-
-	encryptedPubKey, err := encrypt(am.encryptionKey, salt, pubKeyBz)
+	// the salt is kept, other sensitive data is encrypted with it as well
+	encryptedPubKey, err := am.encryptSensitiveData(pubKeyBz)
 	if err != nil {
 		return err
 	}
-	encryptedPrivateKey, err := encrypt(am.encryptionKey, salt, privateKeyBz)
+	encryptedPrivateKey, err := am.encryptSensitiveData(privateKeyBz)
 	if err != nil {
 		return err
 	}
@@ -293,10 +495,6 @@ func (am *Machine) SaveKeysToDB() error {
 		return fmt.Errorf("failed to put private key into db: %w", err)
 	}
 
-	if err = tx.Put([]byte(saltDBKey), salt, nil); err != nil {
-		return fmt.Errorf("failed to put salt into db: %w", err)
-	}
-
 	if err = tx.Commit(); err != nil {
 		return fmt.Errorf("failed to commit tx for saving keys into db: %w", err)
 	}
@@ -1,25 +1,27 @@
 package airgapped
 
 import (
+	"encoding/json"
 	"fmt"
 	"os"
 	"testing"
 
 	"github.com/stretchr/testify/require"
+	"github.com/syndtr/goleveldb/leveldb"
 
+	"github.com/lidofinance/dc4bc/client/modules/logger"
 	client "github.com/lidofinance/dc4bc/client/types"
+	"github.com/lidofinance/dc4bc/fsm/types/responses"
 )
 
 func TestMachine_DropOperationsLog(t *testing.T) {
 	testDir := "/tmp/dc4bc_test_drop_log"
 	dkgIdentifier := "aaa"
+	defer os.RemoveAll(testDir)
 
-	am, err := NewMachine(testDir)
-	if err != nil {
-		t.Fatalf("failed to create airgapped machine: %v", err)
-	}
+	am := newTestMachine(t, testDir)
 
-	err = am.storeOperation(client.Operation{DKGIdentifier: dkgIdentifier, ID: "id_1"})
+	err := am.storeOperation(client.Operation{DKGIdentifier: dkgIdentifier, ID: "id_1"})
 	require.NoError(t, err)
 	err = am.storeOperation(client.Operation{DKGIdentifier: dkgIdentifier, ID: "id_2"})
 	require.NoError(t, err)
@@ -34,6 +36,230 @@ func TestMachine_DropOperationsLog(t *testing.T) {
 	ops, err = am.getOperationsLog(dkgIdentifier)
 	require.NoError(t, err)
 	require.Len(t, ops, 0)
+}
+
+func TestMachine_OperationsLogIntegrity(t *testing.T) {
+	testDir := "/tmp/dc4bc_test_log_integrity"
+	dkgIdentifier := "aaa"
+	defer os.RemoveAll(testDir)
+
+	am := newTestMachine(t, testDir)
+
+	_, err := am.getOperationsLog(dkgIdentifier)
+	require.ErrorIs(t, err, leveldb.ErrNotFound)
+
+	for i := 0; i < 3; i++ {
+		err = am.storeOperation(client.Operation{DKGIdentifier: dkgIdentifier, ID: fmt.Sprintf("id_%d", i)})
+		require.NoError(t, err)
+	}
+	err = am.storeOperation(client.Operation{DKGIdentifier: "bbb", ID: "id_bbb"})
+	require.NoError(t, err)
+
+	ops, err := am.getOperationsLog(dkgIdentifier)
+	require.NoError(t, err)
+	require.Len(t, ops, 3)
+	for i, o := range ops {
+		require.Equal(t, fmt.Sprintf("id_%d", i), o.ID)
+	}
+
+	// a modified operation does not match the digest of the log
+	operationBz, err := json.Marshal(client.Operation{DKGIdentifier: dkgIdentifier, ID: "modified"})
+	require.NoError(t, err)
+	operationBz, err = am.encryptSensitiveData(operationBz)
+	require.NoError(t, err)
+	require.NoError(t, am.db.Put([]byte(makeOperationDBKey(dkgIdentifier, 1)), operationBz, nil))
+	_, err = am.getOperationsLog(dkgIdentifier)
+	require.ErrorContains(t, err, "corrupted")
+
+	require.NoError(t, am.db.Put([]byte(makeOperationDBKey(dkgIdentifier, 1)), []byte("garbage"), nil))
+	_, err = am.getOperationsLog(dkgIdentifier)
+	require.ErrorContains(t, err, "corrupted")
+
+	// so does a lost one
+	require.NoError(t, am.db.Delete([]byte(makeOperationDBKey(dkgIdentifier, 1)), nil))
+	_, err = am.getOperationsLog(dkgIdentifier)
+	require.ErrorContains(t, err, "corrupted")
+
+	ops, err = am.getOperationsLog("bbb")
+	require.NoError(t, err)
+	require.Len(t, ops, 1)
+}
+
+func TestMachine_MigrateOperationsLogs(t *testing.T) {
+	testDir := "/tmp/dc4bc_test_migrate_log"
+	dkgIdentifier := "aaa"
+	defer os.RemoveAll(testDir)
+
+	db, err := leveldb.OpenFile(testDir, nil)
+	require.NoError(t, err)
+	operationsLogBz, err := json.Marshal([]client.Operation{
+		{DKGIdentifier: dkgIdentifier, ID: "id_1"},
+		{DKGIdentifier: dkgIdentifier, ID: "id_2"},
+	})
+	require.NoError(t, err)
+	require.NoError(t, db.Put([]byte(makeRoundDBKey(dkgIdentifier, roundOperationsLogName)), operationsLogBz, nil))
+	require.NoError(t, db.Put([]byte(baseSeedKey), make([]byte, seedSize), nil))
+	require.NoError(t, db.Close())
+
+	am, err := NewMachine(testDir, logger.NewLogger("airgapped"))
+	require.NoError(t, err)
+	am.SetEncryptionKey([]byte("password"))
+	require.ErrorIs(t, am.InitKeys(), ErrNeedsMigration)
+	// the storage is not changed until it is migrated
+	has, err := am.db.Has([]byte(makeRoundDBKey(dkgIdentifier, roundOperationsLogName)), nil)
+	require.NoError(t, err)
+	require.True(t, has)
+
+	require.NoError(t, am.Migrate())
+	require.NoError(t, am.InitKeys())
+
+	has, err = am.db.Has([]byte(makeRoundDBKey(dkgIdentifier, roundOperationsLogName)), nil)
+	require.NoError(t, err)
+	require.False(t, has)
+
+	ops, err := am.getOperationsLog(dkgIdentifier)
+	require.NoError(t, err)
+	require.Len(t, ops, 2)
+	require.Equal(t, "id_1", ops[0].ID)
+	require.Equal(t, "id_2", ops[1].ID)
+
+	require.NoError(t, am.storeOperation(client.Operation{DKGIdentifier: dkgIdentifier, ID: "id_3"}))
+	ops, err = am.getOperationsLog(dkgIdentifier)
+	require.NoError(t, err)
+	require.Len(t, ops, 3)
+}
+
+func TestMachine_MigratePlaintextData(t *testing.T) {
+	testDir := "/tmp/dc4bc_test_encrypt_plaintext"
+	dkgIdentifier := "aaa"
+	defer os.RemoveAll(testDir)
+
+	am := newTestMachine(t, testDir)
+	seed := am.baseSeed
+	require.NoError(t, am.storeOperation(client.Operation{DKGIdentifier: dkgIdentifier, ID: "id_1"}))
+	pubKey := am.pubKey
+
+	// make the storage look like one of a version that kept the seed and the operations in plaintext
+	operationBz, err := json.Marshal(client.Operation{DKGIdentifier: dkgIdentifier, ID: "id_1"})
+	require.NoError(t, err)
+	require.NoError(t, am.db.Put([]byte(makeOperationDBKey(dkgIdentifier, 0)), operationBz, nil))
+	require.NoError(t, am.db.Put([]byte(baseSeedKey), seed, nil))
+	require.NoError(t, am.db.Delete([]byte(encryptedBaseSeedKey), nil))
+	require.NoError(t, am.db.Delete([]byte(schemaVersionDBKey), nil))
+	require.NoError(t, am.db.Close())
 
-	defer os.RemoveAll(fmt.Sprintf("%s/%s-drop_log", testDir, testDB))
+	am, err = NewMachine(testDir, logger.NewLogger("airgapped"))
+	require.NoError(t, err)
+	needsMigration, err := am.NeedsMigration()
+	require.NoError(t, err)
+	require.True(t, needsMigration)
+
+	am.SetEncryptionKey([]byte("password"))
+	require.ErrorIs(t, am.InitKeys(), ErrNeedsMigration)
+
+	am.SetEncryptionKey([]byte("wrong password"))
+	require.Error(t, am.Migrate())
+
+	am.SetEncryptionKey([]byte("password"))
+	require.NoError(t, am.Migrate())
+	needsMigration, err = am.NeedsMigration()
+	require.NoError(t, err)
+	require.False(t, needsMigration)
+
+	require.NoError(t, am.InitKeys())
+	require.Equal(t, seed, am.baseSeed)
+	require.True(t, pubKey.Equal(am.pubKey))
+
+	storedOperationBz, err := am.db.Get([]byte(makeOperationDBKey(dkgIdentifier, 0)), nil)
+	require.NoError(t, err)
+	require.NotEqual(t, operationBz, storedOperationBz)
+	ops, err := am.getOperationsLog(dkgIdentifier)
+	require.NoError(t, err)
+	require.Len(t, ops, 1)
+}
+
+func TestMachine_MigratePlaintextOperations(t *testing.T) {
+	testDir := "/tmp/dc4bc_test_migrate_plaintext_operations"
+	dkgIdentifier := "aaa"
+	defer os.RemoveAll(testDir)
+
+	am := newTestMachine(t, testDir)
+	require.NoError(t, am.storeOperation(client.Operation{DKGIdentifier: dkgIdentifier, ID: "id_1"}))
+
+	// the seed is encrypted, but the operations are stored in plaintext
+	operationBz, err := json.Marshal(client.Operation{DKGIdentifier: dkgIdentifier, ID: "id_1"})
+	require.NoError(t, err)
+	require.NoError(t, am.db.Put([]byte(makeOperationDBKey(dkgIdentifier, 0)), operationBz, nil))
+	require.NoError(t, am.db.Delete([]byte(schemaVersionDBKey), nil))
+	require.NoError(t, am.db.Close())
+
+	am, err = NewMachine(testDir, logger.NewLogger("airgapped"))
+	require.NoError(t, err)
+	am.SetEncryptionKey([]byte("password"))
+	require.ErrorIs(t, am.InitKeys(), ErrNeedsMigration)
+	_, err = am.getOperationsLog(dkgIdentifier)
+	require.ErrorIs(t, err, ErrNeedsMigration)
+
+	// nothing is migrated with a wrong password
+	am.SetEncryptionKey([]byte("wrong password"))
+	require.Error(t, am.Migrate())
+	storedOperationBz, err := am.db.Get([]byte(makeOperationDBKey(dkgIdentifier, 0)), nil)
+	require.NoError(t, err)
+	require.Equal(t, operationBz, storedOperationBz)
+
+	am.SetEncryptionKey([]byte("password"))
+	require.NoError(t, am.Migrate())
+	require.NoError(t, am.InitKeys())
+
+	storedOperationBz, err = am.db.Get([]byte(makeOperationDBKey(dkgIdentifier, 0)), nil)
+	require.NoError(t, err)
+	require.NotEqual(t, operationBz, storedOperationBz)
+	ops, err := am.getOperationsLog(dkgIdentifier)
+	require.NoError(t, err)
+	require.Len(t, ops, 1)
+}
+
+func TestMachine_ChangeEncryptionKey(t *testing.T) {
+	testDir := "/tmp/dc4bc_test_change_encryption_key"
+	dkgIdentifier := "aaa"
+	defer os.RemoveAll(testDir)
+
+	am := newTestMachine(t, testDir)
+	seed := am.baseSeed
+	pubKey := am.pubKey
+	require.NoError(t, am.storeOperation(client.Operation{DKGIdentifier: dkgIdentifier, ID: "id_1"}))
+	roundPubKey, err := am.GenerateRoundKeys()
+	require.NoError(t, err)
+	salt, err := am.db.Get([]byte(saltDBKey), nil)
+	require.NoError(t, err)
+
+	require.NoError(t, am.ChangeEncryptionKey([]byte("new password")))
+	newSalt, err := am.db.Get([]byte(saltDBKey), nil)
+	require.NoError(t, err)
+	require.NotEqual(t, salt, newSalt)
+
+	ops, err := am.getOperationsLog(dkgIdentifier)
+	require.NoError(t, err)
+	require.Len(t, ops, 1)
+	require.NoError(t, am.db.Close())
+
+	am, err = NewMachine(testDir, logger.NewLogger("airgapped"))
+	require.NoError(t, err)
+	am.SetEncryptionKey([]byte("password"))
+	require.Error(t, am.InitKeys())
+
+	am.SetEncryptionKey([]byte("new password"))
+	require.NoError(t, am.InitKeys())
+	require.Equal(t, seed, am.baseSeed)
+	require.True(t, pubKey.Equal(am.pubKey))
+
+	roundPubKeyBz, err := roundPubKey.MarshalBinary()
+	require.NoError(t, err)
+	_, err = am.bindRoundKeys(dkgIdentifier, responses.DKGProposalPubKeysParticipantResponse{
+		{ParticipantId: 0, DkgPubKey: roundPubKeyBz},
+	})
+	require.NoError(t, err)
+	_, secKey, err := am.getRoundKeys(dkgIdentifier)
+	require.NoError(t, err)
+	require.True(t, am.baseSuite.Point().Mul(secKey, nil).Equal(roundPubKey))
 }
@@ -1,40 +1,202 @@
 package airgapped
 
 import (
+	"errors"
 	"fmt"
-	"strings"
+	"strconv"
 
-	"github.com/syndtr/goleveldb/leveldb/util"
+	"github.com/syndtr/goleveldb/leveldb"
 
 	"github.com/lidofinance/dc4bc/dkg"
 )
 
 const (
 	blsKeyringPrefix = "bls_keyring"
+	// a keyring produced by a share refresh, it replaces the keyring of the round when the round switches to its version
+	refreshedBLSKeyringPrefix = "refreshed_bls_keyring"
+	// the number of finished share refreshes of the keyring, absent for a keyring of the DKG
+	keyringVersionPrefix          = "keyring_version"
+	refreshedKeyringVersionPrefix = "refreshed_keyring_version"
 )
 
 func makeBLSKeyKeyringDBKey(key string) string {
-	return fmt.Sprintf("%s_%s", blsKeyringPrefix, key)
+	return makeRoundDBKey(key, blsKeyringPrefix)
 }
 
-func (am *Machine) saveBLSKeyring(dkgID string, blsKeyring *dkg.BLSKeyring) error {
-	salt, err := am.db.Get([]byte(saltDBKey), nil)
+func makeRefreshedBLSKeyringDBKey(key string) string {
+	return makeRoundDBKey(key, refreshedBLSKeyringPrefix)
+}
+
+func makeKeyringVersionDBKey(key string) string {
+	return makeRoundDBKey(key, keyringVersionPrefix)
+}
+
+func makeRefreshedKeyringVersionDBKey(key string) string {
+	return makeRoundDBKey(key, refreshedKeyringVersionPrefix)
+}
+
+func (am *Machine) encryptBLSKeyring(blsKeyring *dkg.BLSKeyring) ([]byte, error) {
+	blsKeyringBz, err := blsKeyring.Bytes()
 	if err != nil {
-		return fmt.Errorf("failed to read salt from db: %w", err)
+		return nil, fmt.Errorf("failed to encode bls keyring: %w", err)
 	}
 
-	blsKeyringBz, err := blsKeyring.Bytes()
+	encryptedKeyring, err := am.encryptSensitiveData(blsKeyringBz)
 	if err != nil {
-		return fmt.Errorf("failed to encode bls keyring: %w", err)
+		return nil, fmt.Errorf("failed to encrypt BLS keyring: %w", err)
 	}
+	return encryptedKeyring, nil
+}
 
-	encryptedKeyring, err := encrypt(am.encryptionKey, salt, blsKeyringBz)
+// saveBLSKeyring saves the keyring produced by the DKG, it drops the versions left by share refreshes of the round
+func (am *Machine) saveBLSKeyring(dkgID string, blsKeyring *dkg.BLSKeyring) error {
+	encryptedKeyring, err := am.encryptBLSKeyring(blsKeyring)
 	if err != nil {
-		return fmt.Errorf("failed to encrypt BLS keyring: %w", err)
+		return err
 	}
-	if err := am.db.Put([]byte(makeBLSKeyKeyringDBKey(dkgID)), encryptedKeyring, nil); err != nil {
+
+	tx, err := am.db.OpenTransaction()
+	if err != nil {
+		return fmt.Errorf("failed to open transcation for db: %w", err)
+	}
+	defer tx.Discard()
+
+	if err := tx.Put([]byte(makeBLSKeyKeyringDBKey(dkgID)), encryptedKeyring, nil); err != nil {
 		return fmt.Errorf("failed to save BLSKeyring into db: %w", err)
 	}
+	for _, key := range []string{
+		makeKeyringVersionDBKey(dkgID),
+		makeRefreshedBLSKeyringDBKey(dkgID),
+		makeRefreshedKeyringVersionDBKey(dkgID),
+	} {
+		if err = tx.Delete([]byte(key), nil); err != nil {
+			return fmt.Errorf("failed to delete %s from db: %w", key, err)
+		}
+	}
+
+	if err = tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit tx for saving BLSKeyring into db: %w", err)
+	}
+	return nil
+}
+
+// saveRefreshedBLSKeyring saves the keyring produced by a share refresh. The keyring of the round is not replaced
+// until the refresh is finished by all participants, see activateBLSKeyring
+func (am *Machine) saveRefreshedBLSKeyring(dkgID string, blsKeyring *dkg.BLSKeyring, version int) error {
+	encryptedKeyring, err := am.encryptBLSKeyring(blsKeyring)
+	if err != nil {
+		return err
+	}
+
+	tx, err := am.db.OpenTransaction()
+	if err != nil {
+		return fmt.Errorf("failed to open transcation for db: %w", err)
+	}
+	defer tx.Discard()
+
+	if err = tx.Put([]byte(makeRefreshedBLSKeyringDBKey(dkgID)), encryptedKeyring, nil); err != nil {
+		return fmt.Errorf("failed to save refreshed BLSKeyring into db: %w", err)
+	}
+	if err = tx.Put([]byte(makeRefreshedKeyringVersionDBKey(dkgID)), []byte(strconv.Itoa(version)), nil); err != nil {
+		return fmt.Errorf("failed to save refreshed BLSKeyring version into db: %w", err)
+	}
+
+	if err = tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit tx for saving refreshed BLSKeyring into db: %w", err)
+	}
+	return nil
+}
+
+func (am *Machine) getKeyringVersion(key string) (int, error) {
+	versionBz, err := am.db.Get([]byte(key), nil)
+	if err != nil {
+		return 0, err
+	}
+	version, err := strconv.Atoi(string(versionBz))
+	if err != nil {
+		return 0, fmt.Errorf("failed to parse keyring version: %w", err)
+	}
+	return version, nil
+}
+
+// GetBLSKeyringVersion returns the number of share refreshes of the keyring of the round
+func (am *Machine) GetBLSKeyringVersion(dkgID string) (int, error) {
+	version, err := am.getKeyringVersion(makeKeyringVersionDBKey(dkgID))
+	if errors.Is(err, leveldb.ErrNotFound) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, fmt.Errorf("failed to get keyring version with dkg id %s: %w", dkgID, err)
+	}
+	return version, nil
+}
+
+// activateBLSKeyring makes the keyring of the version the keyring of the round. The version is set by the FSM,
+// so a keyring refreshed by a canceled share refresh is dropped, and the old shares are deleted by a finished one.
+// The operations of the DKG and the share refresh are dropped with the old shares, the replay of the round
+// starts from the refreshed keyring
+func (am *Machine) activateBLSKeyring(dkgID string, version int) error {
+	activeVersion, err := am.GetBLSKeyringVersion(dkgID)
+	if err != nil {
+		return err
+	}
+
+	refreshedVersion, err := am.getKeyringVersion(makeRefreshedKeyringVersionDBKey(dkgID))
+	if errors.Is(err, leveldb.ErrNotFound) {
+		if version != activeVersion {
+			return fmt.Errorf("keyring version %d does not exist, the keyring version is %d", version, activeVersion)
+		}
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to get refreshed keyring version with dkg id %s: %w", dkgID, err)
+	}
+
+	if version != activeVersion && version != refreshedVersion {
+		return fmt.Errorf("keyring version %d does not exist, the keyring version is %d", version, activeVersion)
+	}
+
+	tx, err := am.db.OpenTransaction()
+	if err != nil {
+		return fmt.Errorf("failed to open transcation for db: %w", err)
+	}
+	defer tx.Discard()
+
+	if version == refreshedVersion {
+		refreshedKeyring, err := tx.Get([]byte(makeRefreshedBLSKeyringDBKey(dkgID)), nil)
+		if err != nil {
+			return fmt.Errorf("failed to get refreshed bls keyring with dkg id %s: %w", dkgID, err)
+		}
+		if err = tx.Put([]byte(makeBLSKeyKeyringDBKey(dkgID)), refreshedKeyring, nil); err != nil {
+			return fmt.Errorf("failed to save BLSKeyring into db: %w", err)
+		}
+		if err = tx.Put([]byte(makeKeyringVersionDBKey(dkgID)), []byte(strconv.Itoa(version)), nil); err != nil {
+			return fmt.Errorf("failed to save BLSKeyring version into db: %w", err)
+		}
+
+		// replaying the operations would deal the old shares again and overwrite the refreshed keyring
+		batch := new(leveldb.Batch)
+		if err = am.replaceOperationsLog(batch, dkgID, nil); err != nil {
+			return fmt.Errorf("failed to prune operationsLog: %w", err)
+		}
+		if err = tx.Write(batch, nil); err != nil {
+			return fmt.Errorf("failed to prune operationsLog: %w", err)
+		}
+	}
+	if err = tx.Delete([]byte(makeRefreshedBLSKeyringDBKey(dkgID)), nil); err != nil {
+		return fmt.Errorf("failed to delete refreshed BLSKeyring from db: %w", err)
+	}
+	if err = tx.Delete([]byte(makeRefreshedKeyringVersionDBKey(dkgID)), nil); err != nil {
+		return fmt.Errorf("failed to delete refreshed BLSKeyring version from db: %w", err)
+	}
+
+	if err = tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit tx for activating BLSKeyring: %w", err)
+	}
+
+	if version == refreshedVersion {
+		am.logger.Infof("Keyring of %s is refreshed to version %d", dkgID, version)
+	}
 	return nil
 }
 
@@ -45,16 +207,11 @@ func (am *Machine) loadBLSKeyring(dkgID string) (*dkg.BLSKeyring, error) {
 		err          error
 	)
 
-	salt, err := am.db.Get([]byte(saltDBKey), nil)
-	if err != nil {
-		return nil, fmt.Errorf("failed to read salt from db: %w", err)
-	}
-
 	if blsKeyringBz, err = am.db.Get([]byte(makeBLSKeyKeyringDBKey(dkgID)), nil); err != nil {
 		return nil, fmt.Errorf("failed to get bls keyring with dkg id %s: %w", dkgID, err)
 	}
 
-	decryptedKeyring, err := decrypt(am.encryptionKey, salt, blsKeyringBz)
+	decryptedKeyring, err := am.decryptSensitiveData(blsKeyringBz)
 	if err != nil {
 		return nil, fmt.Errorf("failed to decrypt BLS keyring: %w", err)
 	}
@@ -65,32 +222,23 @@ func (am *Machine) loadBLSKeyring(dkgID string) (*dkg.BLSKeyring, error) {
 	return blsKeyring, nil
 }
 
+// GetBLSKeyrings returns the keyrings of finished rounds, archived rounds are skipped
 func (am *Machine) GetBLSKeyrings() (map[string]*dkg.BLSKeyring, error) {
-	var (
-		blsKeyring *dkg.BLSKeyring
-		err        error
-	)
-
-	salt, err := am.db.Get([]byte(saltDBKey), nil)
+	rounds, err := am.ListRounds()
 	if err != nil {
-		return nil, fmt.Errorf("failed to read salt from db: %w", err)
+		return nil, err
 	}
 
 	keyrings := make(map[string]*dkg.BLSKeyring)
-	iter := am.db.NewIterator(util.BytesPrefix([]byte(blsKeyringPrefix)), nil)
-	defer iter.Release()
-
-	for iter.Next() {
-		key := iter.Key()
-		value := iter.Value()
-		decryptedKeyring, err := decrypt(am.encryptionKey, salt, value)
-		if err != nil {
-			return nil, fmt.Errorf("failed to decrypt BLS keyring: %w", err)
+	for _, round := range rounds {
+		if !round.Finished || round.Archived {
+			continue
 		}
-		if blsKeyring, err = dkg.LoadBLSKeyringFromBytes(am.baseSuite, decryptedKeyring); err != nil {
-			return nil, fmt.Errorf("failed to decode bls keyring: %w", err)
+		blsKeyring, err := am.loadBLSKeyring(round.DKGIdentifier)
+		if err != nil {
+			return nil, err
 		}
-		keyrings[strings.TrimPrefix(string(key), blsKeyringPrefix+"_")] = blsKeyring
+		keyrings[round.DKGIdentifier] = blsKeyring
 	}
-	return keyrings, iter.Error()
+	return keyrings, nil
 }
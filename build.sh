@@ -15,6 +15,7 @@ echo $1 | {
   go build -o ./build/dc4bc_cli ./cmd/dc4bc_cli/
   go build -o ./build/dc4bc_airgapped ./cmd/airgapped/
   go build -o ./build/dc4bc_dkg_reinitializer ./cmd/dkg_reinitializer/
+  go build -o ./build/dc4bc_board ./cmd/dc4bc_board/
 
   sha1sum ./build/dc4bc_* > "./build/checksum.txt"
 }
\ No newline at end of file
@@ -11,20 +11,26 @@ import (
 // for providing validated and sanitized values to service layer
 
 type MessageDTO struct {
-	ID            string
-	DkgRoundID    string
-	Offset        uint64
-	Event         string
-	Data          []byte
-	Signature     []byte
-	SenderAddr    string
-	RecipientAddr string
+	ID             string
+	DkgRoundID     string
+	Offset         uint64
+	Event          string
+	Data           []byte
+	Signature      []byte
+	SenderAddr     string
+	RecipientAddr  string
+	BoardSignature []byte
 }
 
 type OperationIdDTO struct {
 	OperationID string
 }
 
+type DeclineParticipationDTO struct {
+	OperationID string
+	Reason      string
+}
+
 type DkgIdDTO struct {
 	DkgID string
 }
@@ -56,6 +62,14 @@ type StartDkgDTO struct {
 	Payload []byte
 }
 
+// ProposeResharingDTO proposes to reshare the key of the DKG round to the new committee, the payload is
+// a SignatureProposalParticipantsListRequest. All old participants deal if the dealers are not set
+type ProposeResharingDTO struct {
+	DkgID   string
+	Dealers []string
+	Payload []byte
+}
+
 type ProposeSignMessageDTO struct {
 	DkgID []byte
 	Data  []byte
@@ -0,0 +1,211 @@
+package auth
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"strings"
+
+	"github.com/labstack/echo/v4"
+)
+
+// Role grants access to a group of routes, every role includes the permissions of the lower ones
+type Role int
+
+const (
+	// RoleReadOnly may only read the node state
+	RoleReadOnly Role = iota + 1
+	// RoleOperator may also post messages to the board: start a DKG, propose signatures, approve participation
+	RoleOperator
+	// RoleAdmin may also rewind and reset the node state
+	RoleAdmin
+)
+
+const (
+	tokenSize = 32
+
+	// TokenNameKey is the echo context key of the authenticated token name
+	TokenNameKey = "api_token_name"
+)
+
+var (
+	ErrUnknownRole      = errors.New("unknown role")
+	ErrMissingToken     = errors.New("missing API token")
+	ErrInvalidToken     = errors.New("invalid API token")
+	ErrPermissionDenied = errors.New("API token role does not permit the request")
+)
+
+var roleNames = map[Role]string{
+	RoleReadOnly: "read-only",
+	RoleOperator: "operator",
+	RoleAdmin:    "admin",
+}
+
+func (r Role) String() string {
+	if name, ok := roleNames[r]; ok {
+		return name
+	}
+	return fmt.Sprintf("Role(%d)", int(r))
+}
+
+func ParseRole(s string) (Role, error) {
+	for role, name := range roleNames {
+		if name == s {
+			return role, nil
+		}
+	}
+	return 0, fmt.Errorf("%w: %s", ErrUnknownRole, s)
+}
+
+// Token is an entry of the tokens file, only a hash of the token is kept on the node
+type Token struct {
+	Name        string `json:"name"`
+	Role        string `json:"role"`
+	TokenSHA256 string `json:"token_sha256"`
+}
+
+// NewToken generates a random token and returns it with the entry to be saved to the tokens file
+func NewToken(name string, role Role) (string, Token, error) {
+	if _, ok := roleNames[role]; !ok {
+		return "", Token{}, fmt.Errorf("%w: %s", ErrUnknownRole, role)
+	}
+
+	bz := make([]byte, tokenSize)
+	if _, err := rand.Read(bz); err != nil {
+		return "", Token{}, fmt.Errorf("failed to generate token: %w", err)
+	}
+	token := hex.EncodeToString(bz)
+
+	return token, Token{Name: name, Role: role.String(), TokenSHA256: hashToken(token)}, nil
+}
+
+func hashToken(token string) string {
+	hash := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(hash[:])
+}
+
+func ReadTokensFile(filename string) ([]Token, error) {
+	bz, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read tokens file: %w", err)
+	}
+
+	var tokens []Token
+	if err = json.Unmarshal(bz, &tokens); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal tokens file: %w", err)
+	}
+	return tokens, nil
+}
+
+// AppendTokensFile adds the token to the tokens file, the file is created if it does not exist
+func AppendTokensFile(filename string, token Token) error {
+	tokens, err := ReadTokensFile(filename)
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+
+	for _, t := range tokens {
+		if t.Name == token.Name {
+			return fmt.Errorf("token %s already exists", token.Name)
+		}
+	}
+	tokens = append(tokens, token)
+
+	bz, err := json.MarshalIndent(tokens, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal tokens: %w", err)
+	}
+	if err = ioutil.WriteFile(filename, bz, 0600); err != nil {
+		return fmt.Errorf("failed to write tokens file: %w", err)
+	}
+	return nil
+}
+
+type principal struct {
+	name string
+	role Role
+}
+
+// Authenticator checks bearer tokens of API requests against the tokens file
+type Authenticator struct {
+	// tokens maps token hashes to their owners
+	tokens map[string]principal
+}
+
+func NewAuthenticator(tokens []Token) (*Authenticator, error) {
+	a := &Authenticator{tokens: make(map[string]principal, len(tokens))}
+	for _, t := range tokens {
+		role, err := ParseRole(t.Role)
+		if err != nil {
+			return nil, fmt.Errorf("invalid token %s: %w", t.Name, err)
+		}
+		hash, err := hex.DecodeString(t.TokenSHA256)
+		if err != nil || len(hash) != sha256.Size {
+			return nil, fmt.Errorf("invalid token %s: malformed hash", t.Name)
+		}
+		a.tokens[strings.ToLower(t.TokenSHA256)] = principal{name: t.Name, role: role}
+	}
+	return a, nil
+}
+
+func LoadAuthenticator(filename string) (*Authenticator, error) {
+	tokens, err := ReadTokensFile(filename)
+	if err != nil {
+		return nil, err
+	}
+	return NewAuthenticator(tokens)
+}
+
+// authenticate looks the token up by its hash, timing of the lookup reveals nothing about valid tokens
+func (a *Authenticator) authenticate(token string) (principal, bool) {
+	p, ok := a.tokens[hashToken(token)]
+	return p, ok
+}
+
+// TokenName returns the name of the token which authenticated the request, empty if the API is not authenticated
+func TokenName(c echo.Context) string {
+	name, _ := c.Get(TokenNameKey).(string)
+	return name
+}
+
+// ErrorWriter replies to a request failed authentication, so each API version keeps its error format
+type ErrorWriter func(c echo.Context, code int, err error) error
+
+// Middleware allows requests with a token of the required role or higher,
+// a nil Authenticator allows every request
+func (a *Authenticator) Middleware(required Role, writeError ErrorWriter) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		if a == nil {
+			return next
+		}
+
+		return func(c echo.Context) error {
+			header := c.Request().Header.Get(echo.HeaderAuthorization)
+			if header == "" {
+				return writeError(c, http.StatusUnauthorized, ErrMissingToken)
+			}
+			token := strings.TrimPrefix(header, "Bearer ")
+			if token == header {
+				return writeError(c, http.StatusUnauthorized, ErrInvalidToken)
+			}
+
+			p, ok := a.authenticate(token)
+			if !ok {
+				return writeError(c, http.StatusUnauthorized, ErrInvalidToken)
+			}
+			if p.role < required {
+				return writeError(c, http.StatusForbidden, fmt.Errorf("%w: %s requires %s role", ErrPermissionDenied,
+					c.Path(), required))
+			}
+
+			c.Set(TokenNameKey, p.name)
+			return next(c)
+		}
+	}
+}
@@ -0,0 +1,80 @@
+package auth
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAuthenticator_Middleware(t *testing.T) {
+	req := require.New(t)
+	tokensFile := filepath.Join(t.TempDir(), "tokens.json")
+
+	tokens := map[Role]string{}
+	for _, role := range []Role{RoleReadOnly, RoleOperator, RoleAdmin} {
+		token, entry, err := NewToken(role.String()+"_user", role)
+		req.NoError(err)
+		req.NoError(AppendTokensFile(tokensFile, entry))
+		tokens[role] = token
+	}
+
+	_, entry, err := NewToken("admin_user", RoleAdmin)
+	req.NoError(err)
+	req.Error(AppendTokensFile(tokensFile, entry))
+
+	authenticator, err := LoadAuthenticator(tokensFile)
+	req.NoError(err)
+
+	writeError := func(c echo.Context, code int, err error) error {
+		return c.String(code, err.Error())
+	}
+
+	e := echo.New()
+	ok := func(c echo.Context) error {
+		return c.String(http.StatusOK, c.Get(TokenNameKey).(string))
+	}
+	e.GET("/read", ok, authenticator.Middleware(RoleReadOnly, writeError))
+	e.POST("/operate", ok, authenticator.Middleware(RoleOperator, writeError))
+	e.POST("/admin", ok, authenticator.Middleware(RoleAdmin, writeError))
+
+	do := func(method, path, authorization string) *httptest.ResponseRecorder {
+		r := httptest.NewRequest(method, path, nil)
+		if authorization != "" {
+			r.Header.Set(echo.HeaderAuthorization, authorization)
+		}
+		w := httptest.NewRecorder()
+		e.ServeHTTP(w, r)
+		return w
+	}
+
+	req.Equal(http.StatusUnauthorized, do(http.MethodGet, "/read", "").Code)
+	req.Equal(http.StatusUnauthorized, do(http.MethodGet, "/read", "Bearer wrong").Code)
+	req.Equal(http.StatusUnauthorized, do(http.MethodGet, "/read", tokens[RoleAdmin]).Code)
+
+	w := do(http.MethodGet, "/read", "Bearer "+tokens[RoleReadOnly])
+	req.Equal(http.StatusOK, w.Code)
+	req.Equal("read-only_user", w.Body.String())
+
+	req.Equal(http.StatusForbidden, do(http.MethodPost, "/operate", "Bearer "+tokens[RoleReadOnly]).Code)
+	req.Equal(http.StatusOK, do(http.MethodPost, "/operate", "Bearer "+tokens[RoleOperator]).Code)
+	req.Equal(http.StatusForbidden, do(http.MethodPost, "/admin", "Bearer "+tokens[RoleOperator]).Code)
+	req.Equal(http.StatusOK, do(http.MethodPost, "/admin", "Bearer "+tokens[RoleAdmin]).Code)
+	req.Equal(http.StatusOK, do(http.MethodGet, "/read", "Bearer "+tokens[RoleAdmin]).Code)
+}
+
+func TestAuthenticator_Disabled(t *testing.T) {
+	var authenticator *Authenticator
+
+	e := echo.New()
+	e.POST("/admin", func(c echo.Context) error {
+		return c.NoContent(http.StatusOK)
+	}, authenticator.Middleware(RoleAdmin, nil))
+
+	w := httptest.NewRecorder()
+	e.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/admin", nil))
+	require.Equal(t, http.StatusOK, w.Code)
+}
@@ -0,0 +1,113 @@
+package auth
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"io/ioutil"
+	"math/big"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+type testCert struct {
+	cert     *x509.Certificate
+	key      *ecdsa.PrivateKey
+	certFile string
+	keyFile  string
+}
+
+// newTestCert issues a certificate signed by the parent, a nil parent makes a self-signed CA
+func newTestCert(t *testing.T, name string, parent *testCert) *testCert {
+	req := require.New(t)
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	req.NoError(err)
+
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(time.Now().UnixNano()),
+		Subject:      pkix.Name{CommonName: name},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth},
+		IPAddresses:  []net.IP{net.ParseIP("127.0.0.1")},
+	}
+	signerCert, signerKey := template, key
+	if parent == nil {
+		template.IsCA = true
+		template.BasicConstraintsValid = true
+	} else {
+		signerCert, signerKey = parent.cert, parent.key
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, template, signerCert, &key.PublicKey, signerKey)
+	req.NoError(err)
+	cert, err := x509.ParseCertificate(der)
+	req.NoError(err)
+	keyDer, err := x509.MarshalECPrivateKey(key)
+	req.NoError(err)
+
+	dir := t.TempDir()
+	tc := &testCert{
+		cert:     cert,
+		key:      key,
+		certFile: filepath.Join(dir, name+".crt"),
+		keyFile:  filepath.Join(dir, name+".key"),
+	}
+	req.NoError(ioutil.WriteFile(tc.certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0600))
+	req.NoError(ioutil.WriteFile(tc.keyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDer}), 0600))
+
+	return tc
+}
+
+func TestTLSConfig(t *testing.T) {
+	req := require.New(t)
+
+	ca := newTestCert(t, "ca", nil)
+	serverCert := newTestCert(t, "server", ca)
+	clientCert := newTestCert(t, "client", ca)
+	otherCA := newTestCert(t, "other_ca", nil)
+
+	serverTLS, err := ServerTLSConfig(serverCert.certFile, serverCert.keyFile, ca.certFile)
+	req.NoError(err)
+
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	server.TLS = serverTLS
+	server.StartTLS()
+	defer server.Close()
+
+	get := func(caFile, certFile, keyFile string) error {
+		clientTLS, err := ClientTLSConfig(caFile, certFile, keyFile)
+		req.NoError(err)
+		client := &http.Client{Transport: &http.Transport{TLSClientConfig: clientTLS}}
+
+		resp, err := client.Get(server.URL)
+		if err != nil {
+			return err
+		}
+		resp.Body.Close()
+		req.Equal(http.StatusOK, resp.StatusCode)
+		return nil
+	}
+
+	req.NoError(get(ca.certFile, clientCert.certFile, clientCert.keyFile))
+	// the server is not trusted if its CA is not the pinned one
+	req.Error(get(otherCA.certFile, clientCert.certFile, clientCert.keyFile))
+	// the server requires a client certificate
+	req.Error(get(ca.certFile, "", ""))
+
+	_, err = ClientTLSConfig(filepath.Join(t.TempDir(), "missing.crt"), "", "")
+	req.Error(err)
+}
@@ -0,0 +1,119 @@
+package context_service
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+)
+
+// Error codes of the v1 API, every error response carries one of them
+const (
+	ErrCodeBadRequest   = "bad_request"
+	ErrCodeUnauthorized = "unauthorized"
+	ErrCodeForbidden    = "forbidden"
+	ErrCodeNotFound     = "not_found"
+	ErrCodeConflict     = "conflict"
+	ErrCodeInternal     = "internal"
+)
+
+const (
+	DefaultPageLimit = 100
+	MaxPageLimit     = 1000
+)
+
+type APIError struct {
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+// APIErrorResp is the error response of the v1 API
+type APIErrorResp struct {
+	Error APIError `json:"error"`
+}
+
+// Page is a response of the v1 API listing a collection, Items holds the slice of the requested page
+type Page struct {
+	Items  interface{} `json:"items"`
+	Total  int         `json:"total"`
+	Offset int         `json:"offset"`
+	Limit  int         `json:"limit"`
+}
+
+type PageForm struct {
+	Offset int `query:"offset"`
+	Limit  int `query:"limit"`
+}
+
+// ErrorCode maps an HTTP status to the v1 API error code
+func ErrorCode(status int) string {
+	switch status {
+	case http.StatusBadRequest:
+		return ErrCodeBadRequest
+	case http.StatusUnauthorized:
+		return ErrCodeUnauthorized
+	case http.StatusForbidden:
+		return ErrCodeForbidden
+	case http.StatusNotFound, http.StatusMethodNotAllowed:
+		return ErrCodeNotFound
+	case http.StatusConflict:
+		return ErrCodeConflict
+	default:
+		return ErrCodeInternal
+	}
+}
+
+func (cs *ContextService) ApiJson(code int, data interface{}) error {
+	return cs.JSON(code, data)
+}
+
+func (cs *ContextService) ApiError(code int, err error) error {
+	message := http.StatusText(code)
+	if err != nil {
+		message = err.Error()
+	}
+	return cs.JSON(code, &APIErrorResp{
+		Error: APIError{
+			Code:    ErrorCode(code),
+			Message: message,
+		},
+	})
+}
+
+// BindPage reads offset and limit query parameters, the limit defaults to DefaultPageLimit
+func (cs *ContextService) BindPage() (*PageForm, error) {
+	form := &PageForm{}
+	if err := cs.Bind(form); err != nil {
+		return nil, errors.New("offset and limit must be integers")
+	}
+	if form.Offset < 0 {
+		return nil, errors.New("offset must not be negative")
+	}
+	if form.Limit == 0 {
+		form.Limit = DefaultPageLimit
+	}
+	if form.Limit < 0 || form.Limit > MaxPageLimit {
+		return nil, fmt.Errorf("limit must be between 1 and %d", MaxPageLimit)
+	}
+	return form, nil
+}
+
+// Bounds returns the slice bounds of the page in a collection of the given size
+func (f *PageForm) Bounds(total int) (int, int) {
+	lo, hi := f.Offset, f.Offset+f.Limit
+	if lo > total {
+		lo = total
+	}
+	if hi > total {
+		hi = total
+	}
+	return lo, hi
+}
+
+func (f *PageForm) Page(items interface{}, total int) *Page {
+	return &Page{
+		Items:  items,
+		Total:  total,
+		Offset: f.Offset,
+		Limit:  f.Limit,
+	}
+}
@@ -0,0 +1,56 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+
+	. "github.com/lidofinance/dc4bc/client/api/dto"
+	"github.com/lidofinance/dc4bc/client/api/http_api/auth"
+	cs "github.com/lidofinance/dc4bc/client/api/http_api/context_service"
+	"github.com/lidofinance/dc4bc/client/modules/audit"
+)
+
+var errAuditDisabled = errors.New("audit journal is disabled")
+
+func (a *HTTPApp) V1ListAuditEntries(c echo.Context) error {
+	stx := c.(*cs.ContextService)
+	if a.audit == nil {
+		return stx.ApiError(http.StatusServiceUnavailable, errAuditDisabled)
+	}
+	page, err := stx.BindPage()
+	if err != nil {
+		return stx.ApiError(http.StatusBadRequest, err)
+	}
+
+	entries, err := a.audit.Entries()
+	if err != nil {
+		return stx.ApiError(http.StatusInternalServerError, fmt.Errorf("failed to read audit journal: %w", err))
+	}
+	lo, hi := page.Bounds(len(entries))
+	return stx.ApiJson(http.StatusOK, page.Page(entries[lo:hi], len(entries)))
+}
+
+// recordStateReset records the reset and the messages ignored by it on behalf of the caller's token
+func (a *HTTPApp) recordStateReset(c echo.Context, formDTO *ResetStateDTO, newStateDBDSN string) {
+	actor := auth.TokenName(c)
+	if actor == "" {
+		actor = audit.ActorAnonymous
+	}
+	if len(formDTO.Messages) > 0 {
+		if err := a.audit.Record(actor, audit.ActionMessageIgnored, "", map[string]interface{}{
+			"messages":   formDTO.Messages,
+			"use_offset": formDTO.UseOffset,
+		}); err != nil {
+			c.Logger().Errorf("failed to record ignored messages to audit journal: %v", err)
+		}
+	}
+	if err := a.audit.Record(actor, audit.ActionStateReset, "", map[string]interface{}{
+		"new_state_dbdsn":      newStateDBDSN,
+		"kafka_consumer_group": formDTO.KafkaConsumerGroup,
+	}); err != nil {
+		c.Logger().Errorf("failed to record state reset to audit journal: %v", err)
+	}
+}
@@ -0,0 +1,43 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/labstack/echo/v4"
+
+	cs "github.com/lidofinance/dc4bc/client/api/http_api/context_service"
+	req "github.com/lidofinance/dc4bc/client/api/http_api/requests"
+	"github.com/lidofinance/dc4bc/client/modules/events"
+)
+
+const lastEventIDHeader = "Last-Event-ID"
+
+// V1Events streams node events as server-sent events, a reconnecting client
+// passes the last received event ID in the Last-Event-ID header to get the missed events
+func (a *HTTPApp) V1Events(c echo.Context) error {
+	stx := c.(*cs.ContextService)
+	if a.events == nil {
+		return stx.ApiError(http.StatusServiceUnavailable, errors.New("event stream is disabled"))
+	}
+
+	form := &req.EventsForm{}
+	if err := stx.BindToRequest(form); err != nil {
+		return stx.ApiError(http.StatusBadRequest, err)
+	}
+	if lastEventID := stx.Request().Header.Get(lastEventIDHeader); lastEventID != "" {
+		id, err := strconv.ParseUint(lastEventID, 10, 64)
+		if err != nil {
+			return stx.ApiError(http.StatusBadRequest, fmt.Errorf("invalid %s header: %w", lastEventIDHeader, err))
+		}
+		form.LastEventID = id
+	}
+	filter, err := events.ParseFilter(form.Types, form.DkgID)
+	if err != nil {
+		return stx.ApiError(http.StatusBadRequest, err)
+	}
+
+	return a.events.ServeSSE(stx.Request().Context(), stx.Response(), form.LastEventID, filter)
+}
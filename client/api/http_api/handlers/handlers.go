@@ -1,6 +1,11 @@
 package handlers
 
 import (
+	"net/http"
+
+	"github.com/lidofinance/dc4bc/client/modules/audit"
+	"github.com/lidofinance/dc4bc/client/modules/events"
+	"github.com/lidofinance/dc4bc/client/modules/metrics"
 	"github.com/lidofinance/dc4bc/client/modules/state"
 	"github.com/lidofinance/dc4bc/client/services"
 	"github.com/lidofinance/dc4bc/client/services/fsmservice"
@@ -15,6 +20,9 @@ type HTTPApp struct {
 	state     state.State
 	operation operation_service.OperationService
 	signature signature_service.SignatureService
+	events    *events.Bus
+	metrics   http.Handler
+	audit     *audit.Journal
 }
 
 func NewHTTPApp(node node.NodeService, sp *services.ServiceProvider) *HTTPApp {
@@ -24,5 +32,14 @@ func NewHTTPApp(node node.NodeService, sp *services.ServiceProvider) *HTTPApp {
 		state:     sp.GetState(),
 		operation: sp.GetOperationService(),
 		signature: sp.GetSignatureService(),
+		events:    sp.GetEvents(),
+		audit:     sp.GetAudit(),
+		metrics: metrics.Handler(metrics.NodeState{
+			PendingOperations: func() (int, error) {
+				operations, err := sp.GetOperationService().GetOperations()
+				return len(operations), err
+			},
+			FSMStates: sp.GetFSMService().GetFSMList,
+		}),
 	}
 }
@@ -0,0 +1,10 @@
+package handlers
+
+import (
+	"github.com/labstack/echo/v4"
+)
+
+func (a *HTTPApp) Metrics(c echo.Context) error {
+	a.metrics.ServeHTTP(c.Response(), c.Request())
+	return nil
+}
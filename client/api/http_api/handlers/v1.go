@@ -0,0 +1,432 @@
+package handlers
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"sort"
+
+	"github.com/labstack/echo/v4"
+
+	. "github.com/lidofinance/dc4bc/client/api/dto"
+	cs "github.com/lidofinance/dc4bc/client/api/http_api/context_service"
+	req "github.com/lidofinance/dc4bc/client/api/http_api/requests"
+	resp "github.com/lidofinance/dc4bc/client/api/http_api/responses"
+	"github.com/lidofinance/dc4bc/client/types"
+	fsmtypes "github.com/lidofinance/dc4bc/fsm/types"
+)
+
+// Handlers of the v1 API. They reply with bare resources, paginated collections and APIErrorResp errors,
+// the legacy routes are served by the same handlers (see router.legacyRoute)
+
+var accepted = &resp.Accepted{Status: "accepted"}
+
+func (a *HTTPApp) V1GetNode(c echo.Context) error {
+	stx := c.(*cs.ContextService)
+	return stx.ApiJson(http.StatusOK, &resp.NodeInfo{
+		Username: a.node.GetUsername(),
+		PubKey:   a.node.GetPubKey(),
+	})
+}
+
+func (a *HTTPApp) V1ListDKGs(c echo.Context) error {
+	stx := c.(*cs.ContextService)
+	page, err := stx.BindPage()
+	if err != nil {
+		return stx.ApiError(http.StatusBadRequest, err)
+	}
+
+	fsmList, err := a.fsm.GetFSMList()
+	if err != nil {
+		return stx.ApiError(http.StatusInternalServerError, fmt.Errorf("failed to get DKGs: %w", err))
+	}
+
+	dkgs := make([]resp.DKG, 0, len(fsmList))
+	for id, state := range fsmList {
+		dkgs = append(dkgs, resp.DKG{ID: id, State: state})
+	}
+	sort.Slice(dkgs, func(i, j int) bool {
+		return dkgs[i].ID < dkgs[j].ID
+	})
+
+	lo, hi := page.Bounds(len(dkgs))
+	return stx.ApiJson(http.StatusOK, page.Page(dkgs[lo:hi], len(dkgs)))
+}
+
+// bindDKG binds the dkg_id path parameter and checks that the DKG exists
+func (a *HTTPApp) bindDKG(stx *cs.ContextService, form interface{}, dkgID *string) (int, error) {
+	if err := stx.BindToRequest(form); err != nil {
+		return http.StatusBadRequest, err
+	}
+
+	exists, err := a.fsm.IsExist(*dkgID)
+	if err != nil {
+		return http.StatusInternalServerError, fmt.Errorf("failed to get DKG: %w", err)
+	}
+	if !exists {
+		return http.StatusNotFound, fmt.Errorf("DKG %s not found", *dkgID)
+	}
+	return 0, nil
+}
+
+func (a *HTTPApp) V1GetDKG(c echo.Context) error {
+	stx := c.(*cs.ContextService)
+	form := &req.DkgPathForm{}
+	if code, err := a.bindDKG(stx, form, &form.DkgID); err != nil {
+		return stx.ApiError(code, err)
+	}
+
+	fsmDump, err := a.fsm.GetFSMDump(&DkgIdDTO{DkgID: form.DkgID})
+	if err != nil {
+		return stx.ApiError(http.StatusInternalServerError, err)
+	}
+	return stx.ApiJson(http.StatusOK, fsmDump)
+}
+
+func (a *HTTPApp) V1StartDKG(c echo.Context) error {
+	stx := c.(*cs.ContextService)
+	payload, err := ioutil.ReadAll(stx.Request().Body)
+	if err != nil {
+		return stx.ApiError(http.StatusBadRequest, fmt.Errorf("failed to read request body: %w", err))
+	}
+	if !json.Valid(payload) {
+		return stx.ApiError(http.StatusBadRequest, errors.New("request body is not a valid JSON"))
+	}
+
+	if err = a.node.StartDKG(&StartDkgDTO{Payload: payload}); err != nil {
+		return stx.ApiError(http.StatusInternalServerError, err)
+	}
+	return stx.ApiJson(http.StatusAccepted, accepted)
+}
+
+func (a *HTTPApp) V1ReInitDKG(c echo.Context) error {
+	stx := c.(*cs.ContextService)
+	form := &req.ReInitDKGForm{}
+	if err := stx.BindToRequest(form); err != nil {
+		return stx.ApiError(http.StatusBadRequest, err)
+	}
+	form.ID = stx.Param("dkg_id")
+
+	payload, err := json.Marshal(form)
+	if err != nil {
+		return stx.ApiError(http.StatusBadRequest, fmt.Errorf("failed to marshal request body: %w", err))
+	}
+
+	if err = a.node.ReInitDKG(&ReInitDKGDTO{ID: form.ID, Payload: payload}); err != nil {
+		return stx.ApiError(http.StatusInternalServerError, err)
+	}
+	return stx.ApiJson(http.StatusAccepted, accepted)
+}
+
+func (a *HTTPApp) V1ProposeShareRefresh(c echo.Context) error {
+	stx := c.(*cs.ContextService)
+	form := &req.DkgPathForm{}
+	if code, err := a.bindDKG(stx, form, &form.DkgID); err != nil {
+		return stx.ApiError(code, err)
+	}
+
+	if err := a.node.ProposeShareRefresh(&DkgIdDTO{DkgID: form.DkgID}); err != nil {
+		return stx.ApiError(http.StatusInternalServerError, err)
+	}
+	return stx.ApiJson(http.StatusAccepted, accepted)
+}
+
+func (a *HTTPApp) V1ProposeResharing(c echo.Context) error {
+	stx := c.(*cs.ContextService)
+	form := &req.ResharingForm{}
+	if code, err := a.bindDKG(stx, form, &form.DkgID); err != nil {
+		return stx.ApiError(code, err)
+	}
+
+	payload, err := json.Marshal(form.Committee)
+	if err != nil {
+		return stx.ApiError(http.StatusBadRequest, fmt.Errorf("failed to marshal the new committee: %w", err))
+	}
+
+	if err = a.node.ProposeResharing(&ProposeResharingDTO{DkgID: form.DkgID, Dealers: form.Dealers, Payload: payload}); err != nil {
+		return stx.ApiError(http.StatusInternalServerError, err)
+	}
+	return stx.ApiJson(http.StatusAccepted, accepted)
+}
+
+func (a *HTTPApp) V1ListBatches(c echo.Context) error {
+	stx := c.(*cs.ContextService)
+	page, err := stx.BindPage()
+	if err != nil {
+		return stx.ApiError(http.StatusBadRequest, err)
+	}
+	form := &req.DkgPathForm{}
+	if code, err := a.bindDKG(stx, form, &form.DkgID); err != nil {
+		return stx.ApiError(code, err)
+	}
+
+	batches, err := a.signature.GetBatches(&DkgIdDTO{DkgID: form.DkgID})
+	if err != nil {
+		return stx.ApiError(http.StatusInternalServerError, fmt.Errorf("failed to get batches: %w", err))
+	}
+	if batches == nil {
+		batches = []string{}
+	}
+	sort.Strings(batches)
+
+	lo, hi := page.Bounds(len(batches))
+	return stx.ApiJson(http.StatusOK, page.Page(batches[lo:hi], len(batches)))
+}
+
+func (a *HTTPApp) V1ProposeBatch(c echo.Context) error {
+	stx := c.(*cs.ContextService)
+	form := &req.ProposeBatchForm{}
+	if code, err := a.bindDKG(stx, form, &form.DkgID); err != nil {
+		return stx.ApiError(code, err)
+	}
+	if (len(form.Messages) == 0) == (form.Range == nil) {
+		return stx.ApiError(http.StatusBadRequest, errors.New("either messages or range must be set"))
+	}
+
+	dkgID, err := hex.DecodeString(form.DkgID)
+	if err != nil {
+		return stx.ApiError(http.StatusBadRequest, fmt.Errorf("failed to decode DKG ID: %w", err))
+	}
+
+	batch := &ProposeSignBatchMessagesDTO{DkgID: dkgID, Data: form.Messages}
+	if form.Range != nil {
+		batch.Range = &Range{Start: form.Range.Start, End: form.Range.End}
+	}
+	if err = a.node.ProposeSignMessages(batch); err != nil {
+		return stx.ApiError(http.StatusInternalServerError, err)
+	}
+	return stx.ApiJson(http.StatusAccepted, accepted)
+}
+
+func (a *HTTPApp) V1ListSignatures(c echo.Context) error {
+	stx := c.(*cs.ContextService)
+	page, err := stx.BindPage()
+	if err != nil {
+		return stx.ApiError(http.StatusBadRequest, err)
+	}
+	form := &req.SignaturesForm{}
+	if code, err := a.bindDKG(stx, form, &form.DkgID); err != nil {
+		return stx.ApiError(code, err)
+	}
+
+	storage, err := a.signature.GetSignatures(&DkgIdDTO{DkgID: form.DkgID})
+	if err != nil {
+		return stx.ApiError(http.StatusInternalServerError, fmt.Errorf("failed to get signatures: %w", err))
+	}
+
+	signatures := []fsmtypes.ReconstructedSignature{}
+	for batchID, batch := range storage {
+		if form.BatchID != "" && batchID != form.BatchID {
+			continue
+		}
+		for _, messageSignatures := range batch {
+			signatures = append(signatures, messageSignatures...)
+		}
+	}
+	sort.Slice(signatures, func(i, j int) bool {
+		si, sj := signatures[i], signatures[j]
+		if si.BatchID != sj.BatchID {
+			return si.BatchID < sj.BatchID
+		}
+		if si.MessageID != sj.MessageID {
+			return si.MessageID < sj.MessageID
+		}
+		return si.Username < sj.Username
+	})
+
+	lo, hi := page.Bounds(len(signatures))
+	return stx.ApiJson(http.StatusOK, page.Page(signatures[lo:hi], len(signatures)))
+}
+
+func (a *HTTPApp) V1GetSignature(c echo.Context) error {
+	stx := c.(*cs.ContextService)
+	form := &req.SignaturePathForm{}
+	if code, err := a.bindDKG(stx, form, &form.DkgID); err != nil {
+		return stx.ApiError(code, err)
+	}
+
+	signatures, err := a.signature.GetSignatureByID(&SignatureByIdDTO{ID: form.MessageID, DkgID: form.DkgID})
+	if err != nil {
+		return stx.ApiError(http.StatusInternalServerError, fmt.Errorf("failed to get signatures: %w", err))
+	}
+	if len(signatures) == 0 {
+		return stx.ApiError(http.StatusNotFound, fmt.Errorf("signatures of message %s not found", form.MessageID))
+	}
+	return stx.ApiJson(http.StatusOK, signatures)
+}
+
+func (a *HTTPApp) V1ListOperations(c echo.Context) error {
+	stx := c.(*cs.ContextService)
+	page, err := stx.BindPage()
+	if err != nil {
+		return stx.ApiError(http.StatusBadRequest, err)
+	}
+
+	operationsMap, err := a.operation.GetOperations()
+	if err != nil {
+		return stx.ApiError(http.StatusInternalServerError, fmt.Errorf("failed to get operations: %w", err))
+	}
+
+	operations := make([]*types.Operation, 0, len(operationsMap))
+	for _, operation := range operationsMap {
+		operations = append(operations, operation)
+	}
+	sort.Slice(operations, func(i, j int) bool {
+		if !operations[i].CreatedAt.Equal(operations[j].CreatedAt) {
+			return operations[i].CreatedAt.Before(operations[j].CreatedAt)
+		}
+		return operations[i].ID < operations[j].ID
+	})
+
+	lo, hi := page.Bounds(len(operations))
+	return stx.ApiJson(http.StatusOK, page.Page(operations[lo:hi], len(operations)))
+}
+
+// bindOperation binds the operation_id path parameter and finds the operation
+func (a *HTTPApp) bindOperation(stx *cs.ContextService) (*types.Operation, int, error) {
+	form := &req.OperationPathForm{}
+	if err := stx.BindToRequest(form); err != nil {
+		return nil, http.StatusBadRequest, err
+	}
+	return a.findOperation(form.OperationID)
+}
+
+func (a *HTTPApp) findOperation(operationID string) (*types.Operation, int, error) {
+	operations, err := a.operation.GetOperations()
+	if err != nil {
+		return nil, http.StatusInternalServerError, fmt.Errorf("failed to get operations: %w", err)
+	}
+	operation, ok := operations[operationID]
+	if !ok {
+		return nil, http.StatusNotFound, fmt.Errorf("operation %s not found", operationID)
+	}
+	return operation, 0, nil
+}
+
+func (a *HTTPApp) V1GetOperation(c echo.Context) error {
+	stx := c.(*cs.ContextService)
+	operation, code, err := a.bindOperation(stx)
+	if err != nil {
+		return stx.ApiError(code, err)
+	}
+	return stx.ApiJson(http.StatusOK, operation)
+}
+
+func (a *HTTPApp) V1ApproveOperation(c echo.Context) error {
+	stx := c.(*cs.ContextService)
+	operation, code, err := a.bindOperation(stx)
+	if err != nil {
+		return stx.ApiError(code, err)
+	}
+
+	if err = a.node.ApproveParticipation(&OperationIdDTO{OperationID: operation.ID}); err != nil {
+		return stx.ApiError(http.StatusInternalServerError, err)
+	}
+	return stx.ApiJson(http.StatusAccepted, accepted)
+}
+
+func (a *HTTPApp) V1DeclineOperation(c echo.Context) error {
+	stx := c.(*cs.ContextService)
+	formDTO := &DeclineParticipationDTO{}
+	if err := stx.BindToDTO(&req.DeclineOperationForm{}, formDTO); err != nil {
+		return stx.ApiError(http.StatusBadRequest, err)
+	}
+	if _, code, err := a.findOperation(formDTO.OperationID); err != nil {
+		return stx.ApiError(code, err)
+	}
+
+	if err := a.node.DeclineParticipation(formDTO); err != nil {
+		return stx.ApiError(http.StatusInternalServerError, err)
+	}
+	return stx.ApiJson(http.StatusAccepted, accepted)
+}
+
+func (a *HTTPApp) V1ProcessOperation(c echo.Context) error {
+	stx := c.(*cs.ContextService)
+	formDTO := &OperationDTO{}
+	if err := stx.BindToDTO(&req.OperationForm{}, formDTO); err != nil {
+		return stx.ApiError(http.StatusBadRequest, err)
+	}
+	if formDTO.ID != stx.Param("operation_id") {
+		return stx.ApiError(http.StatusBadRequest, errors.New("operation ID in the body does not match the path"))
+	}
+
+	if err := a.node.ProcessOperation(formDTO); err != nil {
+		return stx.ApiError(http.StatusInternalServerError, err)
+	}
+	return stx.ApiJson(http.StatusAccepted, accepted)
+}
+
+func (a *HTTPApp) V1SendMessage(c echo.Context) error {
+	stx := c.(*cs.ContextService)
+	formDTO := &MessageDTO{}
+	if err := stx.BindToDTO(&req.MessageForm{}, formDTO); err != nil {
+		return stx.ApiError(http.StatusBadRequest, err)
+	}
+
+	if err := a.node.SendMessage(formDTO); err != nil {
+		return stx.ApiError(http.StatusInternalServerError, err)
+	}
+	return stx.ApiJson(http.StatusAccepted, accepted)
+}
+
+func (a *HTTPApp) V1GetStateOffset(c echo.Context) error {
+	stx := c.(*cs.ContextService)
+	offset, err := a.node.GetStateOffset()
+	if err != nil {
+		return stx.ApiError(http.StatusInternalServerError, fmt.Errorf("failed to load offset: %w", err))
+	}
+	return stx.ApiJson(http.StatusOK, &resp.StateOffset{Offset: offset})
+}
+
+func (a *HTTPApp) V1SaveStateOffset(c echo.Context) error {
+	stx := c.(*cs.ContextService)
+	formDTO := &StateOffsetDTO{}
+	if err := stx.BindToDTO(&req.StateOffsetForm{}, formDTO); err != nil {
+		return stx.ApiError(http.StatusBadRequest, err)
+	}
+
+	if err := a.node.SaveOffset(formDTO); err != nil {
+		return stx.ApiError(http.StatusInternalServerError, err)
+	}
+	return stx.ApiJson(http.StatusOK, &resp.StateOffset{Offset: formDTO.Offset})
+}
+
+func (a *HTTPApp) V1ResetState(c echo.Context) error {
+	stx := c.(*cs.ContextService)
+	formDTO := &ResetStateDTO{}
+	if err := stx.BindToDTO(&req.ResetStateForm{}, formDTO); err != nil {
+		return stx.ApiError(http.StatusBadRequest, err)
+	}
+
+	newStateDBDSN, err := a.fsm.ResetFSMState(formDTO)
+	if err != nil {
+		return stx.ApiError(http.StatusInternalServerError, err)
+	}
+	a.recordStateReset(c, formDTO, newStateDBDSN)
+	return stx.ApiJson(http.StatusOK, &resp.ResetState{NewStateDBDSN: newStateDBDSN})
+}
+
+func (a *HTTPApp) V1GetCheckpointDivergences(c echo.Context) error {
+	stx := c.(*cs.ContextService)
+	divergences, err := a.node.GetCheckpointDivergences()
+	if err != nil {
+		return stx.ApiError(http.StatusInternalServerError, fmt.Errorf("failed to get checkpoint divergences: %w", err))
+	}
+	if divergences == nil {
+		divergences = []types.CheckpointDivergence{}
+	}
+	return stx.ApiJson(http.StatusOK, divergences)
+}
+
+func (a *HTTPApp) V1ExportSnapshot(c echo.Context) error {
+	stx := c.(*cs.ContextService)
+	snapshot, err := a.node.ExportSnapshot()
+	if err != nil {
+		return stx.ApiError(http.StatusInternalServerError, fmt.Errorf("failed to export snapshot: %w", err))
+	}
+	return stx.ApiJson(http.StatusOK, snapshot)
+}
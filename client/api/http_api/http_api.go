@@ -2,12 +2,18 @@ package http_api
 
 import (
 	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"net/http"
 
 	"github.com/labstack/echo/v4"
 	echo_middleware "github.com/labstack/echo/v4/middleware"
 
+	"github.com/lidofinance/dc4bc/client/api/http_api/auth"
 	"github.com/lidofinance/dc4bc/client/api/http_api/router"
 	"github.com/lidofinance/dc4bc/client/config"
+	"github.com/lidofinance/dc4bc/client/modules/metrics"
 	"github.com/lidofinance/dc4bc/client/services"
 	"github.com/lidofinance/dc4bc/client/services/node"
 )
@@ -15,9 +21,10 @@ import (
 type RESTApiProvider struct {
 	config       *config.HttpApiConfig
 	echoInstance *echo.Echo
+	tlsConfig    *tls.Config
 }
 
-func NewRESTApi(config *config.Config, node node.NodeService, sp *services.ServiceProvider) *RESTApiProvider {
+func NewRESTApi(config *config.Config, node node.NodeService, sp *services.ServiceProvider) (*RESTApiProvider, error) {
 	p := RESTApiProvider{}
 	p.config = config.HttpApiConfig
 
@@ -33,15 +40,47 @@ func NewRESTApi(config *config.Config, node node.NodeService, sp *services.Servi
 		p.echoInstance.Use(echo_middleware.Logger())
 	}
 
+	p.echoInstance.Use(metrics.EchoMiddleware())
+	if journal := sp.GetAudit(); journal != nil {
+		p.echoInstance.Use(auditMiddleware(journal))
+	}
 	p.echoInstance.Use(contextServiceMiddleware)
 
-	router.SetRouter(p.echoInstance, nil, node, sp)
+	var err error
+	if p.config.TLSCertFile != "" {
+		p.tlsConfig, err = auth.ServerTLSConfig(p.config.TLSCertFile, p.config.TLSKeyFile, p.config.TLSClientCAFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to init API TLS: %w", err)
+		}
+	} else if p.config.TLSClientCAFile != "" {
+		return nil, errors.New("client CA is set, but the API TLS certificate is not")
+	}
+
+	var authenticator *auth.Authenticator
+	if p.config.TokensFile != "" {
+		if authenticator, err = auth.LoadAuthenticator(p.config.TokensFile); err != nil {
+			return nil, fmt.Errorf("failed to load API tokens: %w", err)
+		}
+	} else if p.config.InsecureNoAuth {
+		sp.GetLogger().Warnf("API tokens file is not set, the HTTP API is not authenticated")
+	} else {
+		return nil, errors.New("API tokens file is not set, explicitly disable the API authentication to run without it")
+	}
+
+	router.SetRouter(p.echoInstance, authenticator, node, sp)
 
-	return &p
+	return &p, nil
 }
 
+// Start serves the API over HTTPS if TLS is configured and over plain HTTP otherwise
 func (p *RESTApiProvider) Start() error {
-	return p.echoInstance.Start(p.config.ListenAddr)
+	if p.tlsConfig == nil {
+		return p.echoInstance.Start(p.config.ListenAddr)
+	}
+	return p.echoInstance.StartServer(&http.Server{
+		Addr:      p.config.ListenAddr,
+		TLSConfig: p.tlsConfig,
+	})
 }
 
 func (p *RESTApiProvider) Stop(ctx context.Context) error {
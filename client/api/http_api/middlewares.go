@@ -3,10 +3,15 @@ package http_api
 import (
 	"fmt"
 	"net/http"
+	"strings"
 
 	. "github.com/labstack/echo/v4"
 
+	"github.com/lidofinance/dc4bc/client/api/http_api/auth"
 	cs "github.com/lidofinance/dc4bc/client/api/http_api/context_service"
+	"github.com/lidofinance/dc4bc/client/api/http_api/router"
+	"github.com/lidofinance/dc4bc/client/modules/audit"
+	"github.com/lidofinance/dc4bc/client/modules/metrics"
 )
 
 func contextServiceMiddleware(next HandlerFunc) HandlerFunc {
@@ -15,11 +20,36 @@ func contextServiceMiddleware(next HandlerFunc) HandlerFunc {
 	}
 }
 
+// auditMiddleware records every API call with the caller's token name and the response status to the audit journal
+func auditMiddleware(journal *audit.Journal) MiddlewareFunc {
+	return func(next HandlerFunc) HandlerFunc {
+		return func(ctx Context) error {
+			err := next(ctx)
+
+			actor := auth.TokenName(ctx)
+			if actor == "" {
+				actor = audit.ActorAnonymous
+			}
+			if e := journal.Record(actor, audit.ActionAPICall, ctx.Param("dkg_id"), map[string]interface{}{
+				"method":      ctx.Request().Method,
+				"uri":         ctx.Request().URL.RequestURI(),
+				"status":      metrics.ResponseStatus(ctx, err),
+				"remote_addr": ctx.RealIP(),
+			}); e != nil {
+				ctx.Logger().Errorf("failed to record API call to audit journal: %v", e)
+			}
+			return err
+		}
+	}
+}
+
 // Custom error handler
 func customHTTPErrorHandler(err error, c Context) {
+	code := http.StatusInternalServerError
 	csError, ok := err.(*cs.CSErrorResp)
 	if !ok {
 		if he, ok := err.(*HTTPError); ok {
+			code = he.Code
 			csError = &cs.CSErrorResp{
 				ErrorMessage: fmt.Sprintf("%s", he.Message),
 			}
@@ -33,9 +63,13 @@ func customHTTPErrorHandler(err error, c Context) {
 	// Send response
 	if !c.Response().Committed {
 		if c.Request().Method == http.MethodHead {
-			err = c.NoContent(http.StatusInternalServerError)
+			err = c.NoContent(code)
+		} else if strings.HasPrefix(c.Request().URL.Path, router.V1Prefix+"/") {
+			err = c.JSON(code, &cs.APIErrorResp{
+				Error: cs.APIError{Code: cs.ErrorCode(code), Message: csError.ErrorMessage},
+			})
 		} else {
-			err = c.JSON(http.StatusInternalServerError, csError)
+			err = c.JSON(code, csError)
 		}
 		if err != nil {
 			c.Logger().Error(err)
@@ -0,0 +1,322 @@
+package openapi
+
+import (
+	"encoding/json"
+	"net/http"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const Version = "3.0.3"
+
+// Param is a query parameter of an operation, path parameters are taken from the path
+type Param struct {
+	Name        string
+	Type        string
+	Description string
+	Required    bool
+}
+
+// Operation describes a route, Request and Response are values of the body types, nil means no body
+type Operation struct {
+	Method      string
+	Path        string
+	Tag         string
+	Summary     string
+	Role        string
+	Query       []Param
+	Request     interface{}
+	Response    interface{}
+	Status      int
+	Paginated   bool
+	Deprecated  bool
+	Description string
+}
+
+type Document struct {
+	OpenAPI    string                          `json:"openapi"`
+	Info       Info                            `json:"info"`
+	Paths      map[string]map[string]*PathItem `json:"paths"`
+	Components Components                      `json:"components"`
+	Security   []map[string][]string           `json:"security,omitempty"`
+}
+
+type Info struct {
+	Title       string `json:"title"`
+	Version     string `json:"version"`
+	Description string `json:"description,omitempty"`
+}
+
+type PathItem struct {
+	Tags        []string             `json:"tags,omitempty"`
+	Summary     string               `json:"summary,omitempty"`
+	Description string               `json:"description,omitempty"`
+	OperationID string               `json:"operationId"`
+	Deprecated  bool                 `json:"deprecated,omitempty"`
+	Parameters  []*Parameter         `json:"parameters,omitempty"`
+	RequestBody *RequestBody         `json:"requestBody,omitempty"`
+	Responses   map[string]*Response `json:"responses"`
+	Role        string               `json:"x-dc4bc-role,omitempty"`
+}
+
+type Parameter struct {
+	Name        string  `json:"name"`
+	In          string  `json:"in"`
+	Description string  `json:"description,omitempty"`
+	Required    bool    `json:"required"`
+	Schema      *Schema `json:"schema"`
+}
+
+type RequestBody struct {
+	Required bool                  `json:"required"`
+	Content  map[string]*MediaType `json:"content"`
+}
+
+type Response struct {
+	Description string                `json:"description"`
+	Content     map[string]*MediaType `json:"content,omitempty"`
+}
+
+type MediaType struct {
+	Schema *Schema `json:"schema"`
+}
+
+type Components struct {
+	Schemas         map[string]*Schema         `json:"schemas"`
+	SecuritySchemes map[string]*SecurityScheme `json:"securitySchemes,omitempty"`
+}
+
+type SecurityScheme struct {
+	Type   string `json:"type"`
+	Scheme string `json:"scheme"`
+}
+
+type Schema struct {
+	Ref                  string             `json:"$ref,omitempty"`
+	Type                 string             `json:"type,omitempty"`
+	Format               string             `json:"format,omitempty"`
+	Items                *Schema            `json:"items,omitempty"`
+	Properties           map[string]*Schema `json:"properties,omitempty"`
+	AdditionalProperties *Schema            `json:"additionalProperties,omitempty"`
+}
+
+var pathParamRx = regexp.MustCompile(`:(\w+)`)
+
+// Generate builds the document of the operations, errorResponse is the body of every error response
+// and page wraps the items of paginated responses
+func Generate(info Info, operations []Operation, errorResponse, page interface{}) *Document {
+	g := &generator{schemas: map[string]*Schema{}}
+
+	doc := &Document{
+		OpenAPI: Version,
+		Info:    info,
+		Paths:   map[string]map[string]*PathItem{},
+		Components: Components{
+			Schemas: g.schemas,
+			SecuritySchemes: map[string]*SecurityScheme{
+				"bearerAuth": {Type: "http", Scheme: "bearer"},
+			},
+		},
+		Security: []map[string][]string{{"bearerAuth": {}}},
+	}
+
+	errorSchema := g.schemaOf(reflect.TypeOf(errorResponse))
+	pageType := reflect.TypeOf(page)
+
+	for _, op := range operations {
+		path := pathParamRx.ReplaceAllString(op.Path, "{$1}")
+		item := &PathItem{
+			Summary:     op.Summary,
+			Description: op.Description,
+			OperationID: operationID(op.Method, op.Path),
+			Deprecated:  op.Deprecated,
+			Role:        op.Role,
+			Responses:   map[string]*Response{},
+		}
+		if op.Tag != "" {
+			item.Tags = []string{op.Tag}
+		}
+
+		for _, m := range pathParamRx.FindAllStringSubmatch(op.Path, -1) {
+			item.Parameters = append(item.Parameters, &Parameter{
+				Name:     m[1],
+				In:       "path",
+				Required: true,
+				Schema:   &Schema{Type: "string"},
+			})
+		}
+		query := op.Query
+		if op.Paginated {
+			query = append(query,
+				Param{Name: "offset", Type: "integer", Description: "Number of items to skip"},
+				Param{Name: "limit", Type: "integer", Description: "Maximum number of items to return"},
+			)
+		}
+		for _, p := range query {
+			item.Parameters = append(item.Parameters, &Parameter{
+				Name:        p.Name,
+				In:          "query",
+				Description: p.Description,
+				Required:    p.Required,
+				Schema:      &Schema{Type: p.Type},
+			})
+		}
+
+		if op.Request != nil {
+			item.RequestBody = &RequestBody{
+				Required: true,
+				Content:  jsonContent(g.schemaOf(reflect.TypeOf(op.Request))),
+			}
+		}
+
+		status := op.Status
+		if status == 0 {
+			status = http.StatusOK
+		}
+		response := &Response{Description: http.StatusText(status)}
+		if op.Response != nil {
+			schema := g.schemaOf(reflect.TypeOf(op.Response))
+			if op.Paginated {
+				schema = g.pageSchema(pageType, schema)
+			}
+			response.Content = jsonContent(schema)
+		}
+		item.Responses[strconv.Itoa(status)] = response
+		item.Responses["default"] = &Response{Description: "Error", Content: jsonContent(errorSchema)}
+
+		if doc.Paths[path] == nil {
+			doc.Paths[path] = map[string]*PathItem{}
+		}
+		doc.Paths[path][strings.ToLower(op.Method)] = item
+	}
+
+	return doc
+}
+
+func jsonContent(schema *Schema) map[string]*MediaType {
+	return map[string]*MediaType{"application/json": {Schema: schema}}
+}
+
+// operationID makes an identifier like get_dkgs_dkg_id_batches from the method and the path
+func operationID(method, path string) string {
+	parts := []string{strings.ToLower(method)}
+	for _, p := range strings.Split(path, "/") {
+		p = strings.TrimPrefix(p, ":")
+		if p != "" {
+			parts = append(parts, p)
+		}
+	}
+	return strings.Join(parts, "_")
+}
+
+type generator struct {
+	schemas map[string]*Schema
+}
+
+var (
+	timeType      = reflect.TypeOf(time.Time{})
+	marshalerType = reflect.TypeOf((*json.Marshaler)(nil)).Elem()
+)
+
+// schemaOf returns the schema of a type, named structs are put to the components and referenced
+func (g *generator) schemaOf(t reflect.Type) *Schema {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if t == timeType {
+		return &Schema{Type: "string", Format: "date-time"}
+	}
+	// the JSON form of a type with a custom marshaler is unknown, it is described as any value
+	if t.Implements(marshalerType) || reflect.PtrTo(t).Implements(marshalerType) {
+		return &Schema{}
+	}
+
+	switch t.Kind() {
+	case reflect.Bool:
+		return &Schema{Type: "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return &Schema{Type: "integer"}
+	case reflect.Float32, reflect.Float64:
+		return &Schema{Type: "number"}
+	case reflect.String:
+		return &Schema{Type: "string"}
+	case reflect.Slice, reflect.Array:
+		if t.Elem().Kind() == reflect.Uint8 {
+			return &Schema{Type: "string", Format: "byte"}
+		}
+		return &Schema{Type: "array", Items: g.schemaOf(t.Elem())}
+	case reflect.Map:
+		return &Schema{Type: "object", AdditionalProperties: g.schemaOf(t.Elem())}
+	case reflect.Struct:
+		if t.Name() == "" {
+			return g.structSchema(t)
+		}
+		name := schemaName(t)
+		if _, ok := g.schemas[name]; !ok {
+			// the placeholder stops the recursion on self-referencing types
+			g.schemas[name] = &Schema{}
+			*g.schemas[name] = *g.structSchema(t)
+		}
+		return &Schema{Ref: "#/components/schemas/" + name}
+	default:
+		return &Schema{}
+	}
+}
+
+func (g *generator) structSchema(t reflect.Type) *Schema {
+	schema := &Schema{Type: "object", Properties: map[string]*Schema{}}
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if f.PkgPath != "" {
+			continue
+		}
+
+		name := f.Name
+		if tag, ok := f.Tag.Lookup("json"); ok {
+			tagName := strings.Split(tag, ",")[0]
+			if tagName == "-" {
+				continue
+			}
+			if tagName != "" {
+				name = tagName
+			}
+		}
+
+		if f.Anonymous && f.Type.Kind() == reflect.Struct && name == f.Name {
+			embedded := g.structSchema(f.Type)
+			for k, v := range embedded.Properties {
+				schema.Properties[k] = v
+			}
+			continue
+		}
+		schema.Properties[name] = g.schemaOf(f.Type)
+	}
+	return schema
+}
+
+// pageSchema is the page object with items of the given schema
+func (g *generator) pageSchema(pageType reflect.Type, items *Schema) *Schema {
+	for pageType.Kind() == reflect.Ptr {
+		pageType = pageType.Elem()
+	}
+
+	schema := g.structSchema(pageType)
+	for name, property := range schema.Properties {
+		if property.Type == "" && property.Ref == "" {
+			schema.Properties[name] = &Schema{Type: "array", Items: items}
+		}
+	}
+	return schema
+}
+
+func schemaName(t reflect.Type) string {
+	pkg := t.PkgPath()
+	if i := strings.LastIndex(pkg, "/"); i >= 0 {
+		pkg = pkg[i+1:]
+	}
+	return strings.ReplaceAll(pkg, "_", "") + "." + t.Name()
+}
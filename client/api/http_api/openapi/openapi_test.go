@@ -0,0 +1,75 @@
+package openapi
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+type testItem struct {
+	ID        string            `json:"id"`
+	Data      []byte            `json:"data"`
+	CreatedAt time.Time         `json:"created_at"`
+	Labels    map[string]string `json:"labels,omitempty"`
+	Children  []*testItem       `json:"children"`
+	Ignored   string            `json:"-"`
+	secret    string
+}
+
+type testError struct {
+	Message string `json:"message"`
+}
+
+type testPage struct {
+	Items interface{} `json:"items"`
+	Total int         `json:"total"`
+}
+
+func TestGenerate(t *testing.T) {
+	req := require.New(t)
+
+	doc := Generate(Info{Title: "test", Version: "v1"}, []Operation{
+		{Method: http.MethodGet, Path: "/items", Response: testItem{}, Paginated: true, Role: "read-only"},
+		{Method: http.MethodGet, Path: "/items/:item_id", Response: testItem{}},
+		{Method: http.MethodPost, Path: "/items", Request: testItem{}, Status: http.StatusAccepted},
+	}, testError{}, testPage{})
+
+	_, err := json.Marshal(doc)
+	req.NoError(err)
+
+	list := doc.Paths["/items"]["get"]
+	req.NotNil(list)
+	req.Equal("get_items", list.OperationID)
+	req.Equal("read-only", list.Role)
+	req.Len(list.Parameters, 2)
+	req.Equal("offset", list.Parameters[0].Name)
+	page := list.Responses["200"].Content["application/json"].Schema
+	req.Equal("array", page.Properties["items"].Type)
+	req.Equal("#/components/schemas/openapi.testItem", page.Properties["items"].Items.Ref)
+	req.Equal("integer", page.Properties["total"].Type)
+	req.Equal("#/components/schemas/openapi.testError", list.Responses["default"].Content["application/json"].Schema.Ref)
+
+	get := doc.Paths["/items/{item_id}"]["get"]
+	req.NotNil(get)
+	req.Len(get.Parameters, 1)
+	req.Equal("item_id", get.Parameters[0].Name)
+	req.Equal("path", get.Parameters[0].In)
+
+	post := doc.Paths["/items"]["post"]
+	req.NotNil(post.RequestBody)
+	req.NotNil(post.Responses["202"])
+	req.Nil(post.Responses["202"].Content)
+
+	item := doc.Components.Schemas["openapi.testItem"]
+	req.NotNil(item)
+	req.Equal(&Schema{Type: "string", Format: "byte"}, item.Properties["data"])
+	req.Equal(&Schema{Type: "string", Format: "date-time"}, item.Properties["created_at"])
+	req.Equal("string", item.Properties["labels"].AdditionalProperties.Type)
+	req.Equal("#/components/schemas/openapi.testItem", item.Properties["children"].Items.Ref)
+	req.NotContains(item.Properties, "Ignored")
+	req.NotContains(item.Properties, "secret")
+	req.Len(item.Properties, 5)
+}
@@ -5,6 +5,7 @@ import (
 
 	"github.com/lidofinance/dc4bc/client/types"
 	"github.com/lidofinance/dc4bc/fsm/fsm"
+	fsmrequests "github.com/lidofinance/dc4bc/fsm/types/requests"
 	"github.com/lidofinance/dc4bc/storage"
 )
 
@@ -17,16 +18,31 @@ type MessageForm struct {
 	Signature     []byte `json:"signature" validate:"attr=signature,min=1"`
 	SenderAddr    string `json:"sender"  validate:"attr=signature,min=1"`
 	RecipientAddr string `json:"recipient"`
+	// the signature checked by boards which authenticate writes
+	BoardSignature []byte `json:"board_signature"`
 }
 
 type OperationIdForm struct {
 	OperationID string `query:"operationID" json:"operationID" validate:"attr=operationID,min=32,max=512"`
 }
 
+type DeclineParticipationForm struct {
+	OperationID string `json:"operationID" validate:"attr=operationID,min=32,max=512"`
+	Reason      string `json:"reason" validate:"attr=reason,min=1,max=1024"`
+}
+
 type DkgIdForm struct {
 	DkgID string `query:"dkgID" json:"dkgID" validate:"attr=dkgID,min=32,max=512"`
 }
 
+// ProposeResharingForm proposes to reshare the key of the DKG round to the new committee,
+// all old participants deal if the dealers are not set
+type ProposeResharingForm struct {
+	DkgID     string                                               `json:"dkgID" validate:"attr=dkgID,min=32,max=512"`
+	Dealers   []string                                             `json:"dealers,omitempty"`
+	Committee fsmrequests.SignatureProposalParticipantsListRequest `json:"committee"`
+}
+
 type SignatureByIDForm struct {
 	ID    string `query:"id" json:"id" validate:"attr=id,max=512"`
 	DkgID string `query:"dkgID" json:"dkgID" validate:"attr=dkgID,min=32,max=512"`
@@ -50,10 +66,6 @@ type OperationForm struct {
 	ExtraData []byte `json:"ExtraData"`
 }
 
-type StartDKGForm struct {
-	Payload []byte
-}
-
 type ProposeSignMessageForm struct {
 	DkgID []byte `json:"dkgID"`
 	Data  []byte `json:"data"`
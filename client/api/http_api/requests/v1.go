@@ -0,0 +1,57 @@
+package requests
+
+import (
+	fsmrequests "github.com/lidofinance/dc4bc/fsm/types/requests"
+)
+
+// Forms of the v1 API, resource identifiers are bound from the path
+
+type DkgPathForm struct {
+	DkgID string `param:"dkg_id" validate:"attr=dkg_id,min=32,max=512"`
+}
+
+type OperationPathForm struct {
+	OperationID string `param:"operation_id" validate:"attr=operation_id,min=32,max=512"`
+}
+
+type DeclineOperationForm struct {
+	OperationID string `param:"operation_id" json:"-" validate:"attr=operation_id,min=32,max=512"`
+	Reason      string `json:"reason" validate:"attr=reason,min=1,max=1024"`
+}
+
+// ResharingForm proposes to reshare the key of the DKG round to the new committee,
+// all old participants deal if the dealers are not set
+type ResharingForm struct {
+	DkgID     string                                               `param:"dkg_id" json:"-" validate:"attr=dkg_id,min=32,max=512"`
+	Dealers   []string                                             `json:"dealers,omitempty"`
+	Committee fsmrequests.SignatureProposalParticipantsListRequest `json:"committee"`
+}
+
+type SignaturesForm struct {
+	DkgID   string `param:"dkg_id" validate:"attr=dkg_id,min=32,max=512"`
+	BatchID string `query:"batch_id"`
+}
+
+type SignaturePathForm struct {
+	DkgID     string `param:"dkg_id" validate:"attr=dkg_id,min=32,max=512"`
+	MessageID string `param:"message_id" validate:"attr=message_id,min=1,max=512"`
+}
+
+// ProposeBatchForm proposes to sign either the given messages or a range of baked messages
+type ProposeBatchForm struct {
+	DkgID    string            `param:"dkg_id" json:"-" validate:"attr=dkg_id,min=32,max=512"`
+	Messages map[string][]byte `json:"messages,omitempty"`
+	Range    *RangeForm        `json:"range,omitempty"`
+}
+
+type RangeForm struct {
+	Start int `json:"start"`
+	End   int `json:"end"`
+}
+
+// EventsForm filters the event stream, types is a comma separated list of event types
+type EventsForm struct {
+	Types       string `query:"types"`
+	DkgID       string `query:"dkg_id"`
+	LastEventID uint64 `query:"last_event_id"`
+}
@@ -4,3 +4,29 @@ type BaseResponse struct {
 	ErrorMessage string      `json:"error_message,omitempty"`
 	Result       interface{} `json:"result"`
 }
+
+// Responses of the v1 API
+
+type NodeInfo struct {
+	Username string `json:"username"`
+	PubKey   []byte `json:"pubkey"`
+}
+
+type DKG struct {
+	ID    string `json:"id"`
+	State string `json:"state"`
+}
+
+// Accepted is the response to a request which posts a message to the board,
+// the result is applied when the message is read back from the board
+type Accepted struct {
+	Status string `json:"status"`
+}
+
+type StateOffset struct {
+	Offset uint64 `json:"offset"`
+}
+
+type ResetState struct {
+	NewStateDBDSN string `json:"new_state_dbdsn"`
+}
@@ -3,40 +3,21 @@ package router
 import (
 	"github.com/labstack/echo/v4"
 
+	"github.com/lidofinance/dc4bc/client/api/http_api/auth"
 	"github.com/lidofinance/dc4bc/client/api/http_api/handlers"
 	"github.com/lidofinance/dc4bc/client/services"
 	"github.com/lidofinance/dc4bc/client/services/node"
 )
 
-func SetRouter(e *echo.Echo, authHandler echo.MiddlewareFunc, node node.NodeService, sp *services.ServiceProvider) {
+// SetRouter registers API routes, every route requires a token of its role, nil authenticator disables authentication
+func SetRouter(e *echo.Echo, authenticator *auth.Authenticator, node node.NodeService, sp *services.ServiceProvider) {
 	h := handlers.NewHTTPApp(node, sp)
 
-	e.GET("/getUsername", h.GetUsername)
-	e.GET("/getPubKey", h.GetPubKey)
-
-	e.POST("/sendMessage", h.SendMessage)
-	e.GET("/getOperations", h.GetOperations)
-
-	e.GET("/getSignatures", h.GetSignatures)
-	e.GET("/getBatches", h.GetBatches)
-	e.GET("/getSignatureByID", h.GetSignatureByID)
-
-	e.POST("/handleProcessedOperationJSON", h.ProcessOperation)
-	e.GET("/getOperation", h.GetOperation)
-
-	e.POST("/startDKG", h.StartDKG)
-	e.POST("/proposeSignMessage", h.ProposeSignMessage)
-	e.POST("/proposeSignBatchMessages", h.ProposeSignBatchMessages)
-	e.POST("/proposeSignBakedMessages", h.ProposeSignBakedMessages)
-	e.POST("/approveDKGParticipation", h.ApproveParticipation)
-	e.POST("/reinitDKG", h.ReInitDKG)
-
-	e.POST("/saveOffset", h.SaveStateOffset)
-	e.GET("/getOffset", h.GetStateOffset)
-
-	e.GET("/getFSMDump", h.GetFSMDump)
-	e.GET("/getFSMList", h.GetFSMList)
-
-	e.POST("/resetState", h.ResetState)
+	setV1Router(e, authenticator, h)
 
+	// Legacy routes, they are kept for older clients and served by the v1 handlers
+	for _, r := range legacyRoutes(h) {
+		e.Add(r.method, r.path, r.serve, authenticator.Middleware(r.role, legacyAuthError))
+	}
+	e.GET("/metrics", h.Metrics, authenticator.Middleware(auth.RoleReadOnly, legacyAuthError))
 }
@@ -0,0 +1,405 @@
+package router
+
+import (
+	"bytes"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strconv"
+
+	"github.com/google/uuid"
+	"github.com/labstack/echo/v4"
+
+	"github.com/lidofinance/dc4bc/client/api/http_api/auth"
+	cs "github.com/lidofinance/dc4bc/client/api/http_api/context_service"
+	"github.com/lidofinance/dc4bc/client/api/http_api/handlers"
+	req "github.com/lidofinance/dc4bc/client/api/http_api/requests"
+	resp "github.com/lidofinance/dc4bc/client/api/http_api/responses"
+	"github.com/lidofinance/dc4bc/client/types"
+	fsmtypes "github.com/lidofinance/dc4bc/fsm/types"
+)
+
+// legacyRoute is a route of the legacy API, kept for older clients. It is served by the v1 handler: the legacy
+// request is translated to the v1 one and the v1 response is wrapped to the {"result": ...} or
+// {"error_message": ...} form of the legacy API
+type legacyRoute struct {
+	method  string
+	path    string
+	handler echo.HandlerFunc
+	role    auth.Role
+	// request binds path parameters of the v1 route from the legacy request and rewrites its body if needed
+	request func(c echo.Context) error
+	// result converts the v1 response body to the legacy result, nil keeps the body
+	result func(body []byte) (interface{}, error)
+	// paginated handlers are called page by page, the body is the list of all items
+	paginated bool
+	// successful responses of streaming handlers are written as is, only errors are converted
+	stream bool
+}
+
+func legacyRoutes(h *handlers.HTTPApp) []legacyRoute {
+	return []legacyRoute{
+		{method: http.MethodGet, path: "/getUsername", handler: h.V1GetNode, role: auth.RoleReadOnly,
+			result: func(body []byte) (interface{}, error) {
+				var node resp.NodeInfo
+				err := json.Unmarshal(body, &node)
+				return node.Username, err
+			}},
+		{method: http.MethodGet, path: "/getPubKey", handler: h.V1GetNode, role: auth.RoleReadOnly,
+			result: func(body []byte) (interface{}, error) {
+				var node resp.NodeInfo
+				err := json.Unmarshal(body, &node)
+				return node.PubKey, err
+			}},
+
+		{method: http.MethodPost, path: "/sendMessage", handler: h.V1SendMessage, role: auth.RoleOperator,
+			result: legacyOK},
+		{method: http.MethodGet, path: "/getOperations", handler: h.V1ListOperations, role: auth.RoleReadOnly,
+			paginated: true, result: func(body []byte) (interface{}, error) {
+				var operations []*types.Operation
+				if err := json.Unmarshal(body, &operations); err != nil {
+					return nil, err
+				}
+				result := make(map[string]*types.Operation, len(operations))
+				for _, operation := range operations {
+					result[operation.ID] = operation
+				}
+				return result, nil
+			}},
+
+		{method: http.MethodGet, path: "/getSignatures", handler: h.V1ListSignatures, role: auth.RoleReadOnly,
+			request: legacyDkgID, paginated: true, result: func(body []byte) (interface{}, error) {
+				var signatures []fsmtypes.ReconstructedSignature
+				if err := json.Unmarshal(body, &signatures); err != nil {
+					return nil, err
+				}
+				result := make(map[string]map[string][]fsmtypes.ReconstructedSignature)
+				for _, signature := range signatures {
+					if result[signature.BatchID] == nil {
+						result[signature.BatchID] = make(map[string][]fsmtypes.ReconstructedSignature)
+					}
+					result[signature.BatchID][signature.MessageID] = append(
+						result[signature.BatchID][signature.MessageID], signature)
+				}
+				return result, nil
+			}},
+		{method: http.MethodGet, path: "/getBatches", handler: h.V1ListBatches, role: auth.RoleReadOnly,
+			request: legacyDkgID, paginated: true},
+		{method: http.MethodGet, path: "/getSignatureByID", handler: h.V1GetSignature, role: auth.RoleReadOnly,
+			request: func(c echo.Context) error {
+				form := &req.SignatureByIDForm{}
+				if err := bindLegacyForm(c, form); err != nil {
+					return err
+				}
+				setParams(c, map[string]string{"dkg_id": form.DkgID, "message_id": form.ID})
+				return nil
+			}},
+
+		{method: http.MethodPost, path: "/handleProcessedOperationJSON", handler: h.V1ProcessOperation,
+			role: auth.RoleOperator, result: legacyOK, request: func(c echo.Context) error {
+				form := &req.OperationForm{}
+				if err := bindLegacyForm(c, form); err != nil {
+					return err
+				}
+				setParams(c, map[string]string{"operation_id": form.ID})
+				return nil
+			}},
+		{method: http.MethodGet, path: "/getOperation", handler: h.V1GetOperation, role: auth.RoleReadOnly,
+			request: legacyOperationID},
+
+		{method: http.MethodPost, path: "/startDKG", handler: h.V1StartDKG, role: auth.RoleOperator, result: legacyOK},
+		{method: http.MethodPost, path: "/proposeSignMessage", handler: h.V1ProposeBatch, role: auth.RoleOperator,
+			result: legacyOK, request: func(c echo.Context) error {
+				form := &req.ProposeSignMessageForm{}
+				if err := bindLegacyForm(c, form); err != nil {
+					return err
+				}
+				return setBatch(c, form.DkgID, &req.ProposeBatchForm{
+					Messages: map[string][]byte{uuid.New().String(): form.Data},
+				})
+			}},
+		{method: http.MethodPost, path: "/proposeSignBatchMessages", handler: h.V1ProposeBatch,
+			role: auth.RoleOperator, result: legacyOK, request: func(c echo.Context) error {
+				form := &req.ProposeSignBatchMessagesForm{}
+				if err := bindLegacyForm(c, form); err != nil {
+					return err
+				}
+				return setBatch(c, form.DkgID, &req.ProposeBatchForm{Messages: form.Data})
+			}},
+		{method: http.MethodPost, path: "/proposeSignBakedMessages", handler: h.V1ProposeBatch,
+			role: auth.RoleOperator, result: legacyOK, request: func(c echo.Context) error {
+				form := &req.ProposeSignBakedMessagesForm{}
+				if err := bindLegacyForm(c, form); err != nil {
+					return err
+				}
+				return setBatch(c, form.DkgID, &req.ProposeBatchForm{
+					Range: &req.RangeForm{Start: form.RangeStart, End: form.RangeEnd},
+				})
+			}},
+		{method: http.MethodPost, path: "/approveDKGParticipation", handler: h.V1ApproveOperation,
+			role: auth.RoleOperator, result: legacyOK, request: legacyOperationID},
+		{method: http.MethodPost, path: "/declineDKGParticipation", handler: h.V1DeclineOperation,
+			role: auth.RoleOperator, result: legacyOK, request: func(c echo.Context) error {
+				form := &req.DeclineParticipationForm{}
+				if err := bindLegacyForm(c, form); err != nil {
+					return err
+				}
+				setParams(c, map[string]string{"operation_id": form.OperationID})
+				return nil
+			}},
+		{method: http.MethodPost, path: "/reinitDKG", handler: h.V1ReInitDKG, role: auth.RoleOperator,
+			result: legacyOK, request: func(c echo.Context) error {
+				form := &req.ReInitDKGForm{}
+				if err := bindLegacyForm(c, form); err != nil {
+					return err
+				}
+				setParams(c, map[string]string{"dkg_id": form.ID})
+				return nil
+			}},
+		{method: http.MethodPost, path: "/proposeShareRefresh", handler: h.V1ProposeShareRefresh,
+			role: auth.RoleOperator, result: legacyOK, request: legacyDkgID},
+		{method: http.MethodPost, path: "/proposeResharing", handler: h.V1ProposeResharing, role: auth.RoleOperator,
+			result: legacyOK, request: func(c echo.Context) error {
+				form := &req.ProposeResharingForm{}
+				if err := bindLegacyForm(c, form); err != nil {
+					return err
+				}
+				setParams(c, map[string]string{"dkg_id": form.DkgID})
+				return nil
+			}},
+
+		{method: http.MethodPost, path: "/saveOffset", handler: h.V1SaveStateOffset, role: auth.RoleAdmin,
+			result: legacyOK},
+		{method: http.MethodGet, path: "/getOffset", handler: h.V1GetStateOffset, role: auth.RoleReadOnly,
+			result: func(body []byte) (interface{}, error) {
+				var offset resp.StateOffset
+				err := json.Unmarshal(body, &offset)
+				return offset.Offset, err
+			}},
+		{method: http.MethodGet, path: "/getCheckpointDivergences", handler: h.V1GetCheckpointDivergences,
+			role: auth.RoleReadOnly},
+		{method: http.MethodGet, path: "/exportSnapshot", handler: h.V1ExportSnapshot, role: auth.RoleReadOnly},
+		{method: http.MethodGet, path: "/exportAuditJournal", handler: h.V1ListAuditEntries, role: auth.RoleReadOnly,
+			paginated: true},
+
+		{method: http.MethodGet, path: "/getFSMDump", handler: h.V1GetDKG, role: auth.RoleReadOnly,
+			request: legacyDkgID},
+		{method: http.MethodGet, path: "/getFSMList", handler: h.V1ListDKGs, role: auth.RoleReadOnly,
+			paginated: true, result: func(body []byte) (interface{}, error) {
+				var dkgs []resp.DKG
+				if err := json.Unmarshal(body, &dkgs); err != nil {
+					return nil, err
+				}
+				result := make(map[string]string, len(dkgs))
+				for _, dkg := range dkgs {
+					result[dkg.ID] = dkg.State
+				}
+				return result, nil
+			}},
+
+		{method: http.MethodPost, path: "/resetState", handler: h.V1ResetState, role: auth.RoleAdmin,
+			result: func(body []byte) (interface{}, error) {
+				var reset resp.ResetState
+				err := json.Unmarshal(body, &reset)
+				return reset.NewStateDBDSN, err
+			}},
+
+		{method: http.MethodGet, path: "/events", handler: h.V1Events, role: auth.RoleReadOnly, stream: true},
+	}
+}
+
+func legacyOK([]byte) (interface{}, error) {
+	return "ok", nil
+}
+
+func legacyDkgID(c echo.Context) error {
+	form := &req.DkgIdForm{}
+	if err := bindLegacyForm(c, form); err != nil {
+		return err
+	}
+	setParams(c, map[string]string{"dkg_id": form.DkgID})
+	return nil
+}
+
+func legacyOperationID(c echo.Context) error {
+	form := &req.OperationIdForm{}
+	if err := bindLegacyForm(c, form); err != nil {
+		return err
+	}
+	setParams(c, map[string]string{"operation_id": form.OperationID})
+	return nil
+}
+
+// bindLegacyForm binds the legacy form from the query or the body, the body is kept for the v1 handler
+func bindLegacyForm(c echo.Context, form interface{}) error {
+	var body []byte
+	if c.Request().Body != nil {
+		var err error
+		if body, err = ioutil.ReadAll(c.Request().Body); err != nil {
+			return fmt.Errorf("failed to read request body: %w", err)
+		}
+	}
+
+	setBody(c, body)
+	err := c.(*cs.ContextService).BindToRequest(form)
+	setBody(c, body)
+	return err
+}
+
+func setBody(c echo.Context, body []byte) {
+	c.Request().Body = ioutil.NopCloser(bytes.NewReader(body))
+	c.Request().ContentLength = int64(len(body))
+}
+
+func setParams(c echo.Context, params map[string]string) {
+	names := make([]string, 0, len(params))
+	values := make([]string, 0, len(params))
+	for name, value := range params {
+		names = append(names, name)
+		values = append(values, value)
+	}
+	c.SetParamNames(names...)
+	c.SetParamValues(values...)
+}
+
+// setBatch replaces the body of a legacy signing proposal with the v1 batch of the DKG round
+func setBatch(c echo.Context, dkgID []byte, batch *req.ProposeBatchForm) error {
+	body, err := json.Marshal(batch)
+	if err != nil {
+		return fmt.Errorf("failed to marshal batch: %w", err)
+	}
+	setBody(c, body)
+	setParams(c, map[string]string{"dkg_id": hex.EncodeToString(dkgID)})
+	return nil
+}
+
+func (r legacyRoute) serve(c echo.Context) error {
+	stx := c.(*cs.ContextService)
+	if r.request != nil {
+		if err := r.request(c); err != nil {
+			return stx.JsonError(http.StatusBadRequest, err)
+		}
+	}
+
+	var (
+		code int
+		body []byte
+		err  error
+	)
+	if r.paginated {
+		code, body, err = r.callPages(c)
+	} else {
+		var recorder *legacyRecorder
+		if recorder, err = r.call(c); err == nil {
+			if recorder.passThrough() {
+				return nil
+			}
+			code, body = recorder.code, recorder.body.Bytes()
+		}
+	}
+	if err != nil {
+		return err
+	}
+
+	if code >= http.StatusBadRequest {
+		var apiError cs.APIErrorResp
+		if err = json.Unmarshal(body, &apiError); err != nil || apiError.Error.Message == "" {
+			return stx.JsonError(code, errors.New(http.StatusText(code)))
+		}
+		return stx.JsonError(code, errors.New(apiError.Error.Message))
+	}
+	if r.result == nil {
+		return stx.Json(http.StatusOK, json.RawMessage(body))
+	}
+	result, err := r.result(body)
+	if err != nil {
+		return stx.JsonError(http.StatusInternalServerError, fmt.Errorf("failed to convert response: %w", err))
+	}
+	return stx.Json(http.StatusOK, result)
+}
+
+// call runs the v1 handler, its response is recorded to be written in the legacy form
+func (r legacyRoute) call(c echo.Context) (*legacyRecorder, error) {
+	response := c.Response()
+	writer := response.Writer
+	recorder := &legacyRecorder{ResponseWriter: writer, stream: r.stream}
+
+	response.Writer = recorder
+	err := r.handler(c)
+	response.Writer = writer
+
+	if !recorder.passThrough() {
+		response.Committed, response.Size, response.Status = false, 0, http.StatusOK
+	}
+	return recorder, err
+}
+
+// callPages collects all pages of a paginated v1 handler
+func (r legacyRoute) callPages(c echo.Context) (int, []byte, error) {
+	var (
+		query = c.QueryParams()
+		items = []json.RawMessage{}
+	)
+	for {
+		query.Set("offset", strconv.Itoa(len(items)))
+		query.Set("limit", strconv.Itoa(cs.MaxPageLimit))
+
+		recorder, err := r.call(c)
+		if err != nil || recorder.code >= http.StatusBadRequest {
+			return recorder.code, recorder.body.Bytes(), err
+		}
+
+		var page struct {
+			Items []json.RawMessage `json:"items"`
+			Total int               `json:"total"`
+		}
+		if err = json.Unmarshal(recorder.body.Bytes(), &page); err != nil {
+			return 0, nil, fmt.Errorf("failed to unmarshal page: %w", err)
+		}
+		items = append(items, page.Items...)
+		if len(page.Items) == 0 || len(items) >= page.Total {
+			break
+		}
+	}
+
+	body, err := json.Marshal(items)
+	return http.StatusOK, body, err
+}
+
+// legacyRecorder keeps the response of a v1 handler. Successful responses of streaming handlers are written through
+type legacyRecorder struct {
+	http.ResponseWriter
+	stream bool
+	code   int
+	body   bytes.Buffer
+}
+
+func (r *legacyRecorder) passThrough() bool {
+	return r.stream && r.code != 0 && r.code < http.StatusBadRequest
+}
+
+func (r *legacyRecorder) WriteHeader(code int) {
+	r.code = code
+	if r.passThrough() {
+		r.ResponseWriter.WriteHeader(code)
+	}
+}
+
+func (r *legacyRecorder) Write(b []byte) (int, error) {
+	if r.code == 0 {
+		r.WriteHeader(http.StatusOK)
+	}
+	if r.passThrough() {
+		return r.ResponseWriter.Write(b)
+	}
+	return r.body.Write(b)
+}
+
+func (r *legacyRecorder) Flush() {
+	if flusher, ok := r.ResponseWriter.(http.Flusher); ok && r.passThrough() {
+		flusher.Flush()
+	}
+}
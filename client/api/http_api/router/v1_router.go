@@ -0,0 +1,183 @@
+package router
+
+import (
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+
+	"github.com/lidofinance/dc4bc/client/api/http_api/auth"
+	cs "github.com/lidofinance/dc4bc/client/api/http_api/context_service"
+	"github.com/lidofinance/dc4bc/client/api/http_api/handlers"
+	"github.com/lidofinance/dc4bc/client/api/http_api/openapi"
+	req "github.com/lidofinance/dc4bc/client/api/http_api/requests"
+	resp "github.com/lidofinance/dc4bc/client/api/http_api/responses"
+	"github.com/lidofinance/dc4bc/client/modules/audit"
+	"github.com/lidofinance/dc4bc/client/modules/events"
+	"github.com/lidofinance/dc4bc/client/types"
+	"github.com/lidofinance/dc4bc/fsm/state_machines"
+	fsmtypes "github.com/lidofinance/dc4bc/fsm/types"
+	fsmrequests "github.com/lidofinance/dc4bc/fsm/types/requests"
+	"github.com/lidofinance/dc4bc/storage"
+)
+
+const (
+	V1Prefix      = "/api/v1"
+	V1OpenAPIPath = "/openapi.json"
+)
+
+type v1Route struct {
+	openapi.Operation
+	handler echo.HandlerFunc
+	role    auth.Role
+}
+
+// v1Routes describes every route of the v1 API, the same table registers the routes and generates the OpenAPI document
+func v1Routes(h *handlers.HTTPApp) []v1Route {
+	route := func(op openapi.Operation, handler echo.HandlerFunc, role auth.Role) v1Route {
+		op.Role = role.String()
+		return v1Route{Operation: op, handler: handler, role: role}
+	}
+
+	return []v1Route{
+		route(openapi.Operation{Method: http.MethodGet, Path: "/node", Tag: "node",
+			Summary: "Get the node username and public key", Response: resp.NodeInfo{}},
+			h.V1GetNode, auth.RoleReadOnly),
+
+		route(openapi.Operation{Method: http.MethodGet, Path: "/dkgs", Tag: "dkgs",
+			Summary: "List DKG rounds with their FSM states", Response: resp.DKG{}, Paginated: true},
+			h.V1ListDKGs, auth.RoleReadOnly),
+		route(openapi.Operation{Method: http.MethodPost, Path: "/dkgs", Tag: "dkgs",
+			Summary: "Propose a new DKG round", Request: fsmrequests.SignatureProposalParticipantsListRequest{},
+			Response: resp.Accepted{}, Status: http.StatusAccepted},
+			h.V1StartDKG, auth.RoleOperator),
+		route(openapi.Operation{Method: http.MethodGet, Path: "/dkgs/:dkg_id", Tag: "dkgs",
+			Summary: "Get the FSM dump of a DKG round", Response: state_machines.FSMDump{}},
+			h.V1GetDKG, auth.RoleReadOnly),
+		route(openapi.Operation{Method: http.MethodPost, Path: "/dkgs/:dkg_id/reinit", Tag: "dkgs",
+			Summary: "Reinit a DKG round from the messages of an older version", Request: req.ReInitDKGForm{},
+			Response: resp.Accepted{}, Status: http.StatusAccepted},
+			h.V1ReInitDKG, auth.RoleOperator),
+		route(openapi.Operation{Method: http.MethodPost, Path: "/dkgs/:dkg_id/refresh", Tag: "dkgs",
+			Summary:  "Propose a refresh of the key shares of a DKG round, the public key is not changed",
+			Response: resp.Accepted{}, Status: http.StatusAccepted},
+			h.V1ProposeShareRefresh, auth.RoleOperator),
+		route(openapi.Operation{Method: http.MethodPost, Path: "/dkgs/:dkg_id/reshare", Tag: "dkgs",
+			Summary:     "Propose to reshare the key of a DKG round to a new committee with a new threshold",
+			Description: "The public key is not changed. All old participants deal their key shares if dealers are not set",
+			Request:     req.ResharingForm{}, Response: resp.Accepted{}, Status: http.StatusAccepted},
+			h.V1ProposeResharing, auth.RoleOperator),
+
+		route(openapi.Operation{Method: http.MethodGet, Path: "/dkgs/:dkg_id/batches", Tag: "signatures",
+			Summary: "List IDs of signature batches of a DKG round", Response: "", Paginated: true},
+			h.V1ListBatches, auth.RoleReadOnly),
+		route(openapi.Operation{Method: http.MethodPost, Path: "/dkgs/:dkg_id/batches", Tag: "signatures",
+			Summary:     "Propose to sign a batch of messages",
+			Description: "Either messages (message ID to data) or a range of baked messages must be set",
+			Request:     req.ProposeBatchForm{}, Response: resp.Accepted{}, Status: http.StatusAccepted},
+			h.V1ProposeBatch, auth.RoleOperator),
+		route(openapi.Operation{Method: http.MethodGet, Path: "/dkgs/:dkg_id/signatures", Tag: "signatures",
+			Summary: "List reconstructed signatures of a DKG round", Response: fsmtypes.ReconstructedSignature{},
+			Paginated: true, Query: []openapi.Param{{Name: "batch_id", Type: "string", Description: "Only signatures of the batch"}}},
+			h.V1ListSignatures, auth.RoleReadOnly),
+		route(openapi.Operation{Method: http.MethodGet, Path: "/dkgs/:dkg_id/signatures/:message_id", Tag: "signatures",
+			Summary: "Get reconstructed signatures of a message", Response: []fsmtypes.ReconstructedSignature{}},
+			h.V1GetSignature, auth.RoleReadOnly),
+
+		route(openapi.Operation{Method: http.MethodGet, Path: "/operations", Tag: "operations",
+			Summary: "List pending operations ordered by creation time", Response: types.Operation{}, Paginated: true},
+			h.V1ListOperations, auth.RoleReadOnly),
+		route(openapi.Operation{Method: http.MethodGet, Path: "/operations/:operation_id", Tag: "operations",
+			Summary: "Get a pending operation", Response: types.Operation{}},
+			h.V1GetOperation, auth.RoleReadOnly),
+		route(openapi.Operation{Method: http.MethodPost, Path: "/operations/:operation_id/approve", Tag: "operations",
+			Summary: "Approve participation in the DKG round of the operation", Response: resp.Accepted{},
+			Status: http.StatusAccepted},
+			h.V1ApproveOperation, auth.RoleOperator),
+		route(openapi.Operation{Method: http.MethodPost, Path: "/operations/:operation_id/decline", Tag: "operations",
+			Summary: "Decline participation in the DKG round of the operation", Request: req.DeclineOperationForm{},
+			Response: resp.Accepted{}, Status: http.StatusAccepted},
+			h.V1DeclineOperation, auth.RoleOperator),
+		route(openapi.Operation{Method: http.MethodPost, Path: "/operations/:operation_id/result", Tag: "operations",
+			Summary: "Post the operation processed by the airgapped machine", Request: req.OperationForm{},
+			Response: resp.Accepted{}, Status: http.StatusAccepted},
+			h.V1ProcessOperation, auth.RoleOperator),
+
+		route(openapi.Operation{Method: http.MethodPost, Path: "/messages", Tag: "board",
+			Summary: "Send a message to the board", Request: req.MessageForm{}, Response: resp.Accepted{},
+			Status: http.StatusAccepted},
+			h.V1SendMessage, auth.RoleOperator),
+		route(openapi.Operation{Method: http.MethodGet, Path: "/checkpoints/divergences", Tag: "board",
+			Summary: "List checkpoints of other nodes diverging from the local log", Response: []types.CheckpointDivergence{}},
+			h.V1GetCheckpointDivergences, auth.RoleReadOnly),
+		route(openapi.Operation{Method: http.MethodGet, Path: "/snapshot", Tag: "board",
+			Summary: "Export a snapshot of the board", Response: storage.Snapshot{}},
+			h.V1ExportSnapshot, auth.RoleReadOnly),
+		route(openapi.Operation{Method: http.MethodGet, Path: "/audit", Tag: "state",
+			Summary: "List entries of the signed audit journal of node actions", Response: audit.Entry{},
+			Paginated: true},
+			h.V1ListAuditEntries, auth.RoleReadOnly),
+
+		route(openapi.Operation{Method: http.MethodGet, Path: "/state/offset", Tag: "state",
+			Summary: "Get the offset of the last board message read by the node", Response: resp.StateOffset{}},
+			h.V1GetStateOffset, auth.RoleReadOnly),
+		route(openapi.Operation{Method: http.MethodPut, Path: "/state/offset", Tag: "state",
+			Summary: "Rewind the node to the board offset", Request: req.StateOffsetForm{}, Response: resp.StateOffset{}},
+			h.V1SaveStateOffset, auth.RoleAdmin),
+		route(openapi.Operation{Method: http.MethodPost, Path: "/state/reset", Tag: "state",
+			Summary: "Replay the board into a new state ignoring the given messages", Request: req.ResetStateForm{},
+			Response: resp.ResetState{}},
+			h.V1ResetState, auth.RoleAdmin),
+
+		route(openapi.Operation{Method: http.MethodGet, Path: "/events", Tag: "events",
+			Summary: "Stream node events",
+			Description: "The response is a text/event-stream of events, a reconnecting client sets the Last-Event-ID " +
+				"header to the last received event ID to get missed events",
+			Query: []openapi.Param{
+				{Name: "types", Type: "string", Description: "Comma separated event types: operation, state_change, signature, error"},
+				{Name: "dkg_id", Type: "string", Description: "Only events of the DKG round"},
+				{Name: "last_event_id", Type: "integer", Description: "Stream events after the event, same as the Last-Event-ID header"},
+			},
+			Response: events.Event{}},
+			h.V1Events, auth.RoleReadOnly),
+	}
+}
+
+// setV1Router registers the v1 API and serves its OpenAPI document
+func setV1Router(e *echo.Echo, authenticator *auth.Authenticator, h *handlers.HTTPApp) {
+	g := e.Group(V1Prefix)
+
+	routes := v1Routes(h)
+	operations := make([]openapi.Operation, 0, len(routes)+1)
+	for _, r := range routes {
+		g.Add(r.Method, r.Path, r.handler, authenticator.Middleware(r.role, v1AuthError))
+		operations = append(operations, r.Operation)
+	}
+
+	operations = append(operations, openapi.Operation{Method: http.MethodGet, Path: V1OpenAPIPath, Tag: "node",
+		Summary: "Get the OpenAPI document of the API", Role: auth.RoleReadOnly.String(),
+		Response: map[string]interface{}{}})
+	doc := openapi.Generate(openapi.Info{
+		Title:       "dc4bc node API",
+		Version:     "v1",
+		Description: "Every route requires a bearer token of the x-dc4bc-role role or higher, if API tokens are configured",
+	}, prefixed(operations), &cs.APIErrorResp{}, &cs.Page{})
+
+	g.GET(V1OpenAPIPath, func(c echo.Context) error {
+		return c.JSON(http.StatusOK, doc)
+	}, authenticator.Middleware(auth.RoleReadOnly, v1AuthError))
+}
+
+func prefixed(operations []openapi.Operation) []openapi.Operation {
+	for i := range operations {
+		operations[i].Path = V1Prefix + operations[i].Path
+	}
+	return operations
+}
+
+func v1AuthError(c echo.Context, code int, err error) error {
+	return c.(*cs.ContextService).ApiError(code, err)
+}
+
+func legacyAuthError(c echo.Context, code int, err error) error {
+	return c.(*cs.ContextService).JsonError(code, err)
+}
@@ -4,28 +4,107 @@ type HttpApiConfig struct {
 	ListenAddr    string `mapstructure:"listen_addr"`
 	Debug         bool   `mapstructure:"enable_http_debug"`
 	EnableLogging bool   `mapstructure:"enable_http_logging"`
+	// TokensFile lists API tokens with their roles, the API refuses to start without it unless InsecureNoAuth is set
+	TokensFile     string `mapstructure:"api_tokens_file"`
+	InsecureNoAuth bool   `mapstructure:"api_insecure_no_auth"`
+	// TLSCertFile and TLSKeyFile enable HTTPS, TLSClientCAFile also requires client certificates issued by the CA
+	TLSCertFile     string `mapstructure:"api_tls_cert"`
+	TLSKeyFile      string `mapstructure:"api_tls_key"`
+	TLSClientCAFile string `mapstructure:"api_tls_client_ca"`
+}
+
+// StorageConfig holds the storage settings shared by all backends, the backend is picked by the DBDSN scheme
+// and takes its own settings from its section, see Config.StorageSections
+type StorageConfig struct {
+	DBDSN string `mapstructure:"storage_dbdsn"`
+	// Topic namespaces the node state of the storage, for the Kafka backend it is the topic as well
+	Topic string `mapstructure:"storage_topic"`
+
+	IgnoredMessages    string `mapstructure:"storage_ignore_messages"`
+	UseOffsetInsteadId bool   `mapstructure:"offsets_to_ignore_messages"`
 }
 
 type KafkaStorageConfig struct {
-	DBDSN               string `mapstructure:"storage_dbdsn"`
-	Topic               string `mapstructure:"storage_topic"`
+	// DBDSN and Topic are set from the StorageConfig
+	DBDSN               string `mapstructure:"-"`
+	Topic               string `mapstructure:"-"`
 	ConsumerGroup       string `mapstructure:"kafka_consumer_group"`
 	TlsConfig           string `mapstructure:"kafka_truststore_path"`
 	ProducerCredentials string `mapstructure:"producer_credentials"`
 	ConsumerCredentials string `mapstructure:"consumer_credentials"`
 	ReadDuration        string `mapstructure:"kafka_read_duration"`
 	Timeout             string `mapstructure:"kafka_timeout"`
+}
 
-	IgnoredMessages    string `mapstructure:"storage_ignore_messages"`
-	UseOffsetInsteadId bool   `mapstructure:"offsets_to_ignore_messages"`
+type FileStorageConfig struct {
+	LockFile string `mapstructure:"file_storage_lock_file"`
+}
+
+// SnapshotStorageConfig lists the hex public keys separated by comma, which are trusted to sign replayed snapshots
+type SnapshotStorageConfig struct {
+	TrustedKeys string `mapstructure:"snapshot_trusted_keys"`
+}
+
+type SQLiteStorageConfig struct {
+	BusyTimeout string `mapstructure:"sqlite_busy_timeout"`
+}
+
+type HTTPStorageConfig struct {
+	Timeout string `mapstructure:"http_storage_timeout"`
+}
+
+// SignerConfig picks where the node's hot key is kept: keystore (default), pkcs11 or remote
+type SignerConfig struct {
+	Type               string `mapstructure:"signer"`
+	PKCS11Module       string `mapstructure:"pkcs11_module"`
+	PKCS11TokenLabel   string `mapstructure:"pkcs11_token_label"`
+	PKCS11KeyLabel     string `mapstructure:"pkcs11_key_label"`
+	RemoteSignerSocket string `mapstructure:"remote_signer_socket"`
+	RemoteTimeout      string `mapstructure:"remote_signer_timeout"`
+
+	// PKCS11PIN is the token user PIN, it is never read from the config file or flags
+	PKCS11PIN string `mapstructure:"-"`
+}
+
+// WebhookConfig posts notifications about operations, finished DKGs and signings and timeouts,
+// empty URLs disable webhooks
+type WebhookConfig struct {
+	URLs         string `mapstructure:"webhook_urls"`
+	Events       string `mapstructure:"webhook_events"`
+	MaxRetries   int    `mapstructure:"webhook_max_retries"`
+	RetryBackoff string `mapstructure:"webhook_retry_backoff"`
+	Timeout      string `mapstructure:"webhook_timeout"`
+
+	// Secret signs webhook bodies, it is never read from the config file or flags
+	Secret string `mapstructure:"-"`
+}
+
+// LogConfig sets the level of logged messages (debug, info, warn, error) and the output format (text, json)
+type LogConfig struct {
+	Level  string `mapstructure:"log_level"`
+	Format string `mapstructure:"log_format"`
 }
 
 type Config struct {
 	HttpApiConfig *HttpApiConfig
+	LogConfig     *LogConfig
+	SignerConfig  *SignerConfig
+	WebhookConfig *WebhookConfig
 
-	KafkaStorageConfig *KafkaStorageConfig
+	StorageConfig *StorageConfig
+	// StorageSections are the config sections of the storage backends by their DBDSN schemes, every backend
+	// registers its section along with its factory
+	StorageSections map[string]interface{}
 
 	Username      string `mapstructure:"username"`
 	StateDBSN     string `mapstructure:"state_dbdsn"`
 	KeyStoreDBDSN string `mapstructure:"key_store_dbdsn"`
+	// KeyStorePassword decrypts the node keys, it is never read from the config file or flags
+	KeyStorePassword string `mapstructure:"-"`
+
+	// AuditJournalPath is the file of the signed audit journal of node actions, empty value disables the journal
+	AuditJournalPath string `mapstructure:"audit_journal_path"`
+
+	// CheckpointPeriod is how often the node posts its view of the log to the board, empty value disables checkpoints
+	CheckpointPeriod string `mapstructure:"checkpoint_period"`
 }
@@ -12,6 +12,7 @@ import (
 	"io/ioutil"
 	"log"
 	"net/http"
+	"net/http/httptest"
 	"os"
 	"path"
 	"path/filepath"
@@ -32,6 +33,8 @@ import (
 	api_responses "github.com/lidofinance/dc4bc/client/api/http_api/responses"
 	"github.com/lidofinance/dc4bc/client/config"
 	"github.com/lidofinance/dc4bc/client/modules/keystore"
+	"github.com/lidofinance/dc4bc/client/modules/logger"
+	"github.com/lidofinance/dc4bc/client/modules/signer"
 	state2 "github.com/lidofinance/dc4bc/client/modules/state"
 	oprepo "github.com/lidofinance/dc4bc/client/repositories/operation"
 	sigrepo "github.com/lidofinance/dc4bc/client/repositories/signature"
@@ -48,7 +51,7 @@ import (
 	"github.com/lidofinance/dc4bc/fsm/types/requests"
 	fsm_responses "github.com/lidofinance/dc4bc/fsm/types/responses"
 	"github.com/lidofinance/dc4bc/storage"
-	"github.com/lidofinance/dc4bc/storage/file_storage"
+	"github.com/lidofinance/dc4bc/storage/board"
 )
 
 var (
@@ -79,6 +82,8 @@ const (
 	signMsgDuration    = 8 * time.Second
 	resetStateDuration = 8 * time.Second
 	nodesStopDuration  = 5 * time.Second
+
+	testKeyStorePassword = "test_password"
 )
 
 type operationHandler func(operation *types.Operation, callback processedOperationCallback) error
@@ -111,15 +116,25 @@ type OperationsResponse struct {
 
 type processedOperationCallback func(n *nodeInstance, processedOperation *types.Operation)
 
+// savingLogger keeps logged lines, so tests can check what nodes did
 type savingLogger struct {
+	logger.Logger
 	userName string
 	logs     []string
 }
 
-func (l *savingLogger) Log(format string, args ...interface{}) {
-	str := fmt.Sprintf("[%s] %s\n", l.userName, fmt.Sprintf(format, args...))
+func newSavingLogger(userName string) *savingLogger {
+	l := &savingLogger{userName: userName}
+	l.Logger = logger.New(logger.Config{Username: userName, Level: logger.LevelDebug, Output: l})
+	return l
+}
+
+// Write is called by the embedded logger for every line, lines are never written concurrently
+func (l *savingLogger) Write(p []byte) (int, error) {
+	str := string(p)
 	l.logs = append(l.logs, str)
 	log.Print(str)
+	return len(p), nil
 }
 
 func (l *savingLogger) checkLogsWithRegexp(re *regexp.Regexp, batchSize int) (matches int) {
@@ -162,7 +177,69 @@ func (l *savingLogger) resetLogs() {
 	l.logs = make([]string, 0)
 }
 
-func initNodes(numNodes int, startingPort int, storagePath string, topic string, mnemonics []string, usernames []string) (nodes []*nodeInstance, err error) {
+// flowTestStorages create new empty storages of registered storage backends for flow tests
+var flowTestStorages = map[string]func(t *testing.T) string{
+	"file": func(t *testing.T) string {
+		return "file://" + filepath.Join(t.TempDir(), "dc4bc_storage")
+	},
+	"sqlite": func(t *testing.T) string {
+		return "sqlite://" + filepath.Join(t.TempDir(), "dc4bc_storage.db")
+	},
+	"http": func(t *testing.T) string {
+		boardLog, err := board.OpenLog(filepath.Join(t.TempDir(), "dc4bc_board_log"))
+		if err != nil {
+			t.Fatalf("failed to open board log: %v", err)
+		}
+		server := httptest.NewServer(board.NewServer(&board.Config{}, boardLog))
+		t.Cleanup(func() {
+			server.Close()
+			boardLog.Close()
+		})
+		return server.URL
+	},
+	// Kafka tests need an empty broker, e.g. from tests/docker-compose.yml, since tests share topics
+	"kafka": func(t *testing.T) string {
+		dbdsn := os.Getenv("DC4BC_TEST_KAFKA_DBDSN")
+		if dbdsn == "" {
+			t.Skip("DC4BC_TEST_KAFKA_DBDSN is not set")
+		}
+		return "kafka://" + dbdsn
+	},
+}
+
+// runFlowTest runs the flow test against every registered storage backend
+func runFlowTest(t *testing.T, test func(t *testing.T, newStorageDBDSN func() string)) {
+	for _, scheme := range services.RegisteredStorages() {
+		newStorage, ok := flowTestStorages[scheme]
+		t.Run(scheme, func(t *testing.T) {
+			if !ok {
+				t.Skipf("there is no flow test setup for %s storage", scheme)
+			}
+			test(t, func() string { return newStorage(t) })
+		})
+	}
+}
+
+// setTestStorageConfig sets the storage settings and the Kafka section of the config
+func setTestStorageConfig(cfg *config.Config, storageDBDSN, topic, consumerGroup string) *config.Config {
+	cfg.StorageConfig = &config.StorageConfig{
+		DBDSN: storageDBDSN,
+		Topic: topic,
+	}
+	cfg.StorageSections = map[string]interface{}{
+		"kafka": &config.KafkaStorageConfig{
+			ConsumerGroup:       consumerGroup,
+			TlsConfig:           os.Getenv("DC4BC_TEST_KAFKA_TRUSTSTORE"),
+			ProducerCredentials: "producer:producerpass",
+			ConsumerCredentials: "consumer:consumerpass",
+			ReadDuration:        "10s",
+			Timeout:             "10s",
+		},
+	}
+	return cfg
+}
+
+func initNodes(numNodes int, startingPort int, storageDBDSN string, topic string, mnemonics []string, usernames []string) (nodes []*nodeInstance, err error) {
 	nodes = make([]*nodeInstance, numNodes)
 	for nodeID := 0; nodeID < numNodes; nodeID++ {
 		var ctx, cancel = context.WithCancel(context.Background())
@@ -177,7 +254,7 @@ func initNodes(numNodes int, startingPort int, storagePath string, topic string,
 			return nodes, fmt.Errorf("nodeInstance %d failed to init state: %w\n", nodeID, err)
 		}
 
-		stg, err := file_storage.NewFileStorage(storagePath)
+		stg, err := services.NewStorage(setTestStorageConfig(&config.Config{}, storageDBDSN, topic, userName))
 		if err != nil {
 			return nodes, fmt.Errorf("nodeInstance %d failed to init storage: %w\n", nodeID, err)
 		}
@@ -188,27 +265,28 @@ func initNodes(numNodes int, startingPort int, storagePath string, topic string,
 		}
 
 		keyPair := keystore.NewKeyPair()
-		if err := keyStore.PutKeys(userName, keyPair); err != nil {
+		if err := keyStore.PutKeys(userName, testKeyStorePassword, keyPair); err != nil {
 			return nodes, fmt.Errorf("Failed to PutKeys:%w\n", err)
 		}
 
-		airgappedMachine, err := airgapped.NewMachine(fmt.Sprintf("/tmp/dc4bc_node_%d_airgapped_db", nodeID))
+		airgappedMachine, err := airgapped.NewMachine(fmt.Sprintf("/tmp/dc4bc_node_%d_airgapped_db", nodeID),
+			logger.NewLogger(userName))
 		if err != nil {
 			return nodes, fmt.Errorf("failed to create airgapped machine: %w", err)
 		}
 
-		logger := &savingLogger{userName: userName}
+		logger := newSavingLogger(userName)
 		cfg := config.Config{
-			Username:      userName,
-			KeyStoreDBDSN: fmt.Sprintf("/tmp/dc4bc_node_%d_key_store", nodeID),
+			Username:         userName,
+			KeyStoreDBDSN:    fmt.Sprintf("/tmp/dc4bc_node_%d_key_store", nodeID),
+			KeyStorePassword: testKeyStorePassword,
 			HttpApiConfig: &config.HttpApiConfig{
-				ListenAddr: fmt.Sprintf("localhost:%d", startingPort),
-				Debug:      false,
-			},
-			KafkaStorageConfig: &config.KafkaStorageConfig{
-				Topic: topic,
+				ListenAddr:     fmt.Sprintf("localhost:%d", startingPort),
+				Debug:          false,
+				InsecureNoAuth: true,
 			},
 		}
+		setTestStorageConfig(&cfg, storageDBDSN, topic, userName)
 
 		sigRepo := sigrepo.NewSignatureRepo(state)
 		opRepo, err := oprepo.NewOperationRepo(state, topic)
@@ -223,7 +301,11 @@ func initNodes(numNodes int, startingPort int, storagePath string, topic string,
 		sp := services.ServiceProvider{}
 		sp.SetLogger(logger)
 		sp.SetState(state)
-		sp.SetKeyStore(keyStore)
+		nodeSigner, err := signer.NewKeyStoreSigner(keyStore, userName, testKeyStorePassword)
+		if err != nil {
+			return nodes, fmt.Errorf("failed to init signer: %w", err)
+		}
+		sp.SetSigner(nodeSigner)
 		sp.SetStorage(stg)
 		sp.SetFSMService(fsmService)
 		sp.SetOperationService(opService)
@@ -245,7 +327,10 @@ func initNodes(numNodes int, startingPort int, storagePath string, topic string,
 			return nodes, err
 		}
 
-		server := http_api.NewRESTApi(&cfg, clt, &sp)
+		server, err := http_api.NewRESTApi(&cfg, clt, &sp)
+		if err != nil {
+			return nodes, fmt.Errorf("failed to init HTTP API: %w", err)
+		}
 
 		instance := &nodeInstance{
 			ctx:                   ctx,
@@ -798,6 +883,10 @@ func RemoveContents(dir, mask string) error {
 }
 
 func TestStandardFlow(t *testing.T) {
+	runFlowTest(t, testStandardFlow)
+}
+
+func testStandardFlow(t *testing.T, newStorageDBDSN func() string) {
 	_ = RemoveContents("/tmp", "dc4bc_*")
 	defer func() { _ = RemoveContents("/tmp", "dc4bc_*") }()
 
@@ -805,8 +894,8 @@ func TestStandardFlow(t *testing.T) {
 	threshold := 2
 	startingPort := 8085
 	topic := "test_topic"
-	storagePath := "/tmp/dc4bc_storage"
-	nodes, err := initNodes(numNodes, startingPort, storagePath, topic, nil, nil)
+	storageDBDSN := newStorageDBDSN()
+	nodes, err := initNodes(numNodes, startingPort, storageDBDSN, topic, nil, nil)
 	if err != nil {
 		t.Fatal(fmt.Errorf("failed to init nodes, err: %w", err))
 	}
@@ -871,6 +960,10 @@ func TestStandardFlow(t *testing.T) {
 }
 
 func TestBakedMessagesFlow(t *testing.T) {
+	runFlowTest(t, testBakedMessagesFlow)
+}
+
+func testBakedMessagesFlow(t *testing.T, newStorageDBDSN func() string) {
 	_ = RemoveContents("/tmp", "dc4bc_*")
 	defer func() { _ = RemoveContents("/tmp", "dc4bc_*") }()
 
@@ -878,8 +971,8 @@ func TestBakedMessagesFlow(t *testing.T) {
 	threshold := 2
 	startingPort := 8085
 	topic := "test_topic"
-	storagePath := "/tmp/dc4bc_storage"
-	nodes, err := initNodes(numNodes, startingPort, storagePath, topic, nil, nil)
+	storageDBDSN := newStorageDBDSN()
+	nodes, err := initNodes(numNodes, startingPort, storageDBDSN, topic, nil, nil)
 	if err != nil {
 		t.Fatal(fmt.Errorf("failed to init nodes, err: %w", err))
 	}
@@ -948,6 +1041,10 @@ func TestBakedMessagesFlow(t *testing.T) {
 }
 
 func TestStandardBatchFlow(t *testing.T) {
+	runFlowTest(t, testStandardBatchFlow)
+}
+
+func testStandardBatchFlow(t *testing.T, newStorageDBDSN func() string) {
 	_ = RemoveContents("/tmp", "dc4bc_*")
 	defer func() { _ = RemoveContents("/tmp", "dc4bc_*") }()
 
@@ -955,8 +1052,8 @@ func TestStandardBatchFlow(t *testing.T) {
 	threshold := 2
 	startingPort := 8105
 	topic := "test_topic"
-	storagePath := "/tmp/dc4bc_storage"
-	nodes, err := initNodes(numNodes, startingPort, storagePath, topic, nil, nil)
+	storageDBDSN := newStorageDBDSN()
+	nodes, err := initNodes(numNodes, startingPort, storageDBDSN, topic, nil, nil)
 	if err != nil {
 		t.Fatal(fmt.Errorf("failed to init nodes, err: %w", err))
 	}
@@ -1032,6 +1129,10 @@ func TestStandardBatchFlow(t *testing.T) {
 }
 
 func TestResetStateFlow(t *testing.T) {
+	runFlowTest(t, testResetStateFlow)
+}
+
+func testResetStateFlow(t *testing.T, newStorageDBDSN func() string) {
 	_ = RemoveContents("/tmp", "dc4bc_*")
 	defer func() { _ = RemoveContents("/tmp", "dc4bc_*") }()
 
@@ -1039,8 +1140,8 @@ func TestResetStateFlow(t *testing.T) {
 	threshold := 2
 	startingPort := 8090
 	topic := "test_topic"
-	storagePath := "/tmp/dc4bc_storage"
-	nodes, err := initNodes(numNodes, startingPort, storagePath, topic, nil, nil)
+	storageDBDSN := newStorageDBDSN()
+	nodes, err := initNodes(numNodes, startingPort, storageDBDSN, topic, nil, nil)
 	if err != nil {
 		t.Fatal(fmt.Errorf("failed to init nodes, err: %w", err))
 	}
@@ -1183,6 +1284,10 @@ func convertDKGMessageto0_1_4(orig types.ReDKG) types.ReDKG {
 
 // The test tests reinit procedure with the real kafka log received during corridor testing by engineers with dc4bc v0.1.4 release
 func TestReinitDKGFlow_authentic0_1_4(t *testing.T) {
+	runFlowTest(t, testReinitDKGFlow_authentic0_1_4)
+}
+
+func testReinitDKGFlow_authentic0_1_4(t *testing.T, newStorageDBDSN func() string) {
 	_ = RemoveContents("/tmp", "dc4bc_*")
 	defer func() { _ = RemoveContents("/tmp", "dc4bc_*") }()
 
@@ -1203,8 +1308,8 @@ func TestReinitDKGFlow_authentic0_1_4(t *testing.T) {
 	startingPort := 8095
 
 	topic := "test_topic"
-	storagePath := "/tmp/dc4bc_storage"
-	nodes, err := initNodes(numNodes, startingPort, storagePath, topic, mnemonics, usernames)
+	storageDBDSN := newStorageDBDSN()
+	nodes, err := initNodes(numNodes, startingPort, storageDBDSN, topic, mnemonics, usernames)
 	if err != nil {
 		t.Fatal(fmt.Errorf("failed to init nodes, err: %w", err))
 	}
@@ -1278,6 +1383,10 @@ func TestReinitDKGFlow_authentic0_1_4(t *testing.T) {
 }
 
 func TestReinitDKGFlow(t *testing.T) {
+	runFlowTest(t, testReinitDKGFlow)
+}
+
+func testReinitDKGFlow(t *testing.T, newStorageDBDSN func() string) {
 	_ = RemoveContents("/tmp", "dc4bc_*")
 	defer func() { _ = RemoveContents("/tmp", "dc4bc_*") }()
 
@@ -1293,8 +1402,8 @@ func TestReinitDKGFlow(t *testing.T) {
 	startingPort := 8095
 
 	topic := "test_topic"
-	storagePath := "/tmp/dc4bc_storage"
-	nodes, err := initNodes(numNodes, startingPort, storagePath, topic, mnemonics, nil)
+	storageDBDSN := newStorageDBDSN()
+	nodes, err := initNodes(numNodes, startingPort, storageDBDSN, topic, mnemonics, nil)
 	if err != nil {
 		t.Fatal(fmt.Errorf("failed to init nodes, err: %w", err))
 	}
@@ -1344,7 +1453,7 @@ func TestReinitDKGFlow(t *testing.T) {
 		node.clientCancel()
 	}
 
-	oldStorage, err := file_storage.NewFileStorage(storagePath)
+	oldStorage, err := services.NewStorage(setTestStorageConfig(&config.Config{}, storageDBDSN, topic, "old_storage"))
 	if err != nil {
 		t.Fatalf(err.Error())
 	}
@@ -1365,8 +1474,8 @@ func TestReinitDKGFlow(t *testing.T) {
 
 	waitForNodesStop()
 
-	var newStoragePath = "/tmp/dc4bc_new_storage"
-	newNodes, err := initNodes(numNodes, startingPort, newStoragePath, topic, mnemonics, nil)
+	reinitStorageDBDSN := newStorageDBDSN()
+	newNodes, err := initNodes(numNodes, startingPort, reinitStorageDBDSN, topic, mnemonics, nil)
 	if err != nil {
 		t.Fatal(fmt.Errorf("failed to init nodes, err: %w", err))
 	}
@@ -1457,6 +1566,10 @@ func TestReinitDKGFlow(t *testing.T) {
 }
 
 func TestModifiedMessageSigned(t *testing.T) {
+	runFlowTest(t, testModifiedMessageSigned)
+}
+
+func testModifiedMessageSigned(t *testing.T, newStorageDBDSN func() string) {
 	_ = RemoveContents("/tmp", "dc4bc_*")
 	defer func() { _ = RemoveContents("/tmp", "dc4bc_*") }()
 
@@ -1464,8 +1577,8 @@ func TestModifiedMessageSigned(t *testing.T) {
 	threshold := 2
 	startingPort := 8085
 	topic := "test_topic"
-	storagePath := "/tmp/dc4bc_storage"
-	nodes, err := initNodes(numNodes, startingPort, storagePath, topic, nil, nil)
+	storageDBDSN := newStorageDBDSN()
+	nodes, err := initNodes(numNodes, startingPort, storageDBDSN, topic, nil, nil)
 	if err != nil {
 		t.Fatal(fmt.Errorf("failed to init nodes, err: %w", err))
 	}
@@ -1525,6 +1638,10 @@ func TestModifiedMessageSigned(t *testing.T) {
 }
 
 func TestJunkPartialSignature(t *testing.T) {
+	runFlowTest(t, testJunkPartialSignature)
+}
+
+func testJunkPartialSignature(t *testing.T, newStorageDBDSN func() string) {
 	_ = RemoveContents("/tmp", "dc4bc_*")
 	defer func() { _ = RemoveContents("/tmp", "dc4bc_*") }()
 
@@ -1532,8 +1649,8 @@ func TestJunkPartialSignature(t *testing.T) {
 	threshold := 2
 	startingPort := 8085
 	topic := "test_topic"
-	storagePath := "/tmp/dc4bc_storage"
-	nodes, err := initNodes(numNodes, startingPort, storagePath, topic, nil, nil)
+	storageDBDSN := newStorageDBDSN()
+	nodes, err := initNodes(numNodes, startingPort, storageDBDSN, topic, nil, nil)
 	if err != nil {
 		t.Fatal(fmt.Errorf("failed to init nodes, err: %w", err))
 	}
@@ -1603,14 +1720,18 @@ func TestJunkPartialSignature(t *testing.T) {
 }
 
 func TestSignWithDifferentDKG(t *testing.T) {
+	runFlowTest(t, testSignWithDifferentDKG)
+}
+
+func testSignWithDifferentDKG(t *testing.T, newStorageDBDSN func() string) {
 	_ = RemoveContents("/tmp", "dc4bc_*")
 	defer func() { _ = RemoveContents("/tmp", "dc4bc_*") }()
 
 	numNodes := 3
 	startingPort := 8085
 	topic := "test_topic"
-	storagePath := "/tmp/dc4bc_storage"
-	nodes, err := initNodes(numNodes, startingPort, storagePath, topic, nil, nil)
+	storageDBDSN := newStorageDBDSN()
+	nodes, err := initNodes(numNodes, startingPort, storageDBDSN, topic, nil, nil)
 	if err != nil {
 		t.Fatal(fmt.Errorf("failed to init nodes, err: %w", err))
 	}
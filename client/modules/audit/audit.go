@@ -0,0 +1,278 @@
+package audit
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/ed25519"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/lidofinance/dc4bc/client/modules/signer"
+)
+
+// Audited actions
+const (
+	ActionAPICall            = "api_call"
+	ActionOperationCreated   = "operation_created"
+	ActionOperationProcessed = "operation_processed"
+	ActionOperationDeleted   = "operation_deleted"
+	ActionOffsetChanged      = "offset_changed"
+	ActionStateReset         = "state_reset"
+	ActionMessageIgnored     = "message_ignored"
+)
+
+const (
+	// ActorNode is the actor of actions taken by the node itself
+	ActorNode = "node"
+	// ActorAnonymous is the actor of API calls made without a token, API calls with a token are recorded with its name
+	ActorAnonymous = "anonymous"
+)
+
+// signingDomain prefixes signed entry hashes, the journal is signed by the key which signs board messages
+const signingDomain = "dc4bc-audit-v1"
+
+var ErrCorrupted = errors.New("audit journal is corrupted")
+
+// Entry is a record of the journal. Every entry is hash-chained to its predecessor and signed by the node's key,
+// so an entry can't be changed, removed or inserted without breaking the chain or the signatures
+type Entry struct {
+	Seq        uint64          `json:"seq"`
+	Time       time.Time       `json:"time"`
+	Actor      string          `json:"actor"`
+	Action     string          `json:"action"`
+	DkgRoundID string          `json:"dkg_round_id,omitempty"`
+	Details    json.RawMessage `json:"details,omitempty"`
+	PrevHash   []byte          `json:"prev_hash"`
+	Hash       []byte          `json:"hash"`
+	Signature  []byte          `json:"signature"`
+}
+
+// ChainHash returns a hash of the previous entry hash and the entry itself
+func (e *Entry) ChainHash() []byte {
+	h := sha256.New()
+	writeField := func(field []byte) {
+		lenBz := make([]byte, 8)
+		binary.BigEndian.PutUint64(lenBz, uint64(len(field)))
+		h.Write(lenBz)
+		h.Write(field)
+	}
+
+	seqBz := make([]byte, 8)
+	binary.BigEndian.PutUint64(seqBz, e.Seq)
+
+	writeField(e.PrevHash)
+	writeField(seqBz)
+	writeField([]byte(e.Time.UTC().Format(time.RFC3339Nano)))
+	writeField([]byte(e.Actor))
+	writeField([]byte(e.Action))
+	writeField([]byte(e.DkgRoundID))
+	writeField(e.Details)
+
+	return h.Sum(nil)
+}
+
+// SigningPayload returns the bytes signed for the entry hash
+func SigningPayload(hash []byte) []byte {
+	return append([]byte(signingDomain), hash...)
+}
+
+// Verify checks that entries form an unbroken chain starting from the first entry of a journal
+// and that every entry is signed by the key
+func Verify(entries []Entry, pubKey ed25519.PublicKey) error {
+	var prevHash []byte
+	for i, e := range entries {
+		if err := verifyLink(&e, uint64(i), prevHash); err != nil {
+			return err
+		}
+		if !ed25519.Verify(pubKey, SigningPayload(e.Hash), e.Signature) {
+			return fmt.Errorf("%w: invalid signature of entry %d", ErrCorrupted, e.Seq)
+		}
+		prevHash = e.Hash
+	}
+	return nil
+}
+
+func verifyLink(e *Entry, seq uint64, prevHash []byte) error {
+	if e.Seq != seq {
+		return fmt.Errorf("%w: expected entry %d, got %d", ErrCorrupted, seq, e.Seq)
+	}
+	if !bytes.Equal(e.PrevHash, prevHash) || !bytes.Equal(e.Hash, e.ChainHash()) {
+		return fmt.Errorf("%w: broken hash chain at entry %d", ErrCorrupted, e.Seq)
+	}
+	return nil
+}
+
+// ReadEntries reads a journal stored as JSON lines, e.g. a journal file or its export
+func ReadEntries(r io.Reader) ([]Entry, error) {
+	entries, _, err := readEntries(r)
+	return entries, err
+}
+
+// readEntries also returns the size of complete lines, a partial trailing line is an unfinished write
+func readEntries(r io.Reader) ([]Entry, int64, error) {
+	var (
+		entries []Entry
+		size    int64
+	)
+	reader := bufio.NewReader(r)
+	for {
+		row, err := reader.ReadBytes('\n')
+		if errors.Is(err, io.EOF) {
+			return entries, size, nil
+		}
+		if err != nil {
+			return nil, 0, fmt.Errorf("failed to read an audit journal: %w", err)
+		}
+
+		var e Entry
+		if err = json.Unmarshal(row, &e); err != nil {
+			return nil, 0, fmt.Errorf("failed to unmarshal an audit entry %d: %w", len(entries), err)
+		}
+		entries = append(entries, e)
+		size += int64(len(row))
+	}
+}
+
+// WriteEntries writes entries as JSON lines, the same format as the journal file
+func WriteEntries(w io.Writer, entries []Entry) error {
+	for _, e := range entries {
+		row, err := json.Marshal(e)
+		if err != nil {
+			return fmt.Errorf("failed to marshal an audit entry: %w", err)
+		}
+		if _, err = w.Write(append(row, '\n')); err != nil {
+			return fmt.Errorf("failed to write an audit entry: %w", err)
+		}
+	}
+	return nil
+}
+
+// Journal is an append-only local audit journal of node actions, stored as JSON lines.
+// A nil *Journal is a disabled journal, Record is a no-op for it
+type Journal struct {
+	sync.Mutex
+
+	file     *os.File
+	size     int64
+	signer   signer.Signer
+	nextSeq  uint64
+	lastHash []byte
+}
+
+// Open opens (or creates) the journal file and verifies it. A torn trailing entry is truncated,
+// a journal with a broken chain or signed by another key is not opened
+func Open(filename string, s signer.Signer) (*Journal, error) {
+	f, err := os.OpenFile(filename, os.O_CREATE|os.O_RDWR, 0600)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open an audit journal file: %w", err)
+	}
+
+	j := &Journal{file: f, signer: s}
+	if err = j.load(); err != nil {
+		f.Close()
+		return nil, err
+	}
+
+	return j, nil
+}
+
+func (j *Journal) load() error {
+	entries, size, err := readEntries(j.file)
+	if err != nil {
+		return err
+	}
+	if err = Verify(entries, j.signer.PubKey()); err != nil {
+		return err
+	}
+	if len(entries) > 0 {
+		j.nextSeq = uint64(len(entries))
+		j.lastHash = entries[len(entries)-1].Hash
+	}
+
+	if err = j.file.Truncate(size); err != nil {
+		return fmt.Errorf("failed to truncate an audit journal file: %w", err)
+	}
+	if _, err = j.file.Seek(size, io.SeekStart); err != nil {
+		return fmt.Errorf("failed to seek to the end of an audit journal file: %w", err)
+	}
+	j.size = size
+
+	return nil
+}
+
+// Record appends a signed entry, details are marshalled to JSON
+func (j *Journal) Record(actor, action, dkgRoundID string, details interface{}) error {
+	if j == nil {
+		return nil
+	}
+
+	var (
+		detailsBz []byte
+		err       error
+	)
+	if details != nil {
+		if detailsBz, err = json.Marshal(details); err != nil {
+			return fmt.Errorf("failed to marshal audit details: %w", err)
+		}
+	}
+
+	j.Lock()
+	defer j.Unlock()
+
+	e := Entry{
+		Seq:        j.nextSeq,
+		Time:       time.Now().UTC(),
+		Actor:      actor,
+		Action:     action,
+		DkgRoundID: dkgRoundID,
+		Details:    detailsBz,
+		PrevHash:   j.lastHash,
+	}
+	e.Hash = e.ChainHash()
+	if e.Signature, err = j.signer.Sign(SigningPayload(e.Hash)); err != nil {
+		return fmt.Errorf("failed to sign an audit entry: %w", err)
+	}
+
+	row, err := json.Marshal(e)
+	if err != nil {
+		return fmt.Errorf("failed to marshal an audit entry: %w", err)
+	}
+	row = append(row, '\n')
+	if _, err = j.file.Write(row); err != nil {
+		// a partial write is truncated, so the next entry starts on a fresh line
+		_ = j.file.Truncate(j.size)
+		_, _ = j.file.Seek(j.size, io.SeekStart)
+		return fmt.Errorf("failed to write an audit entry: %w", err)
+	}
+	if err = j.file.Sync(); err != nil {
+		return fmt.Errorf("failed to sync an audit journal file: %w", err)
+	}
+
+	j.size += int64(len(row))
+	j.nextSeq++
+	j.lastHash = e.Hash
+	return nil
+}
+
+// Entries reads all entries of the journal
+func (j *Journal) Entries() ([]Entry, error) {
+	j.Lock()
+	defer j.Unlock()
+
+	entries, _, err := readEntries(io.NewSectionReader(j.file, 0, j.size))
+	return entries, err
+}
+
+func (j *Journal) Close() error {
+	if j == nil {
+		return nil
+	}
+	return j.file.Close()
+}
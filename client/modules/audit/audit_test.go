@@ -0,0 +1,72 @@
+package audit
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/lidofinance/dc4bc/client/modules/keystore"
+	"github.com/lidofinance/dc4bc/client/modules/signer"
+)
+
+func TestJournal(t *testing.T) {
+	req := require.New(t)
+	filename := filepath.Join(t.TempDir(), "journal")
+	s := signer.NewKeyPairSigner(keystore.NewKeyPair())
+
+	j, err := Open(filename, s)
+	req.NoError(err)
+	req.NoError(j.Record("operator", ActionAPICall, "", map[string]interface{}{"path": "/saveOffset", "status": 200}))
+	req.NoError(j.Record(ActorNode, ActionOffsetChanged, "", map[string]uint64{"offset": 10}))
+	req.NoError(j.Close())
+
+	// a torn trailing entry is truncated and the journal is continued
+	f, err := os.OpenFile(filename, os.O_APPEND|os.O_WRONLY, 0600)
+	req.NoError(err)
+	_, err = f.WriteString(`{"seq":2,"act`)
+	req.NoError(err)
+	req.NoError(f.Close())
+
+	j, err = Open(filename, s)
+	req.NoError(err)
+	req.NoError(j.Record(ActorNode, ActionOperationCreated, "dkg", map[string]string{"operation_id": "op"}))
+	entries, err := j.Entries()
+	req.NoError(err)
+	req.NoError(j.Close())
+
+	req.Len(entries, 3)
+	req.Equal(uint64(2), entries[2].Seq)
+	req.Equal("dkg", entries[2].DkgRoundID)
+	req.NoError(Verify(entries, s.PubKey()))
+
+	var buf bytes.Buffer
+	req.NoError(WriteEntries(&buf, entries))
+	exported, err := ReadEntries(&buf)
+	req.NoError(err)
+	req.NoError(Verify(exported, s.PubKey()))
+
+	// the journal is signed by another key
+	req.ErrorIs(Verify(entries, signer.NewKeyPairSigner(keystore.NewKeyPair()).PubKey()), ErrCorrupted)
+	_, err = Open(filename, signer.NewKeyPairSigner(keystore.NewKeyPair()))
+	req.ErrorIs(err, ErrCorrupted)
+
+	// an entry is changed
+	changed := append([]Entry{}, entries...)
+	changed[1].Actor = "someone"
+	req.ErrorIs(Verify(changed, s.PubKey()), ErrCorrupted)
+
+	// an entry is removed
+	req.ErrorIs(Verify([]Entry{entries[0], entries[2]}, s.PubKey()), ErrCorrupted)
+
+	// a signature of the bare hash, e.g. made for another purpose, is not a signature of the entry
+	resigned := append([]Entry{}, entries...)
+	resigned[0].Signature, err = s.Sign(resigned[0].Hash)
+	req.NoError(err)
+	req.ErrorIs(Verify(resigned, s.PubKey()), ErrCorrupted)
+
+	var disabled *Journal
+	req.NoError(disabled.Record(ActorNode, ActionStateReset, "", nil))
+}
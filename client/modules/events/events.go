@@ -0,0 +1,204 @@
+package events
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Type is a kind of node activity
+type Type string
+
+const (
+	// TypeOperation is a new operation waiting to be processed by the airgapped machine
+	TypeOperation Type = "operation"
+	// TypeStateChange is a transition of a DKG round FSM
+	TypeStateChange Type = "state_change"
+	// TypeSignature is a reconstructed signature received from the board
+	TypeSignature Type = "signature"
+	// TypeError is a board message the node failed to process
+	TypeError Type = "error"
+)
+
+var knownTypes = map[Type]struct{}{
+	TypeOperation:   {},
+	TypeStateChange: {},
+	TypeSignature:   {},
+	TypeError:       {},
+}
+
+const (
+	// DefaultHistorySize is how many recent events are kept to be replayed to reconnected subscribers
+	DefaultHistorySize = 1024
+
+	subscriberBufferSize = 256
+)
+
+type Event struct {
+	ID         uint64    `json:"id"`
+	Type       Type      `json:"type"`
+	Time       time.Time `json:"time"`
+	DkgRoundID string    `json:"dkg_round_id,omitempty"`
+	// Offset is the offset of the board message that caused the event
+	Offset uint64          `json:"offset"`
+	Data   json.RawMessage `json:"data,omitempty"`
+}
+
+type OperationEvent struct {
+	OperationID string    `json:"operation_id"`
+	Type        string    `json:"type"`
+	CreatedAt   time.Time `json:"created_at"`
+}
+
+type StateChangeEvent struct {
+	Event string `json:"event"`
+	From  string `json:"from"`
+	To    string `json:"to"`
+}
+
+type SignatureEvent struct {
+	BatchID   string `json:"batch_id"`
+	MessageID string `json:"message_id"`
+	Username  string `json:"username"`
+}
+
+type ErrorEvent struct {
+	Event string `json:"event"`
+	Error string `json:"error"`
+}
+
+// Filter selects events of a subscription, zero value matches every event
+type Filter struct {
+	Types      map[Type]struct{}
+	DkgRoundID string
+}
+
+// ParseFilter parses a comma separated list of event types
+func ParseFilter(types, dkgRoundID string) (Filter, error) {
+	filter := Filter{DkgRoundID: dkgRoundID}
+	if types == "" {
+		return filter, nil
+	}
+
+	filter.Types = map[Type]struct{}{}
+	for _, t := range strings.Split(types, ",") {
+		t = strings.TrimSpace(t)
+		if _, ok := knownTypes[Type(t)]; !ok {
+			return Filter{}, fmt.Errorf("unknown event type %s", t)
+		}
+		filter.Types[Type(t)] = struct{}{}
+	}
+	return filter, nil
+}
+
+func (f Filter) Match(e Event) bool {
+	if f.DkgRoundID != "" && f.DkgRoundID != e.DkgRoundID {
+		return false
+	}
+	if f.Types != nil {
+		if _, ok := f.Types[e.Type]; !ok {
+			return false
+		}
+	}
+	return true
+}
+
+type subscription struct {
+	ch     chan Event
+	filter Filter
+}
+
+// Bus fans node events out to subscribers and keeps a short history,
+// so a subscriber reconnecting with the last seen event ID does not miss events
+type Bus struct {
+	mu          sync.Mutex
+	lastID      uint64
+	history     []Event
+	historySize int
+	subscribers map[*subscription]struct{}
+}
+
+func NewBus(historySize int) *Bus {
+	return &Bus{
+		historySize: historySize,
+		subscribers: map[*subscription]struct{}{},
+	}
+}
+
+// Publish sends an event to subscribers, a nil Bus drops events.
+// A subscriber which does not keep up is unsubscribed, its channel is closed.
+func (b *Bus) Publish(t Type, dkgRoundID string, offset uint64, data interface{}) {
+	if b == nil {
+		return
+	}
+
+	bz, err := json.Marshal(data)
+	if err != nil {
+		bz, _ = json.Marshal(ErrorEvent{Error: fmt.Sprintf("failed to marshal %s event: %v", t, err)})
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.lastID++
+	e := Event{
+		ID:         b.lastID,
+		Type:       t,
+		Time:       time.Now().UTC(),
+		DkgRoundID: dkgRoundID,
+		Offset:     offset,
+		Data:       bz,
+	}
+
+	b.history = append(b.history, e)
+	if len(b.history) > b.historySize {
+		b.history = b.history[len(b.history)-b.historySize:]
+	}
+
+	for sub := range b.subscribers {
+		if !sub.filter.Match(e) {
+			continue
+		}
+		select {
+		case sub.ch <- e:
+		default:
+			delete(b.subscribers, sub)
+			close(sub.ch)
+		}
+	}
+}
+
+// Subscribe returns a channel of events published after the event with lastID, events still kept
+// in the history are replayed first. The returned function cancels the subscription.
+func (b *Bus) Subscribe(lastID uint64, filter Filter) (<-chan Event, func()) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	var replay []Event
+	for _, e := range b.history {
+		if e.ID > lastID && filter.Match(e) {
+			replay = append(replay, e)
+		}
+	}
+
+	sub := &subscription{
+		ch:     make(chan Event, subscriberBufferSize+len(replay)),
+		filter: filter,
+	}
+	for _, e := range replay {
+		sub.ch <- e
+	}
+	b.subscribers[sub] = struct{}{}
+
+	return sub.ch, func() {
+		b.mu.Lock()
+		defer b.mu.Unlock()
+
+		if _, ok := b.subscribers[sub]; ok {
+			delete(b.subscribers, sub)
+			close(sub.ch)
+		}
+	}
+}
@@ -0,0 +1,111 @@
+package events
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestBus(t *testing.T) {
+	req := require.New(t)
+	bus := NewBus(3)
+
+	all, cancelAll := bus.Subscribe(0, Filter{})
+	defer cancelAll()
+	filter, err := ParseFilter("operation,error", "dkg_1")
+	req.NoError(err)
+	filtered, cancelFiltered := bus.Subscribe(0, filter)
+	defer cancelFiltered()
+
+	bus.Publish(TypeOperation, "dkg_1", 1, OperationEvent{OperationID: "op_1"})
+	bus.Publish(TypeOperation, "dkg_2", 2, OperationEvent{OperationID: "op_2"})
+	bus.Publish(TypeStateChange, "dkg_1", 3, StateChangeEvent{From: "a", To: "b"})
+	bus.Publish(TypeError, "dkg_1", 4, ErrorEvent{Error: "failed"})
+
+	for id := uint64(1); id <= 4; id++ {
+		e := <-all
+		req.Equal(id, e.ID)
+	}
+
+	e := <-filtered
+	req.Equal(uint64(1), e.ID)
+	var op OperationEvent
+	req.NoError(json.Unmarshal(e.Data, &op))
+	req.Equal("op_1", op.OperationID)
+	e = <-filtered
+	req.Equal(uint64(4), e.ID)
+	req.Equal(TypeError, e.Type)
+
+	// a reconnected subscriber gets events it missed, if they are still in the history
+	replay, cancelReplay := bus.Subscribe(2, Filter{})
+	req.Equal(uint64(3), (<-replay).ID)
+	req.Equal(uint64(4), (<-replay).ID)
+	cancelReplay()
+	_, ok := <-replay
+	req.False(ok)
+
+	_, err = ParseFilter("unknown", "")
+	req.Error(err)
+
+	var nilBus *Bus
+	nilBus.Publish(TypeError, "", 0, nil)
+}
+
+func TestBus_SlowSubscriber(t *testing.T) {
+	req := require.New(t)
+	bus := NewBus(DefaultHistorySize)
+
+	slow, cancel := bus.Subscribe(0, Filter{})
+	defer cancel()
+	for i := 0; i < subscriberBufferSize+1; i++ {
+		bus.Publish(TypeSignature, "dkg", uint64(i), SignatureEvent{})
+	}
+
+	received := 0
+	for range slow {
+		received++
+	}
+	req.Equal(subscriberBufferSize, received)
+}
+
+func TestServeSSE(t *testing.T) {
+	req := require.New(t)
+	bus := NewBus(DefaultHistorySize)
+	bus.Publish(TypeOperation, "dkg", 1, OperationEvent{OperationID: "op_1"})
+	bus.Publish(TypeOperation, "dkg", 2, OperationEvent{OperationID: "op_2"})
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_ = bus.ServeSSE(r.Context(), w, 1, Filter{})
+	}))
+	defer server.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	r, err := http.NewRequestWithContext(ctx, http.MethodGet, server.URL, nil)
+	req.NoError(err)
+	resp, err := http.DefaultClient.Do(r)
+	req.NoError(err)
+	defer resp.Body.Close()
+	req.Equal("text/event-stream", resp.Header.Get("Content-Type"))
+
+	errDone := errors.New("done")
+	var received []Event
+	err = ReadSSE(resp.Body, func(e Event) error {
+		received = append(received, e)
+		if len(received) == 1 {
+			bus.Publish(TypeError, "dkg", 3, ErrorEvent{Error: "failed"})
+			return nil
+		}
+		return errDone
+	})
+	req.ErrorIs(err, errDone)
+	req.Len(received, 2)
+	req.Equal(uint64(2), received[0].ID)
+	req.Equal(TypeError, received[1].Type)
+	req.Equal(uint64(3), received[1].Offset)
+}
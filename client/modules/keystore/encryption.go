@@ -0,0 +1,134 @@
+package keystore
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/ed25519"
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"io"
+
+	"golang.org/x/crypto/scrypt"
+)
+
+const (
+	// encryptedKeyPairVersion is the current version of the encrypted key pair format
+	encryptedKeyPairVersion = 1
+
+	kdfScrypt = "scrypt"
+
+	scryptN       = 1 << 16
+	scryptR       = 8
+	scryptP       = 1
+	scryptKeySize = 32
+	saltSize      = 32
+
+	// limits of the scrypt parameters read from a keystore, so a corrupted keystore can not exhaust the node's
+	// memory and CPU
+	maxScryptN = 1 << 20
+	maxScryptR = 32
+	maxScryptP = 16
+)
+
+var ErrWrongPassword = errors.New("wrong keystore password")
+
+// encryptedKeyPair is a key pair with the private key encrypted by AES-GCM with a scrypt-derived key.
+// The public key is kept in the clear and authenticated as additional data.
+type encryptedKeyPair struct {
+	Version    int               `json:"version"`
+	Pub        ed25519.PublicKey `json:"pub"`
+	KDF        string            `json:"kdf"`
+	Salt       []byte            `json:"salt"`
+	N          int               `json:"n"`
+	R          int               `json:"r"`
+	P          int               `json:"p"`
+	Nonce      []byte            `json:"nonce"`
+	Ciphertext []byte            `json:"ciphertext"`
+}
+
+func newGCM(password string, salt []byte, n, r, p int) (cipher.AEAD, error) {
+	derivedKey, err := scrypt.Key([]byte(password), salt, n, r, p, scryptKeySize)
+	if err != nil {
+		return nil, fmt.Errorf("failed to derive key: %w", err)
+	}
+
+	c, err := aes.NewCipher(derivedKey)
+	if err != nil {
+		return nil, fmt.Errorf("failed to init cipher: %w", err)
+	}
+
+	return cipher.NewGCM(c)
+}
+
+// validateScryptParams checks that N is a power of two and all parameters are within the limits
+func validateScryptParams(n, r, p int) error {
+	if n <= 1 || n > maxScryptN || n&(n-1) != 0 {
+		return fmt.Errorf("invalid scrypt N %d: a power of two up to %d is expected", n, maxScryptN)
+	}
+	if r < 1 || r > maxScryptR {
+		return fmt.Errorf("invalid scrypt r %d: from 1 to %d is expected", r, maxScryptR)
+	}
+	if p < 1 || p > maxScryptP {
+		return fmt.Errorf("invalid scrypt p %d: from 1 to %d is expected", p, maxScryptP)
+	}
+	return nil
+}
+
+func encryptKeyPair(keyPair *KeyPair, password string) (*encryptedKeyPair, error) {
+	encrypted := &encryptedKeyPair{
+		Version: encryptedKeyPairVersion,
+		Pub:     keyPair.Pub,
+		KDF:     kdfScrypt,
+		Salt:    make([]byte, saltSize),
+		N:       scryptN,
+		R:       scryptR,
+		P:       scryptP,
+	}
+	if _, err := io.ReadFull(rand.Reader, encrypted.Salt); err != nil {
+		return nil, fmt.Errorf("failed to generate salt: %w", err)
+	}
+
+	gcm, err := newGCM(password, encrypted.Salt, encrypted.N, encrypted.R, encrypted.P)
+	if err != nil {
+		return nil, err
+	}
+
+	encrypted.Nonce = make([]byte, gcm.NonceSize())
+	if _, err = io.ReadFull(rand.Reader, encrypted.Nonce); err != nil {
+		return nil, fmt.Errorf("failed to generate nonce: %w", err)
+	}
+	encrypted.Ciphertext = gcm.Seal(nil, encrypted.Nonce, keyPair.Priv, encrypted.Pub)
+
+	return encrypted, nil
+}
+
+func decryptKeyPair(encrypted *encryptedKeyPair, password string) (*KeyPair, error) {
+	if encrypted.Version != encryptedKeyPairVersion {
+		return nil, fmt.Errorf("unsupported key pair version %d", encrypted.Version)
+	}
+	if encrypted.KDF != kdfScrypt {
+		return nil, fmt.Errorf("unsupported key derivation function %s", encrypted.KDF)
+	}
+	if err := validateScryptParams(encrypted.N, encrypted.R, encrypted.P); err != nil {
+		return nil, err
+	}
+
+	gcm, err := newGCM(password, encrypted.Salt, encrypted.N, encrypted.R, encrypted.P)
+	if err != nil {
+		return nil, err
+	}
+	if len(encrypted.Nonce) != gcm.NonceSize() {
+		return nil, fmt.Errorf("invalid nonce length %d", len(encrypted.Nonce))
+	}
+
+	priv, err := gcm.Open(nil, encrypted.Nonce, encrypted.Ciphertext, encrypted.Pub)
+	if err != nil {
+		return nil, ErrWrongPassword
+	}
+	if len(priv) != ed25519.PrivateKeySize {
+		return nil, fmt.Errorf("invalid private key length %d", len(priv))
+	}
+
+	return &KeyPair{Pub: encrypted.Pub, Priv: priv}, nil
+}
@@ -4,24 +4,30 @@ import (
 	"crypto/ed25519"
 	"encoding/hex"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"sort"
 
 	"github.com/syndtr/goleveldb/leveldb"
-
-	"github.com/lidofinance/dc4bc/client/repositories/operation"
 )
 
 const (
+	// secretsKey keeps plaintext key pairs of the legacy keystore format
 	secretsKey = "secrets"
+	// encryptedSecretsKey keeps encrypted key pairs, see encryptedKeyPair
+	encryptedSecretsKey = "encrypted_secrets"
 )
 
+var ErrPlaintextKeyStore = errors.New("keystore keeps plaintext keys, encrypt it with `dc4bc_d migrate_keystore`")
+
 type KeyStore interface {
-	PutKeys(username string, keyPair *KeyPair) error
+	PutKeys(username, password string, keyPair *KeyPair) error
 	LoadKeys(userName, password string) (*KeyPair, error)
+	ChangePassword(userName, oldPassword, newPassword string) error
+	MigratePlaintextKeys(password string) ([]string, error)
 }
 
-// LevelDBKeyStore is a temporary solution for keeping hot node keys.
-// The target state is an encrypted storage with password authentication.
+// LevelDBKeyStore keeps hot node keys encrypted with a password
 type LevelDBKeyStore struct {
 	keystoreDb *leveldb.DB
 }
@@ -36,58 +42,139 @@ func NewLevelDBKeyStore(username, keystorePath string) (KeyStore, error) {
 		keystoreDb: db,
 	}
 
-	if _, err := keystore.keystoreDb.Get([]byte(secretsKey), nil); err != nil {
-		if err := keystore.initJsonKey(secretsKey, map[string]*KeyPair{}); err != nil {
-			return nil, fmt.Errorf("failed to init %s storage: %w", operation.OperationsKey, err)
-		}
+	if err := keystore.initJsonKey(encryptedSecretsKey, map[string]*encryptedKeyPair{}); err != nil {
+		return nil, fmt.Errorf("failed to init %s storage: %w", encryptedSecretsKey, err)
 	}
 
 	return keystore, nil
 }
 
-func (s *LevelDBKeyStore) PutKeys(username string, keyPair *KeyPair) error {
-	bz, err := s.keystoreDb.Get([]byte(secretsKey), nil)
+// PutKeys encrypts the key pair with the password and saves it
+func (s *LevelDBKeyStore) PutKeys(username, password string, keyPair *KeyPair) error {
+	keyPairs, err := s.getEncryptedKeyPairs()
 	if err != nil {
-		return fmt.Errorf("failed to read keystore: %w", err)
+		return err
 	}
 
-	var keyPairs = map[string]*KeyPair{}
+	if keyPairs[username], err = encryptKeyPair(keyPair, password); err != nil {
+		return fmt.Errorf("failed to encrypt key pair: %w", err)
+	}
+
+	return s.putEncryptedKeyPairs(keyPairs, new(leveldb.Batch))
+}
+
+// LoadKeys decrypts the key pair of the user with the password
+func (s *LevelDBKeyStore) LoadKeys(userName, password string) (*KeyPair, error) {
+	keyPairs, err := s.getEncryptedKeyPairs()
+	if err != nil {
+		return nil, err
+	}
+
+	encrypted, ok := keyPairs[userName]
+	if !ok {
+		plaintextKeyPairs, err := s.getPlaintextKeyPairs()
+		if err != nil {
+			return nil, err
+		}
+		if _, ok = plaintextKeyPairs[userName]; ok {
+			return nil, ErrPlaintextKeyStore
+		}
+		return nil, fmt.Errorf("no key pair found for user %s", userName)
+	}
+
+	return decryptKeyPair(encrypted, password)
+}
+
+// ChangePassword re-encrypts the key pair of the user with the new password
+func (s *LevelDBKeyStore) ChangePassword(userName, oldPassword, newPassword string) error {
+	keyPair, err := s.LoadKeys(userName, oldPassword)
+	if err != nil {
+		return err
+	}
+
+	return s.PutKeys(userName, newPassword, keyPair)
+}
+
+// MigratePlaintextKeys encrypts all key pairs of the legacy plaintext format with the password
+// and removes the plaintext keys, it returns usernames of migrated key pairs
+func (s *LevelDBKeyStore) MigratePlaintextKeys(password string) ([]string, error) {
+	plaintextKeyPairs, err := s.getPlaintextKeyPairs()
+	if err != nil {
+		return nil, err
+	}
+
+	keyPairs, err := s.getEncryptedKeyPairs()
+	if err != nil {
+		return nil, err
+	}
+
+	usernames := make([]string, 0, len(plaintextKeyPairs))
+	for username, keyPair := range plaintextKeyPairs {
+		if _, ok := keyPairs[username]; ok {
+			return nil, fmt.Errorf("keystore keeps both plaintext and encrypted keys of user %s", username)
+		}
+		if keyPairs[username], err = encryptKeyPair(keyPair, password); err != nil {
+			return nil, fmt.Errorf("failed to encrypt key pair of user %s: %w", username, err)
+		}
+		usernames = append(usernames, username)
+	}
+	sort.Strings(usernames)
+
+	// encrypted keys are saved and plaintext keys are removed atomically
+	batch := new(leveldb.Batch)
+	batch.Delete([]byte(secretsKey))
+	if err = s.putEncryptedKeyPairs(keyPairs, batch); err != nil {
+		return nil, err
+	}
+
+	return usernames, nil
+}
+
+func (s *LevelDBKeyStore) getEncryptedKeyPairs() (map[string]*encryptedKeyPair, error) {
+	bz, err := s.keystoreDb.Get([]byte(encryptedSecretsKey), nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read keystore: %w", err)
+	}
+
+	var keyPairs = map[string]*encryptedKeyPair{}
 	if err := json.Unmarshal(bz, &keyPairs); err != nil {
-		return fmt.Errorf("failed to unmarshak key pairs: %w", err)
+		return nil, fmt.Errorf("failed to unmarshal key pairs: %w", err)
 	}
 
-	keyPairs[username] = keyPair
+	return keyPairs, nil
+}
 
+func (s *LevelDBKeyStore) putEncryptedKeyPairs(keyPairs map[string]*encryptedKeyPair, batch *leveldb.Batch) error {
 	keyPairsBz, err := json.Marshal(keyPairs)
 	if err != nil {
-		return fmt.Errorf("failed to marshal key pair: %w", err)
+		return fmt.Errorf("failed to marshal key pairs: %w", err)
 	}
 
-	err = s.keystoreDb.Put([]byte(secretsKey), keyPairsBz, nil)
-	if err != nil {
+	batch.Put([]byte(encryptedSecretsKey), keyPairsBz)
+	if err = s.keystoreDb.Write(batch, nil); err != nil {
 		return fmt.Errorf("failed to put key pairs: %w", err)
 	}
 
 	return nil
 }
 
-func (s *LevelDBKeyStore) LoadKeys(userName, password string) (*KeyPair, error) {
+// getPlaintextKeyPairs reads key pairs of the legacy plaintext format, if any
+func (s *LevelDBKeyStore) getPlaintextKeyPairs() (map[string]*KeyPair, error) {
+	var keyPairs = map[string]*KeyPair{}
+
 	bz, err := s.keystoreDb.Get([]byte(secretsKey), nil)
 	if err != nil {
+		if errors.Is(err, leveldb.ErrNotFound) {
+			return keyPairs, nil
+		}
 		return nil, fmt.Errorf("failed to read keystore: %w", err)
 	}
 
-	var keyPairs = map[string]*KeyPair{}
 	if err := json.Unmarshal(bz, &keyPairs); err != nil {
 		return nil, fmt.Errorf("failed to unmarshal key pairs: %w", err)
 	}
 
-	keyPair, ok := keyPairs[userName]
-	if !ok {
-		return nil, fmt.Errorf("no key pair found for user %s", userName)
-	}
-
-	return keyPair, nil
+	return keyPairs, nil
 }
 
 func (s *LevelDBKeyStore) initJsonKey(key string, data interface{}) error {
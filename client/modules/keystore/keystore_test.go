@@ -0,0 +1,96 @@
+package keystore
+
+import (
+	"encoding/json"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestLevelDBKeyStore_Encryption(t *testing.T) {
+	req := require.New(t)
+
+	ks, err := NewLevelDBKeyStore("alice", filepath.Join(t.TempDir(), "keystore"))
+	req.NoError(err)
+
+	keyPair := NewKeyPair()
+	req.NoError(ks.PutKeys("alice", "password", keyPair))
+
+	loaded, err := ks.LoadKeys("alice", "password")
+	req.NoError(err)
+	req.Equal(keyPair, loaded)
+
+	_, err = ks.LoadKeys("alice", "wrong password")
+	req.ErrorIs(err, ErrWrongPassword)
+
+	_, err = ks.LoadKeys("bob", "password")
+	req.Error(err)
+
+	// the private key is not stored in the clear
+	bz, err := ks.(*LevelDBKeyStore).keystoreDb.Get([]byte(encryptedSecretsKey), nil)
+	req.NoError(err)
+	req.NotContains(string(bz), string(keyPair.Priv))
+
+	req.NoError(ks.ChangePassword("alice", "password", "new password"))
+	_, err = ks.LoadKeys("alice", "password")
+	req.ErrorIs(err, ErrWrongPassword)
+	loaded, err = ks.LoadKeys("alice", "new password")
+	req.NoError(err)
+	req.Equal(keyPair, loaded)
+
+	req.ErrorIs(ks.ChangePassword("alice", "password", "another password"), ErrWrongPassword)
+}
+
+func TestLevelDBKeyStore_MigratePlaintextKeys(t *testing.T) {
+	req := require.New(t)
+
+	ks, err := NewLevelDBKeyStore("alice", filepath.Join(t.TempDir(), "keystore"))
+	req.NoError(err)
+	db := ks.(*LevelDBKeyStore).keystoreDb
+
+	// a key store of the legacy plaintext format
+	keyPairs := map[string]*KeyPair{"alice": NewKeyPair(), "bob": NewKeyPair()}
+	bz, err := json.Marshal(keyPairs)
+	req.NoError(err)
+	req.NoError(db.Put([]byte(secretsKey), bz, nil))
+
+	_, err = ks.LoadKeys("alice", "password")
+	req.ErrorIs(err, ErrPlaintextKeyStore)
+
+	usernames, err := ks.MigratePlaintextKeys("password")
+	req.NoError(err)
+	req.Equal([]string{"alice", "bob"}, usernames)
+
+	for username, keyPair := range keyPairs {
+		loaded, err := ks.LoadKeys(username, "password")
+		req.NoError(err)
+		req.Equal(keyPair, loaded)
+	}
+
+	has, err := db.Has([]byte(secretsKey), nil)
+	req.NoError(err)
+	req.False(has)
+
+	usernames, err = ks.MigratePlaintextKeys("password")
+	req.NoError(err)
+	req.Empty(usernames)
+}
+
+func TestDecryptKeyPair_ScryptParams(t *testing.T) {
+	req := require.New(t)
+
+	encrypted, err := encryptKeyPair(NewKeyPair(), "password")
+	req.NoError(err)
+
+	for _, params := range [][3]int{{1 << 30, scryptR, scryptP}, {3 << 10, scryptR, scryptP}, {0, scryptR, scryptP},
+		{scryptN, 1 << 10, scryptP}, {scryptN, scryptR, 1 << 10}, {scryptN, 0, scryptP}} {
+		tampered := *encrypted
+		tampered.N, tampered.R, tampered.P = params[0], params[1], params[2]
+		_, err = decryptKeyPair(&tampered, "password")
+		req.ErrorContains(err, "invalid scrypt", params)
+	}
+
+	_, err = decryptKeyPair(encrypted, "password")
+	req.NoError(err)
+}
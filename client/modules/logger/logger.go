@@ -1,24 +1,242 @@
 package logger
 
 import (
+	"encoding/json"
 	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
 )
 
+// Logger writes levelled messages with structured fields. Log is kept for existing callers and logs at the info level
 type Logger interface {
 	Log(format string, args ...interface{})
+	Debugf(format string, args ...interface{})
+	Infof(format string, args ...interface{})
+	Warnf(format string, args ...interface{})
+	Errorf(format string, args ...interface{})
+	// With returns a logger which adds the fields to every message
+	With(fields ...Field) Logger
+}
+
+// Level of a message, the zero value is the info level
+type Level int
+
+const (
+	LevelDebug Level = iota - 1
+	LevelInfo
+	LevelWarn
+	LevelError
+)
+
+var levelNames = map[Level]string{
+	LevelDebug: "debug",
+	LevelInfo:  "info",
+	LevelWarn:  "warn",
+	LevelError: "error",
+}
+
+func (l Level) String() string {
+	if name, ok := levelNames[l]; ok {
+		return name
+	}
+	return "level(" + strconv.Itoa(int(l)) + ")"
+}
+
+// ParseLevel parses a level name, empty name is the info level
+func ParseLevel(name string) (Level, error) {
+	if name == "" {
+		return LevelInfo, nil
+	}
+	for level, levelName := range levelNames {
+		if strings.EqualFold(name, levelName) {
+			return level, nil
+		}
+	}
+	return 0, fmt.Errorf("unknown log level %s, available levels: debug, info, warn, error", name)
+}
+
+type Format string
+
+const (
+	FormatText Format = "text"
+	FormatJSON Format = "json"
+)
+
+// ParseFormat parses an output format name, empty name is the text format
+func ParseFormat(name string) (Format, error) {
+	switch Format(strings.ToLower(name)) {
+	case "", FormatText:
+		return FormatText, nil
+	case FormatJSON:
+		return FormatJSON, nil
+	}
+	return "", fmt.Errorf("unknown log format %s, available formats: text, json", name)
+}
+
+// Common field keys, so messages about the same DKG round or operation can be grepped across logs
+const (
+	KeyDkgID       = "dkg_id"
+	KeyOffset      = "offset"
+	KeyOperationID = "operation_id"
+	KeyEvent       = "event"
+	KeyError       = "error"
+)
+
+type Field struct {
+	Key   string
+	Value interface{}
+}
+
+func Any(key string, value interface{}) Field {
+	return Field{Key: key, Value: value}
+}
+
+func DkgID(id string) Field {
+	return Field{Key: KeyDkgID, Value: id}
+}
+
+func Offset(offset uint64) Field {
+	return Field{Key: KeyOffset, Value: offset}
+}
+
+func OperationID(id string) Field {
+	return Field{Key: KeyOperationID, Value: id}
+}
+
+func Event(event interface{}) Field {
+	return Field{Key: KeyEvent, Value: fmt.Sprint(event)}
+}
+
+func Err(err error) Field {
+	return Field{Key: KeyError, Value: fmt.Sprint(err)}
+}
+
+type Config struct {
+	// Username is added to every message, the node and the airgapped machine of a participant share it
+	Username string
+	Level    Level
+	Format   Format
+	// Output is os.Stdout if not set
+	Output io.Writer
+}
+
+// output is shared by a logger and its children, so lines written concurrently are never interleaved
+type output struct {
+	mu sync.Mutex
+	w  io.Writer
 }
 
-// logger is a glorious logger implementation.
 type logger struct {
-	userName string
+	cfg    Config
+	out    *output
+	fields []Field
 }
 
-func NewLogger(username string) *logger {
-	return &logger{
-		userName: username,
+func New(cfg Config) Logger {
+	if cfg.Output == nil {
+		cfg.Output = os.Stdout
+	}
+	if cfg.Format == "" {
+		cfg.Format = FormatText
 	}
+	return &logger{cfg: cfg, out: &output{w: cfg.Output}}
+}
+
+// NewLogger creates a text logger of info messages
+func NewLogger(username string) Logger {
+	return New(Config{Username: username, Level: LevelInfo})
 }
 
 func (l *logger) Log(format string, args ...interface{}) {
-	fmt.Printf("[%s] %s\n", l.userName, fmt.Sprintf(format, args...))
+	l.log(LevelInfo, format, args...)
+}
+
+func (l *logger) Debugf(format string, args ...interface{}) {
+	l.log(LevelDebug, format, args...)
+}
+
+func (l *logger) Infof(format string, args ...interface{}) {
+	l.log(LevelInfo, format, args...)
+}
+
+func (l *logger) Warnf(format string, args ...interface{}) {
+	l.log(LevelWarn, format, args...)
+}
+
+func (l *logger) Errorf(format string, args ...interface{}) {
+	l.log(LevelError, format, args...)
+}
+
+func (l *logger) With(fields ...Field) Logger {
+	child := &logger{cfg: l.cfg, out: l.out, fields: make([]Field, 0, len(l.fields)+len(fields))}
+	child.fields = append(child.fields, l.fields...)
+	child.fields = append(child.fields, fields...)
+	return child
+}
+
+func (l *logger) log(level Level, format string, args ...interface{}) {
+	if level < l.cfg.Level {
+		return
+	}
+	msg := strings.TrimRight(fmt.Sprintf(format, args...), "\n")
+
+	var line []byte
+	if l.cfg.Format == FormatJSON {
+		line = l.formatJSON(level, msg)
+	} else {
+		line = l.formatText(level, msg)
+	}
+
+	l.out.mu.Lock()
+	defer l.out.mu.Unlock()
+	_, _ = l.out.w.Write(line)
+}
+
+// formatText writes "time LEVEL [username] message key=value...", values with spaces are quoted
+func (l *logger) formatText(level Level, msg string) []byte {
+	var b strings.Builder
+	b.WriteString(time.Now().UTC().Format(time.RFC3339Nano))
+	b.WriteByte(' ')
+	b.WriteString(strings.ToUpper(level.String()))
+	b.WriteByte(' ')
+	if l.cfg.Username != "" {
+		b.WriteString("[" + l.cfg.Username + "] ")
+	}
+	b.WriteString(msg)
+	for _, field := range l.fields {
+		value := fmt.Sprint(field.Value)
+		if value == "" || strings.ContainsAny(value, " \t\n\"=") {
+			value = strconv.Quote(value)
+		}
+		b.WriteString(" " + field.Key + "=" + value)
+	}
+	b.WriteByte('\n')
+	return []byte(b.String())
+}
+
+func (l *logger) formatJSON(level Level, msg string) []byte {
+	entry := make(map[string]interface{}, len(l.fields)+4)
+	for _, field := range l.fields {
+		entry[field.Key] = field.Value
+	}
+	entry["time"] = time.Now().UTC().Format(time.RFC3339Nano)
+	entry["level"] = level.String()
+	entry["msg"] = msg
+	if l.cfg.Username != "" {
+		entry["username"] = l.cfg.Username
+	}
+
+	line, err := json.Marshal(entry)
+	if err != nil {
+		// a field value is not marshallable, fall back to its string representation
+		for _, field := range l.fields {
+			entry[field.Key] = fmt.Sprint(field.Value)
+		}
+		line, _ = json.Marshal(entry)
+	}
+	return append(line, '\n')
 }
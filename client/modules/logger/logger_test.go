@@ -0,0 +1,71 @@
+package logger
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestLogger_Text(t *testing.T) {
+	req := require.New(t)
+
+	var buf bytes.Buffer
+	l := New(Config{Username: "node_1", Level: LevelInfo, Output: &buf})
+	l.Debugf("debug message is dropped")
+	l.With(DkgID("dkg"), Offset(3), Event("event_sig_proposal_init")).Log("Handling message with offset %d", 3)
+	l.With(Err(errors.New("failed to do something"))).Errorf("Failed to process message\n")
+
+	lines := strings.Split(strings.TrimSuffix(buf.String(), "\n"), "\n")
+	req.Len(lines, 2)
+	req.Contains(lines[0], " INFO [node_1] Handling message with offset 3 dkg_id=dkg offset=3 event=event_sig_proposal_init")
+	req.Contains(lines[1], ` ERROR [node_1] Failed to process message error="failed to do something"`)
+}
+
+func TestLogger_JSON(t *testing.T) {
+	req := require.New(t)
+
+	var buf bytes.Buffer
+	l := New(Config{Username: "node_1", Level: LevelDebug, Format: FormatJSON, Output: &buf})
+	withDkg := l.With(DkgID("dkg"))
+	withDkg.With(OperationID("op")).Debugf("Operation %s is stored", "op")
+	withDkg.Warnf("no operation")
+
+	lines := strings.Split(strings.TrimSuffix(buf.String(), "\n"), "\n")
+	req.Len(lines, 2)
+
+	var entry map[string]interface{}
+	req.NoError(json.Unmarshal([]byte(lines[0]), &entry))
+	req.Equal("debug", entry["level"])
+	req.Equal("node_1", entry["username"])
+	req.Equal("Operation op is stored", entry["msg"])
+	req.Equal("dkg", entry[KeyDkgID])
+	req.Equal("op", entry[KeyOperationID])
+
+	entry = nil
+	req.NoError(json.Unmarshal([]byte(lines[1]), &entry))
+	req.Equal("warn", entry["level"])
+	req.NotContains(entry, KeyOperationID)
+}
+
+func TestParseLevel(t *testing.T) {
+	req := require.New(t)
+
+	level, err := ParseLevel("")
+	req.NoError(err)
+	req.Equal(LevelInfo, level)
+	level, err = ParseLevel("WARN")
+	req.NoError(err)
+	req.Equal(LevelWarn, level)
+	_, err = ParseLevel("verbose")
+	req.Error(err)
+
+	format, err := ParseFormat("json")
+	req.NoError(err)
+	req.Equal(FormatJSON, format)
+	_, err = ParseFormat("xml")
+	req.Error(err)
+}
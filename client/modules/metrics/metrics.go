@@ -0,0 +1,197 @@
+package metrics
+
+import (
+	"net/http"
+	"strconv"
+	"sync/atomic"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+const namespace = "dc4bc"
+
+const (
+	ResultSuccess = "success"
+	ResultFailure = "failure"
+)
+
+var (
+	boardOffset uint64
+	boardHead   uint64
+
+	// MessagesProcessed and MessagesFailed count board messages handled by the node by event type
+	MessagesProcessed = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "messages_processed_total",
+		Help:      "Board messages processed by the node, by event type",
+	}, []string{"event"})
+	MessagesFailed = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "messages_failed_total",
+		Help:      "Board messages the node failed to verify or process, by event type",
+	}, []string{"event"})
+
+	SignatureReconstructions = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "signature_reconstructions_total",
+		Help:      "Threshold signature reconstructions, by result",
+	}, []string{"result"})
+
+	HTTPRequestDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "http_request_duration_seconds",
+		Help:      "Latency of node HTTP API requests, by method, route and status code",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"method", "route", "code"})
+
+	StorageOperations = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "storage_operations_total",
+		Help:      "Operations of the storage backend, by backend, operation and result",
+	}, []string{"backend", "operation", "result"})
+	StorageOperationDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "storage_operation_duration_seconds",
+		Help:      "Latency of storage backend operations, by backend and operation",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"backend", "operation"})
+	StorageMessagesRead = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "storage_messages_read_total",
+		Help:      "Messages read from the storage backend, by backend",
+	}, []string{"backend"})
+
+	boardMetrics = []prometheus.Collector{
+		prometheus.NewGaugeFunc(prometheus.GaugeOpts{
+			Namespace: namespace,
+			Name:      "board_offset",
+			Help:      "Offset of the next board message to be handled by the node",
+		}, func() float64 { return float64(atomic.LoadUint64(&boardOffset)) }),
+		prometheus.NewGaugeFunc(prometheus.GaugeOpts{
+			Namespace: namespace,
+			Name:      "board_head_offset",
+			Help:      "Offset following the last message read from the board",
+		}, func() float64 { return float64(atomic.LoadUint64(&boardHead)) }),
+		prometheus.NewGaugeFunc(prometheus.GaugeOpts{
+			Namespace: namespace,
+			Name:      "board_lag",
+			Help:      "Board messages read by the node but not handled yet",
+		}, func() float64 {
+			head, offset := atomic.LoadUint64(&boardHead), atomic.LoadUint64(&boardOffset)
+			if head < offset {
+				return 0
+			}
+			return float64(head - offset)
+		}),
+	}
+)
+
+// SetBoardOffset sets the offset of the next message to be handled
+func SetBoardOffset(offset uint64) {
+	atomic.StoreUint64(&boardOffset, offset)
+}
+
+// ObserveBoardMessage moves the board head after the message offset, if the message is the newest one
+func ObserveBoardMessage(offset uint64) {
+	for {
+		head := atomic.LoadUint64(&boardHead)
+		if offset < head || atomic.CompareAndSwapUint64(&boardHead, head, offset+1) {
+			return
+		}
+	}
+}
+
+// NodeState is read on every scrape, so gauges of the node state are never stale
+type NodeState struct {
+	PendingOperations func() (int, error)
+	// FSMStates returns the FSM state of every DKG round
+	FSMStates func() (map[string]string, error)
+}
+
+var (
+	pendingOperationsDesc = prometheus.NewDesc(prometheus.BuildFQName(namespace, "", "pending_operations"),
+		"Operations waiting to be processed by the airgapped machine", nil, nil)
+	fsmInstancesDesc = prometheus.NewDesc(prometheus.BuildFQName(namespace, "", "fsm_instances"),
+		"DKG rounds by the FSM state", []string{"state"}, nil)
+)
+
+func (s NodeState) Describe(ch chan<- *prometheus.Desc) {
+	ch <- pendingOperationsDesc
+	ch <- fsmInstancesDesc
+}
+
+func (s NodeState) Collect(ch chan<- prometheus.Metric) {
+	if pending, err := s.PendingOperations(); err != nil {
+		ch <- prometheus.NewInvalidMetric(pendingOperationsDesc, err)
+	} else {
+		ch <- prometheus.MustNewConstMetric(pendingOperationsDesc, prometheus.GaugeValue, float64(pending))
+	}
+
+	states, err := s.FSMStates()
+	if err != nil {
+		ch <- prometheus.NewInvalidMetric(fsmInstancesDesc, err)
+		return
+	}
+	byState := map[string]int{}
+	for _, state := range states {
+		byState[state]++
+	}
+	for state, count := range byState {
+		ch <- prometheus.MustNewConstMetric(fsmInstancesDesc, prometheus.GaugeValue, float64(count), state)
+	}
+}
+
+// Handler serves metrics of the node in the Prometheus text format
+func Handler(state NodeState) http.Handler {
+	registry := prometheus.NewRegistry()
+	registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		MessagesProcessed,
+		MessagesFailed,
+		SignatureReconstructions,
+		HTTPRequestDuration,
+		StorageOperations,
+		StorageOperationDuration,
+		StorageMessagesRead,
+		state,
+	)
+	registry.MustRegister(boardMetrics...)
+
+	return promhttp.HandlerFor(registry, promhttp.HandlerOpts{})
+}
+
+// ResponseStatus returns the status of the response to the request handled with the error. An error is written
+// by the HTTP error handler after the middleware chain, so middlewares can't read its status from the response
+func ResponseStatus(c echo.Context, err error) int {
+	if err == nil || c.Response().Committed {
+		return c.Response().Status
+	}
+	if httpErr, ok := err.(*echo.HTTPError); ok {
+		return httpErr.Code
+	}
+	return http.StatusInternalServerError
+}
+
+// EchoMiddleware observes latencies of HTTP API requests, requests are labeled by the route pattern
+func EchoMiddleware() echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			start := time.Now()
+			err := next(c)
+
+			code := ResponseStatus(c, err)
+			route := c.Path()
+			if route == "" {
+				route = "unknown"
+			}
+			HTTPRequestDuration.WithLabelValues(c.Request().Method, route, strconv.Itoa(code)).
+				Observe(time.Since(start).Seconds())
+			return err
+		}
+	}
+}
@@ -0,0 +1,119 @@
+package metrics
+
+import (
+	"context"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/require"
+
+	"github.com/lidofinance/dc4bc/storage"
+)
+
+type testStorage struct {
+	msgs []storage.Message
+}
+
+func (s *testStorage) Send(messages ...storage.Message) error {
+	s.msgs = append(s.msgs, messages...)
+	return nil
+}
+
+func (s *testStorage) GetMessages(offset uint64) ([]storage.Message, error) {
+	if offset > uint64(len(s.msgs)) {
+		return nil, errors.New("offset is out of range")
+	}
+	return s.msgs[offset:], nil
+}
+
+func (s *testStorage) Close() error                            { return nil }
+func (s *testStorage) IgnoreMessages(_ []string, _ bool) error { return nil }
+func (s *testStorage) UnignoreMessages()                       {}
+
+type testSubscriber struct {
+	testStorage
+}
+
+func (s *testSubscriber) Subscribe(ctx context.Context, offset uint64) <-chan storage.Message {
+	ch := make(chan storage.Message, len(s.msgs))
+	for _, msg := range s.msgs[offset:] {
+		ch <- msg
+	}
+	close(ch)
+	return ch
+}
+
+func TestInstrumentStorage(t *testing.T) {
+	req := require.New(t)
+
+	stg := InstrumentStorage(&testStorage{}, "test")
+	_, isSubscriber := stg.(storage.Subscriber)
+	req.False(isSubscriber)
+
+	req.NoError(stg.Send(storage.Message{Offset: 0}, storage.Message{Offset: 1}, storage.Message{Offset: 2}))
+	msgs, err := stg.GetMessages(1)
+	req.NoError(err)
+	req.Len(msgs, 2)
+	_, err = stg.GetMessages(10)
+	req.Error(err)
+
+	snapshot, err := storage.TakeSnapshot(context.Background(), stg)
+	req.NoError(err)
+	req.Len(snapshot.Messages, 3)
+
+	subscriber := &testSubscriber{}
+	req.NoError(subscriber.Send(storage.Message{Offset: 0}, storage.Message{Offset: 1}))
+	stg = InstrumentStorage(subscriber, "test_subscriber")
+	sub, isSubscriber := stg.(storage.Subscriber)
+	req.True(isSubscriber)
+	received := 0
+	for range sub.Subscribe(context.Background(), 0) {
+		received++
+	}
+	req.Equal(2, received)
+
+	SetBoardOffset(1)
+	body := scrape(t, Handler(NodeState{
+		PendingOperations: func() (int, error) { return 2, nil },
+		FSMStates: func() (map[string]string, error) {
+			return map[string]string{"dkg_1": "state_dkg_master_key_collected", "dkg_2": "state_dkg_master_key_collected"}, nil
+		},
+	}))
+	req.Contains(body, `dc4bc_storage_operations_total{backend="test",operation="get_messages",result="failure"} 1`)
+	req.Contains(body, `dc4bc_storage_operations_total{backend="test",operation="send",result="success"} 1`)
+	req.Contains(body, `dc4bc_storage_messages_read_total{backend="test_subscriber"} 2`)
+	req.Contains(body, "dc4bc_board_head_offset 3")
+	req.Contains(body, "dc4bc_board_lag 2")
+	req.Contains(body, "dc4bc_pending_operations 2")
+	req.Contains(body, `dc4bc_fsm_instances{state="state_dkg_master_key_collected"} 2`)
+}
+
+func TestEchoMiddleware(t *testing.T) {
+	req := require.New(t)
+
+	e := echo.New()
+	e.Use(EchoMiddleware())
+	e.GET("/operations/:id", func(c echo.Context) error {
+		return echo.NewHTTPError(http.StatusNotFound)
+	})
+	e.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/operations/1", nil))
+
+	body := scrape(t, Handler(NodeState{
+		PendingOperations: func() (int, error) { return 0, nil },
+		FSMStates:         func() (map[string]string, error) { return nil, nil },
+	}))
+	req.Contains(body, `dc4bc_http_request_duration_seconds_count{code="404",method="GET",route="/operations/:id"} 1`)
+}
+
+func scrape(t *testing.T, handler http.Handler) string {
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	require.Equal(t, http.StatusOK, w.Code)
+	body, err := ioutil.ReadAll(w.Body)
+	require.NoError(t, err)
+	return string(body)
+}
@@ -0,0 +1,94 @@
+package metrics
+
+import (
+	"context"
+	"time"
+
+	"github.com/lidofinance/dc4bc/storage"
+)
+
+// InstrumentStorage wraps the storage backend to count its operations and observe their latencies.
+// The wrapper keeps the Subscriber capability of the backend.
+func InstrumentStorage(stg storage.Storage, backend string) storage.Storage {
+	instrumented := &instrumentedStorage{Storage: stg, backend: backend}
+	if subscriber, ok := stg.(storage.Subscriber); ok {
+		return &instrumentedSubscriber{instrumentedStorage: instrumented, subscriber: subscriber}
+	}
+	return instrumented
+}
+
+type instrumentedStorage struct {
+	storage.Storage
+	backend string
+}
+
+var _ storage.Snapshotter = (*instrumentedStorage)(nil)
+
+func (s *instrumentedStorage) observe(operation string, start time.Time, err error) {
+	result := ResultSuccess
+	if err != nil {
+		result = ResultFailure
+	}
+	StorageOperations.WithLabelValues(s.backend, operation, result).Inc()
+	StorageOperationDuration.WithLabelValues(s.backend, operation).Observe(time.Since(start).Seconds())
+}
+
+func (s *instrumentedStorage) observeMessages(msgs ...storage.Message) {
+	StorageMessagesRead.WithLabelValues(s.backend).Add(float64(len(msgs)))
+	for _, msg := range msgs {
+		ObserveBoardMessage(msg.Offset)
+	}
+}
+
+func (s *instrumentedStorage) Send(messages ...storage.Message) error {
+	start := time.Now()
+	err := s.Storage.Send(messages...)
+	s.observe("send", start, err)
+	return err
+}
+
+func (s *instrumentedStorage) GetMessages(offset uint64) ([]storage.Message, error) {
+	start := time.Now()
+	msgs, err := s.Storage.GetMessages(offset)
+	s.observe("get_messages", start, err)
+	s.observeMessages(msgs...)
+	return msgs, err
+}
+
+// ReadAll keeps TakeSnapshot working for backends which are Snapshotters
+func (s *instrumentedStorage) ReadAll(ctx context.Context) ([]storage.Message, error) {
+	start := time.Now()
+	var (
+		msgs []storage.Message
+		err  error
+	)
+	if snapshotter, ok := s.Storage.(storage.Snapshotter); ok {
+		msgs, err = snapshotter.ReadAll(ctx)
+	} else {
+		msgs, err = s.Storage.GetMessages(0)
+	}
+	s.observe("read_all", start, err)
+	return msgs, err
+}
+
+type instrumentedSubscriber struct {
+	*instrumentedStorage
+	subscriber storage.Subscriber
+}
+
+func (s *instrumentedSubscriber) Subscribe(ctx context.Context, offset uint64) <-chan storage.Message {
+	in := s.subscriber.Subscribe(ctx, offset)
+	out := make(chan storage.Message)
+	go func() {
+		defer close(out)
+		for msg := range in {
+			s.observeMessages(msg)
+			select {
+			case out <- msg:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	return out
+}
@@ -0,0 +1,182 @@
+//go:build cgo
+
+package signer
+
+import (
+	"crypto/ed25519"
+	"encoding/asn1"
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/miekg/pkcs11"
+)
+
+// PKCS#11 v3.0 constants for Ed25519 keys, not defined by the pkcs11 package
+const (
+	ckkECEdwards = 0x00000040
+	ckmEdDSA     = 0x00001057
+)
+
+// PKCS11Signer signs messages with an Ed25519 key kept in a PKCS#11 token (e.g. an HSM or SoftHSM),
+// the private key never leaves the token
+type PKCS11Signer struct {
+	sync.Mutex
+
+	ctx     *pkcs11.Ctx
+	session pkcs11.SessionHandle
+	privKey pkcs11.ObjectHandle
+	pubKey  ed25519.PublicKey
+}
+
+// NewPKCS11Signer loads the PKCS#11 module, logs into the token with the given label
+// and finds the key pair with the given label
+func NewPKCS11Signer(modulePath, tokenLabel, keyLabel, pin string) (*PKCS11Signer, error) {
+	ctx := pkcs11.New(modulePath)
+	if ctx == nil {
+		return nil, fmt.Errorf("failed to load PKCS#11 module %s", modulePath)
+	}
+	if err := ctx.Initialize(); err != nil {
+		ctx.Destroy()
+		return nil, fmt.Errorf("failed to initialize PKCS#11 module: %w", err)
+	}
+
+	s := &PKCS11Signer{ctx: ctx}
+	if err := s.open(tokenLabel, keyLabel, pin); err != nil {
+		s.Close()
+		return nil, err
+	}
+
+	return s, nil
+}
+
+func (s *PKCS11Signer) open(tokenLabel, keyLabel, pin string) error {
+	slot, err := s.findSlot(tokenLabel)
+	if err != nil {
+		return err
+	}
+
+	if s.session, err = s.ctx.OpenSession(slot, pkcs11.CKF_SERIAL_SESSION); err != nil {
+		return fmt.Errorf("failed to open PKCS#11 session: %w", err)
+	}
+	if err = s.ctx.Login(s.session, pkcs11.CKU_USER, pin); err != nil {
+		return fmt.Errorf("failed to login to PKCS#11 token: %w", err)
+	}
+
+	if s.privKey, err = s.findKey(pkcs11.CKO_PRIVATE_KEY, keyLabel); err != nil {
+		return err
+	}
+	pubKey, err := s.findKey(pkcs11.CKO_PUBLIC_KEY, keyLabel)
+	if err != nil {
+		return err
+	}
+
+	attrs, err := s.ctx.GetAttributeValue(s.session, pubKey, []*pkcs11.Attribute{
+		pkcs11.NewAttribute(pkcs11.CKA_EC_POINT, nil),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to read public key: %w", err)
+	}
+	if s.pubKey, err = parseECPoint(attrs[0].Value); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (s *PKCS11Signer) findSlot(tokenLabel string) (uint, error) {
+	slots, err := s.ctx.GetSlotList(true)
+	if err != nil {
+		return 0, fmt.Errorf("failed to get PKCS#11 slots: %w", err)
+	}
+	for _, slot := range slots {
+		info, err := s.ctx.GetTokenInfo(slot)
+		if err != nil {
+			return 0, fmt.Errorf("failed to get PKCS#11 token info: %w", err)
+		}
+		if info.Label == tokenLabel {
+			return slot, nil
+		}
+	}
+
+	return 0, fmt.Errorf("PKCS#11 token %s not found", tokenLabel)
+}
+
+func (s *PKCS11Signer) findKey(class uint, keyLabel string) (pkcs11.ObjectHandle, error) {
+	template := []*pkcs11.Attribute{
+		pkcs11.NewAttribute(pkcs11.CKA_CLASS, class),
+		pkcs11.NewAttribute(pkcs11.CKA_KEY_TYPE, ckkECEdwards),
+		pkcs11.NewAttribute(pkcs11.CKA_LABEL, keyLabel),
+	}
+	if err := s.ctx.FindObjectsInit(s.session, template); err != nil {
+		return 0, fmt.Errorf("failed to find PKCS#11 key: %w", err)
+	}
+	objects, _, err := s.ctx.FindObjects(s.session, 1)
+	if finalErr := s.ctx.FindObjectsFinal(s.session); err == nil {
+		err = finalErr
+	}
+	if err != nil {
+		return 0, fmt.Errorf("failed to find PKCS#11 key: %w", err)
+	}
+	if len(objects) == 0 {
+		return 0, fmt.Errorf("Ed25519 key %s not found in PKCS#11 token", keyLabel)
+	}
+
+	return objects[0], nil
+}
+
+// parseECPoint parses CKA_EC_POINT of an Ed25519 public key, which is a DER-encoded OCTET STRING
+// (some tokens return a raw key instead)
+func parseECPoint(ecPoint []byte) (ed25519.PublicKey, error) {
+	if len(ecPoint) == ed25519.PublicKeySize {
+		return ecPoint, nil
+	}
+
+	var pubKey []byte
+	if _, err := asn1.Unmarshal(ecPoint, &pubKey); err != nil {
+		return nil, fmt.Errorf("failed to parse public key: %w", err)
+	}
+	if len(pubKey) != ed25519.PublicKeySize {
+		return nil, fmt.Errorf("invalid public key length %d", len(pubKey))
+	}
+
+	return pubKey, nil
+}
+
+func (s *PKCS11Signer) PubKey() ed25519.PublicKey {
+	return s.pubKey
+}
+
+func (s *PKCS11Signer) Sign(message []byte) ([]byte, error) {
+	// PKCS#11 sessions are not thread-safe
+	s.Lock()
+	defer s.Unlock()
+
+	if err := s.ctx.SignInit(s.session, []*pkcs11.Mechanism{pkcs11.NewMechanism(ckmEdDSA, nil)}, s.privKey); err != nil {
+		return nil, fmt.Errorf("failed to init PKCS#11 signing: %w", err)
+	}
+	signature, err := s.ctx.Sign(s.session, message)
+	if err != nil {
+		return nil, fmt.Errorf("failed to sign with PKCS#11 token: %w", err)
+	}
+	if !ed25519.Verify(s.pubKey, message, signature) {
+		return nil, errors.New("PKCS#11 token returned invalid signature")
+	}
+
+	return signature, nil
+}
+
+// Close logs out of the token and unloads the module
+func (s *PKCS11Signer) Close() error {
+	s.Lock()
+	defer s.Unlock()
+
+	if s.session != 0 {
+		_ = s.ctx.Logout(s.session)
+		_ = s.ctx.CloseSession(s.session)
+	}
+	err := s.ctx.Finalize()
+	s.ctx.Destroy()
+
+	return err
+}
@@ -0,0 +1,29 @@
+//go:build !cgo
+
+package signer
+
+import (
+	"crypto/ed25519"
+	"errors"
+)
+
+var errPKCS11Unsupported = errors.New("PKCS#11 signer requires a build with cgo enabled")
+
+// PKCS11Signer is not available without cgo
+type PKCS11Signer struct{}
+
+func NewPKCS11Signer(_, _, _, _ string) (*PKCS11Signer, error) {
+	return nil, errPKCS11Unsupported
+}
+
+func (s *PKCS11Signer) PubKey() ed25519.PublicKey {
+	return nil
+}
+
+func (s *PKCS11Signer) Sign(_ []byte) ([]byte, error) {
+	return nil, errPKCS11Unsupported
+}
+
+func (s *PKCS11Signer) Close() error {
+	return nil
+}
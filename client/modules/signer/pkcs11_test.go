@@ -0,0 +1,76 @@
+//go:build cgo
+
+package signer
+
+import (
+	"crypto/ed25519"
+	"fmt"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/miekg/pkcs11"
+	"github.com/stretchr/testify/require"
+)
+
+// ckmECEdwardsKeyPairGen is the PKCS#11 v3.0 mechanism to generate Ed25519 keys
+const ckmECEdwardsKeyPairGen = 0x00001055
+
+// ed25519OID is the DER-encoded OID 1.3.101.112 of Ed25519 curve parameters
+var ed25519OID = []byte{0x06, 0x03, 0x2b, 0x65, 0x70}
+
+// TestPKCS11Signer runs against an initialized SoftHSM token, e.g.:
+// softhsm2-util --init-token --free --label dc4bc --pin 1234 --so-pin 1234
+// DC4BC_TEST_PKCS11_MODULE=/usr/lib/softhsm/libsofthsm2.so DC4BC_TEST_PKCS11_TOKEN=dc4bc DC4BC_TEST_PKCS11_PIN=1234
+func TestPKCS11Signer(t *testing.T) {
+	modulePath := os.Getenv("DC4BC_TEST_PKCS11_MODULE")
+	if modulePath == "" {
+		t.Skip("DC4BC_TEST_PKCS11_MODULE is not set")
+	}
+	tokenLabel, pin := os.Getenv("DC4BC_TEST_PKCS11_TOKEN"), os.Getenv("DC4BC_TEST_PKCS11_PIN")
+	// a new key on every run, so keys of previous runs are not picked up
+	keyLabel := fmt.Sprintf("dc4bc_test_key_%d", time.Now().UnixNano())
+
+	req := require.New(t)
+	generatePKCS11Key(t, modulePath, tokenLabel, keyLabel, pin)
+
+	pkcs11Signer, err := NewPKCS11Signer(modulePath, tokenLabel, keyLabel, pin)
+	req.NoError(err)
+	defer pkcs11Signer.Close()
+
+	message := []byte("message")
+	signature, err := pkcs11Signer.Sign(message)
+	req.NoError(err)
+	req.True(ed25519.Verify(pkcs11Signer.PubKey(), message, signature))
+}
+
+func generatePKCS11Key(t *testing.T, modulePath, tokenLabel, keyLabel, pin string) {
+	req := require.New(t)
+
+	s := &PKCS11Signer{ctx: pkcs11.New(modulePath)}
+	req.NotNil(s.ctx)
+	req.NoError(s.ctx.Initialize())
+	defer s.Close()
+
+	slot, err := s.findSlot(tokenLabel)
+	req.NoError(err)
+	s.session, err = s.ctx.OpenSession(slot, pkcs11.CKF_SERIAL_SESSION|pkcs11.CKF_RW_SESSION)
+	req.NoError(err)
+	req.NoError(s.ctx.Login(s.session, pkcs11.CKU_USER, pin))
+
+	_, _, err = s.ctx.GenerateKeyPair(s.session,
+		[]*pkcs11.Mechanism{pkcs11.NewMechanism(ckmECEdwardsKeyPairGen, nil)},
+		[]*pkcs11.Attribute{
+			pkcs11.NewAttribute(pkcs11.CKA_TOKEN, true),
+			pkcs11.NewAttribute(pkcs11.CKA_LABEL, keyLabel),
+			pkcs11.NewAttribute(pkcs11.CKA_EC_PARAMS, ed25519OID),
+			pkcs11.NewAttribute(pkcs11.CKA_VERIFY, true),
+		},
+		[]*pkcs11.Attribute{
+			pkcs11.NewAttribute(pkcs11.CKA_TOKEN, true),
+			pkcs11.NewAttribute(pkcs11.CKA_LABEL, keyLabel),
+			pkcs11.NewAttribute(pkcs11.CKA_SIGN, true),
+			pkcs11.NewAttribute(pkcs11.CKA_SENSITIVE, true),
+		})
+	req.NoError(err)
+}
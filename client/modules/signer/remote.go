@@ -0,0 +1,176 @@
+package signer
+
+import (
+	"bufio"
+	"crypto/ed25519"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"time"
+
+	"github.com/lidofinance/dc4bc/client/modules/logger"
+)
+
+// Remote signer protocol: a client sends JSON requests over a Unix socket, one per line,
+// and the signer answers every request with a JSON response line.
+const (
+	RemoteMethodPubKey = "pubkey"
+	RemoteMethodSign   = "sign"
+)
+
+type RemoteRequest struct {
+	Method  string `json:"method"`
+	Message []byte `json:"message,omitempty"`
+}
+
+type RemoteResponse struct {
+	ErrorMessage string            `json:"error_message,omitempty"`
+	PubKey       ed25519.PublicKey `json:"pubkey,omitempty"`
+	Signature    []byte            `json:"signature,omitempty"`
+}
+
+// RemoteSigner asks a signer process listening on a Unix socket to sign messages,
+// so the private key never enters the node process
+type RemoteSigner struct {
+	socketPath string
+	timeout    time.Duration
+	pubKey     ed25519.PublicKey
+}
+
+// NewRemoteSigner connects to the signer and fetches its public key
+func NewRemoteSigner(socketPath string, timeout time.Duration) (*RemoteSigner, error) {
+	s := &RemoteSigner{
+		socketPath: socketPath,
+		timeout:    timeout,
+	}
+
+	resp, err := s.call(RemoteRequest{Method: RemoteMethodPubKey})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get pubkey from remote signer: %w", err)
+	}
+	if len(resp.PubKey) != ed25519.PublicKeySize {
+		return nil, fmt.Errorf("remote signer returned invalid pubkey of %d bytes", len(resp.PubKey))
+	}
+	s.pubKey = resp.PubKey
+
+	return s, nil
+}
+
+func (s *RemoteSigner) PubKey() ed25519.PublicKey {
+	return s.pubKey
+}
+
+// Sign asks the remote signer to sign the message and verifies the signature
+func (s *RemoteSigner) Sign(message []byte) ([]byte, error) {
+	resp, err := s.call(RemoteRequest{Method: RemoteMethodSign, Message: message})
+	if err != nil {
+		return nil, fmt.Errorf("failed to sign message with remote signer: %w", err)
+	}
+	if !ed25519.Verify(s.pubKey, message, resp.Signature) {
+		return nil, errors.New("remote signer returned invalid signature")
+	}
+
+	return resp.Signature, nil
+}
+
+func (s *RemoteSigner) call(req RemoteRequest) (*RemoteResponse, error) {
+	conn, err := net.DialTimeout("unix", s.socketPath, s.timeout)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to remote signer: %w", err)
+	}
+	defer conn.Close()
+
+	if err = conn.SetDeadline(time.Now().Add(s.timeout)); err != nil {
+		return nil, fmt.Errorf("failed to set deadline: %w", err)
+	}
+	if err = json.NewEncoder(conn).Encode(req); err != nil {
+		return nil, fmt.Errorf("failed to send request: %w", err)
+	}
+
+	var resp RemoteResponse
+	if err = json.NewDecoder(conn).Decode(&resp); err != nil {
+		return nil, fmt.Errorf("failed to read response: %w", err)
+	}
+	if resp.ErrorMessage != "" {
+		return nil, fmt.Errorf("remote signer returned an error: %s", resp.ErrorMessage)
+	}
+
+	return &resp, nil
+}
+
+// ListenRemoteSigner creates a Unix socket accessible by the current user only
+func ListenRemoteSigner(socketPath string) (net.Listener, error) {
+	if err := os.Remove(socketPath); err != nil && !os.IsNotExist(err) {
+		return nil, fmt.Errorf("failed to remove stale socket: %w", err)
+	}
+
+	l, err := net.Listen("unix", socketPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to listen on socket: %w", err)
+	}
+	if err = os.Chmod(socketPath, 0600); err != nil {
+		l.Close()
+		return nil, fmt.Errorf("failed to set socket permissions: %w", err)
+	}
+
+	return l, nil
+}
+
+// ServeRemoteSigner serves remote signer requests with the given signer until the listener is closed,
+// failed connections are logged to the logger
+func ServeRemoteSigner(l net.Listener, signer Signer, lg logger.Logger) error {
+	for {
+		conn, err := l.Accept()
+		if err != nil {
+			if errors.Is(err, net.ErrClosed) {
+				return nil
+			}
+			return fmt.Errorf("failed to accept connection: %w", err)
+		}
+
+		go serveRemoteSignerConn(conn, signer, lg)
+	}
+}
+
+func serveRemoteSignerConn(conn net.Conn, signer Signer, lg logger.Logger) {
+	defer conn.Close()
+
+	reader := bufio.NewReader(conn)
+	encoder := json.NewEncoder(conn)
+	for {
+		line, err := reader.ReadBytes('\n')
+		if err != nil {
+			if !errors.Is(err, io.EOF) {
+				lg.Errorf("Failed to read remote signer request: %v", err)
+			}
+			return
+		}
+
+		var (
+			req  RemoteRequest
+			resp RemoteResponse
+		)
+		if err = json.Unmarshal(line, &req); err != nil {
+			resp.ErrorMessage = fmt.Sprintf("failed to unmarshal request: %v", err)
+		} else {
+			switch req.Method {
+			case RemoteMethodPubKey:
+				resp.PubKey = signer.PubKey()
+			case RemoteMethodSign:
+				if resp.Signature, err = signer.Sign(req.Message); err != nil {
+					resp.ErrorMessage = err.Error()
+				}
+			default:
+				resp.ErrorMessage = fmt.Sprintf("unknown method %s", req.Method)
+			}
+		}
+
+		if err = encoder.Encode(resp); err != nil {
+			lg.Errorf("Failed to send remote signer response: %v", err)
+			return
+		}
+	}
+}
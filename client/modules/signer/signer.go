@@ -0,0 +1,41 @@
+package signer
+
+import (
+	"crypto/ed25519"
+	"fmt"
+
+	"github.com/lidofinance/dc4bc/client/modules/keystore"
+)
+
+// Signer signs board messages with the node's hot key. Implementations may keep the private key
+// outside of the node process, e.g. in a PKCS#11 token or in a remote signer.
+type Signer interface {
+	PubKey() ed25519.PublicKey
+	Sign(message []byte) ([]byte, error)
+}
+
+// KeyPairSigner signs messages with a key pair kept in memory
+type KeyPairSigner struct {
+	keyPair *keystore.KeyPair
+}
+
+func NewKeyPairSigner(keyPair *keystore.KeyPair) *KeyPairSigner {
+	return &KeyPairSigner{keyPair: keyPair}
+}
+
+// NewKeyStoreSigner decrypts the user's key pair from the key store
+func NewKeyStoreSigner(ks keystore.KeyStore, username, password string) (*KeyPairSigner, error) {
+	keyPair, err := ks.LoadKeys(username, password)
+	if err != nil {
+		return nil, fmt.Errorf("failed to LoadKeys: %w", err)
+	}
+	return NewKeyPairSigner(keyPair), nil
+}
+
+func (s *KeyPairSigner) PubKey() ed25519.PublicKey {
+	return s.keyPair.Pub
+}
+
+func (s *KeyPairSigner) Sign(message []byte) ([]byte, error) {
+	return ed25519.Sign(s.keyPair.Priv, message), nil
+}
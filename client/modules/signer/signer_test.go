@@ -0,0 +1,38 @@
+package signer
+
+import (
+	"crypto/ed25519"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/lidofinance/dc4bc/client/modules/keystore"
+	"github.com/lidofinance/dc4bc/client/modules/logger"
+)
+
+func TestRemoteSigner(t *testing.T) {
+	req := require.New(t)
+	socketPath := filepath.Join(t.TempDir(), "signer.sock")
+
+	keyPair := keystore.NewKeyPair()
+	l, err := ListenRemoteSigner(socketPath)
+	req.NoError(err)
+	defer l.Close()
+	go func() {
+		req.NoError(ServeRemoteSigner(l, NewKeyPairSigner(keyPair), logger.NewLogger("signer")))
+	}()
+
+	remoteSigner, err := NewRemoteSigner(socketPath, 10*time.Second)
+	req.NoError(err)
+	req.Equal(keyPair.Pub, remoteSigner.PubKey())
+
+	message := []byte("message")
+	signature, err := remoteSigner.Sign(message)
+	req.NoError(err)
+	req.True(ed25519.Verify(keyPair.Pub, message, signature))
+
+	_, err = NewRemoteSigner(filepath.Join(t.TempDir(), "unknown.sock"), time.Second)
+	req.Error(err)
+}
@@ -0,0 +1,304 @@
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/lidofinance/dc4bc/client/modules/events"
+	"github.com/lidofinance/dc4bc/client/modules/logger"
+	"github.com/lidofinance/dc4bc/fsm/fsm"
+	dpf "github.com/lidofinance/dc4bc/fsm/state_machines/dkg_proposal_fsm"
+	sif "github.com/lidofinance/dc4bc/fsm/state_machines/signing_proposal_fsm"
+)
+
+// Webhook events, a subset of node events that need a participant's attention
+const (
+	EventOperation       = "operation"
+	EventDKGFinished     = "dkg_finished"
+	EventSigningFinished = "signing_finished"
+	EventTimeout         = "timeout"
+)
+
+var knownEvents = map[string]struct{}{
+	EventOperation:       {},
+	EventDKGFinished:     {},
+	EventSigningFinished: {},
+	EventTimeout:         {},
+}
+
+const (
+	HeaderEvent     = "X-Dc4bc-Event"
+	HeaderDelivery  = "X-Dc4bc-Delivery"
+	HeaderTimestamp = "X-Dc4bc-Timestamp"
+	// HeaderSignature is "sha256=" and hex encoded HMAC-SHA256 of the timestamp, a dot and the body
+	HeaderSignature = "X-Dc4bc-Signature"
+
+	signaturePrefix = "sha256="
+	queueSize       = 1024
+	maxBackoff      = time.Minute
+)
+
+var ErrInvalidSignature = errors.New("invalid webhook signature")
+
+// Notification is the body of a webhook request
+type Notification struct {
+	// ID is a random prefix of the node run and the event sequence number, so it is unique across nodes
+	// and restarts. Receivers use it to drop duplicates of retried deliveries
+	ID         string          `json:"id"`
+	Event      string          `json:"event"`
+	Username   string          `json:"username"`
+	Time       time.Time       `json:"time"`
+	DkgRoundID string          `json:"dkg_round_id"`
+	Offset     uint64          `json:"offset"`
+	Data       json.RawMessage `json:"data"`
+}
+
+// Classify maps a node event to a webhook event, false means the node event is not notified
+func Classify(e events.Event) (string, bool) {
+	switch e.Type {
+	case events.TypeOperation:
+		return EventOperation, true
+	case events.TypeStateChange:
+		var change events.StateChangeEvent
+		if err := json.Unmarshal(e.Data, &change); err != nil {
+			return "", false
+		}
+		switch {
+		case fsm.State(change.To) == dpf.StateDkgMasterKeyCollected:
+			return EventDKGFinished, true
+		case fsm.State(change.To) == sif.StateSigningPartialSignsCollected:
+			return EventSigningFinished, true
+		case strings.HasSuffix(change.To, "_by_timeout"):
+			return EventTimeout, true
+		}
+	}
+	return "", false
+}
+
+// ParseEvents parses a comma separated list of webhook events, empty list means every event
+func ParseEvents(list string) (map[string]struct{}, error) {
+	if list == "" {
+		return nil, nil
+	}
+	result := map[string]struct{}{}
+	for _, e := range strings.Split(list, ",") {
+		e = strings.TrimSpace(e)
+		if _, ok := knownEvents[e]; !ok {
+			return nil, fmt.Errorf("unknown webhook event %s", e)
+		}
+		result[e] = struct{}{}
+	}
+	return result, nil
+}
+
+func Sign(secret []byte, timestamp string, body []byte) string {
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte(timestamp))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return signaturePrefix + hex.EncodeToString(mac.Sum(nil))
+}
+
+// Verify checks the signature of a webhook request, maxAge limits replays of captured requests
+func Verify(secret []byte, header http.Header, body []byte, maxAge time.Duration) error {
+	timestamp := header.Get(HeaderTimestamp)
+	unix, err := strconv.ParseInt(timestamp, 10, 64)
+	if err != nil {
+		return fmt.Errorf("%w: invalid timestamp", ErrInvalidSignature)
+	}
+	if maxAge > 0 && time.Since(time.Unix(unix, 0)) > maxAge {
+		return fmt.Errorf("%w: request is too old", ErrInvalidSignature)
+	}
+	if !hmac.Equal([]byte(Sign(secret, timestamp, body)), []byte(header.Get(HeaderSignature))) {
+		return ErrInvalidSignature
+	}
+	return nil
+}
+
+type Config struct {
+	URLs   []string
+	Secret []byte
+	// Events are notified events, nil means every event
+	Events       map[string]struct{}
+	Username     string
+	MaxRetries   int
+	RetryBackoff time.Duration
+	Timeout      time.Duration
+}
+
+// Notifier posts webhook notifications about node events to the configured URLs.
+// Every URL has its own queue, so a receiver that is down does not delay the others
+type Notifier struct {
+	cfg    Config
+	client *http.Client
+	l      logger.Logger
+	queues map[string]chan Notification
+	// runID prefixes notification IDs, event IDs of the bus start from 1 on every run
+	runID string
+}
+
+func NewNotifier(cfg Config, l logger.Logger) (*Notifier, error) {
+	if len(cfg.Secret) == 0 {
+		return nil, errors.New("webhook secret is not set")
+	}
+	if cfg.RetryBackoff <= 0 {
+		return nil, errors.New("webhook retry backoff must be positive")
+	}
+
+	queues := make(map[string]chan Notification, len(cfg.URLs))
+	for _, u := range cfg.URLs {
+		if !strings.HasPrefix(u, "http://") && !strings.HasPrefix(u, "https://") {
+			return nil, fmt.Errorf("invalid webhook URL %s", u)
+		}
+		queues[u] = make(chan Notification, queueSize)
+	}
+
+	return &Notifier{
+		cfg:    cfg,
+		client: &http.Client{Timeout: cfg.Timeout},
+		l:      l,
+		queues: queues,
+		runID:  uuid.New().String(),
+	}, nil
+}
+
+// Run notifies about events of the bus until the context is done
+func (n *Notifier) Run(ctx context.Context, bus *events.Bus) {
+	for u, queue := range n.queues {
+		go n.deliverQueue(ctx, u, queue)
+	}
+
+	var lastID uint64
+	for ctx.Err() == nil {
+		// the bus drops a subscriber which does not keep up, then we resubscribe from the last seen event
+		sub, cancel := bus.Subscribe(lastID, events.Filter{})
+	read:
+		for {
+			select {
+			case <-ctx.Done():
+				break read
+			case e, ok := <-sub:
+				if !ok {
+					break read
+				}
+				lastID = e.ID
+				n.enqueue(e)
+			}
+		}
+		cancel()
+	}
+}
+
+func (n *Notifier) enqueue(e events.Event) {
+	event, ok := Classify(e)
+	if !ok {
+		return
+	}
+	if n.cfg.Events != nil {
+		if _, ok = n.cfg.Events[event]; !ok {
+			return
+		}
+	}
+
+	notification := Notification{
+		ID:         n.runID + "-" + strconv.FormatUint(e.ID, 10),
+		Event:      event,
+		Username:   n.cfg.Username,
+		Time:       e.Time,
+		DkgRoundID: e.DkgRoundID,
+		Offset:     e.Offset,
+		Data:       e.Data,
+	}
+	for u, queue := range n.queues {
+		select {
+		case queue <- notification:
+		default:
+			n.l.Warnf("Webhook queue of %s is full, %s notification %s is dropped", u, event, notification.ID)
+		}
+	}
+}
+
+func (n *Notifier) deliverQueue(ctx context.Context, u string, queue <-chan Notification) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case notification := <-queue:
+			if err := n.deliver(ctx, u, notification); err != nil {
+				n.l.Errorf("Failed to deliver %s webhook %s to %s: %v", notification.Event, notification.ID, u, err)
+			}
+		}
+	}
+}
+
+// deliver posts the notification, retrying with exponential backoff while the receiver is unavailable
+func (n *Notifier) deliver(ctx context.Context, u string, notification Notification) error {
+	body, err := json.Marshal(notification)
+	if err != nil {
+		return fmt.Errorf("failed to marshal notification: %w", err)
+	}
+
+	backoff := n.cfg.RetryBackoff
+	for attempt := 0; ; attempt++ {
+		retry, err := n.post(ctx, u, notification, body)
+		if err == nil {
+			return nil
+		}
+		if !retry || attempt >= n.cfg.MaxRetries {
+			return err
+		}
+
+		n.l.Warnf("Failed to deliver %s webhook %s to %s, retrying in %s: %v", notification.Event, notification.ID,
+			u, backoff, err)
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(backoff):
+		}
+		if backoff *= 2; backoff > maxBackoff {
+			backoff = maxBackoff
+		}
+	}
+}
+
+func (n *Notifier) post(ctx context.Context, u string, notification Notification, body []byte) (bool, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, u, bytes.NewReader(body))
+	if err != nil {
+		return false, fmt.Errorf("failed to create request: %w", err)
+	}
+	// the timestamp is taken for every attempt, so a retried request is not rejected as a replay
+	timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(HeaderEvent, notification.Event)
+	req.Header.Set(HeaderDelivery, notification.ID)
+	req.Header.Set(HeaderTimestamp, timestamp)
+	req.Header.Set(HeaderSignature, Sign(n.cfg.Secret, timestamp, body))
+
+	resp, err := n.client.Do(req)
+	if err != nil {
+		return true, err
+	}
+	resp.Body.Close()
+
+	switch {
+	case resp.StatusCode >= 200 && resp.StatusCode < 300:
+		return false, nil
+	case resp.StatusCode >= 500, resp.StatusCode == http.StatusTooManyRequests,
+		resp.StatusCode == http.StatusRequestTimeout:
+		return true, fmt.Errorf("receiver responded with status %d", resp.StatusCode)
+	default:
+		return false, fmt.Errorf("receiver rejected the notification with status %d", resp.StatusCode)
+	}
+}
@@ -0,0 +1,114 @@
+package webhook
+
+import (
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/lidofinance/dc4bc/client/modules/events"
+	"github.com/lidofinance/dc4bc/client/modules/logger"
+	dpf "github.com/lidofinance/dc4bc/fsm/state_machines/dkg_proposal_fsm"
+	sif "github.com/lidofinance/dc4bc/fsm/state_machines/signing_proposal_fsm"
+)
+
+type testWriter struct{ t *testing.T }
+
+func (w testWriter) Write(p []byte) (int, error) {
+	w.t.Log(string(p))
+	return len(p), nil
+}
+
+func TestNotifier(t *testing.T) {
+	req := require.New(t)
+	secret := []byte("secret")
+
+	var attempts int32
+	received := make(chan Notification, 10)
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, err := ioutil.ReadAll(r.Body)
+		if err != nil || Verify(secret, r.Header, body, time.Minute) != nil {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		// the receiver is down for the first attempt
+		if atomic.AddInt32(&attempts, 1) == 1 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		var notification Notification
+		if err = json.Unmarshal(body, &notification); err != nil || r.Header.Get(HeaderDelivery) != notification.ID {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		received <- notification
+	}))
+	defer receiver.Close()
+
+	notifyEvents, err := ParseEvents("operation,dkg_finished,timeout")
+	req.NoError(err)
+	notifier, err := NewNotifier(Config{
+		URLs:         []string{receiver.URL},
+		Secret:       secret,
+		Events:       notifyEvents,
+		Username:     "node",
+		MaxRetries:   3,
+		RetryBackoff: 10 * time.Millisecond,
+		Timeout:      time.Second,
+	}, logger.New(logger.Config{Output: testWriter{t}}))
+	req.NoError(err)
+
+	bus := events.NewBus(events.DefaultHistorySize)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go notifier.Run(ctx, bus)
+
+	// give the notifier time to subscribe, events published before are replayed anyway
+	time.Sleep(10 * time.Millisecond)
+	bus.Publish(events.TypeOperation, "dkg", 1, events.OperationEvent{OperationID: "op"})
+	bus.Publish(events.TypeSignature, "dkg", 2, events.SignatureEvent{})
+	bus.Publish(events.TypeStateChange, "dkg", 3, events.StateChangeEvent{
+		From: string(sif.StateSigningAwaitPartialSigns), To: string(sif.StateSigningPartialSignsCollected)})
+	bus.Publish(events.TypeStateChange, "dkg", 4, events.StateChangeEvent{
+		From: string(dpf.StateDkgMasterKeyAwaitConfirmations), To: string(dpf.StateDkgMasterKeyCollected)})
+	bus.Publish(events.TypeStateChange, "dkg", 5, events.StateChangeEvent{
+		From: string(dpf.StateDkgDealsAwaitConfirmations), To: string(dpf.StateDkgDealsAwaitCanceledByTimeout)})
+
+	for _, expected := range []string{EventOperation, EventDKGFinished, EventTimeout} {
+		select {
+		case notification := <-received:
+			req.Equal(expected, notification.Event)
+			req.Equal("node", notification.Username)
+			req.Equal("dkg", notification.DkgRoundID)
+			req.True(strings.HasPrefix(notification.ID, notifier.runID+"-"))
+		case <-time.After(5 * time.Second):
+			req.FailNow("notification is not delivered", expected)
+		}
+	}
+	req.Equal(int32(4), atomic.LoadInt32(&attempts))
+}
+
+func TestVerify(t *testing.T) {
+	req := require.New(t)
+	secret := []byte("secret")
+	body := []byte(`{"event":"operation"}`)
+
+	timestamp := "1700000000"
+	header := http.Header{}
+	header.Set(HeaderTimestamp, timestamp)
+	header.Set(HeaderSignature, Sign(secret, timestamp, body))
+	req.NoError(Verify(secret, header, body, 0))
+	req.ErrorIs(Verify([]byte("other"), header, body, 0), ErrInvalidSignature)
+	req.ErrorIs(Verify(secret, header, []byte(`{"event":"timeout"}`), 0), ErrInvalidSignature)
+	req.ErrorIs(Verify(secret, header, body, time.Minute), ErrInvalidSignature)
+
+	_, err := ParseEvents("operation,unknown")
+	req.Error(err)
+}
@@ -0,0 +1,106 @@
+package node
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/lidofinance/dc4bc/storage"
+)
+
+const ChainHeadKey = "chain_head"
+
+// ErrChainBroken means that the bulletin board log was tampered with (messages were reordered,
+// dropped or modified), so the node must not process messages after the break.
+var ErrChainBroken = errors.New("bulletin board hash chain is broken")
+
+// chainHead is the last verified entry of the bulletin board hash chain
+type chainHead struct {
+	Offset uint64 `json:"offset"`
+	Hash   []byte `json:"hash"`
+}
+
+func (s *BaseNodeService) loadChainHead() (*chainHead, error) {
+	bz, err := s.getState().Get(ChainHeadKey)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load chain head: %w", err)
+	}
+	if len(bz) == 0 {
+		return nil, nil
+	}
+
+	var head chainHead
+	if err = json.Unmarshal(bz, &head); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal chain head: %w", err)
+	}
+
+	return &head, nil
+}
+
+func (s *BaseNodeService) saveChainHead(head *chainHead) error {
+	bz, err := json.Marshal(head)
+	if err != nil {
+		return fmt.Errorf("failed to marshal chain head: %w", err)
+	}
+	if err = s.getState().Set(ChainHeadKey, bz); err != nil {
+		return fmt.Errorf("failed to save chain head: %w", err)
+	}
+
+	return nil
+}
+
+// resetChainHead drops the last verified entry, e.g. when the offset is changed manually
+func (s *BaseNodeService) resetChainHead() error {
+	if err := s.getState().Delete(ChainHeadKey); err != nil {
+		return fmt.Errorf("failed to reset chain head: %w", err)
+	}
+
+	return nil
+}
+
+// verifyChain checks that the message is correctly linked to the last verified message and moves
+// the chain head forward. Messages without hashes are accepted only if the chain was never started,
+// i.e. the storage backend does not support hash chains.
+func (s *BaseNodeService) verifyChain(message storage.Message) error {
+	head, err := s.loadChainHead()
+	if err != nil {
+		return err
+	}
+
+	if len(message.Hash) == 0 {
+		if head != nil {
+			return fmt.Errorf("%w: message with offset %d has no chain hash", ErrChainBroken, message.Offset)
+		}
+		return nil
+	}
+
+	if !message.VerifyChainHash() {
+		return fmt.Errorf("%w: message with offset %d does not match its chain hash", ErrChainBroken,
+			message.Offset)
+	}
+
+	switch {
+	case head == nil:
+		if message.Offset == 0 && len(message.PrevHash) != 0 {
+			return fmt.Errorf("%w: first message has a predecessor", ErrChainBroken)
+		}
+	case message.Offset == head.Offset && bytes.Equal(message.Hash, head.Hash):
+		// the message was verified, but the offset was not saved
+		return nil
+	case message.Offset <= head.Offset:
+		return fmt.Errorf("%w: message with offset %d follows message with offset %d", ErrChainBroken,
+			message.Offset, head.Offset)
+	case message.Offset == head.Offset+1:
+		if !bytes.Equal(message.PrevHash, head.Hash) {
+			return fmt.Errorf("%w: message with offset %d is not linked to message with offset %d",
+				ErrChainBroken, message.Offset, head.Offset)
+		}
+	default:
+		// ignored messages are read as well, so a gap means that messages were dropped from the log
+		return fmt.Errorf("%w: messages with offsets %d-%d are missing", ErrChainBroken,
+			head.Offset+1, message.Offset-1)
+	}
+
+	return s.saveChainHead(&chainHead{Offset: message.Offset, Hash: message.Hash})
+}
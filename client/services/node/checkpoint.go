@@ -0,0 +1,252 @@
+package node
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/lidofinance/dc4bc/client/modules/logger"
+	"github.com/lidofinance/dc4bc/client/modules/state"
+	"github.com/lidofinance/dc4bc/client/types"
+	"github.com/lidofinance/dc4bc/fsm/fsm"
+	"github.com/lidofinance/dc4bc/storage"
+)
+
+const (
+	LogHeadKey               = "log_head"
+	LogDigestKeyPrefix       = "log_digest"
+	CheckpointDivergencesKey = "checkpoint_divergences"
+)
+
+// updateLogDigest extends the node's view of the log with the message. Unlike the chain head, the log digest
+// is calculated for every storage backend, so checkpoints can be compared even if the board doesn't chain messages.
+// The digest covers every message of the log, ignored ones included, so it fails on missing messages
+func (s *BaseNodeService) updateLogDigest(message storage.Message) error {
+	head, err := s.loadLogHead()
+	if err != nil {
+		return err
+	}
+
+	if head != nil && message.Offset <= head.Offset {
+		// the message was digested, but the offset was not saved
+		return nil
+	}
+
+	var prevDigest []byte
+	switch {
+	case head != nil && message.Offset == head.Offset+1:
+		prevDigest = head.Hash
+	case head != nil:
+		return fmt.Errorf("messages with offsets %d-%d are missing, log digest can't be calculated",
+			head.Offset+1, message.Offset-1)
+	case message.Offset > 0:
+		// the node reads the log from the middle, the board links the message to the previous ones
+		if len(message.PrevHash) == 0 {
+			return fmt.Errorf("messages before offset %d are missing, log digest can't be calculated", message.Offset)
+		}
+		prevDigest = message.PrevHash
+	}
+
+	digest := storage.ChainHash(prevDigest, message)
+	if err = s.getState().Set(logDigestKey(message.Offset), digest); err != nil {
+		return fmt.Errorf("failed to save log digest: %w", err)
+	}
+
+	bz, err := json.Marshal(&chainHead{Offset: message.Offset, Hash: digest})
+	if err != nil {
+		return fmt.Errorf("failed to marshal log head: %w", err)
+	}
+	if err = s.getState().Set(LogHeadKey, bz); err != nil {
+		return fmt.Errorf("failed to save log head: %w", err)
+	}
+
+	if fsm.Event(message.Event) != types.BoardCheckpoint {
+		s.Lock()
+		s.hasUncheckpointedMessages = true
+		s.Unlock()
+	}
+
+	return nil
+}
+
+func (s *BaseNodeService) loadLogHead() (*chainHead, error) {
+	bz, err := s.getState().Get(LogHeadKey)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load log head: %w", err)
+	}
+	if len(bz) == 0 {
+		return nil, nil
+	}
+
+	var head chainHead
+	if err = json.Unmarshal(bz, &head); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal log head: %w", err)
+	}
+
+	return &head, nil
+}
+
+func logDigestKey(offset uint64) string {
+	return state.MakeCompositeKeyString(LogDigestKeyPrefix, strconv.FormatUint(offset, 10))
+}
+
+// postCheckpoint posts a signed checkpoint with the node's log head, if the checkpoint period has passed
+// and there are new messages since the previous checkpoint
+func (s *BaseNodeService) postCheckpoint() error {
+	s.Lock()
+	if s.checkpointPeriod == 0 || !s.hasUncheckpointedMessages || time.Since(s.lastCheckpointAt) < s.checkpointPeriod {
+		s.Unlock()
+		return nil
+	}
+	s.Unlock()
+
+	head, err := s.loadLogHead()
+	if err != nil {
+		return err
+	}
+	if head == nil {
+		return nil
+	}
+
+	data, err := json.Marshal(&types.Checkpoint{
+		Offset: head.Offset,
+		Digest: head.Hash,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to marshal checkpoint: %w", err)
+	}
+
+	message, err := s.buildMessage("", types.BoardCheckpoint, data)
+	if err != nil {
+		return err
+	}
+	if err = s.storage.Send(*message); err != nil {
+		return fmt.Errorf("failed to post checkpoint: %w", err)
+	}
+
+	s.Lock()
+	s.hasUncheckpointedMessages = false
+	s.lastCheckpointAt = time.Now()
+	s.Unlock()
+
+	return nil
+}
+
+// processCheckpoint compares a checkpoint of another participant with the node's view of the log
+func (s *BaseNodeService) processCheckpoint(message storage.Message) error {
+	var checkpoint types.Checkpoint
+	if err := json.Unmarshal(message.Data, &checkpoint); err != nil {
+		return fmt.Errorf("failed to unmarshal checkpoint: %w", err)
+	}
+
+	if err := s.verifyCheckpointSender(message); err != nil {
+		return fmt.Errorf("failed to verify checkpoint: %w", err)
+	}
+
+	divergence := types.CheckpointDivergence{
+		Participant:      message.SenderAddr,
+		Offset:           checkpoint.Offset,
+		CheckpointOffset: message.Offset,
+		TheirDigest:      checkpoint.Digest,
+	}
+
+	if checkpoint.Offset >= message.Offset {
+		divergence.Reason = "checkpoint refers to a message posted after the checkpoint"
+		return s.saveCheckpointDivergence(divergence)
+	}
+
+	ourDigest, err := s.getState().Get(logDigestKey(checkpoint.Offset))
+	if err != nil {
+		return fmt.Errorf("failed to load log digest: %w", err)
+	}
+	if len(ourDigest) == 0 {
+		s.Logger.With(logger.Offset(checkpoint.Offset), logger.Any("participant", message.SenderAddr)).
+			Warnf("Log digest is unknown, skip the checkpoint")
+		return nil
+	}
+
+	if !bytes.Equal(ourDigest, checkpoint.Digest) {
+		divergence.OurDigest = ourDigest
+		divergence.Reason = "log digests do not match"
+		return s.saveCheckpointDivergence(divergence)
+	}
+
+	return nil
+}
+
+// verifyCheckpointSender checks the checkpoint signature with the sender's keys from the DKG rounds the node holds.
+// Checkpoints of senders unknown to the node are dropped
+func (s *BaseNodeService) verifyCheckpointSender(message storage.Message) error {
+	fsmList, err := s.fsmService.GetFSMList()
+	if err != nil {
+		return fmt.Errorf("failed to get FSM list: %w", err)
+	}
+
+	isKnownSender := false
+	for dkgID := range fsmList {
+		fsmInstance, err := s.fsmService.GetFSMInstance(dkgID, false)
+		if err != nil {
+			return fmt.Errorf("failed to get FSM instance: %w", err)
+		}
+		knownPubKey, err := fsmInstance.GetPubKeyByUsername(message.SenderAddr)
+		if err != nil {
+			// the sender does not participate in the round
+			continue
+		}
+		isKnownSender = true
+		if len(knownPubKey) == ed25519.PublicKeySize && ed25519.Verify(knownPubKey, message.Bytes(), message.Signature) {
+			return nil
+		}
+	}
+
+	if !isKnownSender {
+		return fmt.Errorf("sender %s is unknown", message.SenderAddr)
+	}
+	return errors.New("signature is corrupt")
+}
+
+func (s *BaseNodeService) saveCheckpointDivergence(divergence types.CheckpointDivergence) error {
+	s.Logger.With(logger.Offset(divergence.CheckpointOffset), logger.Any("participant", divergence.Participant)).
+		Errorf("ALERT: checkpoint diverges from our view of the log: %s", divergence.Reason)
+
+	divergences, err := s.GetCheckpointDivergences()
+	if err != nil {
+		return err
+	}
+	for _, d := range divergences {
+		if d.CheckpointOffset == divergence.CheckpointOffset {
+			return nil
+		}
+	}
+
+	bz, err := json.Marshal(append(divergences, divergence))
+	if err != nil {
+		return fmt.Errorf("failed to marshal checkpoint divergences: %w", err)
+	}
+	if err = s.getState().Set(CheckpointDivergencesKey, bz); err != nil {
+		return fmt.Errorf("failed to save checkpoint divergences: %w", err)
+	}
+
+	return nil
+}
+
+// GetCheckpointDivergences returns all checkpoints of other participants which don't match the node's view of the log
+func (s *BaseNodeService) GetCheckpointDivergences() ([]types.CheckpointDivergence, error) {
+	bz, err := s.getState().Get(CheckpointDivergencesKey)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load checkpoint divergences: %w", err)
+	}
+
+	divergences := []types.CheckpointDivergence{}
+	if len(bz) > 0 {
+		if err = json.Unmarshal(bz, &divergences); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal checkpoint divergences: %w", err)
+		}
+	}
+
+	return divergences, nil
+}
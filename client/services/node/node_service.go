@@ -1,6 +1,7 @@
 package node
 
 import (
+	"bytes"
 	"context"
 	"crypto/ed25519"
 	"crypto/rand"
@@ -9,7 +10,6 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
-	"log"
 	"math/big"
 	"strconv"
 	"strings"
@@ -23,8 +23,11 @@ import (
 
 	"github.com/lidofinance/dc4bc/client/api/dto"
 	"github.com/lidofinance/dc4bc/client/config"
-	"github.com/lidofinance/dc4bc/client/modules/keystore"
+	"github.com/lidofinance/dc4bc/client/modules/audit"
+	"github.com/lidofinance/dc4bc/client/modules/events"
 	"github.com/lidofinance/dc4bc/client/modules/logger"
+	"github.com/lidofinance/dc4bc/client/modules/metrics"
+	"github.com/lidofinance/dc4bc/client/modules/signer"
 	"github.com/lidofinance/dc4bc/client/modules/state"
 	"github.com/lidofinance/dc4bc/client/services"
 	"github.com/lidofinance/dc4bc/client/services/fsmservice"
@@ -35,6 +38,8 @@ import (
 	"github.com/lidofinance/dc4bc/fsm/fsm"
 	"github.com/lidofinance/dc4bc/fsm/state_machines"
 	dpf "github.com/lidofinance/dc4bc/fsm/state_machines/dkg_proposal_fsm"
+	rf "github.com/lidofinance/dc4bc/fsm/state_machines/resharing_fsm"
+	srf "github.com/lidofinance/dc4bc/fsm/state_machines/share_refresh_fsm"
 	spf "github.com/lidofinance/dc4bc/fsm/state_machines/signature_proposal_fsm"
 	sif "github.com/lidofinance/dc4bc/fsm/state_machines/signing_proposal_fsm"
 	fsmtypes "github.com/lidofinance/dc4bc/fsm/types"
@@ -54,6 +59,7 @@ type NodeService interface {
 	GetPubKey() ed25519.PublicKey
 	GetUsername() string
 	ApproveParticipation(dto *dto.OperationIdDTO) error
+	DeclineParticipation(dto *dto.DeclineParticipationDTO) error
 	SendMessage(dto *dto.MessageDTO) error
 	ProcessMessage(message storage.Message) error
 	ProcessOperation(dto *dto.OperationDTO) error
@@ -61,8 +67,12 @@ type NodeService interface {
 	ReInitDKG(dto *dto.ReInitDKGDTO) error
 	SetSkipCommKeysVerification(bool)
 	ProposeSignMessages(dto *dto.ProposeSignBatchMessagesDTO) error
+	ProposeShareRefresh(dto *dto.DkgIdDTO) error
+	ProposeResharing(dto *dto.ProposeResharingDTO) error
 	SaveOffset(dto *dto.StateOffsetDTO) error
 	GetStateOffset() (uint64, error)
+	GetCheckpointDivergences() ([]types.CheckpointDivergence, error)
+	ExportSnapshot() (*storage.Snapshot, error)
 }
 
 type BaseNodeService struct {
@@ -73,31 +83,47 @@ type BaseNodeService struct {
 	stateMu                  sync.RWMutex
 	state                    state.State
 	storage                  storage.Storage
-	keyStore                 keystore.KeyStore
+	signer                   signer.Signer
 	Logger                   logger.Logger
 	fsmService               fsmservice.FSMService
 	opService                operation.OperationService
 	sigService               signature.SignatureService
+	events                   *events.Bus
+	audit                    *audit.Journal
 	SkipCommKeysVerification bool
+
+	checkpointPeriod          time.Duration
+	lastCheckpointAt          time.Time
+	hasUncheckpointedMessages bool
+
+	// postedTimeouts are the stage deadlines the node has posted timeouts for
+	postedTimeouts map[string]bool
 }
 
 func NewNode(ctx context.Context, config *config.Config, sp *services.ServiceProvider) (NodeService, error) {
-	keyPair, err := sp.GetKeyStore().LoadKeys(config.Username, "")
-	if err != nil {
-		return nil, fmt.Errorf("failed to LoadKeys: %w", err)
+	var checkpointPeriod time.Duration
+	if config.CheckpointPeriod != "" {
+		var err error
+		if checkpointPeriod, err = time.ParseDuration(config.CheckpointPeriod); err != nil {
+			return nil, fmt.Errorf("failed to parse checkpoint period: %w", err)
+		}
 	}
 
 	return &BaseNodeService{
 		ctx:        ctx,
 		userName:   config.Username,
-		pubKey:     keyPair.Pub,
+		pubKey:     sp.GetSigner().PubKey(),
 		state:      sp.GetState(),
 		storage:    sp.GetStorage(),
-		keyStore:   sp.GetKeyStore(),
+		signer:     sp.GetSigner(),
 		Logger:     sp.GetLogger(),
 		fsmService: sp.GetFSMService(),
 		opService:  sp.GetOperationService(),
 		sigService: sp.GetSignatureService(),
+		events:     sp.GetEvents(),
+		audit:      sp.GetAudit(),
+
+		checkpointPeriod: checkpointPeriod,
 	}, nil
 }
 
@@ -113,6 +139,10 @@ func (s *BaseNodeService) ProcessMessage(message storage.Message) error {
 		return nil
 	}
 
+	if fsm.Event(message.Event) == types.BoardCheckpoint {
+		return s.processCheckpoint(message)
+	}
+
 	operation, err := s.processMessage(message)
 	if err != nil {
 		return err
@@ -122,10 +152,55 @@ func (s *BaseNodeService) ProcessMessage(message storage.Message) error {
 		if err := s.opService.PutOperation(operation); err != nil {
 			return fmt.Errorf("failed to PutOperation: %w", err)
 		}
+		s.publishOperation(message, operation)
+		s.recordAudit(audit.ActionOperationCreated, operation, map[string]interface{}{"offset": message.Offset})
 	}
 	return nil
 }
 
+// recordAudit records a node action to the audit journal. A failed record is logged and does not fail the action,
+// since the action itself is already done
+func (s *BaseNodeService) recordAudit(action string, operation *types.Operation, details map[string]interface{}) {
+	dkgRoundID := ""
+	if operation != nil {
+		dkgRoundID = operation.DKGIdentifier
+		details["operation_id"] = operation.ID
+		details["operation_type"] = operation.Type
+	}
+	if err := s.audit.Record(audit.ActorNode, action, dkgRoundID, details); err != nil {
+		s.Logger.With(logger.DkgID(dkgRoundID), logger.Err(err)).Errorf("Failed to record %s to audit journal", action)
+	}
+}
+
+func (s *BaseNodeService) publishOperation(message storage.Message, operation *types.Operation) {
+	s.events.Publish(events.TypeOperation, message.DkgRoundID, message.Offset, events.OperationEvent{
+		OperationID: operation.ID,
+		Type:        string(operation.Type),
+		CreatedAt:   operation.CreatedAt,
+	})
+}
+
+// publishStateChanges publishes every transition of the DKG round FSM made while processing the message
+func (s *BaseNodeService) publishStateChanges(message storage.Message, states []fsm.State) {
+	for i := 1; i < len(states); i++ {
+		if states[i] == states[i-1] {
+			continue
+		}
+		s.events.Publish(events.TypeStateChange, message.DkgRoundID, message.Offset, events.StateChangeEvent{
+			Event: message.Event,
+			From:  string(states[i-1]),
+			To:    string(states[i]),
+		})
+	}
+}
+
+func (s *BaseNodeService) publishError(message storage.Message, err error) {
+	s.events.Publish(events.TypeError, message.DkgRoundID, message.Offset, events.ErrorEvent{
+		Event: message.Event,
+		Error: err.Error(),
+	})
+}
+
 func (s *BaseNodeService) SetSkipCommKeysVerification(b bool) {
 	s.Lock()
 	defer s.Unlock()
@@ -133,8 +208,13 @@ func (s *BaseNodeService) SetSkipCommKeysVerification(b bool) {
 	s.SkipCommKeysVerification = b
 }
 
-// Poll is a main node loop, which gets new messages from an append-only log and processes them
+// Poll is a main node loop, which gets new messages from an append-only log and processes them. If the storage
+// supports subscriptions, messages are pushed to the node, otherwise the storage is polled every pollingPeriod
 func (s *BaseNodeService) Poll() error {
+	if subscriber, ok := s.storage.(storage.Subscriber); ok {
+		return s.consume(subscriber)
+	}
+
 	tk := time.NewTicker(pollingPeriod)
 	for {
 		select {
@@ -143,6 +223,7 @@ func (s *BaseNodeService) Poll() error {
 			if err != nil {
 				return fmt.Errorf("failed to LoadOffset: %w", err)
 			}
+			metrics.SetBoardOffset(offset)
 
 			messages, err := s.storage.GetMessages(offset)
 			if err != nil {
@@ -150,29 +231,134 @@ func (s *BaseNodeService) Poll() error {
 			}
 
 			for _, message := range messages {
-				s.Logger.Log("Handling message with offset %d, type %s", message.Offset, message.Event)
-				if message.RecipientAddr == "" || message.RecipientAddr == s.GetUsername() {
-					if err := s.ProcessMessage(message); err != nil {
-						s.Logger.Log("Failed to process message with offset %d: %v", message.Offset, err)
-					} else {
-						s.Logger.Log("Successfully processed message with offset %d, type %s",
-							message.Offset, message.Event)
-					}
-				} else {
-					s.Logger.Log("Message with offset %d, type %s is not intended for us, skip it",
-						message.Offset, message.Event)
-				}
-				if err := s.getState().SaveOffset(message.Offset + 1); err != nil {
-					s.Logger.Log("Failed to save offset:  %w", err)
+				if err := s.handleMessage(message); err != nil {
+					break
 				}
 			}
+
+			if err := s.postCheckpoint(); err != nil {
+				s.Logger.Warnf("Failed to post checkpoint: %v", err)
+			}
+			if err := s.postTimeouts(); err != nil {
+				s.Logger.Warnf("Failed to post timeouts: %v", err)
+			}
+		case <-s.ctx.Done():
+			s.Logger.Infof("Context closed, stop polling...")
+			return nil
+		}
+	}
+}
+
+// consume handles messages pushed by the storage. The subscription is restarted when it fails,
+// when a message can't be handled, or when the offset is changed, e.g. by SaveOffset
+func (s *BaseNodeService) consume(subscriber storage.Subscriber) error {
+	tk := time.NewTicker(pollingPeriod)
+	defer tk.Stop()
+	for {
+		offset, err := s.getState().LoadOffset()
+		if err != nil {
+			return fmt.Errorf("failed to LoadOffset: %w", err)
+		}
+		metrics.SetBoardOffset(offset)
+
+		ctx, cancel := context.WithCancel(s.ctx)
+		messages := subscriber.Subscribe(ctx, offset)
+		s.consumeSubscription(messages, offset, tk)
+		cancel()
+
+		select {
+		case <-tk.C:
 		case <-s.ctx.Done():
-			log.Println("Context closed, stop polling...")
+			s.Logger.Infof("Context closed, stop polling...")
 			return nil
 		}
 	}
 }
 
+// consumeSubscription handles messages until the subscription should be restarted
+func (s *BaseNodeService) consumeSubscription(messages <-chan storage.Message, offset uint64, tk *time.Ticker) {
+	for {
+		select {
+		case message, ok := <-messages:
+			if !ok {
+				return
+			}
+			if err := s.handleMessage(message); err != nil {
+				return
+			}
+			offset = message.Offset + 1
+		case <-tk.C:
+			if err := s.postCheckpoint(); err != nil {
+				s.Logger.Warnf("Failed to post checkpoint: %v", err)
+			}
+			if err := s.postTimeouts(); err != nil {
+				s.Logger.Warnf("Failed to post timeouts: %v", err)
+			}
+
+			savedOffset, err := s.getState().LoadOffset()
+			if err != nil {
+				s.Logger.Errorf("Failed to LoadOffset: %v", err)
+				return
+			}
+			if savedOffset != offset {
+				s.Logger.Infof("Offset changed from %d to %d, restart subscription", offset, savedOffset)
+				return
+			}
+		case <-s.ctx.Done():
+			return
+		}
+	}
+}
+
+// messageLogger adds the DKG round, offset and event of the message to logged messages
+func (s *BaseNodeService) messageLogger(message storage.Message) logger.Logger {
+	return s.Logger.With(logger.DkgID(message.DkgRoundID), logger.Offset(message.Offset), logger.Event(message.Event))
+}
+
+// handleMessage verifies and processes a message read from the storage, then saves the next offset.
+// An error means that the message was not handled and the node must not move further
+func (s *BaseNodeService) handleMessage(message storage.Message) error {
+	l := s.messageLogger(message)
+	if err := s.verifyChain(message); err != nil {
+		// offset is not saved, so the node gets stuck at the broken message until the board is fixed
+		l.Errorf("ALERT: failed to verify message with offset %d: %v", message.Offset, err)
+		metrics.MessagesFailed.WithLabelValues(message.Event).Inc()
+		s.publishError(message, err)
+		return err
+	}
+	if err := s.updateLogDigest(message); err != nil {
+		l.Errorf("Failed to update log digest with message with offset %d: %v", message.Offset, err)
+		s.publishError(message, err)
+		return err
+	}
+
+	l.Infof("Handling message with offset %d, type %s", message.Offset, message.Event)
+	if message.Ignored {
+		// the message is verified and counted in the log digest, but it's not processed
+		l.Infof("Message with offset %d, type %s is ignored, skip it", message.Offset, message.Event)
+	} else if message.RecipientAddr == "" || message.RecipientAddr == s.GetUsername() {
+		if err := s.ProcessMessage(message); err != nil {
+			l.Errorf("Failed to process message with offset %d: %v", message.Offset, err)
+			metrics.MessagesFailed.WithLabelValues(message.Event).Inc()
+			s.publishError(message, err)
+		} else {
+			metrics.MessagesProcessed.WithLabelValues(message.Event).Inc()
+			l.Infof("Successfully processed message with offset %d, type %s",
+				message.Offset, message.Event)
+		}
+	} else {
+		l.Debugf("Message with offset %d, type %s is not intended for us, skip it",
+			message.Offset, message.Event)
+	}
+	if err := s.getState().SaveOffset(message.Offset + 1); err != nil {
+		l.Errorf("Failed to save offset: %v", err)
+	} else {
+		metrics.SetBoardOffset(message.Offset + 1)
+	}
+
+	return nil
+}
+
 func (s *BaseNodeService) getState() state.State {
 	s.stateMu.RLock()
 	defer s.stateMu.RUnlock()
@@ -200,14 +386,15 @@ func (s *BaseNodeService) GetSkipCommKeysVerification() bool {
 
 func (s *BaseNodeService) SendMessage(dto *dto.MessageDTO) error {
 	if err := s.storage.Send(storage.Message{
-		ID:            dto.ID,
-		DkgRoundID:    dto.DkgRoundID,
-		Offset:        dto.Offset,
-		Event:         dto.Event,
-		Data:          dto.Data,
-		Signature:     dto.Signature,
-		SenderAddr:    dto.SenderAddr,
-		RecipientAddr: dto.RecipientAddr,
+		ID:             dto.ID,
+		DkgRoundID:     dto.DkgRoundID,
+		Offset:         dto.Offset,
+		Event:          dto.Event,
+		Data:           dto.Data,
+		Signature:      dto.Signature,
+		SenderAddr:     dto.SenderAddr,
+		RecipientAddr:  dto.RecipientAddr,
+		BoardSignature: dto.BoardSignature,
 	}); err != nil {
 		return fmt.Errorf("failed to post message: %w", err)
 	}
@@ -268,11 +455,9 @@ func (s *BaseNodeService) executeOperation(operation *types.Operation) error {
 		for i, message := range operation.ResultMsgs {
 			message.SenderAddr = s.GetUsername()
 
-			sig, err := s.signMessage(message.Bytes())
-			if err != nil {
+			if err := s.signMessage(&message); err != nil {
 				return fmt.Errorf("failed to sign a message: %w", err)
 			}
-			message.Signature = sig
 
 			operation.ResultMsgs[i] = message
 		}
@@ -298,20 +483,33 @@ func (s *BaseNodeService) executeOperation(operation *types.Operation) error {
 		}
 	}
 
+	s.recordAudit(audit.ActionOperationProcessed, operation, map[string]interface{}{
+		"event":           operation.Event,
+		"result_messages": len(operation.ResultMsgs),
+	})
+
 	if err := s.opService.DeleteOperation(operation); err != nil {
 		return fmt.Errorf("failed to DeleteOperation: %w", err)
 	}
+	s.recordAudit(audit.ActionOperationDeleted, operation, map[string]interface{}{})
 
 	return nil
 }
 
-func (s *BaseNodeService) signMessage(message []byte) ([]byte, error) {
-	keyPair, err := s.keyStore.LoadKeys(s.userName, "")
+// signMessage signs the message data and, for boards which authenticate writes, the message headers with the data
+func (s *BaseNodeService) signMessage(message *storage.Message) error {
+	signature, err := s.signer.Sign(message.Bytes())
+	if err != nil {
+		return err
+	}
+	boardSignature, err := s.signer.Sign(message.BoardBytes())
 	if err != nil {
-		return nil, fmt.Errorf("failed to LoadKeys: %w", err)
+		return err
 	}
 
-	return ed25519.Sign(keyPair.Priv, message), nil
+	message.Signature = signature
+	message.BoardSignature = boardSignature
+	return nil
 }
 
 func (s *BaseNodeService) verifyMessage(fsmInstance *state_machines.FSMInstance, message storage.Message) error {
@@ -330,6 +528,64 @@ func (s *BaseNodeService) verifyMessage(fsmInstance *state_machines.FSMInstance,
 	return nil
 }
 
+// verifyResharingProposal checks that a resharing proposal is signed by a participant of the reshared round and
+// that the round data in the proposal (master key, public polynomial, threshold and participants) match the FSM
+// of the reshared round held by the node, so the proposer can't substitute any of them
+func (s *BaseNodeService) verifyResharingProposal(message storage.Message) error {
+	var request requests.ResharingProposalRequest
+	if err := json.Unmarshal(message.Data, &request); err != nil {
+		return fmt.Errorf("failed to unmarshal ResharingProposalRequest: %w", err)
+	}
+
+	oldFSMInstance, err := s.fsmService.GetFSMInstance(request.OldDkgID, false)
+	if err != nil {
+		return fmt.Errorf("failed to get FSM instance of the reshared round: %w", err)
+	}
+
+	if err = s.verifyMessage(oldFSMInstance, message); err != nil {
+		return fmt.Errorf("the proposal is not signed by a participant of the reshared round: %w", err)
+	}
+
+	payload := oldFSMInstance.FSMDump().Payload
+	if payload.DKGProposalPayload == nil || payload.SignatureProposalPayload == nil {
+		return fmt.Errorf("DKG round %s is not finished", request.OldDkgID)
+	}
+
+	if request.OldThreshold != payload.GetThreshold() {
+		return fmt.Errorf("threshold mismatch: %d != %d", request.OldThreshold, payload.GetThreshold())
+	}
+	if request.OldKeyringVersion != payload.DKGProposalPayload.KeyringVersion {
+		return fmt.Errorf("keyring version mismatch: %d != %d",
+			request.OldKeyringVersion, payload.DKGProposalPayload.KeyringVersion)
+	}
+	if !bytes.Equal(request.OldPubPolyBz, payload.DKGProposalPayload.PubPolyBz) {
+		return errors.New("public polynomial mismatch")
+	}
+	for _, participant := range payload.DKGProposalPayload.Quorum {
+		if !bytes.Equal(request.OldMasterKey, participant.DkgMasterKey) {
+			return errors.New("master key mismatch")
+		}
+	}
+
+	oldQuorum := payload.SignatureProposalPayload.Quorum
+	if len(request.OldParticipants) != len(oldQuorum) {
+		return fmt.Errorf("participants count mismatch: %d != %d", len(request.OldParticipants), len(oldQuorum))
+	}
+	for _, participant := range request.OldParticipants {
+		oldParticipant, ok := oldQuorum[participant.ParticipantId]
+		if !ok {
+			return fmt.Errorf("unknown participant #%d", participant.ParticipantId)
+		}
+		if participant.Username != oldParticipant.Username ||
+			!bytes.Equal(participant.PubKey, oldParticipant.PubKey) ||
+			!bytes.Equal(participant.DkgPubKey, oldParticipant.DkgPubKey) {
+			return fmt.Errorf("participant #%d mismatch", participant.ParticipantId)
+		}
+	}
+
+	return nil
+}
+
 func (s *BaseNodeService) StartDKG(dto *dto.StartDkgDTO) error {
 	dkgRoundID := sha256.Sum256(dto.Payload)
 	message, err := s.buildMessage(hex.EncodeToString(dkgRoundID[:]), spf.EventInitProposal, dto.Payload)
@@ -353,13 +609,10 @@ func (s *BaseNodeService) buildMessage(dkgRoundID string, event fsm.Event, data
 		Data:       data,
 		SenderAddr: s.GetUsername(),
 	}
-	signature, err := s.signMessage(message.Bytes())
-
-	if err != nil {
+	if err := s.signMessage(&message); err != nil {
 		return nil, fmt.Errorf("failed to sign message: %w", err)
 	}
 
-	message.Signature = signature
 	return &message, nil
 }
 
@@ -443,21 +696,165 @@ func (s *BaseNodeService) ProposeSignMessages(dtoMsg *dto.ProposeSignBatchMessag
 	return nil
 }
 
-func (s *BaseNodeService) ApproveParticipation(dto *dto.OperationIdDTO) error {
-	operation, err := s.getOperation(dto.OperationID)
+// ProposeShareRefresh proposes a refresh of the key shares of a finished DKG round, the group public key is not changed
+func (s *BaseNodeService) ProposeShareRefresh(dto *dto.DkgIdDTO) error {
+	fsmInstance, err := s.fsmService.GetFSMInstance(dto.DkgID, false)
+	if err != nil {
+		return fmt.Errorf("failed to get FSM instance: %w", err)
+	}
 
+	fsmState, err := fsmInstance.State()
 	if err != nil {
-		return err
+		return fmt.Errorf("failed to determine FSM instance state: %w", err)
+	}
+
+	if fsmState != sif.StateSigningIdle {
+		return fmt.Errorf("required FSM state is %s, but have %s", sif.StateSigningIdle, fsmState)
+	}
+
+	participantID, err := fsmInstance.GetIDByUsername(s.GetUsername())
+	if err != nil {
+		return fmt.Errorf("failed to get participantID: %w", err)
+	}
+
+	proposal := requests.ShareRefreshProposalRequest{
+		ParticipantId: participantID,
+		CreatedAt:     time.Now(),
+	}
+
+	proposalBz, err := json.Marshal(proposal)
+	if err != nil {
+		return fmt.Errorf("failed to marshal ShareRefreshProposalRequest: %w", err)
+	}
+
+	message, err := s.buildMessage(dto.DkgID, sif.EventShareRefreshPropose, proposalBz)
+	if err != nil {
+		return fmt.Errorf("failed to build message: %w", err)
+	}
+
+	if err = s.storage.Send(*message); err != nil {
+		return fmt.Errorf("failed to send message: %w", err)
+	}
+
+	return nil
+}
+
+// ProposeResharing proposes to hand the master key of a finished DKG round over to a new committee with
+// another threshold. The dealers deal their key shares in a new round, the group public key is not changed
+func (s *BaseNodeService) ProposeResharing(dto *dto.ProposeResharingDTO) error {
+	fsmInstance, err := s.fsmService.GetFSMInstance(dto.DkgID, false)
+	if err != nil {
+		return fmt.Errorf("failed to get FSM instance: %w", err)
+	}
+
+	fsmState, err := fsmInstance.State()
+	if err != nil {
+		return fmt.Errorf("failed to determine FSM instance state: %w", err)
 	}
 
-	if fsm.State(operation.Type) != spf.StateAwaitParticipantsConfirmations {
-		return fmt.Errorf("cannot approve participation with operationID %s", dto.OperationID)
+	if fsmState != sif.StateSigningIdle {
+		return fmt.Errorf("required FSM state is %s, but have %s", sif.StateSigningIdle, fsmState)
+	}
+
+	var committee requests.SignatureProposalParticipantsListRequest
+	if err = json.Unmarshal(dto.Payload, &committee); err != nil {
+		return fmt.Errorf("failed to unmarshal the new committee: %w", err)
+	}
+
+	payload := fsmInstance.FSMDump().Payload
+	proposal := requests.ResharingProposalRequest{
+		OldDkgID:          dto.DkgID,
+		OldThreshold:      payload.GetThreshold(),
+		OldKeyringVersion: payload.DKGProposalPayload.KeyringVersion,
+		OldPubPolyBz:      payload.DKGProposalPayload.PubPolyBz,
+		Dealers:           dto.Dealers,
+		Participants:      committee.Participants,
+		SigningThreshold:  committee.SigningThreshold,
+		CreatedAt:         time.Now(),
+		Deadlines:         committee.Deadlines,
+	}
+	for _, participant := range payload.DKGProposalPayload.Quorum {
+		proposal.OldMasterKey = participant.DkgMasterKey
+		break
+	}
+	for _, participant := range payload.SignatureProposalPayload.Quorum.GetOrderedParticipants() {
+		proposal.OldParticipants = append(proposal.OldParticipants, &requests.ResharingOldParticipantEntry{
+			ParticipantId: participant.ParticipantID,
+			Username:      participant.Username,
+			PubKey:        participant.PubKey,
+			DkgPubKey:     participant.DkgPubKey,
+		})
+		// every old participant deals by default
+		if len(dto.Dealers) == 0 {
+			proposal.Dealers = append(proposal.Dealers, participant.Username)
+		}
+	}
+
+	if err = proposal.Validate(); err != nil {
+		return fmt.Errorf("invalid resharing proposal: %w", err)
+	}
+
+	proposalBz, err := json.Marshal(proposal)
+	if err != nil {
+		return fmt.Errorf("failed to marshal ResharingProposalRequest: %w", err)
+	}
+
+	dkgRoundID := sha256.Sum256(proposalBz)
+	message, err := s.buildMessage(hex.EncodeToString(dkgRoundID[:]), spf.EventInitResharingProposal, proposalBz)
+	if err != nil {
+		return fmt.Errorf("failed to build message: %w", err)
+	}
+
+	if err = s.storage.Send(*message); err != nil {
+		return fmt.Errorf("failed to send message: %w", err)
+	}
+
+	return nil
+}
+
+func (s *BaseNodeService) ApproveParticipation(dto *dto.OperationIdDTO) error {
+	return s.replyToProposal(dto.OperationID, true, "")
+}
+
+// DeclineParticipation declines participation in a DKG round, the reason is recorded in the FSM dump of the round
+func (s *BaseNodeService) DeclineParticipation(dto *dto.DeclineParticipationDTO) error {
+	return s.replyToProposal(dto.OperationID, false, dto.Reason)
+}
+
+// replyToProposal confirms or declines a proposal of a DKG round or of a resharing
+func (s *BaseNodeService) replyToProposal(operationID string, confirm bool, reason string) error {
+	operation, err := s.getOperation(operationID)
+
+	if err != nil {
+		return err
 	}
 
-	var payload responses.SignatureProposalParticipantInvitationsResponse
+	var (
+		payload responses.SignatureProposalParticipantInvitationsResponse
+		event   fsm.Event
+	)
 
-	if err = json.Unmarshal(operation.Payload, &payload); err != nil {
-		return fmt.Errorf("failed to unmarshal payload: %w", err)
+	switch fsm.State(operation.Type) {
+	case spf.StateAwaitParticipantsConfirmations:
+		if err = json.Unmarshal(operation.Payload, &payload); err != nil {
+			return fmt.Errorf("failed to unmarshal payload: %w", err)
+		}
+		event = spf.EventDeclineProposal
+		if confirm {
+			event = spf.EventConfirmSignatureProposal
+		}
+	case rf.StateResharingAwaitParticipantsConfirmations:
+		var resharingPayload responses.ResharingProposalInvitationResponse
+		if err = json.Unmarshal(operation.Payload, &resharingPayload); err != nil {
+			return fmt.Errorf("failed to unmarshal payload: %w", err)
+		}
+		payload = resharingPayload.Participants
+		event = rf.EventDeclineResharingProposal
+		if confirm {
+			event = rf.EventConfirmResharingProposal
+		}
+	default:
+		return fmt.Errorf("cannot reply to a DKG proposal with operationID %s", operationID)
 	}
 
 	pid := emptyParticipantId
@@ -475,6 +872,10 @@ func (s *BaseNodeService) ApproveParticipation(dto *dto.OperationIdDTO) error {
 	fsmRequest := requests.SignatureProposalParticipantRequest{
 		ParticipantId: pid,
 		CreatedAt:     operation.CreatedAt,
+		Reason:        reason,
+	}
+	if err = fsmRequest.Validate(); err != nil {
+		return fmt.Errorf("invalid FSM request: %w", err)
 	}
 
 	reqBz, err := json.Marshal(fsmRequest)
@@ -482,7 +883,7 @@ func (s *BaseNodeService) ApproveParticipation(dto *dto.OperationIdDTO) error {
 		return fmt.Errorf("failed to generate FSM request: %w", err)
 	}
 
-	operation.Event = spf.EventConfirmSignatureProposal
+	operation.Event = event
 	operation.ResultMsgs = append(operation.ResultMsgs, storage.Message{
 		Event:         string(operation.Event),
 		Data:          reqBz,
@@ -517,6 +918,12 @@ func (s *BaseNodeService) SaveOffset(dto *dto.StateOffsetDTO) error {
 	if err != nil {
 		return fmt.Errorf("failed to save offset:  %w", err)
 	}
+	s.recordAudit(audit.ActionOffsetChanged, nil, map[string]interface{}{"offset": dto.Offset})
+
+	// the chain is verified again starting from the new offset
+	if err = s.resetChainHead(); err != nil {
+		return err
+	}
 
 	return nil
 }
@@ -561,7 +968,7 @@ func (s *BaseNodeService) reinitDKG(message storage.Message) error {
 		if msg.RecipientAddr == "" || msg.RecipientAddr == s.GetUsername() {
 			operation, err := s.processMessage(msg)
 			if err != nil {
-				s.Logger.Log("failed to process operation:  %w", err)
+				s.messageLogger(msg).Errorf("failed to process operation: %v", err)
 			}
 			if operation != nil {
 				operations = append(operations, operation)
@@ -582,6 +989,8 @@ func (s *BaseNodeService) reinitDKG(message storage.Message) error {
 	if err := s.opService.PutOperation(operation); err != nil {
 		return fmt.Errorf("failed to PutOperation: %w", err)
 	}
+	s.publishOperation(message, operation)
+	s.recordAudit(audit.ActionOperationCreated, operation, map[string]interface{}{"offset": message.Offset})
 
 	// save new comm keys into FSM to verify future messages
 	fsmInstance, err := s.fsmService.GetFSMInstance(req.DKGID, true)
@@ -616,7 +1025,17 @@ func (s *BaseNodeService) processSignature(message storage.Message) error {
 		signatures[i].Username = message.SenderAddr
 		signatures[i].DKGRoundID = message.DkgRoundID
 	}
-	return s.sigService.SaveSignatures(signatures)
+	if err = s.sigService.SaveSignatures(signatures); err != nil {
+		return err
+	}
+	for _, sig := range signatures {
+		s.events.Publish(events.TypeSignature, message.DkgRoundID, message.Offset, events.SignatureEvent{
+			BatchID:   sig.BatchID,
+			MessageID: sig.MessageID,
+			Username:  sig.Username,
+		})
+	}
+	return nil
 }
 
 // processBatchSignature saves a broadcasted reconstructed batch signatures to a LevelDB
@@ -667,9 +1086,18 @@ func (s *BaseNodeService) processMessage(message storage.Message) (*types.Operat
 	if err != nil {
 		return nil, fmt.Errorf("failed to getFSMInstance: %w", err)
 	}
+	states := []fsm.State{fsmInstance.FSMDump().State}
+	l := s.messageLogger(message)
 
-	// we can't verify a message at this moment, cause we don't have public keys of participants
-	if fsm.Event(message.Event) != spf.EventInitProposal {
+	switch fsm.Event(message.Event) {
+	case spf.EventInitProposal:
+		// we can't verify a message at this moment, cause we don't have public keys of participants
+	case spf.EventInitResharingProposal:
+		// the participants of the new round are not known yet, the proposal is checked against the reshared round
+		if err := s.verifyResharingProposal(message); err != nil {
+			return nil, fmt.Errorf("failed to verifyResharingProposal %+v: %w", message, err)
+		}
+	default:
 		if err := s.verifyMessage(fsmInstance, message); err != nil {
 			return nil, fmt.Errorf("failed to verifyMessage %+v: %w", message, err)
 		}
@@ -690,7 +1118,7 @@ func (s *BaseNodeService) processMessage(message storage.Message) (*types.Operat
 		if !ok {
 			return nil, fmt.Errorf("failed to convert request to SignatureProposalConfirmationErrorRequest:  %w", err)
 		}
-		s.Logger.Log("Participant #%d got an error during signature reconstruction process: %v", errorRequestTyped.ParticipantId, errorRequestTyped.Error)
+		l.Warnf("Participant #%d got an error during signature reconstruction process: %v", errorRequestTyped.ParticipantId, errorRequestTyped.Error)
 		return nil, nil
 	}
 
@@ -700,28 +1128,38 @@ func (s *BaseNodeService) processMessage(message storage.Message) (*types.Operat
 		if fsmInstance.FSMDump().Payload.DKGProposalPayload != nil {
 			for _, participant := range fsmInstance.FSMDump().Payload.DKGProposalPayload.Quorum {
 				if participant.Error != nil {
-					s.Logger.Log("Participant %s got an error during DKG process: %s. DKG aborted\n",
+					l.Warnf("Participant %s got an error during DKG process: %s. DKG aborted",
 						participant.Username, participant.Error.Error())
 					// if we have an error during DKG, abort the whole DKG procedure.
 					return nil, nil
 				}
 			}
 		}
+		if fsmInstance.FSMDump().Payload.ResharingPayload != nil && fsmInstance.FSMDump().Payload.DKGProposalPayload == nil {
+			for _, participant := range fsmInstance.FSMDump().Payload.ResharingPayload.Quorum {
+				if participant.Error != nil {
+					l.Warnf("Participant %s got an error during resharing: %s. Resharing aborted",
+						participant.Username, participant.Error.Error())
+					return nil, nil
+				}
+			}
+		}
 		if fsmInstance.FSMDump().Payload.SigningProposalPayload != nil {
 			for _, participant := range fsmInstance.FSMDump().Payload.SigningProposalPayload.Quorum {
 				if participant.Error != nil {
-					s.Logger.Log("Participant %s got an error during signing procedure: %s. Signing procedure aborted\n",
+					l.Warnf("Participant %s got an error during signing procedure: %s. Signing procedure aborted",
 						participant.Username, participant.Error.Error())
 					break
 				}
 			}
 			//if we have an error during signing procedure, start a new signing procedure
-			_, fsmDump, err := fsmInstance.Do(sif.EventSigningRestart, requests.DefaultRequest{
+			resp, fsmDump, err := fsmInstance.Do(sif.EventSigningRestart, requests.DefaultRequest{
 				CreatedAt: time.Now(),
 			})
 			if err != nil {
 				return nil, fmt.Errorf("failed to Do operation in FSM: %w", err)
 			}
+			states = append(states, resp.State)
 
 			if err := s.fsmService.SaveFSM(message.DkgRoundID, fsmDump); err != nil {
 				return nil, fmt.Errorf("failed to SaveFSM: %w", err)
@@ -734,22 +1172,28 @@ func (s *BaseNodeService) processMessage(message storage.Message) (*types.Operat
 	if strings.HasSuffix(string(fsmInstance.FSMDump().State), "_timeout") {
 		if strings.HasPrefix(string(fsmInstance.FSMDump().State), "state_sig_") ||
 			strings.HasPrefix(string(fsmInstance.FSMDump().State), "state_dkg") {
-			s.Logger.Log("DKG process with ID \"%s\" aborted cause of timeout\n",
+			l.Warnf("DKG process with ID \"%s\" aborted cause of timeout",
 				fsmInstance.FSMDump().Payload.DkgId)
 			// if we have an error during DKG, abort the whole DKG procedure.
 			return nil, nil
 		}
+		if strings.HasPrefix(string(fsmInstance.FSMDump().State), "state_resharing_") {
+			l.Warnf("Resharing with ID \"%s\" aborted cause of timeout",
+				fsmInstance.FSMDump().Payload.DkgId)
+			return nil, nil
+		}
 		if strings.HasPrefix(string(fsmInstance.FSMDump().State), "state_signing_") {
-			s.Logger.Log("Signing process with ID \"%s\" aborted cause of timeout\n",
+			l.Warnf("Signing process with ID \"%s\" aborted cause of timeout",
 				fsmInstance.FSMDump().Payload.SigningProposalPayload.BatchID)
 
 			//if we have an error during signing procedure, start a new signing procedure
-			_, fsmDump, err := fsmInstance.Do(sif.EventSigningRestart, requests.DefaultRequest{
+			resp, fsmDump, err := fsmInstance.Do(sif.EventSigningRestart, requests.DefaultRequest{
 				CreatedAt: time.Now(),
 			})
 			if err != nil {
 				return nil, fmt.Errorf("failed to Do operation in FSM: %w", err)
 			}
+			states = append(states, resp.State)
 
 			if err := s.fsmService.SaveFSM(message.DkgRoundID, fsmDump); err != nil {
 				return nil, fmt.Errorf("failed to SaveFSM: %w", err)
@@ -757,6 +1201,11 @@ func (s *BaseNodeService) processMessage(message storage.Message) (*types.Operat
 		}
 	}
 
+	if isOutdatedTimeout(fsm.Event(message.Event), fsmInstance.FSMDump().State) {
+		l.Infof("Stage of %s is already finished, skip it", message.Event)
+		return nil, nil
+	}
+
 	fsmReq, err := types.FSMRequestFromMessage(message)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get FSMRequestFromMessage:  %w", err)
@@ -766,8 +1215,20 @@ func (s *BaseNodeService) processMessage(message storage.Message) (*types.Operat
 	if err != nil {
 		return nil, fmt.Errorf("failed to Do operation in FSM: %w", err)
 	}
+	states = append(states, resp.State)
 
-	s.Logger.Log("message %s done successfully from %s", message.Event, message.SenderAddr)
+	if types.IsTimeoutEvent(fsm.Event(message.Event)) {
+		if stage := states[len(states)-2]; resp.State == stage {
+			l.Infof("Timeout of stage %s is posted by %s", stage, message.SenderAddr)
+		} else {
+			l.Warnf("Stage %s is canceled by timeouts, the last one is posted by %s", stage, message.SenderAddr)
+			if err := s.dropOperations(message); err != nil {
+				return nil, fmt.Errorf("failed to drop operations of the round: %w", err)
+			}
+		}
+	}
+
+	l.Infof("message %s done successfully from %s", message.Event, message.SenderAddr)
 
 	// switch FSM state by hand due to implementation specifics
 	if resp.State == spf.StateSignatureProposalCollected {
@@ -775,12 +1236,14 @@ func (s *BaseNodeService) processMessage(message storage.Message) (*types.Operat
 		if err != nil {
 			return nil, fmt.Errorf("failed get state_machines from dump: %w", err)
 		}
+		// the DKG deadline is counted from the start proposal, so it's the same for every participant
 		resp, fsmDump, err = fsmInstance.Do(dpf.EventDKGInitProcess, requests.DefaultRequest{
-			CreatedAt: time.Now(),
+			CreatedAt: fsmInstance.FSMDump().Payload.SignatureProposalPayload.CreatedAt,
 		})
 		if err != nil {
 			return nil, fmt.Errorf("failed to Do operation in FSM: %w", err)
 		}
+		states = append(states, resp.State)
 	}
 	if resp.State == dpf.StateDkgMasterKeyCollected {
 		fsmInstance, err = state_machines.FromDump(fsmDump)
@@ -793,6 +1256,21 @@ func (s *BaseNodeService) processMessage(message storage.Message) (*types.Operat
 		if err != nil {
 			return nil, fmt.Errorf("failed to Do operation in FSM: %w", err)
 		}
+		states = append(states, resp.State)
+	}
+	if resp.State == sif.StateShareRefreshProposed {
+		fsmInstance, err = state_machines.FromDump(fsmDump)
+		if err != nil {
+			return nil, fmt.Errorf("failed get state_machines from dump: %w", err)
+		}
+		// the refresh deadline is counted from the proposal, so it's the same for every participant
+		resp, fsmDump, err = fsmInstance.Do(srf.EventShareRefreshInit, requests.DefaultRequest{
+			CreatedAt: fsmInstance.FSMDump().Payload.ShareRefreshPayload.CreatedAt,
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to Do operation in FSM: %w", err)
+		}
+		states = append(states, resp.State)
 	}
 
 	var operation *types.Operation
@@ -804,8 +1282,20 @@ func (s *BaseNodeService) processMessage(message storage.Message) (*types.Operat
 		dpf.StateDkgDealsAwaitConfirmations,
 		dpf.StateDkgResponsesAwaitConfirmations,
 		dpf.StateDkgMasterKeyAwaitConfirmations,
-		sif.StateSigningAwaitPartialSigns:
-		if resp.Data != nil {
+		sif.StateSigningAwaitPartialSigns,
+		srf.StateShareRefreshCommitsAwaitConfirmations,
+		srf.StateShareRefreshDealsAwaitConfirmations,
+		srf.StateShareRefreshResponsesAwaitConfirmations,
+		srf.StateShareRefreshKeyringAwaitConfirmations,
+		rf.StateResharingAwaitParticipantsConfirmations,
+		rf.StateResharingCommitsAwaitConfirmations,
+		rf.StateResharingDealsAwaitConfirmations,
+		rf.StateResharingResponsesAwaitConfirmations,
+		rf.StateResharingKeyringAwaitConfirmations:
+		// at some stages of a resharing only a part of the participants has to send a message
+		if resp.Data != nil && !fsmInstance.FSMDump().IsStageParticipant(s.GetUsername()) {
+			l.Debugf("State %s does not require an operation from %s", resp.State, s.GetUsername())
+		} else if resp.Data != nil {
 			operationPayloadBz, err := json.Marshal(resp.Data)
 			if err != nil {
 				return nil, fmt.Errorf("failed to marshal FSM response: %w", err)
@@ -816,9 +1306,10 @@ func (s *BaseNodeService) processMessage(message storage.Message) (*types.Operat
 				operationPayloadBz,
 				resp.State,
 			)
+			l.With(logger.OperationID(operation.ID)).Infof("Operation %s is waiting for the airgapped machine", operation.Type)
 		}
 	case sif.StateSigningPartialSignsCollected:
-		s.Logger.Log("Collected enough partial signatures. Full signature reconstruction just started.")
+		l.Infof("Collected enough partial signatures. Full signature reconstruction just started.")
 		signingProcessResponse, ok := resp.Data.(responses.SigningProcessParticipantResponse)
 		if !ok {
 			return nil, fmt.Errorf("failed to cast fsm response payload to responses.SigningProcessParticipantResponse: %w", err)
@@ -826,8 +1317,10 @@ func (s *BaseNodeService) processMessage(message storage.Message) (*types.Operat
 
 		reconstructedSignatures, err := reconstructThresholdSignature(fsmInstance, signingProcessResponse)
 		if err != nil {
+			metrics.SignatureReconstructions.WithLabelValues(metrics.ResultFailure).Inc()
 			return nil, fmt.Errorf("failed to reconstruct signatures: %w", err)
 		}
+		metrics.SignatureReconstructions.WithLabelValues(metrics.ResultSuccess).Inc()
 
 		err = s.broadcastReconstructedSignatures(message, reconstructedSignatures)
 		if err != nil {
@@ -835,7 +1328,7 @@ func (s *BaseNodeService) processMessage(message storage.Message) (*types.Operat
 		}
 
 	default:
-		s.Logger.Log("State %s does not require an operation", resp.State)
+		l.Debugf("State %s does not require an operation", resp.State)
 	}
 
 	// switch FSM state by hand due to implementation specifics
@@ -844,12 +1337,41 @@ func (s *BaseNodeService) processMessage(message storage.Message) (*types.Operat
 		if err != nil {
 			return nil, fmt.Errorf("failed get state_machines from dump: %w", err)
 		}
-		_, fsmDump, err = fsmInstance.Do(sif.EventSigningRestart, requests.DefaultRequest{
+		resp, fsmDump, err = fsmInstance.Do(sif.EventSigningRestart, requests.DefaultRequest{
 			CreatedAt: time.Now(),
 		})
 		if err != nil {
 			return nil, fmt.Errorf("failed to Do operation in FSM: %w", err)
 		}
+		states = append(states, resp.State)
+	}
+	// a finished or canceled share refresh returns the round to signing, a canceled one keeps the current keyring
+	if resp.State == srf.StateShareRefreshKeyringCollected || isCanceledShareRefresh(resp.State) {
+		fsmInstance, err = state_machines.FromDump(fsmDump)
+		if err != nil {
+			return nil, fmt.Errorf("failed get state_machines from dump: %w", err)
+		}
+		refresh := fsmInstance.FSMDump().Payload.ShareRefreshPayload
+		if resp.State == srf.StateShareRefreshKeyringCollected {
+			l.Infof("Share refresh is finished, keyring version is %d", refresh.KeyringVersion)
+		} else {
+			for _, participant := range refresh.Quorum {
+				if participant.Error != nil {
+					l.Warnf("Participant %s got an error during share refresh: %s", participant.Username, participant.Error.Error())
+				}
+			}
+			l.Warnf("Share refresh is canceled in %s, the current keyring is kept", resp.State)
+			if err = s.dropOperations(message); err != nil {
+				return nil, fmt.Errorf("failed to drop operations of the round: %w", err)
+			}
+		}
+		resp, fsmDump, err = fsmInstance.Do(srf.EventShareRefreshFinish, requests.DefaultRequest{
+			CreatedAt: time.Now(),
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to Do operation in FSM: %w", err)
+		}
+		states = append(states, resp.State)
 	}
 
 	// save signing data to the same storage as we save signatures
@@ -863,10 +1385,16 @@ func (s *BaseNodeService) processMessage(message storage.Message) (*types.Operat
 	if err := s.fsmService.SaveFSM(message.DkgRoundID, fsmDump); err != nil {
 		return nil, fmt.Errorf("failed to SaveFSM: %w", err)
 	}
+	s.publishStateChanges(message, states)
 
 	return operation, nil
 }
 
+func isCanceledShareRefresh(state fsm.State) bool {
+	return strings.HasPrefix(string(state), "state_share_refresh_") &&
+		(strings.HasSuffix(string(state), "_error") || strings.HasSuffix(string(state), "_timeout"))
+}
+
 func (s *BaseNodeService) broadcastReconstructedSignatures(message storage.Message, sigs []fsmtypes.ReconstructedSignature) error {
 	data, err := json.Marshal(sigs)
 	if err != nil {
@@ -4,6 +4,8 @@ import (
 	"context"
 	"crypto/ed25519"
 	"encoding/json"
+	"errors"
+	"path/filepath"
 	"testing"
 	"time"
 
@@ -14,7 +16,10 @@ import (
 	"github.com/lidofinance/dc4bc/client/config"
 	"github.com/lidofinance/dc4bc/client/modules/keystore"
 	"github.com/lidofinance/dc4bc/client/modules/logger"
+	"github.com/lidofinance/dc4bc/client/modules/signer"
+	clientState "github.com/lidofinance/dc4bc/client/modules/state"
 	"github.com/lidofinance/dc4bc/client/services"
+	"github.com/lidofinance/dc4bc/client/types"
 	"github.com/lidofinance/dc4bc/fsm/state_machines"
 	spf "github.com/lidofinance/dc4bc/fsm/state_machines/signature_proposal_fsm"
 	"github.com/lidofinance/dc4bc/fsm/types/requests"
@@ -35,20 +40,18 @@ func TestClient_ProcessMessage(t *testing.T) {
 	userName := "user_name"
 	dkgRoundID := "dkg_round_id"
 	state := clientMocks.NewMockState(ctrl)
-	keyStore := clientMocks.NewMockKeyStore(ctrl)
 	stg := storageMocks.NewMockStorage(ctrl)
 	fsmService := serviceMocks.NewMockFSMService(ctrl)
 	opService := serviceMocks.NewMockOperationService(ctrl)
 
 	testClientKeyPair := keystore.NewKeyPair()
-	keyStore.EXPECT().LoadKeys(userName, "").Times(1).Return(testClientKeyPair, nil)
 
 	opService.EXPECT().PutOperation(gomock.Any()).Times(1).Return(nil)
 
 	sp := services.ServiceProvider{}
 	sp.SetLogger(logger.NewLogger(userName))
 	sp.SetState(state)
-	sp.SetKeyStore(keyStore)
+	sp.SetSigner(signer.NewKeyPairSigner(testClientKeyPair))
 	sp.SetStorage(stg)
 	sp.SetFSMService(fsmService)
 	sp.SetOperationService(opService)
@@ -56,7 +59,7 @@ func TestClient_ProcessMessage(t *testing.T) {
 	// minimal config to make test
 	cfg := config.Config{
 		Username: userName,
-		KafkaStorageConfig: &config.KafkaStorageConfig{
+		StorageConfig: &config.StorageConfig{
 			Topic: "topic",
 		},
 	}
@@ -116,3 +119,175 @@ func TestClient_ProcessMessage(t *testing.T) {
 		req.NoError(err)
 	})
 }
+
+func TestClient_VerifyChain(t *testing.T) {
+	req := require.New(t)
+
+	state, err := clientState.NewLevelDBState(filepath.Join(t.TempDir(), "state"), "topic")
+	req.NoError(err)
+
+	clt := &BaseNodeService{
+		state:  state,
+		Logger: logger.NewLogger("user_name"),
+	}
+
+	msgs := make([]storage.Message, 4)
+	var prevHash []byte
+	for i := range msgs {
+		msgs[i] = storage.Message{
+			ID:     uuid.New().String(),
+			Offset: uint64(i),
+			Event:  "event",
+			Data:   []byte{byte(i)},
+		}
+		msgs[i].LinkToChain(prevHash)
+		prevHash = msgs[i].Hash
+	}
+
+	req.NoError(clt.verifyChain(msgs[0]))
+	req.NoError(clt.verifyChain(msgs[1]))
+	// already verified message
+	req.NoError(clt.verifyChain(msgs[1]))
+
+	// reordered messages
+	req.True(errors.Is(clt.verifyChain(msgs[0]), ErrChainBroken))
+
+	// modified message
+	modified := msgs[2]
+	modified.Data = []byte("modified")
+	req.True(errors.Is(clt.verifyChain(modified), ErrChainBroken))
+
+	// message linked to a different predecessor
+	forged := msgs[2]
+	forged.LinkToChain(msgs[0].Hash)
+	req.True(errors.Is(clt.verifyChain(forged), ErrChainBroken))
+
+	// message without a hash after the chain was started
+	unchained := msgs[2]
+	unchained.Hash = nil
+	req.True(errors.Is(clt.verifyChain(unchained), ErrChainBroken))
+
+	// a gap means that messages were dropped, the head is not moved
+	req.True(errors.Is(clt.verifyChain(msgs[3]), ErrChainBroken))
+	req.NoError(clt.verifyChain(msgs[2]))
+	req.NoError(clt.verifyChain(msgs[3]))
+
+	// the chain is verified again after the offset was changed manually
+	req.NoError(clt.resetChainHead())
+	req.NoError(clt.verifyChain(msgs[1]))
+}
+
+func TestClient_Checkpoints(t *testing.T) {
+	var (
+		req  = require.New(t)
+		ctrl = gomock.NewController(t)
+	)
+	defer ctrl.Finish()
+
+	state, err := clientState.NewLevelDBState(filepath.Join(t.TempDir(), "state"), "topic")
+	req.NoError(err)
+
+	userName := "user_name"
+	userKeyPair := keystore.NewKeyPair()
+	participantKeyPair := keystore.NewKeyPair()
+	stg := storageMocks.NewMockStorage(ctrl)
+
+	// checkpoints are verified with the keys of the participants of the rounds the node holds
+	dkgRound, err := state_machines.Create("dkg_round_id")
+	req.NoError(err)
+	participants := []*requests.SignatureProposalParticipantsEntry{
+		{Username: "participant", PubKey: participantKeyPair.Pub, DkgPubKey: make([]byte, 128)},
+	}
+	for _, username := range []string{userName, "111", "222"} {
+		participants = append(participants, &requests.SignatureProposalParticipantsEntry{
+			Username: username, PubKey: keystore.NewKeyPair().Pub, DkgPubKey: make([]byte, 128),
+		})
+	}
+	_, _, err = dkgRound.Do(spf.EventInitProposal, requests.SignatureProposalParticipantsListRequest{
+		Participants:     participants,
+		SigningThreshold: 2,
+		CreatedAt:        time.Now(),
+	})
+	req.NoError(err)
+	fsmService := serviceMocks.NewMockFSMService(ctrl)
+	fsmService.EXPECT().GetFSMList().AnyTimes().Return(map[string]string{"dkg_round_id": ""}, nil)
+	fsmService.EXPECT().GetFSMInstance("dkg_round_id", false).AnyTimes().Return(dkgRound, nil)
+
+	clt := &BaseNodeService{
+		userName:         userName,
+		pubKey:           userKeyPair.Pub,
+		state:            state,
+		storage:          stg,
+		signer:           signer.NewKeyPairSigner(userKeyPair),
+		fsmService:       fsmService,
+		Logger:           logger.NewLogger(userName),
+		checkpointPeriod: time.Minute,
+	}
+
+	for i := 0; i < 3; i++ {
+		req.NoError(clt.updateLogDigest(storage.Message{
+			ID:     uuid.New().String(),
+			Offset: uint64(i),
+			Event:  "event",
+			Data:   []byte{byte(i)},
+		}))
+	}
+
+	var posted storage.Message
+	stg.EXPECT().Send(gomock.Any()).Times(1).DoAndReturn(func(msgs ...storage.Message) error {
+		posted = msgs[0]
+		return nil
+	})
+	req.NoError(clt.postCheckpoint())
+	// nothing new since the last checkpoint
+	req.NoError(clt.postCheckpoint())
+
+	var ownCheckpoint types.Checkpoint
+	req.NoError(json.Unmarshal(posted.Data, &ownCheckpoint))
+	req.Equal(uint64(2), ownCheckpoint.Offset)
+
+	newCheckpointMessage := func(offset uint64, checkpoint types.Checkpoint) storage.Message {
+		data, err := json.Marshal(checkpoint)
+		req.NoError(err)
+
+		message := storage.Message{
+			Offset:     offset,
+			Event:      string(types.BoardCheckpoint),
+			Data:       data,
+			SenderAddr: "participant",
+		}
+		message.Signature = ed25519.Sign(participantKeyPair.Priv, message.Bytes())
+		return message
+	}
+
+	// the same view of the log
+	req.NoError(clt.ProcessMessage(newCheckpointMessage(3, ownCheckpoint)))
+	divergences, err := clt.GetCheckpointDivergences()
+	req.NoError(err)
+	req.Empty(divergences)
+
+	// a forked log
+	req.NoError(clt.ProcessMessage(newCheckpointMessage(4, types.Checkpoint{Offset: 1, Digest: []byte("digest")})))
+	divergences, err = clt.GetCheckpointDivergences()
+	req.NoError(err)
+	req.Len(divergences, 1)
+	req.Equal("participant", divergences[0].Participant)
+	req.Equal(uint64(1), divergences[0].Offset)
+	req.NotEmpty(divergences[0].OurDigest)
+
+	// a forged checkpoint
+	forged := newCheckpointMessage(5, ownCheckpoint)
+	forged.Signature = ed25519.Sign(userKeyPair.Priv, forged.Bytes())
+	req.Error(clt.ProcessMessage(forged))
+
+	// a checkpoint signed with a key unknown to the node
+	forgerKeyPair := keystore.NewKeyPair()
+	forged = newCheckpointMessage(6, types.Checkpoint{Offset: 1, Digest: []byte("digest")})
+	forged.Signature = ed25519.Sign(forgerKeyPair.Priv, forged.Bytes())
+	req.Error(clt.ProcessMessage(forged))
+
+	// a checkpoint of an unknown sender
+	unknown := newCheckpointMessage(7, ownCheckpoint)
+	unknown.SenderAddr = "stranger"
+	req.Error(clt.ProcessMessage(unknown))
+}
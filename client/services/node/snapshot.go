@@ -0,0 +1,24 @@
+package node
+
+import (
+	"fmt"
+
+	"github.com/lidofinance/dc4bc/storage"
+)
+
+// ExportSnapshot reads the whole board log available to the node, so it can be archived
+// and replayed later (see snapshot_storage). The snapshot digest is signed with the node key
+func (s *BaseNodeService) ExportSnapshot() (*storage.Snapshot, error) {
+	snapshot, err := storage.TakeSnapshot(s.ctx, s.storage)
+	if err != nil {
+		return nil, fmt.Errorf("failed to take snapshot: %w", err)
+	}
+
+	if snapshot.Signature, err = s.signer.Sign(snapshot.Digest); err != nil {
+		return nil, fmt.Errorf("failed to sign snapshot: %w", err)
+	}
+	snapshot.Signer = s.userName
+	snapshot.PubKey = s.signer.PubKey()
+
+	return snapshot, nil
+}
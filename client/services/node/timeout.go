@@ -0,0 +1,103 @@
+package node
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/lidofinance/dc4bc/client/modules/audit"
+	"github.com/lidofinance/dc4bc/client/modules/logger"
+	"github.com/lidofinance/dc4bc/client/types"
+	"github.com/lidofinance/dc4bc/fsm/fsm"
+	"github.com/lidofinance/dc4bc/fsm/types/requests"
+	"github.com/lidofinance/dc4bc/storage"
+)
+
+// postTimeouts posts a timeout event for every DKG round the node participates in, if the deadline of the stage
+// the round awaits in has passed. The stage is canceled when n-t+1 participants posted its timeout, the timeouts
+// posted after that are skipped
+func (s *BaseNodeService) postTimeouts() error {
+	fsmList, err := s.fsmService.GetFSMList()
+	if err != nil {
+		return fmt.Errorf("failed to get FSM list: %w", err)
+	}
+
+	now := time.Now()
+	for dkgID, state := range fsmList {
+		event, ok := types.TimeoutEvents[fsm.State(state)]
+		if !ok {
+			continue
+		}
+
+		fsmInstance, err := s.fsmService.GetFSMInstance(dkgID, false)
+		if err != nil {
+			return fmt.Errorf("failed to get FSM instance: %w", err)
+		}
+		if _, err = fsmInstance.GetPubKeyByUsername(s.GetUsername()); err != nil {
+			// the node does not participate in the round
+			continue
+		}
+		expiresAt, ok := fsmInstance.FSMDump().StageExpiresAt()
+		if !ok || now.Before(expiresAt) {
+			continue
+		}
+
+		key := fmt.Sprintf("%s_%s_%d", dkgID, state, expiresAt.UnixNano())
+		s.Lock()
+		posted := s.postedTimeouts[key]
+		s.Unlock()
+		if posted {
+			continue
+		}
+
+		data, err := json.Marshal(requests.TimeoutRequest{CreatedAt: expiresAt})
+		if err != nil {
+			return fmt.Errorf("failed to marshal timeout request: %w", err)
+		}
+		message, err := s.buildMessage(dkgID, event, data)
+		if err != nil {
+			return err
+		}
+		if err = s.storage.Send(*message); err != nil {
+			return fmt.Errorf("failed to post timeout: %w", err)
+		}
+		s.Logger.With(logger.DkgID(dkgID), logger.Event(event)).Warnf("Deadline of %s has passed at %s, timeout is posted",
+			state, expiresAt.Format(time.RFC3339))
+
+		s.Lock()
+		if s.postedTimeouts == nil {
+			s.postedTimeouts = make(map[string]bool)
+		}
+		s.postedTimeouts[key] = true
+		s.Unlock()
+	}
+
+	return nil
+}
+
+// isOutdatedTimeout reports whether the stage of the timeout event is already finished,
+// e.g. canceled by the timeouts posted by other participants
+func isOutdatedTimeout(event fsm.Event, state fsm.State) bool {
+	return types.IsTimeoutEvent(event) && types.TimeoutEvents[state] != event
+}
+
+// dropOperations deletes pending operations of a DKG round canceled by timeout, they can't be processed anymore
+func (s *BaseNodeService) dropOperations(message storage.Message) error {
+	operations, err := s.opService.GetOperations()
+	if err != nil {
+		return fmt.Errorf("failed to get operations: %w", err)
+	}
+	for _, operation := range operations {
+		if operation.DKGIdentifier != message.DkgRoundID {
+			continue
+		}
+		if err = s.opService.DeleteOperation(operation); err != nil {
+			return fmt.Errorf("failed to DeleteOperation: %w", err)
+		}
+		s.recordAudit(audit.ActionOperationDeleted, operation, map[string]interface{}{
+			"offset": message.Offset,
+			"reason": message.Event,
+		})
+	}
+	return nil
+}
@@ -12,21 +12,25 @@ import (
 	"github.com/lidofinance/dc4bc/client/services/signature"
 
 	"github.com/lidofinance/dc4bc/client/config"
-	"github.com/lidofinance/dc4bc/client/modules/keystore"
+	"github.com/lidofinance/dc4bc/client/modules/audit"
+	"github.com/lidofinance/dc4bc/client/modules/events"
 	"github.com/lidofinance/dc4bc/client/modules/logger"
+	"github.com/lidofinance/dc4bc/client/modules/metrics"
+	"github.com/lidofinance/dc4bc/client/modules/signer"
 	"github.com/lidofinance/dc4bc/client/modules/state"
 	"github.com/lidofinance/dc4bc/storage"
-	"github.com/lidofinance/dc4bc/storage/kafka_storage"
 )
 
 type ServiceProvider struct {
 	storage    storage.Storage
-	ks         keystore.KeyStore
+	signer     signer.Signer
 	l          logger.Logger
 	state      state.State
 	fsm        fsmservice.FSMService
 	opService  operation.OperationService
 	sigService signature.SignatureService
+	events     *events.Bus
+	audit      *audit.Journal
 }
 
 func (s *ServiceProvider) GetStorage() storage.Storage {
@@ -37,12 +41,12 @@ func (s *ServiceProvider) SetStorage(stg storage.Storage) {
 	s.storage = stg
 }
 
-func (s *ServiceProvider) GetKeyStore() keystore.KeyStore {
-	return s.ks
+func (s *ServiceProvider) GetSigner() signer.Signer {
+	return s.signer
 }
 
-func (s *ServiceProvider) SetKeyStore(ks keystore.KeyStore) {
-	s.ks = ks
+func (s *ServiceProvider) SetSigner(signer signer.Signer) {
+	s.signer = signer
 }
 
 func (s *ServiceProvider) GetLogger() logger.Logger {
@@ -85,7 +89,24 @@ func (s *ServiceProvider) SetSignatureService(sigService signature.SignatureServ
 	s.sigService = sigService
 }
 
-func parseMessagesToIgnore(cfg *config.KafkaStorageConfig) (msgs []string, err error) {
+func (s *ServiceProvider) GetEvents() *events.Bus {
+	return s.events
+}
+
+func (s *ServiceProvider) SetEvents(bus *events.Bus) {
+	s.events = bus
+}
+
+// GetAudit returns the audit journal, nil if the journal is disabled
+func (s *ServiceProvider) GetAudit() *audit.Journal {
+	return s.audit
+}
+
+func (s *ServiceProvider) SetAudit(journal *audit.Journal) {
+	s.audit = journal
+}
+
+func parseMessagesToIgnore(cfg *config.StorageConfig) (msgs []string, err error) {
 	if cfg == nil {
 		return msgs, err
 	}
@@ -106,44 +127,83 @@ func parseMessagesToIgnore(cfg *config.KafkaStorageConfig) (msgs []string, err e
 	return msgs, nil
 }
 
+// NewLogger creates a logger configured by the log level and format, nil config is an info text logger
+func NewLogger(username string, cfg *config.LogConfig) (logger.Logger, error) {
+	if cfg == nil {
+		return logger.NewLogger(username), nil
+	}
+	level, err := logger.ParseLevel(cfg.Level)
+	if err != nil {
+		return nil, err
+	}
+	format, err := logger.ParseFormat(cfg.Format)
+	if err != nil {
+		return nil, err
+	}
+	return logger.New(logger.Config{Username: username, Level: level, Format: format}), nil
+}
+
 func CreateServiceProviderWithCfg(cfg *config.Config) (*ServiceProvider, error) {
 	var err error
 	sp := ServiceProvider{}
 
-	sp.storage, err = kafka_storage.NewKafkaStorage(cfg.KafkaStorageConfig)
+	sp.l, err = NewLogger(cfg.Username, cfg.LogConfig)
+	if err != nil {
+		return nil, fmt.Errorf("failed to init logger: %w", err)
+	}
+
+	stg, err := NewStorage(cfg)
 	if err != nil {
-		return nil, fmt.Errorf("failed to initialize fafka storgae: %w", err)
+		return nil, fmt.Errorf("failed to initialize storage: %w", err)
+	}
+	backend, _ := splitStorageDBDSN(cfg.StorageConfig.DBDSN)
+	if loggingStg, ok := stg.(storage.LoggingStorage); ok {
+		loggingStg.SetLogger(sp.l.With(logger.Any("backend", backend)))
 	}
+	sp.storage = metrics.InstrumentStorage(stg, backend)
 
-	ignoredMsgs, err := parseMessagesToIgnore(cfg.KafkaStorageConfig)
+	ignoredMsgs, err := parseMessagesToIgnore(cfg.StorageConfig)
 	if err != nil {
 		return nil, fmt.Errorf("failed to ignore messages in storage: %w", err)
 	}
-	if err := sp.storage.IgnoreMessages(ignoredMsgs, cfg.KafkaStorageConfig.UseOffsetInsteadId); err != nil {
+	if err := sp.storage.IgnoreMessages(ignoredMsgs, cfg.StorageConfig.UseOffsetInsteadId); err != nil {
 		return nil, fmt.Errorf("failed to ignore messages in storage: %w", err)
 	}
 
-	sp.ks, err = keystore.NewLevelDBKeyStore(cfg.Username, cfg.KeyStoreDBDSN)
+	sp.signer, err = NewSigner(cfg)
 	if err != nil {
-		return nil, fmt.Errorf("failed to init key store: %w", err)
+		return nil, fmt.Errorf("failed to init signer: %w", err)
 	}
 
-	sp.l = logger.NewLogger(cfg.Username)
+	if cfg.AuditJournalPath != "" {
+		if sp.audit, err = audit.Open(cfg.AuditJournalPath, sp.signer); err != nil {
+			return nil, fmt.Errorf("failed to open audit journal: %w", err)
+		}
+	}
+	if len(ignoredMsgs) > 0 {
+		if err = sp.audit.Record(audit.ActorNode, audit.ActionMessageIgnored, "", map[string]interface{}{
+			"messages":   ignoredMsgs,
+			"use_offset": cfg.StorageConfig.UseOffsetInsteadId,
+		}); err != nil {
+			return nil, fmt.Errorf("failed to record ignored messages to audit journal: %w", err)
+		}
+	}
 
-	sp.state, err = state.NewLevelDBState(cfg.StateDBSN, cfg.KafkaStorageConfig.Topic)
+	sp.state, err = state.NewLevelDBState(cfg.StateDBSN, cfg.StorageConfig.Topic)
 	if err != nil {
 		return nil, fmt.Errorf("failed to init state: %w", err)
 	}
 
 	sigRepo := sigrepo.NewSignatureRepo(sp.state)
-	opRepo, err := oprepo.NewOperationRepo(sp.state, cfg.KafkaStorageConfig.Topic)
+	opRepo, err := oprepo.NewOperationRepo(sp.state, cfg.StorageConfig.Topic)
 	if err != nil {
 		return nil, fmt.Errorf("failed to init operation repo: %w", err)
 	}
 
-	sp.fsm = fsmservice.NewFSMService(sp.state, sp.storage, cfg.KafkaStorageConfig.Topic)
+	sp.fsm = fsmservice.NewFSMService(sp.state, sp.storage, cfg.StorageConfig.Topic)
 	sp.sigService = signature.NewSignatureService(sigRepo)
 	sp.opService = operation.NewOperationService(opRepo)
+	sp.events = events.NewBus(events.DefaultHistorySize)
 
 	return &sp, nil
 }
@@ -0,0 +1,51 @@
+package services
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/lidofinance/dc4bc/client/config"
+	"github.com/lidofinance/dc4bc/client/modules/keystore"
+	"github.com/lidofinance/dc4bc/client/modules/signer"
+)
+
+const (
+	KeyStoreSigner = "keystore"
+	PKCS11Signer   = "pkcs11"
+	RemoteSigner   = "remote"
+
+	defaultRemoteSignerTimeout = 10 * time.Second
+)
+
+// NewSigner creates a signer of the node's hot key picked by the signer config, the key store signer
+// is used by default
+func NewSigner(cfg *config.Config) (signer.Signer, error) {
+	signerCfg := cfg.SignerConfig
+	if signerCfg == nil {
+		signerCfg = &config.SignerConfig{}
+	}
+
+	switch signerCfg.Type {
+	case "", KeyStoreSigner:
+		ks, err := keystore.NewLevelDBKeyStore(cfg.Username, cfg.KeyStoreDBDSN)
+		if err != nil {
+			return nil, fmt.Errorf("failed to init key store: %w", err)
+		}
+		return signer.NewKeyStoreSigner(ks, cfg.Username, cfg.KeyStorePassword)
+	case PKCS11Signer:
+		return signer.NewPKCS11Signer(signerCfg.PKCS11Module, signerCfg.PKCS11TokenLabel,
+			signerCfg.PKCS11KeyLabel, signerCfg.PKCS11PIN)
+	case RemoteSigner:
+		timeout := defaultRemoteSignerTimeout
+		if signerCfg.RemoteTimeout != "" {
+			var err error
+			if timeout, err = time.ParseDuration(signerCfg.RemoteTimeout); err != nil {
+				return nil, fmt.Errorf("failed to parse remote signer timeout: %w", err)
+			}
+		}
+		return signer.NewRemoteSigner(signerCfg.RemoteSignerSocket, timeout)
+	default:
+		return nil, fmt.Errorf("unknown signer %s, available signers: %s, %s, %s", signerCfg.Type,
+			KeyStoreSigner, PKCS11Signer, RemoteSigner)
+	}
+}
@@ -0,0 +1,169 @@
+package services
+
+import (
+	"crypto/ed25519"
+	"encoding/hex"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/lidofinance/dc4bc/client/config"
+	"github.com/lidofinance/dc4bc/storage"
+	"github.com/lidofinance/dc4bc/storage/file_storage"
+	"github.com/lidofinance/dc4bc/storage/http_storage"
+	"github.com/lidofinance/dc4bc/storage/kafka_storage"
+	"github.com/lidofinance/dc4bc/storage/snapshot_storage"
+	"github.com/lidofinance/dc4bc/storage/sqlite_storage"
+)
+
+const (
+	schemeSeparator = "://"
+
+	// DefaultStorageScheme is used if storage DBDSN has no scheme, e.g. a Kafka broker endpoint localhost:9093
+	DefaultStorageScheme = "kafka"
+
+	defaultHTTPStorageTimeout = time.Minute
+	defaultSQLiteBusyTimeout  = 10 * time.Second
+)
+
+// StorageFactory creates a storage backend. address is the storage DBDSN without the scheme, section is the config
+// section registered by the backend, it is nil if the backend has no section or the config does not have it
+type StorageFactory func(address string, storageCfg *config.StorageConfig, section interface{}) (storage.Storage, error)
+
+type storageBackend struct {
+	factory    StorageFactory
+	newSection func() interface{}
+}
+
+var storageBackends = map[string]storageBackend{}
+
+func init() {
+	RegisterStorage("file", newFileStorage, func() interface{} { return &config.FileStorageConfig{} })
+	RegisterStorage("sqlite", newSQLiteStorage, func() interface{} { return &config.SQLiteStorageConfig{} })
+	RegisterStorage("kafka", newKafkaStorage, func() interface{} { return &config.KafkaStorageConfig{} })
+	RegisterStorage("http", newHTTPStorage("http"), func() interface{} { return &config.HTTPStorageConfig{} })
+	RegisterStorage("https", newHTTPStorage("https"), func() interface{} { return &config.HTTPStorageConfig{} })
+	RegisterStorage("snapshot", newSnapshotStorage, func() interface{} { return &config.SnapshotStorageConfig{} })
+}
+
+// RegisterStorage makes a storage backend available by the storage DBDSN scheme. newSection returns an empty
+// config section of the backend to be filled from the node config, it is nil if the backend has no settings
+func RegisterStorage(scheme string, factory StorageFactory, newSection func() interface{}) {
+	storageBackends[scheme] = storageBackend{factory: factory, newSection: newSection}
+}
+
+// RegisteredStorages returns schemes of all registered storage backends
+func RegisteredStorages() []string {
+	schemes := make([]string, 0, len(storageBackends))
+	for scheme := range storageBackends {
+		schemes = append(schemes, scheme)
+	}
+	sort.Strings(schemes)
+
+	return schemes
+}
+
+// NewStorageSections returns empty config sections of the registered storage backends by their schemes
+func NewStorageSections() map[string]interface{} {
+	sections := make(map[string]interface{}, len(storageBackends))
+	for scheme, backend := range storageBackends {
+		if backend.newSection != nil {
+			sections[scheme] = backend.newSection()
+		}
+	}
+
+	return sections
+}
+
+// NewStorage creates a storage backend picked by the storage DBDSN scheme, e.g. file:///var/lib/dc4bc/storage
+func NewStorage(cfg *config.Config) (storage.Storage, error) {
+	if cfg.StorageConfig == nil {
+		return nil, fmt.Errorf("storage config should not be nil value")
+	}
+
+	scheme, address := splitStorageDBDSN(cfg.StorageConfig.DBDSN)
+
+	backend, ok := storageBackends[scheme]
+	if !ok {
+		return nil, fmt.Errorf("unknown storage scheme %s, available schemes: %s", scheme,
+			strings.Join(RegisteredStorages(), ", "))
+	}
+
+	return backend.factory(address, cfg.StorageConfig, cfg.StorageSections[scheme])
+}
+
+func splitStorageDBDSN(dbdsn string) (scheme, address string) {
+	if idx := strings.Index(dbdsn, schemeSeparator); idx >= 0 {
+		return dbdsn[:idx], dbdsn[idx+len(schemeSeparator):]
+	}
+	return DefaultStorageScheme, dbdsn
+}
+
+func newFileStorage(address string, _ *config.StorageConfig, section interface{}) (storage.Storage, error) {
+	if fileCfg, _ := section.(*config.FileStorageConfig); fileCfg != nil && fileCfg.LockFile != "" {
+		return file_storage.NewFileStorage(address, fileCfg.LockFile)
+	}
+	return file_storage.NewFileStorage(address)
+}
+
+func newSQLiteStorage(address string, _ *config.StorageConfig, section interface{}) (storage.Storage, error) {
+	busyTimeout := defaultSQLiteBusyTimeout
+	if sqliteCfg, _ := section.(*config.SQLiteStorageConfig); sqliteCfg != nil && sqliteCfg.BusyTimeout != "" {
+		var err error
+		if busyTimeout, err = time.ParseDuration(sqliteCfg.BusyTimeout); err != nil {
+			return nil, fmt.Errorf("failed to parse busy timeout duration: %w", err)
+		}
+	}
+
+	return sqlite_storage.NewSQLiteStorage(address, busyTimeout)
+}
+
+func newSnapshotStorage(address string, _ *config.StorageConfig, section interface{}) (storage.Storage, error) {
+	snapshotCfg, _ := section.(*config.SnapshotStorageConfig)
+	if snapshotCfg == nil || snapshotCfg.TrustedKeys == "" {
+		return nil, fmt.Errorf("trusted keys of the snapshot signers are not set")
+	}
+
+	var trustedKeys []ed25519.PublicKey
+	for _, keyHex := range strings.Split(snapshotCfg.TrustedKeys, ",") {
+		key, err := hex.DecodeString(strings.TrimSpace(keyHex))
+		if err != nil {
+			return nil, fmt.Errorf("failed to decode trusted key %s: %w", keyHex, err)
+		}
+		if len(key) != ed25519.PublicKeySize {
+			return nil, fmt.Errorf("invalid trusted key %s: expected %d bytes, got %d", keyHex,
+				ed25519.PublicKeySize, len(key))
+		}
+		trustedKeys = append(trustedKeys, key)
+	}
+
+	return snapshot_storage.NewSnapshotStorage(address, trustedKeys)
+}
+
+func newKafkaStorage(address string, storageCfg *config.StorageConfig, section interface{}) (storage.Storage, error) {
+	kafkaCfg, _ := section.(*config.KafkaStorageConfig)
+	if kafkaCfg == nil {
+		return nil, fmt.Errorf("kafka storage config should not be nil value")
+	}
+
+	cfg := *kafkaCfg
+	cfg.DBDSN = address
+	cfg.Topic = storageCfg.Topic
+
+	return kafka_storage.NewKafkaStorage(&cfg)
+}
+
+func newHTTPStorage(scheme string) StorageFactory {
+	return func(address string, _ *config.StorageConfig, section interface{}) (storage.Storage, error) {
+		timeout := defaultHTTPStorageTimeout
+		if httpCfg, _ := section.(*config.HTTPStorageConfig); httpCfg != nil && httpCfg.Timeout != "" {
+			var err error
+			if timeout, err = time.ParseDuration(httpCfg.Timeout); err != nil {
+				return nil, fmt.Errorf("failed to parse timeout duration: %w", err)
+			}
+		}
+
+		return http_storage.NewHTTPStorage(scheme+schemeSeparator+address, timeout)
+	}
+}
@@ -0,0 +1,69 @@
+package services
+
+import (
+	"crypto/ed25519"
+	"encoding/hex"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/lidofinance/dc4bc/client/config"
+	"github.com/lidofinance/dc4bc/storage"
+	"github.com/lidofinance/dc4bc/storage/file_storage"
+	"github.com/lidofinance/dc4bc/storage/http_storage"
+	"github.com/lidofinance/dc4bc/storage/snapshot_storage"
+	"github.com/lidofinance/dc4bc/storage/sqlite_storage"
+)
+
+func TestNewStorage(t *testing.T) {
+	req := require.New(t)
+	dir := t.TempDir()
+
+	newStorageCfg := func(dbdsn string) *config.Config {
+		return &config.Config{
+			StorageConfig: &config.StorageConfig{DBDSN: dbdsn},
+			StorageSections: map[string]interface{}{
+				"file": &config.FileStorageConfig{LockFile: filepath.Join(dir, "lock")},
+			},
+		}
+	}
+
+	stg, err := NewStorage(newStorageCfg("file://" + filepath.Join(dir, "file_storage")))
+	req.NoError(err)
+	req.IsType(&file_storage.FileStorage{}, stg)
+	req.NoError(stg.Close())
+
+	stg, err = NewStorage(newStorageCfg("sqlite://" + filepath.Join(dir, "storage.db")))
+	req.NoError(err)
+	req.IsType(&sqlite_storage.SQLiteStorage{}, stg)
+	req.NoError(stg.Close())
+
+	stg, err = NewStorage(newStorageCfg("http://localhost:9090"))
+	req.NoError(err)
+	req.IsType(&http_storage.HTTPStorage{}, stg)
+
+	snapshotFile := filepath.Join(dir, "snapshot.json")
+	pubKey, privKey, err := ed25519.GenerateKey(nil)
+	req.NoError(err)
+	snapshot := storage.NewSnapshot(nil)
+	snapshot.Signer, snapshot.PubKey, snapshot.Signature = "user", pubKey, ed25519.Sign(privKey, snapshot.Digest)
+	req.NoError(storage.WriteSnapshotFile(snapshotFile, snapshot))
+	_, err = NewStorage(newStorageCfg("snapshot://" + snapshotFile))
+	req.Error(err)
+	snapshotCfg := newStorageCfg("snapshot://" + snapshotFile)
+	snapshotCfg.StorageSections["snapshot"] = &config.SnapshotStorageConfig{TrustedKeys: hex.EncodeToString(pubKey)}
+	stg, err = NewStorage(snapshotCfg)
+	req.NoError(err)
+	req.IsType(&snapshot_storage.SnapshotStorage{}, stg)
+
+	// the kafka backend has no defaults for its section
+	_, err = NewStorage(newStorageCfg("kafka://localhost:9093"))
+	req.ErrorContains(err, "kafka storage config")
+
+	_, err = NewStorage(newStorageCfg("unknown://localhost"))
+	req.Error(err)
+
+	_, err = NewStorage(&config.Config{})
+	req.Error(err)
+}
@@ -0,0 +1,19 @@
+package types
+
+// Checkpoint is a signed statement of a participant about its view of the bulletin board log:
+// the digest of all messages up to (and including) the given offset
+type Checkpoint struct {
+	Offset uint64 `json:"offset"`
+	Digest []byte `json:"digest"`
+}
+
+// CheckpointDivergence is a checkpoint of another participant, which does not match the node's view of the log.
+// It means that the board operator shows different logs to different participants.
+type CheckpointDivergence struct {
+	Participant      string `json:"participant"`
+	Offset           uint64 `json:"offset"`
+	CheckpointOffset uint64 `json:"checkpoint_offset"`
+	TheirDigest      []byte `json:"their_digest"`
+	OurDigest        []byte `json:"our_digest"`
+	Reason           string `json:"reason"`
+}
@@ -15,6 +15,8 @@ import (
 
 	"github.com/lidofinance/dc4bc/fsm/fsm"
 	"github.com/lidofinance/dc4bc/fsm/state_machines/dkg_proposal_fsm"
+	"github.com/lidofinance/dc4bc/fsm/state_machines/resharing_fsm"
+	"github.com/lidofinance/dc4bc/fsm/state_machines/share_refresh_fsm"
 	"github.com/lidofinance/dc4bc/fsm/state_machines/signature_proposal_fsm"
 	"github.com/lidofinance/dc4bc/fsm/types/requests"
 	"github.com/lidofinance/dc4bc/fsm/types/responses"
@@ -28,11 +30,42 @@ const (
 	SignatureReconstructed        fsm.Event     = "signature_reconstructed"
 	SignatureReconstructionFailed fsm.Event     = "signature_reconstruction_failed"
 	ReinitDKG                     fsm.State     = "reinit_dkg"
+	BoardCheckpoint               fsm.Event     = "board_checkpoint"
 
 	// OperationProcessed common event type for successfully processed operations but with an empty result
 	OperationProcessed fsm.Event = "operation_processed_successfully"
 )
 
+// TimeoutEvents are the events posted by nodes when the deadline of the stage the FSM awaits in has passed
+var TimeoutEvents = map[fsm.State]fsm.Event{
+	signature_proposal_fsm.StateAwaitParticipantsConfirmations: signature_proposal_fsm.EventSignatureProposalTimeout,
+	dkg_proposal_fsm.StateDkgCommitsAwaitConfirmations:         dkg_proposal_fsm.EventDKGCommitsTimeout,
+	dkg_proposal_fsm.StateDkgDealsAwaitConfirmations:           dkg_proposal_fsm.EventDKGDealsTimeout,
+	dkg_proposal_fsm.StateDkgResponsesAwaitConfirmations:       dkg_proposal_fsm.EventDKGResponsesTimeout,
+	dkg_proposal_fsm.StateDkgMasterKeyAwaitConfirmations:       dkg_proposal_fsm.EventDKGMasterKeyTimeout,
+	signing_proposal_fsm.StateSigningAwaitPartialSigns:         signing_proposal_fsm.EventSigningPartialSignsTimeout,
+
+	share_refresh_fsm.StateShareRefreshCommitsAwaitConfirmations:   share_refresh_fsm.EventShareRefreshCommitsTimeout,
+	share_refresh_fsm.StateShareRefreshDealsAwaitConfirmations:     share_refresh_fsm.EventShareRefreshDealsTimeout,
+	share_refresh_fsm.StateShareRefreshResponsesAwaitConfirmations: share_refresh_fsm.EventShareRefreshResponsesTimeout,
+	share_refresh_fsm.StateShareRefreshKeyringAwaitConfirmations:   share_refresh_fsm.EventShareRefreshKeyringTimeout,
+
+	resharing_fsm.StateResharingAwaitParticipantsConfirmations: resharing_fsm.EventResharingProposalTimeout,
+	resharing_fsm.StateResharingCommitsAwaitConfirmations:      resharing_fsm.EventResharingCommitsTimeout,
+	resharing_fsm.StateResharingDealsAwaitConfirmations:        resharing_fsm.EventResharingDealsTimeout,
+	resharing_fsm.StateResharingResponsesAwaitConfirmations:    resharing_fsm.EventResharingResponsesTimeout,
+	resharing_fsm.StateResharingKeyringAwaitConfirmations:      resharing_fsm.EventResharingKeyringTimeout,
+}
+
+func IsTimeoutEvent(event fsm.Event) bool {
+	for _, timeoutEvent := range TimeoutEvents {
+		if event == timeoutEvent {
+			return true
+		}
+	}
+	return false
+}
+
 // Operation is the type for any Operation that might be required for
 // both DKG and signing process (e.g.,
 type Operation struct {
@@ -128,6 +161,24 @@ func getShortOperationDescription(operationType OperationType) string {
 		return "partial_sign"
 	case signing_proposal_fsm.StateSigningPartialSignsCollected:
 		return "recover_full_signature"
+	case share_refresh_fsm.StateShareRefreshCommitsAwaitConfirmations:
+		return "send_commits_for_the_share_refresh"
+	case share_refresh_fsm.StateShareRefreshDealsAwaitConfirmations:
+		return "send_deals_for_the_share_refresh"
+	case share_refresh_fsm.StateShareRefreshResponsesAwaitConfirmations:
+		return "send_responses_for_the_share_refresh"
+	case share_refresh_fsm.StateShareRefreshKeyringAwaitConfirmations:
+		return "save_the_refreshed_keyring_and_broadcast_it"
+	case resharing_fsm.StateResharingAwaitParticipantsConfirmations:
+		return "confirm_resharing_participation"
+	case resharing_fsm.StateResharingCommitsAwaitConfirmations:
+		return "send_commits_for_the_resharing"
+	case resharing_fsm.StateResharingDealsAwaitConfirmations:
+		return "send_deals_for_the_resharing"
+	case resharing_fsm.StateResharingResponsesAwaitConfirmations:
+		return "send_responses_for_the_resharing"
+	case resharing_fsm.StateResharingKeyringAwaitConfirmations:
+		return "save_the_reshared_keyring_and_broadcast_it"
 	case ReinitDKG:
 		return "reinit_DKG"
 	default:
@@ -154,6 +205,26 @@ func getStepNumber(operationType OperationType) int {
 	case signing_proposal_fsm.StateSigningPartialSignsCollected:
 		return 2
 
+	case share_refresh_fsm.StateShareRefreshCommitsAwaitConfirmations:
+		return 1
+	case share_refresh_fsm.StateShareRefreshDealsAwaitConfirmations:
+		return 2
+	case share_refresh_fsm.StateShareRefreshResponsesAwaitConfirmations:
+		return 3
+	case share_refresh_fsm.StateShareRefreshKeyringAwaitConfirmations:
+		return 4
+
+	case resharing_fsm.StateResharingAwaitParticipantsConfirmations:
+		return 1
+	case resharing_fsm.StateResharingCommitsAwaitConfirmations:
+		return 2
+	case resharing_fsm.StateResharingDealsAwaitConfirmations:
+		return 3
+	case resharing_fsm.StateResharingResponsesAwaitConfirmations:
+		return 4
+	case resharing_fsm.StateResharingKeyringAwaitConfirmations:
+		return 5
+
 	case ReinitDKG:
 		return 0
 	default:
@@ -165,7 +236,8 @@ func getStepNumber(operationType OperationType) int {
 func FSMRequestFromMessage(message storage.Message) (interface{}, error) {
 	var resolvedValue interface{}
 	switch fsm.Event(message.Event) {
-	case signature_proposal_fsm.EventConfirmSignatureProposal, signature_proposal_fsm.EventDeclineProposal:
+	case signature_proposal_fsm.EventConfirmSignatureProposal, signature_proposal_fsm.EventDeclineProposal,
+		resharing_fsm.EventConfirmResharingProposal, resharing_fsm.EventDeclineResharingProposal:
 		var req requests.SignatureProposalParticipantRequest
 		if err := json.Unmarshal(message.Data, &req); err != nil {
 			return fmt.Errorf("failed to unmarshal fsm req: %w", err), nil
@@ -177,25 +249,35 @@ func FSMRequestFromMessage(message storage.Message) (interface{}, error) {
 			return fmt.Errorf("failed to unmarshal fsm req: %w", err), nil
 		}
 		resolvedValue = req
-	case dkg_proposal_fsm.EventDKGCommitConfirmationReceived:
+	case signature_proposal_fsm.EventInitResharingProposal:
+		var req requests.ResharingProposalRequest
+		if err := json.Unmarshal(message.Data, &req); err != nil {
+			return fmt.Errorf("failed to unmarshal fsm req: %w", err), nil
+		}
+		resolvedValue = req
+	case dkg_proposal_fsm.EventDKGCommitConfirmationReceived, share_refresh_fsm.EventShareRefreshCommitConfirmationReceived,
+		resharing_fsm.EventResharingCommitConfirmationReceived:
 		var req requests.DKGProposalCommitConfirmationRequest
 		if err := json.Unmarshal(message.Data, &req); err != nil {
 			return fmt.Errorf("failed to unmarshal fsm req: %w", err), nil
 		}
 		resolvedValue = req
-	case dkg_proposal_fsm.EventDKGDealConfirmationReceived:
+	case dkg_proposal_fsm.EventDKGDealConfirmationReceived, share_refresh_fsm.EventShareRefreshDealConfirmationReceived,
+		resharing_fsm.EventResharingDealConfirmationReceived:
 		var req requests.DKGProposalDealConfirmationRequest
 		if err := json.Unmarshal(message.Data, &req); err != nil {
 			return fmt.Errorf("failed to unmarshal fsm req: %w", err), nil
 		}
 		resolvedValue = req
-	case dkg_proposal_fsm.EventDKGResponseConfirmationReceived:
+	case dkg_proposal_fsm.EventDKGResponseConfirmationReceived, share_refresh_fsm.EventShareRefreshResponseConfirmationReceived,
+		resharing_fsm.EventResharingResponseConfirmationReceived:
 		var req requests.DKGProposalResponseConfirmationRequest
 		if err := json.Unmarshal(message.Data, &req); err != nil {
 			return fmt.Errorf("failed to unmarshal fsm req: %w", err), nil
 		}
 		resolvedValue = req
-	case dkg_proposal_fsm.EventDKGMasterKeyConfirmationReceived:
+	case dkg_proposal_fsm.EventDKGMasterKeyConfirmationReceived, share_refresh_fsm.EventShareRefreshKeyringConfirmationReceived,
+		resharing_fsm.EventResharingKeyringConfirmationReceived:
 		var req requests.DKGProposalMasterKeyConfirmationRequest
 		if err := json.Unmarshal(message.Data, &req); err != nil {
 			return fmt.Errorf("failed to unmarshal fsm req: %w", err), nil
@@ -214,12 +296,37 @@ func FSMRequestFromMessage(message storage.Message) (interface{}, error) {
 		}
 		resolvedValue = req
 	case dkg_proposal_fsm.EventDKGCommitConfirmationError, dkg_proposal_fsm.EventDKGDealConfirmationError,
-		dkg_proposal_fsm.EventDKGResponseConfirmationError, dkg_proposal_fsm.EventDKGMasterKeyConfirmationError:
+		dkg_proposal_fsm.EventDKGResponseConfirmationError, dkg_proposal_fsm.EventDKGMasterKeyConfirmationError,
+		share_refresh_fsm.EventShareRefreshCommitConfirmationError, share_refresh_fsm.EventShareRefreshDealConfirmationError,
+		share_refresh_fsm.EventShareRefreshResponseConfirmationError, share_refresh_fsm.EventShareRefreshKeyringConfirmationError,
+		resharing_fsm.EventResharingCommitConfirmationError, resharing_fsm.EventResharingDealConfirmationError,
+		resharing_fsm.EventResharingResponseConfirmationError, resharing_fsm.EventResharingKeyringConfirmationError:
 		var req requests.DKGProposalConfirmationErrorRequest
 		if err := json.Unmarshal(message.Data, &req); err != nil {
 			return fmt.Errorf("failed to unmarshal fsm req: %w", err), nil
 		}
 		resolvedValue = req
+	case signature_proposal_fsm.EventSignatureProposalTimeout, dkg_proposal_fsm.EventDKGCommitsTimeout,
+		dkg_proposal_fsm.EventDKGDealsTimeout, dkg_proposal_fsm.EventDKGResponsesTimeout,
+		dkg_proposal_fsm.EventDKGMasterKeyTimeout, signing_proposal_fsm.EventSigningPartialSignsTimeout,
+		share_refresh_fsm.EventShareRefreshCommitsTimeout, share_refresh_fsm.EventShareRefreshDealsTimeout,
+		share_refresh_fsm.EventShareRefreshResponsesTimeout, share_refresh_fsm.EventShareRefreshKeyringTimeout,
+		resharing_fsm.EventResharingProposalTimeout, resharing_fsm.EventResharingCommitsTimeout,
+		resharing_fsm.EventResharingDealsTimeout, resharing_fsm.EventResharingResponsesTimeout,
+		resharing_fsm.EventResharingKeyringTimeout:
+		var req requests.TimeoutRequest
+		if err := json.Unmarshal(message.Data, &req); err != nil {
+			return fmt.Errorf("failed to unmarshal fsm req: %w", err), nil
+		}
+		// the timeout is counted for the sender of the message, which signature is verified
+		req.Username = message.SenderAddr
+		resolvedValue = req
+	case signing_proposal_fsm.EventShareRefreshPropose:
+		var req requests.ShareRefreshProposalRequest
+		if err := json.Unmarshal(message.Data, &req); err != nil {
+			return fmt.Errorf("failed to unmarshal fsm req: %w", err), nil
+		}
+		resolvedValue = req
 	case signing_proposal_fsm.EventSigningPartialSignError, SignatureReconstructionFailed:
 		var req requests.SignatureProposalConfirmationErrorRequest
 		if err := json.Unmarshal(message.Data, &req); err != nil {
@@ -253,6 +360,10 @@ func GenerateReDKGMessage(messages []storage.Message, newCommPubKeys map[string]
 	var reDKG ReDKG
 
 	for _, msg := range messages {
+		// checkpoints are not related to DKG rounds, ignored messages are not replayed
+		if fsm.Event(msg.Event) == BoardCheckpoint || msg.Ignored {
+			continue
+		}
 		if fsm.Event(msg.Event) == signature_proposal_fsm.EventInitProposal {
 			req, err := FSMRequestFromMessage(msg)
 			if err != nil {
@@ -24,6 +24,7 @@ import (
 	"golang.org/x/crypto/ssh/terminal"
 
 	"github.com/lidofinance/dc4bc/airgapped"
+	"github.com/lidofinance/dc4bc/client/modules/logger"
 	client "github.com/lidofinance/dc4bc/client/types"
 )
 
@@ -106,6 +107,26 @@ func NewPrompt(machine *airgapped.Machine) (*prompt, error) {
 		commandHandler: p.generateDKGPubKeyJSON,
 		description:    "generates and saves a JSON with DKG public key that can be read by the Client node",
 	})
+	p.addCommand("generate_round_pubkey_json", &promptCommand{
+		commandHandler: p.generateRoundPubKeyJSON,
+		description:    "generates a new DKG key pair for a new round and saves a JSON with its public key, the round is bound to the key pair when the round starts",
+	})
+	p.addCommand("list_rounds", &promptCommand{
+		commandHandler: p.listRoundsCommand,
+		description:    "shows a list of dkg rounds stored on the machine",
+	})
+	p.addCommand("archive_round", &promptCommand{
+		commandHandler: p.archiveRoundCommand,
+		description:    "archives a given dkg round, the round is kept in the storage but refuses new operations",
+	})
+	p.addCommand("export_round", &promptCommand{
+		commandHandler: p.exportRoundCommand,
+		description:    "saves all records of a given dkg round to a JSON file, sensitive records stay encrypted",
+	})
+	p.addCommand("change_password", &promptCommand{
+		commandHandler: p.changePasswordCommand,
+		description:    "re-encrypts the keys, the seed and the operation logs with a new encryption password",
+	})
 	p.addCommand("set_seed", &promptCommand{
 		commandHandler: p.setSeedCommand,
 		description:    "resets a global random seed using BIP39 word list. WARNING! Only do that on a fresh database with no operation carried out.",
@@ -220,6 +241,12 @@ func (p *prompt) showFinishedDKGCommand() error {
 			continue
 		}
 		p.printf("PubKey: %s\n", base64.StdEncoding.EncodeToString(pubkeyBz))
+		version, err := p.airgapped.GetBLSKeyringVersion(dkgID)
+		if err != nil {
+			p.println("failed to get keyring version: %w", err)
+			continue
+		}
+		p.printf("Keyring version: %d\n", version)
 		p.println("-----------------------------------------------------")
 	}
 	return nil
@@ -378,6 +405,113 @@ func (p *prompt) generateDKGPubKeyJSON() error {
 	return nil
 }
 
+func (p *prompt) generateRoundPubKeyJSON() error {
+	dkgPubKey, err := p.airgapped.GenerateRoundKeys()
+	if err != nil {
+		return fmt.Errorf("failed to generate round keys: %w", err)
+	}
+	dkgPubKeyBz, err := dkgPubKey.MarshalBinary()
+	if err != nil {
+		return fmt.Errorf("failed to marshal DKG pub key: %w", err)
+	}
+
+	dkgPubKeyBase64 := base64.StdEncoding.EncodeToString(dkgPubKeyBz)
+	dkgPubKeyJSON, err := json.Marshal(map[string]string{"dkg_pub_key": dkgPubKeyBase64})
+	if err != nil {
+		return fmt.Errorf("failed to marshal operation: %w", err)
+	}
+
+	path := filepath.Join(p.airgapped.ResultFolder, fmt.Sprintf("dc4bc_dkg_pub_key_%x.json", dkgPubKeyBz[:4]))
+	if err = ioutil.WriteFile(path, dkgPubKeyJSON, 0600); err != nil {
+		return fmt.Errorf("failed to write file: %w", err)
+	}
+
+	p.println(dkgPubKeyBase64)
+	p.printf("A JSON file with DKG public key for a new round was saved to: %s\n", path)
+
+	return nil
+}
+
+func (p *prompt) listRoundsCommand() error {
+	rounds, err := p.airgapped.ListRounds()
+	if err != nil {
+		return fmt.Errorf("failed to get a list of rounds: %w", err)
+	}
+	for _, round := range rounds {
+		p.printf("DKG identifier: %s\n", round.DKGIdentifier)
+		if round.PubKey != nil {
+			p.printf("DKG pub key: %s\n", base64.StdEncoding.EncodeToString(round.PubKey))
+		} else {
+			p.println("DKG pub key: default")
+		}
+		status := "in progress"
+		if round.Finished {
+			status = fmt.Sprintf("finished, keyring version %d", round.KeyringVersion)
+		}
+		if round.Archived {
+			status += ", archived"
+		}
+		p.printf("Status: %s\n", status)
+		p.printf("Operations: %d\n", round.Operations)
+		p.println("-----------------------------------------------------")
+	}
+	return nil
+}
+
+func (p *prompt) archiveRoundCommand() error {
+	p.print("> Enter the DKGRoundIdentifier: ")
+	dkgRoundIdentifier, err := p.reader.ReadString('\n')
+	if err != nil {
+		return fmt.Errorf("failed to read dkgRoundIdentifier: %w", err)
+	}
+
+	if err := p.airgapped.ArchiveRound(strings.Trim(dkgRoundIdentifier, " \n")); err != nil {
+		return fmt.Errorf("failed to ArchiveRound: %w", err)
+	}
+	return nil
+}
+
+func (p *prompt) exportRoundCommand() error {
+	p.print("> Enter the DKGRoundIdentifier: ")
+	dkgRoundIdentifier, err := p.reader.ReadString('\n')
+	if err != nil {
+		return fmt.Errorf("failed to read dkgRoundIdentifier: %w", err)
+	}
+
+	path, err := p.airgapped.ExportRound(strings.Trim(dkgRoundIdentifier, " \n"))
+	if err != nil {
+		return fmt.Errorf("failed to ExportRound: %w", err)
+	}
+
+	p.printf("The round was exported to: %s\n", path)
+	return nil
+}
+
+func (p *prompt) changePasswordCommand() error {
+	p.print("Enter new encryption password: ")
+	password, err := terminal.ReadPassword(syscall.Stdin)
+	if err != nil {
+		return fmt.Errorf("failed to read password: %w", err)
+	}
+	p.println()
+	p.print("Confirm new encryption password: ")
+	confirmedPassword, err := terminal.ReadPassword(syscall.Stdin)
+	if err != nil {
+		return fmt.Errorf("failed to read password: %w", err)
+	}
+	p.println()
+	if !bytes.Equal(password, confirmedPassword) {
+		p.println("Passwords do not match! The password is not changed")
+		return nil
+	}
+
+	if err = p.airgapped.ChangeEncryptionKey(password); err != nil {
+		return fmt.Errorf("failed to change encryption password: %w", err)
+	}
+	p.println("Encryption password was changed")
+	return nil
+}
+
 func (p *prompt) enterEncryptionPasswordIfNeeded() error {
 	p.airgapped.Lock()
 	defer p.airgapped.Unlock()
@@ -407,6 +541,12 @@ func (p *prompt) enterEncryptionPasswordIfNeeded() error {
 			}
 		}
 		p.airgapped.SetEncryptionKey(password)
+		if migratePlaintext {
+			if err = p.airgapped.Migrate(); err != nil {
+				p.printf("Failed to migrate the storage: %v\n", err)
+				continue
+			}
+		}
 		if err = p.airgapped.InitKeys(); err != nil {
 			p.printf("Failed to init keys: %v\n", err)
 			continue
@@ -532,12 +672,20 @@ var (
 	passwordExpiration string
 	dbPath             string
 	resultFolder       string
+	logLevel           string
+	logFormat          string
+	migratePlaintext   bool
 )
 
 func init() {
 	flag.StringVar(&passwordExpiration, "password_expiration", "10m", "Expiration of the encryption password")
 	flag.StringVar(&dbPath, "db_path", "airgapped_db", "Path to airgapped levelDB storage")
 	flag.StringVar(&resultFolder, "result_folder", "/tmp/", "Folder to save result JSON files")
+	flag.StringVar(&logLevel, "log_level", "info", "Level of logged messages: debug, info, warn or error")
+	flag.StringVar(&logFormat, "log_format", "text", "Format of logged messages: text or json")
+	flag.BoolVar(&migratePlaintext, "migrate_plaintext", false,
+		"Migrate the storage made by a previous version, the seed and the operation logs stored in plaintext "+
+			"are encrypted")
 }
 
 func main() {
@@ -548,12 +696,31 @@ func main() {
 		log.Fatalf("invalid password expiration syntax: %v", err)
 	}
 
-	air, err := airgapped.NewMachine(dbPath)
+	level, err := logger.ParseLevel(logLevel)
+	if err != nil {
+		log.Fatalf("invalid log level: %v", err)
+	}
+	format, err := logger.ParseFormat(logFormat)
+	if err != nil {
+		log.Fatalf("invalid log format: %v", err)
+	}
+
+	// log messages go to stderr, so they can be redirected apart from the prompt
+	air, err := airgapped.NewMachine(dbPath, logger.New(logger.Config{Level: level, Format: format, Output: os.Stderr}))
 	if err != nil {
 		log.Fatalf("failed to init airgapped machine %v", err)
 	}
 	air.SetResultFolder(resultFolder)
 
+	needsMigration, err := air.NeedsMigration()
+	if err != nil {
+		log.Fatalf("failed to check airgapped storage: %v", err)
+	}
+	if needsMigration && !migratePlaintext {
+		log.Fatalf("%s is made by a previous version of the machine, make a backup of it and "+
+			"restart with --migrate_plaintext to migrate it", dbPath)
+	}
+
 	c := make(chan os.Signal, 1)
 	signal.Notify(c, os.Interrupt)
 
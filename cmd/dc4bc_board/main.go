@@ -0,0 +1,137 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+
+	"github.com/lidofinance/dc4bc/storage"
+	"github.com/lidofinance/dc4bc/storage/board"
+)
+
+const (
+	flagListenAddr         = "listen_addr"
+	flagLogPath            = "log_path"
+	flagConfig             = "config"
+	flagsEnableHTTPLogging = "enable_http_logging"
+	flagAllowList          = "allow_list"
+	flagAuditLogPath       = "audit_log_path"
+
+	shutdownTimeout = 10 * time.Second
+)
+
+var (
+	cfgFile string
+)
+
+func init() {
+	cobra.OnInitialize(initConfig)
+
+	rootCmd.PersistentFlags().String(flagListenAddr, "localhost:9090", "Listen Address")
+	rootCmd.PersistentFlags().String(flagLogPath, "./dc4bc_board_log", "Path to the append-only log file")
+	rootCmd.PersistentFlags().StringVar(&cfgFile, flagConfig, "", "path to your config file")
+	rootCmd.PersistentFlags().Bool(flagsEnableHTTPLogging, false, "enable http access logging")
+	rootCmd.PersistentFlags().String(flagAllowList, "",
+		"Path to a JSON file with participants' pubkeys ({\"username\": \"base64 pubkey\"}), if set only signed messages are accepted")
+	rootCmd.PersistentFlags().String(flagAuditLogPath, "./dc4bc_board_audit_log", "Path to the audit log of rejected writes")
+
+	exitIfError(viper.BindPFlag(flagListenAddr, rootCmd.PersistentFlags().Lookup(flagListenAddr)))
+	exitIfError(viper.BindPFlag(flagLogPath, rootCmd.PersistentFlags().Lookup(flagLogPath)))
+	exitIfError(viper.BindPFlag(flagsEnableHTTPLogging, rootCmd.PersistentFlags().Lookup(flagsEnableHTTPLogging)))
+	exitIfError(viper.BindPFlag(flagAllowList, rootCmd.PersistentFlags().Lookup(flagAllowList)))
+	exitIfError(viper.BindPFlag(flagAuditLogPath, rootCmd.PersistentFlags().Lookup(flagAuditLogPath)))
+}
+
+func exitIfError(err error) {
+	if err != nil {
+		log.Fatalf("fatal error: %v", err)
+	}
+}
+
+func initConfig() {
+	if cfgFile == "" {
+		return
+	}
+
+	viper.SetConfigFile(cfgFile)
+	exitIfError(viper.ReadInConfig())
+}
+
+func startBoardCommand() *cobra.Command {
+	return &cobra.Command{
+		Use:   "start",
+		Short: "starts dc4bc bulletin board",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			var cfg board.Config
+			if err := viper.Unmarshal(&cfg); err != nil {
+				return fmt.Errorf("failed to parse cli arguments: %w", err)
+			}
+
+			boardLog, err := board.OpenLog(cfg.LogPath)
+			if err != nil {
+				return fmt.Errorf("failed to open board log: %w", err)
+			}
+			defer boardLog.Close()
+
+			server := board.NewServer(&cfg, boardLog)
+			if cfg.AllowListPath != "" {
+				allowList, err := storage.LoadAllowList(cfg.AllowListPath)
+				if err != nil {
+					return fmt.Errorf("failed to load allow-list: %w", err)
+				}
+
+				auditLog, err := board.OpenAuditLog(cfg.AuditLogPath)
+				if err != nil {
+					return fmt.Errorf("failed to open audit log: %w", err)
+				}
+				defer auditLog.Close()
+
+				server.EnableAuthentication(allowList, auditLog)
+				log.Printf("Authentication is enabled, %d participants are allowed to write", len(allowList))
+			}
+
+			sigs := make(chan os.Signal, 1)
+			signal.Notify(sigs, syscall.SIGINT, syscall.SIGTERM)
+			go func() {
+				<-sigs
+
+				log.Println("Received signal, stopping board...")
+				ctx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+				defer cancel()
+				if err := server.Stop(ctx); err != nil {
+					log.Printf("failed to stop board: %v", err)
+				}
+			}()
+
+			log.Printf("Board started on %s, %d messages in the log", cfg.ListenAddr, boardLog.Len())
+			if err = server.Start(); err != nil && err != http.ErrServerClosed {
+				return fmt.Errorf("HTTP server error: %w", err)
+			}
+
+			log.Println("Board stopped, exiting")
+			return nil
+		},
+	}
+}
+
+var rootCmd = &cobra.Command{
+	Use:   "dc4bc_board",
+	Short: "dc4bc bulletin board daemon implementation",
+}
+
+func main() {
+	rootCmd.AddCommand(
+		startBoardCommand(),
+	)
+	if err := rootCmd.Execute(); err != nil {
+		log.Fatalf("Failed to execute root command: %v", err)
+	}
+}
@@ -3,15 +3,18 @@ package main
 import (
 	"bufio"
 	"bytes"
+	"crypto/ed25519"
 	"crypto/md5"
 	"crypto/sha256"
 	"encoding/base64"
 	"encoding/hex"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io/ioutil"
 	"log"
 	"net/http"
+	"net/url"
 	"os"
 	"path"
 	"path/filepath"
@@ -25,17 +28,22 @@ import (
 	"github.com/fatih/color"
 	"github.com/spf13/cobra"
 
+	"github.com/lidofinance/dc4bc/client/api/http_api/auth"
 	httprequests "github.com/lidofinance/dc4bc/client/api/http_api/requests"
 	httpresponses "github.com/lidofinance/dc4bc/client/api/http_api/responses"
+	"github.com/lidofinance/dc4bc/client/modules/audit"
+	"github.com/lidofinance/dc4bc/client/modules/events"
 	"github.com/lidofinance/dc4bc/client/types"
 	"github.com/lidofinance/dc4bc/dkg"
 	"github.com/lidofinance/dc4bc/fsm/fsm"
 	"github.com/lidofinance/dc4bc/fsm/state_machines"
+	"github.com/lidofinance/dc4bc/fsm/state_machines/resharing_fsm"
 	spf "github.com/lidofinance/dc4bc/fsm/state_machines/signature_proposal_fsm"
 	fsmtypes "github.com/lidofinance/dc4bc/fsm/types"
 	"github.com/lidofinance/dc4bc/fsm/types/requests"
 	"github.com/lidofinance/dc4bc/fsm/types/responses"
 	"github.com/lidofinance/dc4bc/pkg/utils"
+	"github.com/lidofinance/dc4bc/storage"
 )
 
 const (
@@ -46,17 +54,36 @@ const (
 	flagMessagesToIgnore        = "messages_to_ignore"
 	flagKafkaConsumerGroup      = "kafka_consumer_group"
 	flagPrintFullSignaturesInfo = "print_only"
+	flagAPIToken                = "api_token"
+	flagTLSCA                   = "tls_ca"
+	flagTLSCert                 = "tls_cert"
+	flagTLSKey                  = "tls_key"
+	flagEventTypes              = "types"
+	flagEventsDkgID             = "dkg_id"
+	flagEventsJSON              = "json"
+	flagAuditPubKey             = "pubkey"
+	flagDeclineReason           = "reason"
+	flagDealers                 = "dealers"
+
+	watchReconnectPeriod = 3 * time.Second
+
+	// envAPIToken is used when --api_token is not set, so the token does not show up in the process list
+	envAPIToken = "DC4BC_API_TOKEN"
 )
 
 var (
+	// apiScheme is switched to https by --tls_ca
+	apiScheme = "http"
+
 	useOffset          bool
 	messagesToIgnore   string
 	newStateDBDSN      string
 	kafkaConsumerGroup string
 
 	rootCmd = &cobra.Command{
-		Use:   "dc4bc_cli",
-		Short: "dc4bc node cli utilities implementation",
+		Use:               "dc4bc_cli",
+		Short:             "dc4bc node cli utilities implementation",
+		PersistentPreRunE: setupHTTPClient,
 	}
 
 	refreshStateCmd = &cobra.Command{
@@ -73,6 +100,10 @@ func init() {
 	rootCmd.PersistentFlags().String(flagListenAddr, "localhost:8080", "Listen Address")
 	rootCmd.PersistentFlags().String(flagJSONFilesFolder, "/tmp", "Folder to save JSON files")
 	rootCmd.PersistentFlags().Bool(flagPrintFullSignaturesInfo, false, "Print full signatures info (each participant)")
+	rootCmd.PersistentFlags().String(flagAPIToken, "", "Node HTTP API token, "+envAPIToken+" environment variable is used if not set")
+	rootCmd.PersistentFlags().String(flagTLSCA, "", "Path to the CA certificate (PEM) of the node HTTP API, enables HTTPS and is the only CA trusted")
+	rootCmd.PersistentFlags().String(flagTLSCert, "", "Path to the client TLS certificate (PEM), if the node requires client certificates")
+	rootCmd.PersistentFlags().String(flagTLSKey, "", "Path to the client TLS private key (PEM)")
 
 	refreshStateCmd.Flags().BoolVarP(&useOffset, flagUseOffsetInsteadId, "o", false,
 		"Ignore messages by offset instead of ids")
@@ -90,6 +121,9 @@ func main() {
 		reinitDKGPathCommand(),
 		readOperationResultCommand(),
 		approveDKGParticipationCommand(),
+		declineDKGParticipationCommand(),
+		proposeShareRefreshCommand(),
+		proposeResharingCommand(),
 		startDKGCommand(),
 		proposeSignMessageCommand(),
 		proposeSignBatchMessagesCommand(),
@@ -102,19 +136,83 @@ func main() {
 		getSignatureCommand(),
 		saveOffsetCommand(),
 		getOffsetCommand(),
+		getCheckpointDivergencesCommand(),
+		exportSnapshotCommand(),
+		verifySnapshotCommand(),
 		getFSMStatusCommand(),
 		getFSMListCommand(),
 		getSignatureDataCommand(),
 		refreshState(),
 		proposeSignBakedMessagesCommand(),
+		watchCommand(),
+		auditCommand(),
 	)
 	if err := rootCmd.Execute(); err != nil {
 		log.Fatal(fmt.Errorf("Failed to execute root command:  %w", err))
 	}
 }
 
+// bearerTokenTransport authenticates every request to the node HTTP API with the token
+type bearerTokenTransport struct {
+	token string
+	next  http.RoundTripper
+}
+
+func (t *bearerTokenTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	req = req.Clone(req.Context())
+	req.Header.Set("Authorization", "Bearer "+t.token)
+	return t.next.RoundTrip(req)
+}
+
+// setupHTTPClient makes the default HTTP client, used by all commands, trust the pinned CA and send the API token
+func setupHTTPClient(cmd *cobra.Command, _ []string) error {
+	var transport http.RoundTripper = http.DefaultTransport
+
+	caFile, err := cmd.Flags().GetString(flagTLSCA)
+	if err != nil {
+		return fmt.Errorf("failed to read TLS CA: %w", err)
+	}
+	if caFile != "" {
+		certFile, err := cmd.Flags().GetString(flagTLSCert)
+		if err != nil {
+			return fmt.Errorf("failed to read TLS certificate: %w", err)
+		}
+		keyFile, err := cmd.Flags().GetString(flagTLSKey)
+		if err != nil {
+			return fmt.Errorf("failed to read TLS key: %w", err)
+		}
+		tlsConfig, err := auth.ClientTLSConfig(caFile, certFile, keyFile)
+		if err != nil {
+			return fmt.Errorf("failed to init TLS: %w", err)
+		}
+
+		httpTransport := http.DefaultTransport.(*http.Transport).Clone()
+		httpTransport.TLSClientConfig = tlsConfig
+		transport = httpTransport
+		apiScheme = "https"
+	}
+
+	token, err := cmd.Flags().GetString(flagAPIToken)
+	if err != nil {
+		return fmt.Errorf("failed to read API token: %w", err)
+	}
+	if token == "" {
+		token = os.Getenv(envAPIToken)
+	}
+	if token != "" {
+		transport = &bearerTokenTransport{token: token, next: transport}
+	}
+
+	http.DefaultClient.Transport = transport
+	return nil
+}
+
+func apiURL(host string) string {
+	return apiScheme + "://" + host
+}
+
 func getOperationsRequest(host string) (*OperationsResponse, error) {
-	resp, err := http.Get(fmt.Sprintf("http://%s/getOperations", host))
+	resp, err := http.Get(fmt.Sprintf("%s/getOperations", apiURL(host)))
 	if err != nil {
 		return nil, fmt.Errorf("failed to get operations: %w", err)
 	}
@@ -150,6 +248,17 @@ func getOperationsCommand() *cobra.Command {
 				return fmt.Errorf("failed to get operations: %s", operations.ErrorMessage)
 			}
 
+			// operations must not be processed if the board shows different logs to participants
+			divergences, err := getCheckpointDivergencesRequest(listenAddr)
+			if err != nil {
+				return fmt.Errorf("failed to get checkpoint divergences: %w", err)
+			}
+			if len(divergences) > 0 {
+				color.New(color.FgRed, color.Bold).Printf("WARNING: %d checkpoint(s) of other participants "+
+					"diverge from the node's log, the log may be forked. See get_checkpoint_divergences\n",
+					len(divergences))
+			}
+
 			if len(operations.Result) == 0 {
 				color.New(color.Bold).Println("The are no available operations yet")
 				return nil
@@ -203,7 +312,7 @@ func getOperationsCommand() *cobra.Command {
 					opCmd := &cobra.Command{}
 
 					switch fsm.State(operations.Result[operationId].Type) {
-					case spf.StateAwaitParticipantsConfirmations:
+					case spf.StateAwaitParticipantsConfirmations, resharing_fsm.StateResharingAwaitParticipantsConfirmations:
 						opCmd = approveDKGParticipationCommand()
 					default:
 						opCmd = getOperationPathCommand()
@@ -225,7 +334,7 @@ func getOperationsCommand() *cobra.Command {
 }
 
 func getBatchesRequest(host string, dkgID string) (*BatchesResponse, error) {
-	resp, err := http.Get(fmt.Sprintf("http://%s/getBatches?dkgID=%s", host, dkgID))
+	resp, err := http.Get(fmt.Sprintf("%s/getBatches?dkgID=%s", apiURL(host), dkgID))
 	if err != nil {
 		return nil, fmt.Errorf("failed to get batches: %w", err)
 	}
@@ -273,7 +382,7 @@ func getBatchesCommand() *cobra.Command {
 }
 
 func getSignatures(host string, dkgID string) (map[string][]fsmtypes.ReconstructedSignature, error) {
-	resp, err := http.Get(fmt.Sprintf("http://%s/getSignatures?dkgID=%s", host, dkgID))
+	resp, err := http.Get(fmt.Sprintf("%s/getSignatures?dkgID=%s", apiURL(host), dkgID))
 	if err != nil {
 		return nil, fmt.Errorf("failed to get signatures: %w", err)
 	}
@@ -381,7 +490,7 @@ func exportSignaturesCommand() *cobra.Command {
 }
 
 func getSignatureRequest(host string, dkgID, dataHash string) (*SignatureResponse, error) {
-	resp, err := http.Get(fmt.Sprintf("http://%s/getSignatureByID?dkgID=%s&id=%s", host, dkgID, dataHash))
+	resp, err := http.Get(fmt.Sprintf("%s/getSignatureByID?dkgID=%s&id=%s", apiURL(host), dkgID, dataHash))
 	if err != nil {
 		return nil, fmt.Errorf("failed to get signatures: %w", err)
 	}
@@ -450,7 +559,7 @@ func getSignatureDataCommand() *cobra.Command {
 }
 
 func getOperationRequest(host string, operationID string) (*OperationResponse, error) {
-	resp, err := http.Get(fmt.Sprintf("http://%s/getOperation?operationID=%s", host, operationID))
+	resp, err := http.Get(fmt.Sprintf("%s/getOperation?operationID=%s", apiURL(host), operationID))
 	if err != nil {
 		return nil, fmt.Errorf("failed to get operation: %w", err)
 	}
@@ -536,7 +645,7 @@ func reinitDKGPathCommand() *cobra.Command {
 				return fmt.Errorf("failed to read file %s: %w", reDKGFile, err)
 			}
 
-			if _, err := rawPostRequest(fmt.Sprintf("http://%s/reinitDKG", listenAddr),
+			if _, err := rawPostRequest(fmt.Sprintf("%s/reinitDKG", apiURL(listenAddr)),
 				"application/json", reDKGDData); err != nil {
 				return fmt.Errorf("failed to reinit DKG: %w", err)
 			}
@@ -573,7 +682,7 @@ func getPubKeyCommand() *cobra.Command {
 				return fmt.Errorf("failed to read configuration:  %w", err)
 			}
 
-			resp, err := rawGetRequest(fmt.Sprintf("http://%s/getPubKey", listenAddr))
+			resp, err := rawGetRequest(fmt.Sprintf("%s/getPubKey", apiURL(listenAddr)))
 			if err != nil {
 				return fmt.Errorf("failed to get node's pubkey: %w", err)
 			}
@@ -606,7 +715,7 @@ func saveOffsetCommand() *cobra.Command {
 			if err != nil {
 				return fmt.Errorf("failed to create request: %w", err)
 			}
-			resp, err := rawPostRequest(fmt.Sprintf("http://%s/saveOffset", listenAddr), "application/json", data)
+			resp, err := rawPostRequest(fmt.Sprintf("%s/saveOffset", apiURL(listenAddr)), "application/json", data)
 			if err != nil {
 				return fmt.Errorf("failed to save offset: %w", err)
 			}
@@ -629,7 +738,7 @@ func getOffsetCommand() *cobra.Command {
 				return fmt.Errorf("failed to read configuration:  %w", err)
 			}
 
-			resp, err := rawGetRequest(fmt.Sprintf("http://%s/getOffset", listenAddr))
+			resp, err := rawGetRequest(fmt.Sprintf("%s/getOffset", apiURL(listenAddr)))
 			if err != nil {
 				return fmt.Errorf("failed to get offset: %w", err)
 			}
@@ -642,8 +751,319 @@ func getOffsetCommand() *cobra.Command {
 	}
 }
 
+func getCheckpointDivergencesRequest(host string) ([]types.CheckpointDivergence, error) {
+	resp, err := http.Get(fmt.Sprintf("%s/getCheckpointDivergences", apiURL(host)))
+	if err != nil {
+		return nil, fmt.Errorf("failed to get checkpoint divergences: %w", err)
+	}
+	defer resp.Body.Close()
+	responseBody, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read body: %w", err)
+	}
+
+	var response CheckpointDivergencesResponse
+	if err = json.Unmarshal(responseBody, &response); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal response:  %w", err)
+	}
+	if response.ErrorMessage != "" {
+		return nil, fmt.Errorf("failed to get checkpoint divergences: %s", response.ErrorMessage)
+	}
+
+	return response.Result, nil
+}
+
+func getCheckpointDivergencesCommand() *cobra.Command {
+	return &cobra.Command{
+		Use:   "get_checkpoint_divergences",
+		Short: "returns checkpoints of other participants which don't match the node's view of the log",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			listenAddr, err := cmd.Flags().GetString(flagListenAddr)
+			if err != nil {
+				return fmt.Errorf("failed to read configuration:  %w", err)
+			}
+
+			divergences, err := getCheckpointDivergencesRequest(listenAddr)
+			if err != nil {
+				return err
+			}
+
+			if len(divergences) == 0 {
+				color.New(color.Bold).Println("All checkpoints match the node's view of the log")
+				return nil
+			}
+
+			color.New(color.FgRed, color.Bold).Println("The board may show different logs to participants!")
+			for _, d := range divergences {
+				fmt.Println("-----------------------------------------------------")
+				fmt.Printf("Participant: %s\n", d.Participant)
+				fmt.Printf("Checkpoint offset: %d\n", d.CheckpointOffset)
+				fmt.Printf("Log offset: %d\n", d.Offset)
+				fmt.Printf("Their digest: %s\n", hex.EncodeToString(d.TheirDigest))
+				fmt.Printf("Our digest: %s\n", hex.EncodeToString(d.OurDigest))
+				fmt.Printf("Reason: %s\n", d.Reason)
+			}
+			return nil
+		},
+	}
+}
+
+func exportSnapshotRequest(host string) (*storage.Snapshot, error) {
+	resp, err := http.Get(fmt.Sprintf("%s/exportSnapshot", apiURL(host)))
+	if err != nil {
+		return nil, fmt.Errorf("failed to export snapshot: %w", err)
+	}
+	defer resp.Body.Close()
+	responseBody, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read body: %w", err)
+	}
+
+	var response SnapshotResponse
+	if err = json.Unmarshal(responseBody, &response); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal response:  %w", err)
+	}
+	if response.ErrorMessage != "" {
+		return nil, fmt.Errorf("failed to export snapshot: %s", response.ErrorMessage)
+	}
+	if err = response.Result.Verify(); err != nil {
+		return nil, fmt.Errorf("failed to verify exported snapshot: %w", err)
+	}
+	if !response.Result.Signed() {
+		return nil, fmt.Errorf("exported snapshot is not signed")
+	}
+
+	return response.Result, nil
+}
+
+func exportSnapshotCommand() *cobra.Command {
+	return &cobra.Command{
+		Use:   "export_snapshot [file]",
+		Args:  cobra.ExactArgs(1),
+		Short: "saves all board messages with offsets, signatures and a digest to the file",
+		Long: "saves all board messages with offsets, signatures and a digest to the file. " +
+			"The snapshot can be replayed into a new node state with --storage_dbdsn snapshot://[file] --snapshot_trusted_keys [public key]",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			listenAddr, err := cmd.Flags().GetString(flagListenAddr)
+			if err != nil {
+				return fmt.Errorf("failed to read configuration:  %w", err)
+			}
+
+			snapshot, err := exportSnapshotRequest(listenAddr)
+			if err != nil {
+				return err
+			}
+
+			if err = storage.WriteSnapshotFile(args[0], snapshot); err != nil {
+				return err
+			}
+
+			fmt.Printf("Snapshot of %d messages is saved to %s, digest: %s, signed by %s (%s)\n",
+				len(snapshot.Messages), args[0], hex.EncodeToString(snapshot.Digest), snapshot.Signer,
+				hex.EncodeToString(snapshot.PubKey))
+			return nil
+		},
+	}
+}
+
+func verifySnapshotCommand() *cobra.Command {
+	return &cobra.Command{
+		Use:   "verify_snapshot [file]",
+		Args:  cobra.ExactArgs(1),
+		Short: "verifies the snapshot digest and compares the snapshot with the live board",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			listenAddr, err := cmd.Flags().GetString(flagListenAddr)
+			if err != nil {
+				return fmt.Errorf("failed to read configuration:  %w", err)
+			}
+
+			snapshot, err := storage.ReadSnapshotFile(args[0])
+			if err != nil {
+				return err
+			}
+			if err = snapshot.Verify(); err != nil {
+				return fmt.Errorf("failed to verify snapshot: %w", err)
+			}
+			fmt.Printf("Snapshot digest is valid: %s\n", hex.EncodeToString(snapshot.Digest))
+			if snapshot.Signed() {
+				fmt.Printf("Snapshot is signed by %s, public key: %s\n", snapshot.Signer, hex.EncodeToString(snapshot.PubKey))
+			} else {
+				color.New(color.FgYellow).Println("Snapshot is not signed, it can't be attributed to a participant")
+			}
+
+			live, err := exportSnapshotRequest(listenAddr)
+			if err != nil {
+				return err
+			}
+
+			report := snapshot.Compare(live.Messages)
+			fmt.Printf("Snapshot messages: %d, live messages: %d\n", len(snapshot.Messages), len(live.Messages))
+			fmt.Printf("Matched: %d\n", report.Matched)
+			fmt.Printf("Removed from the board (e.g. by retention): %d\n", report.Trimmed)
+			fmt.Printf("Older than the snapshot: %d\n", report.Older)
+			fmt.Printf("Newer than the snapshot: %d\n", report.New)
+
+			if !report.Consistent() {
+				color.New(color.FgRed, color.Bold).Println("The snapshot does not match the board!")
+				fmt.Printf("Missing on the board: %v\n", report.Missing)
+				fmt.Printf("Missing in the snapshot: %v\n", report.Unexpected)
+				fmt.Printf("Different messages: %v\n", report.Mismatched)
+				return fmt.Errorf("snapshot does not match the board")
+			}
+
+			color.New(color.Bold).Println("The snapshot matches the board")
+			return nil
+		},
+	}
+}
+
+func exportAuditJournalRequest(host string) ([]audit.Entry, error) {
+	resp, err := http.Get(fmt.Sprintf("%s/exportAuditJournal", apiURL(host)))
+	if err != nil {
+		return nil, fmt.Errorf("failed to export audit journal: %w", err)
+	}
+	defer resp.Body.Close()
+	responseBody, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read body: %w", err)
+	}
+
+	var response AuditJournalResponse
+	if err = json.Unmarshal(responseBody, &response); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal response:  %w", err)
+	}
+	if response.ErrorMessage != "" {
+		return nil, fmt.Errorf("failed to export audit journal: %s", response.ErrorMessage)
+	}
+
+	return response.Result, nil
+}
+
+func getPubKey(listenAddr string) (ed25519.PublicKey, error) {
+	resp, err := rawGetRequest(fmt.Sprintf("%s/getPubKey", apiURL(listenAddr)))
+	if err != nil {
+		return nil, fmt.Errorf("failed to do HTTP request: %w", err)
+	}
+	if resp.ErrorMessage != "" {
+		return nil, fmt.Errorf("failed to get node's pubkey: %v", resp.ErrorMessage)
+	}
+	return decodePubKey(resp.Result.(string))
+}
+
+func decodePubKey(encoded string) (ed25519.PublicKey, error) {
+	pubKey, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode pubkey: %w", err)
+	}
+	if len(pubKey) != ed25519.PublicKeySize {
+		return nil, fmt.Errorf("invalid pubkey size %d", len(pubKey))
+	}
+	return pubKey, nil
+}
+
+func auditCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "audit",
+		Short: "exports and verifies the node's signed audit journal",
+	}
+	cmd.AddCommand(auditExportCommand(), auditVerifyCommand())
+	return cmd
+}
+
+func auditExportCommand() *cobra.Command {
+	return &cobra.Command{
+		Use:   "export [file]",
+		Args:  cobra.ExactArgs(1),
+		Short: "saves the audit journal of the node to the file as JSON lines",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			listenAddr, err := cmd.Flags().GetString(flagListenAddr)
+			if err != nil {
+				return fmt.Errorf("failed to read configuration:  %w", err)
+			}
+
+			entries, err := exportAuditJournalRequest(listenAddr)
+			if err != nil {
+				return err
+			}
+
+			f, err := os.OpenFile(args[0], os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0600)
+			if err != nil {
+				return fmt.Errorf("failed to create file: %w", err)
+			}
+			defer f.Close()
+			if err = audit.WriteEntries(f, entries); err != nil {
+				return err
+			}
+
+			fmt.Printf("Audit journal of %d entries is saved to %s\n", len(entries), args[0])
+			return nil
+		},
+	}
+}
+
+func auditVerifyCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "verify [file]",
+		Args:  cobra.MaximumNArgs(1),
+		Short: "verifies the hash chain and signatures of an exported (or the node's) audit journal",
+		Long: "verifies the hash chain and signatures of an exported audit journal or of a journal file. " +
+			"Without a file the live journal of the node is verified. Entries are checked against the node's " +
+			"pubkey, unless --pubkey is set",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			listenAddr, err := cmd.Flags().GetString(flagListenAddr)
+			if err != nil {
+				return fmt.Errorf("failed to read configuration:  %w", err)
+			}
+			encodedPubKey, err := cmd.Flags().GetString(flagAuditPubKey)
+			if err != nil {
+				return fmt.Errorf("failed to read configuration:  %w", err)
+			}
+
+			var pubKey ed25519.PublicKey
+			if encodedPubKey != "" {
+				pubKey, err = decodePubKey(encodedPubKey)
+			} else {
+				pubKey, err = getPubKey(listenAddr)
+			}
+			if err != nil {
+				return err
+			}
+
+			var entries []audit.Entry
+			if len(args) > 0 {
+				f, err := os.Open(args[0])
+				if err != nil {
+					return fmt.Errorf("failed to open file: %w", err)
+				}
+				defer f.Close()
+				if entries, err = audit.ReadEntries(f); err != nil {
+					return err
+				}
+			} else if entries, err = exportAuditJournalRequest(listenAddr); err != nil {
+				return err
+			}
+
+			if err = audit.Verify(entries, pubKey); err != nil {
+				color.New(color.FgRed, color.Bold).Println("The audit journal is corrupted!")
+				return err
+			}
+
+			color.New(color.Bold).Printf("The audit journal of %d entries is valid\n", len(entries))
+			if len(entries) > 0 {
+				last := entries[len(entries)-1]
+				fmt.Printf("Last entry: %d at %s, hash: %s\n", last.Seq, last.Time.Format(time.RFC3339),
+					hex.EncodeToString(last.Hash))
+			}
+			return nil
+		},
+	}
+	cmd.Flags().String(flagAuditPubKey, "", "Base64 encoded pubkey of the node which signed the journal, "+
+		"the pubkey of the node at --listen_addr if not set")
+	return cmd
+}
+
 func getUsername(listenAddr string) (string, error) {
-	resp, err := rawGetRequest(fmt.Sprintf("http://%s/getUsername", listenAddr))
+	resp, err := rawGetRequest(fmt.Sprintf("%s/getUsername", apiURL(listenAddr)))
 	if err != nil {
 		return "", fmt.Errorf("failed to do HTTP request: %w", err)
 	}
@@ -708,7 +1128,7 @@ func readOperationResultCommand() *cobra.Command {
 				return fmt.Errorf("failed to read Operation file: %w", err)
 			}
 
-			resp, err := rawPostRequest(fmt.Sprintf("http://%s/handleProcessedOperationJSON", listenAddr),
+			resp, err := rawPostRequest(fmt.Sprintf("%s/handleProcessedOperationJSON", apiURL(listenAddr)),
 				"application/json", operationBz)
 			if err != nil {
 				return fmt.Errorf("failed to handle processed operation: %w", err)
@@ -753,7 +1173,7 @@ func startDKGCommand() *cobra.Command {
 			if err != nil {
 				return fmt.Errorf("failed to marshal SignatureProposalParticipantsListRequest:  %w", err)
 			}
-			resp, err := rawPostRequest(fmt.Sprintf("http://%s/startDKG", listenAddr),
+			resp, err := rawPostRequest(fmt.Sprintf("%s/startDKG", apiURL(listenAddr)),
 				"application/json", messageDataBz)
 			if err != nil {
 				return fmt.Errorf("failed to make HTTP request to start DKG: %w", err)
@@ -783,7 +1203,7 @@ func approveDKGParticipationCommand() *cobra.Command {
 			if err != nil {
 				return fmt.Errorf("failed to marshal payload:  %w", err)
 			}
-			resp, err := rawPostRequest(fmt.Sprintf("http://%s/approveDKGParticipation", listenAddr), "application/json", payloadBz)
+			resp, err := rawPostRequest(fmt.Sprintf("%s/approveDKGParticipation", apiURL(listenAddr)), "application/json", payloadBz)
 			if err != nil {
 				return fmt.Errorf("failed to approve participation: %w", err)
 			}
@@ -795,6 +1215,118 @@ func approveDKGParticipationCommand() *cobra.Command {
 	}
 }
 
+func declineDKGParticipationCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "decline_participation [operationID] --reason [reason]",
+		Args:  cobra.ExactArgs(1),
+		Short: "decline participation in a DKG process, the DKG round is canceled for all participants",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			listenAddr, err := cmd.Flags().GetString(flagListenAddr)
+			if err != nil {
+				return fmt.Errorf("failed to read configuration:  %w", err)
+			}
+			reason, err := cmd.Flags().GetString(flagDeclineReason)
+			if err != nil {
+				return fmt.Errorf("failed to read configuration:  %w", err)
+			}
+			if reason == "" {
+				return fmt.Errorf("--%s is required", flagDeclineReason)
+			}
+
+			payloadBz, err := json.Marshal(map[string]string{"operationID": args[0], "reason": reason})
+			if err != nil {
+				return fmt.Errorf("failed to marshal payload:  %w", err)
+			}
+			resp, err := rawPostRequest(fmt.Sprintf("%s/declineDKGParticipation", apiURL(listenAddr)), "application/json", payloadBz)
+			if err != nil {
+				return fmt.Errorf("failed to decline participation: %w", err)
+			}
+			if resp.ErrorMessage != "" {
+				return fmt.Errorf("failed to decline participation: %v", resp.ErrorMessage)
+			}
+			return nil
+		},
+	}
+	cmd.Flags().String(flagDeclineReason, "", "Reason of the decline, it is recorded in the DKG round state of every participant")
+	return cmd
+}
+
+func proposeResharingCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "propose_resharing [dkgID] [committee_file]",
+		Args:  cobra.ExactArgs(2),
+		Short: "propose to reshare the key of a finished DKG round to a new committee with a new threshold",
+		Long: "propose to reshare the key of a finished DKG round to a new committee with a new threshold, the public key " +
+			"is not changed. The committee file has the format of the start_dkg proposing file. All old participants " +
+			"deal their key shares if dealers are not set",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			listenAddr, err := cmd.Flags().GetString(flagListenAddr)
+			if err != nil {
+				return fmt.Errorf("failed to read configuration:  %w", err)
+			}
+			dealers, err := cmd.Flags().GetStringSlice(flagDealers)
+			if err != nil {
+				return fmt.Errorf("failed to read configuration:  %w", err)
+			}
+
+			committeeFileData, err := ioutil.ReadFile(args[1])
+			if err != nil {
+				return fmt.Errorf("failed to read file: %w", err)
+			}
+			var committee requests.SignatureProposalParticipantsListRequest
+			if err = json.Unmarshal(committeeFileData, &committee); err != nil {
+				return fmt.Errorf("failed to unmarshal committee file: %w", err)
+			}
+
+			payloadBz, err := json.Marshal(map[string]interface{}{
+				"dkgID":     args[0],
+				"dealers":   dealers,
+				"committee": committee,
+			})
+			if err != nil {
+				return fmt.Errorf("failed to marshal payload:  %w", err)
+			}
+			resp, err := rawPostRequest(fmt.Sprintf("%s/proposeResharing", apiURL(listenAddr)), "application/json", payloadBz)
+			if err != nil {
+				return fmt.Errorf("failed to propose resharing: %w", err)
+			}
+			if resp.ErrorMessage != "" {
+				return fmt.Errorf("failed to propose resharing: %v", resp.ErrorMessage)
+			}
+			return nil
+		},
+	}
+	cmd.Flags().StringSlice(flagDealers, nil, "usernames of the old participants who deal their key shares")
+	return cmd
+}
+
+func proposeShareRefreshCommand() *cobra.Command {
+	return &cobra.Command{
+		Use:   "propose_share_refresh [dkgID]",
+		Args:  cobra.ExactArgs(1),
+		Short: "propose a refresh of the key shares of a finished DKG round, the public key is not changed",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			listenAddr, err := cmd.Flags().GetString(flagListenAddr)
+			if err != nil {
+				return fmt.Errorf("failed to read configuration:  %w", err)
+			}
+
+			payloadBz, err := json.Marshal(map[string]string{"dkgID": args[0]})
+			if err != nil {
+				return fmt.Errorf("failed to marshal payload:  %w", err)
+			}
+			resp, err := rawPostRequest(fmt.Sprintf("%s/proposeShareRefresh", apiURL(listenAddr)), "application/json", payloadBz)
+			if err != nil {
+				return fmt.Errorf("failed to propose share refresh: %w", err)
+			}
+			if resp.ErrorMessage != "" {
+				return fmt.Errorf("failed to propose share refresh: %v", resp.ErrorMessage)
+			}
+			return nil
+		},
+	}
+}
+
 func getHashOfStartDKGCommand() *cobra.Command {
 	return &cobra.Command{
 		Use:   "get_start_dkg_file_hash [proposing_file]",
@@ -818,6 +1350,12 @@ func getHashOfStartDKGCommand() *cobra.Command {
 			if _, err := hashPayload.Write([]byte(fmt.Sprintf("%d", req.SigningThreshold))); err != nil {
 				return err
 			}
+			// deadlines are hashed only if set, so hashes of proposals without them are unchanged
+			if req.Deadlines != nil {
+				if _, err := hashPayload.Write([]byte(req.Deadlines.String())); err != nil {
+					return err
+				}
+			}
 			for _, p := range participants {
 				if _, err := hashPayload.Write(p.PubKey); err != nil {
 					return err
@@ -884,7 +1422,7 @@ func proposeSignMessageCommand() *cobra.Command {
 				return fmt.Errorf("failed to marshal SigningProposalStartRequest:  %w", err)
 			}
 
-			resp, err := rawPostRequest(fmt.Sprintf("http://%s/proposeSignMessage", listenAddr),
+			resp, err := rawPostRequest(fmt.Sprintf("%s/proposeSignMessage", apiURL(listenAddr)),
 				"application/json", messageDataBz)
 			if err != nil {
 				return fmt.Errorf("failed to make HTTP request to propose message to sign: %w", err)
@@ -941,7 +1479,7 @@ func proposeSignBatchMessagesCommand() *cobra.Command {
 				return fmt.Errorf("failed to marshal SigningBatchProposalStartRequest: %w", err)
 			}
 
-			resp, err := rawPostRequest(fmt.Sprintf("http://%s/proposeSignBatchMessages", listenAddr),
+			resp, err := rawPostRequest(fmt.Sprintf("%s/proposeSignBatchMessages", apiURL(listenAddr)),
 				"application/json", messageDataBz)
 			if err != nil {
 				return fmt.Errorf("failed to make HTTP request to propose message to sign: %w", err)
@@ -992,7 +1530,7 @@ func proposeSignBakedMessagesCommand() *cobra.Command {
 				return fmt.Errorf("failed to Marshal ProposeSignBakedMessagesForm request: %w", err)
 			}
 
-			resp, err := rawPostRequest(fmt.Sprintf("http://%s/proposeSignBakedMessages", listenAddr),
+			resp, err := rawPostRequest(fmt.Sprintf("%s/proposeSignBakedMessages", apiURL(listenAddr)),
 				"application/json", messageDataBz)
 			if err != nil {
 				return fmt.Errorf("failed to make HTTP request to propose message to sign: %w", err)
@@ -1006,7 +1544,7 @@ func proposeSignBakedMessagesCommand() *cobra.Command {
 }
 
 func getFSMDumpRequest(host string, dkgID string) (*FSMDumpResponse, error) {
-	resp, err := http.Get(fmt.Sprintf("http://%s/getFSMDump?dkgID=%s", host, dkgID))
+	resp, err := http.Get(fmt.Sprintf("%s/getFSMDump?dkgID=%s", apiURL(host), dkgID))
 	if err != nil {
 		return nil, fmt.Errorf("failed to get FSM dump: %w", err)
 	}
@@ -1061,6 +1599,18 @@ func getFSMStatusCommand() *cobra.Command {
 					quorum[k] = v
 				}
 			}
+			// the resharing quorum is set when all participants approve the resharing
+			if strings.HasPrefix(string(dump.State), "state_resharing") {
+				if dump.Payload.ResharingPayload.Quorum == nil {
+					for k, v := range dump.Payload.ResharingPayload.Proposal {
+						quorum[k] = v
+					}
+				} else {
+					for k, v := range dump.Payload.ResharingPayload.Quorum {
+						quorum[k] = v
+					}
+				}
+			}
 
 			waiting := make([]string, 0)
 			confirmed := make([]string, 0)
@@ -1097,7 +1647,7 @@ func getFSMStatusCommand() *cobra.Command {
 				fmt.Printf("Participants who got some error during a process: %s\n", strings.Join(waiting, ", "))
 			}
 
-			if len(dump.Payload.DKGProposalPayload.PubPolyBz) != 0 {
+			if dump.Payload.DKGProposalPayload != nil && len(dump.Payload.DKGProposalPayload.PubPolyBz) != 0 {
 				suite := bls12381.NewBLS12381Suite(nil)
 				blsKeyring, err := dkg.LoadPubPolyBLSKeyringFromBytes(suite, dump.Payload.DKGProposalPayload.PubPolyBz)
 				if err != nil {
@@ -1126,7 +1676,7 @@ func getFSMListCommand() *cobra.Command {
 				return fmt.Errorf("failed to read configuration:  %w", err)
 			}
 
-			resp, err := rawGetRequest(fmt.Sprintf("http://%s/getFSMList", listenAddr))
+			resp, err := rawGetRequest(fmt.Sprintf("%s/getFSMList", apiURL(listenAddr)))
 			if err != nil {
 				return fmt.Errorf("failed to make HTTP request to get FSM list: %w", err)
 			}
@@ -1181,7 +1731,7 @@ func refreshState() *cobra.Command {
 			return fmt.Errorf("failed to marshal reset state request: %w", err)
 		}
 
-		resp, err := rawPostRequest(fmt.Sprintf("http://%s/resetState", listenAddr),
+		resp, err := rawPostRequest(fmt.Sprintf("%s/resetState", apiURL(listenAddr)),
 			"application/json", reqBytes)
 		if err != nil {
 			return fmt.Errorf("failed to make HTTP request to reset state: %w", err)
@@ -1220,3 +1770,141 @@ func parseMessagesToIgnore(input string) ([]string, error) {
 		return nil, fmt.Errorf("invalid messages to ignore flag %s, please refer to the flag usage", input)
 	}
 }
+
+func watchCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "watch",
+		Short: "prints node events as they happen: new operations, FSM state changes, signatures and errors",
+		Long: "prints node events as they happen: new operations, FSM state changes, signatures and errors. " +
+			"The command reconnects to the node if the connection is lost and does not miss events kept by the node",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			listenAddr, err := cmd.Flags().GetString(flagListenAddr)
+			if err != nil {
+				return fmt.Errorf("failed to read configuration:  %w", err)
+			}
+			eventTypes, err := cmd.Flags().GetString(flagEventTypes)
+			if err != nil {
+				return fmt.Errorf("failed to read configuration:  %w", err)
+			}
+			dkgID, err := cmd.Flags().GetString(flagEventsDkgID)
+			if err != nil {
+				return fmt.Errorf("failed to read configuration:  %w", err)
+			}
+			printJSON, err := cmd.Flags().GetBool(flagEventsJSON)
+			if err != nil {
+				return fmt.Errorf("failed to read configuration:  %w", err)
+			}
+			if _, err = events.ParseFilter(eventTypes, dkgID); err != nil {
+				return err
+			}
+
+			query := url.Values{}
+			if eventTypes != "" {
+				query.Set("types", eventTypes)
+			}
+			if dkgID != "" {
+				query.Set("dkg_id", dkgID)
+			}
+			eventsURL := fmt.Sprintf("%s/events?%s", apiURL(listenAddr), query.Encode())
+
+			var lastEventID uint64
+			for {
+				err = watchEvents(eventsURL, &lastEventID, func(e events.Event) error {
+					if printJSON {
+						bz, err := json.Marshal(e)
+						if err != nil {
+							return fmt.Errorf("failed to marshal event: %w", err)
+						}
+						fmt.Println(string(bz))
+						return nil
+					}
+					printEvent(e)
+					return nil
+				})
+				var statusErr watchStatusError
+				if errors.As(err, &statusErr) && statusErr.code < http.StatusInternalServerError {
+					return err
+				}
+				if err != nil {
+					color.New(color.FgRed).Fprintf(os.Stderr, "Event stream is interrupted: %v\n", err)
+				}
+				time.Sleep(watchReconnectPeriod)
+			}
+		},
+	}
+	cmd.Flags().String(flagEventTypes, "", "Comma separated event types to watch: operation, state_change, signature, error")
+	cmd.Flags().String(flagEventsDkgID, "", "Watch events of the DKG round only")
+	cmd.Flags().Bool(flagEventsJSON, false, "Print events as JSON lines")
+	return cmd
+}
+
+type watchStatusError struct {
+	code    int
+	message string
+}
+
+func (e watchStatusError) Error() string {
+	return fmt.Sprintf("failed to watch events, status %d: %s", e.code, e.message)
+}
+
+// watchEvents reads the event stream until it ends, lastEventID is updated
+// with every received event so the stream is resumed after a reconnect
+func watchEvents(eventsURL string, lastEventID *uint64, fn func(events.Event) error) error {
+	req, err := http.NewRequest(http.MethodGet, eventsURL, nil)
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Accept", "text/event-stream")
+	if *lastEventID > 0 {
+		req.Header.Set("Last-Event-ID", strconv.FormatUint(*lastEventID, 10))
+	}
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to connect to the node: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := ioutil.ReadAll(resp.Body)
+		return watchStatusError{code: resp.StatusCode, message: strings.TrimSpace(string(body))}
+	}
+
+	return events.ReadSSE(resp.Body, func(e events.Event) error {
+		*lastEventID = e.ID
+		return fn(e)
+	})
+}
+
+func printEvent(e events.Event) {
+	var details string
+	switch e.Type {
+	case events.TypeOperation:
+		var op events.OperationEvent
+		if err := json.Unmarshal(e.Data, &op); err == nil {
+			details = fmt.Sprintf("new operation %s (%s), process it with get_operations", op.OperationID, op.Type)
+		}
+	case events.TypeStateChange:
+		var change events.StateChangeEvent
+		if err := json.Unmarshal(e.Data, &change); err == nil {
+			details = fmt.Sprintf("%s -> %s on %s", change.From, change.To, change.Event)
+		}
+	case events.TypeSignature:
+		var sig events.SignatureEvent
+		if err := json.Unmarshal(e.Data, &sig); err == nil {
+			details = fmt.Sprintf("signature of message %s of batch %s reconstructed by %s",
+				sig.MessageID, sig.BatchID, sig.Username)
+		}
+	case events.TypeError:
+		var errEvent events.ErrorEvent
+		if err := json.Unmarshal(e.Data, &errEvent); err == nil {
+			details = color.RedString("failed to process %s: %s", errEvent.Event, errEvent.Error)
+		}
+	}
+	if details == "" {
+		details = string(e.Data)
+	}
+
+	fmt.Printf("%s %s offset %d %s %s\n", e.Time.Local().Format(time.RFC3339),
+		color.YellowString("%-12s", e.Type), e.Offset, color.CyanString(e.DkgRoundID), details)
+}
@@ -7,17 +7,20 @@ import (
 	"fmt"
 	"sort"
 
+	"github.com/lidofinance/dc4bc/client/modules/audit"
 	"github.com/lidofinance/dc4bc/client/repositories/signature"
 
 	"github.com/lidofinance/dc4bc/client/types"
 	"github.com/lidofinance/dc4bc/fsm/fsm"
 	"github.com/lidofinance/dc4bc/fsm/state_machines"
 	"github.com/lidofinance/dc4bc/fsm/state_machines/dkg_proposal_fsm"
+	"github.com/lidofinance/dc4bc/fsm/state_machines/resharing_fsm"
 	"github.com/lidofinance/dc4bc/fsm/state_machines/signature_proposal_fsm"
 	"github.com/lidofinance/dc4bc/fsm/state_machines/signing_proposal_fsm"
 	fsmtypes "github.com/lidofinance/dc4bc/fsm/types"
 	"github.com/lidofinance/dc4bc/fsm/types/requests"
 	"github.com/lidofinance/dc4bc/fsm/types/responses"
+	"github.com/lidofinance/dc4bc/storage"
 )
 
 type DKGInvitationResponse responses.SignatureProposalParticipantInvitationsResponse
@@ -57,6 +60,21 @@ type SignatureResponse struct {
 	Result       []fsmtypes.ReconstructedSignature `json:"result"`
 }
 
+type CheckpointDivergencesResponse struct {
+	ErrorMessage string                       `json:"error_message,omitempty"`
+	Result       []types.CheckpointDivergence `json:"result"`
+}
+
+type SnapshotResponse struct {
+	ErrorMessage string            `json:"error_message,omitempty"`
+	Result       *storage.Snapshot `json:"result"`
+}
+
+type AuditJournalResponse struct {
+	ErrorMessage string        `json:"error_message,omitempty"`
+	Result       []audit.Entry `json:"result"`
+}
+
 type OperationResponse struct {
 	ErrorMessage string           `json:"error_message,omitempty"`
 	Result       *types.Operation `json:"result"`
@@ -76,6 +94,12 @@ func calcStartDKGMessageHash(payload []byte) ([]byte, error) {
 	if _, err := hashPayload.Write([]byte(fmt.Sprintf("%d", msg[0].Threshold))); err != nil {
 		return nil, err
 	}
+	// deadlines are the same for everyone too and are hashed only if set
+	if msg[0].Deadlines != nil {
+		if _, err := hashPayload.Write([]byte(msg[0].Deadlines.String())); err != nil {
+			return nil, err
+		}
+	}
 	for _, p := range msg {
 		if _, err := hashPayload.Write(p.PubKey); err != nil {
 			return nil, err
@@ -95,6 +119,8 @@ func getShortOperationDescription(operationType types.OperationType) string {
 	switch fsm.State(operationType) {
 	case signature_proposal_fsm.StateAwaitParticipantsConfirmations:
 		return "confirm participation in the new DKG round"
+	case resharing_fsm.StateResharingAwaitParticipantsConfirmations:
+		return "confirm participation in the resharing of a DKG round"
 	case dkg_proposal_fsm.StateDkgCommitsAwaitConfirmations:
 		return "send commits for the DKG round"
 	case dkg_proposal_fsm.StateDkgDealsAwaitConfirmations:
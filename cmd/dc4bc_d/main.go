@@ -1,19 +1,29 @@
 package main
 
 import (
+	"bytes"
 	"context"
+	"encoding/hex"
 	"fmt"
+	"io/ioutil"
 	"log"
 	"os"
 	"os/signal"
+	"strings"
 	"syscall"
+	"time"
 
 	"github.com/spf13/cobra"
 	"github.com/spf13/viper"
+	"golang.org/x/crypto/ssh/terminal"
 
 	"github.com/lidofinance/dc4bc/client/api/http_api"
+	"github.com/lidofinance/dc4bc/client/api/http_api/auth"
 	apiconfig "github.com/lidofinance/dc4bc/client/config"
 	"github.com/lidofinance/dc4bc/client/modules/keystore"
+	"github.com/lidofinance/dc4bc/client/modules/logger"
+	"github.com/lidofinance/dc4bc/client/modules/signer"
+	"github.com/lidofinance/dc4bc/client/modules/webhook"
 	"github.com/lidofinance/dc4bc/client/services"
 	"github.com/lidofinance/dc4bc/client/services/node"
 	"github.com/lidofinance/dc4bc/fsm/config"
@@ -38,6 +48,35 @@ const (
 	flagOffsetsToIgnoreMessages  = "offsets_to_ignore_messages"
 	flagsEnableHTTPLogging       = "enable_http_logging"
 	flagsEnableHTTPDebug         = "enable_http_debug"
+	flagCheckpointPeriod         = "checkpoint_period"
+	flagFileStorageLockFile      = "file_storage_lock_file"
+	flagHTTPStorageTimeout       = "http_storage_timeout"
+	flagSQLiteBusyTimeout        = "sqlite_busy_timeout"
+	flagSnapshotTrustedKeys      = "snapshot_trusted_keys"
+	flagKeyStorePasswordFile     = "key_store_password_file"
+	flagSigner                   = "signer"
+	flagPKCS11Module             = "pkcs11_module"
+	flagPKCS11TokenLabel         = "pkcs11_token_label"
+	flagPKCS11KeyLabel           = "pkcs11_key_label"
+	flagPKCS11PINFile            = "pkcs11_pin_file"
+	flagRemoteSignerSocket       = "remote_signer_socket"
+	flagRemoteSignerTimeout      = "remote_signer_timeout"
+	flagAPITokensFile            = "api_tokens_file"
+	flagAPIInsecureNoAuth        = "api_insecure_no_auth"
+	flagAPITLSCert               = "api_tls_cert"
+	flagAPITLSKey                = "api_tls_key"
+	flagAPITLSClientCA           = "api_tls_client_ca"
+	flagWebhookURLs              = "webhook_urls"
+	flagWebhookEvents            = "webhook_events"
+	flagWebhookSecretFile        = "webhook_secret_file"
+	flagWebhookMaxRetries        = "webhook_max_retries"
+	flagWebhookRetryBackoff      = "webhook_retry_backoff"
+	flagWebhookTimeout           = "webhook_timeout"
+	flagAuditJournalPath         = "audit_journal_path"
+	flagLogLevel                 = "log_level"
+	flagLogFormat                = "log_format"
+	flagAPITokenName             = "name"
+	flagAPITokenRole             = "role"
 )
 
 var (
@@ -50,7 +89,7 @@ func init() {
 	rootCmd.PersistentFlags().String(flagUserName, "testUser", "Username")
 	rootCmd.PersistentFlags().String(flagListenAddr, "localhost:8080", "Listen Address")
 	rootCmd.PersistentFlags().String(flagStateDBDSN, "./dc4bc_client_state", "State DBDSN")
-	rootCmd.PersistentFlags().String(flagStorageDBDSN, "./dc4bc_file_storage", "Storage DBDSN")
+	rootCmd.PersistentFlags().String(flagStorageDBDSN, "file://./dc4bc_file_storage", "Storage DBDSN, the scheme picks the storage: file://, sqlite://, kafka://, http:// or https://. DBDSN without a scheme is a Kafka broker endpoint")
 	rootCmd.PersistentFlags().String(flagStorageTopic, "messages", "Storage Topic (Kafka)")
 	rootCmd.PersistentFlags().String(flagKafkaProducerCredentials, "producer:producerpass", "Producer credentials for Kafka: username:password")
 	rootCmd.PersistentFlags().String(flagKafkaConsumerCredentials, "consumer:consumerpass", "Consumer credentials for Kafka: username:password")
@@ -65,6 +104,33 @@ func init() {
 	rootCmd.PersistentFlags().Bool(flagOffsetsToIgnoreMessages, false, "Consider values provided in "+flagStorageIgnoreMessages+" flag to be message offsets instead of ids")
 	rootCmd.PersistentFlags().Bool(flagsEnableHTTPLogging, false, "enable http access logging")
 	rootCmd.PersistentFlags().Bool(flagsEnableHTTPDebug, false, "enable http debug messages")
+	rootCmd.PersistentFlags().String(flagFileStorageLockFile, "", "Lock file of the file storage (file://), shared by all nodes using the storage")
+	rootCmd.PersistentFlags().String(flagHTTPStorageTimeout, "60s", "HTTP bulletin board (http://, https://) I/O Timeout")
+	rootCmd.PersistentFlags().String(flagSQLiteBusyTimeout, "10s", "How long writers of the SQLite storage (sqlite://) wait for each other")
+	rootCmd.PersistentFlags().String(flagSnapshotTrustedKeys, "", "Hex public keys separated by comma, which are trusted to sign the replayed snapshot (snapshot://)")
+	rootCmd.PersistentFlags().String(flagKeyStorePasswordFile, "", "Path to a file with the key store password, the password is prompted if not set")
+	rootCmd.PersistentFlags().String(flagSigner, services.KeyStoreSigner, "Signer of board messages: keystore, pkcs11 or remote")
+	rootCmd.PersistentFlags().String(flagPKCS11Module, "", "Path to the PKCS#11 module (pkcs11 signer), e.g. /usr/lib/softhsm/libsofthsm2.so")
+	rootCmd.PersistentFlags().String(flagPKCS11TokenLabel, "", "Label of the PKCS#11 token (pkcs11 signer)")
+	rootCmd.PersistentFlags().String(flagPKCS11KeyLabel, "", "Label of the Ed25519 key pair in the PKCS#11 token (pkcs11 signer)")
+	rootCmd.PersistentFlags().String(flagPKCS11PINFile, "", "Path to a file with the PKCS#11 user PIN, the PIN is prompted if not set")
+	rootCmd.PersistentFlags().String(flagRemoteSignerSocket, "./dc4bc_signer.sock", "Unix socket of the remote signer (remote signer, serve_signer)")
+	rootCmd.PersistentFlags().String(flagRemoteSignerTimeout, "10s", "Remote signer I/O Timeout")
+	rootCmd.PersistentFlags().String(flagAPITokensFile, "", "Path to the file with HTTP API tokens and their roles, required unless --"+flagAPIInsecureNoAuth+" is set")
+	rootCmd.PersistentFlags().Bool(flagAPIInsecureNoAuth, false, "Serve the HTTP API without authentication if the API tokens file is not set")
+	rootCmd.PersistentFlags().String(flagAPITLSCert, "", "Path to the HTTP API TLS certificate (PEM), enables HTTPS")
+	rootCmd.PersistentFlags().String(flagAPITLSKey, "", "Path to the HTTP API TLS private key (PEM)")
+	rootCmd.PersistentFlags().String(flagAPITLSClientCA, "", "Path to the CA certificate (PEM) of HTTP API clients, if set the clients must present a certificate issued by it")
+	rootCmd.PersistentFlags().String(flagWebhookURLs, "", "Webhook URLs separated by comma, notified about new operations, finished DKGs and signings and timeouts")
+	rootCmd.PersistentFlags().String(flagWebhookEvents, "", "Webhook events separated by comma: operation, dkg_finished, signing_finished, timeout. All events if not set")
+	rootCmd.PersistentFlags().String(flagWebhookSecretFile, "", "Path to a file with the secret of webhook HMAC signatures, required if webhooks are set")
+	rootCmd.PersistentFlags().Int(flagWebhookMaxRetries, 5, "How many times a failed webhook is retried")
+	rootCmd.PersistentFlags().String(flagWebhookRetryBackoff, "1s", "Delay before the first webhook retry, doubled for every next retry")
+	rootCmd.PersistentFlags().String(flagWebhookTimeout, "10s", "Webhook request Timeout")
+	rootCmd.PersistentFlags().String(flagAuditJournalPath, "./dc4bc_audit_journal", "Path to the signed audit journal of node actions, empty value disables the journal")
+	rootCmd.PersistentFlags().String(flagLogLevel, "info", "Level of logged messages: debug, info, warn or error")
+	rootCmd.PersistentFlags().String(flagLogFormat, "text", "Format of logged messages: text or json")
+	rootCmd.PersistentFlags().String(flagCheckpointPeriod, "10m", "How often to post a signed checkpoint of the log to the board, empty value disables checkpoints")
 
 	exitIfError(viper.BindPFlag(flagUserName, rootCmd.PersistentFlags().Lookup(flagUserName)))
 	exitIfError(viper.BindPFlag(flagListenAddr, rootCmd.PersistentFlags().Lookup(flagListenAddr)))
@@ -84,6 +150,33 @@ func init() {
 	exitIfError(viper.BindPFlag(flagOffsetsToIgnoreMessages, rootCmd.PersistentFlags().Lookup(flagOffsetsToIgnoreMessages)))
 	exitIfError(viper.BindPFlag(flagsEnableHTTPLogging, rootCmd.PersistentFlags().Lookup(flagsEnableHTTPLogging)))
 	exitIfError(viper.BindPFlag(flagsEnableHTTPDebug, rootCmd.PersistentFlags().Lookup(flagsEnableHTTPDebug)))
+	exitIfError(viper.BindPFlag(flagFileStorageLockFile, rootCmd.PersistentFlags().Lookup(flagFileStorageLockFile)))
+	exitIfError(viper.BindPFlag(flagHTTPStorageTimeout, rootCmd.PersistentFlags().Lookup(flagHTTPStorageTimeout)))
+	exitIfError(viper.BindPFlag(flagSQLiteBusyTimeout, rootCmd.PersistentFlags().Lookup(flagSQLiteBusyTimeout)))
+	exitIfError(viper.BindPFlag(flagSnapshotTrustedKeys, rootCmd.PersistentFlags().Lookup(flagSnapshotTrustedKeys)))
+	exitIfError(viper.BindPFlag(flagCheckpointPeriod, rootCmd.PersistentFlags().Lookup(flagCheckpointPeriod)))
+	exitIfError(viper.BindPFlag(flagKeyStorePasswordFile, rootCmd.PersistentFlags().Lookup(flagKeyStorePasswordFile)))
+	exitIfError(viper.BindPFlag(flagSigner, rootCmd.PersistentFlags().Lookup(flagSigner)))
+	exitIfError(viper.BindPFlag(flagPKCS11Module, rootCmd.PersistentFlags().Lookup(flagPKCS11Module)))
+	exitIfError(viper.BindPFlag(flagPKCS11TokenLabel, rootCmd.PersistentFlags().Lookup(flagPKCS11TokenLabel)))
+	exitIfError(viper.BindPFlag(flagPKCS11KeyLabel, rootCmd.PersistentFlags().Lookup(flagPKCS11KeyLabel)))
+	exitIfError(viper.BindPFlag(flagPKCS11PINFile, rootCmd.PersistentFlags().Lookup(flagPKCS11PINFile)))
+	exitIfError(viper.BindPFlag(flagRemoteSignerSocket, rootCmd.PersistentFlags().Lookup(flagRemoteSignerSocket)))
+	exitIfError(viper.BindPFlag(flagRemoteSignerTimeout, rootCmd.PersistentFlags().Lookup(flagRemoteSignerTimeout)))
+	exitIfError(viper.BindPFlag(flagAPITokensFile, rootCmd.PersistentFlags().Lookup(flagAPITokensFile)))
+	exitIfError(viper.BindPFlag(flagAPIInsecureNoAuth, rootCmd.PersistentFlags().Lookup(flagAPIInsecureNoAuth)))
+	exitIfError(viper.BindPFlag(flagAPITLSCert, rootCmd.PersistentFlags().Lookup(flagAPITLSCert)))
+	exitIfError(viper.BindPFlag(flagAPITLSKey, rootCmd.PersistentFlags().Lookup(flagAPITLSKey)))
+	exitIfError(viper.BindPFlag(flagAPITLSClientCA, rootCmd.PersistentFlags().Lookup(flagAPITLSClientCA)))
+	exitIfError(viper.BindPFlag(flagWebhookURLs, rootCmd.PersistentFlags().Lookup(flagWebhookURLs)))
+	exitIfError(viper.BindPFlag(flagWebhookEvents, rootCmd.PersistentFlags().Lookup(flagWebhookEvents)))
+	exitIfError(viper.BindPFlag(flagWebhookSecretFile, rootCmd.PersistentFlags().Lookup(flagWebhookSecretFile)))
+	exitIfError(viper.BindPFlag(flagWebhookMaxRetries, rootCmd.PersistentFlags().Lookup(flagWebhookMaxRetries)))
+	exitIfError(viper.BindPFlag(flagWebhookRetryBackoff, rootCmd.PersistentFlags().Lookup(flagWebhookRetryBackoff)))
+	exitIfError(viper.BindPFlag(flagWebhookTimeout, rootCmd.PersistentFlags().Lookup(flagWebhookTimeout)))
+	exitIfError(viper.BindPFlag(flagAuditJournalPath, rootCmd.PersistentFlags().Lookup(flagAuditJournalPath)))
+	exitIfError(viper.BindPFlag(flagLogLevel, rootCmd.PersistentFlags().Lookup(flagLogLevel)))
+	exitIfError(viper.BindPFlag(flagLogFormat, rootCmd.PersistentFlags().Lookup(flagLogFormat)))
 
 }
 
@@ -104,10 +197,19 @@ func initConfig() {
 
 func prepareConfig() (*apiconfig.Config, error) {
 	cfg := apiconfig.Config{}
-	kafkaCfg := apiconfig.KafkaStorageConfig{}
+	storageCfg := apiconfig.StorageConfig{}
 	httpCfg := apiconfig.HttpApiConfig{}
-
-	for _, c := range []interface{}{&cfg, &kafkaCfg, &httpCfg} {
+	signerCfg := apiconfig.SignerConfig{}
+	webhookCfg := apiconfig.WebhookConfig{}
+	logCfg := apiconfig.LogConfig{}
+	// every storage backend takes its settings from its own section
+	storageSections := services.NewStorageSections()
+
+	sections := []interface{}{&cfg, &storageCfg, &httpCfg, &signerCfg, &webhookCfg, &logCfg}
+	for _, section := range storageSections {
+		sections = append(sections, section)
+	}
+	for _, c := range sections {
 		err := viper.Unmarshal(c)
 		if err != nil {
 			return nil, fmt.Errorf("failed to parse cli arguments: %w", err)
@@ -115,11 +217,109 @@ func prepareConfig() (*apiconfig.Config, error) {
 	}
 
 	cfg.HttpApiConfig = &httpCfg
-	cfg.KafkaStorageConfig = &kafkaCfg
+	cfg.StorageConfig = &storageCfg
+	cfg.StorageSections = storageSections
+	cfg.SignerConfig = &signerCfg
+	cfg.WebhookConfig = &webhookCfg
+	cfg.LogConfig = &logCfg
 
 	return &cfg, nil
 }
 
+// readPassword prompts for a password, if confirm is set the password must be entered twice
+func readPassword(prompt string, confirm bool) (string, error) {
+	for {
+		fmt.Print(prompt)
+		password, err := terminal.ReadPassword(syscall.Stdin)
+		fmt.Println()
+		if err != nil {
+			return "", fmt.Errorf("failed to read password: %w", err)
+		}
+		if !confirm {
+			return string(password), nil
+		}
+
+		fmt.Print("Confirm password: ")
+		confirmedPassword, err := terminal.ReadPassword(syscall.Stdin)
+		fmt.Println()
+		if err != nil {
+			return "", fmt.Errorf("failed to read password: %w", err)
+		}
+		if bytes.Equal(password, confirmedPassword) {
+			return string(password), nil
+		}
+		fmt.Println("Passwords do not match! Try again!")
+	}
+}
+
+// keyStorePassword reads the key store password from the password file or prompts for it
+func keyStorePassword(prompt string, confirm bool) (string, error) {
+	return readSecret(viper.GetString(flagKeyStorePasswordFile), prompt, confirm)
+}
+
+// readSecret reads a secret from the file, or prompts for it if the file is not set
+func readSecret(filename, prompt string, confirm bool) (string, error) {
+	if filename == "" {
+		return readPassword(prompt, confirm)
+	}
+
+	secret, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return "", fmt.Errorf("failed to read %s: %w", filename, err)
+	}
+	return strings.TrimRight(string(secret), "\r\n"), nil
+}
+
+// readSignerSecrets reads the key store password or the PKCS#11 PIN, if the configured signer needs it
+func readSignerSecrets(cfg *apiconfig.Config) (err error) {
+	switch cfg.SignerConfig.Type {
+	case "", services.KeyStoreSigner:
+		cfg.KeyStorePassword, err = keyStorePassword("Enter key store password: ", false)
+	case services.PKCS11Signer:
+		cfg.SignerConfig.PKCS11PIN, err = readSecret(viper.GetString(flagPKCS11PINFile), "Enter PKCS#11 user PIN: ", false)
+	}
+	return err
+}
+
+// newWebhookNotifier returns nil if webhooks are not configured
+func newWebhookNotifier(cfg *apiconfig.Config, l logger.Logger) (*webhook.Notifier, error) {
+	webhookCfg := cfg.WebhookConfig
+	if webhookCfg.URLs == "" {
+		return nil, nil
+	}
+
+	secretFile := viper.GetString(flagWebhookSecretFile)
+	if secretFile == "" {
+		return nil, fmt.Errorf("--%s is not set", flagWebhookSecretFile)
+	}
+	secret, err := readSecret(secretFile, "", false)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read webhook secret: %w", err)
+	}
+	notifyEvents, err := webhook.ParseEvents(webhookCfg.Events)
+	if err != nil {
+		return nil, err
+	}
+	retryBackoff, err := time.ParseDuration(webhookCfg.RetryBackoff)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse webhook retry backoff: %w", err)
+	}
+	timeout, err := time.ParseDuration(webhookCfg.Timeout)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse webhook timeout: %w", err)
+	}
+
+	return webhook.NewNotifier(webhook.Config{
+		URLs:         strings.Split(webhookCfg.URLs, ","),
+		Secret:       []byte(secret),
+		Events:       notifyEvents,
+		Username:     cfg.Username,
+		MaxRetries:   webhookCfg.MaxRetries,
+		RetryBackoff: retryBackoff,
+		Timeout:      timeout,
+	}, l)
+}
+
 func genKeyPairCommand() *cobra.Command {
 	return &cobra.Command{
 		Use:   "gen_keys",
@@ -137,12 +337,17 @@ func genKeyPairCommand() *cobra.Command {
 
 			keyStoreDBDSN := viper.GetString(flagStoreDBDSN)
 
+			password, err := keyStorePassword("Enter key store password: ", true)
+			if err != nil {
+				return err
+			}
+
 			keyPair := keystore.NewKeyPair()
 			keyStore, err := keystore.NewLevelDBKeyStore(username, keyStoreDBDSN)
 			if err != nil {
 				return fmt.Errorf("failed to init key store: %w", err)
 			}
-			if err = keyStore.PutKeys(username, keyPair); err != nil {
+			if err = keyStore.PutKeys(username, password, keyPair); err != nil {
 				return fmt.Errorf("failed to save keypair: %w", err)
 			}
 			fmt.Printf("keypair generated for user %s and saved to %s\n", username, keyStoreDBDSN)
@@ -151,6 +356,159 @@ func genKeyPairCommand() *cobra.Command {
 	}
 }
 
+func migrateKeyStoreCommand() *cobra.Command {
+	return &cobra.Command{
+		Use:   "migrate_keystore",
+		Short: "encrypts keys of a plaintext key store with a password",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			keyStoreDBDSN := viper.GetString(flagStoreDBDSN)
+
+			keyStore, err := keystore.NewLevelDBKeyStore(viper.GetString(flagUserName), keyStoreDBDSN)
+			if err != nil {
+				return fmt.Errorf("failed to init key store: %w", err)
+			}
+
+			password, err := keyStorePassword("Enter new key store password: ", true)
+			if err != nil {
+				return err
+			}
+
+			usernames, err := keyStore.MigratePlaintextKeys(password)
+			if err != nil {
+				return fmt.Errorf("failed to migrate key store: %w", err)
+			}
+			if len(usernames) == 0 {
+				fmt.Printf("there are no plaintext keys in %s\n", keyStoreDBDSN)
+				return nil
+			}
+			fmt.Printf("keys of users %s are encrypted in %s\n", strings.Join(usernames, ", "), keyStoreDBDSN)
+			return nil
+		},
+	}
+}
+
+func changeKeyStorePasswordCommand() *cobra.Command {
+	return &cobra.Command{
+		Use:   "change_keystore_password",
+		Short: "re-encrypts the user keys with a new password",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			username := viper.GetString(flagUserName)
+			keyStoreDBDSN := viper.GetString(flagStoreDBDSN)
+
+			keyStore, err := keystore.NewLevelDBKeyStore(username, keyStoreDBDSN)
+			if err != nil {
+				return fmt.Errorf("failed to init key store: %w", err)
+			}
+
+			oldPassword, err := readPassword("Enter current key store password: ", false)
+			if err != nil {
+				return err
+			}
+			newPassword, err := readPassword("Enter new key store password: ", true)
+			if err != nil {
+				return err
+			}
+
+			if err = keyStore.ChangePassword(username, oldPassword, newPassword); err != nil {
+				return fmt.Errorf("failed to change key store password: %w", err)
+			}
+			fmt.Printf("key store password is changed for user %s\n", username)
+			return nil
+		},
+	}
+}
+
+func serveSignerCommand() *cobra.Command {
+	return &cobra.Command{
+		Use:   "serve_signer",
+		Short: "serves a remote signer on a Unix socket, so the node does not keep the hot key in memory",
+		Long: "serves a remote signer on a Unix socket with the key from the key store (or a PKCS#11 token, " +
+			"if --signer pkcs11 is set), start the node with --signer remote and the same --remote_signer_socket",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			cfg, err := prepareConfig()
+			if err != nil {
+				return fmt.Errorf("failed to prepare config: %w", err)
+			}
+			if cfg.SignerConfig.Type == services.RemoteSigner {
+				return fmt.Errorf("remote signer can't serve another remote signer")
+			}
+			if err = readSignerSecrets(cfg); err != nil {
+				return fmt.Errorf("failed to read signer secrets: %w", err)
+			}
+
+			nodeSigner, err := services.NewSigner(cfg)
+			if err != nil {
+				return fmt.Errorf("failed to init signer: %w", err)
+			}
+			signerLogger, err := services.NewLogger(cfg.Username, cfg.LogConfig)
+			if err != nil {
+				return fmt.Errorf("failed to init logger: %w", err)
+			}
+
+			l, err := signer.ListenRemoteSigner(cfg.SignerConfig.RemoteSignerSocket)
+			if err != nil {
+				return err
+			}
+
+			sigs := make(chan os.Signal, 1)
+			signal.Notify(sigs, syscall.SIGINT, syscall.SIGTERM)
+			go func() {
+				<-sigs
+				signerLogger.Infof("Received signal, stopping signer...")
+				l.Close()
+			}()
+
+			signerLogger.Infof("Signer of %s started on %s", hex.EncodeToString(nodeSigner.PubKey()),
+				cfg.SignerConfig.RemoteSignerSocket)
+			return signer.ServeRemoteSigner(l, nodeSigner, signerLogger)
+		},
+	}
+}
+
+func genAPITokenCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "gen_api_token",
+		Short: "generates an HTTP API token with the given role and adds its hash to the tokens file",
+		Long: "generates an HTTP API token with the given role (read-only, operator or admin) and adds its hash " +
+			"to --api_tokens_file, the token is printed once and must be passed to dc4bc_cli with --api_token",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			tokensFile := viper.GetString(flagAPITokensFile)
+			if tokensFile == "" {
+				return fmt.Errorf("--%s is not set", flagAPITokensFile)
+			}
+
+			name, err := cmd.Flags().GetString(flagAPITokenName)
+			if err != nil {
+				return fmt.Errorf("failed to read token name: %w", err)
+			}
+			if name == "" {
+				return fmt.Errorf("--%s is not set", flagAPITokenName)
+			}
+			roleName, err := cmd.Flags().GetString(flagAPITokenRole)
+			if err != nil {
+				return fmt.Errorf("failed to read token role: %w", err)
+			}
+			role, err := auth.ParseRole(roleName)
+			if err != nil {
+				return err
+			}
+
+			token, entry, err := auth.NewToken(name, role)
+			if err != nil {
+				return err
+			}
+			if err = auth.AppendTokensFile(tokensFile, entry); err != nil {
+				return fmt.Errorf("failed to save token: %w", err)
+			}
+			fmt.Printf("%s token %s is added to %s, restart the node to apply it:\n%s\n", role, name, tokensFile, token)
+			return nil
+		},
+	}
+	cmd.Flags().String(flagAPITokenName, "", "Token name, e.g. the name of its holder")
+	cmd.Flags().String(flagAPITokenRole, auth.RoleReadOnly.String(), "Token role: read-only, operator or admin")
+	return cmd
+}
+
 func startClientCommand() *cobra.Command {
 	return &cobra.Command{
 		Use:   "start",
@@ -162,6 +520,10 @@ func startClientCommand() *cobra.Command {
 				log.Fatalln("failed to prepare config: ", err)
 			}
 
+			if err = readSignerSecrets(cfg); err != nil {
+				log.Fatalln("failed to read signer secrets: ", err)
+			}
+
 			ctx := context.Background()
 			ctx, cancel := context.WithCancel(ctx)
 
@@ -177,33 +539,44 @@ func startClientCommand() *cobra.Command {
 
 			nodeInstance.SetSkipCommKeysVerification(viper.GetBool(flagSkipCommKeysVerification))
 
+			notifier, err := newWebhookNotifier(cfg, sp.GetLogger())
+			if err != nil {
+				log.Fatalf("failed to init webhooks: %v", err)
+			}
+			if notifier != nil {
+				go notifier.Run(ctx, sp.GetEvents())
+			}
+
 			sigs := make(chan os.Signal, 1)
 			signal.Notify(sigs, syscall.SIGINT, syscall.SIGTERM)
 			go func() {
 				<-sigs
 
-				log.Println("Received signal, stopping node...")
+				sp.GetLogger().Infof("Received signal, stopping node...")
 				cancel()
 
-				log.Println("BaseNode stopped, exiting")
+				sp.GetLogger().Infof("BaseNode stopped, exiting")
 				os.Exit(0)
 			}()
 
-			server := http_api.NewRESTApi(cfg, nodeInstance, sp)
+			server, err := http_api.NewRESTApi(cfg, nodeInstance, sp)
+			if err != nil {
+				log.Fatalf("failed to init HTTP API: %v", err)
+			}
 
 			go func() {
 				if err := server.Start(); err != nil {
 					log.Fatalf("HTTP server error: %v", err)
 				}
 			}()
-			nodeInstance.GetLogger().Log("BaseNode started to poll messages from append-only log")
-			nodeInstance.GetLogger().Log("Waiting for messages from append-only log...")
+			nodeInstance.GetLogger().Infof("BaseNode started to poll messages from append-only log")
+			nodeInstance.GetLogger().Infof("Waiting for messages from append-only log...")
 
 			if err = nodeInstance.Poll(); err != nil {
 				return fmt.Errorf("error while handling operations: %w", err)
 			}
 
-			nodeInstance.GetLogger().Log("polling is stopped")
+			nodeInstance.GetLogger().Infof("polling is stopped")
 			return nil
 		},
 	}
@@ -218,6 +591,10 @@ func main() {
 	rootCmd.AddCommand(
 		startClientCommand(),
 		genKeyPairCommand(),
+		migrateKeyStoreCommand(),
+		changeKeyStorePasswordCommand(),
+		serveSignerCommand(),
+		genAPITokenCommand(),
 	)
 	if err := rootCmd.Execute(); err != nil {
 		log.Fatalf("Failed to execute root command: %v", err)
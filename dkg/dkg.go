@@ -25,6 +25,16 @@ type DKG struct {
 	responses *messageStore
 	pubKeys   PKStore
 
+	// the resharing fields: the participants holding the shares to reshare, our index among them
+	// (-1 if we are a new participant), the indices of the dealers, the number of the participants receiving
+	// the new shares and the public coefficients of the key to reshare
+	resharing        bool
+	oldPubKeys       PKStore
+	oldParticipantID int
+	dealers          []int
+	receivers        int
+	publicCoeffs     []kyber.Point
+
 	pubKey        kyber.Point
 	secKey        kyber.Scalar
 	suite         vss.Suite
@@ -104,9 +114,25 @@ func (d *DKG) StorePubKey(participant string, pid int, pk kyber.Point) bool {
 	})
 }
 
+// StoreOldPubKey stores the pub key of a participant holding a share of the key to reshare
+func (d *DKG) StoreOldPubKey(participant string, pid int, pk kyber.Point) bool {
+	d.Lock()
+	defer d.Unlock()
+
+	return d.oldPubKeys.Add(&PK2Participant{
+		Participant:   participant,
+		PK:            pk,
+		ParticipantID: pid,
+	})
+}
+
 func (d *DKG) calcParticipantID() int {
-	for idx, p := range d.pubKeys {
-		if p.PK.Equal(d.pubKey) {
+	return calcIndex(d.pubKeys, d.pubKey)
+}
+
+func calcIndex(pubKeys PKStore, pubKey kyber.Point) int {
+	for idx, p := range pubKeys {
+		if p.PK.Equal(pubKey) {
 			return idx
 		}
 	}
@@ -139,8 +165,127 @@ func (d *DKG) InitDKGInstance(seed []byte) (err error) {
 	return nil
 }
 
+// InitRefreshInstance initializes a resharing of the keyring among the same participants. Every participant deals
+// its current share, so the new shares are the shares of the same distributed key
+func (d *DKG) InitRefreshInstance(seed []byte, keyring *BLSKeyring) (err error) {
+	sort.Sort(d.pubKeys)
+
+	publicKeys := d.pubKeys.GetPKs()
+
+	participantsCount := len(publicKeys)
+
+	participantID := d.calcParticipantID()
+
+	if participantID < 0 {
+		return fmt.Errorf("failed to determine participant index")
+	}
+
+	if keyring.Share.I != participantID {
+		return fmt.Errorf("keyring share index %d does not match participant index %d", keyring.Share.I, participantID)
+	}
+
+	d.ParticipantID = participantID
+
+	d.responses = newMessageStore(int(math.Pow(float64(participantsCount)-1, 2)))
+
+	_, commits := keyring.PubPoly.Info()
+
+	d.instance, err = dkg.NewDistKeyHandler(&dkg.Config{
+		Suite:    d.suite,
+		Longterm: d.secKey,
+		OldNodes: publicKeys,
+		NewNodes: publicKeys,
+		Share: &dkg.DistKeyShare{
+			Commits: commits,
+			Share:   keyring.Share,
+		},
+		Threshold:      d.Threshold,
+		OldThreshold:   len(commits),
+		Reader:         frand.NewCustom(seed, 32, 20),
+		UserReaderOnly: true,
+	})
+	if err != nil {
+		return err
+	}
+	return nil
+}
+
+// InitResharingInstance initializes a resharing of the keyring of the old participants (stored with StoreOldPubKey)
+// among the first receivers participants (stored with StorePubKey). The keyring is our share of the key to reshare,
+// it is nil if we are not one of the dealers. The new shares are the shares of the key with the public coefficients
+func (d *DKG) InitResharingInstance(seed []byte, keyring *BLSKeyring, receivers, oldThreshold int,
+	publicCoeffs []kyber.Point, dealers []int) (err error) {
+	sort.Sort(d.pubKeys)
+	sort.Sort(d.oldPubKeys)
+
+	if receivers <= 0 || receivers > len(d.pubKeys) {
+		return fmt.Errorf("invalid number of receivers %d", receivers)
+	}
+	if len(publicCoeffs) != oldThreshold {
+		return fmt.Errorf("number of public coefficients %d does not match old threshold %d", len(publicCoeffs), oldThreshold)
+	}
+
+	participantID := d.calcParticipantID()
+
+	if participantID < 0 {
+		return fmt.Errorf("failed to determine participant index")
+	}
+
+	oldNodes := d.oldPubKeys.GetPKs()
+	d.oldParticipantID = calcIndex(d.oldPubKeys, d.pubKey)
+
+	var distKeyShare *dkg.DistKeyShare
+	if keyring != nil {
+		if keyring.Share.I != d.oldParticipantID {
+			return fmt.Errorf("keyring share index %d does not match old participant index %d", keyring.Share.I, d.oldParticipantID)
+		}
+		distKeyShare = &dkg.DistKeyShare{
+			Commits: publicCoeffs,
+			Share:   keyring.Share,
+		}
+	}
+
+	d.ParticipantID = participantID
+	d.resharing = true
+	d.dealers = dealers
+	d.receivers = receivers
+	d.publicCoeffs = publicCoeffs
+
+	d.responses = newMessageStore(len(oldNodes))
+
+	d.instance, err = dkg.NewDistKeyHandler(&dkg.Config{
+		Suite:          d.suite,
+		Longterm:       d.secKey,
+		OldNodes:       oldNodes,
+		NewNodes:       d.pubKeys.GetPKs()[:receivers],
+		Share:          distKeyShare,
+		PublicCoeffs:   publicCoeffs,
+		Threshold:      d.Threshold,
+		OldThreshold:   oldThreshold,
+		Reader:         frand.NewCustom(seed, 32, 20),
+		UserReaderOnly: true,
+	})
+	if err != nil {
+		return err
+	}
+	return nil
+}
+
+// GetReceivers returns the number of the participants receiving the new shares, they are the first participants
+func (d *DKG) GetReceivers() int {
+	if !d.resharing {
+		return len(d.pubKeys)
+	}
+	return d.receivers
+}
+
+// GetCommits returns the commits of our deals, nil if we do not deal at the resharing
 func (d *DKG) GetCommits() []kyber.Point {
-	return d.instance.GetDealer().Commits()
+	dealer := d.instance.GetDealer()
+	if dealer == nil {
+		return nil
+	}
+	return dealer.Commits()
 }
 
 func (d *DKG) StoreCommits(participant string, commits []kyber.Point) {
@@ -167,8 +312,12 @@ func (d *DKG) StoreDeal(participant string, deal *dkg.Deal) {
 
 func (d *DKG) ProcessDeals() ([]*dkg.Response, error) {
 	responses := make([]*dkg.Response, 0)
+	dealerID := d.ParticipantID
+	if d.resharing {
+		dealerID = d.oldParticipantID
+	}
 	for _, deal := range d.deals {
-		if deal.Index == uint32(d.ParticipantID) {
+		if deal.Index == uint32(dealerID) {
 			continue
 		}
 		resp, err := d.instance.ProcessDeal(deal)
@@ -217,7 +366,7 @@ func (d *DKG) ProcessResponses() error {
 		}
 	}
 
-	if !d.instance.Certified() {
+	if !d.certified() {
 		return fmt.Errorf("praticipant %v is not certified", d.ParticipantID)
 	}
 
@@ -230,7 +379,11 @@ func (d *DKG) processDealCommits(verifier *vss.Verifier, deal *dkg.Deal) (bool,
 		return false, err
 	}
 
-	participant := d.pubKeys.GetParticipantByIndex(int(deal.Index))
+	dealers := d.pubKeys
+	if d.resharing {
+		dealers = d.oldPubKeys
+	}
+	participant := dealers.GetParticipantByIndex(int(deal.Index))
 
 	commitsData, ok := d.commits[participant]
 
@@ -272,7 +425,7 @@ func (d *DKG) GetDistributedPublicKey() (kyber.Point, error) {
 }
 
 func (d *DKG) GetBLSKeyring() (*BLSKeyring, error) {
-	if d.instance == nil || !d.instance.Certified() {
+	if d.instance == nil || !d.certified() {
 		return nil, fmt.Errorf("dkg instance is not ready")
 	}
 
@@ -283,8 +436,21 @@ func (d *DKG) GetBLSKeyring() (*BLSKeyring, error) {
 
 	masterPubKey := share.NewPubPoly(d.suite, nil, distKeyShare.Commitments())
 
+	if d.resharing && !masterPubKey.Commit().Equal(d.publicCoeffs[0]) {
+		return nil, fmt.Errorf("master key is changed by the resharing")
+	}
+
 	return &BLSKeyring{
 		PubPoly: masterPubKey,
 		Share:   distKeyShare.PriShare(),
 	}, nil
 }
+
+// certified returns true if all deals are certified. Only the dealers of the resharing deal, so all of them
+// and at least the old threshold of them must be certified
+func (d *DKG) certified() bool {
+	if d.resharing {
+		return len(d.instance.QUAL()) == len(d.dealers) && d.instance.ThresholdCertified()
+	}
+	return d.instance.Certified()
+}
@@ -11,6 +11,10 @@ const (
 	SignatureProposalSigningThresholdMinCount = 2
 	ParticipantsMinCount                      = 2
 	SignatureProposalConfirmationDeadline     = time.Hour * 24 * 7
+	DeclineReasonMaxLength                    = 1024
+
+	// StageDeadlineMin is the shortest deadline of a stage which can be set by the start proposal
+	StageDeadlineMin = time.Minute
 
 	// DKG
 	DkgConfirmationDeadline = time.Hour * 24 * 7
@@ -5,7 +5,6 @@ import (
 	"fmt"
 	"reflect"
 
-	"github.com/lidofinance/dc4bc/fsm/config"
 	"github.com/lidofinance/dc4bc/fsm/fsm"
 	"github.com/lidofinance/dc4bc/fsm/state_machines/internal"
 	"github.com/lidofinance/dc4bc/fsm/types/requests"
@@ -37,7 +36,7 @@ func (m *DKGProposalFSM) actionInitDKGProposal(inEvent fsm.Event, args ...interf
 	m.payload.DKGProposalPayload = &internal.DKGConfirmation{
 		Quorum:    make(internal.DKGProposalQuorum),
 		CreatedAt: request.CreatedAt,
-		ExpiresAt: request.CreatedAt.Add(config.DkgConfirmationDeadline),
+		ExpiresAt: request.CreatedAt.Add(m.payload.DKGDeadline()),
 	}
 
 	for participantId, participant := range m.payload.SignatureProposalPayload.Quorum {
@@ -591,3 +590,41 @@ func (m *DKGProposalFSM) actionConfirmationError(inEvent fsm.Event, args ...inte
 
 	return
 }
+
+// actionTimeout records the timeout of the current stage posted after the DKG deadline, the stage is canceled
+// when enough participants posted it, so a single participant can't cancel it
+func (m *DKGProposalFSM) actionTimeout(inEvent fsm.Event, args ...interface{}) (outEvent fsm.Event, response interface{}, err error) {
+	m.payloadMu.Lock()
+	defer m.payloadMu.Unlock()
+
+	if len(args) != 1 {
+		err = errors.New("{arg0} required {TimeoutRequest}")
+		return
+	}
+
+	request, ok := args[0].(requests.TimeoutRequest)
+
+	if !ok {
+		err = errors.New("cannot cast {arg0} to type {TimeoutRequest}")
+		return
+	}
+
+	if err = request.Validate(); err != nil {
+		return
+	}
+
+	if request.CreatedAt.Before(m.payload.DKGProposalPayload.ExpiresAt) {
+		err = fmt.Errorf("cannot cancel by timeout before {ExpiresAt} = {\"%s\"}", m.payload.DKGProposalPayload.ExpiresAt)
+		return
+	}
+
+	votes := m.payload.AddTimeoutVote(inEvent, m.payload.DKGProposalPayload.ExpiresAt, request.Username)
+	if votes < internal.TimeoutQuorum(m.payload.DKGQuorumCount(), m.payload.GetThreshold()) {
+		return
+	}
+
+	m.payload.DKGProposalPayload.UpdatedAt = request.CreatedAt
+	outEvent = timeoutCancelEvents[inEvent]
+
+	return
+}
@@ -74,8 +74,22 @@ const (
 	eventAutoDKGValidateMasterKeyConfirmationInternal    = fsm.Event("event_dkg_master_key_validate_internal")
 
 	EventDKGMasterKeyRequiredInternal = fsm.Event("event_dkg_master_key_required_internal")
+
+	// Posted by nodes when the deadline has passed
+	EventDKGCommitsTimeout   = fsm.Event("event_dkg_commits_timeout")
+	EventDKGDealsTimeout     = fsm.Event("event_dkg_deals_timeout")
+	EventDKGResponsesTimeout = fsm.Event("event_dkg_responses_timeout")
+	EventDKGMasterKeyTimeout = fsm.Event("event_dkg_master_key_timeout")
 )
 
+// timeoutCancelEvents cancel the stage when enough participants posted its timeout
+var timeoutCancelEvents = map[fsm.Event]fsm.Event{
+	EventDKGCommitsTimeout:   eventDKGCommitsConfirmationCancelByTimeoutInternal,
+	EventDKGDealsTimeout:     eventDKGDealsConfirmationCancelByTimeoutInternal,
+	EventDKGResponsesTimeout: eventDKGResponseConfirmationCancelByTimeoutInternal,
+	EventDKGMasterKeyTimeout: eventDKGMasterKeyConfirmationCancelByTimeoutInternal,
+}
+
 type DKGProposalFSM struct {
 	*fsm.FSM
 	payload   *internal.DumpedMachineStatePayload
@@ -107,6 +121,7 @@ func New() internal.DumpedMachineProvider {
 			// Canceled
 			{Name: EventDKGCommitConfirmationError, SrcState: []fsm.State{StateDkgCommitsAwaitConfirmations, StateDkgCommitsAwaitCanceledByError}, DstState: StateDkgCommitsAwaitCanceledByError},
 			{Name: eventDKGCommitsConfirmationCancelByTimeoutInternal, SrcState: []fsm.State{StateDkgCommitsAwaitConfirmations}, DstState: StateDkgCommitsAwaitCanceledByTimeout, IsInternal: true},
+			{Name: EventDKGCommitsTimeout, SrcState: []fsm.State{StateDkgCommitsAwaitConfirmations}, DstState: StateDkgCommitsAwaitConfirmations},
 
 			{Name: eventAutoDKGValidateConfirmationCommitsInternal, SrcState: []fsm.State{StateDkgCommitsAwaitConfirmations}, DstState: StateDkgCommitsAwaitConfirmations, IsInternal: true, IsAuto: true},
 
@@ -118,6 +133,7 @@ func New() internal.DumpedMachineProvider {
 			// Canceled
 			{Name: EventDKGDealConfirmationError, SrcState: []fsm.State{StateDkgDealsAwaitConfirmations, StateDkgDealsAwaitCanceledByError}, DstState: StateDkgDealsAwaitCanceledByError},
 			{Name: eventDKGDealsConfirmationCancelByTimeoutInternal, SrcState: []fsm.State{StateDkgDealsAwaitConfirmations}, DstState: StateDkgDealsAwaitCanceledByTimeout, IsInternal: true},
+			{Name: EventDKGDealsTimeout, SrcState: []fsm.State{StateDkgDealsAwaitConfirmations}, DstState: StateDkgDealsAwaitConfirmations},
 			{Name: eventAutoDKGValidateConfirmationDealsInternal, SrcState: []fsm.State{StateDkgDealsAwaitConfirmations}, DstState: StateDkgDealsAwaitConfirmations, IsInternal: true, IsAuto: true},
 
 			{Name: eventDKGDealsConfirmedInternal, SrcState: []fsm.State{StateDkgDealsAwaitConfirmations}, DstState: StateDkgResponsesAwaitConfirmations, IsInternal: true},
@@ -127,6 +143,7 @@ func New() internal.DumpedMachineProvider {
 			// Canceled
 			{Name: EventDKGResponseConfirmationError, SrcState: []fsm.State{StateDkgResponsesAwaitConfirmations, StateDkgResponsesAwaitCanceledByError}, DstState: StateDkgResponsesAwaitCanceledByError},
 			{Name: eventDKGResponseConfirmationCancelByTimeoutInternal, SrcState: []fsm.State{StateDkgResponsesAwaitConfirmations}, DstState: StateDkgResponsesAwaitCanceledByTimeout, IsInternal: true},
+			{Name: EventDKGResponsesTimeout, SrcState: []fsm.State{StateDkgResponsesAwaitConfirmations}, DstState: StateDkgResponsesAwaitConfirmations},
 
 			{Name: eventAutoDKGValidateResponsesConfirmationInternal, SrcState: []fsm.State{StateDkgResponsesAwaitConfirmations}, DstState: StateDkgResponsesAwaitConfirmations, IsInternal: true, IsAuto: true},
 
@@ -138,6 +155,7 @@ func New() internal.DumpedMachineProvider {
 			{Name: EventDKGMasterKeyConfirmationError, SrcState: []fsm.State{StateDkgMasterKeyAwaitConfirmations, StateDkgMasterKeyAwaitCanceledByError}, DstState: StateDkgMasterKeyAwaitCanceledByError},
 			{Name: eventDKGMasterKeyConfirmationCancelByErrorInternal, SrcState: []fsm.State{StateDkgMasterKeyAwaitConfirmations}, DstState: StateDkgMasterKeyAwaitCanceledByError, IsInternal: true},
 			{Name: eventDKGMasterKeyConfirmationCancelByTimeoutInternal, SrcState: []fsm.State{StateDkgMasterKeyAwaitConfirmations}, DstState: StateDkgMasterKeyAwaitCanceledByTimeout, IsInternal: true},
+			{Name: EventDKGMasterKeyTimeout, SrcState: []fsm.State{StateDkgMasterKeyAwaitConfirmations}, DstState: StateDkgMasterKeyAwaitConfirmations},
 
 			{Name: eventAutoDKGValidateMasterKeyConfirmationInternal, SrcState: []fsm.State{StateDkgMasterKeyAwaitConfirmations}, DstState: StateDkgMasterKeyAwaitConfirmations, IsInternal: true, IsAuto: true},
 
@@ -162,6 +180,11 @@ func New() internal.DumpedMachineProvider {
 			EventDKGMasterKeyConfirmationReceived:             machine.actionMasterKeyConfirmationReceived,
 			EventDKGMasterKeyConfirmationError:                machine.actionConfirmationError,
 			eventAutoDKGValidateMasterKeyConfirmationInternal: machine.actionValidateDkgProposalAwaitMasterKey,
+
+			EventDKGCommitsTimeout:   machine.actionTimeout,
+			EventDKGDealsTimeout:     machine.actionTimeout,
+			EventDKGResponsesTimeout: machine.actionTimeout,
+			EventDKGMasterKeyTimeout: machine.actionTimeout,
 		},
 	)
 	return machine
@@ -3,9 +3,13 @@ package internal
 import (
 	"crypto/ed25519"
 	"errors"
+	"fmt"
+	"time"
 
+	"github.com/lidofinance/dc4bc/fsm/config"
 	"github.com/lidofinance/dc4bc/fsm/fsm"
 	"github.com/lidofinance/dc4bc/fsm/fsm_pool"
+	"github.com/lidofinance/dc4bc/fsm/types"
 )
 
 type DumpedMachineProvider interface {
@@ -21,8 +25,68 @@ type DumpedMachineStatePayload struct {
 	SignatureProposalPayload *SignatureConfirmation
 	DKGProposalPayload       *DKGConfirmation
 	SigningProposalPayload   *SigningConfirmation
-	PubKeys                  map[string]ed25519.PublicKey
-	IDs                      map[string]int
+	ShareRefreshPayload      *ShareRefreshConfirmation `json:",omitempty"`
+	// ResharingPayload is set for rounds started by a resharing of another round, it's kept for the roles of participants
+	ResharingPayload *ResharingConfirmation `json:",omitempty"`
+	PubKeys          map[string]ed25519.PublicKey
+	IDs              map[string]int
+	// Deadlines are set by the start proposal, nil for rounds started without them
+	Deadlines *types.StageDeadlines `json:",omitempty"`
+	// TimeoutVotes are the participants who posted the timeout of a stage, by the stage and its deadline
+	TimeoutVotes map[string][]string `json:",omitempty"`
+}
+
+// Deadlines
+
+func (p *DumpedMachineStatePayload) SignatureProposalDeadline() time.Duration {
+	if p.Deadlines != nil && p.Deadlines.SignatureProposal != 0 {
+		return time.Duration(p.Deadlines.SignatureProposal)
+	}
+	return config.SignatureProposalConfirmationDeadline
+}
+
+func (p *DumpedMachineStatePayload) DKGDeadline() time.Duration {
+	if p.Deadlines != nil && p.Deadlines.DKG != 0 {
+		return time.Duration(p.Deadlines.DKG)
+	}
+	return config.DkgConfirmationDeadline
+}
+
+func (p *DumpedMachineStatePayload) SigningDeadline() time.Duration {
+	if p.Deadlines != nil && p.Deadlines.Signing != 0 {
+		return time.Duration(p.Deadlines.Signing)
+	}
+	return config.SigningConfirmationDeadline
+}
+
+// Timeouts
+
+// AddTimeoutVote records the timeout of the stage with the given deadline posted by the participant and returns
+// the number of participants who posted it
+func (p *DumpedMachineStatePayload) AddTimeoutVote(event fsm.Event, expiresAt time.Time, username string) int {
+	if p.TimeoutVotes == nil {
+		p.TimeoutVotes = make(map[string][]string)
+	}
+
+	key := fmt.Sprintf("%s_%d", event, expiresAt.UnixNano())
+	for _, voted := range p.TimeoutVotes[key] {
+		if voted == username {
+			return len(p.TimeoutVotes[key])
+		}
+	}
+	p.TimeoutVotes[key] = append(p.TimeoutVotes[key], username)
+
+	return len(p.TimeoutVotes[key])
+}
+
+// TimeoutQuorum is the number of participants who must post the timeout of a stage to cancel it. Any n-t+1
+// participants include an honest one, as long as less than t participants are malicious
+func TimeoutQuorum(participants, threshold int) int {
+	if threshold < 1 || threshold > participants {
+		// the threshold is not valid, every participant is required
+		return participants
+	}
+	return participants - threshold + 1
 }
 
 // Signature quorum
@@ -125,6 +189,68 @@ func (p *DumpedMachineStatePayload) SigningQuorumUpdate(id int, participant *Sig
 	}
 }
 
+// Share refresh quorum
+
+func (p *DumpedMachineStatePayload) ShareRefreshQuorumCount() int {
+	var count int
+	if p.ShareRefreshPayload.Quorum != nil {
+		count = len(p.ShareRefreshPayload.Quorum)
+	}
+	return count
+}
+
+func (p *DumpedMachineStatePayload) ShareRefreshQuorumExists(id int) bool {
+	var exists bool
+	if p.ShareRefreshPayload.Quorum != nil {
+		_, exists = p.ShareRefreshPayload.Quorum[id]
+	}
+	return exists
+}
+
+func (p *DumpedMachineStatePayload) ShareRefreshQuorumGet(id int) (participant *DKGProposalParticipant) {
+	if p.ShareRefreshPayload.Quorum != nil {
+		participant = p.ShareRefreshPayload.Quorum[id]
+	}
+	return participant
+}
+
+func (p *DumpedMachineStatePayload) ShareRefreshQuorumUpdate(id int, participant *DKGProposalParticipant) {
+	if p.ShareRefreshPayload.Quorum != nil {
+		p.ShareRefreshPayload.Quorum[id] = participant
+	}
+}
+
+// Resharing quorum
+
+func (p *DumpedMachineStatePayload) ResharingQuorumCount() int {
+	var count int
+	if p.ResharingPayload.Quorum != nil {
+		count = len(p.ResharingPayload.Quorum)
+	}
+	return count
+}
+
+func (p *DumpedMachineStatePayload) ResharingQuorumExists(id int) bool {
+	var exists bool
+	if p.ResharingPayload.Quorum != nil {
+		_, exists = p.ResharingPayload.Quorum[id]
+	}
+	return exists
+}
+
+func (p *DumpedMachineStatePayload) ResharingQuorumGet(id int) (participant *DKGProposalParticipant) {
+	if p.ResharingPayload.Quorum != nil {
+		participant = p.ResharingPayload.Quorum[id]
+	}
+	return participant
+}
+
+func (p *DumpedMachineStatePayload) ResharingQuorumUpdate(id int, participant *DKGProposalParticipant) {
+	if p.ResharingPayload.Quorum != nil {
+		p.ResharingPayload.Quorum[id] = participant
+	}
+}
+
 func (p *DumpedMachineStatePayload) SetPubKeyUsername(username string, pubKey ed25519.PublicKey) {
 	if p.PubKeys == nil {
 		p.PubKeys = make(map[string]ed25519.PublicKey)
@@ -51,8 +51,10 @@ type SignatureProposalParticipant struct {
 	// For validation user confirmation: sign(InvitationSecret, PubKey) => user
 	InvitationSecret string
 	Status           ConfirmationParticipantStatus
-	Threshold        int
-	UpdatedAt        time.Time
+	// DeclineReason is given by the participant when declining the proposal
+	DeclineReason string `json:",omitempty"`
+	Threshold     int
+	UpdatedAt     time.Time
 }
 
 func (sigP SignatureProposalParticipant) GetStatus() ParticipantStatus {
@@ -155,6 +157,8 @@ type DKGConfirmation struct {
 	UpdatedAt time.Time
 	ExpiresAt time.Time
 	PubPolyBz []byte
+	// KeyringVersion is the number of finished share refreshes, PubPolyBz is the public polynomial of the version
+	KeyringVersion int `json:",omitempty"`
 }
 
 func (c *DKGConfirmation) IsExpired() bool {
@@ -194,6 +198,88 @@ func (s DKGParticipantStatus) String() string {
 	return str
 }
 
+// Share refresh
+
+// ShareRefreshConfirmation is a refresh of the key shares of a finished DKG round. Participants run
+// a resharing among themselves, which gives fresh shares of the same master key, so the statuses of DKG
+// participants are used for its stages
+type ShareRefreshConfirmation struct {
+	// KeyringVersion is the version of the keyring produced by the refresh
+	KeyringVersion int
+	InitiatorId    int
+	Quorum         DKGProposalQuorum
+	PubPolyBz      []byte
+	CreatedAt      time.Time
+	UpdatedAt      time.Time
+	ExpiresAt      time.Time
+}
+
+func (c *ShareRefreshConfirmation) IsExpired() bool {
+	return c.ExpiresAt.Before(c.UpdatedAt)
+}
+
+// Resharing
+
+// ResharingConfirmation is a handoff of the master key of a finished DKG round to a new committee with another
+// threshold. The dealers, a quorum of the old participants, deal their key shares to the new committee members,
+// so the resharing round gets the same master key. The new committee members are the first participants of the round,
+// the dealers who are not its members follow them and leave the round after dealing
+type ResharingConfirmation struct {
+	OldDkgID          string
+	OldThreshold      int
+	OldKeyringVersion int
+	OldPubPolyBz      []byte
+	OldMasterKey      []byte
+	// OldParticipants are the participants of the old round by their ids in it
+	OldParticipants map[int]*ResharingOldParticipant
+	// Dealers maps the ids of the dealers in the round to their ids in the old round
+	Dealers map[int]int
+	// Receivers is the number of the new committee members
+	Receivers int
+	Threshold int
+	// Proposal is the quorum approving the resharing, Quorum is the quorum of its stages
+	Proposal  SignatureProposalQuorum
+	Quorum    DKGProposalQuorum
+	PubPolyBz []byte
+	CreatedAt time.Time
+	UpdatedAt time.Time
+	ExpiresAt time.Time
+}
+
+type ResharingOldParticipant struct {
+	Username  string
+	DkgPubKey []byte
+}
+
+func (c *ResharingConfirmation) IsExpired() bool {
+	return c.ExpiresAt.Before(c.UpdatedAt)
+}
+
+// IsDealer reports whether the participant deals its key share of the old round
+func (c *ResharingConfirmation) IsDealer(username string) bool {
+	participantID, ok := c.participantID(username)
+	if !ok {
+		return false
+	}
+	_, ok = c.Dealers[participantID]
+	return ok
+}
+
+// IsReceiver reports whether the participant is a member of the new committee
+func (c *ResharingConfirmation) IsReceiver(username string) bool {
+	participantID, ok := c.participantID(username)
+	return ok && participantID < c.Receivers
+}
+
+func (c *ResharingConfirmation) participantID(username string) (int, bool) {
+	for participantID, participant := range c.Proposal {
+		if participant.Username == username {
+			return participantID, true
+		}
+	}
+	return -1, false
+}
+
 // Signing proposal
 
 type SigningConfirmation struct {
@@ -6,6 +6,7 @@ import (
 	"errors"
 	"fmt"
 	"strings"
+	"time"
 
 	"github.com/lidofinance/dc4bc/fsm/state_machines/signing_proposal_fsm"
 
@@ -14,6 +15,8 @@ import (
 	"github.com/lidofinance/dc4bc/fsm/fsm"
 	"github.com/lidofinance/dc4bc/fsm/fsm_pool"
 	"github.com/lidofinance/dc4bc/fsm/state_machines/internal"
+	"github.com/lidofinance/dc4bc/fsm/state_machines/resharing_fsm"
+	"github.com/lidofinance/dc4bc/fsm/state_machines/share_refresh_fsm"
 	"github.com/lidofinance/dc4bc/fsm/state_machines/signature_proposal_fsm"
 )
 
@@ -51,6 +54,8 @@ func Create(dkgID string) (*FSMInstance, error) {
 		signature_proposal_fsm.New(),
 		dkg_proposal_fsm.New(),
 		signing_proposal_fsm.New(),
+		share_refresh_fsm.New(),
+		resharing_fsm.New(),
 	)
 
 	machine, err := fsmPoolProvider.EntryPointMachine()
@@ -71,6 +76,8 @@ func FromDump(data []byte) (*FSMInstance, error) {
 		signature_proposal_fsm.New(),
 		dkg_proposal_fsm.New(),
 		signing_proposal_fsm.New(),
+		share_refresh_fsm.New(),
+		resharing_fsm.New(),
 	)
 
 	i := &FSMInstance{
@@ -179,6 +186,44 @@ func (i *FSMInstance) FSMDump() *FSMDump {
 	return i.dump
 }
 
+// StageExpiresAt returns the deadline of the stage awaiting participants, false if the round doesn't await them
+func (d *FSMDump) StageExpiresAt() (time.Time, bool) {
+	switch d.State {
+	case signature_proposal_fsm.StateAwaitParticipantsConfirmations:
+		return d.Payload.SignatureProposalPayload.ExpiresAt, true
+	case dkg_proposal_fsm.StateDkgCommitsAwaitConfirmations, dkg_proposal_fsm.StateDkgDealsAwaitConfirmations,
+		dkg_proposal_fsm.StateDkgResponsesAwaitConfirmations, dkg_proposal_fsm.StateDkgMasterKeyAwaitConfirmations:
+		return d.Payload.DKGProposalPayload.ExpiresAt, true
+	case signing_proposal_fsm.StateSigningAwaitPartialSigns:
+		return d.Payload.SigningProposalPayload.ExpiresAt, true
+	case share_refresh_fsm.StateShareRefreshCommitsAwaitConfirmations, share_refresh_fsm.StateShareRefreshDealsAwaitConfirmations,
+		share_refresh_fsm.StateShareRefreshResponsesAwaitConfirmations, share_refresh_fsm.StateShareRefreshKeyringAwaitConfirmations:
+		return d.Payload.ShareRefreshPayload.ExpiresAt, true
+	case resharing_fsm.StateResharingAwaitParticipantsConfirmations, resharing_fsm.StateResharingCommitsAwaitConfirmations,
+		resharing_fsm.StateResharingDealsAwaitConfirmations, resharing_fsm.StateResharingResponsesAwaitConfirmations,
+		resharing_fsm.StateResharingKeyringAwaitConfirmations:
+		return d.Payload.ResharingPayload.ExpiresAt, true
+	}
+	return time.Time{}, false
+}
+
+// IsStageParticipant returns true if the participant has to send a message at the current stage of the round.
+// Only the resharing has stages where a part of the participants has nothing to send: the deals are sent by
+// the dealers only and the new committee is the only one who processes them and uses the new keyring later
+func (d *FSMDump) IsStageParticipant(username string) bool {
+	if d.Payload.ResharingPayload == nil {
+		return true
+	}
+	switch d.State {
+	case resharing_fsm.StateResharingAwaitParticipantsConfirmations, resharing_fsm.StateResharingCommitsAwaitConfirmations:
+		return true
+	case resharing_fsm.StateResharingDealsAwaitConfirmations:
+		return d.Payload.ResharingPayload.IsDealer(username)
+	default:
+		return d.Payload.ResharingPayload.IsReceiver(username)
+	}
+}
+
 // TODO: Add encryption
 func (d *FSMDump) Marshal() ([]byte, error) {
 	return json.Marshal(d)
@@ -12,11 +12,16 @@ import (
 
 	"github.com/stretchr/testify/require"
 
+	rf "github.com/lidofinance/dc4bc/fsm/state_machines/resharing_fsm"
+	srf "github.com/lidofinance/dc4bc/fsm/state_machines/share_refresh_fsm"
 	sif "github.com/lidofinance/dc4bc/fsm/state_machines/signing_proposal_fsm"
 
+	"github.com/lidofinance/dc4bc/fsm/config"
 	"github.com/lidofinance/dc4bc/fsm/fsm"
 	dpf "github.com/lidofinance/dc4bc/fsm/state_machines/dkg_proposal_fsm"
+	"github.com/lidofinance/dc4bc/fsm/state_machines/internal"
 	spf "github.com/lidofinance/dc4bc/fsm/state_machines/signature_proposal_fsm"
+	"github.com/lidofinance/dc4bc/fsm/types"
 	"github.com/lidofinance/dc4bc/fsm/types/requests"
 	"github.com/lidofinance/dc4bc/fsm/types/responses"
 )
@@ -152,6 +157,34 @@ func compareState(t *testing.T, expected fsm.State, got fsm.State) {
 }
 
 // Test Workflow
+// postTimeouts posts the timeout by the participants one by one, only the last of n-t+1 timeouts cancels the stage
+func postTimeouts(t *testing.T, testFSMInstance *FSMInstance, event fsm.Event, createdAt time.Time) *fsm.Response {
+	inState, err := testFSMInstance.State()
+	require.NoError(t, err)
+
+	var (
+		fsmResponse *fsm.Response
+		votes       int
+		quorum      = internal.TimeoutQuorum(participantsNumber, threshold)
+	)
+	for username := range testUsernameMapParticipants {
+		request := requests.TimeoutRequest{Username: username, CreatedAt: createdAt}
+		fsmResponse, _, err = testFSMInstance.Do(event, request)
+		require.NoError(t, err)
+		if votes++; votes == quorum {
+			break
+		}
+		compareState(t, inState, fsmResponse.State)
+
+		// a repeated timeout of the participant is not counted
+		fsmResponse, _, err = testFSMInstance.Do(event, request)
+		require.NoError(t, err)
+		compareState(t, inState, fsmResponse.State)
+	}
+
+	return fsmResponse
+}
+
 func Test_SignatureProposal_Init(t *testing.T) {
 	testFSMInstance, err := Create(dkgId)
 
@@ -303,6 +336,7 @@ func Test_SignatureProposal_EventConfirmSignatureProposal_Canceled_Participant(t
 	fsmResponse, testFSMDumpLocal, err := testFSMInstance.Do(spf.EventDeclineProposal, requests.SignatureProposalParticipantRequest{
 		ParticipantId: 0,
 		CreatedAt:     time.Now(),
+		Reason:        "unknown participant in the quorum",
 	})
 	require.NoError(t, err)
 
@@ -313,6 +347,13 @@ func Test_SignatureProposal_EventConfirmSignatureProposal_Canceled_Participant(t
 	compareFSMResponseNotNil(t, fsmResponse)
 
 	compareState(t, spf.StateValidationCanceledByParticipant, fsmResponse.State)
+
+	// the reason is recorded in the dump
+	dump := &FSMDump{}
+	require.NoError(t, dump.Unmarshal(testFSMDumpLocal))
+	participant := dump.Payload.SignatureProposalPayload.Quorum[0]
+	require.Equal(t, internal.SigConfirmationDeclined, participant.Status)
+	require.Equal(t, "unknown participant in the quorum", participant.DeclineReason)
 }
 
 func Test_SignatureProposal_EventConfirmSignatureProposal_Canceled_Timeout(t *testing.T) {
@@ -339,6 +380,51 @@ func Test_SignatureProposal_EventConfirmSignatureProposal_Canceled_Timeout(t *te
 	compareState(t, spf.StateValidationCanceledByTimeout, fsmResponse.State)
 }
 
+func Test_SignatureProposal_EventSignatureProposalTimeout(t *testing.T) {
+	testFSMInstance, err := FromDump(testFSMDump[spf.StateAwaitParticipantsConfirmations])
+	require.NoError(t, err)
+
+	// the deadline has not passed yet
+	_, _, err = testFSMInstance.Do(spf.EventSignatureProposalTimeout, requests.TimeoutRequest{
+		Username:  testParticipantsListRequest.Participants[0].Username,
+		CreatedAt: tm.Add(time.Hour),
+	})
+	require.Error(t, err)
+
+	testFSMInstance, err = FromDump(testFSMDump[spf.StateAwaitParticipantsConfirmations])
+	require.NoError(t, err)
+	fsmResponse := postTimeouts(t, testFSMInstance, spf.EventSignatureProposalTimeout,
+		tm.Add(config.SignatureProposalConfirmationDeadline))
+	compareState(t, spf.StateValidationCanceledByTimeout, fsmResponse.State)
+}
+
+func Test_SignatureProposal_Deadlines(t *testing.T) {
+	testFSMInstance, err := FromDump(testFSMDump[spf.StateParticipantsConfirmationsInit])
+	require.NoError(t, err)
+
+	request := testParticipantsListRequest
+	request.Deadlines = &types.StageDeadlines{SignatureProposal: types.Duration(time.Second)}
+	_, _, err = testFSMInstance.Do(spf.EventInitProposal, request)
+	require.Error(t, err)
+
+	// deadlines are durations in a start proposal file
+	require.NoError(t, json.Unmarshal([]byte(`{"SignatureProposal": "1h"}`), &request.Deadlines))
+	fsmResponse, dump, err := testFSMInstance.Do(spf.EventInitProposal, request)
+	require.NoError(t, err)
+	invitations, ok := fsmResponse.Data.(responses.SignatureProposalParticipantInvitationsResponse)
+	require.True(t, ok)
+	require.Equal(t, time.Hour, time.Duration(invitations[0].Deadlines.SignatureProposal))
+
+	// the deadlines survive the dump
+	testFSMInstance, err = FromDump(dump)
+	require.NoError(t, err)
+	expiresAt, ok := testFSMInstance.FSMDump().StageExpiresAt()
+	require.True(t, ok)
+	require.True(t, expiresAt.Equal(tm.Add(time.Hour)))
+	fsmResponse = postTimeouts(t, testFSMInstance, spf.EventSignatureProposalTimeout, tm.Add(time.Hour))
+	compareState(t, spf.StateValidationCanceledByTimeout, fsmResponse.State)
+}
+
 func Test_DkgProposal_EventDKGInitProcess_Positive(t *testing.T) {
 	var fsmResponse *fsm.Response
 
@@ -508,6 +594,31 @@ func Test_DkgProposal_EventDKGCommitConfirmationReceived_Canceled_Timeout(t *tes
 
 }
 
+func Test_DkgProposal_EventDKGCommitsTimeout(t *testing.T) {
+	testFSMInstance, err := FromDump(testFSMDump[dpf.StateDkgCommitsAwaitConfirmations])
+	require.NoError(t, err)
+
+	_, _, err = testFSMInstance.Do(dpf.EventDKGCommitsTimeout, requests.TimeoutRequest{
+		Username:  testParticipantsListRequest.Participants[0].Username,
+		CreatedAt: time.Now(),
+	})
+	require.Error(t, err)
+
+	testFSMInstance, err = FromDump(testFSMDump[dpf.StateDkgCommitsAwaitConfirmations])
+	require.NoError(t, err)
+	fsmResponse := postTimeouts(t, testFSMInstance, dpf.EventDKGCommitsTimeout, time.Now().Add(config.DkgConfirmationDeadline))
+	compareState(t, dpf.StateDkgCommitsAwaitCanceledByTimeout, fsmResponse.State)
+
+	// a timeout of another stage
+	testFSMInstance, err = FromDump(testFSMDump[dpf.StateDkgCommitsAwaitConfirmations])
+	require.NoError(t, err)
+	_, _, err = testFSMInstance.Do(dpf.EventDKGDealsTimeout, requests.TimeoutRequest{
+		Username:  testParticipantsListRequest.Participants[0].Username,
+		CreatedAt: time.Now().Add(config.DkgConfirmationDeadline),
+	})
+	require.Error(t, err)
+}
+
 // Deals
 func Test_DkgProposal_EventDKGDealConfirmationReceived(t *testing.T) {
 	var (
@@ -1023,6 +1134,23 @@ func Test_SigningProposal_EventSigningStart_BakedData(t *testing.T) {
 	compareDumpNotZero(t, testFSMDump[sif.StateSigningAwaitPartialSigns])
 }
 
+func Test_SigningProposal_EventSigningPartialSignsTimeout(t *testing.T) {
+	testFSMInstance, err := FromDump(testFSMDump[sif.StateSigningAwaitPartialSigns])
+	require.NoError(t, err)
+
+	_, _, err = testFSMInstance.Do(sif.EventSigningPartialSignsTimeout, requests.TimeoutRequest{
+		Username:  testParticipantsListRequest.Participants[0].Username,
+		CreatedAt: time.Now(),
+	})
+	require.Error(t, err)
+
+	testFSMInstance, err = FromDump(testFSMDump[sif.StateSigningAwaitPartialSigns])
+	require.NoError(t, err)
+	fsmResponse := postTimeouts(t, testFSMInstance, sif.EventSigningPartialSignsTimeout,
+		time.Now().Add(config.SigningConfirmationDeadline))
+	compareState(t, sif.StateSigningPartialSignsAwaitCancelledByTimeout, fsmResponse.State)
+}
+
 func Test_SigningProposal_EventPartialKeysReceived_Failed_Participants(t *testing.T) {
 	var (
 		fsmResponse      *fsm.Response
@@ -1079,6 +1207,312 @@ func Test_SigningProposal_EventPartialKeysReceived_Failed_Participants(t *testin
 	compareState(t, sif.StateSigningPartialSignsAwaitCancelledByError, fsmResponse.State)
 }
 
+// Share refresh
+func Test_ShareRefresh_Positive(t *testing.T) {
+	testFSMInstance, err := FromDump(testFSMDump[sif.StateSigningIdle])
+	require.NoError(t, err)
+
+	masterKey := testFSMInstance.FSMDump().Payload.DKGProposalPayload.Quorum[0].DkgMasterKey
+	pubPoly := genDataMock(keysMockLen)
+
+	fsmResponse, dump, err := testFSMInstance.Do(sif.EventShareRefreshPropose, requests.ShareRefreshProposalRequest{
+		ParticipantId: 0,
+		CreatedAt:     time.Now(),
+	})
+	require.NoError(t, err)
+	compareState(t, sif.StateShareRefreshProposed, fsmResponse.State)
+
+	testFSMInstance, err = FromDump(dump)
+	require.NoError(t, err)
+	fsmResponse, dump, err = testFSMInstance.Do(srf.EventShareRefreshInit, requests.DefaultRequest{
+		CreatedAt: time.Now(),
+	})
+	require.NoError(t, err)
+	compareState(t, srf.StateShareRefreshCommitsAwaitConfirmations, fsmResponse.State)
+
+	invitation, ok := fsmResponse.Data.(responses.ShareRefreshInvitationResponse)
+	require.True(t, ok)
+	require.Equal(t, 0, invitation.KeyringVersion)
+	require.Len(t, invitation.Participants, participantsNumber)
+	require.Equal(t, threshold, invitation.Participants[0].Threshold)
+
+	stages := []struct {
+		event   fsm.Event
+		request func(participantId int) interface{}
+		state   fsm.State
+	}{
+		{srf.EventShareRefreshCommitConfirmationReceived, func(participantId int) interface{} {
+			return requests.DKGProposalCommitConfirmationRequest{ParticipantId: participantId, Commit: genDataMock(keysMockLen), CreatedAt: time.Now()}
+		}, srf.StateShareRefreshDealsAwaitConfirmations},
+		{srf.EventShareRefreshDealConfirmationReceived, func(participantId int) interface{} {
+			return requests.DKGProposalDealConfirmationRequest{ParticipantId: participantId, Deal: genDataMock(keysMockLen), CreatedAt: time.Now()}
+		}, srf.StateShareRefreshResponsesAwaitConfirmations},
+		{srf.EventShareRefreshResponseConfirmationReceived, func(participantId int) interface{} {
+			return requests.DKGProposalResponseConfirmationRequest{ParticipantId: participantId, Response: genDataMock(keysMockLen), CreatedAt: time.Now()}
+		}, srf.StateShareRefreshKeyringAwaitConfirmations},
+		{srf.EventShareRefreshKeyringConfirmationReceived, func(participantId int) interface{} {
+			return requests.DKGProposalMasterKeyConfirmationRequest{ParticipantId: participantId, MasterKey: masterKey, PubPolyBz: pubPoly, CreatedAt: time.Now()}
+		}, srf.StateShareRefreshKeyringCollected},
+	}
+	for _, stage := range stages {
+		for participantId := range testIdMapParticipants {
+			testFSMInstance, err = FromDump(dump)
+			require.NoError(t, err)
+			fsmResponse, dump, err = testFSMInstance.Do(stage.event, stage.request(participantId))
+			require.NoError(t, err)
+		}
+		compareState(t, stage.state, fsmResponse.State)
+	}
+
+	testFSMInstance, err = FromDump(dump)
+	require.NoError(t, err)
+	fsmResponse, dump, err = testFSMInstance.Do(srf.EventShareRefreshFinish, requests.DefaultRequest{
+		CreatedAt: time.Now(),
+	})
+	require.NoError(t, err)
+	compareState(t, sif.StateSigningIdle, fsmResponse.State)
+
+	testFSMInstance, err = FromDump(dump)
+	require.NoError(t, err)
+	payload := testFSMInstance.FSMDump().Payload
+	require.Equal(t, 1, payload.DKGProposalPayload.KeyringVersion)
+	require.Equal(t, pubPoly, payload.DKGProposalPayload.PubPolyBz)
+
+	// signing uses the refreshed keyring
+	fsmResponse, _, err = testFSMInstance.Do(sif.EventSigningStart, requests.SigningBatchProposalStartRequest{
+		BatchID:       "refreshed-batch",
+		ParticipantId: 0,
+		SigningTasks:  []requests.SigningTask{{MessageID: "test-signing-id", Payload: []byte("message to sign")}},
+		CreatedAt:     time.Now(),
+	})
+	require.NoError(t, err)
+	invitations, ok := fsmResponse.Data.(responses.SigningPartialSignsParticipantInvitationsResponse)
+	require.True(t, ok)
+	require.Equal(t, 1, invitations.KeyringVersion)
+}
+
+func Test_ShareRefresh_Canceled_Error(t *testing.T) {
+	testFSMInstance, err := FromDump(testFSMDump[sif.StateSigningIdle])
+	require.NoError(t, err)
+
+	_, dump, err := testFSMInstance.Do(sif.EventShareRefreshPropose, requests.ShareRefreshProposalRequest{
+		ParticipantId: 0,
+		CreatedAt:     time.Now(),
+	})
+	require.NoError(t, err)
+
+	testFSMInstance, err = FromDump(dump)
+	require.NoError(t, err)
+	_, dump, err = testFSMInstance.Do(srf.EventShareRefreshInit, requests.DefaultRequest{
+		CreatedAt: time.Now(),
+	})
+	require.NoError(t, err)
+
+	// the refresh is canceled and the round returns to signing with the old keyring
+	testFSMInstance, err = FromDump(dump)
+	require.NoError(t, err)
+	fsmResponse, dump, err := testFSMInstance.Do(srf.EventShareRefreshCommitConfirmationError, requests.DKGProposalConfirmationErrorRequest{
+		ParticipantId: 0,
+		Error:         requests.NewFSMError(errors.New("test error")),
+		CreatedAt:     time.Now(),
+	})
+	require.NoError(t, err)
+	compareState(t, srf.StateShareRefreshCommitsAwaitCanceledByError, fsmResponse.State)
+
+	testFSMInstance, err = FromDump(dump)
+	require.NoError(t, err)
+	fsmResponse, dump, err = testFSMInstance.Do(srf.EventShareRefreshFinish, requests.DefaultRequest{
+		CreatedAt: time.Now(),
+	})
+	require.NoError(t, err)
+	compareState(t, sif.StateSigningIdle, fsmResponse.State)
+
+	testFSMInstance, err = FromDump(dump)
+	require.NoError(t, err)
+	require.Equal(t, 0, testFSMInstance.FSMDump().Payload.DKGProposalPayload.KeyringVersion)
+}
+
+// testResharingProposalRequest returns a resharing of the finished round to the old participants #0-#5 and
+// two new participants, the old participants #0, #7 and #8 deal their shares
+func testResharingProposalRequest(t *testing.T) requests.ResharingProposalRequest {
+	oldFSMInstance, err := FromDump(testFSMDump[sif.StateSigningIdle])
+	require.NoError(t, err)
+	oldPayload := oldFSMInstance.FSMDump().Payload
+
+	request := requests.ResharingProposalRequest{
+		OldDkgID:         dkgId,
+		OldThreshold:     threshold,
+		OldPubPolyBz:     genDataMock(keysMockLen),
+		OldMasterKey:     oldPayload.DKGProposalPayload.Quorum[0].DkgMasterKey,
+		SigningThreshold: threshold + 1,
+		CreatedAt:        time.Now(),
+	}
+	for participantId := 0; participantId < participantsNumber; participantId++ {
+		participant := oldPayload.SignatureProposalPayload.Quorum[participantId]
+		request.OldParticipants = append(request.OldParticipants, &requests.ResharingOldParticipantEntry{
+			ParticipantId: participantId,
+			Username:      participant.Username,
+			PubKey:        participant.PubKey,
+			DkgPubKey:     participant.DkgPubKey,
+		})
+		if participantId < 6 {
+			request.Participants = append(request.Participants, &requests.SignatureProposalParticipantsEntry{
+				Username:  participant.Username,
+				PubKey:    participant.PubKey,
+				DkgPubKey: participant.DkgPubKey,
+			})
+		}
+	}
+	for i := 0; i < 2; i++ {
+		request.Participants = append(request.Participants, &requests.SignatureProposalParticipantsEntry{
+			Username:  base64.StdEncoding.EncodeToString(genDataMock(usernameMockLen)),
+			PubKey:    genDataMock(keysMockLen),
+			DkgPubKey: genDataMock(keysMockLen),
+		})
+	}
+	for _, participantId := range []int{0, 7, 8} {
+		request.Dealers = append(request.Dealers, request.OldParticipants[participantId].Username)
+	}
+	return request
+}
+
+func Test_Resharing_Positive(t *testing.T) {
+	request := testResharingProposalRequest(t)
+
+	testFSMInstance, err := Create("resharing")
+	require.NoError(t, err)
+	fsmResponse, dump, err := testFSMInstance.Do(spf.EventInitResharingProposal, request)
+	require.NoError(t, err)
+	compareState(t, rf.StateResharingAwaitParticipantsConfirmations, fsmResponse.State)
+
+	proposal, ok := fsmResponse.Data.(responses.ResharingProposalInvitationResponse)
+	require.True(t, ok)
+	require.Len(t, proposal.Participants, len(request.Participants)+2)
+	require.Len(t, proposal.Dealers, len(request.Dealers))
+
+	for _, participant := range proposal.Participants {
+		testFSMInstance, err = FromDump(dump)
+		require.NoError(t, err)
+		fsmResponse, dump, err = testFSMInstance.Do(rf.EventConfirmResharingProposal, requests.SignatureProposalParticipantRequest{
+			ParticipantId: participant.ParticipantId,
+			CreatedAt:     time.Now(),
+		})
+		require.NoError(t, err)
+	}
+	compareState(t, rf.StateResharingCommitsAwaitConfirmations, fsmResponse.State)
+
+	invitation, ok := fsmResponse.Data.(responses.ResharingInvitationResponse)
+	require.True(t, ok)
+	require.Equal(t, len(request.Participants), invitation.Receivers)
+	require.Equal(t, request.SigningThreshold, invitation.Threshold)
+	require.Len(t, invitation.OldParticipants, participantsNumber)
+	require.Len(t, invitation.Participants, len(proposal.Participants))
+	require.Equal(t, map[int]int{0: 0, 8: 7, 9: 8}, invitation.Dealers)
+
+	var (
+		dealers, receivers, all []int
+		pubPoly                 = genDataMock(keysMockLen)
+	)
+	for _, participant := range invitation.Participants {
+		all = append(all, participant.ParticipantId)
+		if _, ok := invitation.Dealers[participant.ParticipantId]; ok {
+			dealers = append(dealers, participant.ParticipantId)
+		}
+		if participant.ParticipantId < invitation.Receivers {
+			receivers = append(receivers, participant.ParticipantId)
+		}
+	}
+
+	stages := []struct {
+		event        fsm.Event
+		participants []int
+		request      func(participantId int) interface{}
+		state        fsm.State
+	}{
+		{rf.EventResharingCommitConfirmationReceived, all, func(participantId int) interface{} {
+			return requests.DKGProposalCommitConfirmationRequest{ParticipantId: participantId, Commit: genDataMock(keysMockLen), CreatedAt: time.Now()}
+		}, rf.StateResharingDealsAwaitConfirmations},
+		{rf.EventResharingDealConfirmationReceived, dealers, func(participantId int) interface{} {
+			return requests.DKGProposalDealConfirmationRequest{ParticipantId: participantId, Deal: genDataMock(keysMockLen), CreatedAt: time.Now()}
+		}, rf.StateResharingResponsesAwaitConfirmations},
+		{rf.EventResharingResponseConfirmationReceived, receivers, func(participantId int) interface{} {
+			return requests.DKGProposalResponseConfirmationRequest{ParticipantId: participantId, Response: genDataMock(keysMockLen), CreatedAt: time.Now()}
+		}, rf.StateResharingKeyringAwaitConfirmations},
+		{rf.EventResharingKeyringConfirmationReceived, receivers, func(participantId int) interface{} {
+			return requests.DKGProposalMasterKeyConfirmationRequest{ParticipantId: participantId, MasterKey: request.OldMasterKey, PubPolyBz: pubPoly, CreatedAt: time.Now()}
+		}, rf.StateResharingKeyringCollected},
+	}
+	var keyringDump []byte
+	for _, stage := range stages {
+		if stage.state == rf.StateResharingKeyringCollected {
+			keyringDump = dump
+		}
+		for _, participantId := range stage.participants {
+			testFSMInstance, err = FromDump(dump)
+			require.NoError(t, err)
+			fsmResponse, dump, err = testFSMInstance.Do(stage.event, stage.request(participantId))
+			require.NoError(t, err)
+		}
+		compareState(t, stage.state, fsmResponse.State)
+	}
+
+	// a keyring of another master key fails the resharing
+	for _, participantId := range receivers {
+		testFSMInstance, err = FromDump(keyringDump)
+		require.NoError(t, err)
+		masterKey := request.OldMasterKey
+		if participantId == receivers[len(receivers)-1] {
+			masterKey = genDataMock(keysMockLen)
+		}
+		fsmResponse, keyringDump, err = testFSMInstance.Do(rf.EventResharingKeyringConfirmationReceived, requests.DKGProposalMasterKeyConfirmationRequest{
+			ParticipantId: participantId,
+			MasterKey:     masterKey,
+			PubPolyBz:     pubPoly,
+			CreatedAt:     time.Now(),
+		})
+		require.NoError(t, err)
+	}
+	compareState(t, rf.StateResharingKeyringAwaitCanceledByError, fsmResponse.State)
+
+	// the new committee runs the round as if it has finished the DKG
+	testFSMInstance, err = FromDump(dump)
+	require.NoError(t, err)
+	payload := testFSMInstance.FSMDump().Payload
+	require.Equal(t, request.SigningThreshold, payload.GetThreshold())
+	require.Len(t, payload.SignatureProposalPayload.Quorum, invitation.Receivers)
+	require.Len(t, payload.DKGProposalPayload.Quorum, invitation.Receivers)
+	require.Equal(t, pubPoly, payload.DKGProposalPayload.PubPolyBz)
+
+	dealer := request.OldParticipants[7].Username
+	require.False(t, testFSMInstance.FSMDump().IsStageParticipant(dealer))
+	require.True(t, testFSMInstance.FSMDump().IsStageParticipant(request.Participants[0].Username))
+
+	fsmResponse, _, err = testFSMInstance.Do(sif.EventSigningInit, requests.DefaultRequest{
+		CreatedAt: time.Now(),
+	})
+	require.NoError(t, err)
+	compareState(t, sif.StateSigningIdle, fsmResponse.State)
+}
+
+func Test_Resharing_Canceled_Participant(t *testing.T) {
+	request := testResharingProposalRequest(t)
+
+	testFSMInstance, err := Create("resharing")
+	require.NoError(t, err)
+	_, dump, err := testFSMInstance.Do(spf.EventInitResharingProposal, request)
+	require.NoError(t, err)
+
+	testFSMInstance, err = FromDump(dump)
+	require.NoError(t, err)
+	fsmResponse, _, err := testFSMInstance.Do(rf.EventDeclineResharingProposal, requests.SignatureProposalParticipantRequest{
+		ParticipantId: 0,
+		Reason:        "not now",
+		CreatedAt:     time.Now(),
+	})
+	require.NoError(t, err)
+	compareState(t, rf.StateResharingCanceledByParticipant, fsmResponse.State)
+}
+
 func Test_Parallel(t *testing.T) {
 	var (
 		id1 = "123"
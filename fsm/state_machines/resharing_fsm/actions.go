@@ -0,0 +1,658 @@
+package resharing_fsm
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+
+	"github.com/lidofinance/dc4bc/fsm/fsm"
+	"github.com/lidofinance/dc4bc/fsm/state_machines/internal"
+	"github.com/lidofinance/dc4bc/fsm/types/requests"
+	"github.com/lidofinance/dc4bc/fsm/types/responses"
+)
+
+// Approval
+
+func (m *ResharingFSM) actionProposalResponseByParticipant(inEvent fsm.Event, args ...interface{}) (outEvent fsm.Event, response interface{}, err error) {
+	m.payloadMu.Lock()
+	defer m.payloadMu.Unlock()
+
+	if len(args) != 1 {
+		err = errors.New("{arg0} required {SignatureProposalParticipantRequest}")
+		return
+	}
+
+	request, ok := args[0].(requests.SignatureProposalParticipantRequest)
+
+	if !ok {
+		err = errors.New("cannot cast {arg0} to type {SignatureProposalParticipantRequest}")
+		return
+	}
+
+	if err = request.Validate(); err != nil {
+		return
+	}
+
+	participant, ok := m.payload.ResharingPayload.Proposal[request.ParticipantId]
+	if !ok {
+		err = errors.New("{ParticipantId} not exist in quorum")
+		return
+	}
+
+	if participant.Status != internal.SigConfirmationAwaitConfirmation {
+		err = fmt.Errorf("cannot apply reply participant with {Status} = {\"%s\"}", participant.Status)
+		return
+	}
+
+	switch inEvent {
+	case EventConfirmResharingProposal:
+		participant.Status = internal.SigConfirmationConfirmed
+	case EventDeclineResharingProposal:
+		participant.Status = internal.SigConfirmationDeclined
+		participant.DeclineReason = request.Reason
+	default:
+		err = fmt.Errorf("unsupported event for action {inEvent} = {\"%s\"}", inEvent)
+		return
+	}
+
+	participant.UpdatedAt = request.CreatedAt
+	m.payload.ResharingPayload.UpdatedAt = request.CreatedAt
+
+	return
+}
+
+func (m *ResharingFSM) actionValidateResharingProposal(inEvent fsm.Event, args ...interface{}) (outEvent fsm.Event, response interface{}, err error) {
+	m.payloadMu.Lock()
+	defer m.payloadMu.Unlock()
+
+	resharing := m.payload.ResharingPayload
+
+	if resharing.IsExpired() {
+		outEvent = eventResharingProposalCanceledByTimeout
+		return
+	}
+
+	var confirmed int
+	for _, participant := range resharing.Proposal {
+		switch participant.Status {
+		case internal.SigConfirmationDeclined:
+			outEvent = eventResharingProposalCanceledByParticipant
+			return
+		case internal.SigConfirmationConfirmed:
+			confirmed++
+		}
+	}
+
+	if confirmed < len(resharing.Proposal) {
+		return
+	}
+
+	outEvent = eventResharingProposalConfirmedInternal
+
+	// the deadline of the stages is counted from the proposal, as the DKG one
+	resharing.ExpiresAt = resharing.CreatedAt.Add(m.payload.DKGDeadline())
+	resharing.Quorum = make(internal.DKGProposalQuorum)
+
+	for participantId, participant := range resharing.Proposal {
+		resharing.Quorum[participantId] = &internal.DKGProposalParticipant{
+			Username:  participant.Username,
+			DkgPubKey: make([]byte, len(participant.DkgPubKey)),
+			Status:    internal.CommitAwaitConfirmation,
+			UpdatedAt: resharing.UpdatedAt,
+		}
+		copy(resharing.Quorum[participantId].DkgPubKey, participant.DkgPubKey)
+	}
+
+	// Make response
+
+	responseData := responses.ResharingInvitationResponse{
+		OldDkgID:          resharing.OldDkgID,
+		OldThreshold:      resharing.OldThreshold,
+		OldKeyringVersion: resharing.OldKeyringVersion,
+		OldPubPolyBz:      resharing.OldPubPolyBz,
+		OldParticipants:   make(responses.DKGProposalPubKeysParticipantResponse, 0, len(resharing.OldParticipants)),
+		Dealers:           resharing.Dealers,
+		Receivers:         resharing.Receivers,
+		Threshold:         resharing.Threshold,
+		Participants:      make(responses.DKGProposalPubKeysParticipantResponse, 0, len(resharing.Quorum)),
+	}
+
+	for participantId := 0; participantId < len(resharing.OldParticipants); participantId++ {
+		participant := resharing.OldParticipants[participantId]
+		responseData.OldParticipants = append(responseData.OldParticipants, &responses.DKGProposalPubKeysParticipantEntry{
+			ParticipantId: participantId,
+			Username:      participant.Username,
+			DkgPubKey:     participant.DkgPubKey,
+			Threshold:     resharing.OldThreshold,
+		})
+	}
+
+	for _, participant := range resharing.Quorum.GetOrderedParticipants() {
+		responseData.Participants = append(responseData.Participants, &responses.DKGProposalPubKeysParticipantEntry{
+			ParticipantId: participant.ParticipantID,
+			Username:      participant.Username,
+			DkgPubKey:     participant.DkgPubKey,
+			Threshold:     resharing.Threshold,
+		})
+	}
+
+	response = responseData
+
+	return
+}
+
+// getAwaitingParticipant returns a participant of the resharing quorum if it awaits the status
+func (m *ResharingFSM) getAwaitingParticipant(participantId int, status internal.DKGParticipantStatus) (*internal.DKGProposalParticipant, error) {
+	if !m.payload.ResharingQuorumExists(participantId) {
+		return nil, errors.New("{ParticipantId} not exist in quorum")
+	}
+
+	participant := m.payload.ResharingQuorumGet(participantId)
+
+	if participant.Status != status {
+		return nil, fmt.Errorf("cannot confirm {Status} = {\"%s\"}", participant.Status)
+	}
+
+	return participant, nil
+}
+
+// setStageStatuses sets the awaiting status of the next stage to its participants, the others have nothing to send
+// at the stage, so they are confirmed at once
+func (m *ResharingFSM) setStageStatuses(isStageParticipant func(participantId int) bool,
+	awaitStatus, confirmedStatus internal.DKGParticipantStatus) {
+	for participantId, participant := range m.payload.ResharingPayload.Quorum {
+		if isStageParticipant(participantId) {
+			participant.Status = awaitStatus
+		} else {
+			participant.Status = confirmedStatus
+		}
+	}
+}
+
+func (m *ResharingFSM) isDealer(participantId int) bool {
+	_, ok := m.payload.ResharingPayload.Dealers[participantId]
+	return ok
+}
+
+func (m *ResharingFSM) isReceiver(participantId int) bool {
+	return participantId < m.payload.ResharingPayload.Receivers
+}
+
+// Commits
+
+func (m *ResharingFSM) actionCommitConfirmationReceived(inEvent fsm.Event, args ...interface{}) (outEvent fsm.Event, response interface{}, err error) {
+	m.payloadMu.Lock()
+	defer m.payloadMu.Unlock()
+
+	if len(args) != 1 {
+		err = errors.New("{arg0} required {DKGProposalCommitConfirmationRequest}")
+		return
+	}
+
+	request, ok := args[0].(requests.DKGProposalCommitConfirmationRequest)
+
+	if !ok {
+		err = errors.New("cannot cast {arg0} to type {DKGProposalCommitConfirmationRequest}")
+		return
+	}
+
+	if err = request.Validate(); err != nil {
+		return
+	}
+
+	participant, err := m.getAwaitingParticipant(request.ParticipantId, internal.CommitAwaitConfirmation)
+	if err != nil {
+		return
+	}
+
+	participant.DkgCommit = make([]byte, len(request.Commit))
+	copy(participant.DkgCommit, request.Commit)
+	participant.Status = internal.CommitConfirmed
+
+	participant.UpdatedAt = request.CreatedAt
+	m.payload.ResharingPayload.UpdatedAt = request.CreatedAt
+
+	m.payload.ResharingQuorumUpdate(request.ParticipantId, participant)
+
+	return
+}
+
+func (m *ResharingFSM) actionValidateResharingAwaitCommits(inEvent fsm.Event, args ...interface{}) (outEvent fsm.Event, response interface{}, err error) {
+	m.payloadMu.Lock()
+	defer m.payloadMu.Unlock()
+
+	if m.payload.ResharingPayload.IsExpired() {
+		outEvent = eventResharingCommitsConfirmationCancelByTimeoutInternal
+		return
+	}
+
+	confirmed, isContainsError := m.countStatuses(internal.CommitConfirmed, internal.CommitConfirmationError)
+
+	if isContainsError {
+		outEvent = eventResharingCommitsConfirmationCancelByErrorInternal
+		return
+	}
+
+	if confirmed < m.payload.ResharingQuorumCount() {
+		return
+	}
+
+	outEvent = eventResharingCommitsConfirmedInternal
+
+	m.setStageStatuses(m.isDealer, internal.DealAwaitConfirmation, internal.DealConfirmed)
+
+	// Make response
+
+	responseData := make(responses.DKGProposalCommitParticipantResponse, 0)
+
+	for _, participant := range m.payload.ResharingPayload.Quorum.GetOrderedParticipants() {
+		responseEntry := &responses.DKGProposalCommitParticipantEntry{
+			ParticipantId: participant.ParticipantID,
+			Username:      participant.Username,
+			DkgCommit:     participant.DkgCommit,
+		}
+		responseData = append(responseData, responseEntry)
+	}
+
+	response = responseData
+
+	return
+}
+
+// Deals
+
+func (m *ResharingFSM) actionDealConfirmationReceived(inEvent fsm.Event, args ...interface{}) (outEvent fsm.Event, response interface{}, err error) {
+	m.payloadMu.Lock()
+	defer m.payloadMu.Unlock()
+
+	if len(args) != 1 {
+		err = errors.New("{arg0} required {DKGProposalDealConfirmationRequest}")
+		return
+	}
+
+	request, ok := args[0].(requests.DKGProposalDealConfirmationRequest)
+
+	if !ok {
+		err = errors.New("cannot cast {arg0} to type {DKGProposalDealConfirmationRequest}")
+		return
+	}
+
+	if err = request.Validate(); err != nil {
+		return
+	}
+
+	participant, err := m.getAwaitingParticipant(request.ParticipantId, internal.DealAwaitConfirmation)
+	if err != nil {
+		return
+	}
+
+	participant.DkgDeal = make([]byte, len(request.Deal))
+	copy(participant.DkgDeal, request.Deal)
+	participant.Status = internal.DealConfirmed
+
+	participant.UpdatedAt = request.CreatedAt
+	m.payload.ResharingPayload.UpdatedAt = request.CreatedAt
+
+	m.payload.ResharingQuorumUpdate(request.ParticipantId, participant)
+
+	return
+}
+
+func (m *ResharingFSM) actionValidateResharingAwaitDeals(inEvent fsm.Event, args ...interface{}) (outEvent fsm.Event, response interface{}, err error) {
+	m.payloadMu.Lock()
+	defer m.payloadMu.Unlock()
+
+	if m.payload.ResharingPayload.IsExpired() {
+		outEvent = eventResharingDealsConfirmationCancelByTimeoutInternal
+		return
+	}
+
+	confirmed, isContainsError := m.countStatuses(internal.DealConfirmed, internal.DealConfirmationError)
+
+	if isContainsError {
+		outEvent = eventResharingDealsConfirmationCancelByErrorInternal
+		return
+	}
+
+	if confirmed < m.payload.ResharingQuorumCount() {
+		return
+	}
+
+	outEvent = eventResharingDealsConfirmedInternal
+
+	m.setStageStatuses(m.isReceiver, internal.ResponseAwaitConfirmation, internal.ResponseConfirmed)
+
+	// Make response
+
+	responseData := responses.ResharingDealsParticipantResponse{
+		Commits: make(responses.DKGProposalCommitParticipantResponse, 0),
+		Deals:   make(responses.DKGProposalDealParticipantResponse, 0),
+	}
+
+	for _, participant := range m.payload.ResharingPayload.Quorum.GetOrderedParticipants() {
+		if !m.isDealer(participant.ParticipantID) {
+			continue
+		}
+		responseData.Commits = append(responseData.Commits, &responses.DKGProposalCommitParticipantEntry{
+			ParticipantId: participant.ParticipantID,
+			Username:      participant.Username,
+			DkgCommit:     participant.DkgCommit,
+		})
+		if len(participant.DkgDeal) == 0 {
+			continue
+		}
+		responseData.Deals = append(responseData.Deals, &responses.DKGProposalDealParticipantEntry{
+			ParticipantId: participant.ParticipantID,
+			Username:      participant.Username,
+			DkgDeal:       participant.DkgDeal,
+		})
+	}
+
+	response = responseData
+
+	return
+}
+
+// Responses
+
+func (m *ResharingFSM) actionResponseConfirmationReceived(inEvent fsm.Event, args ...interface{}) (outEvent fsm.Event, response interface{}, err error) {
+	m.payloadMu.Lock()
+	defer m.payloadMu.Unlock()
+
+	if len(args) != 1 {
+		err = errors.New("{arg0} required {DKGProposalResponseConfirmationRequest}")
+		return
+	}
+
+	request, ok := args[0].(requests.DKGProposalResponseConfirmationRequest)
+
+	if !ok {
+		err = errors.New("cannot cast {arg0} to type {DKGProposalResponseConfirmationRequest}")
+		return
+	}
+
+	if err = request.Validate(); err != nil {
+		return
+	}
+
+	participant, err := m.getAwaitingParticipant(request.ParticipantId, internal.ResponseAwaitConfirmation)
+	if err != nil {
+		return
+	}
+
+	participant.DkgResponse = make([]byte, len(request.Response))
+	copy(participant.DkgResponse, request.Response)
+	participant.Status = internal.ResponseConfirmed
+
+	participant.UpdatedAt = request.CreatedAt
+	m.payload.ResharingPayload.UpdatedAt = request.CreatedAt
+
+	m.payload.ResharingQuorumUpdate(request.ParticipantId, participant)
+
+	return
+}
+
+func (m *ResharingFSM) actionValidateResharingAwaitResponses(inEvent fsm.Event, args ...interface{}) (outEvent fsm.Event, response interface{}, err error) {
+	m.payloadMu.Lock()
+	defer m.payloadMu.Unlock()
+
+	if m.payload.ResharingPayload.IsExpired() {
+		outEvent = eventResharingResponsesConfirmationCancelByTimeoutInternal
+		return
+	}
+
+	confirmed, isContainsError := m.countStatuses(internal.ResponseConfirmed, internal.ResponseConfirmationError)
+
+	if isContainsError {
+		outEvent = eventResharingResponsesConfirmationCancelByErrorInternal
+		return
+	}
+
+	if confirmed < m.payload.ResharingQuorumCount() {
+		return
+	}
+
+	outEvent = eventResharingResponsesConfirmedInternal
+
+	m.setStageStatuses(m.isReceiver, internal.MasterKeyAwaitConfirmation, internal.MasterKeyConfirmed)
+
+	// Make response
+
+	responseData := make(responses.DKGProposalResponseParticipantResponse, 0)
+
+	for _, participant := range m.payload.ResharingPayload.Quorum.GetOrderedParticipants() {
+		if !m.isReceiver(participant.ParticipantID) {
+			continue
+		}
+		responseEntry := &responses.DKGProposalResponseParticipantEntry{
+			ParticipantId: participant.ParticipantID,
+			Username:      participant.Username,
+			DkgResponse:   participant.DkgResponse,
+		}
+		responseData = append(responseData, responseEntry)
+	}
+
+	response = responseData
+
+	return
+}
+
+// Keyring
+
+// actionKeyringConfirmationReceived stores the master key of the new keyring, it must be the master key
+// of the old round, and the public polynomial, it must be the same for all new committee members
+func (m *ResharingFSM) actionKeyringConfirmationReceived(inEvent fsm.Event, args ...interface{}) (outEvent fsm.Event, response interface{}, err error) {
+	m.payloadMu.Lock()
+	defer m.payloadMu.Unlock()
+
+	if len(args) != 1 {
+		err = errors.New("{arg0} required {DKGProposalMasterKeyConfirmationRequest}")
+		return
+	}
+
+	request, ok := args[0].(requests.DKGProposalMasterKeyConfirmationRequest)
+
+	if !ok {
+		err = errors.New("cannot cast {arg0} to type {DKGProposalMasterKeyConfirmationRequest}")
+		return
+	}
+
+	if err = request.Validate(); err != nil {
+		return
+	}
+
+	participant, err := m.getAwaitingParticipant(request.ParticipantId, internal.MasterKeyAwaitConfirmation)
+	if err != nil {
+		return
+	}
+
+	resharing := m.payload.ResharingPayload
+
+	participant.DkgMasterKey = make([]byte, len(request.MasterKey))
+	copy(participant.DkgMasterKey, request.MasterKey)
+	participant.Status = internal.MasterKeyConfirmed
+
+	if !bytes.Equal(request.MasterKey, resharing.OldMasterKey) {
+		participant.Status = internal.MasterKeyConfirmationError
+		participant.Error = requests.NewFSMError(errors.New("master key is changed by the resharing"))
+	} else if resharing.PubPolyBz == nil {
+		resharing.PubPolyBz = request.PubPolyBz
+	} else if !bytes.Equal(request.PubPolyBz, resharing.PubPolyBz) {
+		participant.Status = internal.MasterKeyConfirmationError
+		participant.Error = requests.NewFSMError(errors.New("public polynomial is mismatched"))
+	}
+
+	participant.UpdatedAt = request.CreatedAt
+	resharing.UpdatedAt = request.CreatedAt
+
+	m.payload.ResharingQuorumUpdate(request.ParticipantId, participant)
+
+	return
+}
+
+func (m *ResharingFSM) actionValidateResharingAwaitKeyring(inEvent fsm.Event, args ...interface{}) (outEvent fsm.Event, response interface{}, err error) {
+	m.payloadMu.Lock()
+	defer m.payloadMu.Unlock()
+
+	resharing := m.payload.ResharingPayload
+
+	if resharing.IsExpired() {
+		outEvent = eventResharingKeyringConfirmationCancelByTimeoutInternal
+		return
+	}
+
+	confirmed, isContainsError := m.countStatuses(internal.MasterKeyConfirmed, internal.MasterKeyConfirmationError)
+
+	if isContainsError {
+		outEvent = eventResharingKeyringConfirmationCancelByErrorInternal
+		return
+	}
+
+	if confirmed < m.payload.ResharingQuorumCount() {
+		return
+	}
+
+	outEvent = eventResharingKeyringConfirmedInternal
+
+	// The round is the same as a round finished by the DKG among the new committee from now on
+	m.payload.SignatureProposalPayload = &internal.SignatureConfirmation{
+		Quorum:    make(internal.SignatureProposalQuorum),
+		CreatedAt: resharing.CreatedAt,
+		UpdatedAt: resharing.UpdatedAt,
+		ExpiresAt: resharing.ExpiresAt,
+	}
+	m.payload.DKGProposalPayload = &internal.DKGConfirmation{
+		Quorum:    make(internal.DKGProposalQuorum),
+		CreatedAt: resharing.CreatedAt,
+		UpdatedAt: resharing.UpdatedAt,
+		ExpiresAt: resharing.ExpiresAt,
+		PubPolyBz: resharing.PubPolyBz,
+	}
+
+	for participantId := 0; participantId < resharing.Receivers; participantId++ {
+		proposalParticipant := *resharing.Proposal[participantId]
+		m.payload.SignatureProposalPayload.Quorum[participantId] = &proposalParticipant
+
+		dkgParticipant := *resharing.Quorum[participantId]
+		m.payload.DKGProposalPayload.Quorum[participantId] = &dkgParticipant
+	}
+
+	return
+}
+
+// countStatuses returns the number of participants with the confirmed status
+// and whether any participant has the error status
+func (m *ResharingFSM) countStatuses(confirmedStatus, errorStatus internal.DKGParticipantStatus) (confirmed int, isContainsError bool) {
+	for _, participant := range m.payload.ResharingPayload.Quorum {
+		switch participant.Status {
+		case errorStatus:
+			isContainsError = true
+		case confirmedStatus:
+			confirmed++
+		}
+	}
+	return
+}
+
+// Errors
+
+func (m *ResharingFSM) actionConfirmationError(inEvent fsm.Event, args ...interface{}) (outEvent fsm.Event, response interface{}, err error) {
+	m.payloadMu.Lock()
+	defer m.payloadMu.Unlock()
+
+	if len(args) != 1 {
+		err = errors.New("{arg0} required {DKGProposalConfirmationErrorRequest}")
+		return
+	}
+
+	request, ok := args[0].(requests.DKGProposalConfirmationErrorRequest)
+
+	if !ok {
+		err = errors.New("cannot cast {arg0} to type {DKGProposalConfirmationErrorRequest}")
+		return
+	}
+
+	if err = request.Validate(); err != nil {
+		return
+	}
+
+	if !m.payload.ResharingQuorumExists(request.ParticipantId) {
+		err = errors.New("{ParticipantId} not exist in quorum")
+		return
+	}
+
+	var awaitStatus, errorStatus internal.DKGParticipantStatus
+	switch inEvent {
+	case EventResharingCommitConfirmationError:
+		awaitStatus, errorStatus = internal.CommitAwaitConfirmation, internal.CommitConfirmationError
+	case EventResharingDealConfirmationError:
+		awaitStatus, errorStatus = internal.DealAwaitConfirmation, internal.DealConfirmationError
+	case EventResharingResponseConfirmationError:
+		awaitStatus, errorStatus = internal.ResponseAwaitConfirmation, internal.ResponseConfirmationError
+	case EventResharingKeyringConfirmationError:
+		awaitStatus, errorStatus = internal.MasterKeyAwaitConfirmation, internal.MasterKeyConfirmationError
+	default:
+		err = fmt.Errorf("{%s} event cannot be used for action {actionConfirmationError}", inEvent)
+		return
+	}
+
+	participant := m.payload.ResharingQuorumGet(request.ParticipantId)
+
+	switch participant.Status {
+	case awaitStatus:
+		participant.Status = errorStatus
+	case errorStatus:
+		err = fmt.Errorf("{Status} already has {\"%s\"}", errorStatus)
+		return
+	default:
+		err = fmt.Errorf("{Status} now is \"%s\" and cannot set to {\"%s\"}", participant.Status, errorStatus)
+		return
+	}
+
+	participant.Error = request.Error
+
+	participant.UpdatedAt = request.CreatedAt
+	m.payload.ResharingPayload.UpdatedAt = request.CreatedAt
+
+	m.payload.ResharingQuorumUpdate(request.ParticipantId, participant)
+
+	return
+}
+
+// actionTimeout records the timeout of the approval or the current stage posted after the deadline, it's canceled
+// when enough participants posted it, so a single participant can't cancel it
+func (m *ResharingFSM) actionTimeout(inEvent fsm.Event, args ...interface{}) (outEvent fsm.Event, response interface{}, err error) {
+	m.payloadMu.Lock()
+	defer m.payloadMu.Unlock()
+
+	if len(args) != 1 {
+		err = errors.New("{arg0} required {TimeoutRequest}")
+		return
+	}
+
+	request, ok := args[0].(requests.TimeoutRequest)
+
+	if !ok {
+		err = errors.New("cannot cast {arg0} to type {TimeoutRequest}")
+		return
+	}
+
+	if err = request.Validate(); err != nil {
+		return
+	}
+
+	if request.CreatedAt.Before(m.payload.ResharingPayload.ExpiresAt) {
+		err = fmt.Errorf("cannot cancel by timeout before {ExpiresAt} = {\"%s\"}", m.payload.ResharingPayload.ExpiresAt)
+		return
+	}
+
+	votes := m.payload.AddTimeoutVote(inEvent, m.payload.ResharingPayload.ExpiresAt, request.Username)
+	if votes < internal.TimeoutQuorum(len(m.payload.ResharingPayload.Proposal), m.payload.ResharingPayload.Threshold) {
+		return
+	}
+
+	m.payload.ResharingPayload.UpdatedAt = request.CreatedAt
+	outEvent = timeoutCancelEvents[inEvent]
+
+	return
+}
@@ -0,0 +1,220 @@
+package resharing_fsm
+
+import (
+	"sync"
+
+	"github.com/lidofinance/dc4bc/fsm/fsm"
+	dpf "github.com/lidofinance/dc4bc/fsm/state_machines/dkg_proposal_fsm"
+	"github.com/lidofinance/dc4bc/fsm/state_machines/internal"
+	spf "github.com/lidofinance/dc4bc/fsm/state_machines/signature_proposal_fsm"
+)
+
+// The resharing hands the master key of a finished DKG round over to a new committee with another threshold. All
+// participants approve the resharing, then the dealers deal their key shares of the old round to the new committee
+// members in the same stages as the DKG ones. The master key of the new keyring must be the old one, after that
+// the round is the same as a round finished by the DKG
+const (
+	FsmName = "resharing_fsm"
+
+	StateResharingInitial = spf.StateResharingAwaitParticipantsConfirmations
+
+	// Approving by participants
+	StateResharingAwaitParticipantsConfirmations = StateResharingInitial
+	// Canceled
+	StateResharingCanceledByParticipant = fsm.State("state_resharing_canceled_by_participant")
+	StateResharingCanceledByTimeout     = fsm.State("state_resharing_canceled_by_timeout")
+
+	// Sending commits, the participants who don't deal send empty ones
+	StateResharingCommitsAwaitConfirmations = fsm.State("state_resharing_commits_await_confirmations")
+	// Canceled
+	StateResharingCommitsAwaitCanceledByError   = fsm.State("state_resharing_commits_await_canceled_by_error")
+	StateResharingCommitsAwaitCanceledByTimeout = fsm.State("state_resharing_commits_await_canceled_by_timeout")
+
+	// Sending deals by the dealers
+	StateResharingDealsAwaitConfirmations = fsm.State("state_resharing_deals_await_confirmations")
+	// Canceled
+	StateResharingDealsAwaitCanceledByError   = fsm.State("state_resharing_deals_await_canceled_by_error")
+	StateResharingDealsAwaitCanceledByTimeout = fsm.State("state_resharing_deals_await_canceled_by_timeout")
+
+	// Sending responses by the new committee
+	StateResharingResponsesAwaitConfirmations = fsm.State("state_resharing_responses_await_confirmations")
+	// Canceled
+	StateResharingResponsesAwaitCanceledByError   = fsm.State("state_resharing_responses_await_canceled_by_error")
+	StateResharingResponsesAwaitCanceledByTimeout = fsm.State("state_resharing_responses_await_canceled_by_timeout")
+
+	// Confirming the new keyring by the new committee, its master key must be the old one
+	StateResharingKeyringAwaitConfirmations     = fsm.State("state_resharing_keyring_await_confirmations")
+	StateResharingKeyringAwaitCanceledByError   = fsm.State("state_resharing_keyring_await_canceled_by_error")
+	StateResharingKeyringAwaitCanceledByTimeout = fsm.State("state_resharing_keyring_await_canceled_by_timeout")
+
+	// The resharing is done, the round goes to signing
+	StateResharingKeyringCollected = dpf.StateDkgMasterKeyCollected
+
+	// Events
+	EventConfirmResharingProposal               = fsm.Event("event_resharing_proposal_confirm_by_participant")
+	EventDeclineResharingProposal               = fsm.Event("event_resharing_proposal_decline_by_participant")
+	eventAutoValidateResharingProposalInternal  = fsm.Event("event_resharing_proposal_validate_internal")
+	eventResharingProposalCanceledByParticipant = fsm.Event("event_resharing_proposal_canceled_participant_internal")
+	eventResharingProposalCanceledByTimeout     = fsm.Event("event_resharing_proposal_canceled_timeout_internal")
+	eventResharingProposalConfirmedInternal     = fsm.Event("event_resharing_proposal_confirmed_internal")
+
+	EventResharingCommitConfirmationReceived                 = fsm.Event("event_resharing_commit_confirm_received")
+	EventResharingCommitConfirmationError                    = fsm.Event("event_resharing_commit_confirm_canceled_by_error")
+	eventResharingCommitsConfirmationCancelByTimeoutInternal = fsm.Event("event_resharing_commits_confirm_canceled_by_timeout_internal")
+	eventResharingCommitsConfirmationCancelByErrorInternal   = fsm.Event("event_resharing_commits_confirm_canceled_by_error_internal")
+	eventResharingCommitsConfirmedInternal                   = fsm.Event("event_resharing_commits_confirmed_internal")
+	eventAutoResharingValidateCommitsInternal                = fsm.Event("event_resharing_commits_validate_internal")
+
+	EventResharingDealConfirmationReceived                 = fsm.Event("event_resharing_deal_confirm_received")
+	EventResharingDealConfirmationError                    = fsm.Event("event_resharing_deal_confirm_canceled_by_error")
+	eventResharingDealsConfirmationCancelByTimeoutInternal = fsm.Event("event_resharing_deals_confirm_canceled_by_timeout_internal")
+	eventResharingDealsConfirmationCancelByErrorInternal   = fsm.Event("event_resharing_deals_confirm_canceled_by_error_internal")
+	eventResharingDealsConfirmedInternal                   = fsm.Event("event_resharing_deals_confirmed_internal")
+	eventAutoResharingValidateDealsInternal                = fsm.Event("event_resharing_deals_validate_internal")
+
+	EventResharingResponseConfirmationReceived                 = fsm.Event("event_resharing_response_confirm_received")
+	EventResharingResponseConfirmationError                    = fsm.Event("event_resharing_response_confirm_canceled_by_error")
+	eventResharingResponsesConfirmationCancelByTimeoutInternal = fsm.Event("event_resharing_responses_confirm_canceled_by_timeout_internal")
+	eventResharingResponsesConfirmationCancelByErrorInternal   = fsm.Event("event_resharing_responses_confirm_canceled_by_error_internal")
+	eventResharingResponsesConfirmedInternal                   = fsm.Event("event_resharing_responses_confirmed_internal")
+	eventAutoResharingValidateResponsesInternal                = fsm.Event("event_resharing_responses_validate_internal")
+
+	EventResharingKeyringConfirmationReceived                = fsm.Event("event_resharing_keyring_confirm_received")
+	EventResharingKeyringConfirmationError                   = fsm.Event("event_resharing_keyring_confirm_canceled_by_error")
+	eventResharingKeyringConfirmationCancelByTimeoutInternal = fsm.Event("event_resharing_keyring_confirm_canceled_by_timeout_internal")
+	eventResharingKeyringConfirmationCancelByErrorInternal   = fsm.Event("event_resharing_keyring_confirm_canceled_by_error_internal")
+	eventResharingKeyringConfirmedInternal                   = fsm.Event("event_resharing_keyring_confirmed_internal")
+	eventAutoResharingValidateKeyringInternal                = fsm.Event("event_resharing_keyring_validate_internal")
+
+	// Posted by nodes when the deadline has passed
+	EventResharingProposalTimeout  = fsm.Event("event_resharing_proposal_timeout")
+	EventResharingCommitsTimeout   = fsm.Event("event_resharing_commits_timeout")
+	EventResharingDealsTimeout     = fsm.Event("event_resharing_deals_timeout")
+	EventResharingResponsesTimeout = fsm.Event("event_resharing_responses_timeout")
+	EventResharingKeyringTimeout   = fsm.Event("event_resharing_keyring_timeout")
+)
+
+// timeoutCancelEvents cancel the stage when enough participants posted its timeout
+var timeoutCancelEvents = map[fsm.Event]fsm.Event{
+	EventResharingProposalTimeout:  eventResharingProposalCanceledByTimeout,
+	EventResharingCommitsTimeout:   eventResharingCommitsConfirmationCancelByTimeoutInternal,
+	EventResharingDealsTimeout:     eventResharingDealsConfirmationCancelByTimeoutInternal,
+	EventResharingResponsesTimeout: eventResharingResponsesConfirmationCancelByTimeoutInternal,
+	EventResharingKeyringTimeout:   eventResharingKeyringConfirmationCancelByTimeoutInternal,
+}
+
+type ResharingFSM struct {
+	*fsm.FSM
+	payload   *internal.DumpedMachineStatePayload
+	payloadMu sync.RWMutex
+}
+
+func New() internal.DumpedMachineProvider {
+	machine := &ResharingFSM{}
+
+	machine.FSM = fsm.MustNewFSM(
+		FsmName,
+		StateResharingInitial,
+		[]fsm.EventDesc{
+			// Approval
+			{Name: EventConfirmResharingProposal, SrcState: []fsm.State{StateResharingAwaitParticipantsConfirmations}, DstState: StateResharingAwaitParticipantsConfirmations},
+			{Name: EventDeclineResharingProposal, SrcState: []fsm.State{StateResharingAwaitParticipantsConfirmations}, DstState: StateResharingAwaitParticipantsConfirmations},
+			// Canceled
+			{Name: eventResharingProposalCanceledByParticipant, SrcState: []fsm.State{StateResharingAwaitParticipantsConfirmations}, DstState: StateResharingCanceledByParticipant, IsInternal: true},
+			{Name: eventResharingProposalCanceledByTimeout, SrcState: []fsm.State{StateResharingAwaitParticipantsConfirmations}, DstState: StateResharingCanceledByTimeout, IsInternal: true},
+			{Name: EventResharingProposalTimeout, SrcState: []fsm.State{StateResharingAwaitParticipantsConfirmations}, DstState: StateResharingAwaitParticipantsConfirmations},
+
+			{Name: eventAutoValidateResharingProposalInternal, SrcState: []fsm.State{StateResharingAwaitParticipantsConfirmations}, DstState: StateResharingAwaitParticipantsConfirmations, IsInternal: true, IsAuto: true},
+
+			// Approved
+			{Name: eventResharingProposalConfirmedInternal, SrcState: []fsm.State{StateResharingAwaitParticipantsConfirmations}, DstState: StateResharingCommitsAwaitConfirmations, IsInternal: true},
+
+			// Commits
+			{Name: EventResharingCommitConfirmationReceived, SrcState: []fsm.State{StateResharingCommitsAwaitConfirmations}, DstState: StateResharingCommitsAwaitConfirmations},
+			// Canceled
+			{Name: EventResharingCommitConfirmationError, SrcState: []fsm.State{StateResharingCommitsAwaitConfirmations, StateResharingCommitsAwaitCanceledByError}, DstState: StateResharingCommitsAwaitCanceledByError},
+			{Name: eventResharingCommitsConfirmationCancelByErrorInternal, SrcState: []fsm.State{StateResharingCommitsAwaitConfirmations}, DstState: StateResharingCommitsAwaitCanceledByError, IsInternal: true},
+			{Name: eventResharingCommitsConfirmationCancelByTimeoutInternal, SrcState: []fsm.State{StateResharingCommitsAwaitConfirmations}, DstState: StateResharingCommitsAwaitCanceledByTimeout, IsInternal: true},
+			{Name: EventResharingCommitsTimeout, SrcState: []fsm.State{StateResharingCommitsAwaitConfirmations}, DstState: StateResharingCommitsAwaitConfirmations},
+
+			{Name: eventAutoResharingValidateCommitsInternal, SrcState: []fsm.State{StateResharingCommitsAwaitConfirmations}, DstState: StateResharingCommitsAwaitConfirmations, IsInternal: true, IsAuto: true},
+
+			// Confirmed
+			{Name: eventResharingCommitsConfirmedInternal, SrcState: []fsm.State{StateResharingCommitsAwaitConfirmations}, DstState: StateResharingDealsAwaitConfirmations, IsInternal: true},
+
+			// Deals
+			{Name: EventResharingDealConfirmationReceived, SrcState: []fsm.State{StateResharingDealsAwaitConfirmations}, DstState: StateResharingDealsAwaitConfirmations},
+			// Canceled
+			{Name: EventResharingDealConfirmationError, SrcState: []fsm.State{StateResharingDealsAwaitConfirmations, StateResharingDealsAwaitCanceledByError}, DstState: StateResharingDealsAwaitCanceledByError},
+			{Name: eventResharingDealsConfirmationCancelByErrorInternal, SrcState: []fsm.State{StateResharingDealsAwaitConfirmations}, DstState: StateResharingDealsAwaitCanceledByError, IsInternal: true},
+			{Name: eventResharingDealsConfirmationCancelByTimeoutInternal, SrcState: []fsm.State{StateResharingDealsAwaitConfirmations}, DstState: StateResharingDealsAwaitCanceledByTimeout, IsInternal: true},
+			{Name: EventResharingDealsTimeout, SrcState: []fsm.State{StateResharingDealsAwaitConfirmations}, DstState: StateResharingDealsAwaitConfirmations},
+
+			{Name: eventAutoResharingValidateDealsInternal, SrcState: []fsm.State{StateResharingDealsAwaitConfirmations}, DstState: StateResharingDealsAwaitConfirmations, IsInternal: true, IsAuto: true},
+
+			{Name: eventResharingDealsConfirmedInternal, SrcState: []fsm.State{StateResharingDealsAwaitConfirmations}, DstState: StateResharingResponsesAwaitConfirmations, IsInternal: true},
+
+			// Responses
+			{Name: EventResharingResponseConfirmationReceived, SrcState: []fsm.State{StateResharingResponsesAwaitConfirmations}, DstState: StateResharingResponsesAwaitConfirmations},
+			// Canceled
+			{Name: EventResharingResponseConfirmationError, SrcState: []fsm.State{StateResharingResponsesAwaitConfirmations, StateResharingResponsesAwaitCanceledByError}, DstState: StateResharingResponsesAwaitCanceledByError},
+			{Name: eventResharingResponsesConfirmationCancelByErrorInternal, SrcState: []fsm.State{StateResharingResponsesAwaitConfirmations}, DstState: StateResharingResponsesAwaitCanceledByError, IsInternal: true},
+			{Name: eventResharingResponsesConfirmationCancelByTimeoutInternal, SrcState: []fsm.State{StateResharingResponsesAwaitConfirmations}, DstState: StateResharingResponsesAwaitCanceledByTimeout, IsInternal: true},
+			{Name: EventResharingResponsesTimeout, SrcState: []fsm.State{StateResharingResponsesAwaitConfirmations}, DstState: StateResharingResponsesAwaitConfirmations},
+
+			{Name: eventAutoResharingValidateResponsesInternal, SrcState: []fsm.State{StateResharingResponsesAwaitConfirmations}, DstState: StateResharingResponsesAwaitConfirmations, IsInternal: true, IsAuto: true},
+
+			{Name: eventResharingResponsesConfirmedInternal, SrcState: []fsm.State{StateResharingResponsesAwaitConfirmations}, DstState: StateResharingKeyringAwaitConfirmations, IsInternal: true},
+
+			// Keyring
+			{Name: EventResharingKeyringConfirmationReceived, SrcState: []fsm.State{StateResharingKeyringAwaitConfirmations}, DstState: StateResharingKeyringAwaitConfirmations},
+			// Canceled
+			{Name: EventResharingKeyringConfirmationError, SrcState: []fsm.State{StateResharingKeyringAwaitConfirmations, StateResharingKeyringAwaitCanceledByError}, DstState: StateResharingKeyringAwaitCanceledByError},
+			{Name: eventResharingKeyringConfirmationCancelByErrorInternal, SrcState: []fsm.State{StateResharingKeyringAwaitConfirmations}, DstState: StateResharingKeyringAwaitCanceledByError, IsInternal: true},
+			{Name: eventResharingKeyringConfirmationCancelByTimeoutInternal, SrcState: []fsm.State{StateResharingKeyringAwaitConfirmations}, DstState: StateResharingKeyringAwaitCanceledByTimeout, IsInternal: true},
+			{Name: EventResharingKeyringTimeout, SrcState: []fsm.State{StateResharingKeyringAwaitConfirmations}, DstState: StateResharingKeyringAwaitConfirmations},
+
+			{Name: eventAutoResharingValidateKeyringInternal, SrcState: []fsm.State{StateResharingKeyringAwaitConfirmations}, DstState: StateResharingKeyringAwaitConfirmations, IsInternal: true, IsAuto: true},
+
+			// Done
+			{Name: eventResharingKeyringConfirmedInternal, SrcState: []fsm.State{StateResharingKeyringAwaitConfirmations}, DstState: StateResharingKeyringCollected, IsInternal: true},
+		},
+		fsm.Callbacks{
+			EventConfirmResharingProposal:              machine.actionProposalResponseByParticipant,
+			EventDeclineResharingProposal:              machine.actionProposalResponseByParticipant,
+			eventAutoValidateResharingProposalInternal: machine.actionValidateResharingProposal,
+
+			EventResharingCommitConfirmationReceived:  machine.actionCommitConfirmationReceived,
+			EventResharingCommitConfirmationError:     machine.actionConfirmationError,
+			eventAutoResharingValidateCommitsInternal: machine.actionValidateResharingAwaitCommits,
+
+			EventResharingDealConfirmationReceived:  machine.actionDealConfirmationReceived,
+			EventResharingDealConfirmationError:     machine.actionConfirmationError,
+			eventAutoResharingValidateDealsInternal: machine.actionValidateResharingAwaitDeals,
+
+			EventResharingResponseConfirmationReceived:  machine.actionResponseConfirmationReceived,
+			EventResharingResponseConfirmationError:     machine.actionConfirmationError,
+			eventAutoResharingValidateResponsesInternal: machine.actionValidateResharingAwaitResponses,
+
+			EventResharingKeyringConfirmationReceived: machine.actionKeyringConfirmationReceived,
+			EventResharingKeyringConfirmationError:    machine.actionConfirmationError,
+			eventAutoResharingValidateKeyringInternal: machine.actionValidateResharingAwaitKeyring,
+
+			EventResharingProposalTimeout:  machine.actionTimeout,
+			EventResharingCommitsTimeout:   machine.actionTimeout,
+			EventResharingDealsTimeout:     machine.actionTimeout,
+			EventResharingResponsesTimeout: machine.actionTimeout,
+			EventResharingKeyringTimeout:   machine.actionTimeout,
+		},
+	)
+	return machine
+}
+
+func (m *ResharingFSM) WithSetup(state fsm.State, payload *internal.DumpedMachineStatePayload) internal.DumpedMachineProvider {
+	m.payloadMu.Lock()
+	defer m.payloadMu.Unlock()
+
+	m.payload = payload
+	m.FSM = m.FSM.MustCopyWithState(state)
+	return m
+}
@@ -0,0 +1,571 @@
+package share_refresh_fsm
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+
+	"github.com/lidofinance/dc4bc/fsm/fsm"
+	"github.com/lidofinance/dc4bc/fsm/state_machines/internal"
+	"github.com/lidofinance/dc4bc/fsm/types/requests"
+	"github.com/lidofinance/dc4bc/fsm/types/responses"
+)
+
+// Init
+
+func (m *ShareRefreshFSM) actionInitShareRefresh(inEvent fsm.Event, args ...interface{}) (outEvent fsm.Event, response interface{}, err error) {
+	m.payloadMu.Lock()
+	defer m.payloadMu.Unlock()
+
+	if len(args) != 1 {
+		err = errors.New("{arg0} required {DefaultRequest}")
+		return
+	}
+
+	request, ok := args[0].(requests.DefaultRequest)
+
+	if !ok {
+		err = errors.New("cannot cast {arg0} to type {DefaultRequest}")
+		return
+	}
+
+	if m.payload.ShareRefreshPayload == nil {
+		err = errors.New("share refresh is not proposed")
+		return
+	}
+
+	m.payload.ShareRefreshPayload.Quorum = make(internal.DKGProposalQuorum)
+	m.payload.ShareRefreshPayload.UpdatedAt = request.CreatedAt
+
+	for participantId, participant := range m.payload.DKGProposalPayload.Quorum {
+		m.payload.ShareRefreshPayload.Quorum[participantId] = &internal.DKGProposalParticipant{
+			Username:  participant.Username,
+			DkgPubKey: make([]byte, len(participant.DkgPubKey)),
+			Status:    internal.CommitAwaitConfirmation,
+			UpdatedAt: request.CreatedAt,
+		}
+		copy(m.payload.ShareRefreshPayload.Quorum[participantId].DkgPubKey, participant.DkgPubKey)
+	}
+
+	// Make response
+
+	responseData := responses.ShareRefreshInvitationResponse{
+		KeyringVersion: m.payload.DKGProposalPayload.KeyringVersion,
+		Participants:   make(responses.DKGProposalPubKeysParticipantResponse, 0),
+	}
+
+	for _, participant := range m.payload.ShareRefreshPayload.Quorum.GetOrderedParticipants() {
+		responseEntry := &responses.DKGProposalPubKeysParticipantEntry{
+			ParticipantId: participant.ParticipantID,
+			Username:      participant.Username,
+			DkgPubKey:     participant.DkgPubKey,
+			Threshold:     m.payload.SignatureProposalPayload.Quorum[0].Threshold,
+		}
+		responseData.Participants = append(responseData.Participants, responseEntry)
+	}
+
+	return inEvent, responseData, nil
+}
+
+// getAwaitingParticipant returns a participant of the refresh quorum if it awaits the status
+func (m *ShareRefreshFSM) getAwaitingParticipant(participantId int, status internal.DKGParticipantStatus) (*internal.DKGProposalParticipant, error) {
+	if !m.payload.ShareRefreshQuorumExists(participantId) {
+		return nil, errors.New("{ParticipantId} not exist in quorum")
+	}
+
+	participant := m.payload.ShareRefreshQuorumGet(participantId)
+
+	if participant.Status != status {
+		return nil, fmt.Errorf("cannot confirm {Status} = {\"%s\"}", participant.Status)
+	}
+
+	return participant, nil
+}
+
+// Commits
+
+func (m *ShareRefreshFSM) actionCommitConfirmationReceived(inEvent fsm.Event, args ...interface{}) (outEvent fsm.Event, response interface{}, err error) {
+	m.payloadMu.Lock()
+	defer m.payloadMu.Unlock()
+
+	if len(args) != 1 {
+		err = errors.New("{arg0} required {DKGProposalCommitConfirmationRequest}")
+		return
+	}
+
+	request, ok := args[0].(requests.DKGProposalCommitConfirmationRequest)
+
+	if !ok {
+		err = errors.New("cannot cast {arg0} to type {DKGProposalCommitConfirmationRequest}")
+		return
+	}
+
+	if err = request.Validate(); err != nil {
+		return
+	}
+
+	participant, err := m.getAwaitingParticipant(request.ParticipantId, internal.CommitAwaitConfirmation)
+	if err != nil {
+		return
+	}
+
+	participant.DkgCommit = make([]byte, len(request.Commit))
+	copy(participant.DkgCommit, request.Commit)
+	participant.Status = internal.CommitConfirmed
+
+	participant.UpdatedAt = request.CreatedAt
+	m.payload.ShareRefreshPayload.UpdatedAt = request.CreatedAt
+
+	m.payload.ShareRefreshQuorumUpdate(request.ParticipantId, participant)
+
+	return
+}
+
+func (m *ShareRefreshFSM) actionValidateShareRefreshAwaitCommits(inEvent fsm.Event, args ...interface{}) (outEvent fsm.Event, response interface{}, err error) {
+	m.payloadMu.Lock()
+	defer m.payloadMu.Unlock()
+
+	if m.payload.ShareRefreshPayload.IsExpired() {
+		outEvent = eventShareRefreshCommitsConfirmationCancelByTimeoutInternal
+		return
+	}
+
+	confirmed, isContainsError := m.countStatuses(internal.CommitConfirmed, internal.CommitConfirmationError)
+
+	if isContainsError {
+		outEvent = eventShareRefreshCommitsConfirmationCancelByErrorInternal
+		return
+	}
+
+	if confirmed < m.payload.ShareRefreshQuorumCount() {
+		return
+	}
+
+	outEvent = eventShareRefreshCommitsConfirmedInternal
+
+	for _, participant := range m.payload.ShareRefreshPayload.Quorum {
+		participant.Status = internal.DealAwaitConfirmation
+	}
+
+	// Make response
+
+	responseData := make(responses.DKGProposalCommitParticipantResponse, 0)
+
+	for _, participant := range m.payload.ShareRefreshPayload.Quorum.GetOrderedParticipants() {
+		responseEntry := &responses.DKGProposalCommitParticipantEntry{
+			ParticipantId: participant.ParticipantID,
+			Username:      participant.Username,
+			DkgCommit:     participant.DkgCommit,
+		}
+		responseData = append(responseData, responseEntry)
+	}
+
+	response = responseData
+
+	return
+}
+
+// Deals
+
+func (m *ShareRefreshFSM) actionDealConfirmationReceived(inEvent fsm.Event, args ...interface{}) (outEvent fsm.Event, response interface{}, err error) {
+	m.payloadMu.Lock()
+	defer m.payloadMu.Unlock()
+
+	if len(args) != 1 {
+		err = errors.New("{arg0} required {DKGProposalDealConfirmationRequest}")
+		return
+	}
+
+	request, ok := args[0].(requests.DKGProposalDealConfirmationRequest)
+
+	if !ok {
+		err = errors.New("cannot cast {arg0} to type {DKGProposalDealConfirmationRequest}")
+		return
+	}
+
+	if err = request.Validate(); err != nil {
+		return
+	}
+
+	participant, err := m.getAwaitingParticipant(request.ParticipantId, internal.DealAwaitConfirmation)
+	if err != nil {
+		return
+	}
+
+	participant.DkgDeal = make([]byte, len(request.Deal))
+	copy(participant.DkgDeal, request.Deal)
+	participant.Status = internal.DealConfirmed
+
+	participant.UpdatedAt = request.CreatedAt
+	m.payload.ShareRefreshPayload.UpdatedAt = request.CreatedAt
+
+	m.payload.ShareRefreshQuorumUpdate(request.ParticipantId, participant)
+
+	return
+}
+
+func (m *ShareRefreshFSM) actionValidateShareRefreshAwaitDeals(inEvent fsm.Event, args ...interface{}) (outEvent fsm.Event, response interface{}, err error) {
+	m.payloadMu.Lock()
+	defer m.payloadMu.Unlock()
+
+	if m.payload.ShareRefreshPayload.IsExpired() {
+		outEvent = eventShareRefreshDealsConfirmationCancelByTimeoutInternal
+		return
+	}
+
+	confirmed, isContainsError := m.countStatuses(internal.DealConfirmed, internal.DealConfirmationError)
+
+	if isContainsError {
+		outEvent = eventShareRefreshDealsConfirmationCancelByErrorInternal
+		return
+	}
+
+	if confirmed < m.payload.ShareRefreshQuorumCount() {
+		return
+	}
+
+	outEvent = eventShareRefreshDealsConfirmedInternal
+
+	for _, participant := range m.payload.ShareRefreshPayload.Quorum {
+		participant.Status = internal.ResponseAwaitConfirmation
+	}
+
+	// Make response
+
+	responseData := make(responses.DKGProposalDealParticipantResponse, 0)
+
+	for _, participant := range m.payload.ShareRefreshPayload.Quorum.GetOrderedParticipants() {
+		if len(participant.DkgDeal) == 0 {
+			continue
+		}
+		responseEntry := &responses.DKGProposalDealParticipantEntry{
+			ParticipantId: participant.ParticipantID,
+			Username:      participant.Username,
+			DkgDeal:       participant.DkgDeal,
+		}
+		responseData = append(responseData, responseEntry)
+	}
+
+	response = responseData
+
+	return
+}
+
+// Responses
+
+func (m *ShareRefreshFSM) actionResponseConfirmationReceived(inEvent fsm.Event, args ...interface{}) (outEvent fsm.Event, response interface{}, err error) {
+	m.payloadMu.Lock()
+	defer m.payloadMu.Unlock()
+
+	if len(args) != 1 {
+		err = errors.New("{arg0} required {DKGProposalResponseConfirmationRequest}")
+		return
+	}
+
+	request, ok := args[0].(requests.DKGProposalResponseConfirmationRequest)
+
+	if !ok {
+		err = errors.New("cannot cast {arg0} to type {DKGProposalResponseConfirmationRequest}")
+		return
+	}
+
+	if err = request.Validate(); err != nil {
+		return
+	}
+
+	participant, err := m.getAwaitingParticipant(request.ParticipantId, internal.ResponseAwaitConfirmation)
+	if err != nil {
+		return
+	}
+
+	participant.DkgResponse = make([]byte, len(request.Response))
+	copy(participant.DkgResponse, request.Response)
+	participant.Status = internal.ResponseConfirmed
+
+	participant.UpdatedAt = request.CreatedAt
+	m.payload.ShareRefreshPayload.UpdatedAt = request.CreatedAt
+
+	m.payload.ShareRefreshQuorumUpdate(request.ParticipantId, participant)
+
+	return
+}
+
+func (m *ShareRefreshFSM) actionValidateShareRefreshAwaitResponses(inEvent fsm.Event, args ...interface{}) (outEvent fsm.Event, response interface{}, err error) {
+	m.payloadMu.Lock()
+	defer m.payloadMu.Unlock()
+
+	if m.payload.ShareRefreshPayload.IsExpired() {
+		outEvent = eventShareRefreshResponsesConfirmationCancelByTimeoutInternal
+		return
+	}
+
+	confirmed, isContainsError := m.countStatuses(internal.ResponseConfirmed, internal.ResponseConfirmationError)
+
+	if isContainsError {
+		outEvent = eventShareRefreshResponsesConfirmationCancelByErrorInternal
+		return
+	}
+
+	if confirmed < m.payload.ShareRefreshQuorumCount() {
+		return
+	}
+
+	outEvent = eventShareRefreshResponsesConfirmedInternal
+
+	for _, participant := range m.payload.ShareRefreshPayload.Quorum {
+		participant.Status = internal.MasterKeyAwaitConfirmation
+	}
+
+	// Make response
+
+	responseData := make(responses.DKGProposalResponseParticipantResponse, 0)
+
+	for _, participant := range m.payload.ShareRefreshPayload.Quorum.GetOrderedParticipants() {
+		responseEntry := &responses.DKGProposalResponseParticipantEntry{
+			ParticipantId: participant.ParticipantID,
+			Username:      participant.Username,
+			DkgResponse:   participant.DkgResponse,
+		}
+		responseData = append(responseData, responseEntry)
+	}
+
+	response = responseData
+
+	return
+}
+
+// Keyring
+
+// actionKeyringConfirmationReceived stores the master key of the refreshed keyring, it must be the master key
+// of the DKG round, and the public polynomial, it must be the same for all participants
+func (m *ShareRefreshFSM) actionKeyringConfirmationReceived(inEvent fsm.Event, args ...interface{}) (outEvent fsm.Event, response interface{}, err error) {
+	m.payloadMu.Lock()
+	defer m.payloadMu.Unlock()
+
+	if len(args) != 1 {
+		err = errors.New("{arg0} required {DKGProposalMasterKeyConfirmationRequest}")
+		return
+	}
+
+	request, ok := args[0].(requests.DKGProposalMasterKeyConfirmationRequest)
+
+	if !ok {
+		err = errors.New("cannot cast {arg0} to type {DKGProposalMasterKeyConfirmationRequest}")
+		return
+	}
+
+	if err = request.Validate(); err != nil {
+		return
+	}
+
+	participant, err := m.getAwaitingParticipant(request.ParticipantId, internal.MasterKeyAwaitConfirmation)
+	if err != nil {
+		return
+	}
+
+	participant.DkgMasterKey = make([]byte, len(request.MasterKey))
+	copy(participant.DkgMasterKey, request.MasterKey)
+	participant.Status = internal.MasterKeyConfirmed
+
+	if !bytes.Equal(request.MasterKey, m.dkgMasterKey()) {
+		participant.Status = internal.MasterKeyConfirmationError
+		participant.Error = requests.NewFSMError(errors.New("master key is changed by the refresh"))
+	} else if m.payload.ShareRefreshPayload.PubPolyBz == nil {
+		m.payload.ShareRefreshPayload.PubPolyBz = request.PubPolyBz
+	} else if !bytes.Equal(request.PubPolyBz, m.payload.ShareRefreshPayload.PubPolyBz) {
+		participant.Status = internal.MasterKeyConfirmationError
+		participant.Error = requests.NewFSMError(errors.New("public polynomial is mismatched"))
+	}
+
+	participant.UpdatedAt = request.CreatedAt
+	m.payload.ShareRefreshPayload.UpdatedAt = request.CreatedAt
+
+	m.payload.ShareRefreshQuorumUpdate(request.ParticipantId, participant)
+
+	return
+}
+
+func (m *ShareRefreshFSM) actionValidateShareRefreshAwaitKeyring(inEvent fsm.Event, args ...interface{}) (outEvent fsm.Event, response interface{}, err error) {
+	m.payloadMu.Lock()
+	defer m.payloadMu.Unlock()
+
+	if m.payload.ShareRefreshPayload.IsExpired() {
+		outEvent = eventShareRefreshKeyringConfirmationCancelByTimeoutInternal
+		return
+	}
+
+	confirmed, isContainsError := m.countStatuses(internal.MasterKeyConfirmed, internal.MasterKeyConfirmationError)
+
+	if isContainsError {
+		outEvent = eventShareRefreshKeyringConfirmationCancelByErrorInternal
+		return
+	}
+
+	if confirmed < m.payload.ShareRefreshQuorumCount() {
+		return
+	}
+
+	outEvent = eventShareRefreshKeyringConfirmedInternal
+
+	// The refreshed keyring is the keyring of the round from now on
+	m.payload.DKGProposalPayload.PubPolyBz = m.payload.ShareRefreshPayload.PubPolyBz
+	m.payload.DKGProposalPayload.KeyringVersion = m.payload.ShareRefreshPayload.KeyringVersion
+
+	return
+}
+
+// countStatuses returns the number of participants with the confirmed status
+// and whether any participant has the error status
+func (m *ShareRefreshFSM) countStatuses(confirmedStatus, errorStatus internal.DKGParticipantStatus) (confirmed int, isContainsError bool) {
+	for _, participant := range m.payload.ShareRefreshPayload.Quorum {
+		switch participant.Status {
+		case errorStatus:
+			isContainsError = true
+		case confirmedStatus:
+			confirmed++
+		}
+	}
+	return
+}
+
+// dkgMasterKey returns the master key confirmed by the DKG participants
+func (m *ShareRefreshFSM) dkgMasterKey() []byte {
+	for _, participant := range m.payload.DKGProposalPayload.Quorum {
+		if len(participant.DkgMasterKey) != 0 {
+			return participant.DkgMasterKey
+		}
+	}
+	return nil
+}
+
+// Errors
+
+func (m *ShareRefreshFSM) actionConfirmationError(inEvent fsm.Event, args ...interface{}) (outEvent fsm.Event, response interface{}, err error) {
+	m.payloadMu.Lock()
+	defer m.payloadMu.Unlock()
+
+	if len(args) != 1 {
+		err = errors.New("{arg0} required {DKGProposalConfirmationErrorRequest}")
+		return
+	}
+
+	request, ok := args[0].(requests.DKGProposalConfirmationErrorRequest)
+
+	if !ok {
+		err = errors.New("cannot cast {arg0} to type {DKGProposalConfirmationErrorRequest}")
+		return
+	}
+
+	if err = request.Validate(); err != nil {
+		return
+	}
+
+	if !m.payload.ShareRefreshQuorumExists(request.ParticipantId) {
+		err = errors.New("{ParticipantId} not exist in quorum")
+		return
+	}
+
+	var awaitStatus, errorStatus internal.DKGParticipantStatus
+	switch inEvent {
+	case EventShareRefreshCommitConfirmationError:
+		awaitStatus, errorStatus = internal.CommitAwaitConfirmation, internal.CommitConfirmationError
+	case EventShareRefreshDealConfirmationError:
+		awaitStatus, errorStatus = internal.DealAwaitConfirmation, internal.DealConfirmationError
+	case EventShareRefreshResponseConfirmationError:
+		awaitStatus, errorStatus = internal.ResponseAwaitConfirmation, internal.ResponseConfirmationError
+	case EventShareRefreshKeyringConfirmationError:
+		awaitStatus, errorStatus = internal.MasterKeyAwaitConfirmation, internal.MasterKeyConfirmationError
+	default:
+		err = fmt.Errorf("{%s} event cannot be used for action {actionConfirmationError}", inEvent)
+		return
+	}
+
+	participant := m.payload.ShareRefreshQuorumGet(request.ParticipantId)
+
+	switch participant.Status {
+	case awaitStatus:
+		participant.Status = errorStatus
+	case errorStatus:
+		err = fmt.Errorf("{Status} already has {\"%s\"}", errorStatus)
+		return
+	default:
+		err = fmt.Errorf("{Status} now is \"%s\" and cannot set to {\"%s\"}", participant.Status, errorStatus)
+		return
+	}
+
+	participant.Error = request.Error
+
+	participant.UpdatedAt = request.CreatedAt
+	m.payload.ShareRefreshPayload.UpdatedAt = request.CreatedAt
+
+	m.payload.ShareRefreshQuorumUpdate(request.ParticipantId, participant)
+
+	return
+}
+
+// actionTimeout records the timeout of the current stage posted after the refresh deadline, the stage is canceled
+// when enough participants posted it, so a single participant can't cancel it
+func (m *ShareRefreshFSM) actionTimeout(inEvent fsm.Event, args ...interface{}) (outEvent fsm.Event, response interface{}, err error) {
+	m.payloadMu.Lock()
+	defer m.payloadMu.Unlock()
+
+	if len(args) != 1 {
+		err = errors.New("{arg0} required {TimeoutRequest}")
+		return
+	}
+
+	request, ok := args[0].(requests.TimeoutRequest)
+
+	if !ok {
+		err = errors.New("cannot cast {arg0} to type {TimeoutRequest}")
+		return
+	}
+
+	if err = request.Validate(); err != nil {
+		return
+	}
+
+	if request.CreatedAt.Before(m.payload.ShareRefreshPayload.ExpiresAt) {
+		err = fmt.Errorf("cannot cancel by timeout before {ExpiresAt} = {\"%s\"}", m.payload.ShareRefreshPayload.ExpiresAt)
+		return
+	}
+
+	votes := m.payload.AddTimeoutVote(inEvent, m.payload.ShareRefreshPayload.ExpiresAt, request.Username)
+	if votes < internal.TimeoutQuorum(m.payload.ShareRefreshQuorumCount(), m.payload.GetThreshold()) {
+		return
+	}
+
+	m.payload.ShareRefreshPayload.UpdatedAt = request.CreatedAt
+	outEvent = timeoutCancelEvents[inEvent]
+
+	return
+}
+
+// Finish
+
+// actionFinishShareRefresh returns the round to signing, the refresh payload is kept until the next refresh
+// for the history of the round
+func (m *ShareRefreshFSM) actionFinishShareRefresh(inEvent fsm.Event, args ...interface{}) (outEvent fsm.Event, response interface{}, err error) {
+	m.payloadMu.Lock()
+	defer m.payloadMu.Unlock()
+
+	if len(args) != 1 {
+		err = errors.New("{arg0} required {DefaultRequest}")
+		return
+	}
+
+	request, ok := args[0].(requests.DefaultRequest)
+
+	if !ok {
+		err = errors.New("cannot cast {arg0} to type {DefaultRequest}")
+		return
+	}
+
+	if err = request.Validate(); err != nil {
+		return
+	}
+
+	m.payload.ShareRefreshPayload.UpdatedAt = request.CreatedAt
+
+	return
+}
@@ -0,0 +1,203 @@
+package share_refresh_fsm
+
+import (
+	"sync"
+
+	"github.com/lidofinance/dc4bc/fsm/fsm"
+	"github.com/lidofinance/dc4bc/fsm/state_machines/internal"
+	sif "github.com/lidofinance/dc4bc/fsm/state_machines/signing_proposal_fsm"
+)
+
+// The share refresh runs a resharing of the DKG master key among the participants of the round: every participant
+// deals its current share, and the new shares are the shares of the same master key, so the public key
+// is not changed, but the old shares are useless with the new ones. The stages are the same as the DKG ones
+const (
+	FsmName = "share_refresh_fsm"
+
+	StateShareRefreshInitial = sif.StateShareRefreshProposed
+
+	// Sending commits
+	StateShareRefreshCommitsAwaitConfirmations = fsm.State("state_share_refresh_commits_await_confirmations")
+	// Canceled
+	StateShareRefreshCommitsAwaitCanceledByError   = fsm.State("state_share_refresh_commits_await_canceled_by_error")
+	StateShareRefreshCommitsAwaitCanceledByTimeout = fsm.State("state_share_refresh_commits_await_canceled_by_timeout")
+
+	// Sending deals
+	StateShareRefreshDealsAwaitConfirmations = fsm.State("state_share_refresh_deals_await_confirmations")
+	// Canceled
+	StateShareRefreshDealsAwaitCanceledByError   = fsm.State("state_share_refresh_deals_await_canceled_by_error")
+	StateShareRefreshDealsAwaitCanceledByTimeout = fsm.State("state_share_refresh_deals_await_canceled_by_timeout")
+
+	// Sending responses
+	StateShareRefreshResponsesAwaitConfirmations = fsm.State("state_share_refresh_responses_await_confirmations")
+	// Canceled
+	StateShareRefreshResponsesAwaitCanceledByError   = fsm.State("state_share_refresh_responses_await_canceled_by_error")
+	StateShareRefreshResponsesAwaitCanceledByTimeout = fsm.State("state_share_refresh_responses_await_canceled_by_timeout")
+
+	// Confirming the new keyring, its master key must be the same
+	StateShareRefreshKeyringAwaitConfirmations     = fsm.State("state_share_refresh_keyring_await_confirmations")
+	StateShareRefreshKeyringAwaitCanceledByError   = fsm.State("state_share_refresh_keyring_await_canceled_by_error")
+	StateShareRefreshKeyringAwaitCanceledByTimeout = fsm.State("state_share_refresh_keyring_await_canceled_by_timeout")
+
+	StateShareRefreshKeyringCollected = fsm.State("state_share_refresh_keyring_collected")
+
+	// Events
+	EventShareRefreshInit = fsm.Event("event_share_refresh_init")
+
+	EventShareRefreshCommitConfirmationReceived                 = fsm.Event("event_share_refresh_commit_confirm_received")
+	EventShareRefreshCommitConfirmationError                    = fsm.Event("event_share_refresh_commit_confirm_canceled_by_error")
+	eventShareRefreshCommitsConfirmationCancelByTimeoutInternal = fsm.Event("event_share_refresh_commits_confirm_canceled_by_timeout_internal")
+	eventShareRefreshCommitsConfirmationCancelByErrorInternal   = fsm.Event("event_share_refresh_commits_confirm_canceled_by_error_internal")
+	eventShareRefreshCommitsConfirmedInternal                   = fsm.Event("event_share_refresh_commits_confirmed_internal")
+	eventAutoShareRefreshValidateCommitsInternal                = fsm.Event("event_share_refresh_commits_validate_internal")
+
+	EventShareRefreshDealConfirmationReceived                 = fsm.Event("event_share_refresh_deal_confirm_received")
+	EventShareRefreshDealConfirmationError                    = fsm.Event("event_share_refresh_deal_confirm_canceled_by_error")
+	eventShareRefreshDealsConfirmationCancelByTimeoutInternal = fsm.Event("event_share_refresh_deals_confirm_canceled_by_timeout_internal")
+	eventShareRefreshDealsConfirmationCancelByErrorInternal   = fsm.Event("event_share_refresh_deals_confirm_canceled_by_error_internal")
+	eventShareRefreshDealsConfirmedInternal                   = fsm.Event("event_share_refresh_deals_confirmed_internal")
+	eventAutoShareRefreshValidateDealsInternal                = fsm.Event("event_share_refresh_deals_validate_internal")
+
+	EventShareRefreshResponseConfirmationReceived                 = fsm.Event("event_share_refresh_response_confirm_received")
+	EventShareRefreshResponseConfirmationError                    = fsm.Event("event_share_refresh_response_confirm_canceled_by_error")
+	eventShareRefreshResponsesConfirmationCancelByTimeoutInternal = fsm.Event("event_share_refresh_responses_confirm_canceled_by_timeout_internal")
+	eventShareRefreshResponsesConfirmationCancelByErrorInternal   = fsm.Event("event_share_refresh_responses_confirm_canceled_by_error_internal")
+	eventShareRefreshResponsesConfirmedInternal                   = fsm.Event("event_share_refresh_responses_confirmed_internal")
+	eventAutoShareRefreshValidateResponsesInternal                = fsm.Event("event_share_refresh_responses_validate_internal")
+
+	EventShareRefreshKeyringConfirmationReceived                = fsm.Event("event_share_refresh_keyring_confirm_received")
+	EventShareRefreshKeyringConfirmationError                   = fsm.Event("event_share_refresh_keyring_confirm_canceled_by_error")
+	eventShareRefreshKeyringConfirmationCancelByTimeoutInternal = fsm.Event("event_share_refresh_keyring_confirm_canceled_by_timeout_internal")
+	eventShareRefreshKeyringConfirmationCancelByErrorInternal   = fsm.Event("event_share_refresh_keyring_confirm_canceled_by_error_internal")
+	eventShareRefreshKeyringConfirmedInternal                   = fsm.Event("event_share_refresh_keyring_confirmed_internal")
+	eventAutoShareRefreshValidateKeyringInternal                = fsm.Event("event_share_refresh_keyring_validate_internal")
+
+	// Posted by nodes when the deadline has passed
+	EventShareRefreshCommitsTimeout   = fsm.Event("event_share_refresh_commits_timeout")
+	EventShareRefreshDealsTimeout     = fsm.Event("event_share_refresh_deals_timeout")
+	EventShareRefreshResponsesTimeout = fsm.Event("event_share_refresh_responses_timeout")
+	EventShareRefreshKeyringTimeout   = fsm.Event("event_share_refresh_keyring_timeout")
+
+	// Returns the round to signing, whether the refresh is finished or canceled
+	EventShareRefreshFinish = fsm.Event("event_share_refresh_finish")
+)
+
+// timeoutCancelEvents cancel the stage when enough participants posted its timeout
+var timeoutCancelEvents = map[fsm.Event]fsm.Event{
+	EventShareRefreshCommitsTimeout:   eventShareRefreshCommitsConfirmationCancelByTimeoutInternal,
+	EventShareRefreshDealsTimeout:     eventShareRefreshDealsConfirmationCancelByTimeoutInternal,
+	EventShareRefreshResponsesTimeout: eventShareRefreshResponsesConfirmationCancelByTimeoutInternal,
+	EventShareRefreshKeyringTimeout:   eventShareRefreshKeyringConfirmationCancelByTimeoutInternal,
+}
+
+type ShareRefreshFSM struct {
+	*fsm.FSM
+	payload   *internal.DumpedMachineStatePayload
+	payloadMu sync.RWMutex
+}
+
+func New() internal.DumpedMachineProvider {
+	machine := &ShareRefreshFSM{}
+
+	machine.FSM = fsm.MustNewFSM(
+		FsmName,
+		StateShareRefreshInitial,
+		[]fsm.EventDesc{
+			{Name: EventShareRefreshInit, SrcState: []fsm.State{StateShareRefreshInitial}, DstState: StateShareRefreshCommitsAwaitConfirmations},
+
+			// Commits
+			{Name: EventShareRefreshCommitConfirmationReceived, SrcState: []fsm.State{StateShareRefreshCommitsAwaitConfirmations}, DstState: StateShareRefreshCommitsAwaitConfirmations},
+			// Canceled
+			{Name: EventShareRefreshCommitConfirmationError, SrcState: []fsm.State{StateShareRefreshCommitsAwaitConfirmations, StateShareRefreshCommitsAwaitCanceledByError}, DstState: StateShareRefreshCommitsAwaitCanceledByError},
+			{Name: eventShareRefreshCommitsConfirmationCancelByErrorInternal, SrcState: []fsm.State{StateShareRefreshCommitsAwaitConfirmations}, DstState: StateShareRefreshCommitsAwaitCanceledByError, IsInternal: true},
+			{Name: eventShareRefreshCommitsConfirmationCancelByTimeoutInternal, SrcState: []fsm.State{StateShareRefreshCommitsAwaitConfirmations}, DstState: StateShareRefreshCommitsAwaitCanceledByTimeout, IsInternal: true},
+			{Name: EventShareRefreshCommitsTimeout, SrcState: []fsm.State{StateShareRefreshCommitsAwaitConfirmations}, DstState: StateShareRefreshCommitsAwaitConfirmations},
+
+			{Name: eventAutoShareRefreshValidateCommitsInternal, SrcState: []fsm.State{StateShareRefreshCommitsAwaitConfirmations}, DstState: StateShareRefreshCommitsAwaitConfirmations, IsInternal: true, IsAuto: true},
+
+			// Confirmed
+			{Name: eventShareRefreshCommitsConfirmedInternal, SrcState: []fsm.State{StateShareRefreshCommitsAwaitConfirmations}, DstState: StateShareRefreshDealsAwaitConfirmations, IsInternal: true},
+
+			// Deals
+			{Name: EventShareRefreshDealConfirmationReceived, SrcState: []fsm.State{StateShareRefreshDealsAwaitConfirmations}, DstState: StateShareRefreshDealsAwaitConfirmations},
+			// Canceled
+			{Name: EventShareRefreshDealConfirmationError, SrcState: []fsm.State{StateShareRefreshDealsAwaitConfirmations, StateShareRefreshDealsAwaitCanceledByError}, DstState: StateShareRefreshDealsAwaitCanceledByError},
+			{Name: eventShareRefreshDealsConfirmationCancelByErrorInternal, SrcState: []fsm.State{StateShareRefreshDealsAwaitConfirmations}, DstState: StateShareRefreshDealsAwaitCanceledByError, IsInternal: true},
+			{Name: eventShareRefreshDealsConfirmationCancelByTimeoutInternal, SrcState: []fsm.State{StateShareRefreshDealsAwaitConfirmations}, DstState: StateShareRefreshDealsAwaitCanceledByTimeout, IsInternal: true},
+			{Name: EventShareRefreshDealsTimeout, SrcState: []fsm.State{StateShareRefreshDealsAwaitConfirmations}, DstState: StateShareRefreshDealsAwaitConfirmations},
+
+			{Name: eventAutoShareRefreshValidateDealsInternal, SrcState: []fsm.State{StateShareRefreshDealsAwaitConfirmations}, DstState: StateShareRefreshDealsAwaitConfirmations, IsInternal: true, IsAuto: true},
+
+			{Name: eventShareRefreshDealsConfirmedInternal, SrcState: []fsm.State{StateShareRefreshDealsAwaitConfirmations}, DstState: StateShareRefreshResponsesAwaitConfirmations, IsInternal: true},
+
+			// Responses
+			{Name: EventShareRefreshResponseConfirmationReceived, SrcState: []fsm.State{StateShareRefreshResponsesAwaitConfirmations}, DstState: StateShareRefreshResponsesAwaitConfirmations},
+			// Canceled
+			{Name: EventShareRefreshResponseConfirmationError, SrcState: []fsm.State{StateShareRefreshResponsesAwaitConfirmations, StateShareRefreshResponsesAwaitCanceledByError}, DstState: StateShareRefreshResponsesAwaitCanceledByError},
+			{Name: eventShareRefreshResponsesConfirmationCancelByErrorInternal, SrcState: []fsm.State{StateShareRefreshResponsesAwaitConfirmations}, DstState: StateShareRefreshResponsesAwaitCanceledByError, IsInternal: true},
+			{Name: eventShareRefreshResponsesConfirmationCancelByTimeoutInternal, SrcState: []fsm.State{StateShareRefreshResponsesAwaitConfirmations}, DstState: StateShareRefreshResponsesAwaitCanceledByTimeout, IsInternal: true},
+			{Name: EventShareRefreshResponsesTimeout, SrcState: []fsm.State{StateShareRefreshResponsesAwaitConfirmations}, DstState: StateShareRefreshResponsesAwaitConfirmations},
+
+			{Name: eventAutoShareRefreshValidateResponsesInternal, SrcState: []fsm.State{StateShareRefreshResponsesAwaitConfirmations}, DstState: StateShareRefreshResponsesAwaitConfirmations, IsInternal: true, IsAuto: true},
+
+			{Name: eventShareRefreshResponsesConfirmedInternal, SrcState: []fsm.State{StateShareRefreshResponsesAwaitConfirmations}, DstState: StateShareRefreshKeyringAwaitConfirmations, IsInternal: true},
+
+			// Keyring
+			{Name: EventShareRefreshKeyringConfirmationReceived, SrcState: []fsm.State{StateShareRefreshKeyringAwaitConfirmations}, DstState: StateShareRefreshKeyringAwaitConfirmations},
+			// Canceled
+			{Name: EventShareRefreshKeyringConfirmationError, SrcState: []fsm.State{StateShareRefreshKeyringAwaitConfirmations, StateShareRefreshKeyringAwaitCanceledByError}, DstState: StateShareRefreshKeyringAwaitCanceledByError},
+			{Name: eventShareRefreshKeyringConfirmationCancelByErrorInternal, SrcState: []fsm.State{StateShareRefreshKeyringAwaitConfirmations}, DstState: StateShareRefreshKeyringAwaitCanceledByError, IsInternal: true},
+			{Name: eventShareRefreshKeyringConfirmationCancelByTimeoutInternal, SrcState: []fsm.State{StateShareRefreshKeyringAwaitConfirmations}, DstState: StateShareRefreshKeyringAwaitCanceledByTimeout, IsInternal: true},
+			{Name: EventShareRefreshKeyringTimeout, SrcState: []fsm.State{StateShareRefreshKeyringAwaitConfirmations}, DstState: StateShareRefreshKeyringAwaitConfirmations},
+
+			{Name: eventAutoShareRefreshValidateKeyringInternal, SrcState: []fsm.State{StateShareRefreshKeyringAwaitConfirmations}, DstState: StateShareRefreshKeyringAwaitConfirmations, IsInternal: true, IsAuto: true},
+
+			// Done
+			{Name: eventShareRefreshKeyringConfirmedInternal, SrcState: []fsm.State{StateShareRefreshKeyringAwaitConfirmations}, DstState: StateShareRefreshKeyringCollected, IsInternal: true},
+
+			{Name: EventShareRefreshFinish, SrcState: []fsm.State{
+				StateShareRefreshKeyringCollected,
+				StateShareRefreshCommitsAwaitCanceledByError, StateShareRefreshCommitsAwaitCanceledByTimeout,
+				StateShareRefreshDealsAwaitCanceledByError, StateShareRefreshDealsAwaitCanceledByTimeout,
+				StateShareRefreshResponsesAwaitCanceledByError, StateShareRefreshResponsesAwaitCanceledByTimeout,
+				StateShareRefreshKeyringAwaitCanceledByError, StateShareRefreshKeyringAwaitCanceledByTimeout,
+			}, DstState: sif.StateSigningIdle},
+		},
+		fsm.Callbacks{
+			EventShareRefreshInit: machine.actionInitShareRefresh,
+
+			EventShareRefreshCommitConfirmationReceived:  machine.actionCommitConfirmationReceived,
+			EventShareRefreshCommitConfirmationError:     machine.actionConfirmationError,
+			eventAutoShareRefreshValidateCommitsInternal: machine.actionValidateShareRefreshAwaitCommits,
+
+			EventShareRefreshDealConfirmationReceived:  machine.actionDealConfirmationReceived,
+			EventShareRefreshDealConfirmationError:     machine.actionConfirmationError,
+			eventAutoShareRefreshValidateDealsInternal: machine.actionValidateShareRefreshAwaitDeals,
+
+			EventShareRefreshResponseConfirmationReceived:  machine.actionResponseConfirmationReceived,
+			EventShareRefreshResponseConfirmationError:     machine.actionConfirmationError,
+			eventAutoShareRefreshValidateResponsesInternal: machine.actionValidateShareRefreshAwaitResponses,
+
+			EventShareRefreshKeyringConfirmationReceived: machine.actionKeyringConfirmationReceived,
+			EventShareRefreshKeyringConfirmationError:    machine.actionConfirmationError,
+			eventAutoShareRefreshValidateKeyringInternal: machine.actionValidateShareRefreshAwaitKeyring,
+
+			EventShareRefreshCommitsTimeout:   machine.actionTimeout,
+			EventShareRefreshDealsTimeout:     machine.actionTimeout,
+			EventShareRefreshResponsesTimeout: machine.actionTimeout,
+			EventShareRefreshKeyringTimeout:   machine.actionTimeout,
+
+			EventShareRefreshFinish: machine.actionFinishShareRefresh,
+		},
+	)
+	return machine
+}
+
+func (m *ShareRefreshFSM) WithSetup(state fsm.State, payload *internal.DumpedMachineStatePayload) internal.DumpedMachineProvider {
+	m.payloadMu.Lock()
+	defer m.payloadMu.Unlock()
+
+	m.payload = payload
+	m.FSM = m.FSM.MustCopyWithState(state)
+	return m
+}
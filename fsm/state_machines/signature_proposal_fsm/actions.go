@@ -4,7 +4,6 @@ import (
 	"errors"
 	"fmt"
 
-	"github.com/lidofinance/dc4bc/fsm/config"
 	"github.com/lidofinance/dc4bc/fsm/fsm"
 	"github.com/lidofinance/dc4bc/fsm/state_machines/internal"
 	"github.com/lidofinance/dc4bc/fsm/types/requests"
@@ -33,10 +32,11 @@ func (m *SignatureProposalFSM) actionInitSignatureProposal(inEvent fsm.Event, ar
 		return
 	}
 
+	m.payload.Deadlines = request.Deadlines
 	m.payload.SignatureProposalPayload = &internal.SignatureConfirmation{
 		Quorum:    make(internal.SignatureProposalQuorum),
 		CreatedAt: request.CreatedAt,
-		ExpiresAt: request.CreatedAt.Add(config.SignatureProposalConfirmationDeadline),
+		ExpiresAt: request.CreatedAt.Add(m.payload.SignatureProposalDeadline()),
 	}
 
 	for index, participant := range request.Participants {
@@ -71,6 +71,7 @@ func (m *SignatureProposalFSM) actionInitSignatureProposal(inEvent fsm.Event, ar
 			Threshold:     participant.Threshold,
 			DkgPubKey:     participant.DkgPubKey,
 			PubKey:        participant.PubKey,
+			Deadlines:     m.payload.Deadlines,
 		}
 		responseData = append(responseData, responseEntry)
 	}
@@ -105,7 +106,7 @@ func (m *SignatureProposalFSM) actionProposalResponseByParticipant(inEvent fsm.E
 	}
 
 	signatureProposalParticipant := m.payload.SigQuorumGet(request.ParticipantId)
-	if signatureProposalParticipant.UpdatedAt.Add(config.SignatureProposalConfirmationDeadline).Before(request.CreatedAt) {
+	if signatureProposalParticipant.UpdatedAt.Add(m.payload.SignatureProposalDeadline()).Before(request.CreatedAt) {
 		outEvent = eventSetValidationCanceledByTimeout
 		return
 	}
@@ -120,6 +121,7 @@ func (m *SignatureProposalFSM) actionProposalResponseByParticipant(inEvent fsm.E
 		signatureProposalParticipant.Status = internal.SigConfirmationConfirmed
 	case EventDeclineProposal:
 		signatureProposalParticipant.Status = internal.SigConfirmationDeclined
+		signatureProposalParticipant.DeclineReason = request.Reason
 	default:
 		err = fmt.Errorf("unsupported event for action {inEvent} = {\"%s\"}", inEvent)
 		return
@@ -133,6 +135,44 @@ func (m *SignatureProposalFSM) actionProposalResponseByParticipant(inEvent fsm.E
 	return
 }
 
+// actionProposalTimeout records the timeout of the proposal posted after the deadline, the proposal is canceled
+// when enough participants posted it, so a single participant can't cancel it
+func (m *SignatureProposalFSM) actionProposalTimeout(inEvent fsm.Event, args ...interface{}) (outEvent fsm.Event, response interface{}, err error) {
+	m.payloadMu.Lock()
+	defer m.payloadMu.Unlock()
+
+	if len(args) != 1 {
+		err = errors.New("{arg0} required {TimeoutRequest}")
+		return
+	}
+
+	request, ok := args[0].(requests.TimeoutRequest)
+
+	if !ok {
+		err = errors.New("cannot cast {arg0} to type {TimeoutRequest}")
+		return
+	}
+
+	if err = request.Validate(); err != nil {
+		return
+	}
+
+	if request.CreatedAt.Before(m.payload.SignatureProposalPayload.ExpiresAt) {
+		err = fmt.Errorf("cannot cancel by timeout before {ExpiresAt} = {\"%s\"}", m.payload.SignatureProposalPayload.ExpiresAt)
+		return
+	}
+
+	votes := m.payload.AddTimeoutVote(inEvent, m.payload.SignatureProposalPayload.ExpiresAt, request.Username)
+	if votes < internal.TimeoutQuorum(m.payload.SigQuorumCount(), m.payload.GetThreshold()) {
+		return
+	}
+
+	m.payload.SignatureProposalPayload.UpdatedAt = request.CreatedAt
+	outEvent = timeoutCancelEvents[inEvent]
+
+	return
+}
+
 func (m *SignatureProposalFSM) actionValidateSignatureProposal(fsm.Event, ...interface{}) (outEvent fsm.Event, response interface{}, err error) {
 	var (
 		isContainsDecline bool
@@ -179,3 +219,104 @@ func (m *SignatureProposalFSM) actionValidateSignatureProposal(fsm.Event, ...int
 
 	return eventSetProposalValidatedInternal, responseData, nil
 }
+
+// actionInitResharingProposal stores the proposal of a resharing of another round, the new committee members
+// are the first participants of the round and the dealers who are not its members follow them
+func (m *SignatureProposalFSM) actionInitResharingProposal(inEvent fsm.Event, args ...interface{}) (outEvent fsm.Event, response interface{}, err error) {
+	m.payloadMu.Lock()
+	defer m.payloadMu.Unlock()
+
+	if len(args) != 1 {
+		err = errors.New("{arg0} required {ResharingProposalRequest}")
+		return
+	}
+
+	request, ok := args[0].(requests.ResharingProposalRequest)
+
+	if !ok {
+		err = errors.New("cannot cast {arg0} to type {ResharingProposalRequest}")
+		return
+	}
+
+	if err = request.Validate(); err != nil {
+		return
+	}
+
+	m.payload.Deadlines = request.Deadlines
+	m.payload.Threshold = request.SigningThreshold
+	m.payload.ResharingPayload = &internal.ResharingConfirmation{
+		OldDkgID:          request.OldDkgID,
+		OldThreshold:      request.OldThreshold,
+		OldKeyringVersion: request.OldKeyringVersion,
+		OldPubPolyBz:      request.OldPubPolyBz,
+		OldMasterKey:      request.OldMasterKey,
+		OldParticipants:   make(map[int]*internal.ResharingOldParticipant),
+		Dealers:           make(map[int]int),
+		Receivers:         len(request.Participants),
+		Threshold:         request.SigningThreshold,
+		Proposal:          make(internal.SignatureProposalQuorum),
+		CreatedAt:         request.CreatedAt,
+		UpdatedAt:         request.CreatedAt,
+		ExpiresAt:         request.CreatedAt.Add(m.payload.SignatureProposalDeadline()),
+	}
+	resharing := m.payload.ResharingPayload
+
+	addParticipant := func(username string, pubKey, dkgPubKey []byte) {
+		participantID := len(resharing.Proposal)
+		resharing.Proposal[participantID] = &internal.SignatureProposalParticipant{
+			Username:  username,
+			PubKey:    pubKey,
+			DkgPubKey: dkgPubKey,
+			Status:    internal.SigConfirmationAwaitConfirmation,
+			Threshold: request.SigningThreshold,
+			UpdatedAt: request.CreatedAt,
+		}
+
+		m.payload.SetPubKeyUsername(username, pubKey)
+		m.payload.SetIDUsername(username, participantID)
+	}
+
+	for _, participant := range request.Participants {
+		addParticipant(participant.Username, participant.PubKey, participant.DkgPubKey)
+	}
+
+	oldParticipants := make(map[string]*requests.ResharingOldParticipantEntry)
+	for _, participant := range request.OldParticipants {
+		resharing.OldParticipants[participant.ParticipantId] = &internal.ResharingOldParticipant{
+			Username:  participant.Username,
+			DkgPubKey: participant.DkgPubKey,
+		}
+		oldParticipants[participant.Username] = participant
+	}
+
+	for _, dealer := range request.Dealers {
+		oldParticipant := oldParticipants[dealer]
+		if _, isReceiver := m.payload.IDs[dealer]; !isReceiver {
+			addParticipant(oldParticipant.Username, oldParticipant.PubKey, oldParticipant.DkgPubKey)
+		}
+		resharing.Dealers[m.payload.IDs[dealer]] = oldParticipant.ParticipantId
+	}
+
+	// Make response
+
+	responseData := responses.ResharingProposalInvitationResponse{
+		OldDkgID:     resharing.OldDkgID,
+		OldThreshold: resharing.OldThreshold,
+		Dealers:      request.Dealers,
+		Participants: make(responses.SignatureProposalParticipantInvitationsResponse, 0),
+	}
+
+	for _, participant := range resharing.Proposal.GetOrderedParticipants() {
+		responseEntry := &responses.SignatureProposalParticipantInvitationEntry{
+			ParticipantId: participant.ParticipantID,
+			Username:      participant.Username,
+			Threshold:     participant.Threshold,
+			DkgPubKey:     participant.DkgPubKey,
+			PubKey:        participant.PubKey,
+			Deadlines:     m.payload.Deadlines,
+		}
+		responseData.Participants = append(responseData.Participants, responseEntry)
+	}
+
+	return inEvent, responseData, nil
+}
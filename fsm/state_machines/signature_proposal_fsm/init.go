@@ -22,6 +22,7 @@ const (
 	EventInitProposal                       = fsm.Event("event_sig_proposal_init")
 	EventConfirmSignatureProposal           = fsm.Event("event_sig_proposal_confirm_by_participant")
 	EventDeclineProposal                    = fsm.Event("event_sig_proposal_decline_by_participant")
+	EventSignatureProposalTimeout           = fsm.Event("event_sig_proposal_timeout")
 	eventAutoValidateProposalInternal       = fsm.Event("event_sig_proposal_validate")
 	eventSetProposalValidatedInternal       = fsm.Event("event_sig_proposal_set_validated")
 	eventSetValidationCanceledByTimeout     = fsm.Event("event_sig_proposal_canceled_timeout")
@@ -31,8 +32,17 @@ const (
 
 	// Switch to next fsm
 
+	// A resharing of another round starts a round too, its approval and stages are run by resharing_fsm
+	StateResharingAwaitParticipantsConfirmations = fsm.State("state_resharing_await_participants_confirmations")
+
+	EventInitResharingProposal = fsm.Event("event_resharing_proposal_init")
 )
 
+// timeoutCancelEvents cancel the stage when enough participants posted its timeout
+var timeoutCancelEvents = map[fsm.Event]fsm.Event{
+	EventSignatureProposalTimeout: eventSetValidationCanceledByTimeout,
+}
+
 type SignatureProposalFSM struct {
 	*fsm.FSM
 	payload   *internal.DumpedMachineStatePayload
@@ -68,12 +78,19 @@ func New() internal.DumpedMachineProvider {
 
 			// nan
 			{Name: eventSetValidationCanceledByTimeout, SrcState: []fsm.State{StateAwaitParticipantsConfirmations}, DstState: StateValidationCanceledByTimeout, IsInternal: true},
+			// Posted by nodes when the deadline has passed
+			{Name: EventSignatureProposalTimeout, SrcState: []fsm.State{StateAwaitParticipantsConfirmations}, DstState: StateAwaitParticipantsConfirmations},
+
+			// Resharing
+			{Name: EventInitResharingProposal, SrcState: []fsm.State{StateParticipantsConfirmationsInit}, DstState: StateResharingAwaitParticipantsConfirmations},
 		},
 		fsm.Callbacks{
 			EventInitProposal:                 machine.actionInitSignatureProposal,
 			EventConfirmSignatureProposal:     machine.actionProposalResponseByParticipant,
 			EventDeclineProposal:              machine.actionProposalResponseByParticipant,
+			EventSignatureProposalTimeout:     machine.actionProposalTimeout,
 			eventAutoValidateProposalInternal: machine.actionValidateSignatureProposal,
+			EventInitResharingProposal:        machine.actionInitResharingProposal,
 		},
 	)
 	return machine
@@ -5,7 +5,6 @@ import (
 	"errors"
 	"fmt"
 
-	"github.com/lidofinance/dc4bc/fsm/config"
 	"github.com/lidofinance/dc4bc/fsm/fsm"
 	"github.com/lidofinance/dc4bc/fsm/state_machines/internal"
 	"github.com/lidofinance/dc4bc/fsm/types/requests"
@@ -35,7 +34,7 @@ func (m *SigningProposalFSM) actionInitSigningProposal(inEvent fsm.Event, args .
 	m.payload.SigningProposalPayload = &internal.SigningConfirmation{
 		Quorum:    make(internal.SigningProposalQuorum),
 		CreatedAt: request.CreatedAt,
-		ExpiresAt: request.CreatedAt.Add(config.SigningConfirmationDeadline),
+		ExpiresAt: request.CreatedAt.Add(m.payload.SigningDeadline()),
 	}
 
 	return
@@ -68,6 +67,9 @@ func (m *SigningProposalFSM) actionStartSigningProposal(inEvent fsm.Event, args
 	}
 
 	m.payload.SigningProposalPayload.CreatedAt = request.CreatedAt
+	// every batch has its own deadline
+	m.payload.SigningProposalPayload.ExpiresAt = request.CreatedAt.Add(m.payload.SigningDeadline())
+	m.payload.SigningProposalPayload.UpdatedAt = request.CreatedAt
 	m.payload.SigningProposalPayload.BatchID = request.BatchID
 	m.payload.SigningProposalPayload.InitiatorId = request.ParticipantId
 	m.payload.SigningProposalPayload.SrcPayload = payload
@@ -84,10 +86,11 @@ func (m *SigningProposalFSM) actionStartSigningProposal(inEvent fsm.Event, args
 
 	// Make response
 	responseData := responses.SigningPartialSignsParticipantInvitationsResponse{
-		BatchID:      m.payload.SigningProposalPayload.BatchID,
-		InitiatorId:  m.payload.SigningProposalPayload.InitiatorId,
-		SrcPayload:   m.payload.SigningProposalPayload.SrcPayload,
-		Participants: make([]*responses.SigningPartialSignsParticipantInvitationEntry, 0),
+		BatchID:        m.payload.SigningProposalPayload.BatchID,
+		InitiatorId:    m.payload.SigningProposalPayload.InitiatorId,
+		SrcPayload:     m.payload.SigningProposalPayload.SrcPayload,
+		Participants:   make([]*responses.SigningPartialSignsParticipantInvitationEntry, 0),
+		KeyringVersion: m.payload.DKGProposalPayload.KeyringVersion,
 	}
 
 	for _, participant := range m.payload.SigningProposalPayload.Quorum.GetOrderedParticipants() {
@@ -210,6 +213,81 @@ func (m *SigningProposalFSM) actionSigningRestart(inEvent fsm.Event, args ...int
 	return
 }
 
+// actionProposeShareRefresh stores the proposal of a share refresh, the refresh is started by share_refresh_fsm
+func (m *SigningProposalFSM) actionProposeShareRefresh(inEvent fsm.Event, args ...interface{}) (outEvent fsm.Event, response interface{}, err error) {
+	m.payloadMu.Lock()
+	defer m.payloadMu.Unlock()
+
+	if len(args) != 1 {
+		err = errors.New("{arg0} required {ShareRefreshProposalRequest}")
+		return
+	}
+
+	request, ok := args[0].(requests.ShareRefreshProposalRequest)
+
+	if !ok {
+		err = errors.New("cannot cast {arg0} to type {ShareRefreshProposalRequest}")
+		return
+	}
+
+	if err = request.Validate(); err != nil {
+		return
+	}
+
+	if !m.payload.DKGQuorumExists(request.ParticipantId) {
+		err = errors.New("{ParticipantId} not exist in quorum")
+		return
+	}
+
+	m.payload.ShareRefreshPayload = &internal.ShareRefreshConfirmation{
+		KeyringVersion: m.payload.DKGProposalPayload.KeyringVersion + 1,
+		InitiatorId:    request.ParticipantId,
+		CreatedAt:      request.CreatedAt,
+		UpdatedAt:      request.CreatedAt,
+		ExpiresAt:      request.CreatedAt.Add(m.payload.DKGDeadline()),
+	}
+
+	return
+}
+
+// actionTimeout records the timeout of the signing posted after the deadline of the batch, the signing is canceled
+// when enough participants posted it, so a single participant can't cancel it
+func (m *SigningProposalFSM) actionTimeout(inEvent fsm.Event, args ...interface{}) (outEvent fsm.Event, response interface{}, err error) {
+	m.payloadMu.Lock()
+	defer m.payloadMu.Unlock()
+
+	if len(args) != 1 {
+		err = errors.New("{arg0} required {TimeoutRequest}")
+		return
+	}
+
+	request, ok := args[0].(requests.TimeoutRequest)
+
+	if !ok {
+		err = errors.New("cannot cast {arg0} to type {TimeoutRequest}")
+		return
+	}
+
+	if err = request.Validate(); err != nil {
+		return
+	}
+
+	if request.CreatedAt.Before(m.payload.SigningProposalPayload.ExpiresAt) {
+		err = fmt.Errorf("cannot cancel by timeout before {ExpiresAt} = {\"%s\"}", m.payload.SigningProposalPayload.ExpiresAt)
+		return
+	}
+
+	votes := m.payload.AddTimeoutVote(inEvent, m.payload.SigningProposalPayload.ExpiresAt, request.Username)
+	if votes < internal.TimeoutQuorum(m.payload.SigningQuorumCount(), m.payload.GetThreshold()) {
+		return
+	}
+
+	m.payload.SigningProposalPayload.UpdatedAt = request.CreatedAt
+	outEvent = timeoutCancelEvents[inEvent]
+
+	return
+}
+
 // Errors
 func (m *SigningProposalFSM) actionConfirmationError(inEvent fsm.Event, args ...interface{}) (outEvent fsm.Event, response interface{}, err error) {
 	m.payloadMu.Lock()
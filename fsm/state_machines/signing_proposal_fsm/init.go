@@ -22,6 +22,9 @@ const (
 
 	StateSigningPartialSignsCollected = fsm.State("state_signing_partial_signs_collected")
 
+	// The refresh itself is run by share_refresh_fsm
+	StateShareRefreshProposed = fsm.State("state_share_refresh_proposed")
+
 	EventSigningInit = fsm.Event("event_signing_init")
 
 	EventSigningStart = fsm.Event("event_signing_start")
@@ -31,14 +34,23 @@ const (
 	EventSigningPartialSignError                         = fsm.Event("event_signing_partial_sign_error_received")
 	eventSigningPartialSignsAwaitCancelByTimeoutInternal = fsm.Event("event_signing_partial_signs_await_cancel_by_timeout_internal")
 	eventSigningPartialSignsAwaitCancelByErrorInternal   = fsm.Event("event_signing_partial_signs_await_sign_cancel_by_error_internal")
+	// Posted by nodes when the deadline has passed
+	EventSigningPartialSignsTimeout = fsm.Event("event_signing_partial_signs_timeout")
 
 	eventAutoSigningValidatePartialSignInternal = fsm.Event("event_signing_partial_signs_await_validate")
 
 	eventSigningPartialSignsConfirmedInternal = fsm.Event("event_signing_partial_signs_confirmed_internal")
 
 	EventSigningRestart = fsm.Event("event_signing_restart")
+
+	EventShareRefreshPropose = fsm.Event("event_share_refresh_propose")
 )
 
+// timeoutCancelEvents cancel the stage when enough participants posted its timeout
+var timeoutCancelEvents = map[fsm.Event]fsm.Event{
+	EventSigningPartialSignsTimeout: eventSigningPartialSignsAwaitCancelByTimeoutInternal,
+}
+
 type SigningProposalFSM struct {
 	*fsm.FSM
 	payload   *internal.DumpedMachineStatePayload
@@ -60,6 +72,7 @@ func New() internal.DumpedMachineProvider {
 
 			{Name: EventSigningPartialSignError, SrcState: []fsm.State{StateSigningAwaitPartialSigns}, DstState: StateSigningAwaitPartialSigns},
 			{Name: eventSigningPartialSignsAwaitCancelByTimeoutInternal, SrcState: []fsm.State{StateSigningAwaitPartialSigns}, DstState: StateSigningPartialSignsAwaitCancelledByTimeout, IsInternal: true},
+			{Name: EventSigningPartialSignsTimeout, SrcState: []fsm.State{StateSigningAwaitPartialSigns}, DstState: StateSigningAwaitPartialSigns},
 			{Name: eventSigningPartialSignsAwaitCancelByErrorInternal, SrcState: []fsm.State{StateSigningAwaitPartialSigns}, DstState: StateSigningPartialSignsAwaitCancelledByError, IsInternal: true},
 
 			{Name: eventAutoSigningValidatePartialSignInternal, SrcState: []fsm.State{StateSigningAwaitPartialSigns}, DstState: StateSigningAwaitPartialSigns, IsInternal: true, IsAuto: true},
@@ -67,6 +80,8 @@ func New() internal.DumpedMachineProvider {
 			{Name: eventSigningPartialSignsConfirmedInternal, SrcState: []fsm.State{StateSigningAwaitPartialSigns}, DstState: StateSigningPartialSignsCollected, IsInternal: true},
 
 			{Name: EventSigningRestart, SrcState: []fsm.State{StateSigningPartialSignsCollected, StateSigningPartialSignsAwaitCancelledByTimeout, StateSigningPartialSignsAwaitCancelledByError}, DstState: StateSigningIdle},
+
+			{Name: EventShareRefreshPropose, SrcState: []fsm.State{StateSigningIdle}, DstState: StateShareRefreshProposed},
 		},
 		fsm.Callbacks{
 			EventSigningInit:                            machine.actionInitSigningProposal,
@@ -75,6 +90,8 @@ func New() internal.DumpedMachineProvider {
 			eventAutoSigningValidatePartialSignInternal: machine.actionValidateSigningPartialSignsAwaitConfirmations,
 			EventSigningPartialSignError:                machine.actionConfirmationError,
 			EventSigningRestart:                         machine.actionSigningRestart,
+			EventSigningPartialSignsTimeout:             machine.actionTimeout,
+			EventShareRefreshPropose:                    machine.actionProposeShareRefresh,
 		},
 	)
 
@@ -5,3 +5,10 @@ import "time"
 type DefaultRequest struct {
 	CreatedAt time.Time
 }
+
+// Event: "event_*_timeout"
+type TimeoutRequest struct {
+	// Username is the sender of the timeout, it's set by the node from the verified message, not from its data
+	Username  string `json:"-"`
+	CreatedAt time.Time
+}
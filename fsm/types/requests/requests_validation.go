@@ -9,3 +9,15 @@ func (r *DefaultRequest) Validate() error {
 
 	return nil
 }
+
+func (r *TimeoutRequest) Validate() error {
+	if r.Username == "" {
+		return errors.New("{Username} is not set")
+	}
+
+	if r.CreatedAt.IsZero() {
+		return errors.New("{CreatedAt} is not set")
+	}
+
+	return nil
+}
@@ -0,0 +1,35 @@
+package requests
+
+import (
+	"time"
+
+	"github.com/lidofinance/dc4bc/fsm/types"
+)
+
+// States: "__idle"
+// Events: "event_resharing_proposal_init"
+type ResharingProposalRequest struct {
+	// OldDkgID is the finished DKG round which master key is handed over
+	OldDkgID          string
+	OldThreshold      int
+	OldKeyringVersion int
+	OldPubPolyBz      []byte
+	OldMasterKey      []byte
+	OldParticipants   []*ResharingOldParticipantEntry
+	// Dealers are the usernames of the old participants who deal their shares, at least OldThreshold of them
+	Dealers []string
+	// Participants are the new committee, it gets the shares of the same master key
+	Participants     []*SignatureProposalParticipantsEntry
+	SigningThreshold int
+	CreatedAt        time.Time
+	// Deadlines are optional, the deadlines from the config are used if not set
+	Deadlines *types.StageDeadlines `json:",omitempty"`
+}
+
+type ResharingOldParticipantEntry struct {
+	// ParticipantId is the id in the old round, it's the index of the key share of the participant
+	ParticipantId int
+	Username      string
+	PubKey        []byte
+	DkgPubKey     []byte
+}
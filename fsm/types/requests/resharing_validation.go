@@ -0,0 +1,101 @@
+package requests
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+
+	"github.com/lidofinance/dc4bc/fsm/config"
+)
+
+func (r *ResharingProposalRequest) Validate() error {
+	if r.OldDkgID == "" {
+		return errors.New("{OldDkgID} cannot be empty")
+	}
+
+	newCommittee := SignatureProposalParticipantsListRequest{
+		Participants:     r.Participants,
+		SigningThreshold: r.SigningThreshold,
+		CreatedAt:        r.CreatedAt,
+		Deadlines:        r.Deadlines,
+	}
+	if err := newCommittee.Validate(); err != nil {
+		return err
+	}
+
+	if r.OldThreshold < config.SignatureProposalSigningThresholdMinCount {
+		return fmt.Errorf("{OldThreshold} minimum count is {%d}", config.SignatureProposalSigningThresholdMinCount)
+	}
+
+	if r.OldThreshold > len(r.OldParticipants) {
+		return errors.New("{OldThreshold} cannot be higher than {OldParticipantsCount}")
+	}
+
+	if r.OldKeyringVersion < 0 {
+		return errors.New("{OldKeyringVersion} cannot be a negative number")
+	}
+
+	if len(r.OldPubPolyBz) == 0 {
+		return errors.New("{OldPubPolyBz} cannot be empty")
+	}
+
+	if len(r.OldMasterKey) == 0 {
+		return errors.New("{OldMasterKey} cannot be empty")
+	}
+
+	// the ids of the old participants are the indices of their key shares, so all of them are required
+	oldParticipants := make(map[string]*ResharingOldParticipantEntry)
+	oldIDs := make(map[int]bool)
+	for _, participant := range r.OldParticipants {
+		if participant.ParticipantId < 0 || participant.ParticipantId >= len(r.OldParticipants) {
+			return errors.New("{ParticipantId} of old participants must be in [0, {OldParticipantsCount})")
+		}
+		if oldIDs[participant.ParticipantId] {
+			return errors.New("{ParticipantId} of old participants must be unique")
+		}
+		oldIDs[participant.ParticipantId] = true
+
+		if _, ok := oldParticipants[participant.Username]; ok {
+			return errors.New("{Username} of old participants must be unique")
+		}
+		oldParticipants[participant.Username] = participant
+
+		if len(participant.PubKey) < config.ParticipantPubKeyMinLength {
+			return errors.New("{PubKey} too short")
+		}
+
+		if len(participant.DkgPubKey) < config.DkgPubKeyMinLength {
+			return errors.New("{DkgPubKey} too short")
+		}
+	}
+
+	if len(r.Dealers) < r.OldThreshold {
+		return errors.New("{Dealers} count cannot be lower than {OldThreshold}")
+	}
+
+	uniqueDealers := make(map[string]bool)
+	for _, dealer := range r.Dealers {
+		if uniqueDealers[dealer] {
+			return errors.New("{Dealers} must be unique")
+		}
+		uniqueDealers[dealer] = true
+
+		if _, ok := oldParticipants[dealer]; !ok {
+			return fmt.Errorf("dealer {%s} is not an old participant", dealer)
+		}
+	}
+
+	// the deals are verified by the DKG keys, so a member of both committees must keep its key and username
+	for _, participant := range r.Participants {
+		for _, oldParticipant := range r.OldParticipants {
+			sameKey := bytes.Equal(participant.DkgPubKey, oldParticipant.DkgPubKey)
+			sameUsername := participant.Username == oldParticipant.Username
+			if sameKey != sameUsername {
+				return fmt.Errorf("participant {%s} of both committees must keep its {Username} and {DkgPubKey}",
+					participant.Username)
+			}
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,10 @@
+package requests
+
+import "time"
+
+// States: "stage_signing_idle"
+// Events: "event_share_refresh_propose"
+type ShareRefreshProposalRequest struct {
+	ParticipantId int
+	CreatedAt     time.Time
+}
@@ -0,0 +1,13 @@
+package requests
+
+import "errors"
+
+func (r *ShareRefreshProposalRequest) Validate() error {
+	if r.ParticipantId < 0 {
+		return errors.New("{ParticipantId} cannot be a negative number")
+	}
+	if r.CreatedAt.IsZero() {
+		return errors.New("{CreatedAt} is not set")
+	}
+	return nil
+}
@@ -1,6 +1,10 @@
 package requests
 
-import "time"
+import (
+	"time"
+
+	"github.com/lidofinance/dc4bc/fsm/types"
+)
 
 // Requests
 
@@ -10,6 +14,8 @@ type SignatureProposalParticipantsListRequest struct {
 	Participants     []*SignatureProposalParticipantsEntry
 	SigningThreshold int
 	CreatedAt        time.Time
+	// Deadlines are optional, the deadlines from the config are used if not set
+	Deadlines *types.StageDeadlines `json:",omitempty"`
 }
 
 type SignatureProposalParticipantsEntry struct {
@@ -25,6 +31,8 @@ type SignatureProposalParticipantsEntry struct {
 type SignatureProposalParticipantRequest struct {
 	ParticipantId int
 	CreatedAt     time.Time
+	// Reason is an optional explanation of a declined participation
+	Reason string `json:",omitempty"`
 }
 
 type SignatureProposalConfirmationErrorRequest struct {
@@ -3,8 +3,10 @@ package requests
 import (
 	"errors"
 	"fmt"
+	"time"
 
 	"github.com/lidofinance/dc4bc/fsm/config"
+	"github.com/lidofinance/dc4bc/fsm/types"
 )
 
 func (r *SignatureProposalParticipantsListRequest) Validate() error {
@@ -53,6 +55,25 @@ func (r *SignatureProposalParticipantsListRequest) Validate() error {
 		return errors.New("{CreatedAt} cannot be a nil")
 	}
 
+	if r.Deadlines != nil {
+		if err := validateDeadline("SignatureProposal", r.Deadlines.SignatureProposal); err != nil {
+			return err
+		}
+		if err := validateDeadline("DKG", r.Deadlines.DKG); err != nil {
+			return err
+		}
+		if err := validateDeadline("Signing", r.Deadlines.Signing); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func validateDeadline(name string, deadline types.Duration) error {
+	if deadline != 0 && time.Duration(deadline) < config.StageDeadlineMin {
+		return fmt.Errorf("{Deadlines.%s} minimum is {%s}", name, config.StageDeadlineMin)
+	}
 	return nil
 }
 
@@ -64,6 +85,10 @@ func (r *SignatureProposalParticipantRequest) Validate() error {
 	if r.CreatedAt.IsZero() {
 		return errors.New("{CreatedAt} cannot be a nil")
 	}
+
+	if len(r.Reason) > config.DeclineReasonMaxLength {
+		return fmt.Errorf("{Reason} maximum length is {%d}", config.DeclineReasonMaxLength)
+	}
 	return nil
 }
 
@@ -0,0 +1,39 @@
+package responses
+
+// Event:  "event_resharing_proposal_init"
+// States: "state_resharing_await_participants_confirmations"
+type ResharingProposalInvitationResponse struct {
+	OldDkgID     string
+	OldThreshold int
+	// Dealers are the usernames of the old participants who deal their shares
+	Dealers []string
+	// Participants are the participants of the resharing, the new committee and the dealers who are not its members,
+	// the threshold of every entry is the threshold of the new committee
+	Participants SignatureProposalParticipantInvitationsResponse
+}
+
+// Event:  "event_resharing_proposal_confirmed_internal"
+// States: "state_resharing_commits_await_confirmations"
+type ResharingInvitationResponse struct {
+	OldDkgID          string
+	OldThreshold      int
+	OldKeyringVersion int
+	OldPubPolyBz      []byte
+	// OldParticipants are the participants of the old round with their ids in it
+	OldParticipants DKGProposalPubKeysParticipantResponse
+	// Dealers maps the ids of the dealers in the resharing to their ids in the old round
+	Dealers map[int]int
+	// Receivers is the number of the new committee members, they are the first participants of the resharing
+	Receivers int
+	Threshold int
+	// Participants are the participants of the resharing, the threshold of every entry is the new one
+	Participants DKGProposalPubKeysParticipantResponse
+}
+
+// Event:  "event_resharing_deals_confirmed_internal"
+// States: "state_resharing_responses_await_confirmations"
+type ResharingDealsParticipantResponse struct {
+	// Commits are the commits of the dealers, the new committee members who don't deal don't have them yet
+	Commits DKGProposalCommitParticipantResponse
+	Deals   DKGProposalDealParticipantResponse
+}
@@ -0,0 +1,9 @@
+package responses
+
+// Event:  "event_share_refresh_init"
+// States: "state_share_refresh_commits_await_confirmations"
+type ShareRefreshInvitationResponse struct {
+	// KeyringVersion is the version of the keyring to refresh, the refresh produces the next version
+	KeyringVersion int
+	Participants   DKGProposalPubKeysParticipantResponse
+}
@@ -1,5 +1,7 @@
 package responses
 
+import "github.com/lidofinance/dc4bc/fsm/types"
+
 // Response
 
 // Event: "event_sig_proposal_init"
@@ -12,6 +14,8 @@ type SignatureProposalParticipantInvitationEntry struct {
 	Threshold     int
 	DkgPubKey     []byte
 	PubKey        []byte
+	// Deadlines are the same for everyone, nil if the proposal uses the default ones
+	Deadlines *types.StageDeadlines `json:",omitempty"`
 }
 
 // Public lists for proposal confirmation process
@@ -8,6 +8,8 @@ type SigningPartialSignsParticipantInvitationsResponse struct {
 	Participants []*SigningPartialSignsParticipantInvitationEntry
 	// Source message for signing
 	SrcPayload []byte
+	// KeyringVersion is the version of the keyring to sign with, it's changed by share refreshes
+	KeyringVersion int `json:",omitempty"`
 }
 
 type SigningPartialSignsParticipantInvitationEntry struct {
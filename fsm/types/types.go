@@ -1,5 +1,11 @@
 package types
 
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
 type BatchPartialSignatures map[string][][]byte
 
 func (b BatchPartialSignatures) AddPartialSignature(messageID string, partialSignature []byte) {
@@ -17,3 +23,35 @@ type ReconstructedSignature struct {
 	// Special field with additional info for "sign baked data" routine
 	ValIdx int64
 }
+
+// Duration is a time.Duration marshalled to JSON as a string, e.g. "72h"
+type Duration time.Duration
+
+func (d Duration) MarshalJSON() ([]byte, error) {
+	return json.Marshal(time.Duration(d).String())
+}
+
+func (d *Duration) UnmarshalJSON(data []byte) error {
+	var s string
+	if err := json.Unmarshal(data, &s); err != nil {
+		return fmt.Errorf("duration must be a string, e.g. \"72h\": %w", err)
+	}
+	duration, err := time.ParseDuration(s)
+	if err != nil {
+		return err
+	}
+	*d = Duration(duration)
+	return nil
+}
+
+// StageDeadlines override the deadlines of the DKG round stages, a zero deadline is the default one
+type StageDeadlines struct {
+	SignatureProposal Duration `json:",omitempty"`
+	DKG               Duration `json:",omitempty"`
+	Signing           Duration `json:",omitempty"`
+}
+
+// String is used to calculate the hash of the start proposal
+func (d StageDeadlines) String() string {
+	return fmt.Sprintf("%s;%s;%s", time.Duration(d.SignatureProposal), time.Duration(d.DKG), time.Duration(d.Signing))
+}
@@ -8,7 +8,6 @@ import (
 	reflect "reflect"
 
 	gomock "github.com/golang/mock/gomock"
-
 	keystore "github.com/lidofinance/dc4bc/client/modules/keystore"
 )
 
@@ -35,6 +34,20 @@ func (m *MockKeyStore) EXPECT() *MockKeyStoreMockRecorder {
 	return m.recorder
 }
 
+// ChangePassword mocks base method.
+func (m *MockKeyStore) ChangePassword(userName, oldPassword, newPassword string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ChangePassword", userName, oldPassword, newPassword)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// ChangePassword indicates an expected call of ChangePassword.
+func (mr *MockKeyStoreMockRecorder) ChangePassword(userName, oldPassword, newPassword interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ChangePassword", reflect.TypeOf((*MockKeyStore)(nil).ChangePassword), userName, oldPassword, newPassword)
+}
+
 // LoadKeys mocks base method.
 func (m *MockKeyStore) LoadKeys(userName, password string) (*keystore.KeyPair, error) {
 	m.ctrl.T.Helper()
@@ -50,16 +63,31 @@ func (mr *MockKeyStoreMockRecorder) LoadKeys(userName, password interface{}) *go
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "LoadKeys", reflect.TypeOf((*MockKeyStore)(nil).LoadKeys), userName, password)
 }
 
+// MigratePlaintextKeys mocks base method.
+func (m *MockKeyStore) MigratePlaintextKeys(password string) ([]string, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "MigratePlaintextKeys", password)
+	ret0, _ := ret[0].([]string)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// MigratePlaintextKeys indicates an expected call of MigratePlaintextKeys.
+func (mr *MockKeyStoreMockRecorder) MigratePlaintextKeys(password interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "MigratePlaintextKeys", reflect.TypeOf((*MockKeyStore)(nil).MigratePlaintextKeys), password)
+}
+
 // PutKeys mocks base method.
-func (m *MockKeyStore) PutKeys(username string, keyPair *keystore.KeyPair) error {
+func (m *MockKeyStore) PutKeys(username, password string, keyPair *keystore.KeyPair) error {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "PutKeys", username, keyPair)
+	ret := m.ctrl.Call(m, "PutKeys", username, password, keyPair)
 	ret0, _ := ret[0].(error)
 	return ret0
 }
 
 // PutKeys indicates an expected call of PutKeys.
-func (mr *MockKeyStoreMockRecorder) PutKeys(username, keyPair interface{}) *gomock.Call {
+func (mr *MockKeyStoreMockRecorder) PutKeys(username, password, keyPair interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "PutKeys", reflect.TypeOf((*MockKeyStore)(nil).PutKeys), username, keyPair)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "PutKeys", reflect.TypeOf((*MockKeyStore)(nil).PutKeys), username, password, keyPair)
 }
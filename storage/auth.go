@@ -0,0 +1,57 @@
+package storage
+
+import (
+	"crypto/ed25519"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+)
+
+var (
+	ErrUnknownSender    = errors.New("sender is not in the allow-list")
+	ErrInvalidSignature = errors.New("invalid signature")
+)
+
+// AllowList maps participants' usernames to their hot ed25519 public keys. Boards which authenticate
+// writes accept only messages signed by the key of the message sender.
+type AllowList map[string]ed25519.PublicKey
+
+// LoadAllowList reads an allow-list from a JSON file, e.g. {"alice": "<base64 pubkey>", ...},
+// pubkeys are the same as returned by `dc4bc_cli get_pubkey`
+func LoadAllowList(filename string) (AllowList, error) {
+	data, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read allow-list file: %w", err)
+	}
+
+	var keys map[string][]byte
+	if err = json.Unmarshal(data, &keys); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal allow-list: %w", err)
+	}
+
+	allowList := make(AllowList, len(keys))
+	for username, key := range keys {
+		if len(key) != ed25519.PublicKeySize {
+			return nil, fmt.Errorf("invalid pubkey of %s: expected %d bytes, got %d",
+				username, ed25519.PublicKeySize, len(key))
+		}
+		allowList[username] = key
+	}
+
+	return allowList, nil
+}
+
+// Authenticate checks that the message headers and data are signed by the allow-listed key of its sender.
+// A message posted again as it is still passes the check
+func (a AllowList) Authenticate(m Message) error {
+	pubKey, ok := a[m.SenderAddr]
+	if !ok {
+		return fmt.Errorf("%w: %s", ErrUnknownSender, m.SenderAddr)
+	}
+	if !m.VerifyBoardSignature(pubKey) {
+		return fmt.Errorf("%w of %s", ErrInvalidSignature, m.SenderAddr)
+	}
+
+	return nil
+}
@@ -0,0 +1,161 @@
+package board
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/lidofinance/dc4bc/storage"
+)
+
+// Rejection is an audit record of a write rejected by the board
+type Rejection struct {
+	Offset     uint64          `json:"offset"`
+	Time       time.Time       `json:"time"`
+	RemoteAddr string          `json:"remote_addr"`
+	Reason     string          `json:"reason"`
+	Message    storage.Message `json:"message"`
+}
+
+// AuditLog is a persistent append-only log of rejected writes, stored as JSON lines like the Log
+type AuditLog struct {
+	sync.RWMutex
+
+	file       *os.File
+	size       int64
+	rejections []Rejection
+
+	// appended is closed (and replaced) on every append to wake up waiting readers
+	appended chan struct{}
+}
+
+// OpenAuditLog opens (or creates) the audit log file and loads all stored records into memory
+func OpenAuditLog(filename string) (*AuditLog, error) {
+	f, err := os.OpenFile(filename, os.O_CREATE|os.O_RDWR, 0644)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open an audit log file: %w", err)
+	}
+
+	l := &AuditLog{file: f, appended: make(chan struct{})}
+	if err = l.load(); err != nil {
+		f.Close()
+		return nil, err
+	}
+
+	return l, nil
+}
+
+func (l *AuditLog) load() error {
+	reader := bufio.NewReader(l.file)
+	for {
+		row, err := reader.ReadBytes('\n')
+		if errors.Is(err, io.EOF) {
+			// a partial line without the trailing newline is an unfinished write
+			break
+		}
+		if err != nil {
+			return fmt.Errorf("failed to read an audit log file: %w", err)
+		}
+
+		var rejection Rejection
+		if err = json.Unmarshal(row, &rejection); err != nil {
+			return fmt.Errorf("failed to unmarshal an audit record at offset %d: %w", len(l.rejections), err)
+		}
+
+		l.rejections = append(l.rejections, rejection)
+		l.size += int64(len(row))
+	}
+
+	if err := l.file.Truncate(l.size); err != nil {
+		return fmt.Errorf("failed to truncate an audit log file: %w", err)
+	}
+	if _, err := l.file.Seek(l.size, io.SeekStart); err != nil {
+		return fmt.Errorf("failed to seek to the end of an audit log file: %w", err)
+	}
+
+	return nil
+}
+
+// Append appends audit records, offsets are assigned by the log
+func (l *AuditLog) Append(rejections ...Rejection) error {
+	l.Lock()
+	defer l.Unlock()
+
+	var buf []byte
+	for i := range rejections {
+		rejections[i].Offset = uint64(len(l.rejections) + i)
+
+		data, err := json.Marshal(rejections[i])
+		if err != nil {
+			return fmt.Errorf("failed to marshal an audit record: %w", err)
+		}
+		buf = append(append(buf, data...), '\n')
+	}
+
+	if _, err := l.file.Write(buf); err != nil {
+		_ = l.file.Truncate(l.size)
+		_, _ = l.file.Seek(l.size, io.SeekStart)
+		return fmt.Errorf("failed to write audit records: %w", err)
+	}
+
+	l.size += int64(len(buf))
+	l.rejections = append(l.rejections, rejections...)
+
+	close(l.appended)
+	l.appended = make(chan struct{})
+
+	return nil
+}
+
+// Read returns at most limit records starting from the given offset, zero limit means no limit
+func (l *AuditLog) Read(offset uint64, limit int) []Rejection {
+	l.RLock()
+	defer l.RUnlock()
+
+	if offset >= uint64(len(l.rejections)) {
+		return []Rejection{}
+	}
+
+	end := uint64(len(l.rejections))
+	if limit > 0 && offset+uint64(limit) < end {
+		end = offset + uint64(limit)
+	}
+
+	rejections := make([]Rejection, end-offset)
+	copy(rejections, l.rejections[offset:end])
+
+	return rejections
+}
+
+// Wait blocks until there is a record with the given offset in the log or the context is done.
+// It returns false if the context is done first.
+func (l *AuditLog) Wait(ctx context.Context, offset uint64) bool {
+	for {
+		l.RLock()
+		if offset < uint64(len(l.rejections)) {
+			l.RUnlock()
+			return true
+		}
+		appended := l.appended
+		l.RUnlock()
+
+		select {
+		case <-appended:
+		case <-ctx.Done():
+			return false
+		}
+	}
+}
+
+func (l *AuditLog) Close() error {
+	l.Lock()
+	defer l.Unlock()
+
+	return l.file.Close()
+}
@@ -0,0 +1,199 @@
+package board
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+
+	"github.com/google/uuid"
+
+	"github.com/lidofinance/dc4bc/storage"
+)
+
+// Log is a persistent append-only log of bulletin board messages.
+// Every message is stored as a single JSON line, the line number is the message offset.
+// Messages are hash-chained: each entry carries the hash of its predecessor (see storage.ChainHash).
+type Log struct {
+	sync.RWMutex
+
+	file     *os.File
+	size     int64
+	messages []storage.Message
+
+	// appended is closed (and replaced) on every append to wake up waiting readers
+	appended chan struct{}
+}
+
+// OpenLog opens (or creates) the log file and loads all stored messages into memory.
+// A torn trailing record (e.g. after a crash in the middle of a write) is truncated.
+func OpenLog(filename string) (*Log, error) {
+	f, err := os.OpenFile(filename, os.O_CREATE|os.O_RDWR, 0644)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open a log file: %w", err)
+	}
+
+	l := &Log{file: f, appended: make(chan struct{})}
+	if err = l.load(); err != nil {
+		f.Close()
+		return nil, err
+	}
+
+	return l, nil
+}
+
+func (l *Log) load() error {
+	reader := bufio.NewReader(l.file)
+	for {
+		row, err := reader.ReadBytes('\n')
+		if errors.Is(err, io.EOF) {
+			// a partial line without the trailing newline is an unfinished write
+			break
+		}
+		if err != nil {
+			return fmt.Errorf("failed to read a log file: %w", err)
+		}
+
+		var msg storage.Message
+		if err = json.Unmarshal(row, &msg); err != nil {
+			return fmt.Errorf("failed to unmarshal a message at offset %d: %w", len(l.messages), err)
+		}
+		if msg.Offset != uint64(len(l.messages)) {
+			return fmt.Errorf("log is corrupted: expected offset %d, got %d", len(l.messages), msg.Offset)
+		}
+		if !bytes.Equal(msg.PrevHash, l.lastHash()) || !msg.VerifyChainHash() {
+			return fmt.Errorf("log is corrupted: broken hash chain at offset %d", msg.Offset)
+		}
+
+		l.messages = append(l.messages, msg)
+		l.size += int64(len(row))
+	}
+
+	if err := l.file.Truncate(l.size); err != nil {
+		return fmt.Errorf("failed to truncate a log file: %w", err)
+	}
+	if _, err := l.file.Seek(l.size, io.SeekStart); err != nil {
+		return fmt.Errorf("failed to seek to the end of a log file: %w", err)
+	}
+
+	return nil
+}
+
+// lastHash returns the hash of the last entry, nil for an empty log
+func (l *Log) lastHash() []byte {
+	if len(l.messages) == 0 {
+		return nil
+	}
+	return l.messages[len(l.messages)-1].Hash
+}
+
+// Append atomically appends messages to the log. Offsets, chain hashes (and ids, if missing) are assigned
+// by the log, either all messages are persisted or none of them.
+func (l *Log) Append(msgs ...storage.Message) ([]storage.Message, error) {
+	l.Lock()
+	defer l.Unlock()
+
+	buf := bytes.NewBuffer(nil)
+	stored := make([]storage.Message, len(msgs))
+	prevHash := l.lastHash()
+	for i, m := range msgs {
+		if m.ID == "" {
+			m.ID = uuid.New().String()
+		}
+		m.Offset = uint64(len(l.messages) + i)
+		m.LinkToChain(prevHash)
+		prevHash = m.Hash
+
+		data, err := json.Marshal(m)
+		if err != nil {
+			return nil, fmt.Errorf("failed to marshal a message %v: %w", m, err)
+		}
+		buf.Write(data)
+		buf.WriteByte('\n')
+
+		stored[i] = m
+	}
+
+	if _, err := l.file.Write(buf.Bytes()); err != nil {
+		l.rollback()
+		return nil, fmt.Errorf("failed to write messages to a log file: %w", err)
+	}
+	if err := l.file.Sync(); err != nil {
+		l.rollback()
+		return nil, fmt.Errorf("failed to sync a log file: %w", err)
+	}
+
+	l.size += int64(buf.Len())
+	l.messages = append(l.messages, stored...)
+
+	close(l.appended)
+	l.appended = make(chan struct{})
+
+	return stored, nil
+}
+
+// rollback drops a partially written batch
+func (l *Log) rollback() {
+	_ = l.file.Truncate(l.size)
+	_, _ = l.file.Seek(l.size, io.SeekStart)
+}
+
+// Read returns at most limit messages starting from the given offset, zero limit means no limit.
+func (l *Log) Read(offset uint64, limit int) []storage.Message {
+	l.RLock()
+	defer l.RUnlock()
+
+	if offset >= uint64(len(l.messages)) {
+		return []storage.Message{}
+	}
+
+	end := uint64(len(l.messages))
+	if limit > 0 && offset+uint64(limit) < end {
+		end = offset + uint64(limit)
+	}
+
+	msgs := make([]storage.Message, end-offset)
+	copy(msgs, l.messages[offset:end])
+
+	return msgs
+}
+
+// Wait blocks until there is a message with the given offset in the log or the context is done.
+// It returns false if the context is done first.
+func (l *Log) Wait(ctx context.Context, offset uint64) bool {
+	for {
+		l.RLock()
+		if offset < uint64(len(l.messages)) {
+			l.RUnlock()
+			return true
+		}
+		appended := l.appended
+		l.RUnlock()
+
+		select {
+		case <-appended:
+		case <-ctx.Done():
+			return false
+		}
+	}
+}
+
+// Len returns the number of messages in the log
+func (l *Log) Len() uint64 {
+	l.RLock()
+	defer l.RUnlock()
+
+	return uint64(len(l.messages))
+}
+
+func (l *Log) Close() error {
+	l.Lock()
+	defer l.Unlock()
+
+	return l.file.Close()
+}
@@ -0,0 +1,130 @@
+package board
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/lidofinance/dc4bc/storage"
+)
+
+func TestLog_AppendAndReopen(t *testing.T) {
+	req := require.New(t)
+	filename := filepath.Join(t.TempDir(), "board_log")
+
+	l, err := OpenLog(filename)
+	req.NoError(err)
+
+	stored, err := l.Append(
+		storage.Message{Event: "event_1", Data: []byte("data_1")},
+		storage.Message{Event: "event_2", Data: []byte("data_2")},
+	)
+	req.NoError(err)
+	req.Len(stored, 2)
+	req.Equal(uint64(0), stored[0].Offset)
+	req.Equal(uint64(1), stored[1].Offset)
+	req.NotEmpty(stored[0].ID)
+
+	stored, err = l.Append(storage.Message{ID: "id_3", Event: "event_3"})
+	req.NoError(err)
+	req.Equal(uint64(2), stored[0].Offset)
+	req.Equal("id_3", stored[0].ID)
+
+	req.Len(l.Read(1, 0), 2)
+	req.Len(l.Read(0, 1), 1)
+	req.Empty(l.Read(3, 0))
+	req.NoError(l.Close())
+
+	l, err = OpenLog(filename)
+	req.NoError(err)
+	defer l.Close()
+
+	req.Equal(uint64(3), l.Len())
+	msgs := l.Read(0, 0)
+	req.Equal([]byte("data_2"), msgs[1].Data)
+	req.Equal("event_3", msgs[2].Event)
+
+	req.Empty(msgs[0].PrevHash)
+	for i, m := range msgs {
+		req.True(m.VerifyChainHash())
+		if i > 0 {
+			req.Equal(msgs[i-1].Hash, m.PrevHash)
+		}
+	}
+}
+
+func TestLog_TamperedLog(t *testing.T) {
+	req := require.New(t)
+	filename := filepath.Join(t.TempDir(), "board_log")
+
+	l, err := OpenLog(filename)
+	req.NoError(err)
+	_, err = l.Append(
+		storage.Message{Event: "event_1", Data: []byte("data_1")},
+		storage.Message{Event: "event_2", Data: []byte("data_2")},
+	)
+	req.NoError(err)
+	req.NoError(l.Close())
+
+	data, err := os.ReadFile(filename)
+	req.NoError(err)
+	req.NoError(os.WriteFile(filename, bytes.Replace(data, []byte("event_2"), []byte("event_X"), 1), 0644))
+
+	_, err = OpenLog(filename)
+	req.Error(err)
+}
+
+func TestLog_TornWrite(t *testing.T) {
+	req := require.New(t)
+	filename := filepath.Join(t.TempDir(), "board_log")
+
+	l, err := OpenLog(filename)
+	req.NoError(err)
+	_, err = l.Append(storage.Message{Event: "event_1"})
+	req.NoError(err)
+	req.NoError(l.Close())
+
+	f, err := os.OpenFile(filename, os.O_APPEND|os.O_WRONLY, 0644)
+	req.NoError(err)
+	_, err = f.WriteString(`{"id":"torn","offset":1,"ev`)
+	req.NoError(err)
+	req.NoError(f.Close())
+
+	l, err = OpenLog(filename)
+	req.NoError(err)
+	defer l.Close()
+	req.Equal(uint64(1), l.Len())
+
+	stored, err := l.Append(storage.Message{Event: "event_2"})
+	req.NoError(err)
+	req.Equal(uint64(1), stored[0].Offset)
+}
+
+func TestLog_Wait(t *testing.T) {
+	req := require.New(t)
+
+	l, err := OpenLog(filepath.Join(t.TempDir(), "board_log"))
+	req.NoError(err)
+	defer l.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	req.False(l.Wait(ctx, 0))
+
+	go func() {
+		time.Sleep(50 * time.Millisecond)
+		_, err := l.Append(storage.Message{Event: "event_1"})
+		req.NoError(err)
+	}()
+
+	ctx, cancel = context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	req.True(l.Wait(ctx, 0))
+	req.True(l.Wait(ctx, 0))
+	req.Len(l.Read(0, 0), 1)
+}
@@ -0,0 +1,203 @@
+package board
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	echo_middleware "github.com/labstack/echo/v4/middleware"
+
+	"github.com/lidofinance/dc4bc/storage"
+)
+
+const (
+	MessagesPath = "/messages"
+	AuditPath    = "/audit"
+
+	OffsetParam = "offset"
+	LimitParam  = "limit"
+	WaitParam   = "wait"
+
+	// MaxReadLimit is the maximum number of messages returned by a single read request
+	MaxReadLimit = 1000
+
+	// MaxWait is the maximum time a read request waits for new messages (long polling)
+	MaxWait = time.Minute
+)
+
+// Response is a common envelope for all bulletin board responses
+type Response struct {
+	ErrorMessage string      `json:"error_message,omitempty"`
+	Result       interface{} `json:"result"`
+}
+
+type Config struct {
+	ListenAddr    string `mapstructure:"listen_addr"`
+	LogPath       string `mapstructure:"log_path"`
+	EnableLogging bool   `mapstructure:"enable_http_logging"`
+	AllowListPath string `mapstructure:"allow_list"`
+	AuditLogPath  string `mapstructure:"audit_log_path"`
+}
+
+// Server is a self-contained HTTP bulletin board serving a persistent append-only log
+type Server struct {
+	config       *Config
+	log          *Log
+	echoInstance *echo.Echo
+
+	// allowList and auditLog are set if writes are authenticated (see EnableAuthentication)
+	allowList storage.AllowList
+	auditLog  *AuditLog
+}
+
+func NewServer(cfg *Config, log *Log) *Server {
+	s := Server{
+		config:       cfg,
+		log:          log,
+		echoInstance: echo.New(),
+	}
+
+	s.echoInstance.HideBanner = true
+	if cfg.EnableLogging {
+		s.echoInstance.Use(echo_middleware.Logger())
+	}
+
+	s.echoInstance.POST(MessagesPath, s.sendMessages)
+	s.echoInstance.GET(MessagesPath, s.getMessages)
+	s.echoInstance.GET(AuditPath, s.getRejections)
+
+	return &s
+}
+
+// EnableAuthentication makes the board accept only messages signed by allow-listed keys,
+// rejected writes are recorded to the audit log
+func (s *Server) EnableAuthentication(allowList storage.AllowList, auditLog *AuditLog) {
+	s.allowList = allowList
+	s.auditLog = auditLog
+}
+
+// ServeHTTP allows to use the server as a http.Handler
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.echoInstance.ServeHTTP(w, r)
+}
+
+func (s *Server) Start() error {
+	return s.echoInstance.Start(s.config.ListenAddr)
+}
+
+func (s *Server) Stop(ctx context.Context) error {
+	return s.echoInstance.Shutdown(ctx)
+}
+
+func (s *Server) sendMessages(c echo.Context) error {
+	var msgs []storage.Message
+	if err := c.Bind(&msgs); err != nil {
+		return c.JSON(http.StatusBadRequest, &Response{ErrorMessage: fmt.Sprintf("failed to read request body: %v", err)})
+	}
+
+	if err := s.authenticate(c, msgs); err != nil {
+		return c.JSON(http.StatusForbidden, &Response{ErrorMessage: err.Error()})
+	}
+
+	stored, err := s.log.Append(msgs...)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, &Response{ErrorMessage: err.Error()})
+	}
+
+	return c.JSON(http.StatusOK, &Response{Result: stored})
+}
+
+// authenticate checks signatures of all messages, a batch is rejected as a whole if any message is not
+// authenticated, since batches are stored atomically
+func (s *Server) authenticate(c echo.Context, msgs []storage.Message) error {
+	if s.allowList == nil {
+		return nil
+	}
+
+	var (
+		rejections []Rejection
+		firstErr   error
+	)
+	for _, m := range msgs {
+		if err := s.allowList.Authenticate(m); err != nil {
+			rejections = append(rejections, Rejection{
+				Time:       time.Now().UTC(),
+				RemoteAddr: c.RealIP(),
+				Reason:     err.Error(),
+				Message:    m,
+			})
+			if firstErr == nil {
+				firstErr = err
+			}
+		}
+	}
+	if firstErr == nil {
+		return nil
+	}
+
+	if err := s.auditLog.Append(rejections...); err != nil {
+		c.Logger().Errorf("failed to audit rejected writes: %v", err)
+	}
+
+	return fmt.Errorf("%d of %d messages are rejected: %w", len(rejections), len(msgs), firstErr)
+}
+
+func (s *Server) getMessages(c echo.Context) error {
+	offset, limit, wait, err := parseReadParams(c)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, &Response{ErrorMessage: err.Error()})
+	}
+
+	if wait > 0 {
+		// an empty result is returned if there are no new messages before the timeout
+		ctx, cancel := context.WithTimeout(c.Request().Context(), wait)
+		defer cancel()
+		s.log.Wait(ctx, offset)
+	}
+
+	return c.JSON(http.StatusOK, &Response{Result: s.log.Read(offset, limit)})
+}
+
+// getRejections streams the audit log of rejected writes, request parameters are the same as for messages
+func (s *Server) getRejections(c echo.Context) error {
+	if s.auditLog == nil {
+		return c.JSON(http.StatusNotFound, &Response{ErrorMessage: "authentication is disabled on the board"})
+	}
+
+	offset, limit, wait, err := parseReadParams(c)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, &Response{ErrorMessage: err.Error()})
+	}
+
+	if wait > 0 {
+		ctx, cancel := context.WithTimeout(c.Request().Context(), wait)
+		defer cancel()
+		s.auditLog.Wait(ctx, offset)
+	}
+
+	return c.JSON(http.StatusOK, &Response{Result: s.auditLog.Read(offset, limit)})
+}
+
+func parseReadParams(c echo.Context) (offset uint64, limit int, wait time.Duration, err error) {
+	if offset, err = strconv.ParseUint(c.QueryParam(OffsetParam), 10, 64); err != nil {
+		return 0, 0, 0, fmt.Errorf("invalid offset: %v", err)
+	}
+
+	limit = MaxReadLimit
+	if limitParam := c.QueryParam(LimitParam); limitParam != "" {
+		if limit, err = strconv.Atoi(limitParam); err != nil || limit <= 0 || limit > MaxReadLimit {
+			return 0, 0, 0, fmt.Errorf("invalid limit: %s", limitParam)
+		}
+	}
+
+	if waitParam := c.QueryParam(WaitParam); waitParam != "" {
+		if wait, err = time.ParseDuration(waitParam); err != nil || wait < 0 || wait > MaxWait {
+			return 0, 0, 0, fmt.Errorf("invalid wait: %s", waitParam)
+		}
+	}
+
+	return offset, limit, wait, nil
+}
@@ -0,0 +1,92 @@
+package board
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/lidofinance/dc4bc/storage"
+)
+
+func postMessages(t *testing.T, server *Server, msgs ...storage.Message) int {
+	data, err := json.Marshal(msgs)
+	require.NoError(t, err)
+
+	req := httptest.NewRequest(http.MethodPost, MessagesPath, bytes.NewReader(data))
+	req.Header.Set("Content-Type", "application/json")
+	rec := httptest.NewRecorder()
+	server.ServeHTTP(rec, req)
+
+	return rec.Code
+}
+
+func TestServer_Authentication(t *testing.T) {
+	req := require.New(t)
+	dir := t.TempDir()
+
+	boardLog, err := OpenLog(filepath.Join(dir, "board_log"))
+	req.NoError(err)
+	defer boardLog.Close()
+
+	auditLog, err := OpenAuditLog(filepath.Join(dir, "audit_log"))
+	req.NoError(err)
+	defer auditLog.Close()
+
+	pubKey, privKey, err := ed25519.GenerateKey(nil)
+	req.NoError(err)
+	_, otherPrivKey, err := ed25519.GenerateKey(nil)
+	req.NoError(err)
+
+	server := NewServer(&Config{}, boardLog)
+	server.EnableAuthentication(storage.AllowList{"alice": pubKey}, auditLog)
+
+	signed := storage.Message{SenderAddr: "alice", Event: "event", Data: []byte("data")}
+	signed.Signature = ed25519.Sign(privKey, signed.Bytes())
+	signed.BoardSignature = ed25519.Sign(privKey, signed.BoardBytes())
+
+	forged := storage.Message{SenderAddr: "alice", Event: "event", Data: []byte("data")}
+	forged.BoardSignature = ed25519.Sign(otherPrivKey, forged.BoardBytes())
+
+	// the signed data is posted under another event
+	moved := signed
+	moved.Event = "other_event"
+
+	unknown := storage.Message{SenderAddr: "mallory", Data: []byte("data")}
+	unknown.BoardSignature = ed25519.Sign(otherPrivKey, unknown.BoardBytes())
+
+	req.Equal(http.StatusOK, postMessages(t, server, signed))
+	req.Equal(http.StatusForbidden, postMessages(t, server, signed, forged))
+	req.Equal(http.StatusForbidden, postMessages(t, server, moved))
+	req.Equal(http.StatusForbidden, postMessages(t, server, unknown))
+
+	// a rejected batch is not stored at all
+	req.Equal(uint64(1), boardLog.Len())
+
+	rec := httptest.NewRecorder()
+	server.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, AuditPath+"?offset=0", nil))
+	req.Equal(http.StatusOK, rec.Code)
+
+	var resp struct {
+		Result []Rejection `json:"result"`
+	}
+	req.NoError(json.Unmarshal(rec.Body.Bytes(), &resp))
+	req.Len(resp.Result, 3)
+	req.Contains(resp.Result[0].Reason, storage.ErrInvalidSignature.Error())
+	req.Contains(resp.Result[1].Reason, storage.ErrInvalidSignature.Error())
+	req.Contains(resp.Result[2].Reason, storage.ErrUnknownSender.Error())
+	req.Equal(uint64(2), resp.Result[2].Offset)
+	req.Equal("mallory", resp.Result[2].Message.SenderAddr)
+	req.NoError(auditLog.Close())
+
+	// the audit log survives restarts
+	auditLog, err = OpenAuditLog(filepath.Join(dir, "audit_log"))
+	req.NoError(err)
+	defer auditLog.Close()
+	req.Len(auditLog.Read(0, 0), 3)
+}
@@ -0,0 +1,45 @@
+package storage
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/binary"
+)
+
+// ChainHash returns a hash of the previous log entry hash and the message itself,
+// so every message is linked to all messages posted before it.
+func ChainHash(prevHash []byte, m Message) []byte {
+	h := sha256.New()
+	writeField := func(field []byte) {
+		lenBz := make([]byte, 8)
+		binary.BigEndian.PutUint64(lenBz, uint64(len(field)))
+		h.Write(lenBz)
+		h.Write(field)
+	}
+
+	offsetBz := make([]byte, 8)
+	binary.BigEndian.PutUint64(offsetBz, m.Offset)
+
+	writeField(prevHash)
+	writeField(offsetBz)
+	writeField([]byte(m.ID))
+	writeField([]byte(m.DkgRoundID))
+	writeField([]byte(m.Event))
+	writeField(m.Data)
+	writeField(m.Signature)
+	writeField([]byte(m.SenderAddr))
+	writeField([]byte(m.RecipientAddr))
+
+	return h.Sum(nil)
+}
+
+// LinkToChain sets the chain fields of the message following the entry with the given hash
+func (m *Message) LinkToChain(prevHash []byte) {
+	m.PrevHash = prevHash
+	m.Hash = ChainHash(prevHash, *m)
+}
+
+// VerifyChainHash checks that the message hash matches its content and the previous entry hash
+func (m *Message) VerifyChainHash() bool {
+	return len(m.Hash) > 0 && bytes.Equal(m.Hash, ChainHash(m.PrevHash, *m))
+}
@@ -2,19 +2,26 @@ package file_storage
 
 import (
 	"bufio"
+	"context"
 	"encoding/json"
 	"fmt"
 	"io"
 	"os"
 	"strconv"
 
+	"github.com/lidofinance/dc4bc/client/modules/logger"
 	"github.com/lidofinance/dc4bc/storage"
 
+	"github.com/fsnotify/fsnotify"
 	"github.com/google/uuid"
 	"github.com/juju/fslock"
 )
 
-var _ storage.Storage = (*FileStorage)(nil)
+var (
+	_ storage.Storage        = (*FileStorage)(nil)
+	_ storage.Subscriber     = (*FileStorage)(nil)
+	_ storage.LoggingStorage = (*FileStorage)(nil)
+)
 
 type FileStorage struct {
 	lockFile *fslock.Lock
@@ -23,6 +30,8 @@ type FileStorage struct {
 
 	idIgnoreList     map[string]struct{}
 	offsetIgnoreList map[uint64]struct{}
+
+	logger logger.Logger
 }
 
 const (
@@ -59,9 +68,14 @@ func NewFileStorage(filename string, lockFilename ...string) (storage.Storage, e
 
 	fs.idIgnoreList = map[string]struct{}{}
 	fs.offsetIgnoreList = map[uint64]struct{}{}
+	fs.logger = logger.NewLogger("")
 	return &fs, nil
 }
 
+func (fs *FileStorage) SetLogger(l logger.Logger) {
+	fs.logger = l
+}
+
 // Send sends a message to an append-only data file, returns a message with offset and id
 func (fs *FileStorage) send(m storage.Message) (storage.Message, error) {
 	var (
@@ -103,16 +117,101 @@ func (fs *FileStorage) Send(msgs ...storage.Message) error {
 
 // GetMessages returns a slice of messages from append-only data file with given offset
 func (fs *FileStorage) GetMessages(offset uint64) ([]storage.Message, error) {
-	var (
-		msgs []storage.Message
-		err  error
-		row  []byte
-		data storage.Message
-	)
-	if _, err = fs.dataFile.Seek(0, 0); err != nil {
+	if _, err := fs.dataFile.Seek(0, 0); err != nil {
 		return nil, fmt.Errorf("failed to seek a offset to the start of a data file:  %w", err)
 	}
-	scanner := bufio.NewScanner(fs.dataFile)
+
+	msgs, err := readMessages(fs.dataFile, offset)
+	if err != nil {
+		return nil, err
+	}
+	return fs.markIgnored(msgs), nil
+}
+
+// Subscribe streams messages from the data file, new messages are read when the file is written
+func (fs *FileStorage) Subscribe(ctx context.Context, offset uint64) <-chan storage.Message {
+	msgs := make(chan storage.Message)
+	go func() {
+		defer close(msgs)
+
+		watcher, err := fsnotify.NewWatcher()
+		if err != nil {
+			fs.logger.Errorf("failed to create a data file watcher: %v", err)
+			return
+		}
+		defer watcher.Close()
+
+		// the watcher is added before the first read, so no write is missed
+		if err = watcher.Add(fs.dataFile.Name()); err != nil {
+			fs.logger.Errorf("failed to watch a data file: %v", err)
+			return
+		}
+
+		// a separate file handle, so the subscription does not move the offset of the storage file
+		dataFile, err := os.Open(fs.dataFile.Name())
+		if err != nil {
+			fs.logger.Errorf("failed to open a data file: %v", err)
+			return
+		}
+		defer dataFile.Close()
+
+		// only the rows appended since the last read are read, rows before the offset are skipped
+		var (
+			pos  int64
+			rows uint64
+		)
+		for {
+			page, newPos, err := readAppendedMessages(dataFile, pos, &rows, offset)
+			if err != nil {
+				fs.logger.Errorf("failed to read messages: %v", err)
+				return
+			}
+			pos = newPos
+
+			for _, m := range fs.markIgnored(page) {
+				select {
+				case msgs <- m:
+				case <-ctx.Done():
+					return
+				}
+			}
+
+			if !waitForWrite(ctx, watcher, fs.logger) {
+				return
+			}
+		}
+	}()
+
+	return msgs
+}
+
+// waitForWrite returns true when the watched file is written, and false when the context is done
+// or the watcher fails
+func waitForWrite(ctx context.Context, watcher *fsnotify.Watcher, l logger.Logger) bool {
+	for {
+		select {
+		case event, ok := <-watcher.Events:
+			if !ok {
+				return false
+			}
+			if event.Op&fsnotify.Write == fsnotify.Write {
+				return true
+			}
+		case err, ok := <-watcher.Errors:
+			if ok {
+				l.Errorf("data file watcher failed: %v", err)
+			}
+			return false
+		case <-ctx.Done():
+			return false
+		}
+	}
+}
+
+// readMessages reads all messages starting from the given offset (line number)
+func readMessages(r io.Reader, offset uint64) ([]storage.Message, error) {
+	var msgs []storage.Message
+	scanner := bufio.NewScanner(r)
 	buf := make([]byte, 0, 64*1024)
 	scanner.Buffer(buf, 1024*1024)
 	for scanner.Scan() {
@@ -121,16 +220,11 @@ func (fs *FileStorage) GetMessages(offset uint64) ([]storage.Message, error) {
 			continue
 		}
 
-		row = scanner.Bytes()
-		if err = json.Unmarshal(row, &data); err != nil {
-			return nil, fmt.Errorf("failed to unmarshal a message %s: %w", string(row), err)
-		}
-
-		_, idOk := fs.idIgnoreList[data.ID]
-		_, offsetOk := fs.offsetIgnoreList[data.Offset]
-		if !idOk && !offsetOk {
-			msgs = append(msgs, data)
+		data, err := unmarshalMessage(scanner.Bytes())
+		if err != nil {
+			return nil, err
 		}
+		msgs = append(msgs, data)
 	}
 	if scanner.Err() != nil {
 		return nil, fmt.Errorf("failed to read a data file: %w", scanner.Err())
@@ -138,6 +232,58 @@ func (fs *FileStorage) GetMessages(offset uint64) ([]storage.Message, error) {
 	return msgs, nil
 }
 
+// readAppendedMessages reads the complete rows written after the given position of the file and returns
+// the position after the last of them. rows is the number of rows read so far, rows before the offset are skipped
+func readAppendedMessages(f io.ReadSeeker, pos int64, rows *uint64, offset uint64) ([]storage.Message, int64, error) {
+	if _, err := f.Seek(pos, io.SeekStart); err != nil {
+		return nil, pos, fmt.Errorf("failed to seek a data file: %w", err)
+	}
+
+	var msgs []storage.Message
+	reader := bufio.NewReader(f)
+	for {
+		row, err := reader.ReadBytes('\n')
+		if err == io.EOF {
+			// the last row is not written completely yet, it's read again on the next write
+			return msgs, pos, nil
+		}
+		if err != nil {
+			return nil, pos, fmt.Errorf("failed to read a data file: %w", err)
+		}
+		pos += int64(len(row))
+		*rows++
+		if *rows <= offset {
+			continue
+		}
+
+		data, err := unmarshalMessage(row)
+		if err != nil {
+			return nil, pos, err
+		}
+		msgs = append(msgs, data)
+	}
+}
+
+// unmarshalMessage decodes a row of the data file. A new message is used for every row, otherwise
+// decoded byte slices share the same memory
+func unmarshalMessage(row []byte) (storage.Message, error) {
+	var data storage.Message
+	if err := json.Unmarshal(row, &data); err != nil {
+		return data, fmt.Errorf("failed to unmarshal a message %s: %w", string(row), err)
+	}
+	return data, nil
+}
+
+// markIgnored marks messages from the ignore list, they are not dropped to keep the log verifiable
+func (fs *FileStorage) markIgnored(page []storage.Message) []storage.Message {
+	for i, m := range page {
+		_, idOk := fs.idIgnoreList[m.ID]
+		_, offsetOk := fs.offsetIgnoreList[m.Offset]
+		page[i].Ignored = idOk || offsetOk
+	}
+	return page
+}
+
 func (fs *FileStorage) Close() error {
 	return fs.dataFile.Close()
 }
@@ -1,6 +1,7 @@
 package file_storage
 
 import (
+	"context"
 	"math/rand"
 	"os"
 	"reflect"
@@ -87,7 +88,9 @@ func TestFileStorage_IgnoreMessages(t *testing.T) {
 		t.Error(err)
 	}
 
-	expectedMsgs := msgs[2:]
+	// ignored messages are read, but marked
+	expectedMsgs := append([]storage.Message{}, msgs...)
+	expectedMsgs[0].Ignored, expectedMsgs[1].Ignored = true, true
 	if !reflect.DeepEqual(msgsAfterIgnoring, expectedMsgs) {
 		t.Errorf("expected messages: %v, actual messages: %v", expectedMsgs, msgsAfterIgnoring)
 	}
@@ -103,3 +106,77 @@ func TestFileStorage_IgnoreMessages(t *testing.T) {
 		t.Errorf("expected messages: %v, actual messages: %v", msgs, msgsAfterUnignoring)
 	}
 }
+
+func TestFileStorage_Subscribe(t *testing.T) {
+	N := 10
+	var testFile = "/tmp/dc4bc_test_file_storage"
+	fs, err := NewFileStorage(testFile)
+	if err != nil {
+		t.Error(err)
+	}
+	defer fs.Close()
+	defer os.Remove(testFile)
+
+	msgs := make([]storage.Message, N)
+	if err = fs.Send(msgs[:N/2]...); err != nil {
+		t.Error(err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	subscription := fs.(storage.Subscriber).Subscribe(ctx, 2)
+
+	// messages sent after subscribing are pushed as well
+	go func() {
+		if err := fs.Send(msgs[N/2:]...); err != nil {
+			t.Error(err)
+		}
+	}()
+
+	for i := 2; i < N; i++ {
+		m, ok := <-subscription
+		if !ok {
+			t.Fatalf("subscription closed before message %d", i)
+		}
+		if m.Offset != uint64(i) {
+			t.Errorf("expected offset: %d, actual offset: %d", i, m.Offset)
+		}
+	}
+
+	cancel()
+	for range subscription {
+	}
+}
+
+func TestReadAppendedMessages(t *testing.T) {
+	f, err := os.CreateTemp(t.TempDir(), "data")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	// the last row is not written completely yet
+	if _, err = f.WriteString(`{"id":"0","offset":0}` + "\n" + `{"id":"1","offset":1}` + "\n" + `{"id":"2",`); err != nil {
+		t.Fatal(err)
+	}
+
+	var rows uint64
+	msgs, pos, err := readAppendedMessages(f, 0, &rows, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(msgs) != 1 || msgs[0].ID != "1" || rows != 2 {
+		t.Fatalf("unexpected messages: %v, rows read: %d", msgs, rows)
+	}
+
+	if _, err = f.WriteString(`"offset":2}` + "\n"); err != nil {
+		t.Fatal(err)
+	}
+	msgs, _, err = readAppendedMessages(f, pos, &rows, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(msgs) != 1 || msgs[0].ID != "2" || rows != 3 {
+		t.Fatalf("unexpected messages: %v, rows read: %d", msgs, rows)
+	}
+}
@@ -0,0 +1,236 @@
+package http_storage
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/lidofinance/dc4bc/client/modules/logger"
+	"github.com/lidofinance/dc4bc/storage"
+	"github.com/lidofinance/dc4bc/storage/board"
+)
+
+var (
+	_ storage.Storage        = (*HTTPStorage)(nil)
+	_ storage.Subscriber     = (*HTTPStorage)(nil)
+	_ storage.LoggingStorage = (*HTTPStorage)(nil)
+)
+
+// maxLongPollWait is the maximum time a single subscription request waits for new messages
+const maxLongPollWait = 30 * time.Second
+
+type messagesResponse struct {
+	ErrorMessage string            `json:"error_message,omitempty"`
+	Result       []storage.Message `json:"result"`
+}
+
+// HTTPStorage is a client of the HTTP bulletin board (see storage/board)
+type HTTPStorage struct {
+	endpoint     string
+	client       *http.Client
+	longPollWait time.Duration
+
+	idIgnoreList     map[string]struct{}
+	offsetIgnoreList map[uint64]struct{}
+
+	logger logger.Logger
+}
+
+// NewHTTPStorage inits a bulletin board client, endpoint is a board's base URL, e.g. http://localhost:9090
+func NewHTTPStorage(endpoint string, timeout time.Duration) (*HTTPStorage, error) {
+	if _, err := url.ParseRequestURI(endpoint); err != nil {
+		return nil, fmt.Errorf("invalid board endpoint %s: %w", endpoint, err)
+	}
+
+	// a long polling request must finish before the client timeout
+	longPollWait := timeout / 2
+	if longPollWait > maxLongPollWait {
+		longPollWait = maxLongPollWait
+	}
+
+	return &HTTPStorage{
+		endpoint:     strings.TrimRight(endpoint, "/"),
+		client:       &http.Client{Timeout: timeout},
+		longPollWait: longPollWait,
+
+		idIgnoreList:     map[string]struct{}{},
+		offsetIgnoreList: map[uint64]struct{}{},
+
+		logger: logger.NewLogger(""),
+	}, nil
+}
+
+func (s *HTTPStorage) SetLogger(l logger.Logger) {
+	s.logger = l
+}
+
+// Send posts all messages to the board in a single request, so they are stored atomically.
+// Offsets and ids assigned by the board are written back to the given messages.
+func (s *HTTPStorage) Send(msgs ...storage.Message) error {
+	data, err := json.Marshal(msgs)
+	if err != nil {
+		return fmt.Errorf("failed to marshal messages: %w", err)
+	}
+
+	resp, err := s.client.Post(s.endpoint+board.MessagesPath, "application/json", bytes.NewReader(data))
+	if err != nil {
+		return fmt.Errorf("failed to send messages: %w", err)
+	}
+
+	stored, err := readMessagesResponse(resp)
+	if err != nil {
+		return fmt.Errorf("failed to send messages: %w", err)
+	}
+	if len(stored) != len(msgs) {
+		return fmt.Errorf("board stored %d messages, %d expected", len(stored), len(msgs))
+	}
+	copy(msgs, stored)
+
+	return nil
+}
+
+// GetMessages returns all messages starting from the given offset
+func (s *HTTPStorage) GetMessages(offset uint64) ([]storage.Message, error) {
+	var msgs []storage.Message
+	for {
+		page, err := s.getPage(context.Background(), offset, 0)
+		if err != nil {
+			return nil, err
+		}
+
+		msgs = append(msgs, s.markIgnored(page)...)
+
+		if len(page) < board.MaxReadLimit {
+			return msgs, nil
+		}
+		offset = page[len(page)-1].Offset + 1
+	}
+}
+
+// Subscribe streams messages using long polling requests to the board
+func (s *HTTPStorage) Subscribe(ctx context.Context, offset uint64) <-chan storage.Message {
+	msgs := make(chan storage.Message)
+	go func() {
+		defer close(msgs)
+		for ctx.Err() == nil {
+			page, err := s.getPage(ctx, offset, s.longPollWait)
+			if err != nil {
+				if ctx.Err() == nil {
+					s.logger.Errorf("failed to get messages from the board: %v", err)
+				}
+				return
+			}
+			if len(page) > 0 {
+				offset = page[len(page)-1].Offset + 1
+			}
+
+			for _, m := range s.markIgnored(page) {
+				select {
+				case msgs <- m:
+				case <-ctx.Done():
+					return
+				}
+			}
+		}
+	}()
+
+	return msgs
+}
+
+// markIgnored marks messages from the ignore list, they are not dropped to keep the log verifiable
+func (s *HTTPStorage) markIgnored(page []storage.Message) []storage.Message {
+	for i, m := range page {
+		_, idOk := s.idIgnoreList[m.ID]
+		_, offsetOk := s.offsetIgnoreList[m.Offset]
+		page[i].Ignored = idOk || offsetOk
+	}
+	return page
+}
+
+// getPage reads a page of messages, if wait is not zero the board waits for new messages (long polling)
+func (s *HTTPStorage) getPage(ctx context.Context, offset uint64, wait time.Duration) ([]storage.Message, error) {
+	query := url.Values{}
+	query.Set(board.OffsetParam, strconv.FormatUint(offset, 10))
+	query.Set(board.LimitParam, strconv.Itoa(board.MaxReadLimit))
+	if wait > 0 {
+		query.Set(board.WaitParam, wait.String())
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, s.endpoint+board.MessagesPath+"?"+query.Encode(), nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get messages: %w", err)
+	}
+
+	msgs, err := readMessagesResponse(resp)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get messages: %w", err)
+	}
+
+	return msgs, nil
+}
+
+func readMessagesResponse(resp *http.Response) ([]storage.Message, error) {
+	defer resp.Body.Close()
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read body: %w", err)
+	}
+
+	var response messagesResponse
+	if resp.StatusCode < http.StatusOK || resp.StatusCode >= http.StatusMultipleChoices {
+		// the error message is reported if the board sent it, a proxy in front of the board may send anything
+		if err = json.Unmarshal(body, &response); err == nil && response.ErrorMessage != "" {
+			return nil, fmt.Errorf("board returned an error (status %d): %s", resp.StatusCode, response.ErrorMessage)
+		}
+		return nil, fmt.Errorf("board returned status %d", resp.StatusCode)
+	}
+
+	if err = json.Unmarshal(body, &response); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal response: %w", err)
+	}
+	if response.ErrorMessage != "" {
+		return nil, fmt.Errorf("board returned an error: %s", response.ErrorMessage)
+	}
+
+	return response.Result, nil
+}
+
+func (s *HTTPStorage) Close() error {
+	s.client.CloseIdleConnections()
+	return nil
+}
+
+func (s *HTTPStorage) IgnoreMessages(messages []string, useOffset bool) error {
+	for _, msg := range messages {
+		if useOffset {
+			offset, err := strconv.ParseUint(msg, 10, 64)
+			if err != nil {
+				return fmt.Errorf("failed to parse message offset: %w", err)
+			}
+			s.offsetIgnoreList[offset] = struct{}{}
+
+			continue
+		}
+
+		s.idIgnoreList[msg] = struct{}{}
+	}
+
+	return nil
+}
+
+func (s *HTTPStorage) UnignoreMessages() {
+	s.idIgnoreList = map[string]struct{}{}
+	s.offsetIgnoreList = map[uint64]struct{}{}
+}
@@ -0,0 +1,163 @@
+package http_storage
+
+import (
+	"context"
+	"math/rand"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/lidofinance/dc4bc/storage"
+	"github.com/lidofinance/dc4bc/storage/board"
+)
+
+func randomBytes(n int) []byte {
+	rand.Seed(time.Now().UnixNano())
+	b := make([]byte, n)
+	if _, err := rand.Read(b); err != nil {
+		return nil
+	}
+	return b
+}
+
+func newTestStorage(t *testing.T) *HTTPStorage {
+	boardLog, err := board.OpenLog(filepath.Join(t.TempDir(), "board_log"))
+	require.NoError(t, err)
+	t.Cleanup(func() { boardLog.Close() })
+
+	server := httptest.NewServer(board.NewServer(&board.Config{}, boardLog))
+	t.Cleanup(server.Close)
+
+	stg, err := NewHTTPStorage(server.URL, 10*time.Second)
+	require.NoError(t, err)
+
+	return stg
+}
+
+func TestHTTPStorage_Send(t *testing.T) {
+	var (
+		N   = 10
+		req = require.New(t)
+		stg = newTestStorage(t)
+	)
+
+	msgs := make([]storage.Message, 0, N)
+	for i := 0; i < N; i++ {
+		msgs = append(msgs, storage.Message{
+			Data:      randomBytes(10),
+			Signature: randomBytes(10),
+		})
+	}
+
+	req.NoError(stg.Send(msgs...))
+	for i, m := range msgs {
+		req.Equal(uint64(i), m.Offset)
+		req.NotEmpty(m.ID)
+	}
+
+	offsetMsgs, err := stg.GetMessages(0)
+	req.NoError(err)
+	req.Equal(msgs, offsetMsgs)
+
+	offsetMsgs, err = stg.GetMessages(uint64(N - 2))
+	req.NoError(err)
+	req.Equal(msgs[N-2:], offsetMsgs)
+}
+
+func TestHTTPStorage_GetMessagesPagination(t *testing.T) {
+	var (
+		N   = board.MaxReadLimit + 10
+		req = require.New(t)
+		stg = newTestStorage(t)
+	)
+
+	msgs := make([]storage.Message, N)
+	req.NoError(stg.Send(msgs...))
+
+	offsetMsgs, err := stg.GetMessages(5)
+	req.NoError(err)
+	req.Len(offsetMsgs, N-5)
+	req.Equal(uint64(N-1), offsetMsgs[len(offsetMsgs)-1].Offset)
+}
+
+func TestHTTPStorage_IgnoreMessages(t *testing.T) {
+	var (
+		N   = 10
+		req = require.New(t)
+		stg = newTestStorage(t)
+	)
+
+	msgs := make([]storage.Message, N)
+	req.NoError(stg.Send(msgs...))
+
+	req.NoError(stg.IgnoreMessages([]string{msgs[0].ID}, false))
+	req.NoError(stg.IgnoreMessages([]string{strconv.Itoa(1)}, true))
+
+	msgsAfterIgnoring, err := stg.GetMessages(0)
+	req.NoError(err)
+	// ignored messages are read, but marked
+	expectedMsgs := append([]storage.Message{}, msgs...)
+	expectedMsgs[0].Ignored, expectedMsgs[1].Ignored = true, true
+	req.Equal(expectedMsgs, msgsAfterIgnoring)
+
+	stg.UnignoreMessages()
+
+	msgsAfterUnignoring, err := stg.GetMessages(0)
+	req.NoError(err)
+	req.Equal(msgs, msgsAfterUnignoring)
+}
+
+func TestHTTPStorage_Subscribe(t *testing.T) {
+	var (
+		N   = 10
+		req = require.New(t)
+		stg = newTestStorage(t)
+	)
+
+	msgs := make([]storage.Message, N)
+	req.NoError(stg.Send(msgs[:N/2]...))
+	req.NoError(stg.IgnoreMessages([]string{strconv.Itoa(N - 1)}, true))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	subscription := stg.Subscribe(ctx, 2)
+
+	// the subscription is waiting on the board, messages sent later are pushed as well
+	go func() {
+		time.Sleep(100 * time.Millisecond)
+		req.NoError(stg.Send(msgs[N/2:]...))
+	}()
+
+	for i := 2; i < N-1; i++ {
+		m, ok := <-subscription
+		req.True(ok)
+		req.Equal(uint64(i), m.Offset)
+	}
+
+	cancel()
+	for range subscription {
+	}
+}
+
+func TestHTTPStorage_ErrorStatus(t *testing.T) {
+	req := require.New(t)
+
+	// a proxy in front of the board answers with a JSON body without an error message
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadGateway)
+		_, _ = w.Write([]byte(`{"status": "unavailable"}`))
+	}))
+	defer server.Close()
+
+	stg, err := NewHTTPStorage(server.URL, 10*time.Second)
+	req.NoError(err)
+
+	_, err = stg.GetMessages(0)
+	req.ErrorContains(err, "status 502")
+	req.ErrorContains(stg.Send(storage.Message{Data: randomBytes(10)}), "status 502")
+}
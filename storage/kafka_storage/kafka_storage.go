@@ -6,7 +6,6 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
-	"log"
 	"net"
 	"strconv"
 	"strings"
@@ -16,6 +15,7 @@ import (
 	"github.com/segmentio/kafka-go/sasl/plain"
 
 	"github.com/lidofinance/dc4bc/client/config"
+	"github.com/lidofinance/dc4bc/client/modules/logger"
 	"github.com/lidofinance/dc4bc/storage"
 )
 
@@ -26,6 +26,13 @@ const (
 	kafkaBatchBytes  = 10e6
 )
 
+var (
+	_ storage.Storage        = (*KafkaStorage)(nil)
+	_ storage.Subscriber     = (*KafkaStorage)(nil)
+	_ storage.Snapshotter    = (*KafkaStorage)(nil)
+	_ storage.LoggingStorage = (*KafkaStorage)(nil)
+)
+
 type KafkaAuthCredentials struct {
 	Username string
 	Password string
@@ -44,6 +51,8 @@ type KafkaStorage struct {
 
 	idIgnoreList     map[string]struct{}
 	offsetIgnoreList map[uint64]struct{}
+
+	logger logger.Logger
 }
 
 func parseKafkaSaslPlain(creds string) (*plain.Mechanism, error) {
@@ -99,6 +108,8 @@ func NewKafkaStorage(cfg *config.KafkaStorageConfig) (*KafkaStorage, error) {
 
 		idIgnoreList:     map[string]struct{}{},
 		offsetIgnoreList: map[uint64]struct{}{},
+
+		logger: logger.NewLogger(""),
 	}
 	if err := ks.reset(); err != nil {
 		return nil, fmt.Errorf("failed to create a NewKafkaStorage: %w", err)
@@ -141,10 +152,7 @@ func (ks *KafkaStorage) GetMessages(_ uint64) ([]storage.Message, error) {
 	ctx, cancel := context.WithDeadline(ks.readerCtx, time.Now().Add(ks.readDuration))
 	defer cancel()
 
-	var (
-		message  storage.Message
-		messages []storage.Message
-	)
+	var messages []storage.Message
 	for {
 		kafkaMessage, err := ks.reader.ReadMessage(ctx)
 		if err != nil {
@@ -155,16 +163,87 @@ func (ks *KafkaStorage) GetMessages(_ uint64) ([]storage.Message, error) {
 			}
 		}
 
-		if err = json.Unmarshal(kafkaMessage.Value, &message); err != nil {
-			log.Printf("failed to unmarshal a message %s: %s", string(kafkaMessage.Value), err.Error())
-			continue
+		if message, ok := ks.kafkaToStorageMessage(kafkaMessage); ok {
+			messages = append(messages, message)
 		}
+	}
 
-		message.Offset = uint64(kafkaMessage.Offset)
+	return messages, nil
+}
+
+// Subscribe streams messages starting from the given offset. Unlike GetMessages, it reads the partition
+// without the consumer group, so no offset is committed by the reader: the node saves the offset after
+// a message is handled, and a message which was not handled is read again on restart.
+// The topic is expected to have a single partition.
+func (ks *KafkaStorage) Subscribe(ctx context.Context, offset uint64) <-chan storage.Message {
+	messages := make(chan storage.Message)
+
+	go func() {
+		defer close(messages)
+
+		reader := ks.newPartitionReader()
+		defer reader.Close()
+		if err := reader.SetOffset(int64(offset)); err != nil {
+			ks.logger.Errorf("failed to SetOffset: %v", err)
+			return
+		}
 
-		_, idOk := ks.idIgnoreList[message.ID]
-		_, offsetOk := ks.offsetIgnoreList[message.Offset]
-		if !idOk && !offsetOk {
+		for {
+			kafkaMessage, err := reader.ReadMessage(ctx)
+			if err != nil {
+				if !errors.Is(err, context.Canceled) {
+					ks.logger.Errorf("failed to ReadMessage: %v", err)
+				}
+				return
+			}
+
+			// a corrupted message is skipped, the node sees the gap in the log and stops there
+			message, ok := ks.kafkaToStorageMessage(kafkaMessage)
+			if !ok {
+				continue
+			}
+
+			select {
+			case messages <- message:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	return messages
+}
+
+// ReadAll reads all messages which are still kept in the topic, from the first offset to the last one.
+// Unlike GetMessages, it does not use the consumer group, so the consumer group offset is not moved.
+// The topic is expected to have a single partition.
+func (ks *KafkaStorage) ReadAll(ctx context.Context) ([]storage.Message, error) {
+	dialer := ks.consumerDialer()
+	conn, err := dialer.DialLeader(ctx, "tcp", ks.brokerEndpoint, ks.topic, 0)
+	if err != nil {
+		return nil, fmt.Errorf("failed to DialLeader: %w", err)
+	}
+	first, last, err := conn.ReadOffsets()
+	conn.Close()
+	if err != nil {
+		return nil, fmt.Errorf("failed to ReadOffsets: %w", err)
+	}
+
+	reader := ks.newPartitionReader()
+	defer reader.Close()
+	if err = reader.SetOffset(first); err != nil {
+		return nil, fmt.Errorf("failed to SetOffset: %w", err)
+	}
+
+	var messages []storage.Message
+	for offset := first; offset < last; {
+		kafkaMessage, err := reader.ReadMessage(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("failed to ReadMessage: %w", err)
+		}
+		offset = kafkaMessage.Offset + 1
+
+		if message, ok := ks.kafkaToStorageMessage(kafkaMessage); ok {
 			messages = append(messages, message)
 		}
 	}
@@ -172,6 +251,28 @@ func (ks *KafkaStorage) GetMessages(_ uint64) ([]storage.Message, error) {
 	return messages, nil
 }
 
+// kafkaToStorageMessage decodes a Kafka message, it returns false if the message is corrupted.
+// Messages from the ignore list are marked, not dropped, to keep the log verifiable
+func (ks *KafkaStorage) kafkaToStorageMessage(kafkaMessage kafka.Message) (storage.Message, bool) {
+	var message storage.Message
+	if err := json.Unmarshal(kafkaMessage.Value, &message); err != nil {
+		ks.logger.With(logger.Offset(uint64(kafkaMessage.Offset)), logger.Err(err)).
+			Warnf("failed to unmarshal a message %s", string(kafkaMessage.Value))
+		return message, false
+	}
+
+	message.Offset = uint64(kafkaMessage.Offset)
+
+	_, idOk := ks.idIgnoreList[message.ID]
+	_, offsetOk := ks.offsetIgnoreList[message.Offset]
+	message.Ignored = idOk || offsetOk
+	return message, true
+}
+
+func (ks *KafkaStorage) SetLogger(l logger.Logger) {
+	ks.logger = l
+}
+
 func (ks *KafkaStorage) IgnoreMessages(messages []string, useOffset bool) error {
 	for _, msg := range messages {
 		if useOffset {
@@ -229,12 +330,7 @@ func (ks *KafkaStorage) reset() error {
 		MinBytes:    kafkaMinBytes,
 		MaxBytes:    kafkaMaxBytes,
 		MaxAttempts: kafkaMaxAttempts,
-		Dialer: &kafka.Dialer{
-			Timeout:       ks.timeout,
-			DualStack:     true,
-			TLS:           ks.tlsConfig,
-			SASLMechanism: ks.consumerCreds,
-		},
+		Dialer:      ks.consumerDialer(),
 	})
 	ks.readerCtx, ks.readerCtxCancel = context.WithCancel(context.Background())
 
@@ -258,3 +354,25 @@ func (ks *KafkaStorage) reset() error {
 
 	return nil
 }
+
+// newPartitionReader returns a reader of the first partition of the topic which does not use the consumer group
+func (ks *KafkaStorage) newPartitionReader() *kafka.Reader {
+	return kafka.NewReader(kafka.ReaderConfig{
+		Brokers:     []string{ks.brokerEndpoint},
+		Topic:       ks.topic,
+		Partition:   0,
+		MinBytes:    kafkaMinBytes,
+		MaxBytes:    kafkaMaxBytes,
+		MaxAttempts: kafkaMaxAttempts,
+		Dialer:      ks.consumerDialer(),
+	})
+}
+
+func (ks *KafkaStorage) consumerDialer() *kafka.Dialer {
+	return &kafka.Dialer{
+		Timeout:       ks.timeout,
+		DualStack:     true,
+		TLS:           ks.tlsConfig,
+		SASLMechanism: ks.consumerCreds,
+	}
+}
@@ -0,0 +1,226 @@
+package storage
+
+import (
+	"bytes"
+	"context"
+	"crypto/ed25519"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"time"
+)
+
+// SnapshotVersion is the current version of the snapshot format
+const SnapshotVersion = 1
+
+var (
+	ErrSnapshotCorrupted       = errors.New("snapshot is corrupted")
+	ErrSnapshotNotSigned       = errors.New("snapshot is not signed")
+	ErrUntrustedSnapshotSigner = errors.New("snapshot is signed by an untrusted key")
+)
+
+// Snapshot is an archive of the board log: all messages with their offsets and signatures,
+// sealed with a digest over the whole log. The digest is signed by the node which took the snapshot
+type Snapshot struct {
+	Version   int       `json:"version"`
+	CreatedAt time.Time `json:"created_at"`
+	Messages  []Message `json:"messages"`
+	Digest    []byte    `json:"digest"`
+
+	Signer    string            `json:"signer,omitempty"`
+	PubKey    ed25519.PublicKey `json:"pub_key,omitempty"`
+	Signature []byte            `json:"signature,omitempty"`
+}
+
+// Snapshotter is an optional Storage capability to read the whole log regardless of a reading
+// position, e.g. Kafka consumer group offsets
+type Snapshotter interface {
+	ReadAll(ctx context.Context) ([]Message, error)
+}
+
+// TakeSnapshot reads all messages from the storage, ignored messages included. Storages which are not Snapshotters
+// are read from the zero offset.
+func TakeSnapshot(ctx context.Context, stg Storage) (*Snapshot, error) {
+	var (
+		msgs []Message
+		err  error
+	)
+	if snapshotter, ok := stg.(Snapshotter); ok {
+		msgs, err = snapshotter.ReadAll(ctx)
+	} else {
+		msgs, err = stg.GetMessages(0)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read messages: %w", err)
+	}
+
+	return NewSnapshot(msgs), nil
+}
+
+func NewSnapshot(msgs []Message) *Snapshot {
+	if msgs == nil {
+		msgs = []Message{}
+	}
+	return &Snapshot{
+		Version:   SnapshotVersion,
+		CreatedAt: time.Now().UTC(),
+		Messages:  msgs,
+		Digest:    SnapshotDigest(msgs),
+	}
+}
+
+// SnapshotDigest chains all messages with ChainHash, so the digest covers offsets, signatures and order
+func SnapshotDigest(msgs []Message) []byte {
+	var digest []byte
+	for _, m := range msgs {
+		digest = ChainHash(digest, m)
+	}
+	return digest
+}
+
+// Signed reports whether the snapshot digest is signed
+func (s *Snapshot) Signed() bool {
+	return len(s.Signature) != 0
+}
+
+// Verify checks the snapshot format, the order of offsets, the digest and its signature if the snapshot is signed
+func (s *Snapshot) Verify() error {
+	if s.Version != SnapshotVersion {
+		return fmt.Errorf("unsupported snapshot version %d", s.Version)
+	}
+	for i := 1; i < len(s.Messages); i++ {
+		if s.Messages[i].Offset <= s.Messages[i-1].Offset {
+			return fmt.Errorf("%w: offset %d follows offset %d", ErrSnapshotCorrupted,
+				s.Messages[i].Offset, s.Messages[i-1].Offset)
+		}
+	}
+	if !bytes.Equal(s.Digest, SnapshotDigest(s.Messages)) {
+		return fmt.Errorf("%w: digest mismatch", ErrSnapshotCorrupted)
+	}
+	if s.Signed() && (len(s.PubKey) != ed25519.PublicKeySize || !ed25519.Verify(s.PubKey, s.Digest, s.Signature)) {
+		return fmt.Errorf("%w: signature of %s is corrupt", ErrSnapshotCorrupted, s.Signer)
+	}
+
+	return nil
+}
+
+// VerifySigner checks that the snapshot is signed by one of the trusted keys. The key in the snapshot proves nothing
+// by itself, anyone who edits a snapshot can sign it again with their own key, so the snapshot must be verified with
+// Verify as well
+func (s *Snapshot) VerifySigner(trustedKeys []ed25519.PublicKey) error {
+	if !s.Signed() {
+		return ErrSnapshotNotSigned
+	}
+	for _, key := range trustedKeys {
+		if key.Equal(s.PubKey) {
+			return nil
+		}
+	}
+
+	return fmt.Errorf("%w: %s (%s)", ErrUntrustedSnapshotSigner, s.Signer, hex.EncodeToString(s.PubKey))
+}
+
+// SnapshotReport is a result of a snapshot comparison with a live log
+type SnapshotReport struct {
+	// Matched is the number of snapshot messages found in the live log
+	Matched int `json:"matched"`
+	// Trimmed is the number of snapshot messages older than the first live message, e.g. removed by retention
+	Trimmed int `json:"trimmed"`
+	// New is the number of live messages newer than the last snapshot message
+	New int `json:"new"`
+	// Older is the number of live messages older than the first snapshot message
+	Older int `json:"older"`
+	// Missing are offsets of snapshot messages absent in the live log
+	Missing []uint64 `json:"missing,omitempty"`
+	// Unexpected are offsets of live messages absent in the snapshot
+	Unexpected []uint64 `json:"unexpected,omitempty"`
+	// Mismatched are offsets of messages which differ in the snapshot and the live log
+	Mismatched []uint64 `json:"mismatched,omitempty"`
+}
+
+// Consistent is true if the snapshot and the live log agree on all common offsets
+func (r *SnapshotReport) Consistent() bool {
+	return len(r.Missing) == 0 && len(r.Unexpected) == 0 && len(r.Mismatched) == 0
+}
+
+// Compare compares the snapshot with live messages. Messages removed from the live log by retention
+// and messages appended after the snapshot was taken are counted, but do not make the report inconsistent.
+func (s *Snapshot) Compare(live []Message) *SnapshotReport {
+	report := &SnapshotReport{}
+	if len(live) == 0 {
+		report.Trimmed = len(s.Messages)
+		return report
+	}
+
+	var (
+		firstLive    = live[0].Offset
+		liveByOffset = make(map[uint64]Message, len(live))
+	)
+	for _, m := range live {
+		liveByOffset[m.Offset] = m
+	}
+
+	snapshotOffsets := make(map[uint64]struct{}, len(s.Messages))
+	for _, m := range s.Messages {
+		snapshotOffsets[m.Offset] = struct{}{}
+		if m.Offset < firstLive {
+			report.Trimmed++
+			continue
+		}
+
+		liveMessage, ok := liveByOffset[m.Offset]
+		switch {
+		case !ok:
+			report.Missing = append(report.Missing, m.Offset)
+		case !bytes.Equal(ChainHash(nil, m), ChainHash(nil, liveMessage)):
+			report.Mismatched = append(report.Mismatched, m.Offset)
+		default:
+			report.Matched++
+		}
+	}
+
+	for _, m := range live {
+		if _, ok := snapshotOffsets[m.Offset]; ok {
+			continue
+		}
+		switch {
+		case len(s.Messages) == 0 || m.Offset > s.Messages[len(s.Messages)-1].Offset:
+			report.New++
+		case m.Offset < s.Messages[0].Offset:
+			report.Older++
+		default:
+			report.Unexpected = append(report.Unexpected, m.Offset)
+		}
+	}
+
+	return report
+}
+
+func ReadSnapshotFile(filename string) (*Snapshot, error) {
+	data, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read snapshot file: %w", err)
+	}
+
+	var snapshot Snapshot
+	if err = json.Unmarshal(data, &snapshot); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal snapshot: %w", err)
+	}
+
+	return &snapshot, nil
+}
+
+func WriteSnapshotFile(filename string, snapshot *Snapshot) error {
+	data, err := json.Marshal(snapshot)
+	if err != nil {
+		return fmt.Errorf("failed to marshal snapshot: %w", err)
+	}
+
+	if err = ioutil.WriteFile(filename, data, 0644); err != nil {
+		return fmt.Errorf("failed to write snapshot file: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,93 @@
+package snapshot_storage
+
+import (
+	"crypto/ed25519"
+	"errors"
+	"fmt"
+	"strconv"
+
+	"github.com/lidofinance/dc4bc/storage"
+)
+
+var _ storage.Storage = (*SnapshotStorage)(nil)
+
+var ErrReadOnly = errors.New("snapshot storage is read-only")
+
+// SnapshotStorage is a read-only storage serving messages of a board snapshot,
+// it is used to replay an archived log into a new node state
+type SnapshotStorage struct {
+	snapshot *storage.Snapshot
+
+	idIgnoreList     map[string]struct{}
+	offsetIgnoreList map[uint64]struct{}
+}
+
+// NewSnapshotStorage reads and verifies a snapshot file, the snapshot must be signed by one of the trusted keys
+func NewSnapshotStorage(filename string, trustedKeys []ed25519.PublicKey) (*SnapshotStorage, error) {
+	snapshot, err := storage.ReadSnapshotFile(filename)
+	if err != nil {
+		return nil, err
+	}
+	if err = snapshot.Verify(); err != nil {
+		return nil, fmt.Errorf("failed to verify snapshot: %w", err)
+	}
+	if err = snapshot.VerifySigner(trustedKeys); err != nil {
+		return nil, fmt.Errorf("failed to verify snapshot: %w", err)
+	}
+
+	return &SnapshotStorage{
+		snapshot: snapshot,
+
+		idIgnoreList:     map[string]struct{}{},
+		offsetIgnoreList: map[uint64]struct{}{},
+	}, nil
+}
+
+func (s *SnapshotStorage) Send(_ ...storage.Message) error {
+	return ErrReadOnly
+}
+
+// GetMessages returns snapshot messages with offsets starting from the given one
+func (s *SnapshotStorage) GetMessages(offset uint64) ([]storage.Message, error) {
+	var msgs []storage.Message
+	for _, m := range s.snapshot.Messages {
+		if m.Offset < offset {
+			continue
+		}
+
+		// ignored messages are marked, not dropped, to keep the log verifiable
+		_, idOk := s.idIgnoreList[m.ID]
+		_, offsetOk := s.offsetIgnoreList[m.Offset]
+		m.Ignored = idOk || offsetOk
+		msgs = append(msgs, m)
+	}
+
+	return msgs, nil
+}
+
+func (s *SnapshotStorage) Close() error {
+	return nil
+}
+
+func (s *SnapshotStorage) IgnoreMessages(messages []string, useOffset bool) error {
+	for _, msg := range messages {
+		if useOffset {
+			offset, err := strconv.ParseUint(msg, 10, 64)
+			if err != nil {
+				return fmt.Errorf("failed to parse message offset: %w", err)
+			}
+			s.offsetIgnoreList[offset] = struct{}{}
+
+			continue
+		}
+
+		s.idIgnoreList[msg] = struct{}{}
+	}
+
+	return nil
+}
+
+func (s *SnapshotStorage) UnignoreMessages() {
+	s.idIgnoreList = map[string]struct{}{}
+	s.offsetIgnoreList = map[uint64]struct{}{}
+}
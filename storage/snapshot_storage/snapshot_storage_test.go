@@ -0,0 +1,116 @@
+package snapshot_storage
+
+import (
+	"crypto/ed25519"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/lidofinance/dc4bc/storage"
+)
+
+func newTestMessages(first, n int) []storage.Message {
+	msgs := make([]storage.Message, 0, n)
+	for i := first; i < first+n; i++ {
+		msgs = append(msgs, storage.Message{
+			ID:        string(rune('a' + i)),
+			Offset:    uint64(i),
+			Event:     "event",
+			Data:      []byte{byte(i)},
+			Signature: []byte{byte(i)},
+		})
+	}
+	return msgs
+}
+
+func TestSnapshot_Verify(t *testing.T) {
+	req := require.New(t)
+
+	snapshot := storage.NewSnapshot(newTestMessages(0, 5))
+	req.NoError(snapshot.Verify())
+
+	snapshot.Messages[2].Signature = []byte("forged")
+	req.ErrorIs(snapshot.Verify(), storage.ErrSnapshotCorrupted)
+
+	snapshot = storage.NewSnapshot(newTestMessages(0, 5))
+	snapshot.Messages = snapshot.Messages[1:]
+	req.ErrorIs(snapshot.Verify(), storage.ErrSnapshotCorrupted)
+
+	pubKey, privKey, err := ed25519.GenerateKey(nil)
+	req.NoError(err)
+	snapshot = storage.NewSnapshot(newTestMessages(0, 5))
+	snapshot.Signer, snapshot.PubKey, snapshot.Signature = "user", pubKey, ed25519.Sign(privKey, snapshot.Digest)
+	req.NoError(snapshot.Verify())
+
+	// the signature does not match the digest of another log
+	snapshot.Messages = snapshot.Messages[:4]
+	snapshot.Digest = storage.SnapshotDigest(snapshot.Messages)
+	req.ErrorIs(snapshot.Verify(), storage.ErrSnapshotCorrupted)
+}
+
+func TestSnapshot_Compare(t *testing.T) {
+	req := require.New(t)
+
+	snapshot := storage.NewSnapshot(newTestMessages(0, 10))
+
+	// the first messages are removed by retention, new messages are appended after the snapshot
+	report := snapshot.Compare(newTestMessages(4, 10))
+	req.True(report.Consistent())
+	req.Equal(4, report.Trimmed)
+	req.Equal(6, report.Matched)
+	req.Equal(4, report.New)
+
+	live := newTestMessages(0, 10)
+	live[5].Data = []byte("forged")
+	live = append(live[:7], live[8:]...)
+	report = snapshot.Compare(live)
+	req.False(report.Consistent())
+	req.Equal([]uint64{5}, report.Mismatched)
+	req.Equal([]uint64{7}, report.Missing)
+}
+
+func TestSnapshotStorage(t *testing.T) {
+	req := require.New(t)
+	filename := filepath.Join(t.TempDir(), "snapshot.json")
+
+	pubKey, privKey, err := ed25519.GenerateKey(nil)
+	req.NoError(err)
+	otherPubKey, otherPrivKey, err := ed25519.GenerateKey(nil)
+	req.NoError(err)
+
+	msgs := newTestMessages(3, 5)
+	snapshot := storage.NewSnapshot(msgs)
+	req.NoError(storage.WriteSnapshotFile(filename, snapshot))
+	_, err = NewSnapshotStorage(filename, []ed25519.PublicKey{pubKey})
+	req.ErrorIs(err, storage.ErrSnapshotNotSigned)
+
+	// a snapshot signed again with another key
+	snapshot.Signer, snapshot.PubKey, snapshot.Signature = "mallory", otherPubKey, ed25519.Sign(otherPrivKey, snapshot.Digest)
+	req.NoError(storage.WriteSnapshotFile(filename, snapshot))
+	_, err = NewSnapshotStorage(filename, []ed25519.PublicKey{pubKey})
+	req.ErrorIs(err, storage.ErrUntrustedSnapshotSigner)
+
+	snapshot.Signer, snapshot.PubKey, snapshot.Signature = "user", pubKey, ed25519.Sign(privKey, snapshot.Digest)
+	req.NoError(storage.WriteSnapshotFile(filename, snapshot))
+	stg, err := NewSnapshotStorage(filename, []ed25519.PublicKey{otherPubKey, pubKey})
+	req.NoError(err)
+	defer stg.Close()
+
+	stored, err := stg.GetMessages(0)
+	req.NoError(err)
+	req.Equal(msgs, stored)
+
+	stored, err = stg.GetMessages(5)
+	req.NoError(err)
+	req.Equal(msgs[2:], stored)
+
+	req.NoError(stg.IgnoreMessages([]string{"6"}, true))
+	stored, err = stg.GetMessages(5)
+	req.NoError(err)
+	ignored := msgs[3]
+	ignored.Ignored = true
+	req.Equal([]storage.Message{msgs[2], ignored, msgs[4]}, stored)
+
+	req.ErrorIs(stg.Send(storage.Message{}), ErrReadOnly)
+}
@@ -0,0 +1,181 @@
+package sqlite_storage
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/google/uuid"
+	_ "modernc.org/sqlite"
+
+	"github.com/lidofinance/dc4bc/storage"
+)
+
+var _ storage.Storage = (*SQLiteStorage)(nil)
+
+const (
+	// writers wait for each other for the busy timeout instead of failing with SQLITE_BUSY, a write transaction
+	// takes the lock at the beginning, so offsets can't be assigned twice
+	dsnParams = "?_pragma=busy_timeout(%d)&_pragma=journal_mode(WAL)&_txlock=immediate"
+
+	createMessagesTable = `CREATE TABLE IF NOT EXISTS messages (
+		message_offset INTEGER PRIMARY KEY,
+		id             TEXT NOT NULL UNIQUE,
+		dkg_round_id   TEXT NOT NULL,
+		event          TEXT NOT NULL,
+		data           BLOB,
+		signature      BLOB,
+		sender         TEXT NOT NULL,
+		recipient      TEXT NOT NULL,
+		prev_hash      BLOB,
+		hash           BLOB
+	)`
+
+	selectLastMessage = `SELECT message_offset, hash FROM messages ORDER BY message_offset DESC LIMIT 1`
+
+	insertMessage = `INSERT INTO messages
+		(message_offset, id, dkg_round_id, event, data, signature, sender, recipient, prev_hash, hash)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`
+
+	selectMessages = `SELECT message_offset, id, dkg_round_id, event, data, signature, sender, recipient, prev_hash, hash
+		FROM messages WHERE message_offset >= ? ORDER BY message_offset`
+)
+
+// SQLiteStorage is an append-only log in an embedded SQLite database. Messages are indexed by offsets
+// and hash-chained like in the HTTP bulletin board (see storage.ChainHash).
+type SQLiteStorage struct {
+	db *sql.DB
+
+	idIgnoreList     map[string]struct{}
+	offsetIgnoreList map[uint64]struct{}
+}
+
+// NewSQLiteStorage opens (or creates) an SQLite database with the given filename, writers wait for each other
+// for busyTimeout
+func NewSQLiteStorage(filename string, busyTimeout time.Duration) (*SQLiteStorage, error) {
+	db, err := sql.Open("sqlite", filename+fmt.Sprintf(dsnParams, busyTimeout.Milliseconds()))
+	if err != nil {
+		return nil, fmt.Errorf("failed to open SQLite database: %w", err)
+	}
+
+	if _, err = db.Exec(createMessagesTable); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("failed to create messages table: %w", err)
+	}
+
+	return &SQLiteStorage{
+		db: db,
+
+		idIgnoreList:     map[string]struct{}{},
+		offsetIgnoreList: map[uint64]struct{}{},
+	}, nil
+}
+
+// Send stores all messages in a single transaction, offsets and ids are written back to the given messages
+func (s *SQLiteStorage) Send(msgs ...storage.Message) (err error) {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer func() {
+		if err != nil {
+			_ = tx.Rollback()
+		}
+	}()
+
+	var (
+		nextOffset uint64
+		prevHash   []byte
+		lastOffset uint64
+	)
+	err = tx.QueryRow(selectLastMessage).Scan(&lastOffset, &prevHash)
+	switch {
+	case errors.Is(err, sql.ErrNoRows):
+		err = nil
+	case err != nil:
+		return fmt.Errorf("failed to get the last message: %w", err)
+	default:
+		nextOffset = lastOffset + 1
+	}
+
+	stored := make([]storage.Message, len(msgs))
+	for i, m := range msgs {
+		if m.ID == "" {
+			m.ID = uuid.New().String()
+		}
+		m.Offset = nextOffset + uint64(i)
+		m.LinkToChain(prevHash)
+		prevHash = m.Hash
+
+		if _, err = tx.Exec(insertMessage, m.Offset, m.ID, m.DkgRoundID, m.Event, m.Data, m.Signature,
+			m.SenderAddr, m.RecipientAddr, m.PrevHash, m.Hash); err != nil {
+			return fmt.Errorf("failed to insert message: %w", err)
+		}
+		stored[i] = m
+	}
+
+	if err = tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	copy(msgs, stored)
+
+	return nil
+}
+
+// GetMessages returns all messages starting from the given offset
+func (s *SQLiteStorage) GetMessages(offset uint64) ([]storage.Message, error) {
+	rows, err := s.db.Query(selectMessages, offset)
+	if err != nil {
+		return nil, fmt.Errorf("failed to select messages: %w", err)
+	}
+	defer rows.Close()
+
+	var msgs []storage.Message
+	for rows.Next() {
+		var m storage.Message
+		if err = rows.Scan(&m.Offset, &m.ID, &m.DkgRoundID, &m.Event, &m.Data, &m.Signature,
+			&m.SenderAddr, &m.RecipientAddr, &m.PrevHash, &m.Hash); err != nil {
+			return nil, fmt.Errorf("failed to scan message: %w", err)
+		}
+
+		// ignored messages are marked, not dropped, to keep the log verifiable
+		_, idOk := s.idIgnoreList[m.ID]
+		_, offsetOk := s.offsetIgnoreList[m.Offset]
+		m.Ignored = idOk || offsetOk
+		msgs = append(msgs, m)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read messages: %w", err)
+	}
+
+	return msgs, nil
+}
+
+func (s *SQLiteStorage) Close() error {
+	return s.db.Close()
+}
+
+func (s *SQLiteStorage) IgnoreMessages(messages []string, useOffset bool) error {
+	for _, msg := range messages {
+		if useOffset {
+			offset, err := strconv.ParseUint(msg, 10, 64)
+			if err != nil {
+				return fmt.Errorf("failed to parse message offset: %w", err)
+			}
+			s.offsetIgnoreList[offset] = struct{}{}
+
+			continue
+		}
+
+		s.idIgnoreList[msg] = struct{}{}
+	}
+
+	return nil
+}
+
+func (s *SQLiteStorage) UnignoreMessages() {
+	s.idIgnoreList = map[string]struct{}{}
+	s.offsetIgnoreList = map[uint64]struct{}{}
+}
@@ -0,0 +1,127 @@
+package sqlite_storage
+
+import (
+	"math/rand"
+	"path/filepath"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/lidofinance/dc4bc/storage"
+)
+
+func randomBytes(n int) []byte {
+	rand.Seed(time.Now().UnixNano())
+	b := make([]byte, n)
+	if _, err := rand.Read(b); err != nil {
+		return nil
+	}
+	return b
+}
+
+func newTestStorage(t *testing.T) (*SQLiteStorage, string) {
+	filename := filepath.Join(t.TempDir(), "dc4bc_storage.db")
+	stg, err := NewSQLiteStorage(filename, 10*time.Second)
+	require.NoError(t, err)
+	t.Cleanup(func() { stg.Close() })
+
+	return stg, filename
+}
+
+func newTestMessages(n int) []storage.Message {
+	msgs := make([]storage.Message, 0, n)
+	for i := 0; i < n; i++ {
+		msgs = append(msgs, storage.Message{
+			DkgRoundID: "dkg_round_id",
+			Event:      "event",
+			Data:       randomBytes(10),
+			Signature:  randomBytes(10),
+			SenderAddr: "sender",
+		})
+	}
+	return msgs
+}
+
+func TestSQLiteStorage_Send(t *testing.T) {
+	var (
+		N        = 10
+		req      = require.New(t)
+		stg, dsn = newTestStorage(t)
+	)
+
+	msgs := newTestMessages(N)
+	req.NoError(stg.Send(msgs[:N/2]...))
+	req.NoError(stg.Send(msgs[N/2:]...))
+	for i, m := range msgs {
+		req.Equal(uint64(i), m.Offset)
+		req.NotEmpty(m.ID)
+		req.True(m.VerifyChainHash())
+		if i > 0 {
+			req.Equal(msgs[i-1].Hash, m.PrevHash)
+		}
+	}
+
+	offsetMsgs, err := stg.GetMessages(0)
+	req.NoError(err)
+	req.Equal(msgs, offsetMsgs)
+
+	offsetMsgs, err = stg.GetMessages(uint64(N - 2))
+	req.NoError(err)
+	req.Equal(msgs[N-2:], offsetMsgs)
+
+	// another storage instance on the same database continues the log
+	stg2, err := NewSQLiteStorage(dsn, 10*time.Second)
+	req.NoError(err)
+	defer stg2.Close()
+
+	msg := newTestMessages(1)
+	req.NoError(stg2.Send(msg...))
+	req.Equal(uint64(N), msg[0].Offset)
+	req.Equal(msgs[N-1].Hash, msg[0].PrevHash)
+}
+
+func TestSQLiteStorage_SendDuplicateID(t *testing.T) {
+	var (
+		req    = require.New(t)
+		stg, _ = newTestStorage(t)
+	)
+
+	msgs := newTestMessages(2)
+	msgs[0].ID = "id"
+	msgs[1].ID = "id"
+	req.Error(stg.Send(msgs...))
+
+	// the whole batch is rolled back
+	offsetMsgs, err := stg.GetMessages(0)
+	req.NoError(err)
+	req.Empty(offsetMsgs)
+}
+
+func TestSQLiteStorage_IgnoreMessages(t *testing.T) {
+	var (
+		N      = 10
+		req    = require.New(t)
+		stg, _ = newTestStorage(t)
+	)
+
+	msgs := newTestMessages(N)
+	req.NoError(stg.Send(msgs...))
+
+	req.NoError(stg.IgnoreMessages([]string{msgs[0].ID}, false))
+	req.NoError(stg.IgnoreMessages([]string{strconv.Itoa(1)}, true))
+
+	msgsAfterIgnoring, err := stg.GetMessages(0)
+	req.NoError(err)
+	// ignored messages are read, but marked
+	expectedMsgs := append([]storage.Message{}, msgs...)
+	expectedMsgs[0].Ignored, expectedMsgs[1].Ignored = true, true
+	req.Equal(expectedMsgs, msgsAfterIgnoring)
+
+	stg.UnignoreMessages()
+
+	msgsAfterUnignoring, err := stg.GetMessages(0)
+	req.NoError(err)
+	req.Equal(msgs, msgsAfterUnignoring)
+}
@@ -2,7 +2,11 @@ package storage
 
 import (
 	"bytes"
+	"context"
 	"crypto/ed25519"
+	"encoding/binary"
+
+	"github.com/lidofinance/dc4bc/client/modules/logger"
 )
 
 type Message struct {
@@ -14,6 +18,19 @@ type Message struct {
 	Signature     []byte `json:"signature"`
 	SenderAddr    string `json:"sender"`
 	RecipientAddr string `json:"recipient"`
+
+	// BoardSignature is the sender's signature of the message headers and data, see BoardBytes. Boards which
+	// authenticate writes check it, so a signed payload can not be posted under another event, round or sender
+	BoardSignature []byte `json:"board_signature,omitempty"`
+
+	// PrevHash and Hash link the message to its predecessor in the log, they are set by boards
+	// which support tamper-evident logs (see ChainHash).
+	PrevHash []byte `json:"prev_hash,omitempty"`
+	Hash     []byte `json:"hash,omitempty"`
+
+	// Ignored is set by the storage for messages from its ignore list. Ignored messages are still read,
+	// so the log stays verifiable, but they must not be processed
+	Ignored bool `json:"-"`
 }
 
 func (m *Message) Bytes() []byte {
@@ -27,6 +44,35 @@ func (m *Message) Verify(pubKey ed25519.PublicKey) bool {
 	return ed25519.Verify(pubKey, m.Bytes(), m.Signature)
 }
 
+// boardSigningDomain separates board signatures from signatures of the message data
+const boardSigningDomain = "dc4bc-board-message-v1"
+
+// BoardBytes returns a canonical encoding of the message headers and data, which is signed by BoardSignature.
+// The id and the offset are assigned by the board, so they are not signed
+func (m *Message) BoardBytes() []byte {
+	buf := bytes.NewBuffer(nil)
+	writeField := func(field []byte) {
+		lenBz := make([]byte, 8)
+		binary.BigEndian.PutUint64(lenBz, uint64(len(field)))
+		buf.Write(lenBz)
+		buf.Write(field)
+	}
+
+	writeField([]byte(boardSigningDomain))
+	writeField([]byte(m.DkgRoundID))
+	writeField([]byte(m.Event))
+	writeField([]byte(m.SenderAddr))
+	writeField([]byte(m.RecipientAddr))
+	writeField(m.Data)
+
+	return buf.Bytes()
+}
+
+// VerifyBoardSignature checks the signature of the message headers and data
+func (m *Message) VerifyBoardSignature(pubKey ed25519.PublicKey) bool {
+	return ed25519.Verify(pubKey, m.BoardBytes(), m.BoardSignature)
+}
+
 type Storage interface {
 	// Send is expected to be an atomic operation.
 	Send(messages ...Message) error
@@ -35,3 +81,15 @@ type Storage interface {
 	IgnoreMessages(messages []string, useOffset bool) error
 	UnignoreMessages()
 }
+
+// LoggingStorage is an optional Storage capability to log failures of background reads to the node's logger
+type LoggingStorage interface {
+	SetLogger(l logger.Logger)
+}
+
+// Subscriber is an optional Storage capability to push new messages instead of being polled
+type Subscriber interface {
+	// Subscribe streams messages starting from the given offset until the context is done.
+	// The channel is closed when the context is done or the subscription fails.
+	Subscribe(ctx context.Context, offset uint64) <-chan Message
+}